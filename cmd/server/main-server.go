@@ -14,12 +14,15 @@ import (
 	"time"
 
 	"github.com/wavetermdev/waveterm/pkg/authkey"
+	"github.com/wavetermdev/waveterm/pkg/backup"
 	"github.com/wavetermdev/waveterm/pkg/blockcontroller"
 	"github.com/wavetermdev/waveterm/pkg/blocklogger"
+	"github.com/wavetermdev/waveterm/pkg/dbcrypt"
 	"github.com/wavetermdev/waveterm/pkg/filestore"
 	"github.com/wavetermdev/waveterm/pkg/panichandler"
 	"github.com/wavetermdev/waveterm/pkg/remote/conncontroller"
 	"github.com/wavetermdev/waveterm/pkg/remote/fileshare/wshfs"
+	"github.com/wavetermdev/waveterm/pkg/scheduler"
 	"github.com/wavetermdev/waveterm/pkg/service"
 	"github.com/wavetermdev/waveterm/pkg/telemetry"
 	"github.com/wavetermdev/waveterm/pkg/telemetry/telemetrydata"
@@ -64,6 +67,10 @@ func doShutdown(reason string) {
 		// TODO deal with flush in progress
 		clearTempFiles()
 		filestore.WFS.FlushCache(ctx)
+		err := wcore.FlushEventJournal(ctx)
+		if err != nil {
+			log.Printf("%v\n", err)
+		}
 		watcher := wconfig.GetWatcher()
 		if watcher != nil {
 			watcher.Close()
@@ -105,6 +112,41 @@ func telemetryLoop() {
 	}
 }
 
+// removes closed tabs and blocks past the trash retention window
+func trashPurgeLoop() {
+	defer func() {
+		panichandler.PanicHandler("trashPurgeLoop", recover())
+	}()
+	for {
+		ctx, cancelFn := context.WithTimeout(context.Background(), 5*time.Minute)
+		numPurged, err := wcore.PurgeTrash(ctx)
+		cancelFn()
+		if err != nil {
+			log.Printf("error purging trash: %v\n", err)
+		} else if numPurged > 0 {
+			log.Printf("purged %d trash entries\n", numPurged)
+		}
+		time.Sleep(wcore.TrashPurgeInterval)
+	}
+}
+
+func eventJournalPurgeLoop() {
+	defer func() {
+		panichandler.PanicHandler("eventJournalPurgeLoop", recover())
+	}()
+	for {
+		ctx, cancelFn := context.WithTimeout(context.Background(), 5*time.Minute)
+		numPurged, err := wcore.PurgeEventJournal(ctx)
+		cancelFn()
+		if err != nil {
+			log.Printf("error purging event journal: %v\n", err)
+		} else if numPurged > 0 {
+			log.Printf("purged %d events from the event journal\n", numPurged)
+		}
+		time.Sleep(wcore.EventJournalPurgeInterval)
+	}
+}
+
 func panicTelemetryHandler(panicName string) {
 	activity := wshrpc.ActivityUpdate{NumPanics: 1}
 	err := telemetry.UpdateActivity(context.Background(), activity)
@@ -259,9 +301,22 @@ func grabAndRemoveEnvVars() error {
 	if err != nil {
 		return err
 	}
+	dbcrypt.CacheAndRemoveEnvVars()
 	return nil
 }
 
+// the keys have to be loaded before the dbs are opened, which is before the config watcher starts,
+// so this reads the settings file directly
+func initDBEncryption() error {
+	settings, cerrs := wconfig.ReadWaveHomeConfigFile(wconfig.SettingsFile)
+	for _, cerr := range cerrs {
+		log.Printf("error reading settings: %s\n", cerr.Err)
+	}
+	encrypt := settings.GetBool(wconfig.ConfigKey_DbEncrypt, false)
+	keySource := settings.GetString(wconfig.ConfigKey_DbKeySource, dbcrypt.KeySourceAuto)
+	return dbcrypt.Init(encrypt, keySource)
+}
+
 func clearTempFiles() error {
 	ctx, cancelFn := context.WithTimeout(context.Background(), 2*time.Second)
 	defer cancelFn()
@@ -325,6 +380,16 @@ func main() {
 	log.Printf("wave version: %s (%s)\n", WaveVersion, BuildTime)
 	log.Printf("wave data dir: %s\n", wavebase.GetWaveDataDir())
 	log.Printf("wave config dir: %s\n", wavebase.GetWaveConfigDir())
+	err = backup.ApplyPendingRestore()
+	if err != nil {
+		log.Printf("error applying restored backup: %v\n", err)
+		return
+	}
+	err = initDBEncryption()
+	if err != nil {
+		log.Printf("error initializing database encryption: %v\n", err)
+		return
+	}
 	err = filestore.InitFilestore()
 	if err != nil {
 		log.Printf("error initializing filestore: %v\n", err)
@@ -335,7 +400,13 @@ func main() {
 		log.Printf("error initializing wstore: %v\n", err)
 		return
 	}
+	err = wcore.StartEventJournal(context.Background())
+	if err != nil {
+		log.Printf("error starting event journal: %v\n", err)
+		return
+	}
 	panichandler.PanicTelemetryHandler = panicTelemetryHandler
+	filestore.GetQuotas = wcore.GetFileStoreQuotas
 	go func() {
 		defer func() {
 			panichandler.PanicHandler("InitCustomShellStartupFiles", recover())
@@ -355,8 +426,54 @@ func main() {
 		log.Printf("error clearing temp files: %v\n", err)
 		return
 	}
+	go func() {
+		defer func() {
+			panichandler.PanicHandler("CleanupOrphanedSessions", recover())
+		}()
+		ctx, cancelFn := context.WithTimeout(context.Background(), 30*time.Second)
+		defer cancelFn()
+		blockcontroller.CleanupOrphanedSessions(ctx)
+	}()
+
+	go func() {
+		defer func() {
+			panichandler.PanicHandler("RunFileStoreGC", recover())
+		}()
+		time.Sleep(wcore.FileStoreGCStartupDelay)
+		ctx, cancelFn := context.WithTimeout(context.Background(), 5*time.Minute)
+		defer cancelFn()
+		_, err := wcore.RunFileStoreGC(ctx, wshrpc.CommandFileStoreGCData{})
+		if err != nil {
+			log.Printf("error running filestore gc: %v\n", err)
+		}
+	}()
+
+	go trashPurgeLoop()
+	go eventJournalPurgeLoop()
 
 	createMainWshClient()
+	go func() {
+		defer func() {
+			panichandler.PanicHandler("scheduler.Start", recover())
+		}()
+		ctx, cancelFn := context.WithTimeout(context.Background(), 30*time.Second)
+		defer cancelFn()
+		err := scheduler.Start(ctx)
+		if err != nil {
+			log.Printf("error starting scheduler: %v\n", err)
+		}
+	}()
+	go func() {
+		defer func() {
+			panichandler.PanicHandler("blockcontroller.StartInputGroups", recover())
+		}()
+		ctx, cancelFn := context.WithTimeout(context.Background(), 30*time.Second)
+		defer cancelFn()
+		err := blockcontroller.StartInputGroups(ctx)
+		if err != nil {
+			log.Printf("error starting input groups: %v\n", err)
+		}
+	}()
 	sigutil.InstallShutdownSignalHandlers(doShutdown)
 	sigutil.InstallSIGUSR1Handler()
 	startConfigWatcher()
@@ -0,0 +1,85 @@
+// Copyright 2025, Command Line Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package cmd
+
+import (
+	"testing"
+
+	"github.com/wavetermdev/waveterm/pkg/wps"
+)
+
+func TestEventMatchExpr(t *testing.T) {
+	event := &wps.WaveEvent{
+		Event:  wps.Event_ControllerStatus,
+		Scopes: []string{"tab:1", "block:2"},
+		Data: map[string]any{
+			"shellprocstatus":   "done",
+			"shellprocexitcode": 0,
+			"error":             "",
+			"cmd":               "a==b",
+		},
+	}
+	tests := []struct {
+		expr    string
+		want    bool
+		wantErr bool
+	}{
+		{expr: "", want: true},
+		{expr: `.data.shellprocstatus == "done"`, want: true},
+		{expr: `.data.shellprocstatus != "done"`, want: false},
+		{expr: `.data.shellprocexitcode == 0`, want: true},
+		{expr: `.scopes[1] == "block:2"`, want: true},
+		{expr: `.scopes[5] == "block:2"`, want: false},
+		{expr: `.data.error`, want: false},
+		{expr: `not .data.error`, want: true},
+		{expr: `.data.missing != "x"`, want: true},
+		{expr: `.event == "controllerstatus" and .data.shellprocstatus == "running"`, want: false},
+		{expr: `.data.shellprocstatus == "running" or .data.shellprocstatus == "done"`, want: true},
+		{expr: `.data.shellprocstatus == "a and b"`, want: false},
+		{expr: `.data.cmd == "a==b"`, want: true},
+		{expr: `.data.cmd != "a==b"`, want: false},
+		{expr: `.data.shellprocstatus != "x==y"`, want: true},
+		{expr: `.data.cmd == "a!=b"`, want: false},
+		{expr: `data.shellprocstatus`, wantErr: true},
+		{expr: `.data.shellprocstatus == done`, wantErr: true},
+	}
+	for _, tt := range tests {
+		expr, err := parseEventMatchExpr(tt.expr)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("expr %q: expected error", tt.expr)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("expr %q: unexpected error: %v", tt.expr, err)
+			continue
+		}
+		got, err := expr.matchEvent(event)
+		if err != nil {
+			t.Errorf("expr %q: match error: %v", tt.expr, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("expr %q: got %v, want %v", tt.expr, got, tt.want)
+		}
+	}
+}
+
+func TestEventQueueDrops(t *testing.T) {
+	queue := makeEventQueue()
+	numEvents := cap(queue.ch) + 10
+	for i := 0; i < numEvents; i++ {
+		// must not block when nobody reads the queue
+		queue.push(&wps.WaveEvent{Event: wps.Event_ControllerStatus})
+	}
+	if len(queue.ch) != cap(queue.ch) || queue.dropped.Load() != 10 {
+		t.Errorf("expected %d queued and 10 dropped events, got %d and %d", cap(queue.ch), len(queue.ch), queue.dropped.Load())
+	}
+	select {
+	case <-queue.dropCh:
+	default:
+		t.Errorf("the drop should be signaled")
+	}
+}
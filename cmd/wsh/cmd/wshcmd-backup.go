@@ -0,0 +1,141 @@
+// Copyright 2025, Command Line Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/wavetermdev/waveterm/pkg/wshrpc"
+	"github.com/wavetermdev/waveterm/pkg/wshrpc/wshclient"
+)
+
+const backupTimeout = 30 * 60 * 1000
+
+var backupCmd = &cobra.Command{
+	Use:   "backup",
+	Short: "back up and restore wave's state (workspaces, blocks, files and config)",
+	Long: `Back up and restore wave's state as a single .tar.gz archive.
+
+A backup holds consistent snapshots of wave's databases and the files in the config dir.  A full restore
+replaces the current state the next time wave starts (the current state is kept in a "pre-restore" dir in
+the data dir).  A single workspace can be restored into the running app with --workspace.`,
+}
+
+var backupCreateCmd = &cobra.Command{
+	Use:     "create FILE",
+	Short:   "write a backup to FILE",
+	Example: "  wsh backup create ~/wave-backup.tar.gz",
+	Args:    cobra.ExactArgs(1),
+	RunE:    activityWrap("backup", backupCreateRun),
+	PreRunE: preRunSetupRpcClient,
+}
+
+var backupRestoreCmd = &cobra.Command{
+	Use:     "restore FILE [--workspace id] [--dryrun]",
+	Short:   "restore a backup (applied on the next start), or a single workspace from it",
+	Example: "  wsh backup restore ~/wave-backup.tar.gz\n  wsh backup restore ~/wave-backup.tar.gz --workspace 0d1e3bc2-...",
+	Args:    cobra.ExactArgs(1),
+	RunE:    activityWrap("backup", backupRestoreRun),
+	PreRunE: preRunSetupRpcClient,
+}
+
+var backupInfoCmd = &cobra.Command{
+	Use:     "info FILE",
+	Short:   "validate a backup and list its workspaces",
+	Args:    cobra.ExactArgs(1),
+	RunE:    activityWrap("backup", backupInfoRun),
+	PreRunE: preRunSetupRpcClient,
+}
+
+var backupRestoreWorkspace string
+var backupRestoreDryRun bool
+
+func init() {
+	backupRestoreCmd.Flags().StringVar(&backupRestoreWorkspace, "workspace", "", "only restore this workspace (it must not exist)")
+	backupRestoreCmd.Flags().BoolVar(&backupRestoreDryRun, "dryrun", false, "only validate the backup")
+	backupCmd.AddCommand(backupCreateCmd)
+	backupCmd.AddCommand(backupRestoreCmd)
+	backupCmd.AddCommand(backupInfoCmd)
+	rootCmd.AddCommand(backupCmd)
+}
+
+// backups are read and written by the wave app, so the path must be local
+func getBackupPath(fileName string) (string, error) {
+	if RpcContext.Conn != "" {
+		return "", fmt.Errorf("backups can only be made from local blocks (they are written by the Wave app)")
+	}
+	absPath, err := filepath.Abs(fileName)
+	if err != nil {
+		return "", fmt.Errorf("getting absolute path: %w", err)
+	}
+	return absPath, nil
+}
+
+func backupCreateRun(cmd *cobra.Command, args []string) error {
+	path, err := getBackupPath(args[0])
+	if err != nil {
+		return err
+	}
+	rtn, err := wshclient.BackupCreateCommand(RpcClient, wshrpc.CommandBackupCreateData{Path: path}, &wshrpc.RpcOpts{Timeout: backupTimeout})
+	if err != nil {
+		return fmt.Errorf("creating backup: %w", err)
+	}
+	WriteStdout("wrote %s (%d bytes, %d workspaces)\n", rtn.Path, rtn.Size, len(rtn.Manifest.Workspaces))
+	return nil
+}
+
+func backupRestoreRun(cmd *cobra.Command, args []string) error {
+	path, err := getBackupPath(args[0])
+	if err != nil {
+		return err
+	}
+	data := wshrpc.CommandBackupRestoreData{Path: path, WorkspaceId: backupRestoreWorkspace, DryRun: backupRestoreDryRun}
+	rtn, err := wshclient.BackupRestoreCommand(RpcClient, data, &wshrpc.RpcOpts{Timeout: backupTimeout})
+	if err != nil {
+		return fmt.Errorf("restoring backup: %w", err)
+	}
+	switch {
+	case backupRestoreDryRun:
+		WriteStdout("backup is valid\n")
+	case backupRestoreWorkspace != "":
+		WriteStdout("restored workspace %s (%d objects, %d files)\n", backupRestoreWorkspace, rtn.NumObjects, rtn.NumFiles)
+	default:
+		WriteStdout("backup from %s will be restored when wave is restarted\n", formatBackupTs(rtn.Manifest.CreatedTs))
+	}
+	return nil
+}
+
+func backupInfoRun(cmd *cobra.Command, args []string) error {
+	path, err := getBackupPath(args[0])
+	if err != nil {
+		return err
+	}
+	data := wshrpc.CommandBackupRestoreData{Path: path, DryRun: true}
+	rtn, err := wshclient.BackupRestoreCommand(RpcClient, data, &wshrpc.RpcOpts{Timeout: backupTimeout})
+	if err != nil {
+		return err
+	}
+	m := rtn.Manifest
+	WriteStdout("created:  %s (wave %s)\n", formatBackupTs(m.CreatedTs), m.WaveVersion)
+	WriteStdout("schema:   wstore %d, filestore %d\n", m.WStoreVersion, m.FilestoreVersion)
+	if m.EncryptionKeyId != "" {
+		WriteStdout("key:      %s\n", m.EncryptionKeyId)
+	}
+	WriteStdout("\n")
+	w := tabwriter.NewWriter(os.Stdout, 0, 8, 2, ' ', 0)
+	fmt.Fprintf(w, "WORKSPACE\tNAME\tTABS\n")
+	for _, ws := range m.Workspaces {
+		fmt.Fprintf(w, "%s\t%s\t%d\n", ws.WorkspaceId, ws.Name, ws.NumTabs)
+	}
+	return w.Flush()
+}
+
+func formatBackupTs(ts int64) string {
+	return time.UnixMilli(ts).Format("2006-01-02 15:04:05")
+}
@@ -0,0 +1,151 @@
+// Copyright 2025, Command Line Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+	"github.com/wavetermdev/waveterm/pkg/waveobj"
+	"github.com/wavetermdev/waveterm/pkg/wshrpc"
+	"github.com/wavetermdev/waveterm/pkg/wshrpc/wshclient"
+)
+
+var blocksCmd = &cobra.Command{
+	Use:   "blocks",
+	Short: "list blocks and their resource usage",
+}
+
+var blocksListCmd = &cobra.Command{
+	Use:   "list [--workspace id] [--json]",
+	Short: "list blocks, busiest first",
+	Long: `List the blocks of all workspaces with the cpu, memory and open files used by the processes running in them
+(the shell and everything started from it).  Running blocks are sorted by cpu usage.`,
+	Args:    cobra.NoArgs,
+	RunE:    activityWrap("blocks", blocksListRun),
+	PreRunE: preRunSetupRpcClient,
+}
+
+var blocksStatsCmd = &cobra.Command{
+	Use:     "stats [-b blockid] [-n count] [-i interval]",
+	Short:   "print the resource usage of a block every interval",
+	Args:    cobra.NoArgs,
+	RunE:    activityWrap("blocks", blocksStatsRun),
+	PreRunE: preRunSetupRpcClient,
+}
+
+var blocksListWorkspace string
+var blocksListJson bool
+var blocksListNoStats bool
+var blocksListLimit int
+var blocksStatsCount int
+var blocksStatsInterval int
+
+func init() {
+	blocksListCmd.Flags().StringVar(&blocksListWorkspace, "workspace", "", "only list the blocks of this workspace")
+	blocksListCmd.Flags().BoolVar(&blocksListJson, "json", false, "output blocks as json")
+	blocksListCmd.Flags().BoolVar(&blocksListNoStats, "nostats", false, "don't sample process usage (faster)")
+	blocksListCmd.Flags().IntVarP(&blocksListLimit, "limit", "n", 0, "only list the top n blocks")
+	blocksStatsCmd.Flags().IntVarP(&blocksStatsCount, "count", "n", 0, "number of samples (default until interrupted)")
+	blocksStatsCmd.Flags().IntVarP(&blocksStatsInterval, "interval", "i", 1000, "interval between samples in milliseconds")
+	blocksCmd.AddCommand(blocksListCmd)
+	blocksCmd.AddCommand(blocksStatsCmd)
+	rootCmd.AddCommand(blocksCmd)
+}
+
+func formatMemSize(size int64) string {
+	const unit = 1024
+	if size < unit {
+		return fmt.Sprintf("%dB", size)
+	}
+	value := float64(size) / unit
+	for _, suffix := range []string{"K", "M", "G"} {
+		if value < unit {
+			return fmt.Sprintf("%.1f%s", value, suffix)
+		}
+		value /= unit
+	}
+	return fmt.Sprintf("%.1fT", value)
+}
+
+func sortBlocksByUsage(blocks []*wshrpc.BlocksListEntry) {
+	sort.SliceStable(blocks, func(i, j int) bool {
+		si, sj := blocks[i].Stats, blocks[j].Stats
+		if si == nil || sj == nil {
+			return si != nil
+		}
+		if si.Cpu != sj.Cpu {
+			return si.Cpu > sj.Cpu
+		}
+		return si.Rss > sj.Rss
+	})
+}
+
+func blocksListRun(cmd *cobra.Command, args []string) error {
+	data := wshrpc.CommandBlocksListData{WorkspaceId: blocksListWorkspace, Stats: !blocksListNoStats}
+	blocks, err := wshclient.BlocksListCommand(RpcClient, data, &wshrpc.RpcOpts{Timeout: 10000})
+	if err != nil {
+		return fmt.Errorf("listing blocks: %w", err)
+	}
+	sortBlocksByUsage(blocks)
+	if blocksListLimit > 0 && len(blocks) > blocksListLimit {
+		blocks = blocks[:blocksListLimit]
+	}
+	if blocksListJson {
+		barr, err := json.MarshalIndent(blocks, "", "  ")
+		if err != nil {
+			return fmt.Errorf("formatting blocks: %w", err)
+		}
+		WriteStdout("%s\n", string(barr))
+		return nil
+	}
+	writer := tabwriter.NewWriter(os.Stdout, 0, 8, 2, ' ', 0)
+	fmt.Fprintf(writer, "BLOCK\tVIEW\tCONN\tSTATUS\tCPU%%\tMEM\tFDS\tPROCS\tTOP\n")
+	for _, block := range blocks {
+		conn := block.Connection
+		if conn == "" {
+			conn = "local"
+		}
+		status := block.Status
+		if status == "" {
+			status = "-"
+		}
+		cpu, mem, fds, procs, top := "-", "-", "-", "-", ""
+		if block.Stats != nil {
+			cpu = fmt.Sprintf("%.1f", block.Stats.Cpu)
+			mem = formatMemSize(block.Stats.Rss)
+			fds = fmt.Sprintf("%d", block.Stats.NumFds)
+			procs = fmt.Sprintf("%d", block.Stats.NumProcs)
+			top = strings.Join(block.Stats.Children, ",")
+		} else if block.StatsError != "" {
+			top = "error: " + block.StatsError
+		}
+		fmt.Fprintf(writer, "%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\n", block.BlockId, block.View, conn, status, cpu, mem, fds, procs, top)
+	}
+	return writer.Flush()
+}
+
+func blocksStatsRun(cmd *cobra.Command, args []string) error {
+	fullORef, err := resolveBlockArg()
+	if err != nil {
+		return err
+	}
+	if fullORef.OType != waveobj.OType_Block {
+		return fmt.Errorf("stats require a block")
+	}
+	req := wshrpc.BlockProcStatsRequest{BlockId: fullORef.OID, Interval: blocksStatsInterval, Count: blocksStatsCount}
+	for respUnion := range wshclient.StreamBlockProcStatsCommand(RpcClient, req, &wshrpc.RpcOpts{Timeout: TimeoutYear}) {
+		if respUnion.Error != nil {
+			return respUnion.Error
+		}
+		stats := respUnion.Response
+		WriteStdout("cpu %5.1f%%  mem %7s  fds %4d  procs %3d  %s\n", stats.Cpu, formatMemSize(stats.Rss), stats.NumFds, stats.NumProcs, strings.Join(stats.Children, ","))
+	}
+	return nil
+}
@@ -5,8 +5,12 @@ package cmd
 
 import (
 	"encoding/json"
+	"fmt"
+	"os"
+	"text/tabwriter"
 
 	"github.com/spf13/cobra"
+	"github.com/wavetermdev/waveterm/pkg/wshrpc"
 	"github.com/wavetermdev/waveterm/pkg/wshrpc/wshclient"
 )
 
@@ -38,7 +42,67 @@ var debugGetTabCmd = &cobra.Command{
 	Hidden: true,
 }
 
+var debugRecompressCmd = &cobra.Command{
+	Use:    "recompress [--zone zoneid] [--off]",
+	Short:  "compress (or decompress) the data of existing wave files",
+	Args:   cobra.NoArgs,
+	RunE:   debugRecompressRun,
+	Hidden: true,
+}
+
+var debugGCCmd = &cobra.Command{
+	Use:    "gc [--dryrun] [--json]",
+	Short:  "remove the files of deleted blocks and vacuum the filestore db",
+	Args:   cobra.NoArgs,
+	RunE:   debugGCRun,
+	Hidden: true,
+}
+
+var debugStorageCmd = &cobra.Command{
+	Use:    "storage [--zone zoneid] [--limit n] [--json]",
+	Short:  "show how much data the wave files use, by block and by file type",
+	Args:   cobra.NoArgs,
+	RunE:   debugStorageRun,
+	Hidden: true,
+}
+
+var debugEncryptCmd = &cobra.Command{
+	Use:    "encrypt [--off] [--rotate] [--keysource keyring|passphrase|file]",
+	Short:  "encrypt (or decrypt) the existing data in the wave dbs, or rotate the encryption key",
+	Args:   cobra.NoArgs,
+	RunE:   debugEncryptRun,
+	Hidden: true,
+}
+
+var debugGCDryRun bool
+var debugGCJson bool
+
+var debugStorageZone string
+var debugStorageLimit int
+var debugStorageJson bool
+
+var debugRecompressZone string
+var debugRecompressOff bool
+
+var debugEncryptOff bool
+var debugEncryptRotate bool
+var debugEncryptKeySource string
+
 func init() {
+	debugGCCmd.Flags().BoolVar(&debugGCDryRun, "dryrun", false, "only report the orphaned zones and how much space they use")
+	debugGCCmd.Flags().BoolVar(&debugGCJson, "json", false, "output the report as json")
+	debugCmd.AddCommand(debugGCCmd)
+	debugStorageCmd.Flags().StringVar(&debugStorageZone, "zone", "", "also list the files of this zone (block id)")
+	debugStorageCmd.Flags().IntVar(&debugStorageLimit, "limit", 0, "number of zones to show (largest first, default 20)")
+	debugStorageCmd.Flags().BoolVar(&debugStorageJson, "json", false, "output the report as json")
+	debugCmd.AddCommand(debugStorageCmd)
+	debugRecompressCmd.Flags().StringVar(&debugRecompressZone, "zone", "", "only recompress the files of this zone (block id)")
+	debugRecompressCmd.Flags().BoolVar(&debugRecompressOff, "off", false, "decompress the files instead")
+	debugCmd.AddCommand(debugRecompressCmd)
+	debugEncryptCmd.Flags().BoolVar(&debugEncryptOff, "off", false, "decrypt the data and stop encrypting new data")
+	debugEncryptCmd.Flags().BoolVar(&debugEncryptRotate, "rotate", false, "switch to a new key and re-encrypt the data")
+	debugEncryptCmd.Flags().StringVar(&debugEncryptKeySource, "keysource", "", "where to keep the key when creating or rotating it (keyring, passphrase or file)")
+	debugCmd.AddCommand(debugEncryptCmd)
 	debugCmd.AddCommand(debugBlockIdsCmd)
 	debugCmd.AddCommand(debugSendTelemetryCmd)
 	debugCmd.AddCommand(debugGetTabCmd)
@@ -79,3 +143,121 @@ func debugBlockIdsRun(cmd *cobra.Command, args []string) error {
 	WriteStdout("%s\n", string(barr))
 	return nil
 }
+
+func debugRecompressRun(cmd *cobra.Command, args []string) error {
+	data := wshrpc.CommandFileStoreRecompressData{ZoneId: debugRecompressZone, Compress: !debugRecompressOff}
+	rtn, err := wshclient.FileStoreRecompressCommand(RpcClient, data, &wshrpc.RpcOpts{Timeout: 10 * 60 * 1000})
+	if err != nil {
+		return err
+	}
+	WriteStdout("recompressed %d files (%d parts): %d -> %d bytes\n", rtn.NumFiles, rtn.NumParts, rtn.OldBytes, rtn.NewBytes)
+	return nil
+}
+
+func debugEncryptRun(cmd *cobra.Command, args []string) error {
+	data := wshrpc.CommandDBEncryptData{Off: debugEncryptOff, Rotate: debugEncryptRotate, KeySource: debugEncryptKeySource}
+	rtn, err := wshclient.DBEncryptCommand(RpcClient, data, &wshrpc.RpcOpts{Timeout: 10 * 60 * 1000})
+	if err != nil {
+		return err
+	}
+	if rtn.Encrypted {
+		WriteStdout("encrypted %d objects and %d files (%d parts) with key %s (%s)\n", rtn.NumObjects, rtn.NumFiles, rtn.NumParts, rtn.KeyId, rtn.KeySource)
+	} else {
+		WriteStdout("decrypted %d objects and %d files (%d parts)\n", rtn.NumObjects, rtn.NumFiles, rtn.NumParts)
+	}
+	return nil
+}
+
+func debugGCRun(cmd *cobra.Command, args []string) error {
+	// always vacuum when run by hand
+	data := wshrpc.CommandFileStoreGCData{DryRun: debugGCDryRun, Vacuum: true}
+	rtn, err := wshclient.FileStoreGCCommand(RpcClient, data, &wshrpc.RpcOpts{Timeout: 10 * 60 * 1000})
+	if err != nil {
+		return err
+	}
+	if debugGCJson {
+		barr, err := json.MarshalIndent(rtn, "", "  ")
+		if err != nil {
+			return err
+		}
+		WriteStdout("%s\n", string(barr))
+		return nil
+	}
+	if len(rtn.Zones) > 0 {
+		writer := tabwriter.NewWriter(os.Stdout, 0, 8, 2, ' ', 0)
+		fmt.Fprintf(writer, "ZONE\tFILES\tSIZE\tSTORED\n")
+		for _, zone := range rtn.Zones {
+			fmt.Fprintf(writer, "%s\t%d\t%d\t%d\n", zone.ZoneId, zone.NumFiles, zone.Size, zone.DataBytes)
+		}
+		writer.Flush()
+	}
+	if rtn.DryRun {
+		WriteStdout("%d orphaned zones, %d bytes reclaimable (+%d bytes free in the db)\n", len(rtn.Zones), rtn.ReclaimableBytes, rtn.FreeBytes)
+		return nil
+	}
+	WriteStdout("removed %d orphaned zones (%d bytes), db size %d -> %d bytes\n", len(rtn.Zones), rtn.ReclaimableBytes, rtn.DBSizeBefore, rtn.DBSizeAfter)
+	return nil
+}
+
+func formatQuota(limit int64) string {
+	if limit <= 0 {
+		return "none"
+	}
+	return fmt.Sprintf("%d", limit)
+}
+
+func debugStorageRun(cmd *cobra.Command, args []string) error {
+	data := wshrpc.CommandFileStoreUsageData{ZoneId: debugStorageZone, Limit: debugStorageLimit}
+	rtn, err := wshclient.FileStoreUsageCommand(RpcClient, data, &wshrpc.RpcOpts{Timeout: 60 * 1000})
+	if err != nil {
+		return err
+	}
+	if debugStorageJson {
+		barr, err := json.MarshalIndent(rtn, "", "  ")
+		if err != nil {
+			return err
+		}
+		WriteStdout("%s\n", string(barr))
+		return nil
+	}
+	WriteStdout("%d zones, %d bytes of data (%d bytes stored), db size %d bytes\n", rtn.NumZones, rtn.DataLength, rtn.DataBytes, rtn.DBSize)
+	WriteStdout("quotas: block %s, total %s", formatQuota(rtn.Quotas.MaxZoneSize), formatQuota(rtn.Quotas.MaxTotalSize))
+	for name, limit := range rtn.Quotas.MaxFileTypeSize {
+		WriteStdout(", %q files %s", name, formatQuota(limit))
+	}
+	WriteStdout("\n\n")
+	writer := tabwriter.NewWriter(os.Stdout, 0, 8, 2, ' ', 0)
+	fmt.Fprintf(writer, "ZONE\tOWNER\tFILES\tSIZE\tSTORED\n")
+	for _, zone := range rtn.Zones {
+		owner := zone.OType
+		if zone.View != "" {
+			owner += ":" + zone.View
+		}
+		if owner == "" {
+			owner = "(deleted)"
+		}
+		fmt.Fprintf(writer, "%s\t%s\t%d\t%d\t%d\n", zone.ZoneId, owner, zone.NumFiles, zone.DataLength, zone.DataBytes)
+	}
+	writer.Flush()
+	WriteStdout("\n")
+	writer = tabwriter.NewWriter(os.Stdout, 0, 8, 2, ' ', 0)
+	fmt.Fprintf(writer, "FILE TYPE\tFILES\tSIZE\tSTORED\n")
+	for _, fileType := range rtn.FileTypes {
+		fmt.Fprintf(writer, "%s\t%d\t%d\t%d\n", fileType.Name, fileType.NumFiles, fileType.DataLength, fileType.DataBytes)
+	}
+	writer.Flush()
+	if debugStorageZone != "" {
+		WriteStdout("\n")
+		writer = tabwriter.NewWriter(os.Stdout, 0, 8, 2, ' ', 0)
+		fmt.Fprintf(writer, "FILE\tSIZE\tSTORED\tWRITTEN\n")
+		for _, file := range rtn.Files {
+			name := file.Name
+			if file.Circular {
+				name += " (circular)"
+			}
+			fmt.Fprintf(writer, "%s\t%d\t%d\t%d\n", name, file.DataLength, file.DataBytes, file.Size)
+		}
+		writer.Flush()
+	}
+	return nil
+}
@@ -0,0 +1,591 @@
+// Copyright 2025, Command Line Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"syscall"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/wavetermdev/waveterm/pkg/util/utilfn"
+	"github.com/wavetermdev/waveterm/pkg/wps"
+	"github.com/wavetermdev/waveterm/pkg/wshrpc"
+	"github.com/wavetermdev/waveterm/pkg/wshrpc/wshclient"
+)
+
+// events that are subscribed to when no --event flag is given
+var defaultTailEvents = []string{
+	wps.Event_BlockClose,
+	wps.Event_ConnChange,
+	wps.Event_ControllerStatus,
+	wps.Event_WaveObjUpdate,
+	wps.Event_Config,
+	wps.Event_UserInput,
+	wps.Event_RouteGone,
+	wps.Event_WorkspaceUpdate,
+	wps.Event_FileChange,
+}
+
+var eventsCmd = &cobra.Command{
+	Use:   "events",
+	Short: "subscribe to wave events and print them (one json object per line)",
+	Long: `Subscribe to the Wave event stream and print each event as a single line of JSON until interrupted.
+
+If no --event flag is given, all common events are printed (blockfile and sysinfo events must be requested explicitly).
+Scopes are object references like "block:[blockid]" or "tab:[tabid]", and may contain "*" wildcards.`,
+	Example: "  wsh events --event controllerstatus --scope block:*\n  wsh events --event connchange --history 10",
+	Args:    cobra.NoArgs,
+	RunE:    activityWrap("events", eventsRun),
+	PreRunE: preRunSetupRpcClient,
+}
+
+var eventsWaitCmd = &cobra.Command{
+	Use:   "wait --event X [--match expr]",
+	Short: "block until a matching event arrives",
+	Long: `Block until an event matching the given expression arrives, print it, and exit.
+
+The match expression is a small jq-like language evaluated against the event JSON:
+  .data.shellprocstatus == "done"
+  .data.connected == true and .scopes[0] == "block:..."
+  .data.error                  (true if the value is present and not false/null/"")
+Supported operators are ==, !=, and, or, and a leading "not".`,
+	Example: `  wsh events wait --event controllerstatus --scope block:$WAVETERM_BLOCKID --match '.data.shellprocstatus == "done"'`,
+	Args:    cobra.NoArgs,
+	RunE:    activityWrap("events", eventsWaitRun),
+	PreRunE: preRunSetupRpcClient,
+}
+
+var eventsReplayCmd = &cobra.Command{
+	Use:   "replay [--since seq] [--follow]",
+	Short: "print events from the event journal",
+	Long: `Print the events after the given sequence number from the event journal (one json object per line, in seq order).
+
+Only the events listed in eventjournal:events are journaled, and only while eventjournal:enabled is set.
+Every journaled event has a "seq" field, pass the last seq you have seen to --since to get the events you missed.
+With --follow, new events are printed as they arrive (without gaps or duplicates).`,
+	Example: "  wsh events replay --since 120\n  wsh events replay --event connchange --follow",
+	Args:    cobra.NoArgs,
+	RunE:    activityWrap("events", eventsReplayRun),
+	PreRunE: preRunSetupRpcClient,
+}
+
+var eventsNames []string
+var eventsScopes []string
+var eventsHistory int
+var eventsWaitMatch string
+var eventsWaitTimeout int64
+var eventsReplaySince int64
+var eventsReplayLimit int
+var eventsReplayFollow bool
+
+func init() {
+	eventsCmd.Flags().StringArrayVarP(&eventsNames, "event", "e", nil, "event name to subscribe to (can be repeated)")
+	eventsCmd.Flags().StringArrayVarP(&eventsScopes, "scope", "s", nil, "only show events with this scope (can be repeated)")
+	eventsCmd.Flags().IntVar(&eventsHistory, "history", 0, "print up to N persisted events for each event/scope before tailing")
+	eventsWaitCmd.Flags().StringArrayVarP(&eventsNames, "event", "e", nil, "event name to wait for (can be repeated)")
+	eventsWaitCmd.Flags().StringArrayVarP(&eventsScopes, "scope", "s", nil, "only match events with this scope (can be repeated)")
+	eventsWaitCmd.Flags().StringVarP(&eventsWaitMatch, "match", "m", "", "jq-like match expression (matches any event if empty)")
+	eventsWaitCmd.Flags().Int64VarP(&eventsWaitTimeout, "timeout", "t", 0, "timeout in milliseconds (0 waits forever)")
+	eventsWaitCmd.Flags().IntVar(&eventsHistory, "history", 0, "also match up to N persisted events for each event/scope")
+	eventsWaitCmd.MarkFlagRequired("event")
+	eventsReplayCmd.Flags().Int64Var(&eventsReplaySince, "since", 0, "print the events after this seq (0 prints the whole journal)")
+	eventsReplayCmd.Flags().StringArrayVarP(&eventsNames, "event", "e", nil, "only print this event (can be repeated)")
+	eventsReplayCmd.Flags().StringArrayVarP(&eventsScopes, "scope", "s", nil, "only print events with this scope (can be repeated)")
+	eventsReplayCmd.Flags().IntVarP(&eventsReplayLimit, "limit", "n", 0, "print at most N journaled events (0 for no limit)")
+	eventsReplayCmd.Flags().BoolVarP(&eventsReplayFollow, "follow", "f", false, "keep printing new events as they arrive")
+	eventsCmd.AddCommand(eventsWaitCmd)
+	eventsCmd.AddCommand(eventsReplayCmd)
+	rootCmd.AddCommand(eventsCmd)
+}
+
+func printEventLine(event *wps.WaveEvent) error {
+	barr, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("marshaling event: %w", err)
+	}
+	WriteStdout("%s\n", string(barr))
+	return nil
+}
+
+func subscribeToEvents(eventNames []string, scopes []string, fn func(*wps.WaveEvent)) error {
+	for _, eventName := range eventNames {
+		RpcClient.EventListener.On(eventName, fn)
+		subReq := wps.SubscriptionRequest{Event: eventName, Scopes: scopes, AllScopes: len(scopes) == 0}
+		err := wshclient.EventSubCommand(RpcClient, subReq, &wshrpc.RpcOpts{Timeout: 2000})
+		if err != nil {
+			return fmt.Errorf("subscribing to %q: %w", eventName, err)
+		}
+	}
+	return nil
+}
+
+func makeInterruptCh() chan os.Signal {
+	sigCh := make(chan os.Signal, 1)
+	signal.Notify(sigCh, syscall.SIGINT, syscall.SIGTERM)
+	return sigCh
+}
+
+// events are handed off from the rpc client through a bounded queue.  if they are not printed fast enough they
+// are dropped (and counted) instead of blocking the rpc client.
+type eventQueue struct {
+	ch      chan *wps.WaveEvent
+	dropped atomic.Int64
+	dropCh  chan struct{} // signaled when an event is dropped
+}
+
+func makeEventQueue() *eventQueue {
+	return &eventQueue{ch: make(chan *wps.WaveEvent, 64), dropCh: make(chan struct{}, 1)}
+}
+
+func (q *eventQueue) push(event *wps.WaveEvent) {
+	select {
+	case q.ch <- event:
+	default:
+		q.dropped.Add(1)
+		select {
+		case q.dropCh <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// reads up to maxItems persisted events for each event/scope
+func readEventHistory(eventNames []string, scopes []string, maxItems int) ([]*wps.WaveEvent, error) {
+	if maxItems <= 0 {
+		return nil, nil
+	}
+	if len(scopes) == 0 {
+		scopes = []string{""}
+	}
+	var rtn []*wps.WaveEvent
+	for _, eventName := range eventNames {
+		for _, scope := range scopes {
+			histData := wshrpc.CommandEventReadHistoryData{Event: eventName, Scope: scope, MaxItems: maxItems}
+			events, err := wshclient.EventReadHistoryCommand(RpcClient, histData, &wshrpc.RpcOpts{Timeout: 5000})
+			if err != nil {
+				return nil, fmt.Errorf("reading event history for %q: %w", eventName, err)
+			}
+			rtn = append(rtn, events...)
+		}
+	}
+	return rtn, nil
+}
+
+func eventKey(event *wps.WaveEvent) string {
+	barr, _ := json.Marshal(event)
+	return string(barr)
+}
+
+func eventsRun(cmd *cobra.Command, args []string) error {
+	eventNames := eventsNames
+	if len(eventNames) == 0 {
+		eventNames = defaultTailEvents
+	}
+	// subscribe before reading the history, so no event is missed in between
+	queue := makeEventQueue()
+	err := subscribeToEvents(eventNames, eventsScopes, queue.push)
+	if err != nil {
+		return err
+	}
+	defer wshclient.EventUnsubAllCommand(RpcClient, &wshrpc.RpcOpts{NoResponse: true})
+	history, err := readEventHistory(eventNames, eventsScopes, eventsHistory)
+	if err != nil {
+		return err
+	}
+	historyKeys := make(map[string]bool)
+	for _, event := range history {
+		if err := printEventLine(event); err != nil {
+			return err
+		}
+		historyKeys[eventKey(event)] = true
+	}
+	// the events that arrived while the history was read can also be in the history
+	numPending := len(queue.ch)
+	sigCh := makeInterruptCh()
+	for {
+		select {
+		case event := <-queue.ch:
+			if numPending > 0 {
+				numPending--
+				if historyKeys[eventKey(event)] {
+					continue
+				}
+			}
+			if err := printEventLine(event); err != nil {
+				return err
+			}
+		case <-queue.dropCh:
+			WriteStderr("[warning] dropped %d events (they arrived faster than they could be printed)\n", queue.dropped.Swap(0))
+		case <-sigCh:
+			return nil
+		}
+	}
+}
+
+func eventsWaitRun(cmd *cobra.Command, args []string) error {
+	matchExpr, err := parseEventMatchExpr(eventsWaitMatch)
+	if err != nil {
+		return fmt.Errorf("invalid match expression: %w", err)
+	}
+	matchCh := make(chan *wps.WaveEvent, 1)
+	err = subscribeToEvents(eventsNames, eventsScopes, func(event *wps.WaveEvent) {
+		ok, err := matchExpr.matchEvent(event)
+		if err != nil || !ok {
+			return
+		}
+		select {
+		case matchCh <- event:
+		default:
+		}
+	})
+	if err != nil {
+		return err
+	}
+	defer wshclient.EventUnsubAllCommand(RpcClient, &wshrpc.RpcOpts{NoResponse: true})
+	// the history is read after subscribing, so an event published in between is matched either way
+	history, err := readEventHistory(eventsNames, eventsScopes, eventsHistory)
+	if err != nil {
+		return err
+	}
+	for _, event := range history {
+		if ok, _ := matchExpr.matchEvent(event); ok {
+			return printEventLine(event)
+		}
+	}
+	var timeoutCh <-chan time.Time
+	if eventsWaitTimeout > 0 {
+		timeoutCh = time.After(time.Duration(eventsWaitTimeout) * time.Millisecond)
+	}
+	sigCh := makeInterruptCh()
+	select {
+	case event := <-matchCh:
+		return printEventLine(event)
+	case <-timeoutCh:
+		return fmt.Errorf("timeout waiting for event")
+	case <-sigCh:
+		WshExitCode = 1
+		return nil
+	}
+}
+
+// prints the journaled events after sinceSeq (up to limit if > 0), returns the seq of the last event read
+func printReplayedEvents(sinceSeq int64, limit int, firstPage func(*wshrpc.EventReplayRtnData)) (int64, int, error) {
+	numPrinted := 0
+	for {
+		data := wshrpc.CommandEventReplayData{SinceSeq: sinceSeq, Events: eventsNames, Scopes: eventsScopes}
+		if limit > 0 {
+			data.MaxItems = limit - numPrinted
+		}
+		rtn, err := wshclient.EventReplayCommand(RpcClient, data, &wshrpc.RpcOpts{Timeout: 10000})
+		if err != nil {
+			return sinceSeq, numPrinted, fmt.Errorf("replaying events: %w", err)
+		}
+		if firstPage != nil {
+			firstPage(rtn)
+			firstPage = nil
+		}
+		for _, event := range rtn.Events {
+			if err := printEventLine(event); err != nil {
+				return sinceSeq, numPrinted, err
+			}
+			sinceSeq = event.Seq
+			numPrinted++
+		}
+		if !rtn.More || (limit > 0 && numPrinted >= limit) {
+			return sinceSeq, numPrinted, nil
+		}
+	}
+}
+
+func eventsReplayRun(cmd *cobra.Command, args []string) error {
+	if eventsReplaySince < 0 {
+		return fmt.Errorf("--since must not be negative")
+	}
+	var journalEvents []string
+	lastSeq, numPrinted, err := printReplayedEvents(eventsReplaySince, eventsReplayLimit, func(rtn *wshrpc.EventReplayRtnData) {
+		journalEvents = rtn.JournalEvents
+		if !rtn.Enabled {
+			WriteStderr("[warning] the event journal is not enabled (set eventjournal:enabled)\n")
+		}
+		if eventsReplaySince > 0 && rtn.FirstSeq > eventsReplaySince+1 {
+			WriteStderr("[warning] events up to seq %d were removed from the journal\n", rtn.FirstSeq-1)
+		}
+	})
+	if err != nil || !eventsReplayFollow {
+		return err
+	}
+	if eventsReplayLimit > 0 && numPrinted >= eventsReplayLimit {
+		return nil
+	}
+	eventNames := eventsNames
+	if len(eventNames) == 0 {
+		eventNames = journalEvents
+	}
+	if len(eventNames) == 0 {
+		return fmt.Errorf("no events are journaled")
+	}
+	queue := makeEventQueue()
+	err = subscribeToEvents(eventNames, eventsScopes, queue.push)
+	if err != nil {
+		return err
+	}
+	defer wshclient.EventUnsubAllCommand(RpcClient, &wshrpc.RpcOpts{NoResponse: true})
+	// events journaled between the replay and the subscription
+	lastSeq, _, err = printReplayedEvents(lastSeq, 0, nil)
+	if err != nil {
+		return err
+	}
+	sigCh := makeInterruptCh()
+	for {
+		select {
+		case event := <-queue.ch:
+			if event.Seq > 0 && event.Seq <= lastSeq {
+				continue
+			}
+			if err := printEventLine(event); err != nil {
+				return err
+			}
+			lastSeq = max(lastSeq, event.Seq)
+		case <-queue.dropCh:
+			// the dropped events are still in the journal (events that are not journaled are lost)
+			numDropped := queue.dropped.Swap(0)
+			lastSeq, _, err = printReplayedEvents(lastSeq, 0, nil)
+			if err != nil {
+				return err
+			}
+			for _, eventName := range eventNames {
+				if !utilfn.ContainsStr(journalEvents, eventName) {
+					WriteStderr("[warning] dropped %d events, the ones that are not journaled are lost\n", numDropped)
+					break
+				}
+			}
+		case <-sigCh:
+			return nil
+		}
+	}
+}
+
+// eventMatchExpr is a tiny jq-like expression.  it is an "or" of "and" groups of terms.
+type eventMatchExpr struct {
+	OrGroups [][]eventMatchTerm
+}
+
+type eventMatchTerm struct {
+	Negate bool
+	Path   []string
+	Op     string // "", "==", "!="
+	Value  any
+}
+
+func parseEventMatchExpr(exprStr string) (*eventMatchExpr, error) {
+	rtn := &eventMatchExpr{}
+	exprStr = strings.TrimSpace(exprStr)
+	if exprStr == "" {
+		return rtn, nil
+	}
+	for _, orPart := range splitExprKeyword(exprStr, "or") {
+		var group []eventMatchTerm
+		for _, andPart := range splitExprKeyword(orPart, "and") {
+			term, err := parseEventMatchTerm(andPart)
+			if err != nil {
+				return nil, err
+			}
+			group = append(group, term)
+		}
+		rtn.OrGroups = append(rtn.OrGroups, group)
+	}
+	return rtn, nil
+}
+
+// splits on a keyword surrounded by whitespace, ignoring keywords inside of double quotes
+func splitExprKeyword(exprStr string, keyword string) []string {
+	var rtn []string
+	inQuote := false
+	start := 0
+	sep := " " + keyword + " "
+	for i := 0; i < len(exprStr); i++ {
+		ch := exprStr[i]
+		if ch == '\\' && inQuote {
+			i++
+			continue
+		}
+		if ch == '"' {
+			inQuote = !inQuote
+			continue
+		}
+		if !inQuote && strings.HasPrefix(exprStr[i:], sep) {
+			rtn = append(rtn, strings.TrimSpace(exprStr[start:i]))
+			i += len(sep) - 1
+			start = i + 1
+		}
+	}
+	rtn = append(rtn, strings.TrimSpace(exprStr[start:]))
+	return rtn
+}
+
+// returns the index of the first == or != that is not inside of double quotes (-1 if there is none)
+func findExprOperator(termStr string) (int, string) {
+	inQuote := false
+	for i := 0; i < len(termStr); i++ {
+		ch := termStr[i]
+		if ch == '\\' && inQuote {
+			i++
+			continue
+		}
+		if ch == '"' {
+			inQuote = !inQuote
+			continue
+		}
+		if inQuote {
+			continue
+		}
+		for _, op := range []string{"==", "!="} {
+			if strings.HasPrefix(termStr[i:], op) {
+				return i, op
+			}
+		}
+	}
+	return -1, ""
+}
+
+func parseEventMatchTerm(termStr string) (eventMatchTerm, error) {
+	var term eventMatchTerm
+	termStr = strings.TrimSpace(termStr)
+	if strings.HasPrefix(termStr, "not ") {
+		term.Negate = true
+		termStr = strings.TrimSpace(strings.TrimPrefix(termStr, "not "))
+	}
+	pathStr := termStr
+	if idx, op := findExprOperator(termStr); idx != -1 {
+		pathStr = strings.TrimSpace(termStr[:idx])
+		valStr := strings.TrimSpace(termStr[idx+len(op):])
+		var val any
+		if err := json.Unmarshal([]byte(valStr), &val); err != nil {
+			return term, fmt.Errorf("invalid value %q (values must be json, strings must be quoted)", valStr)
+		}
+		term.Op = op
+		term.Value = val
+	}
+	path, err := parseEventMatchPath(pathStr)
+	if err != nil {
+		return term, err
+	}
+	term.Path = path
+	return term, nil
+}
+
+// parses ".a.b[0].c" into ["a", "b", "0", "c"]
+func parseEventMatchPath(pathStr string) ([]string, error) {
+	if !strings.HasPrefix(pathStr, ".") {
+		return nil, fmt.Errorf("path %q must start with '.'", pathStr)
+	}
+	if pathStr == "." {
+		return nil, nil
+	}
+	pathStr = strings.ReplaceAll(pathStr, "[", ".")
+	pathStr = strings.ReplaceAll(pathStr, "]", "")
+	parts := strings.Split(pathStr[1:], ".")
+	for _, part := range parts {
+		if part == "" {
+			return nil, fmt.Errorf("invalid path %q", pathStr)
+		}
+	}
+	return parts, nil
+}
+
+func lookupMatchPath(val any, path []string) (any, bool) {
+	for _, part := range path {
+		switch v := val.(type) {
+		case map[string]any:
+			next, ok := v[part]
+			if !ok {
+				return nil, false
+			}
+			val = next
+		case []any:
+			idx, err := strconv.Atoi(part)
+			if err != nil || idx < 0 || idx >= len(v) {
+				return nil, false
+			}
+			val = v[idx]
+		default:
+			return nil, false
+		}
+	}
+	return val, true
+}
+
+func isTruthy(val any) bool {
+	switch v := val.(type) {
+	case nil:
+		return false
+	case bool:
+		return v
+	case string:
+		return v != ""
+	}
+	return true
+}
+
+func (term eventMatchTerm) eval(val any) bool {
+	found, ok := lookupMatchPath(val, term.Path)
+	var rtn bool
+	switch term.Op {
+	case "==":
+		rtn = ok && jsonValuesEqual(found, term.Value)
+	case "!=":
+		rtn = !ok || !jsonValuesEqual(found, term.Value)
+	default:
+		rtn = ok && isTruthy(found)
+	}
+	if term.Negate {
+		return !rtn
+	}
+	return rtn
+}
+
+func jsonValuesEqual(v1 any, v2 any) bool {
+	b1, err1 := json.Marshal(v1)
+	b2, err2 := json.Marshal(v2)
+	return err1 == nil && err2 == nil && string(b1) == string(b2)
+}
+
+func (expr *eventMatchExpr) matchValue(val any) bool {
+	if len(expr.OrGroups) == 0 {
+		return true
+	}
+	for _, group := range expr.OrGroups {
+		allMatch := true
+		for _, term := range group {
+			if !term.eval(val) {
+				allMatch = false
+				break
+			}
+		}
+		if allMatch {
+			return true
+		}
+	}
+	return false
+}
+
+func (expr *eventMatchExpr) matchEvent(event *wps.WaveEvent) (bool, error) {
+	// normalize the event through json so typed data and map data are handled the same way
+	barr, err := json.Marshal(event)
+	if err != nil {
+		return false, err
+	}
+	var val any
+	if err := json.Unmarshal(barr, &val); err != nil {
+		return false, err
+	}
+	return expr.matchValue(val), nil
+}
@@ -11,8 +11,11 @@ import (
 	"io"
 	"log"
 	"os"
+	"os/exec"
 	"path"
 	"path/filepath"
+	"runtime"
+	"strconv"
 	"strings"
 	"text/tabwriter"
 	"time"
@@ -106,6 +109,30 @@ func init() {
 	fileMvCmd.Flags().BoolP("recursive", "r", false, "move directories recursively")
 	fileMvCmd.Flags().BoolP("force", "f", false, "force overwrite of existing files")
 	fileCmd.AddCommand(fileMvCmd)
+	fileSyncCmd.Flags().Bool("delete", false, "delete files in the destination that are not in the source")
+	fileSyncCmd.Flags().BoolP("dry-run", "n", false, "show what would be copied and deleted without making changes")
+	fileSyncCmd.Flags().BoolP("checksum", "c", false, "compare file checksums instead of modification times (wsh connections only)")
+	fileSyncCmd.Flags().StringArrayP("exclude", "x", nil, "exclude files matching this glob pattern (can be repeated)")
+	fileSyncCmd.Flags().BoolP("quiet", "q", false, "only print the summary")
+	fileCmd.AddCommand(fileSyncCmd)
+	fileFindCmd.Flags().StringArrayP("name", "n", nil, "only match file names matching this glob pattern (can be repeated)")
+	fileFindCmd.Flags().StringP("grep", "g", "", "only match files containing a line matching this regular expression")
+	fileFindCmd.Flags().BoolP("ignore-case", "i", false, "case insensitive name and content matching")
+	fileFindCmd.Flags().BoolP("files-with-matches", "l", false, "only print file paths, not matching lines")
+	fileFindCmd.Flags().String("min-size", "", "only match files of at least this size (e.g. 10k, 5M)")
+	fileFindCmd.Flags().String("max-size", "", "only match files of at most this size (e.g. 10k, 5M)")
+	fileFindCmd.Flags().String("newer", "", "only match files modified within this duration (e.g. 30m, 24h, 7d)")
+	fileFindCmd.Flags().String("older", "", "only match files not modified within this duration (e.g. 30m, 24h, 7d)")
+	fileFindCmd.Flags().Bool("dirs", false, "also match directories (ignored with --grep)")
+	fileFindCmd.Flags().StringArrayP("exclude", "x", nil, "skip paths matching this glob pattern (can be repeated)")
+	fileFindCmd.Flags().IntP("max", "m", 0, "maximum number of results (default 1000)")
+	fileCmd.AddCommand(fileFindCmd)
+	fileWatchCmd.Flags().BoolP("recursive", "r", false, "watch subdirectories recursively")
+	fileWatchCmd.Flags().StringArrayP("exclude", "x", nil, "ignore paths matching this glob pattern (can be repeated)")
+	fileWatchCmd.Flags().StringP("exec", "e", "", "run this shell command after changes")
+	fileWatchCmd.Flags().Int("debounce", 200, "milliseconds to wait for more changes before running --exec")
+	fileWatchCmd.Flags().BoolP("quiet", "q", false, "do not print change events")
+	fileCmd.AddCommand(fileWatchCmd)
 }
 
 var fileListCmd = &cobra.Command{
@@ -190,6 +217,54 @@ var fileMvCmd = &cobra.Command{
 	PreRunE: preRunSetupRpcClient,
 }
 
+var fileFindCmd = &cobra.Command{
+	Use:   "find [uri]",
+	Short: "search for files by name, size, age and content",
+	Long: `Search a directory tree for files. By default, searches the current directory.
+
+The search runs on the storage system itself (for wsh URIs, on the remote host), so only the
+matching results are transferred. Name patterns are matched against the file name, or against the
+path relative to the search root if the pattern contains a "/". With --grep, matching lines are
+printed as "path:line:text". Exits with status 1 if nothing matches.` + UriHelpText,
+	Example: "  wsh file find -n '*.go' -g 'TODO' //devbox/~/src\n  wsh file find --min-size 100M --older 30d /var/log\n  wsh file find -n '*.json' s3://my-bucket/configs",
+	Args:    cobra.MaximumNArgs(1),
+	RunE:    activityWrap("file", fileFindRun),
+	PreRunE: preRunSetupRpcClient,
+}
+
+var fileWatchCmd = &cobra.Command{
+	Use:   "watch [uri]",
+	Short: "watch a file or directory for changes",
+	Long: `Watch a file or directory and print a line ("op path") for each change until interrupted.
+Ops are create, write, remove and rename.
+
+With --exec, the command is run through the shell after each burst of changes (see --debounce). Runs never
+overlap: changes that happen while the command is running trigger one more run when it finishes. The
+command gets the last change in $WAVE_WATCH_OP and $WAVE_WATCH_PATH.
+
+Watching is only supported for wsh URIs (local and remote paths).` + UriHelpText,
+	Example: "  wsh file watch -r -x .git -x node_modules --exec 'make' //devbox/~/project\n  wsh file watch ./config.yaml --exec 'systemctl --user restart myapp'",
+	Args:    cobra.MaximumNArgs(1),
+	RunE:    activityWrap("file", fileWatchRun),
+	PreRunE: preRunSetupRpcClient,
+}
+
+var fileSyncCmd = &cobra.Command{
+	Use:   "sync [source-uri] [destination-uri]",
+	Short: "incrementally sync a directory to another storage system",
+	Long: `Make the destination directory match the source directory, transferring only files that are new or changed.
+
+Files are compared by size and modification time, or by checksum with --checksum. Copies to wsh URIs keep
+the source's modification time, so any difference counts as a change. Other destinations set their own
+modification time, so there a file is only copied when the source is newer. Checksums are computed on the remote side and are only available for wsh URIs.
+Exclude patterns are matched against relative paths and against each path element, so "node_modules"
+excludes that directory anywhere in the tree.` + UriHelpText,
+	Example: "  wsh file sync ./build wsh://user@devbox/home/user/app/build\n  wsh file sync --delete -x node_modules -x '*.log' ./src //devbox/~/src\n  wsh file sync -n ./site s3://my-bucket/site",
+	Args:    cobra.ExactArgs(2),
+	RunE:    activityWrap("file", fileSyncRun),
+	PreRunE: preRunSetupRpcClient,
+}
+
 func fileCatRun(cmd *cobra.Command, args []string) error {
 	path, err := fixRelativePaths(args[0])
 	if err != nil {
@@ -416,6 +491,261 @@ func fileCpRun(cmd *cobra.Command, args []string) error {
 	return nil
 }
 
+func fileSyncRun(cmd *cobra.Command, args []string) error {
+	src, dst := args[0], args[1]
+	deleteExtra, _ := cmd.Flags().GetBool("delete")
+	dryRun, _ := cmd.Flags().GetBool("dry-run")
+	checksum, _ := cmd.Flags().GetBool("checksum")
+	excludes, _ := cmd.Flags().GetStringArray("exclude")
+	quiet, _ := cmd.Flags().GetBool("quiet")
+
+	srcPath, err := fixRelativePaths(src)
+	if err != nil {
+		return fmt.Errorf("unable to parse src path: %w", err)
+	}
+	destPath, err := fixRelativePaths(dst)
+	if err != nil {
+		return fmt.Errorf("unable to parse dest path: %w", err)
+	}
+	log.Printf("Syncing %s to %s; delete: %v, dryrun: %v, checksum: %v, excludes: %v", srcPath, destPath, deleteExtra, dryRun, checksum, excludes)
+	data := wshrpc.CommandFileSyncData{
+		SrcUri:  srcPath,
+		DestUri: destPath,
+		Opts: &wshrpc.FileSyncOpts{
+			Delete:   deleteExtra,
+			DryRun:   dryRun,
+			Checksum: checksum,
+			Excludes: excludes,
+			Timeout:  TimeoutYear,
+		},
+	}
+	rtnCh := wshclient.FileSyncCommand(RpcClient, data, &wshrpc.RpcOpts{Timeout: TimeoutYear})
+	for respUnion := range rtnCh {
+		if respUnion.Error != nil {
+			return fmt.Errorf("syncing files: %w", respUnion.Error)
+		}
+		update := respUnion.Response
+		switch update.Op {
+		case wshrpc.FileSyncOp_Copy:
+			if !quiet {
+				WriteStdout("[%d/%d] copy %s (%s, %d bytes)\n", update.FilesDone, update.FilesTotal, update.Path, update.Reason, update.Size)
+			}
+		case wshrpc.FileSyncOp_Delete:
+			if !quiet {
+				WriteStdout("delete %s\n", update.Path)
+			}
+		case wshrpc.FileSyncOp_Summary:
+			verb := "synced"
+			if update.DryRun {
+				verb = "dry run, would have synced"
+			}
+			WriteStdout("%s: %d files copied (%d bytes), %d deleted, %d unchanged\n", verb, update.FilesDone, update.BytesDone, update.NumDeleted, update.NumUnchanged)
+		}
+	}
+	return nil
+}
+
+// parseSizeArg parses a byte count with an optional k/m/g suffix (powers of 1024)
+func parseSizeArg(sizeStr string) (int64, error) {
+	if sizeStr == "" {
+		return 0, nil
+	}
+	mult := int64(1)
+	numStr := strings.TrimSuffix(strings.ToLower(sizeStr), "b")
+	switch {
+	case strings.HasSuffix(numStr, "k"):
+		mult = 1024
+	case strings.HasSuffix(numStr, "m"):
+		mult = 1024 * 1024
+	case strings.HasSuffix(numStr, "g"):
+		mult = 1024 * 1024 * 1024
+	}
+	if mult > 1 {
+		numStr = numStr[:len(numStr)-1]
+	}
+	num, err := strconv.ParseInt(numStr, 10, 64)
+	if err != nil || num < 0 {
+		return 0, fmt.Errorf("invalid size %q", sizeStr)
+	}
+	return num * mult, nil
+}
+
+// parseAgeArg converts a duration (time.ParseDuration syntax, plus a "d" suffix for days) to a unix ms cutoff relative to now
+func parseAgeArg(ageStr string) (int64, error) {
+	if ageStr == "" {
+		return 0, nil
+	}
+	var dur time.Duration
+	if days, ok := strings.CutSuffix(ageStr, "d"); ok {
+		numDays, err := strconv.Atoi(days)
+		if err != nil || numDays < 0 {
+			return 0, fmt.Errorf("invalid duration %q", ageStr)
+		}
+		dur = time.Duration(numDays) * 24 * time.Hour
+	} else {
+		var err error
+		dur, err = time.ParseDuration(ageStr)
+		if err != nil || dur < 0 {
+			return 0, fmt.Errorf("invalid duration %q", ageStr)
+		}
+	}
+	return time.Now().Add(-dur).UnixMilli(), nil
+}
+
+func fileFindRun(cmd *cobra.Command, args []string) error {
+	names, _ := cmd.Flags().GetStringArray("name")
+	grepRe, _ := cmd.Flags().GetString("grep")
+	ignoreCase, _ := cmd.Flags().GetBool("ignore-case")
+	filesOnly, _ := cmd.Flags().GetBool("files-with-matches")
+	minSizeStr, _ := cmd.Flags().GetString("min-size")
+	maxSizeStr, _ := cmd.Flags().GetString("max-size")
+	newer, _ := cmd.Flags().GetString("newer")
+	older, _ := cmd.Flags().GetString("older")
+	includeDirs, _ := cmd.Flags().GetBool("dirs")
+	excludes, _ := cmd.Flags().GetStringArray("exclude")
+	maxResults, _ := cmd.Flags().GetInt("max")
+
+	opts := &wshrpc.FileSearchOpts{
+		NamePatterns: names,
+		ContentRegex: grepRe,
+		IgnoreCase:   ignoreCase,
+		IncludeDirs:  includeDirs,
+		Excludes:     excludes,
+		MaxResults:   maxResults,
+		Timeout:      TimeoutYear,
+	}
+	var err error
+	if opts.MinSize, err = parseSizeArg(minSizeStr); err != nil {
+		return err
+	}
+	if opts.MaxSize, err = parseSizeArg(maxSizeStr); err != nil {
+		return err
+	}
+	if opts.ModifiedAfter, err = parseAgeArg(newer); err != nil {
+		return err
+	}
+	if opts.ModifiedBefore, err = parseAgeArg(older); err != nil {
+		return err
+	}
+
+	if len(args) == 0 {
+		args = []string{"."}
+	}
+	path, err := fixRelativePaths(args[0])
+	if err != nil {
+		return err
+	}
+	log.Printf("Searching %s; opts: %+v", path, opts)
+	rtnCh := wshclient.FileSearchCommand(RpcClient, wshrpc.FileSearchData{Path: path, Opts: opts}, &wshrpc.RpcOpts{Timeout: TimeoutYear})
+	defer utilfn.DrainChannelSafe(rtnCh, "fileFindRun")
+	numResults := 0
+	for respUnion := range rtnCh {
+		if respUnion.Error != nil {
+			return fmt.Errorf("searching files: %w", respUnion.Error)
+		}
+		for _, result := range respUnion.Response.Results {
+			numResults++
+			if len(result.Matches) == 0 || filesOnly {
+				WriteStdout("%s\n", result.Info.Path)
+				continue
+			}
+			for _, match := range result.Matches {
+				WriteStdout("%s:%d:%s\n", result.Info.Path, match.LineNum, match.Line)
+			}
+		}
+	}
+	if numResults == 0 {
+		WshExitCode = 1
+	}
+	return nil
+}
+
+func startWatchExec(execCmd string, lastEvent wshrpc.FileWatchEvent, doneCh chan<- struct{}) {
+	var proc *exec.Cmd
+	if runtime.GOOS == "windows" {
+		proc = exec.Command("cmd", "/C", execCmd)
+	} else {
+		proc = exec.Command("sh", "-c", execCmd)
+	}
+	proc.Stdin = os.Stdin
+	proc.Stdout = os.Stdout
+	proc.Stderr = os.Stderr
+	proc.Env = append(os.Environ(), "WAVE_WATCH_OP="+lastEvent.Op, "WAVE_WATCH_PATH="+lastEvent.Path)
+	go func() {
+		defer func() {
+			doneCh <- struct{}{}
+		}()
+		if err := proc.Run(); err != nil {
+			WriteStderr("[watch] command failed: %v\n", err)
+		}
+	}()
+}
+
+func fileWatchRun(cmd *cobra.Command, args []string) error {
+	recursive, _ := cmd.Flags().GetBool("recursive")
+	excludes, _ := cmd.Flags().GetStringArray("exclude")
+	execCmd, _ := cmd.Flags().GetString("exec")
+	debounceMs, _ := cmd.Flags().GetInt("debounce")
+	quiet, _ := cmd.Flags().GetBool("quiet")
+
+	if len(args) == 0 {
+		args = []string{"."}
+	}
+	path, err := fixRelativePaths(args[0])
+	if err != nil {
+		return err
+	}
+	log.Printf("Watching %s; recursive: %v, excludes: %v, exec: %q", path, recursive, excludes, execCmd)
+	rpcOpts := &wshrpc.RpcOpts{Timeout: TimeoutYear}
+	rtnCh := wshclient.FileWatchCommand(RpcClient, wshrpc.CommandFileWatchData{Uri: path, Recursive: recursive, Excludes: excludes}, rpcOpts)
+	defer func() {
+		if rpcOpts.StreamCancelFn != nil {
+			rpcOpts.StreamCancelFn()
+		}
+	}()
+	sigCh := makeInterruptCh()
+	debounceTimer := time.NewTimer(time.Hour)
+	debounceTimer.Stop()
+	doneCh := make(chan struct{}, 1)
+	var lastEvent wshrpc.FileWatchEvent
+	running := false
+	pending := false
+	for {
+		select {
+		case <-sigCh:
+			return nil
+		case respUnion, ok := <-rtnCh:
+			if !ok {
+				return nil
+			}
+			if respUnion.Error != nil {
+				return fmt.Errorf("watching %s: %w", path, respUnion.Error)
+			}
+			lastEvent = respUnion.Response
+			if !quiet {
+				WriteStdout("%s %s\n", lastEvent.Op, lastEvent.Path)
+			}
+			if execCmd != "" {
+				debounceTimer.Reset(time.Duration(debounceMs) * time.Millisecond)
+			}
+		case <-debounceTimer.C:
+			if running {
+				pending = true
+				continue
+			}
+			running = true
+			startWatchExec(execCmd, lastEvent, doneCh)
+		case <-doneCh:
+			running = false
+			if pending {
+				pending = false
+				running = true
+				startWatchExec(execCmd, lastEvent, doneCh)
+			}
+		}
+	}
+}
+
 func fileMvRun(cmd *cobra.Command, args []string) error {
 	src, dst := args[0], args[1]
 	recursive, err := cmd.Flags().GetBool("recursive")
@@ -0,0 +1,144 @@
+// Copyright 2025, Command Line Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package cmd
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/wavetermdev/waveterm/pkg/waveobj"
+	"github.com/wavetermdev/waveterm/pkg/wshrpc"
+	"github.com/wavetermdev/waveterm/pkg/wshrpc/wshclient"
+)
+
+var historyCmd = &cobra.Command{
+	Use:   "history [-b blockid] [-s text] [--failed] [--since 2h]",
+	Short: "list commands recorded by shell integration",
+	Long: `List the commands run in Wave terminals, oldest first.  Commands are recorded from the shell integration
+markers that Wave's bash, zsh, fish, and pwsh startup files emit (set WAVETERM_SHELLINTEGRATION=0 to disable).
+
+Lists commands from all blocks unless -b is given.  Use "wsh history output HISTORYID" to print the output of a command.`,
+	Example: "  wsh history -n 20\n  wsh history -b this --failed\n  wsh history -s docker --since 1d",
+	Args:    cobra.NoArgs,
+	RunE:    activityWrap("history", historyRun),
+	PreRunE: preRunSetupRpcClient,
+}
+
+var historyOutputCmd = &cobra.Command{
+	Use:     "output HISTORYID [--format raw|text]",
+	Short:   "print the output of a recorded command",
+	Long:    "Print the output of a recorded command.  The output is read from the block's stored terminal output, so it is unavailable once the block is deleted or the output has scrolled out.",
+	Args:    cobra.ExactArgs(1),
+	RunE:    activityWrap("history", historyOutputRun),
+	PreRunE: preRunSetupRpcClient,
+}
+
+var historyConn string
+var historySearch string
+var historyFailed bool
+var historySince string
+var historyLimit int
+var historyJson bool
+var historyOutputFormat string
+
+func init() {
+	historyCmd.Flags().StringVar(&historyConn, "conn", "", "only show commands run on this connection")
+	historyCmd.Flags().StringVarP(&historySearch, "search", "s", "", "only show commands containing this text")
+	historyCmd.Flags().BoolVar(&historyFailed, "failed", false, "only show commands that exited with a non-zero exit code")
+	historyCmd.Flags().StringVar(&historySince, "since", "", "only show commands started within this duration (e.g. 30m, 2h, 7d)")
+	historyCmd.Flags().IntVarP(&historyLimit, "limit", "n", 0, "maximum number of commands to show (default 100)")
+	historyCmd.Flags().BoolVar(&historyJson, "json", false, "output entries as json")
+	historyOutputCmd.Flags().StringVarP(&historyOutputFormat, "format", "f", wshrpc.ScrollbackFormat_Text, "output format (raw or text)")
+	historyCmd.AddCommand(historyOutputCmd)
+	rootCmd.AddCommand(historyCmd)
+}
+
+func formatHistoryEntry(entry *wshrpc.CmdHistoryEntry) string {
+	startTs := time.UnixMilli(entry.StartTs).Format("2006-01-02 15:04:05")
+	exitStr := "-"
+	if entry.ExitCode != nil {
+		exitStr = fmt.Sprintf("%d", *entry.ExitCode)
+	}
+	cmdText := strings.ReplaceAll(entry.CmdText, "\n", "\\n")
+	return fmt.Sprintf("%s  %s  %3s  %s  %s", entry.HistoryId[:8], startTs, exitStr, entry.Cwd, cmdText)
+}
+
+func historyRun(cmd *cobra.Command, args []string) error {
+	since, err := parseAgeArg(historySince)
+	if err != nil {
+		return fmt.Errorf("invalid --since: %w", err)
+	}
+	data := wshrpc.CommandHistoryListData{
+		Conn:       historyConn,
+		Search:     historySearch,
+		FailedOnly: historyFailed,
+		Since:      since,
+		Limit:      historyLimit,
+	}
+	if blockArg != "" {
+		fullORef, err := resolveBlockArg()
+		if err != nil {
+			return err
+		}
+		if fullORef.OType != waveobj.OType_Block {
+			return fmt.Errorf("history requires a block")
+		}
+		data.BlockId = fullORef.OID
+	}
+	entries, err := wshclient.HistoryListCommand(RpcClient, data, &wshrpc.RpcOpts{Timeout: 10000})
+	if err != nil {
+		return fmt.Errorf("listing history: %w", err)
+	}
+	if historyJson {
+		barr, err := json.MarshalIndent(entries, "", "  ")
+		if err != nil {
+			return fmt.Errorf("formatting history: %w", err)
+		}
+		WriteStdout("%s\n", string(barr))
+		return nil
+	}
+	for _, entry := range entries {
+		WriteStdout("%s\n", formatHistoryEntry(entry))
+	}
+	return nil
+}
+
+func historyOutputRun(cmd *cobra.Command, args []string) error {
+	historyId := args[0]
+	if len(historyId) < 36 {
+		// allow the short ids printed by "wsh history"
+		entries, err := wshclient.HistoryListCommand(RpcClient, wshrpc.CommandHistoryListData{Limit: 10000}, &wshrpc.RpcOpts{Timeout: 10000})
+		if err != nil {
+			return fmt.Errorf("listing history: %w", err)
+		}
+		var found []string
+		for _, entry := range entries {
+			if strings.HasPrefix(entry.HistoryId, historyId) {
+				found = append(found, entry.HistoryId)
+			}
+		}
+		if len(found) != 1 {
+			return fmt.Errorf("history id %q matches %d commands", historyId, len(found))
+		}
+		historyId = found[0]
+	}
+	data := wshrpc.CommandHistoryGetOutputData{HistoryId: historyId, Format: historyOutputFormat}
+	rtn, err := wshclient.HistoryGetOutputCommand(RpcClient, data, &wshrpc.RpcOpts{Timeout: 10000})
+	if err != nil {
+		return fmt.Errorf("getting command output: %w", err)
+	}
+	output, err := base64.StdEncoding.DecodeString(rtn.Data64)
+	if err != nil {
+		return fmt.Errorf("decoding command output: %w", err)
+	}
+	if rtn.Truncated {
+		WriteStderr("[output truncated, the start of the output has scrolled out]\n")
+	}
+	WrappedStdout.Write(output)
+	return nil
+}
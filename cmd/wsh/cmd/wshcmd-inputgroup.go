@@ -0,0 +1,182 @@
+// Copyright 2025, Command Line Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package cmd
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+	"github.com/wavetermdev/waveterm/pkg/waveobj"
+	"github.com/wavetermdev/waveterm/pkg/wshrpc"
+	"github.com/wavetermdev/waveterm/pkg/wshrpc/wshclient"
+)
+
+var inputGroupCmd = &cobra.Command{
+	Use:   "inputgroup",
+	Short: "manage input broadcast groups",
+	Long: `Manage input groups.  Input typed into a block in a group is also sent to the other blocks in the group,
+so the same commands can be run in many terminals at once.`,
+}
+
+var inputGroupListCmd = &cobra.Command{
+	Use:     "list [--json]",
+	Short:   "list input groups and their blocks",
+	Args:    cobra.NoArgs,
+	RunE:    activityWrap("inputgroup", inputGroupListRun),
+	PreRunE: preRunSetupRpcClient,
+}
+
+var inputGroupJoinCmd = &cobra.Command{
+	Use:     "join [-b blockid] [--confirm] group",
+	Short:   "add a block to an input group",
+	Args:    cobra.ExactArgs(1),
+	RunE:    activityWrap("inputgroup", inputGroupJoinRun),
+	PreRunE: preRunSetupRpcClient,
+}
+
+var inputGroupLeaveCmd = &cobra.Command{
+	Use:     "leave [-b blockid]",
+	Short:   "remove a block from its input group",
+	Args:    cobra.NoArgs,
+	RunE:    activityWrap("inputgroup", inputGroupLeaveRun),
+	PreRunE: preRunSetupRpcClient,
+}
+
+var inputGroupDisbandCmd = &cobra.Command{
+	Use:     "disband group",
+	Short:   "remove all blocks from an input group",
+	Args:    cobra.ExactArgs(1),
+	RunE:    activityWrap("inputgroup", inputGroupDisbandRun),
+	PreRunE: preRunSetupRpcClient,
+}
+
+var inputGroupSendCmd = &cobra.Command{
+	Use:     "send [--noenter] group text...",
+	Short:   "send text (followed by enter) to all blocks in an input group",
+	Args:    cobra.MinimumNArgs(2),
+	RunE:    activityWrap("inputgroup", inputGroupSendRun),
+	PreRunE: preRunSetupRpcClient,
+}
+
+var inputGroupJson bool
+var inputGroupConfirm bool
+var inputGroupNoEnter bool
+
+func init() {
+	inputGroupListCmd.Flags().BoolVar(&inputGroupJson, "json", false, "output groups as json")
+	inputGroupJoinCmd.Flags().BoolVar(&inputGroupConfirm, "confirm", false, "confirm before destructive keys typed in this block (ctrl-c, rm -rf, ...) are sent to the group")
+	inputGroupSendCmd.Flags().BoolVar(&inputGroupNoEnter, "noenter", false, "don't press enter after the text")
+	inputGroupCmd.AddCommand(inputGroupListCmd)
+	inputGroupCmd.AddCommand(inputGroupJoinCmd)
+	inputGroupCmd.AddCommand(inputGroupLeaveCmd)
+	inputGroupCmd.AddCommand(inputGroupDisbandCmd)
+	inputGroupCmd.AddCommand(inputGroupSendCmd)
+	rootCmd.AddCommand(inputGroupCmd)
+}
+
+func resolveInputGroupBlock() (string, error) {
+	fullORef, err := resolveBlockArg()
+	if err != nil {
+		return "", err
+	}
+	if fullORef.OType != waveobj.OType_Block {
+		return "", fmt.Errorf("input groups require a block")
+	}
+	return fullORef.OID, nil
+}
+
+func inputGroupListRun(cmd *cobra.Command, args []string) error {
+	groups, err := wshclient.InputGroupListCommand(RpcClient, &wshrpc.RpcOpts{Timeout: 5000})
+	if err != nil {
+		return fmt.Errorf("listing input groups: %w", err)
+	}
+	if inputGroupJson {
+		barr, err := json.MarshalIndent(groups, "", "  ")
+		if err != nil {
+			return fmt.Errorf("formatting input groups: %w", err)
+		}
+		WriteStdout("%s\n", string(barr))
+		return nil
+	}
+	writer := tabwriter.NewWriter(os.Stdout, 0, 8, 2, ' ', 0)
+	fmt.Fprintf(writer, "GROUP\tBLOCK\tSTATUS\tCONFIRM\n")
+	for _, group := range groups {
+		for _, member := range group.Members {
+			status := member.Status
+			if status == "" {
+				status = "-"
+			}
+			fmt.Fprintf(writer, "%s\t%s\t%s\t%v\n", group.Name, member.BlockId, status, member.Confirm)
+		}
+	}
+	return writer.Flush()
+}
+
+func inputGroupJoinRun(cmd *cobra.Command, args []string) error {
+	blockId, err := resolveInputGroupBlock()
+	if err != nil {
+		return err
+	}
+	data := wshrpc.CommandInputGroupSetData{BlockIds: []string{blockId}, Group: args[0], Confirm: &inputGroupConfirm}
+	err = wshclient.InputGroupSetCommand(RpcClient, data, &wshrpc.RpcOpts{Timeout: 5000})
+	if err != nil {
+		return fmt.Errorf("joining input group: %w", err)
+	}
+	WriteStdout("block %s joined input group %q\n", blockId, args[0])
+	return nil
+}
+
+func inputGroupLeaveRun(cmd *cobra.Command, args []string) error {
+	blockId, err := resolveInputGroupBlock()
+	if err != nil {
+		return err
+	}
+	data := wshrpc.CommandInputGroupSetData{BlockIds: []string{blockId}}
+	err = wshclient.InputGroupSetCommand(RpcClient, data, &wshrpc.RpcOpts{Timeout: 5000})
+	if err != nil {
+		return fmt.Errorf("leaving input group: %w", err)
+	}
+	return nil
+}
+
+func inputGroupDisbandRun(cmd *cobra.Command, args []string) error {
+	groups, err := wshclient.InputGroupListCommand(RpcClient, &wshrpc.RpcOpts{Timeout: 5000})
+	if err != nil {
+		return fmt.Errorf("listing input groups: %w", err)
+	}
+	for _, group := range groups {
+		if group.Name != args[0] {
+			continue
+		}
+		var data wshrpc.CommandInputGroupSetData
+		for _, member := range group.Members {
+			data.BlockIds = append(data.BlockIds, member.BlockId)
+		}
+		err = wshclient.InputGroupSetCommand(RpcClient, data, &wshrpc.RpcOpts{Timeout: 5000})
+		if err != nil {
+			return fmt.Errorf("disbanding input group: %w", err)
+		}
+		WriteStdout("removed %d blocks from input group %q\n", len(data.BlockIds), args[0])
+		return nil
+	}
+	return fmt.Errorf("input group %q not found", args[0])
+}
+
+func inputGroupSendRun(cmd *cobra.Command, args []string) error {
+	text := strings.Join(args[1:], " ")
+	if !inputGroupNoEnter {
+		text += "\r"
+	}
+	data := wshrpc.CommandInputGroupSendData{Group: args[0], InputData64: base64.StdEncoding.EncodeToString([]byte(text))}
+	err := wshclient.InputGroupSendCommand(RpcClient, data, &wshrpc.RpcOpts{Timeout: 5000})
+	if err != nil {
+		return fmt.Errorf("sending to input group: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,104 @@
+// Copyright 2025, Command Line Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strconv"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+	"github.com/wavetermdev/waveterm/pkg/waveobj"
+	"github.com/wavetermdev/waveterm/pkg/wshrpc"
+	"github.com/wavetermdev/waveterm/pkg/wshrpc/wshclient"
+)
+
+var portsCmd = &cobra.Command{
+	Use:   "ports [-b blockid] [--json]",
+	Short: "list the tcp ports blocks are listening on",
+	Long: `List the tcp ports the processes running in blocks are listening on.  Without -b the ports of all blocks
+are listed (as of their last check, every couple of seconds); with -b the block is checked now.`,
+	Args:    cobra.NoArgs,
+	RunE:    activityWrap("ports", portsRun),
+	PreRunE: preRunSetupRpcClient,
+}
+
+var portsOpenCmd = &cobra.Command{
+	Use:   "open [-b blockid] port",
+	Short: "open a web block showing a port",
+	Long: `Open a web block showing a port the block is listening on.  Ports on ssh connections are forwarded
+through the connection.`,
+	Args:    cobra.ExactArgs(1),
+	RunE:    activityWrap("ports", portsOpenRun),
+	PreRunE: preRunSetupRpcClient,
+}
+
+var portsJson bool
+
+func init() {
+	portsCmd.Flags().BoolVar(&portsJson, "json", false, "output ports as json")
+	portsCmd.AddCommand(portsOpenCmd)
+	rootCmd.AddCommand(portsCmd)
+}
+
+func portsRun(cmd *cobra.Command, args []string) error {
+	var data wshrpc.CommandPortsListData
+	if blockArg != "" {
+		fullORef, err := resolveBlockArg()
+		if err != nil {
+			return err
+		}
+		if fullORef.OType != waveobj.OType_Block {
+			return fmt.Errorf("ports require a block")
+		}
+		data.BlockId = fullORef.OID
+	}
+	blocks, err := wshclient.PortsListCommand(RpcClient, data, &wshrpc.RpcOpts{Timeout: 10000})
+	if err != nil {
+		return fmt.Errorf("listing ports: %w", err)
+	}
+	if portsJson {
+		barr, err := json.MarshalIndent(blocks, "", "  ")
+		if err != nil {
+			return fmt.Errorf("formatting ports: %w", err)
+		}
+		WriteStdout("%s\n", string(barr))
+		return nil
+	}
+	writer := tabwriter.NewWriter(os.Stdout, 0, 8, 2, ' ', 0)
+	fmt.Fprintf(writer, "BLOCK\tCONN\tPORT\tADDRESS\tPID\tPROCESS\n")
+	for _, block := range blocks {
+		conn := block.Connection
+		if conn == "" {
+			conn = "local"
+		}
+		for _, port := range block.Ports {
+			fmt.Fprintf(writer, "%s\t%s\t%d\t%s\t%d\t%s\n", block.BlockId, conn, port.Port, port.Address, port.Pid, port.Name)
+		}
+	}
+	return writer.Flush()
+}
+
+func portsOpenRun(cmd *cobra.Command, args []string) error {
+	port, err := strconv.Atoi(args[0])
+	if err != nil {
+		return fmt.Errorf("invalid port %q", args[0])
+	}
+	fullORef, err := resolveBlockArg()
+	if err != nil {
+		return err
+	}
+	if fullORef.OType != waveobj.OType_Block {
+		return fmt.Errorf("ports require a block")
+	}
+	data := wshrpc.CommandPortOpenData{BlockId: fullORef.OID, Port: port}
+	rtn, err := wshclient.PortOpenCommand(RpcClient, data, &wshrpc.RpcOpts{Timeout: 10000})
+	if err != nil {
+		return fmt.Errorf("opening port: %w", err)
+	}
+	WriteStdout("opened %s in block %s\n", rtn.Url, rtn.BlockId)
+	return nil
+}
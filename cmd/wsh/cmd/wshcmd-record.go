@@ -0,0 +1,303 @@
+// Copyright 2025, Command Line Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package cmd
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/wavetermdev/waveterm/pkg/remote/connparse"
+	"github.com/wavetermdev/waveterm/pkg/remote/fileshare/fsutil"
+	"github.com/wavetermdev/waveterm/pkg/util/castutil"
+	"github.com/wavetermdev/waveterm/pkg/util/wavefileutil"
+	"github.com/wavetermdev/waveterm/pkg/wavebase"
+	"github.com/wavetermdev/waveterm/pkg/waveobj"
+	"github.com/wavetermdev/waveterm/pkg/wshrpc"
+	"github.com/wavetermdev/waveterm/pkg/wshrpc/wshclient"
+)
+
+const castReadTimeout = 60000
+
+var recordCmd = &cobra.Command{
+	Use:   "record",
+	Short: "record terminal blocks as asciinema (asciicast v2) files",
+	Long: `Record the output (and optionally the input) of a terminal block in asciicast v2 format.
+
+Recordings are stored in the block's "cast" file unless a local path is given.  Recording sets the
+term:record block metadata, so it continues when the block's shell is restarted (each restart starts a new recording).`,
+}
+
+var recordStartCmd = &cobra.Command{
+	Use:     "start [-b blockid] [-o path] [--input]",
+	Short:   "start recording a block",
+	Args:    cobra.NoArgs,
+	RunE:    activityWrap("record", recordStartRun),
+	PreRunE: preRunSetupRpcClient,
+}
+
+var recordStopCmd = &cobra.Command{
+	Use:     "stop [-b blockid]",
+	Short:   "stop recording a block",
+	Args:    cobra.NoArgs,
+	RunE:    activityWrap("record", recordStopRun),
+	PreRunE: preRunSetupRpcClient,
+}
+
+var recordExportCmd = &cobra.Command{
+	Use:     "export [-b blockid] [-o file]",
+	Short:   "export a block's recording as a .cast file",
+	Example: "  wsh record export -o session.cast\n  wsh record export -b 2 > session.cast",
+	Args:    cobra.NoArgs,
+	RunE:    activityWrap("record", recordExportRun),
+	PreRunE: preRunSetupRpcClient,
+}
+
+var playCmd = &cobra.Command{
+	Use:   "play [file | wavefile://blockid/cast] [--speed N] [--idle-limit secs]",
+	Short: "replay an asciicast v2 recording",
+	Long: `Replay an asciicast v2 recording in a new block (or in the current terminal with --here).
+
+With no file, plays the recording of the block given by -b.`,
+	Example: "  wsh play session.cast --speed 2\n  wsh play -b 2 --idle-limit 1",
+	Args:    cobra.MaximumNArgs(1),
+	RunE:    activityWrap("play", playRun),
+	PreRunE: preRunSetupRpcClient,
+}
+
+var recordOutPath string
+var recordInput bool
+var playSpeed float64
+var playIdleLimit float64
+var playHere bool
+
+func init() {
+	recordStartCmd.Flags().StringVarP(&recordOutPath, "output", "o", "", "record to this local file instead of the block's cast file")
+	recordStartCmd.Flags().BoolVar(&recordInput, "input", false, "also record input (keystrokes, including passwords typed at prompts)")
+	recordExportCmd.Flags().StringVarP(&recordOutPath, "output", "o", "", "write the recording to a file instead of stdout")
+	recordCmd.AddCommand(recordStartCmd)
+	recordCmd.AddCommand(recordStopCmd)
+	recordCmd.AddCommand(recordExportCmd)
+	rootCmd.AddCommand(recordCmd)
+	playCmd.Flags().Float64VarP(&playSpeed, "speed", "s", 1, "playback speed multiplier")
+	playCmd.Flags().Float64VarP(&playIdleLimit, "idle-limit", "i", 0, "limit idle time between events to this many seconds (0 for no limit)")
+	playCmd.Flags().BoolVar(&playHere, "here", false, "play in the current terminal instead of a new block")
+	rootCmd.AddCommand(playCmd)
+}
+
+func resolveRecordBlockId() (string, error) {
+	fullORef, err := resolveBlockArg()
+	if err != nil {
+		return "", err
+	}
+	if fullORef.OType != waveobj.OType_Block {
+		return "", fmt.Errorf("recording requires a block")
+	}
+	return fullORef.OID, nil
+}
+
+func recordStartRun(cmd *cobra.Command, args []string) error {
+	blockId, err := resolveRecordBlockId()
+	if err != nil {
+		return err
+	}
+	data := wshrpc.CommandRecordStartData{BlockId: blockId, RecordInput: recordInput}
+	if recordOutPath != "" {
+		if RpcContext.Conn != "" {
+			return fmt.Errorf("-o is only supported for local blocks (recordings are written by the Wave app), use \"wsh record export\" instead")
+		}
+		data.Path, err = filepath.Abs(recordOutPath)
+		if err != nil {
+			return fmt.Errorf("getting absolute path: %w", err)
+		}
+	}
+	rtn, err := wshclient.RecordStartCommand(RpcClient, data, nil)
+	if err != nil {
+		return fmt.Errorf("starting recording: %w", err)
+	}
+	if rtn.Active {
+		WriteStdout("recording to %s\n", rtn.Location)
+	} else {
+		WriteStdout("block is not running, recording to %s will start with the next shell\n", rtn.Location)
+	}
+	return nil
+}
+
+func recordStopRun(cmd *cobra.Command, args []string) error {
+	blockId, err := resolveRecordBlockId()
+	if err != nil {
+		return err
+	}
+	rtn, err := wshclient.RecordStopCommand(RpcClient, wshrpc.CommandRecordStopData{BlockId: blockId}, nil)
+	if err != nil {
+		return fmt.Errorf("stopping recording: %w", err)
+	}
+	WriteStdout("recording saved to %s (%d bytes)\n", rtn.Location, rtn.Size)
+	return nil
+}
+
+func readWaveFileCast(ctx context.Context, uri string, writer io.Writer) error {
+	fileData := wshrpc.FileData{Info: &wshrpc.FileInfo{Path: uri}}
+	ch := wshclient.FileReadStreamCommand(RpcClient, fileData, &wshrpc.RpcOpts{Timeout: castReadTimeout})
+	return convertNotFoundErr(fsutil.ReadFileStreamToWriter(ctx, ch, writer))
+}
+
+func recordExportRun(cmd *cobra.Command, args []string) error {
+	blockId, err := resolveRecordBlockId()
+	if err != nil {
+		return err
+	}
+	var writer io.Writer = WrappedStdout
+	if recordOutPath != "" {
+		fd, err := os.Create(recordOutPath)
+		if err != nil {
+			return fmt.Errorf("creating output file: %w", err)
+		}
+		defer fd.Close()
+		writer = fd
+	}
+	uri := fmt.Sprintf(wavefileutil.WaveFilePathPattern, blockId, wavebase.BlockFile_Cast)
+	meta, err := wshclient.GetMetaCommand(RpcClient, wshrpc.CommandGetMetaData{ORef: waveobj.MakeORef(waveobj.OType_Block, blockId)}, nil)
+	if err != nil {
+		return fmt.Errorf("getting block metadata: %w", err)
+	}
+	if recordPath := meta.GetString(waveobj.MetaKey_TermRecordPath, ""); recordPath != "" {
+		// recorded with -o, the file is on the machine running wave
+		uri = (&connparse.Connection{Scheme: connparse.ConnectionTypeWsh, Host: wshrpc.LocalConnName, Path: recordPath}).GetFullURI()
+	}
+	err = readWaveFileCast(cmd.Context(), uri, writer)
+	if err != nil {
+		return fmt.Errorf("reading recording: %w", err)
+	}
+	return nil
+}
+
+func playRun(cmd *cobra.Command, args []string) error {
+	if playSpeed <= 0 {
+		return fmt.Errorf("--speed must be greater than 0")
+	}
+	var castPath string
+	if len(args) > 0 {
+		castPath = args[0]
+	} else {
+		blockId, err := resolveRecordBlockId()
+		if err != nil {
+			return err
+		}
+		castPath = fmt.Sprintf(wavefileutil.WaveFilePathPattern, blockId, wavebase.BlockFile_Cast)
+	}
+	if !strings.Contains(castPath, "://") {
+		var err error
+		castPath, err = filepath.Abs(castPath)
+		if err != nil {
+			return fmt.Errorf("getting absolute path: %w", err)
+		}
+		if _, err := os.Stat(castPath); err != nil {
+			return err
+		}
+	}
+	if playHere {
+		return playCast(cmd.Context(), castPath)
+	}
+	return playInNewBlock(castPath)
+}
+
+// runs "wsh play --here" in a new cmd block (on the same connection, so local paths resolve)
+func playInNewBlock(castPath string) error {
+	wshPath, err := os.Executable()
+	if err != nil {
+		return fmt.Errorf("finding wsh binary: %w", err)
+	}
+	playArgs := []string{"play", "--here", "--speed", fmt.Sprintf("%g", playSpeed)}
+	if playIdleLimit > 0 {
+		playArgs = append(playArgs, "--idle-limit", fmt.Sprintf("%g", playIdleLimit))
+	}
+	playArgs = append(playArgs, castPath)
+	createMeta := map[string]any{
+		waveobj.MetaKey_View:            "term",
+		waveobj.MetaKey_Controller:      "cmd",
+		waveobj.MetaKey_Cmd:             wshPath,
+		waveobj.MetaKey_CmdArgs:         playArgs,
+		waveobj.MetaKey_CmdShell:        false,
+		waveobj.MetaKey_CmdRunOnce:      true,
+		waveobj.MetaKey_CmdRunOnStart:   true,
+		waveobj.MetaKey_CmdClearOnStart: true,
+	}
+	if RpcContext.Conn != "" {
+		createMeta[waveobj.MetaKey_Connection] = RpcContext.Conn
+	}
+	createBlockData := wshrpc.CommandCreateBlockData{
+		BlockDef: &waveobj.BlockDef{Meta: createMeta},
+	}
+	oref, err := wshclient.CreateBlockCommand(RpcClient, createBlockData, nil)
+	if err != nil {
+		return fmt.Errorf("creating play block: %w", err)
+	}
+	WriteStdout("play block created: %s\n", oref)
+	return nil
+}
+
+func playCast(ctx context.Context, castPath string) error {
+	var reader io.Reader
+	if strings.Contains(castPath, "://") {
+		pipeReader, pipeWriter := io.Pipe()
+		go func() {
+			pipeWriter.CloseWithError(readWaveFileCast(ctx, castPath, pipeWriter))
+		}()
+		defer pipeReader.Close()
+		reader = pipeReader
+	} else {
+		fd, err := os.Open(castPath)
+		if err != nil {
+			return err
+		}
+		defer fd.Close()
+		reader = fd
+	}
+	scanner := bufio.NewScanner(reader)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	if !scanner.Scan() {
+		if scanner.Err() != nil {
+			return fmt.Errorf("reading recording: %w", scanner.Err())
+		}
+		return fmt.Errorf("recording is empty")
+	}
+	if _, err := castutil.ParseHeader(scanner.Bytes()); err != nil {
+		return err
+	}
+	interruptCh := makeInterruptCh()
+	var lastTs float64
+	for scanner.Scan() {
+		var event castutil.Event
+		if err := json.Unmarshal(scanner.Bytes(), &event); err != nil || event.Type != castutil.EventType_Output {
+			continue
+		}
+		ts, data := event.Time, event.Data
+		delay := ts - lastTs
+		lastTs = ts
+		if playIdleLimit > 0 {
+			delay = min(delay, playIdleLimit)
+		}
+		if delay > 0 {
+			select {
+			case <-time.After(time.Duration(delay / playSpeed * float64(time.Second))):
+			case <-interruptCh:
+				WrappedStdout.Write([]byte("\x1b[0m\r\n"))
+				return nil
+			}
+		}
+		WrappedStdout.Write([]byte(data))
+	}
+	if scanner.Err() != nil {
+		return fmt.Errorf("reading recording: %w", scanner.Err())
+	}
+	return nil
+}
@@ -0,0 +1,51 @@
+// Copyright 2025, Command Line Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+	"github.com/wavetermdev/waveterm/pkg/sandbox"
+)
+
+var sandboxExecCmd = &cobra.Command{
+	Use:                   "sandboxexec [--exec] --opts json -- cmd [args...]",
+	Hidden:                true,
+	Short:                 "run a command in a sandbox (started by wavesrv)",
+	Args:                  cobra.MinimumNArgs(1),
+	DisableFlagsInUseLine: true,
+	SilenceUsage:          true,
+	RunE:                  sandboxExecRun,
+}
+
+var sandboxExecOpts string
+var sandboxExecStage2 bool
+
+func init() {
+	sandboxExecCmd.Flags().StringVar(&sandboxExecOpts, "opts", "", "sandbox options (json)")
+	sandboxExecCmd.Flags().BoolVar(&sandboxExecStage2, "exec", false, "restrict this process and exec the command (stage 2)")
+	rootCmd.AddCommand(sandboxExecCmd)
+}
+
+func sandboxExecRun(cmd *cobra.Command, args []string) error {
+	var opts sandbox.Opts
+	err := json.Unmarshal([]byte(sandboxExecOpts), &opts)
+	if err != nil {
+		return fmt.Errorf("sandbox: invalid --opts: %w", err)
+	}
+	if sandboxExecStage2 {
+		// only returns on error
+		err := sandbox.Exec(opts, args)
+		return fmt.Errorf("sandbox: cannot run %s: %w", args[0], err)
+	}
+	exitCode, err := sandbox.Run(opts, args)
+	if err != nil {
+		return fmt.Errorf("sandbox: %w", err)
+	}
+	os.Exit(exitCode)
+	return nil
+}
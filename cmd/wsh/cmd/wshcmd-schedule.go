@@ -0,0 +1,207 @@
+// Copyright 2025, Command Line Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/wavetermdev/waveterm/pkg/util/cronutil"
+	"github.com/wavetermdev/waveterm/pkg/waveobj"
+	"github.com/wavetermdev/waveterm/pkg/wshrpc"
+	"github.com/wavetermdev/waveterm/pkg/wshrpc/wshclient"
+)
+
+var scheduleCmd = &cobra.Command{
+	Use:   "schedule",
+	Short: "run cmd blocks on a schedule",
+	Long: `Rerun the command of a cmd block on a schedule.  The schedule is stored in the block's cmd:schedule
+metadata and is either a cron expression ("*/5 * * * *", "0 3 * * mon-fri", "@daily") or an interval
+("30s", "@every 10m").  A run is skipped if the previous run is still running.`,
+}
+
+var scheduleSetCmd = &cobra.Command{
+	Use:     "set [-b blockid] SCHEDULE",
+	Short:   "set the schedule of a cmd block",
+	Example: "  wsh schedule set 30s\n  wsh schedule set -b 2 \"0 3 * * *\"",
+	Args:    cobra.ExactArgs(1),
+	RunE:    activityWrap("schedule", scheduleSetRun),
+	PreRunE: preRunSetupRpcClient,
+}
+
+var scheduleClearCmd = &cobra.Command{
+	Use:     "clear [-b blockid]",
+	Short:   "remove the schedule of a block",
+	Args:    cobra.NoArgs,
+	RunE:    activityWrap("schedule", scheduleClearRun),
+	PreRunE: preRunSetupRpcClient,
+}
+
+var schedulePauseCmd = &cobra.Command{
+	Use:     "pause [-b blockid]",
+	Short:   "pause the schedule of a block",
+	Args:    cobra.NoArgs,
+	RunE:    activityWrap("schedule", schedulePauseRun),
+	PreRunE: preRunSetupRpcClient,
+}
+
+var scheduleResumeCmd = &cobra.Command{
+	Use:     "resume [-b blockid]",
+	Short:   "resume a paused schedule",
+	Args:    cobra.NoArgs,
+	RunE:    activityWrap("schedule", scheduleResumeRun),
+	PreRunE: preRunSetupRpcClient,
+}
+
+var scheduleListCmd = &cobra.Command{
+	Use:     "list [-b blockid] [--json]",
+	Short:   "list scheduled blocks and their recent runs",
+	Long:    "List scheduled blocks with their next run and the results of their recent runs.  Lists all scheduled blocks unless -b is given.",
+	Args:    cobra.NoArgs,
+	RunE:    activityWrap("schedule", scheduleListRun),
+	PreRunE: preRunSetupRpcClient,
+}
+
+var scheduleListJson bool
+var scheduleListRuns bool
+
+func init() {
+	scheduleListCmd.Flags().BoolVar(&scheduleListJson, "json", false, "output schedules as json")
+	scheduleListCmd.Flags().BoolVarP(&scheduleListRuns, "runs", "r", false, "show every recorded run")
+	scheduleCmd.AddCommand(scheduleSetCmd)
+	scheduleCmd.AddCommand(scheduleClearCmd)
+	scheduleCmd.AddCommand(schedulePauseCmd)
+	scheduleCmd.AddCommand(scheduleResumeCmd)
+	scheduleCmd.AddCommand(scheduleListCmd)
+	rootCmd.AddCommand(scheduleCmd)
+}
+
+func resolveScheduleBlockId() (string, error) {
+	fullORef, err := resolveBlockArg()
+	if err != nil {
+		return "", err
+	}
+	if fullORef.OType != waveobj.OType_Block {
+		return "", fmt.Errorf("schedules require a block")
+	}
+	return fullORef.OID, nil
+}
+
+func setScheduleMeta(meta waveobj.MetaMapType) (string, error) {
+	blockId, err := resolveScheduleBlockId()
+	if err != nil {
+		return "", err
+	}
+	data := wshrpc.CommandSetMetaData{
+		ORef: waveobj.MakeORef(waveobj.OType_Block, blockId),
+		Meta: meta,
+	}
+	err = wshclient.SetMetaCommand(RpcClient, data, nil)
+	if err != nil {
+		return "", fmt.Errorf("setting block metadata: %w", err)
+	}
+	return blockId, nil
+}
+
+func scheduleSetRun(cmd *cobra.Command, args []string) error {
+	sched, err := cronutil.Parse(args[0])
+	if err != nil {
+		return err
+	}
+	blockId, err := setScheduleMeta(waveobj.MetaMapType{
+		waveobj.MetaKey_CmdSchedule:       args[0],
+		waveobj.MetaKey_CmdSchedulePaused: nil,
+	})
+	if err != nil {
+		return err
+	}
+	WriteStdout("block %s scheduled, next run at %s\n", blockId, sched.Next(time.Now()).Format("2006-01-02 15:04:05"))
+	return nil
+}
+
+func scheduleClearRun(cmd *cobra.Command, args []string) error {
+	_, err := setScheduleMeta(waveobj.MetaMapType{
+		waveobj.MetaKey_CmdSchedule:       nil,
+		waveobj.MetaKey_CmdSchedulePaused: nil,
+	})
+	return err
+}
+
+func schedulePauseRun(cmd *cobra.Command, args []string) error {
+	_, err := setScheduleMeta(waveobj.MetaMapType{waveobj.MetaKey_CmdSchedulePaused: true})
+	return err
+}
+
+func scheduleResumeRun(cmd *cobra.Command, args []string) error {
+	_, err := setScheduleMeta(waveobj.MetaMapType{waveobj.MetaKey_CmdSchedulePaused: nil})
+	return err
+}
+
+func formatScheduleTs(ts int64) string {
+	if ts == 0 {
+		return "-"
+	}
+	return time.UnixMilli(ts).Format("2006-01-02 15:04:05")
+}
+
+func formatScheduleRun(run *wshrpc.ScheduleRun) string {
+	if run.Skipped {
+		return fmt.Sprintf("%s  skipped (still running)", formatScheduleTs(run.StartTs))
+	}
+	if run.ExitCode == nil {
+		if run.EndTs != 0 {
+			return fmt.Sprintf("%s  failed to start", formatScheduleTs(run.StartTs))
+		}
+		return fmt.Sprintf("%s  running", formatScheduleTs(run.StartTs))
+	}
+	duration := time.Duration(run.EndTs-run.StartTs) * time.Millisecond
+	return fmt.Sprintf("%s  exit %d  (%v)", formatScheduleTs(run.StartTs), *run.ExitCode, duration.Round(time.Second))
+}
+
+func scheduleListRun(cmd *cobra.Command, args []string) error {
+	var data wshrpc.CommandScheduleListData
+	if blockArg != "" {
+		blockId, err := resolveScheduleBlockId()
+		if err != nil {
+			return err
+		}
+		data.BlockId = blockId
+	}
+	schedules, err := wshclient.ScheduleListCommand(RpcClient, data, nil)
+	if err != nil {
+		return fmt.Errorf("listing schedules: %w", err)
+	}
+	if scheduleListJson {
+		barr, err := json.MarshalIndent(schedules, "", "  ")
+		if err != nil {
+			return fmt.Errorf("formatting schedules: %w", err)
+		}
+		WriteStdout("%s\n", string(barr))
+		return nil
+	}
+	if len(schedules) == 0 {
+		WriteStderr("no scheduled blocks\n")
+		return nil
+	}
+	for _, info := range schedules {
+		state := "next " + formatScheduleTs(info.NextRunTs)
+		if info.Paused {
+			state = "paused"
+		}
+		if info.Error != "" {
+			state = "error: " + info.Error
+		}
+		WriteStdout("%s  %-16q  %s\n", info.BlockId, info.Schedule, state)
+		runs := info.Runs
+		if !scheduleListRuns && len(runs) > 1 {
+			runs = runs[len(runs)-1:]
+		}
+		for _, run := range runs {
+			WriteStdout("    %s\n", formatScheduleRun(run))
+		}
+	}
+	return nil
+}
@@ -0,0 +1,170 @@
+// Copyright 2025, Command Line Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package cmd
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/spf13/cobra"
+	"github.com/wavetermdev/waveterm/pkg/waveobj"
+	"github.com/wavetermdev/waveterm/pkg/wshrpc"
+	"github.com/wavetermdev/waveterm/pkg/wshrpc/wshclient"
+)
+
+var scrollbackCmd = &cobra.Command{
+	Use:   "scrollback",
+	Short: "export or search terminal output",
+	Long:  "Export or search the stored output of terminal blocks. Works on the output saved by Wave, so blocks do not need to be visible.",
+}
+
+var scrollbackDumpCmd = &cobra.Command{
+	Use:     "dump [-b blockid] [--format raw|text|html]",
+	Short:   "export a block's terminal output",
+	Example: "  wsh scrollback dump --format text > out.txt\n  wsh scrollback dump -b 2 --format html -o out.html",
+	Args:    cobra.NoArgs,
+	RunE:    activityWrap("scrollback", scrollbackDumpRun),
+	PreRunE: preRunSetupRpcClient,
+}
+
+var scrollbackGrepCmd = &cobra.Command{
+	Use:   "grep [-b blockid | --tab | --all] PATTERN",
+	Short: "search terminal output with a regular expression",
+	Long: `Search the stored terminal output of one block (default), every block in the current tab including sub-blocks (--tab), or every block (--all).
+
+PATTERN uses Go regular expression syntax. Escape sequences are removed before matching.
+Each match is printed as "blockid:line:offset: text", where offset is the byte offset of the match in the block's term file.`,
+	Example: "  wsh scrollback grep -i error\n  wsh scrollback grep --all -C 2 'panic: .*'",
+	Args:    cobra.ExactArgs(1),
+	RunE:    activityWrap("scrollback", scrollbackGrepRun),
+	PreRunE: preRunSetupRpcClient,
+}
+
+const scrollbackDumpTimeout = 60000
+
+var scrollbackFormat string
+var scrollbackOutFile string
+var scrollbackGrepTab bool
+var scrollbackGrepAll bool
+var scrollbackGrepIgnoreCase bool
+var scrollbackGrepContext int
+var scrollbackGrepMax int
+var scrollbackGrepJson bool
+
+func init() {
+	scrollbackDumpCmd.Flags().StringVarP(&scrollbackFormat, "format", "f", wshrpc.ScrollbackFormat_Raw, "output format (raw, text, or html)")
+	scrollbackDumpCmd.Flags().StringVarP(&scrollbackOutFile, "output", "o", "", "write output to a file instead of stdout")
+	scrollbackGrepCmd.Flags().BoolVar(&scrollbackGrepTab, "tab", false, "search all blocks in the current tab")
+	scrollbackGrepCmd.Flags().BoolVar(&scrollbackGrepAll, "all", false, "search all blocks")
+	scrollbackGrepCmd.Flags().BoolVarP(&scrollbackGrepIgnoreCase, "ignore-case", "i", false, "case insensitive matching")
+	scrollbackGrepCmd.Flags().IntVarP(&scrollbackGrepContext, "context", "C", 0, "print N lines of context around each match")
+	scrollbackGrepCmd.Flags().IntVarP(&scrollbackGrepMax, "max", "m", 0, "maximum number of matches to return")
+	scrollbackGrepCmd.Flags().BoolVar(&scrollbackGrepJson, "json", false, "output matches as json")
+	scrollbackCmd.AddCommand(scrollbackDumpCmd)
+	scrollbackCmd.AddCommand(scrollbackGrepCmd)
+	rootCmd.AddCommand(scrollbackCmd)
+}
+
+func scrollbackDumpRun(cmd *cobra.Command, args []string) error {
+	fullORef, err := resolveBlockArg()
+	if err != nil {
+		return err
+	}
+	if fullORef.OType != waveobj.OType_Block {
+		return fmt.Errorf("scrollback dump requires a block")
+	}
+	data := wshrpc.CommandScrollbackDumpData{
+		BlockId: fullORef.OID,
+		Format:  scrollbackFormat,
+	}
+	var writer io.Writer = WrappedStdout
+	if scrollbackOutFile != "" {
+		fd, err := os.OpenFile(scrollbackOutFile, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0644)
+		if err != nil {
+			return fmt.Errorf("writing output file: %w", err)
+		}
+		defer fd.Close()
+		writer = fd
+	}
+	// the output is streamed in parts, so large term files are never held in memory as a whole
+	for respUnion := range wshclient.ScrollbackDumpCommand(RpcClient, data, &wshrpc.RpcOpts{Timeout: scrollbackDumpTimeout}) {
+		if respUnion.Error != nil {
+			return fmt.Errorf("dumping scrollback: %w", respUnion.Error)
+		}
+		output, err := base64.StdEncoding.DecodeString(respUnion.Response.Data64)
+		if err != nil {
+			return fmt.Errorf("decoding scrollback data: %w", err)
+		}
+		_, err = writer.Write(output)
+		if err != nil {
+			return fmt.Errorf("writing output: %w", err)
+		}
+	}
+	return nil
+}
+
+func scrollbackGrepRun(cmd *cobra.Command, args []string) error {
+	if scrollbackGrepTab && scrollbackGrepAll {
+		return fmt.Errorf("--tab and --all cannot be used together")
+	}
+	data := wshrpc.CommandScrollbackGrepData{
+		Scope:        wshrpc.ScrollbackScope_Block,
+		Pattern:      args[0],
+		IgnoreCase:   scrollbackGrepIgnoreCase,
+		ContextLines: scrollbackGrepContext,
+		MaxMatches:   scrollbackGrepMax,
+	}
+	switch {
+	case scrollbackGrepAll:
+		data.Scope = wshrpc.ScrollbackScope_All
+	case scrollbackGrepTab:
+		data.Scope = wshrpc.ScrollbackScope_Tab
+		tabORef, err := resolveSimpleId("tab")
+		if err != nil {
+			return fmt.Errorf("resolving tab: %w", err)
+		}
+		data.TabId = tabORef.OID
+	default:
+		fullORef, err := resolveBlockArg()
+		if err != nil {
+			return err
+		}
+		if fullORef.OType != waveobj.OType_Block {
+			return fmt.Errorf("scrollback grep requires a block")
+		}
+		data.BlockId = fullORef.OID
+	}
+	matches, err := wshclient.ScrollbackGrepCommand(RpcClient, data, &wshrpc.RpcOpts{Timeout: 30000})
+	if err != nil {
+		return fmt.Errorf("searching scrollback: %w", err)
+	}
+	if scrollbackGrepJson {
+		barr, err := json.MarshalIndent(matches, "", "  ")
+		if err != nil {
+			return fmt.Errorf("formatting matches: %w", err)
+		}
+		WriteStdout("%s\n", string(barr))
+		return nil
+	}
+	for idx, match := range matches {
+		if scrollbackGrepContext > 0 && idx > 0 {
+			WriteStdout("--\n")
+		}
+		prefix := fmt.Sprintf("%s:%d", match.BlockId, match.LineNum)
+		for i, line := range match.Before {
+			WriteStdout("%s-%d- %s\n", match.BlockId, match.LineNum-len(match.Before)+i, line)
+		}
+		WriteStdout("%s:%d: %s\n", prefix, match.StartOffset, match.Line)
+		for i, line := range match.After {
+			WriteStdout("%s-%d- %s\n", match.BlockId, match.LineNum+i+1, line)
+		}
+	}
+	if len(matches) == 0 {
+		WshExitCode = 1
+	}
+	return nil
+}
@@ -0,0 +1,43 @@
+// Copyright 2025, Command Line Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+
+	"github.com/spf13/cobra"
+	"github.com/wavetermdev/waveterm/pkg/sessiond"
+)
+
+var sessiondCmd = &cobra.Command{
+	Use:                   "sessiond --socket path [--rows N] [--cols N] -- cmd [args...]",
+	Hidden:                true,
+	Short:                 "supervise a persistent shell session (started by wavesrv)",
+	Args:                  cobra.MinimumNArgs(1),
+	DisableFlagsInUseLine: true,
+	RunE:                  sessiondRun,
+}
+
+var sessiondSocket string
+var sessiondRows int
+var sessiondCols int
+
+func init() {
+	sessiondCmd.Flags().StringVar(&sessiondSocket, "socket", "", "unix socket to serve the session on")
+	sessiondCmd.Flags().IntVar(&sessiondRows, "rows", 25, "initial terminal rows")
+	sessiondCmd.Flags().IntVar(&sessiondCols, "cols", 80, "initial terminal cols")
+	rootCmd.AddCommand(sessiondCmd)
+}
+
+func sessiondRun(cmd *cobra.Command, args []string) error {
+	if sessiondSocket == "" {
+		return fmt.Errorf("--socket is required")
+	}
+	// the environment and cwd are set up by wavesrv when it starts us
+	shellCmd := exec.Command(args[0], args[1:]...)
+	shellCmd.Env = os.Environ()
+	return sessiond.RunServer(sessiondSocket, shellCmd, sessiondRows, sessiondCols)
+}
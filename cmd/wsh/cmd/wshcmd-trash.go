@@ -0,0 +1,130 @@
+// Copyright 2025, Command Line Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/wavetermdev/waveterm/pkg/waveobj"
+	"github.com/wavetermdev/waveterm/pkg/wshrpc"
+	"github.com/wavetermdev/waveterm/pkg/wshrpc/wshclient"
+)
+
+var trashCmd = &cobra.Command{
+	Use:   "trash",
+	Short: "list and restore closed tabs and blocks",
+	Long: `Closed tabs and blocks (with their terminal output and other files) are kept in the trash for a while
+(see trash:retentiondays).  Restoring puts them back into their original workspace and position.`,
+}
+
+var trashListCmd = &cobra.Command{
+	Use:     "list [--workspace id] [--tabs] [--blocks] [--json]",
+	Short:   "list the trash, most recently closed first",
+	Args:    cobra.NoArgs,
+	RunE:    activityWrap("trash", trashListRun),
+	PreRunE: preRunSetupRpcClient,
+}
+
+var trashRestoreCmd = &cobra.Command{
+	Use:     "restore [TRASHID] [--workspace id]",
+	Short:   "restore a closed tab or block (the most recently closed one if no id is given)",
+	Example: "  wsh trash restore\n  wsh trash restore 3f2a9c1e\n  wsh trash restore 3f2a9c1e --workspace 0d1e3bc2-...",
+	Args:    cobra.MaximumNArgs(1),
+	RunE:    activityWrap("trash", trashRestoreRun),
+	PreRunE: preRunSetupRpcClient,
+}
+
+var trashListWorkspace string
+var trashListTabs bool
+var trashListBlocks bool
+var trashListJson bool
+var trashRestoreWorkspace string
+
+func init() {
+	trashListCmd.Flags().StringVar(&trashListWorkspace, "workspace", "", "only list the tabs and blocks closed in this workspace")
+	trashListCmd.Flags().BoolVar(&trashListTabs, "tabs", false, "only list tabs")
+	trashListCmd.Flags().BoolVar(&trashListBlocks, "blocks", false, "only list blocks")
+	trashListCmd.Flags().BoolVar(&trashListJson, "json", false, "output the trash as json")
+	trashRestoreCmd.Flags().StringVar(&trashRestoreWorkspace, "workspace", "", "restore a tab into this workspace (instead of the one it was closed in)")
+	trashCmd.AddCommand(trashListCmd)
+	trashCmd.AddCommand(trashRestoreCmd)
+	rootCmd.AddCommand(trashCmd)
+}
+
+func shortTrashId(id string) string {
+	if len(id) > 8 {
+		return id[:8]
+	}
+	return id
+}
+
+func trashListRun(cmd *cobra.Command, args []string) error {
+	if trashListTabs && trashListBlocks {
+		return fmt.Errorf("--tabs and --blocks cannot be used together")
+	}
+	data := wshrpc.CommandTrashListData{WorkspaceId: trashListWorkspace}
+	if trashListTabs {
+		data.OType = waveobj.OType_Tab
+	} else if trashListBlocks {
+		data.OType = waveobj.OType_Block
+	}
+	entries, err := wshclient.TrashListCommand(RpcClient, data, &wshrpc.RpcOpts{Timeout: 5000})
+	if err != nil {
+		return fmt.Errorf("listing trash: %w", err)
+	}
+	if trashListJson {
+		barr, err := json.MarshalIndent(entries, "", "  ")
+		if err != nil {
+			return fmt.Errorf("formatting trash: %w", err)
+		}
+		WriteStdout("%s\n", string(barr))
+		return nil
+	}
+	if len(entries) == 0 {
+		WriteStdout("trash is empty\n")
+		return nil
+	}
+	w := tabwriter.NewWriter(os.Stdout, 0, 8, 2, ' ', 0)
+	fmt.Fprintf(w, "ID\tTYPE\tCLOSED\tWORKSPACE\tNAME\tBLOCKS\n")
+	for _, entry := range entries {
+		name := entry.Name
+		if entry.OType == waveobj.OType_Block {
+			name = entry.View
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%d\n", shortTrashId(entry.TrashId), entry.OType, time.UnixMilli(entry.DeletedTs).Format("2006-01-02 15:04:05"), shortTrashId(entry.WorkspaceId), name, entry.NumBlocks)
+	}
+	return w.Flush()
+}
+
+func trashRestoreRun(cmd *cobra.Command, args []string) error {
+	var trashId string
+	if len(args) > 0 {
+		trashId = args[0]
+	} else {
+		entries, err := wshclient.TrashListCommand(RpcClient, wshrpc.CommandTrashListData{}, &wshrpc.RpcOpts{Timeout: 5000})
+		if err != nil {
+			return fmt.Errorf("listing trash: %w", err)
+		}
+		if len(entries) == 0 {
+			return fmt.Errorf("trash is empty")
+		}
+		trashId = entries[0].TrashId
+	}
+	data := wshrpc.CommandTrashRestoreData{TrashId: trashId, WorkspaceId: trashRestoreWorkspace}
+	rtn, err := wshclient.TrashRestoreCommand(RpcClient, data, &wshrpc.RpcOpts{Timeout: 10000})
+	if err != nil {
+		return fmt.Errorf("restoring %s: %w", trashId, err)
+	}
+	if rtn.OType == waveobj.OType_Tab {
+		WriteStdout("restored tab %s (%d blocks) into workspace %s\n", rtn.OID, rtn.NumBlocks, rtn.WorkspaceId)
+	} else {
+		WriteStdout("restored block %s into tab %s\n", rtn.OID, rtn.TabId)
+	}
+	return nil
+}
@@ -4,6 +4,9 @@
 package cmd
 
 import (
+	"fmt"
+	"path/filepath"
+
 	"github.com/spf13/cobra"
 	"github.com/wavetermdev/waveterm/pkg/wshrpc"
 	"github.com/wavetermdev/waveterm/pkg/wshrpc/wshclient"
@@ -15,8 +18,17 @@ var workspaceCommand = &cobra.Command{
 	// Args:    cobra.MinimumNArgs(1),
 }
 
+const workspaceExportTimeout = 5 * 60 * 1000
+
+var workspaceExportWorkspace string
+var workspaceExportFiles bool
+
 func init() {
+	workspaceExportCommand.Flags().StringVar(&workspaceExportWorkspace, "workspace", "", "workspace to export (defaults to the current workspace)")
+	workspaceExportCommand.Flags().BoolVar(&workspaceExportFiles, "files", false, "include the files of the workspace, tabs and blocks (terminal output, etc.)")
 	workspaceCommand.AddCommand(workspaceListCommand)
+	workspaceCommand.AddCommand(workspaceExportCommand)
+	workspaceCommand.AddCommand(workspaceImportCommand)
 	rootCmd.AddCommand(workspaceCommand)
 }
 
@@ -49,3 +61,67 @@ func workspaceListRun(cmd *cobra.Command, args []string) {
 	}
 	WriteStdout("]\n")
 }
+
+var workspaceExportCommand = &cobra.Command{
+	Use:     "export [--workspace id] [--files] FILE",
+	Short:   "export a workspace (tabs, layouts and blocks) as json",
+	Example: "  wsh workspace export ~/debug-setup.json\n  wsh workspace export --files ~/debug-setup.json",
+	Args:    cobra.ExactArgs(1),
+	RunE:    activityWrap("workspace", workspaceExportRun),
+	PreRunE: preRunSetupRpcClient,
+}
+
+var workspaceImportCommand = &cobra.Command{
+	Use:     "import FILE",
+	Short:   "import an exported workspace as a new workspace",
+	Args:    cobra.ExactArgs(1),
+	RunE:    activityWrap("workspace", workspaceImportRun),
+	PreRunE: preRunSetupRpcClient,
+}
+
+// exports are read and written by the Wave app, so the path must be local
+func getWorkspaceExportPath(fileName string) (string, error) {
+	if RpcContext.Conn != "" {
+		return "", fmt.Errorf("workspaces can only be exported and imported from local blocks (the file is read and written by the Wave app)")
+	}
+	absPath, err := filepath.Abs(fileName)
+	if err != nil {
+		return "", fmt.Errorf("getting absolute path: %w", err)
+	}
+	return absPath, nil
+}
+
+func workspaceExportRun(cmd *cobra.Command, args []string) error {
+	path, err := getWorkspaceExportPath(args[0])
+	if err != nil {
+		return err
+	}
+	workspaceId := workspaceExportWorkspace
+	if workspaceId == "" {
+		oref, err := resolveSimpleId("workspace")
+		if err != nil {
+			return fmt.Errorf("resolving workspace: %w", err)
+		}
+		workspaceId = oref.OID
+	}
+	data := wshrpc.CommandWorkspaceExportData{WorkspaceId: workspaceId, Path: path, Files: workspaceExportFiles}
+	rtn, err := wshclient.WorkspaceExportCommand(RpcClient, data, &wshrpc.RpcOpts{Timeout: workspaceExportTimeout})
+	if err != nil {
+		return fmt.Errorf("exporting workspace: %w", err)
+	}
+	WriteStdout("wrote %s (%d bytes, %d objects, %d files)\n", rtn.Path, rtn.Size, rtn.NumObjects, rtn.NumFiles)
+	return nil
+}
+
+func workspaceImportRun(cmd *cobra.Command, args []string) error {
+	path, err := getWorkspaceExportPath(args[0])
+	if err != nil {
+		return err
+	}
+	rtn, err := wshclient.WorkspaceImportCommand(RpcClient, wshrpc.CommandWorkspaceImportData{Path: path}, &wshrpc.RpcOpts{Timeout: workspaceExportTimeout})
+	if err != nil {
+		return fmt.Errorf("importing workspace: %w", err)
+	}
+	WriteStdout("imported workspace %q as %s (%d objects, %d files)\n", rtn.Name, rtn.WorkspaceId, rtn.NumObjects, rtn.NumFiles)
+	return nil
+}
@@ -0,0 +1,2 @@
+-- compressed parts can't be read without the codec, decompress them first (wsh debug recompress --off)
+ALTER TABLE db_file_data DROP COLUMN codec;
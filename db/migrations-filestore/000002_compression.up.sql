@@ -0,0 +1,2 @@
+-- the codec of a data part ('' for uncompressed data)
+ALTER TABLE db_file_data ADD COLUMN codec varchar(20) NOT NULL DEFAULT '';
@@ -0,0 +1,15 @@
+CREATE TABLE db_cmdhistory (
+    historyid varchar(36) PRIMARY KEY,
+    blockid varchar(36) NOT NULL,
+    conn varchar(200) NOT NULL,
+    cmdtext text NOT NULL,
+    cwd text NOT NULL,
+    startts bigint NOT NULL,
+    endts bigint NOT NULL,
+    exitcode int NULL DEFAULT NULL,
+    outputstart bigint NOT NULL,
+    outputend bigint NOT NULL
+);
+
+CREATE INDEX idx_cmdhistory_startts ON db_cmdhistory (startts);
+CREATE INDEX idx_cmdhistory_blockid ON db_cmdhistory (blockid);
@@ -0,0 +1,2 @@
+DROP TABLE db_trashobj;
+DROP TABLE db_trash;
@@ -0,0 +1,19 @@
+CREATE TABLE db_trash (
+    trashid varchar(36) PRIMARY KEY,
+    otype varchar(10) NOT NULL,
+    oid varchar(36) NOT NULL,
+    deletedts bigint NOT NULL,
+    entry json NOT NULL
+);
+
+CREATE INDEX idx_trash_deletedts ON db_trash (deletedts);
+
+CREATE TABLE db_trashobj (
+    otype varchar(10) NOT NULL,
+    oid varchar(36) NOT NULL,
+    trashid varchar(36) NOT NULL,
+    data json NOT NULL,
+    PRIMARY KEY (otype, oid)
+);
+
+CREATE INDEX idx_trashobj_trashid ON db_trashobj (trashid);
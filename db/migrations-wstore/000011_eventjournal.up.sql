@@ -0,0 +1,10 @@
+CREATE TABLE db_event (
+    seq integer PRIMARY KEY AUTOINCREMENT,
+    ts bigint NOT NULL,
+    event varchar(50) NOT NULL,
+    data json NOT NULL
+);
+
+CREATE INDEX idx_event_ts ON db_event (ts);
+
+CREATE INDEX idx_event_event ON db_event (event, seq);
@@ -27,11 +27,26 @@ class RpcApiType {
         return client.wshRpcCall("authenticatetoken", data, opts);
     }
 
+    // command "backupcreate" [call]
+    BackupCreateCommand(client: WshClient, data: CommandBackupCreateData, opts?: RpcOpts): Promise<BackupCreateRtnData> {
+        return client.wshRpcCall("backupcreate", data, opts);
+    }
+
+    // command "backuprestore" [call]
+    BackupRestoreCommand(client: WshClient, data: CommandBackupRestoreData, opts?: RpcOpts): Promise<BackupRestoreRtnData> {
+        return client.wshRpcCall("backuprestore", data, opts);
+    }
+
     // command "blockinfo" [call]
     BlockInfoCommand(client: WshClient, data: string, opts?: RpcOpts): Promise<BlockInfoData> {
         return client.wshRpcCall("blockinfo", data, opts);
     }
 
+    // command "blockslist" [call]
+    BlocksListCommand(client: WshClient, data: CommandBlocksListData, opts?: RpcOpts): Promise<BlocksListEntry[]> {
+        return client.wshRpcCall("blockslist", data, opts);
+    }
+
     // command "connconnect" [call]
     ConnConnectCommand(client: WshClient, data: ConnRequest, opts?: RpcOpts): Promise<void> {
         return client.wshRpcCall("connconnect", data, opts);
@@ -102,6 +117,11 @@ class RpcApiType {
         return client.wshRpcCall("createsubblock", data, opts);
     }
 
+    // command "dbencrypt" [call]
+    DBEncryptCommand(client: WshClient, data: CommandDBEncryptData, opts?: RpcOpts): Promise<DBEncryptRtnData> {
+        return client.wshRpcCall("dbencrypt", data, opts);
+    }
+
     // command "deleteblock" [call]
     DeleteBlockCommand(client: WshClient, data: CommandDeleteBlockData, opts?: RpcOpts): Promise<void> {
         return client.wshRpcCall("deleteblock", data, opts);
@@ -147,6 +167,11 @@ class RpcApiType {
         return client.wshRpcCall("eventrecv", data, opts);
     }
 
+    // command "eventreplay" [call]
+    EventReplayCommand(client: WshClient, data: CommandEventReplayData, opts?: RpcOpts): Promise<EventReplayRtnData> {
+        return client.wshRpcCall("eventreplay", data, opts);
+    }
+
     // command "eventsub" [call]
     EventSubCommand(client: WshClient, data: SubscriptionRequest, opts?: RpcOpts): Promise<void> {
         return client.wshRpcCall("eventsub", data, opts);
@@ -232,16 +257,46 @@ class RpcApiType {
         return client.wshRpcStream("filereadstream", data, opts);
     }
 
+    // command "filesearch" [responsestream]
+	FileSearchCommand(client: WshClient, data: FileSearchData, opts?: RpcOpts): AsyncGenerator<CommandFileSearchRtnData, void, boolean> {
+        return client.wshRpcStream("filesearch", data, opts);
+    }
+
     // command "filesharecapability" [call]
     FileShareCapabilityCommand(client: WshClient, data: string, opts?: RpcOpts): Promise<FileShareCapability> {
         return client.wshRpcCall("filesharecapability", data, opts);
     }
 
+    // command "filestoregc" [call]
+    FileStoreGCCommand(client: WshClient, data: CommandFileStoreGCData, opts?: RpcOpts): Promise<FileStoreGCRtnData> {
+        return client.wshRpcCall("filestoregc", data, opts);
+    }
+
+    // command "filestorerecompress" [call]
+    FileStoreRecompressCommand(client: WshClient, data: CommandFileStoreRecompressData, opts?: RpcOpts): Promise<FileStoreRecompressRtnData> {
+        return client.wshRpcCall("filestorerecompress", data, opts);
+    }
+
+    // command "filestoreusage" [call]
+    FileStoreUsageCommand(client: WshClient, data: CommandFileStoreUsageData, opts?: RpcOpts): Promise<FileStoreUsageRtnData> {
+        return client.wshRpcCall("filestoreusage", data, opts);
+    }
+
     // command "filestreamtar" [responsestream]
 	FileStreamTarCommand(client: WshClient, data: CommandRemoteStreamTarData, opts?: RpcOpts): AsyncGenerator<Packet, void, boolean> {
         return client.wshRpcStream("filestreamtar", data, opts);
     }
 
+    // command "filesync" [responsestream]
+	FileSyncCommand(client: WshClient, data: CommandFileSyncData, opts?: RpcOpts): AsyncGenerator<FileSyncUpdate, void, boolean> {
+        return client.wshRpcStream("filesync", data, opts);
+    }
+
+    // command "filewatch" [responsestream]
+	FileWatchCommand(client: WshClient, data: CommandFileWatchData, opts?: RpcOpts): AsyncGenerator<FileWatchEvent, void, boolean> {
+        return client.wshRpcStream("filewatch", data, opts);
+    }
+
     // command "filewrite" [call]
     FileWriteCommand(client: WshClient, data: FileData, opts?: RpcOpts): Promise<void> {
         return client.wshRpcCall("filewrite", data, opts);
@@ -277,6 +332,31 @@ class RpcApiType {
         return client.wshRpcCall("getvar", data, opts);
     }
 
+    // command "historygetoutput" [call]
+    HistoryGetOutputCommand(client: WshClient, data: CommandHistoryGetOutputData, opts?: RpcOpts): Promise<CommandHistoryGetOutputRtnData> {
+        return client.wshRpcCall("historygetoutput", data, opts);
+    }
+
+    // command "historylist" [call]
+    HistoryListCommand(client: WshClient, data: CommandHistoryListData, opts?: RpcOpts): Promise<CmdHistoryEntry[]> {
+        return client.wshRpcCall("historylist", data, opts);
+    }
+
+    // command "inputgrouplist" [call]
+    InputGroupListCommand(client: WshClient, opts?: RpcOpts): Promise<InputGroupInfo[]> {
+        return client.wshRpcCall("inputgrouplist", null, opts);
+    }
+
+    // command "inputgroupsend" [call]
+    InputGroupSendCommand(client: WshClient, data: CommandInputGroupSendData, opts?: RpcOpts): Promise<void> {
+        return client.wshRpcCall("inputgroupsend", data, opts);
+    }
+
+    // command "inputgroupset" [call]
+    InputGroupSetCommand(client: WshClient, data: CommandInputGroupSetData, opts?: RpcOpts): Promise<void> {
+        return client.wshRpcCall("inputgroupset", data, opts);
+    }
+
     // command "message" [call]
     MessageCommand(client: WshClient, data: CommandMessageData, opts?: RpcOpts): Promise<void> {
         return client.wshRpcCall("message", data, opts);
@@ -287,11 +367,36 @@ class RpcApiType {
         return client.wshRpcCall("notify", data, opts);
     }
 
+    // command "patchmeta" [call]
+    PatchMetaCommand(client: WshClient, data: CommandPatchMetaData, opts?: RpcOpts): Promise<MetaType> {
+        return client.wshRpcCall("patchmeta", data, opts);
+    }
+
     // command "path" [call]
     PathCommand(client: WshClient, data: PathCommandData, opts?: RpcOpts): Promise<string> {
         return client.wshRpcCall("path", data, opts);
     }
 
+    // command "portopen" [call]
+    PortOpenCommand(client: WshClient, data: CommandPortOpenData, opts?: RpcOpts): Promise<PortOpenRtnData> {
+        return client.wshRpcCall("portopen", data, opts);
+    }
+
+    // command "portslist" [call]
+    PortsListCommand(client: WshClient, data: CommandPortsListData, opts?: RpcOpts): Promise<BlockPortsData[]> {
+        return client.wshRpcCall("portslist", data, opts);
+    }
+
+    // command "recordstart" [call]
+    RecordStartCommand(client: WshClient, data: CommandRecordStartData, opts?: RpcOpts): Promise<CommandRecordRtnData> {
+        return client.wshRpcCall("recordstart", data, opts);
+    }
+
+    // command "recordstop" [call]
+    RecordStopCommand(client: WshClient, data: CommandRecordStopData, opts?: RpcOpts): Promise<CommandRecordRtnData> {
+        return client.wshRpcCall("recordstop", data, opts);
+    }
+
     // command "recordtevent" [call]
     RecordTEventCommand(client: WshClient, data: TEvent, opts?: RpcOpts): Promise<void> {
         return client.wshRpcCall("recordtevent", data, opts);
@@ -317,16 +422,31 @@ class RpcApiType {
         return client.wshRpcCall("remotefilejoin", data, opts);
     }
 
+    // command "remotefilemanifest" [responsestream]
+	RemoteFileManifestCommand(client: WshClient, data: CommandRemoteFileManifestData, opts?: RpcOpts): AsyncGenerator<CommandRemoteFileManifestRtnData, void, boolean> {
+        return client.wshRpcStream("remotefilemanifest", data, opts);
+    }
+
     // command "remotefilemove" [call]
     RemoteFileMoveCommand(client: WshClient, data: CommandFileCopyData, opts?: RpcOpts): Promise<void> {
         return client.wshRpcCall("remotefilemove", data, opts);
     }
 
+    // command "remotefilesearch" [responsestream]
+	RemoteFileSearchCommand(client: WshClient, data: FileSearchData, opts?: RpcOpts): AsyncGenerator<CommandFileSearchRtnData, void, boolean> {
+        return client.wshRpcStream("remotefilesearch", data, opts);
+    }
+
     // command "remotefiletouch" [call]
     RemoteFileTouchCommand(client: WshClient, data: string, opts?: RpcOpts): Promise<void> {
         return client.wshRpcCall("remotefiletouch", data, opts);
     }
 
+    // command "remotefilewatch" [responsestream]
+	RemoteFileWatchCommand(client: WshClient, data: CommandRemoteFileWatchData, opts?: RpcOpts): AsyncGenerator<FileWatchEvent, void, boolean> {
+        return client.wshRpcStream("remotefilewatch", data, opts);
+    }
+
     // command "remotegetinfo" [call]
     RemoteGetInfoCommand(client: WshClient, opts?: RpcOpts): Promise<RemoteInfo> {
         return client.wshRpcCall("remotegetinfo", null, opts);
@@ -347,6 +467,11 @@ class RpcApiType {
         return client.wshRpcCall("remotemkdir", data, opts);
     }
 
+    // command "remoteproctree" [call]
+    RemoteProcTreeCommand(client: WshClient, data: CommandRemoteProcTreeData, opts?: RpcOpts): Promise<ProcTreeData> {
+        return client.wshRpcCall("remoteproctree", data, opts);
+    }
+
     // command "remotestreamcpudata" [responsestream]
 	RemoteStreamCpuDataCommand(client: WshClient, opts?: RpcOpts): AsyncGenerator<TimeSeriesData, void, boolean> {
         return client.wshRpcStream("remotestreamcpudata", null, opts);
@@ -382,6 +507,21 @@ class RpcApiType {
         return client.wshRpcCall("routeunannounce", null, opts);
     }
 
+    // command "schedulelist" [call]
+    ScheduleListCommand(client: WshClient, data: CommandScheduleListData, opts?: RpcOpts): Promise<ScheduleInfo[]> {
+        return client.wshRpcCall("schedulelist", data, opts);
+    }
+
+    // command "scrollbackdump" [responsestream]
+	ScrollbackDumpCommand(client: WshClient, data: CommandScrollbackDumpData, opts?: RpcOpts): AsyncGenerator<CommandScrollbackDumpRtnData, void, boolean> {
+        return client.wshRpcStream("scrollbackdump", data, opts);
+    }
+
+    // command "scrollbackgrep" [call]
+    ScrollbackGrepCommand(client: WshClient, data: CommandScrollbackGrepData, opts?: RpcOpts): Promise<ScrollbackGrepMatch[]> {
+        return client.wshRpcCall("scrollbackgrep", data, opts);
+    }
+
     // command "sendtelemetry" [call]
     SendTelemetryCommand(client: WshClient, opts?: RpcOpts): Promise<void> {
         return client.wshRpcCall("sendtelemetry", null, opts);
@@ -417,6 +557,11 @@ class RpcApiType {
         return client.wshRpcCall("setworkspacewidgetconfig", data, opts);
     }
 
+    // command "streamblockprocstats" [responsestream]
+	StreamBlockProcStatsCommand(client: WshClient, data: BlockProcStatsRequest, opts?: RpcOpts): AsyncGenerator<BlockProcStats, void, boolean> {
+        return client.wshRpcStream("streamblockprocstats", data, opts);
+    }
+
     // command "streamcpudata" [responsestream]
 	StreamCpuDataCommand(client: WshClient, data: CpuDataRequest, opts?: RpcOpts): AsyncGenerator<TimeSeriesData, void, boolean> {
         return client.wshRpcStream("streamcpudata", data, opts);
@@ -437,6 +582,16 @@ class RpcApiType {
         return client.wshRpcCall("test", data, opts);
     }
 
+    // command "trashlist" [call]
+    TrashListCommand(client: WshClient, data: CommandTrashListData, opts?: RpcOpts): Promise<TrashEntry[]> {
+        return client.wshRpcCall("trashlist", data, opts);
+    }
+
+    // command "trashrestore" [call]
+    TrashRestoreCommand(client: WshClient, data: CommandTrashRestoreData, opts?: RpcOpts): Promise<TrashRestoreRtnData> {
+        return client.wshRpcCall("trashrestore", data, opts);
+    }
+
     // command "vdomasyncinitiation" [call]
     VDomAsyncInitiationCommand(client: WshClient, data: VDomAsyncInitiationRequest, opts?: RpcOpts): Promise<void> {
         return client.wshRpcCall("vdomasyncinitiation", data, opts);
@@ -472,6 +627,16 @@ class RpcApiType {
         return client.wshRpcCall("webselector", data, opts);
     }
 
+    // command "workspaceexport" [call]
+    WorkspaceExportCommand(client: WshClient, data: CommandWorkspaceExportData, opts?: RpcOpts): Promise<WorkspaceExportRtnData> {
+        return client.wshRpcCall("workspaceexport", data, opts);
+    }
+
+    // command "workspaceimport" [call]
+    WorkspaceImportCommand(client: WshClient, data: CommandWorkspaceImportData, opts?: RpcOpts): Promise<WorkspaceImportRtnData> {
+        return client.wshRpcCall("workspaceimport", data, opts);
+    }
+
     // command "workspacelist" [call]
     WorkspaceListCommand(client: WshClient, opts?: RpcOpts): Promise<WorkspaceInfoData[]> {
         return client.wshRpcCall("workspacelist", null, opts);
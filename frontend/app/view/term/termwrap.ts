@@ -125,6 +125,12 @@ function handleOsc7Command(data: string, blockId: string, loaded: boolean): bool
             return false;
         }
         data = data.substring(nextSlashIdx);
+        try {
+            // the shell integration scripts percent-encode the path
+            data = decodeURIComponent(data);
+        } catch (e) {
+            console.log("Invalid OSC 7 command received (bad encoding)", data);
+        }
     }
     setTimeout(() => {
         fireAndForget(() =>
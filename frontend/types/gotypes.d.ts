@@ -45,6 +45,40 @@ declare global {
         message?: string;
     };
 
+    // wshrpc.BackupCreateRtnData
+    type BackupCreateRtnData = {
+        path: string;
+        size: number;
+        manifest: BackupManifest;
+    };
+
+    // wshrpc.BackupManifest
+    type BackupManifest = {
+        backupversion: number;
+        waveversion: string;
+        createdts: number;
+        wstoreversion: number;
+        filestoreversion: number;
+        encryptionkeyid?: string;
+        encryptionkeyids?: string[];
+        workspaces: BackupWorkspace[];
+    };
+
+    // wshrpc.BackupRestoreRtnData
+    type BackupRestoreRtnData = {
+        manifest: BackupManifest;
+        numobjects?: number;
+        numfiles?: number;
+        restartrequired?: boolean;
+    };
+
+    // wshrpc.BackupWorkspace
+    type BackupWorkspace = {
+        workspaceid: string;
+        name?: string;
+        numtabs: number;
+    };
+
     // waveobj.Block
     type Block = WaveObj & {
         parentoref?: string;
@@ -60,6 +94,11 @@ declare global {
         shellprocstatus?: string;
         shellprocconnname?: string;
         shellprocexitcode: number;
+        restartcount?: number;
+        lastexitcode?: number;
+        lastexitts?: number;
+        nextrestartts?: number;
+        crashloop?: boolean;
     };
 
     // waveobj.BlockDef
@@ -84,6 +123,47 @@ declare global {
         inputdata64: string;
     };
 
+    // wshrpc.BlockPortsData
+    type BlockPortsData = {
+        blockid: string;
+        connection?: string;
+        ports: ListeningPort[];
+        opened?: ListeningPort[];
+        closed?: ListeningPort[];
+    };
+
+    // wshrpc.BlockProcStats
+    type BlockProcStats = {
+        blockid: string;
+        ts: number;
+        rootpid: number;
+        numprocs: number;
+        cpu: number;
+        rss: number;
+        numfds: number;
+        children?: string[];
+    };
+
+    // wshrpc.BlockProcStatsRequest
+    type BlockProcStatsRequest = {
+        blockid: string;
+        interval?: number;
+        count?: number;
+    };
+
+    // wshrpc.BlocksListEntry
+    type BlocksListEntry = {
+        workspaceid: string;
+        tabid: string;
+        blockid: string;
+        view?: string;
+        controller?: string;
+        connection?: string;
+        status?: string;
+        stats?: BlockProcStats;
+        statserror?: string;
+    };
+
     // waveobj.Client
     type Client = WaveObj & {
         windowids: string[];
@@ -98,6 +178,20 @@ declare global {
         newactivetabid?: string;
     };
 
+    // wshrpc.CmdHistoryEntry
+    type CmdHistoryEntry = {
+        historyid: string;
+        blockid: string;
+        conn: string;
+        cmdtext: string;
+        cwd?: string;
+        startts: number;
+        endts?: number;
+        exitcode?: number;
+        outputstart: number;
+        outputend: number;
+    };
+
     // wshrpc.CommandAppendIJsonData
     type CommandAppendIJsonData = {
         zoneid: string;
@@ -118,6 +212,18 @@ declare global {
         token: string;
     };
 
+    // wshrpc.CommandBackupCreateData
+    type CommandBackupCreateData = {
+        path: string;
+    };
+
+    // wshrpc.CommandBackupRestoreData
+    type CommandBackupRestoreData = {
+        path: string;
+        workspaceid?: string;
+        dryrun?: boolean;
+    };
+
     // wshrpc.CommandBlockInputData
     type CommandBlockInputData = {
         blockid: string;
@@ -132,6 +238,12 @@ declare global {
         view: string;
     };
 
+    // wshrpc.CommandBlocksListData
+    type CommandBlocksListData = {
+        workspaceid?: string;
+        stats?: boolean;
+    };
+
     // wshrpc.CommandControllerAppendOutputData
     type CommandControllerAppendOutputData = {
         blockid: string;
@@ -163,6 +275,13 @@ declare global {
         blockdef: BlockDef;
     };
 
+    // wshrpc.CommandDBEncryptData
+    type CommandDBEncryptData = {
+        off?: boolean;
+        rotate?: boolean;
+        keysource?: string;
+    };
+
     // wshrpc.CommandDeleteBlockData
     type CommandDeleteBlockData = {
         blockid: string;
@@ -186,6 +305,14 @@ declare global {
         maxitems: number;
     };
 
+    // wshrpc.CommandEventReplayData
+    type CommandEventReplayData = {
+        sinceseq: number;
+        events?: string[];
+        scopes?: string[];
+        maxitems?: number;
+    };
+
     // wshrpc.CommandFileCopyData
     type CommandFileCopyData = {
         srcuri: string;
@@ -193,17 +320,145 @@ declare global {
         opts?: FileCopyOpts;
     };
 
+    // wshrpc.CommandFileSearchRtnData
+    type CommandFileSearchRtnData = {
+        results: FileSearchResult[];
+    };
+
+    // wshrpc.CommandFileStoreGCData
+    type CommandFileStoreGCData = {
+        dryrun?: boolean;
+        vacuum?: boolean;
+    };
+
+    // wshrpc.CommandFileStoreRecompressData
+    type CommandFileStoreRecompressData = {
+        zoneid?: string;
+        compress: boolean;
+    };
+
+    // wshrpc.CommandFileStoreUsageData
+    type CommandFileStoreUsageData = {
+        zoneid?: string;
+        limit?: number;
+    };
+
+    // wshrpc.CommandFileSyncData
+    type CommandFileSyncData = {
+        srcuri: string;
+        desturi: string;
+        opts?: FileSyncOpts;
+    };
+
+    // wshrpc.CommandFileWatchData
+    type CommandFileWatchData = {
+        uri: string;
+        recursive?: boolean;
+        excludes?: string[];
+    };
+
     // wshrpc.CommandGetMetaData
     type CommandGetMetaData = {
         oref: ORef;
     };
 
+    // wshrpc.CommandHistoryGetOutputData
+    type CommandHistoryGetOutputData = {
+        historyid: string;
+        format?: string;
+    };
+
+    // wshrpc.CommandHistoryGetOutputRtnData
+    type CommandHistoryGetOutputRtnData = {
+        entry: CmdHistoryEntry;
+        data64: string;
+        truncated?: boolean;
+    };
+
+    // wshrpc.CommandHistoryListData
+    type CommandHistoryListData = {
+        blockid?: string;
+        conn?: string;
+        search?: string;
+        failedonly?: boolean;
+        since?: number;
+        limit?: number;
+    };
+
+    // wshrpc.CommandInputGroupSendData
+    type CommandInputGroupSendData = {
+        group: string;
+        inputdata64: string;
+    };
+
+    // wshrpc.CommandInputGroupSetData
+    type CommandInputGroupSetData = {
+        blockids: string[];
+        group: string;
+        confirm?: boolean;
+    };
+
     // wshrpc.CommandMessageData
     type CommandMessageData = {
         oref: ORef;
         message: string;
     };
 
+    // wshrpc.CommandPatchMetaData
+    type CommandPatchMetaData = {
+        oref: ORef;
+        commands: {[key: string]: any}[];
+    };
+
+    // wshrpc.CommandPortOpenData
+    type CommandPortOpenData = {
+        blockid: string;
+        port: number;
+    };
+
+    // wshrpc.CommandPortsListData
+    type CommandPortsListData = {
+        blockid?: string;
+    };
+
+    // wshrpc.CommandRecordRtnData
+    type CommandRecordRtnData = {
+        location: string;
+        active: boolean;
+        size?: number;
+    };
+
+    // wshrpc.CommandRecordStartData
+    type CommandRecordStartData = {
+        blockid: string;
+        path?: string;
+        recordinput?: boolean;
+    };
+
+    // wshrpc.CommandRecordStopData
+    type CommandRecordStopData = {
+        blockid: string;
+    };
+
+    // wshrpc.CommandRemoteFileManifestData
+    type CommandRemoteFileManifestData = {
+        path: string;
+        checksum?: boolean;
+        excludes?: string[];
+    };
+
+    // wshrpc.CommandRemoteFileManifestRtnData
+    type CommandRemoteFileManifestRtnData = {
+        entries: FileManifestEntry[];
+    };
+
+    // wshrpc.CommandRemoteFileWatchData
+    type CommandRemoteFileWatchData = {
+        path: string;
+        recursive?: boolean;
+        excludes?: string[];
+    };
+
     // wshrpc.CommandRemoteListEntriesData
     type CommandRemoteListEntriesData = {
         path: string;
@@ -215,6 +470,13 @@ declare global {
         fileinfo?: FileInfo[];
     };
 
+    // wshrpc.CommandRemoteProcTreeData
+    type CommandRemoteProcTreeData = {
+        blockid: string;
+        pid?: number;
+        ports?: boolean;
+    };
+
     // wshrpc.CommandRemoteStreamFileData
     type CommandRemoteStreamFileData = {
         path: string;
@@ -238,12 +500,54 @@ declare global {
         resolvedids: {[key: string]: ORef};
     };
 
+    // wshrpc.CommandScheduleListData
+    type CommandScheduleListData = {
+        blockid?: string;
+    };
+
+    // wshrpc.CommandScrollbackDumpData
+    type CommandScrollbackDumpData = {
+        blockid: string;
+        format?: string;
+    };
+
+    // wshrpc.CommandScrollbackDumpRtnData
+    type CommandScrollbackDumpRtnData = {
+        blockid: string;
+        offset: number;
+        data64: string;
+        truncated?: boolean;
+    };
+
+    // wshrpc.CommandScrollbackGrepData
+    type CommandScrollbackGrepData = {
+        scope?: string;
+        blockid?: string;
+        tabid?: string;
+        pattern: string;
+        ignorecase?: boolean;
+        contextlines?: number;
+        maxmatches?: number;
+    };
+
     // wshrpc.CommandSetMetaData
     type CommandSetMetaData = {
         oref: ORef;
         meta: MetaType;
     };
 
+    // wshrpc.CommandTrashListData
+    type CommandTrashListData = {
+        workspaceid?: string;
+        otype?: string;
+    };
+
+    // wshrpc.CommandTrashRestoreData
+    type CommandTrashRestoreData = {
+        trashid: string;
+        workspaceid?: string;
+    };
+
     // wshrpc.CommandVarData
     type CommandVarData = {
         key: string;
@@ -275,6 +579,18 @@ declare global {
         opts?: WebSelectorOpts;
     };
 
+    // wshrpc.CommandWorkspaceExportData
+    type CommandWorkspaceExportData = {
+        workspaceid: string;
+        path: string;
+        files?: boolean;
+    };
+
+    // wshrpc.CommandWorkspaceImportData
+    type CommandWorkspaceImportData = {
+        path: string;
+    };
+
     // wconfig.ConfigError
     type ConfigError = {
         file: string;
@@ -379,6 +695,16 @@ declare global {
         widget?: WidgetInfo;
     };
 
+    // wshrpc.DBEncryptRtnData
+    type DBEncryptRtnData = {
+        encrypted: boolean;
+        keysource?: string;
+        keyid?: string;
+        numobjects: number;
+        numfiles: number;
+        numparts: number;
+    };
+
     // waveobj.DefaultTabConfig
     type DefaultTabConfig = {
         name: string;
@@ -403,6 +729,16 @@ declare global {
         workspaceid: string;
     };
 
+    // wshrpc.EventReplayRtnData
+    type EventReplayRtnData = {
+        events: WaveEvent[];
+        firstseq: number;
+        lastseq: number;
+        more?: boolean;
+        enabled: boolean;
+        journalevents: string[];
+    };
+
     // wshrpc.FetchSuggestionsData
     type FetchSuggestionsData = {
         suggestiontype: string;
@@ -426,6 +762,7 @@ declare global {
         recursive?: boolean;
         merge?: boolean;
         timeout?: number;
+        files?: string[];
     };
 
     // wshrpc.FileData
@@ -479,6 +816,16 @@ declare global {
         limit?: number;
     };
 
+    // wshrpc.FileManifestEntry
+    type FileManifestEntry = {
+        path: string;
+        size?: number;
+        modtime?: number;
+        mode?: number;
+        isdir?: boolean;
+        checksum?: string;
+    };
+
     // wshrpc.FileOpts
     type FileOpts = {
         maxsize?: number;
@@ -487,12 +834,155 @@ declare global {
         ijsonbudget?: number;
         truncate?: boolean;
         append?: boolean;
+        compress?: boolean;
+        modtime?: number;
+    };
+
+    // wshrpc.FileSearchData
+    type FileSearchData = {
+        path: string;
+        opts?: FileSearchOpts;
+    };
+
+    // wshrpc.FileSearchLineMatch
+    type FileSearchLineMatch = {
+        linenum: number;
+        line: string;
+    };
+
+    // wshrpc.FileSearchOpts
+    type FileSearchOpts = {
+        namepatterns?: string[];
+        contentregex?: string;
+        ignorecase?: boolean;
+        minsize?: number;
+        maxsize?: number;
+        modifiedafter?: number;
+        modifiedbefore?: number;
+        includedirs?: boolean;
+        excludes?: string[];
+        maxresults?: number;
+        timeout?: number;
+    };
+
+    // wshrpc.FileSearchResult
+    type FileSearchResult = {
+        info: FileInfo;
+        matches?: FileSearchLineMatch[];
     };
 
     // wshrpc.FileShareCapability
     type FileShareCapability = {
         canappend: boolean;
         canmkdir: boolean;
+        cansetmodtime: boolean;
+    };
+
+    // wshrpc.FileStoreFileUsage
+    type FileStoreFileUsage = {
+        name: string;
+        size: number;
+        datalength: number;
+        databytes: number;
+        circular?: boolean;
+        modts: number;
+    };
+
+    // wshrpc.FileStoreGCRtnData
+    type FileStoreGCRtnData = {
+        dryrun?: boolean;
+        zones: FileStoreGCZone[];
+        reclaimablebytes: number;
+        freebytes: number;
+        vacuumed?: boolean;
+        dbsizebefore: number;
+        dbsizeafter: number;
+    };
+
+    // wshrpc.FileStoreGCZone
+    type FileStoreGCZone = {
+        zoneid: string;
+        numfiles: number;
+        size: number;
+        databytes: number;
+    };
+
+    // wshrpc.FileStoreQuotas
+    type FileStoreQuotas = {
+        maxzonesize?: number;
+        maxfiletypesize?: {[key: string]: number};
+        maxtotalsize?: number;
+    };
+
+    // wshrpc.FileStoreRecompressRtnData
+    type FileStoreRecompressRtnData = {
+        numfiles: number;
+        numparts: number;
+        oldbytes: number;
+        newbytes: number;
+    };
+
+    // wshrpc.FileStoreTypeUsage
+    type FileStoreTypeUsage = {
+        name: string;
+        numfiles: number;
+        datalength: number;
+        databytes: number;
+    };
+
+    // wshrpc.FileStoreUsageRtnData
+    type FileStoreUsageRtnData = {
+        dbsize: number;
+        datalength: number;
+        databytes: number;
+        numzones: number;
+        quotas: FileStoreQuotas;
+        zones: FileStoreZoneUsage[];
+        filetypes: FileStoreTypeUsage[];
+        files?: FileStoreFileUsage[];
+    };
+
+    // wshrpc.FileStoreZoneUsage
+    type FileStoreZoneUsage = {
+        zoneid: string;
+        otype?: string;
+        view?: string;
+        numfiles: number;
+        datalength: number;
+        databytes: number;
+        modts: number;
+    };
+
+    // wshrpc.FileSyncOpts
+    type FileSyncOpts = {
+        delete?: boolean;
+        dryrun?: boolean;
+        checksum?: boolean;
+        excludes?: string[];
+        timeout?: number;
+    };
+
+    // wshrpc.FileSyncUpdate
+    type FileSyncUpdate = {
+        op: string;
+        path?: string;
+        reason?: string;
+        size?: number;
+        dryrun?: boolean;
+        filesdone: number;
+        filestotal: number;
+        bytesdone: number;
+        bytestotal: number;
+        numdeleted?: number;
+        numunchanged?: number;
+    };
+
+    // wshrpc.FileWatchEvent
+    type FileWatchEvent = {
+        path: string;
+        op: string;
+        isdir?: boolean;
+        ts: number;
     };
 
     // wconfig.FullConfigType
@@ -523,6 +1013,19 @@ declare global {
         error?: string;
     };
 
+    // wshrpc.InputGroupInfo
+    type InputGroupInfo = {
+        name: string;
+        members: InputGroupMember[];
+    };
+
+    // wshrpc.InputGroupMember
+    type InputGroupMember = {
+        blockid: string;
+        status?: string;
+        confirm?: boolean;
+    };
+
     // waveobj.LayoutActionData
     type LayoutActionData = {
         actiontype: string;
@@ -558,6 +1061,14 @@ declare global {
         error?: string;
     };
 
+    // wshrpc.ListeningPort
+    type ListeningPort = {
+        port: number;
+        address: string;
+        pid: number;
+        name?: string;
+    };
+
     // waveobj.MetaTSType
     type MetaType = {
         view?: string;
@@ -594,6 +1105,13 @@ declare global {
         "cmd:args"?: string[];
         "cmd:shell"?: boolean;
         "cmd:allowconnchange"?: boolean;
+        "cmd:restart"?: string;
+        "cmd:restartdelay"?: number;
+        "cmd:restartmaxdelay"?: number;
+        "cmd:restartmax"?: number;
+        "cmd:restartwindow"?: number;
+        "cmd:schedule"?: string;
+        "cmd:schedulepaused"?: boolean;
         "cmd:env"?: {[key: string]: string};
         "cmd:cwd"?: string;
         "cmd:initscript"?: string;
@@ -640,6 +1158,20 @@ declare global {
         "term:transparency"?: number;
         "term:allowbracketedpaste"?: boolean;
         "term:conndebug"?: string;
+        "term:record"?: boolean;
+        "term:recordinput"?: boolean;
+        "term:recordpath"?: string;
+        "term:persistent"?: boolean;
+        "term:triggers"?: TermTrigger[];
+        "term:detectports"?: boolean;
+        "term:openports"?: boolean;
+        "term:inputgroup"?: string;
+        "term:inputgroupconfirm"?: boolean;
+        "shell:*"?: boolean;
+        "shell:sandbox"?: boolean;
+        "shell:sandboxwritable"?: string[];
+        "shell:sandboxreadonly"?: string[];
+        "shell:sandboxnonet"?: boolean;
         "web:zoom"?: number;
         "web:hidenav"?: boolean;
         "web:partition"?: string;
@@ -689,6 +1221,31 @@ declare global {
         y: number;
     };
 
+    // wshrpc.PortOpenRtnData
+    type PortOpenRtnData = {
+        url: string;
+        blockid: string;
+    };
+
+    // wshrpc.ProcInfo
+    type ProcInfo = {
+        pid: number;
+        ppid: number;
+        name: string;
+        cputime: number;
+        rss: number;
+        numfds: number;
+        starttime: number;
+    };
+
+    // wshrpc.ProcTreeData
+    type ProcTreeData = {
+        ts: number;
+        rootpid: number;
+        procs: ProcInfo[];
+        ports?: ListeningPort[];
+    };
+
     // wshrpc.RemoteInfo
     type RemoteInfo = {
         clientarch: string;
@@ -745,6 +1302,35 @@ declare global {
         meta?: MetaType;
     };
 
+    // wshrpc.ScheduleInfo
+    type ScheduleInfo = {
+        blockid: string;
+        schedule: string;
+        paused?: boolean;
+        nextrunts?: number;
+        error?: string;
+        runs?: ScheduleRun[];
+    };
+
+    // wshrpc.ScheduleRun
+    type ScheduleRun = {
+        startts: number;
+        endts?: number;
+        exitcode?: number;
+        skipped?: boolean;
+    };
+
+    // wshrpc.ScrollbackGrepMatch
+    type ScrollbackGrepMatch = {
+        blockid: string;
+        linenum: number;
+        startoffset: number;
+        endoffset: number;
+        line: string;
+        before?: string[];
+        after?: string[];
+    };
+
     // webcmd.SetBlockTermSizeWSCommand
     type SetBlockTermSizeWSCommand = {
         wscommand: "setblocktermsize";
@@ -782,6 +1368,10 @@ declare global {
         "term:copyonselect"?: boolean;
         "term:transparency"?: number;
         "term:allowbracketedpaste"?: boolean;
+        "term:persistent"?: boolean;
+        "term:detectports"?: boolean;
+        "term:openports"?: boolean;
+        "term:compress"?: boolean;
         "editor:minimapenabled"?: boolean;
         "editor:stickyscrollenabled"?: boolean;
         "editor:wordwrap"?: boolean;
@@ -827,6 +1417,22 @@ declare global {
         "conn:*"?: boolean;
         "conn:askbeforewshinstall"?: boolean;
         "conn:wshenabled"?: boolean;
+        "db:*"?: boolean;
+        "db:encrypt"?: boolean;
+        "db:keysource"?: string;
+        "storage:*"?: boolean;
+        "storage:maxblockmb"?: number;
+        "storage:maxtermmb"?: number;
+        "storage:maxtotalmb"?: number;
+        "trash:*"?: boolean;
+        "trash:disabled"?: boolean;
+        "trash:retentiondays"?: number;
+        "trash:maxentries"?: number;
+        "eventjournal:*"?: boolean;
+        "eventjournal:enabled"?: boolean;
+        "eventjournal:events"?: string[];
+        "eventjournal:retentiondays"?: number;
+        "eventjournal:maxentries"?: number;
     };
 
     // waveobj.StickerClickOptsType
@@ -855,6 +1461,7 @@ declare global {
         event: string;
         scopes?: string[];
         allscopes?: boolean;
+        sinceseq?: number;
     };
 
     // wshrpc.SuggestionType
@@ -977,6 +1584,31 @@ declare global {
         cursor: string;
     };
 
+    // waveobj.TermTrigger
+    type TermTrigger = {
+        name?: string;
+        pattern: string;
+        action: string;
+        message?: string;
+        input?: string;
+        once?: boolean;
+        cooldown?: number;
+    };
+
+    // wps.TermTriggerEventData
+    type TermTriggerEventData = {
+        blockid: string;
+        name?: string;
+        pattern: string;
+        action: string;
+        line: string;
+        match: string;
+        text?: string;
+        offset: number;
+        length: number;
+        ts: number;
+    };
+
     // time.Time
     type Time = {
     };
@@ -987,6 +1619,40 @@ declare global {
         values: {[key: string]: number};
     };
 
+    // wshrpc.TrashEntry
+    type TrashEntry = {
+        trashid: string;
+        otype: string;
+        oid: string;
+        deletedts: number;
+        name?: string;
+        view?: string;
+        workspaceid?: string;
+        tabid?: string;
+        parentoref?: string;
+        index: number;
+        pinned?: boolean;
+        numblocks: number;
+        layout?: TrashLayoutPos;
+    };
+
+    // wshrpc.TrashLayoutPos
+    type TrashLayoutPos = {
+        targetblockid?: string;
+        position?: string;
+        actiontype?: string;
+        nodesize?: number;
+    };
+
+    // wshrpc.TrashRestoreRtnData
+    type TrashRestoreRtnData = {
+        otype: string;
+        oid: string;
+        workspaceid?: string;
+        tabid?: string;
+        numblocks: number;
+    };
+
     // waveobj.UIContext
     type UIContext = {
         windowid: string;
@@ -1292,6 +1958,8 @@ declare global {
         scopes?: string[];
         sender?: string;
         persist?: number;
+        seq?: number;
+        ts?: number;
         data?: any;
     };
 
@@ -1480,6 +2148,14 @@ declare global {
         active_tab_id?: string;
     };
 
+    // wshrpc.WorkspaceExportRtnData
+    type WorkspaceExportRtnData = {
+        path: string;
+        size: number;
+        numobjects: number;
+        numfiles: number;
+    };
+
     // waveobj.WorkspaceFavorite
     type WorkspaceFavorite = {
         favoriteid: string;
@@ -1496,6 +2172,14 @@ declare global {
         meta?: MetaType;
     };
 
+    // wshrpc.WorkspaceImportRtnData
+    type WorkspaceImportRtnData = {
+        workspaceid: string;
+        name?: string;
+        numobjects: number;
+        numfiles: number;
+    };
+
     // wshrpc.WorkspaceInfoData
     type WorkspaceInfoData = {
         windowid: string;
@@ -0,0 +1,446 @@
+// Copyright 2025, Command Line Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+// backups of the wave state (both dbs and the config dir) as a single tar.gz archive.
+//
+// the archive holds a manifest, consistent snapshots of the dbs (made with VACUUM INTO) and the files of the
+// config dir (which includes the workspace favorites).  a full restore is staged in the data dir and swapped in
+// by ApplyPendingRestore the next time wave starts (before the dbs are opened).  a single workspace can also be
+// restored into the running app.
+package backup
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/fs"
+	"log"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/wavetermdev/waveterm/pkg/dbcrypt"
+	"github.com/wavetermdev/waveterm/pkg/filestore"
+	"github.com/wavetermdev/waveterm/pkg/util/migrateutil"
+	"github.com/wavetermdev/waveterm/pkg/wavebase"
+	"github.com/wavetermdev/waveterm/pkg/waveobj"
+	"github.com/wavetermdev/waveterm/pkg/wshrpc"
+	"github.com/wavetermdev/waveterm/pkg/wstore"
+
+	dbfs "github.com/wavetermdev/waveterm/db"
+)
+
+const BackupVersion = 1
+
+const ManifestName = "manifest.json"
+
+// restores are staged here (in the data dir) until the next start
+const PendingRestoreDir = "restore-pending"
+
+const (
+	archiveDBDir     = "db"
+	archiveConfigDir = "config"
+)
+
+const maxArchiveFileSize = 64 * 1024 * 1024 * 1024
+
+type snapshotDB struct {
+	store          string
+	fileName       string
+	migrationFS    fs.FS
+	migrationsName string
+}
+
+func getSnapshotDBs(dir string) []snapshotDB {
+	return []snapshotDB{
+		{store: "wstore", fileName: filepath.Join(dir, archiveDBDir, wstore.WStoreDBName), migrationFS: dbfs.WStoreMigrationFS, migrationsName: "migrations-wstore"},
+		{store: "filestore", fileName: filepath.Join(dir, archiveDBDir, filestore.FilestoreDBName), migrationFS: dbfs.FilestoreMigrationFS, migrationsName: "migrations-filestore"},
+	}
+}
+
+func openSnapshot(fileName string) (*sqlx.DB, error) {
+	db, err := sqlx.Open("sqlite3", fmt.Sprintf("file:%s?mode=rw&_busy_timeout=5000", fileName))
+	if err != nil {
+		return nil, err
+	}
+	db.DB.SetMaxOpenConns(1)
+	return db, nil
+}
+
+// returns the migration version of a db snapshot
+func getSnapshotVersion(sdb snapshotDB) (uint, error) {
+	db, err := openSnapshot(sdb.fileName)
+	if err != nil {
+		return 0, err
+	}
+	defer db.Close()
+	m, err := migrateutil.MakeMigrate(sdb.store, db.DB, sdb.migrationFS, sdb.migrationsName)
+	if err != nil {
+		return 0, err
+	}
+	version, dirty, err := migrateutil.GetMigrateVersion(m)
+	if err != nil {
+		return 0, fmt.Errorf("cannot get %s version: %w", sdb.store, err)
+	}
+	if dirty {
+		return 0, fmt.Errorf("%s db is dirty", sdb.store)
+	}
+	return version, nil
+}
+
+// a temp dir in the data dir (the snapshots may be large, and may hold unencrypted data)
+func makeTempDir(prefix string) (string, error) {
+	return os.MkdirTemp(wavebase.GetWaveDataDir(), prefix)
+}
+
+// writes a backup of the current state to fileName
+func Create(ctx context.Context, fileName string) (*wshrpc.BackupCreateRtnData, error) {
+	if !filepath.IsAbs(fileName) {
+		return nil, fmt.Errorf("backup path must be absolute")
+	}
+	tmpDir, err := makeTempDir("backup-")
+	if err != nil {
+		return nil, fmt.Errorf("error creating temp dir: %w", err)
+	}
+	defer os.RemoveAll(tmpDir)
+	err = os.Mkdir(filepath.Join(tmpDir, archiveDBDir), 0700)
+	if err != nil {
+		return nil, err
+	}
+	sdbs := getSnapshotDBs(tmpDir)
+	err = wstore.DBBackupTo(ctx, sdbs[0].fileName)
+	if err != nil {
+		return nil, err
+	}
+	err = filestore.WFS.BackupTo(ctx, sdbs[1].fileName)
+	if err != nil {
+		return nil, err
+	}
+	manifest := &wshrpc.BackupManifest{
+		BackupVersion: BackupVersion,
+		WaveVersion:   wavebase.WaveVersion,
+		CreatedTs:     time.Now().UnixMilli(),
+	}
+	manifest.WStoreVersion, err = getSnapshotVersion(sdbs[0])
+	if err != nil {
+		return nil, err
+	}
+	manifest.FilestoreVersion, err = getSnapshotVersion(sdbs[1])
+	if err != nil {
+		return nil, err
+	}
+	if status := dbcrypt.GetStatus(); status.KeyId != "" {
+		manifest.EncryptionKeyId = status.KeyId
+		manifest.EncryptionKeyIds = dbcrypt.GetKeyIds()
+	}
+	manifest.Workspaces, err = getSnapshotWorkspaces(ctx, sdbs[0].fileName)
+	if err != nil {
+		return nil, err
+	}
+	size, err := writeArchive(fileName, manifest, tmpDir)
+	if err != nil {
+		return nil, err
+	}
+	log.Printf("[backup] wrote %s (%d bytes, %d workspaces)\n", fileName, size, len(manifest.Workspaces))
+	return &wshrpc.BackupCreateRtnData{Path: fileName, Size: size, Manifest: manifest}, nil
+}
+
+func getSnapshotWorkspaces(ctx context.Context, fileName string) ([]*wshrpc.BackupWorkspace, error) {
+	db, err := openSnapshot(fileName)
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close()
+	objs, err := readSnapshotObjs(ctx, db, waveobj.OType_Workspace, nil)
+	if err != nil {
+		return nil, err
+	}
+	rtn := make([]*wshrpc.BackupWorkspace, 0, len(objs))
+	for _, obj := range objs {
+		ws := obj.(*waveobj.Workspace)
+		rtn = append(rtn, &wshrpc.BackupWorkspace{WorkspaceId: ws.OID, Name: ws.Name, NumTabs: len(ws.TabIds) + len(ws.PinnedTabIds)})
+	}
+	return rtn, nil
+}
+
+// reads objects from a wstore snapshot (all objects of the type if oids is nil)
+func readSnapshotObjs(ctx context.Context, db *sqlx.DB, otype string, oids []string) ([]waveobj.WaveObj, error) {
+	type rowType struct {
+		OId     string
+		Version int
+		Data    []byte
+	}
+	var rows []rowType
+	query := fmt.Sprintf("SELECT oid, version, data FROM db_%s", otype)
+	var args []any
+	if oids != nil {
+		barr, _ := json.Marshal(oids)
+		query += " WHERE oid IN (SELECT value FROM json_each(?))"
+		args = append(args, string(barr))
+	}
+	err := db.SelectContext(ctx, &rows, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("error reading %s objects: %w", otype, err)
+	}
+	rtn := make([]waveobj.WaveObj, 0, len(rows))
+	for _, row := range rows {
+		obj, err := wstore.DecodeDBObj(otype, row.OId, row.Version, row.Data)
+		if err != nil {
+			return nil, err
+		}
+		rtn = append(rtn, obj)
+	}
+	return rtn, nil
+}
+
+func writeArchive(fileName string, manifest *wshrpc.BackupManifest, tmpDir string) (int64, error) {
+	tmpName := fileName + ".tmp"
+	fd, err := os.OpenFile(tmpName, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
+	if err != nil {
+		return 0, fmt.Errorf("error creating backup file: %w", err)
+	}
+	err = writeArchiveTo(fd, manifest, tmpDir)
+	closeErr := fd.Close()
+	if err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(tmpName, fileName)
+	}
+	if err != nil {
+		os.Remove(tmpName)
+		return 0, fmt.Errorf("error writing backup: %w", err)
+	}
+	finfo, err := os.Stat(fileName)
+	if err != nil {
+		return 0, err
+	}
+	return finfo.Size(), nil
+}
+
+func writeArchiveTo(w io.Writer, manifest *wshrpc.BackupManifest, tmpDir string) error {
+	gzw := gzip.NewWriter(w)
+	tw := tar.NewWriter(gzw)
+	manifestBytes, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return err
+	}
+	err = tw.WriteHeader(&tar.Header{Name: ManifestName, Mode: 0600, Size: int64(len(manifestBytes)), ModTime: time.Now()})
+	if err != nil {
+		return err
+	}
+	if _, err := tw.Write(manifestBytes); err != nil {
+		return err
+	}
+	err = addDirToArchive(tw, filepath.Join(tmpDir, archiveDBDir), archiveDBDir)
+	if err != nil {
+		return err
+	}
+	err = addDirToArchive(tw, wavebase.GetWaveConfigDir(), archiveConfigDir)
+	if err != nil {
+		return err
+	}
+	if err := tw.Close(); err != nil {
+		return err
+	}
+	return gzw.Close()
+}
+
+// adds the regular files in dir (recursively) under archiveDir
+func addDirToArchive(tw *tar.Writer, dir string, archiveDir string) error {
+	return filepath.WalkDir(dir, func(filePath string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if filePath == dir && os.IsNotExist(err) {
+				return nil
+			}
+			return err
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+		relPath, err := filepath.Rel(dir, filePath)
+		if err != nil {
+			return err
+		}
+		finfo, err := d.Info()
+		if err != nil {
+			return err
+		}
+		fd, err := os.Open(filePath)
+		if err != nil {
+			return err
+		}
+		defer fd.Close()
+		hdr := &tar.Header{Name: path.Join(archiveDir, filepath.ToSlash(relPath)), Mode: 0600, Size: finfo.Size(), ModTime: finfo.ModTime()}
+		if err := tw.WriteHeader(hdr); err != nil {
+			return err
+		}
+		_, err = io.CopyN(tw, fd, finfo.Size())
+		return err
+	})
+}
+
+// extracts the archive into dir (which must exist) and returns its manifest
+func extractArchive(fileName string, dir string) (*wshrpc.BackupManifest, error) {
+	fd, err := os.Open(fileName)
+	if err != nil {
+		return nil, fmt.Errorf("error opening backup: %w", err)
+	}
+	defer fd.Close()
+	gzr, err := gzip.NewReader(fd)
+	if err != nil {
+		return nil, fmt.Errorf("not a backup archive: %w", err)
+	}
+	tr := tar.NewReader(gzr)
+	var manifest *wshrpc.BackupManifest
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("error reading backup: %w", err)
+		}
+		if hdr.Typeflag != tar.TypeReg {
+			continue
+		}
+		if hdr.Size > maxArchiveFileSize {
+			return nil, fmt.Errorf("invalid file in backup: %s is too large", hdr.Name)
+		}
+		if hdr.Name == ManifestName {
+			var m wshrpc.BackupManifest
+			err = json.NewDecoder(io.LimitReader(tr, hdr.Size)).Decode(&m)
+			if err != nil {
+				return nil, fmt.Errorf("invalid backup manifest: %w", err)
+			}
+			manifest = &m
+			continue
+		}
+		cleanName := path.Clean(hdr.Name)
+		if !filepath.IsLocal(cleanName) || !(strings.HasPrefix(cleanName, archiveDBDir+"/") || strings.HasPrefix(cleanName, archiveConfigDir+"/")) {
+			return nil, fmt.Errorf("invalid file in backup: %q", hdr.Name)
+		}
+		err = extractFile(tr, filepath.Join(dir, filepath.FromSlash(cleanName)), hdr.Size)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if manifest == nil {
+		return nil, fmt.Errorf("invalid backup: no %s", ManifestName)
+	}
+	return manifest, nil
+}
+
+func extractFile(r io.Reader, fileName string, size int64) error {
+	err := os.MkdirAll(filepath.Dir(fileName), 0700)
+	if err != nil {
+		return err
+	}
+	fd, err := os.OpenFile(fileName, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+	if err != nil {
+		return fmt.Errorf("error extracting backup: %w", err)
+	}
+	defer fd.Close()
+	_, err = io.CopyN(fd, r, size)
+	if err != nil {
+		return fmt.Errorf("error extracting backup: %w", err)
+	}
+	return nil
+}
+
+// checks the manifest against the extracted snapshots and the migrations of this version of wave
+func validateBackup(manifest *wshrpc.BackupManifest, dir string) error {
+	if manifest.BackupVersion < 1 || manifest.BackupVersion > BackupVersion {
+		return fmt.Errorf("unsupported backup version %d (this version of wave supports up to %d)", manifest.BackupVersion, BackupVersion)
+	}
+	manifestVersions := []uint{manifest.WStoreVersion, manifest.FilestoreVersion}
+	for idx, sdb := range getSnapshotDBs(dir) {
+		if _, err := os.Stat(sdb.fileName); err != nil {
+			return fmt.Errorf("invalid backup: no %s db", sdb.store)
+		}
+		version, err := getSnapshotVersion(sdb)
+		if err != nil {
+			return fmt.Errorf("invalid backup: %w", err)
+		}
+		if version != manifestVersions[idx] {
+			return fmt.Errorf("invalid backup: %s db is version %d, manifest says %d", sdb.store, version, manifestVersions[idx])
+		}
+		maxVersion, err := migrateutil.GetMaxVersion(sdb.migrationFS, sdb.migrationsName)
+		if err != nil {
+			return err
+		}
+		if version > maxVersion {
+			return fmt.Errorf("backup was made by a newer version of wave (%s) with %s schema version %d, this version supports up to %d", manifest.WaveVersion, sdb.store, version, maxVersion)
+		}
+	}
+	return checkBackupKeys(manifest)
+}
+
+// the data in the backup may be encrypted with any of the keys that were in the keyset when it was made (older
+// backups only record the current key), so all of them have to be loaded
+func checkBackupKeys(manifest *wshrpc.BackupManifest) error {
+	keyIds := manifest.EncryptionKeyIds
+	if len(keyIds) == 0 && manifest.EncryptionKeyId != "" {
+		keyIds = []string{manifest.EncryptionKeyId}
+	}
+	var missingIds []string
+	for _, keyId := range keyIds {
+		if !dbcrypt.HasKey(keyId) {
+			missingIds = append(missingIds, keyId)
+		}
+	}
+	if len(missingIds) > 0 {
+		return fmt.Errorf("backup may contain data encrypted with database key(s) %s, which are not loaded (run \"wsh debug encrypt --off\" before making the backup, or copy the keys)", strings.Join(missingIds, ", "))
+	}
+	return nil
+}
+
+// validates a backup, then either stages it to replace the current state on the next start, or restores a single
+// workspace from it into the running app
+func Restore(ctx context.Context, data wshrpc.CommandBackupRestoreData) (*wshrpc.BackupRestoreRtnData, error) {
+	if !filepath.IsAbs(data.Path) {
+		return nil, fmt.Errorf("backup path must be absolute")
+	}
+	tmpDir, err := makeTempDir("restore-")
+	if err != nil {
+		return nil, fmt.Errorf("error creating temp dir: %w", err)
+	}
+	defer os.RemoveAll(tmpDir)
+	manifest, err := extractArchive(data.Path, tmpDir)
+	if err != nil {
+		return nil, err
+	}
+	err = validateBackup(manifest, tmpDir)
+	if err != nil {
+		return nil, err
+	}
+	rtn := &wshrpc.BackupRestoreRtnData{Manifest: manifest}
+	if data.DryRun {
+		return rtn, nil
+	}
+	if data.WorkspaceId != "" {
+		rtn.NumObjects, rtn.NumFiles, err = restoreWorkspace(ctx, tmpDir, data.WorkspaceId)
+		if err != nil {
+			return nil, err
+		}
+		return rtn, nil
+	}
+	pendingDir := filepath.Join(wavebase.GetWaveDataDir(), PendingRestoreDir)
+	err = os.RemoveAll(pendingDir)
+	if err != nil {
+		return nil, fmt.Errorf("error removing old pending restore: %w", err)
+	}
+	err = os.Rename(tmpDir, pendingDir)
+	if err != nil {
+		return nil, fmt.Errorf("error staging restore: %w", err)
+	}
+	log.Printf("[backup] staged restore of %s, it will be applied on the next start\n", data.Path)
+	rtn.RestartRequired = true
+	return rtn, nil
+}
@@ -0,0 +1,211 @@
+// Copyright 2025, Command Line Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package backup
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/wavetermdev/waveterm/pkg/dbcrypt"
+	"github.com/wavetermdev/waveterm/pkg/wavebase"
+	"github.com/wavetermdev/waveterm/pkg/wshrpc"
+)
+
+func writeTestFile(t *testing.T, fileName string, contents string) {
+	err := os.MkdirAll(filepath.Dir(fileName), 0700)
+	if err != nil {
+		t.Fatalf("error creating dir: %v", err)
+	}
+	err = os.WriteFile(fileName, []byte(contents), 0600)
+	if err != nil {
+		t.Fatalf("error writing file: %v", err)
+	}
+}
+
+func TestArchiveRoundTrip(t *testing.T) {
+	srcDir := t.TempDir()
+	oldConfigDir := wavebase.ConfigHome_VarCache
+	wavebase.ConfigHome_VarCache = filepath.Join(srcDir, "configdir")
+	defer func() { wavebase.ConfigHome_VarCache = oldConfigDir }()
+	writeTestFile(t, filepath.Join(srcDir, archiveDBDir, "waveterm.db"), "wstore")
+	writeTestFile(t, filepath.Join(wavebase.ConfigHome_VarCache, "settings.json"), `{"term:fontsize": 12}`)
+	writeTestFile(t, filepath.Join(wavebase.ConfigHome_VarCache, "presets", "ai.json"), "{}")
+	manifest := &wshrpc.BackupManifest{BackupVersion: BackupVersion, WStoreVersion: 7, Workspaces: []*wshrpc.BackupWorkspace{{WorkspaceId: "ws1", Name: "work", NumTabs: 2}}}
+	fileName := filepath.Join(srcDir, "backup.tar.gz")
+	_, err := writeArchive(fileName, manifest, srcDir)
+	if err != nil {
+		t.Fatalf("error writing archive: %v", err)
+	}
+	destDir := t.TempDir()
+	rtnManifest, err := extractArchive(fileName, destDir)
+	if err != nil {
+		t.Fatalf("error extracting archive: %v", err)
+	}
+	if rtnManifest.WStoreVersion != 7 || len(rtnManifest.Workspaces) != 1 || rtnManifest.Workspaces[0].Name != "work" {
+		t.Errorf("manifest mismatch: %#v", rtnManifest)
+	}
+	for relPath, expected := range map[string]string{
+		"db/waveterm.db":         "wstore",
+		"config/settings.json":   `{"term:fontsize": 12}`,
+		"config/presets/ai.json": "{}",
+	} {
+		barr, err := os.ReadFile(filepath.Join(destDir, filepath.FromSlash(relPath)))
+		if err != nil {
+			t.Errorf("error reading %s: %v", relPath, err)
+			continue
+		}
+		if string(barr) != expected {
+			t.Errorf("%s: expected %q, got %q", relPath, expected, string(barr))
+		}
+	}
+}
+
+func TestExtractRejectsBadPaths(t *testing.T) {
+	for _, name := range []string{"../evil", "config/../../evil", "/etc/evil", "other/file"} {
+		var buf bytes.Buffer
+		gzw := gzip.NewWriter(&buf)
+		tw := tar.NewWriter(gzw)
+		tw.WriteHeader(&tar.Header{Name: name, Mode: 0600, Size: 4, Typeflag: tar.TypeReg})
+		tw.Write([]byte("evil"))
+		tw.Close()
+		gzw.Close()
+		dir := t.TempDir()
+		fileName := filepath.Join(dir, "bad.tar.gz")
+		writeTestFile(t, fileName, buf.String())
+		_, err := extractArchive(fileName, filepath.Join(dir, "out"))
+		if err == nil || !strings.Contains(err.Error(), "invalid file") {
+			t.Errorf("%s: expected invalid file error, got %v", name, err)
+		}
+	}
+}
+
+func TestApplyPendingRestoreRollback(t *testing.T) {
+	oldDataDir, oldConfigDir := wavebase.DataHome_VarCache, wavebase.ConfigHome_VarCache
+	wavebase.DataHome_VarCache = t.TempDir()
+	wavebase.ConfigHome_VarCache = filepath.Join(t.TempDir(), "config")
+	defer func() { wavebase.DataHome_VarCache, wavebase.ConfigHome_VarCache = oldDataDir, oldConfigDir }()
+	dataDir, configDir := wavebase.DataHome_VarCache, wavebase.ConfigHome_VarCache
+	pendingDir := filepath.Join(dataDir, PendingRestoreDir)
+	for _, sdb := range getSnapshotDBs(pendingDir) {
+		writeTestFile(t, sdb.fileName, "restored")
+	}
+	writeTestFile(t, filepath.Join(configDir, "settings.json"), "current settings")
+	writeTestFile(t, filepath.Join(configDir, "extra.json"), "current extra")
+	writeTestFile(t, filepath.Join(pendingDir, archiveConfigDir, "settings.json"), "restored settings")
+	writeTestFile(t, filepath.Join(pendingDir, archiveConfigDir, "presets", "ai.json"), "restored presets")
+	checkFiles := func(expected map[string]string) {
+		t.Helper()
+		for fileName, contents := range expected {
+			barr, err := os.ReadFile(fileName)
+			if contents == "" {
+				if err == nil {
+					t.Errorf("%s should not exist", fileName)
+				}
+				continue
+			}
+			if string(barr) != contents {
+				t.Errorf("%s: expected %q, got %q (err %v)", fileName, contents, string(barr), err)
+			}
+		}
+	}
+
+	// there is no db dir, so the dbs cannot be moved in (after the config dir was replaced)
+	err := ApplyPendingRestore()
+	if err == nil {
+		t.Fatalf("expected the restore to fail")
+	}
+	if !strings.Contains(err.Error(), "waveterm.db") {
+		t.Errorf("expected the restore to fail on waveterm.db, got %v", err)
+	}
+	checkFiles(map[string]string{
+		filepath.Join(pendingDir, archiveDBDir, "waveterm.db"): "restored",
+		filepath.Join(configDir, "settings.json"):              "current settings",
+		filepath.Join(configDir, "extra.json"):                 "current extra",
+		filepath.Join(configDir, "presets", "ai.json"):         "",
+	})
+	for _, pattern := range []string{filepath.Join(dataDir, "pre-restore-*"), configDir + ".*"} {
+		if matches, _ := filepath.Glob(pattern); len(matches) != 0 {
+			t.Errorf("%v was not removed", matches)
+		}
+	}
+
+	for _, sdb := range getSnapshotDBs(pendingDir) {
+		dbName := filepath.Base(sdb.fileName)
+		writeTestFile(t, filepath.Join(dataDir, wavebase.WaveDBDir, dbName), "current")
+		writeTestFile(t, filepath.Join(dataDir, wavebase.WaveDBDir, dbName+"-wal"), "current wal")
+	}
+	err = ApplyPendingRestore()
+	if err != nil {
+		t.Fatalf("error applying restore: %v", err)
+	}
+	saveDirs, _ := filepath.Glob(filepath.Join(dataDir, "pre-restore-*"))
+	if len(saveDirs) != 1 {
+		t.Fatalf("expected one pre-restore dir, got %v", saveDirs)
+	}
+	// files that are not in the backup are removed from the config dir
+	checkFiles(map[string]string{
+		filepath.Join(dataDir, wavebase.WaveDBDir, "waveterm.db"):     "restored",
+		filepath.Join(dataDir, wavebase.WaveDBDir, "waveterm.db-wal"): "",
+		filepath.Join(configDir, "settings.json"):                     "restored settings",
+		filepath.Join(configDir, "presets", "ai.json"):                "restored presets",
+		filepath.Join(configDir, "extra.json"):                        "",
+		filepath.Join(saveDirs[0], archiveDBDir, "waveterm.db-wal"):   "current wal",
+		filepath.Join(saveDirs[0], archiveConfigDir, "settings.json"): "current settings",
+		filepath.Join(saveDirs[0], archiveConfigDir, "extra.json"):    "current extra",
+	})
+	if matches, _ := filepath.Glob(configDir + ".*"); len(matches) != 0 {
+		t.Errorf("%v was not removed", matches)
+	}
+	if _, err := os.Stat(pendingDir); err == nil {
+		t.Errorf("pending restore dir was not removed")
+	}
+}
+
+func TestCheckBackupKeys(t *testing.T) {
+	oldDataDir := wavebase.DataHome_VarCache
+	wavebase.DataHome_VarCache = t.TempDir()
+	defer func() { wavebase.DataHome_VarCache = oldDataDir }()
+	err := os.MkdirAll(filepath.Join(wavebase.DataHome_VarCache, wavebase.WaveDBDir), 0700)
+	if err != nil {
+		t.Fatalf("error creating db dir: %v", err)
+	}
+	err = dbcrypt.Init(true, dbcrypt.KeySourceFile)
+	if err != nil {
+		t.Fatalf("error initializing dbcrypt: %v", err)
+	}
+	defer dbcrypt.Disable()
+	oldKeyId := dbcrypt.GetStatus().KeyId
+	newKeyId, err := dbcrypt.RotateKey("")
+	if err != nil {
+		t.Fatalf("error rotating key: %v", err)
+	}
+	keyIds := dbcrypt.GetKeyIds()
+	if len(keyIds) != 2 {
+		t.Fatalf("expected 2 keys, got %v", keyIds)
+	}
+	if err := checkBackupKeys(&wshrpc.BackupManifest{EncryptionKeyId: newKeyId, EncryptionKeyIds: keyIds}); err != nil {
+		t.Errorf("expected the keys to be loaded: %v", err)
+	}
+	err = dbcrypt.PruneKeys()
+	if err != nil {
+		t.Fatalf("error pruning keys: %v", err)
+	}
+	// the backup was made before the data was re-encrypted, so it may still need the old key
+	err = checkBackupKeys(&wshrpc.BackupManifest{EncryptionKeyId: newKeyId, EncryptionKeyIds: keyIds})
+	if err == nil || !strings.Contains(err.Error(), oldKeyId) {
+		t.Errorf("expected an error about key %s, got %v", oldKeyId, err)
+	}
+	// older backups only record the current key
+	if err := checkBackupKeys(&wshrpc.BackupManifest{EncryptionKeyId: newKeyId}); err != nil {
+		t.Errorf("expected the current key to be loaded: %v", err)
+	}
+	if err := checkBackupKeys(&wshrpc.BackupManifest{EncryptionKeyId: oldKeyId}); err == nil {
+		t.Errorf("expected an error for a pruned key")
+	}
+}
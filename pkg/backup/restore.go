@@ -0,0 +1,270 @@
+// Copyright 2025, Command Line Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package backup
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"log"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/wavetermdev/waveterm/pkg/filestore"
+	"github.com/wavetermdev/waveterm/pkg/util/migrateutil"
+	"github.com/wavetermdev/waveterm/pkg/wavebase"
+	"github.com/wavetermdev/waveterm/pkg/waveobj"
+	"github.com/wavetermdev/waveterm/pkg/wstore"
+)
+
+// restores a workspace (with its tabs, layouts, blocks and their files) from an extracted backup into the running app.
+// the objects keep their ids, so the workspace must not exist anymore.  returns the number of objects and files restored.
+func restoreWorkspace(ctx context.Context, dir string, workspaceId string) (int, int, error) {
+	sdbs := getSnapshotDBs(dir)
+	for _, sdb := range sdbs {
+		// older backups are brought up to the current schema (the snapshots are temp copies)
+		db, err := openSnapshot(sdb.fileName)
+		if err != nil {
+			return 0, 0, err
+		}
+		err = migrateutil.Migrate("backup "+sdb.store, db.DB, sdb.migrationFS, sdb.migrationsName)
+		db.Close()
+		if err != nil {
+			return 0, 0, err
+		}
+	}
+	wdb, err := openSnapshot(sdbs[0].fileName)
+	if err != nil {
+		return 0, 0, err
+	}
+	defer wdb.Close()
+	wsObjs, err := readSnapshotObjs(ctx, wdb, waveobj.OType_Workspace, []string{workspaceId})
+	if err != nil {
+		return 0, 0, err
+	}
+	if len(wsObjs) == 0 {
+		return 0, 0, fmt.Errorf("workspace %s not found in backup", workspaceId)
+	}
+	ws := wsObjs[0].(*waveobj.Workspace)
+	objs := []waveobj.WaveObj{ws}
+	tabObjs, err := readSnapshotObjs(ctx, wdb, waveobj.OType_Tab, append(append([]string{}, ws.TabIds...), ws.PinnedTabIds...))
+	if err != nil {
+		return 0, 0, err
+	}
+	objs = append(objs, tabObjs...)
+	var layoutIds, blockIds []string
+	for _, obj := range tabObjs {
+		tab := obj.(*waveobj.Tab)
+		if tab.LayoutState != "" {
+			layoutIds = append(layoutIds, tab.LayoutState)
+		}
+		blockIds = append(blockIds, tab.BlockIds...)
+	}
+	layoutObjs, err := readSnapshotObjs(ctx, wdb, waveobj.OType_LayoutState, layoutIds)
+	if err != nil {
+		return 0, 0, err
+	}
+	objs = append(objs, layoutObjs...)
+	// blocks and their sub-blocks
+	for len(blockIds) > 0 {
+		blockObjs, err := readSnapshotObjs(ctx, wdb, waveobj.OType_Block, blockIds)
+		if err != nil {
+			return 0, 0, err
+		}
+		objs = append(objs, blockObjs...)
+		blockIds = nil
+		for _, obj := range blockObjs {
+			blockIds = append(blockIds, obj.(*waveobj.Block).SubBlockIds...)
+		}
+	}
+	err = wstore.WithTx(ctx, func(tx *wstore.TxWrap) error {
+		for _, obj := range objs {
+			oref := waveobj.ORefFromWaveObj(obj)
+			exists, err := wstore.DBExistsORef(tx.Context(), *oref)
+			if err != nil {
+				return err
+			}
+			if exists {
+				return fmt.Errorf("cannot restore workspace, %s already exists", oref)
+			}
+			err = wstore.DBInsert(tx.Context(), obj)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, 0, err
+	}
+	zoneIds := make([]string, 0, len(objs))
+	for _, obj := range objs {
+		zoneIds = append(zoneIds, waveobj.GetOID(obj))
+	}
+	fdb, err := openSnapshot(sdbs[1].fileName)
+	if err != nil {
+		return len(objs), 0, err
+	}
+	defer fdb.Close()
+	numFiles, err := filestore.WFS.CopyZonesFrom(ctx, fdb, zoneIds)
+	if err != nil {
+		return len(objs), 0, fmt.Errorf("error restoring files: %w", err)
+	}
+	log.Printf("[backup] restored workspace %s (%d objects, %d files)\n", workspaceId, len(objs), numFiles)
+	return len(objs), numFiles, nil
+}
+
+// swaps in a restore staged by Restore.  must be called before the dbs are opened.  the current dbs and config dir
+// are moved to a "pre-restore" dir in the data dir first (the config dir is replaced as a whole, so files that are
+// not in the backup do not survive the restore).  the restored files are staged next to their destination before
+// anything is replaced, and if a step fails the replaced files are put back, so a failed restore leaves the current
+// state in place.
+func ApplyPendingRestore() error {
+	dataDir := wavebase.GetWaveDataDir()
+	pendingDir := filepath.Join(dataDir, PendingRestoreDir)
+	if _, err := os.Stat(pendingDir); errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	saveDir := filepath.Join(dataDir, fmt.Sprintf("pre-restore-%d", time.Now().UnixMilli()))
+	err := os.MkdirAll(filepath.Join(saveDir, archiveDBDir), 0700)
+	if err != nil {
+		return err
+	}
+	undo := []func() error{func() error { return os.RemoveAll(saveDir) }}
+	defer func() {
+		// undo runs in reverse order, it is cleared once the restore is complete
+		for idx := len(undo) - 1; idx >= 0; idx-- {
+			if err := undo[idx](); err != nil {
+				log.Printf("[backup] error rolling back restore: %v\n", err)
+			}
+		}
+	}()
+	rename := func(src string, dest string) error {
+		err := os.Rename(src, dest)
+		if err != nil {
+			return err
+		}
+		undo = append(undo, func() error { return os.Rename(dest, src) })
+		return nil
+	}
+
+	// stage: the dbs are renamed into place (same filesystem), the config dir is copied next to its destination
+	// (the config dir may be on another filesystem)
+	dbDir := filepath.Join(dataDir, wavebase.WaveDBDir)
+	sdbs := getSnapshotDBs(pendingDir)
+	for _, sdb := range sdbs {
+		if _, err := os.Stat(sdb.fileName); err != nil {
+			return fmt.Errorf("error restoring %s: %w", filepath.Base(sdb.fileName), err)
+		}
+	}
+	configDir := wavebase.GetWaveConfigDir()
+	stagedConfigDir := configDir + ".restore-tmp"
+	oldConfigDir := configDir + "." + filepath.Base(saveDir)
+	undo = append(undo, func() error { return os.RemoveAll(stagedConfigDir) })
+	err = os.RemoveAll(stagedConfigDir)
+	if err != nil {
+		return fmt.Errorf("error restoring config: %w", err)
+	}
+	err = copyDir(filepath.Join(pendingDir, archiveConfigDir), stagedConfigDir)
+	if err != nil {
+		return fmt.Errorf("error restoring config: %w", err)
+	}
+
+	// move the current state out of the way and the restored files in
+	err = rename(configDir, oldConfigDir)
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("error moving config dir: %w", err)
+	}
+	err = rename(stagedConfigDir, configDir)
+	if err != nil {
+		return fmt.Errorf("error restoring config: %w", err)
+	}
+	for _, sdb := range sdbs {
+		dbName := filepath.Base(sdb.fileName)
+		for _, suffix := range []string{"", "-wal", "-shm"} {
+			err = rename(filepath.Join(dbDir, dbName+suffix), filepath.Join(saveDir, archiveDBDir, dbName+suffix))
+			if err != nil && !errors.Is(err, fs.ErrNotExist) {
+				return fmt.Errorf("error moving %s: %w", dbName+suffix, err)
+			}
+		}
+		err = rename(sdb.fileName, filepath.Join(dbDir, dbName))
+		if err != nil {
+			return fmt.Errorf("error restoring %s: %w", dbName, err)
+		}
+	}
+	undo = nil
+
+	// the old config dir is kept with the old dbs (copied if it is on another filesystem)
+	if _, err := os.Stat(oldConfigDir); err == nil {
+		saveConfigDir := filepath.Join(saveDir, archiveConfigDir)
+		err = os.Rename(oldConfigDir, saveConfigDir)
+		if err != nil {
+			err = copyDir(oldConfigDir, saveConfigDir)
+			if err == nil {
+				err = os.RemoveAll(oldConfigDir)
+			}
+		}
+		if err != nil {
+			log.Printf("error moving %s to %s: %v\n", oldConfigDir, saveConfigDir, err)
+		}
+	}
+	err = os.RemoveAll(pendingDir)
+	if err != nil {
+		log.Printf("error removing %s: %v\n", pendingDir, err)
+	}
+	log.Printf("[backup] applied restore, the previous state was moved to %s\n", saveDir)
+	return nil
+}
+
+// copies the regular files in src (recursively) to dest.  dest is created even if src does not exist.
+func copyDir(src string, dest string) error {
+	err := os.MkdirAll(dest, 0700)
+	if err != nil {
+		return err
+	}
+	return filepath.WalkDir(src, func(filePath string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if filePath == src && errors.Is(err, fs.ErrNotExist) {
+				return nil
+			}
+			return err
+		}
+		relPath, err := filepath.Rel(src, filePath)
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			return os.MkdirAll(filepath.Join(dest, relPath), 0700)
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+		return copyFile(filePath, filepath.Join(dest, relPath))
+	})
+}
+
+func copyFile(src string, dest string) error {
+	err := os.MkdirAll(filepath.Dir(dest), 0700)
+	if err != nil {
+		return err
+	}
+	srcFd, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer srcFd.Close()
+	destFd, err := os.OpenFile(dest, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(destFd, srcFd)
+	closeErr := destFd.Close()
+	if err != nil {
+		return err
+	}
+	return closeErr
+}
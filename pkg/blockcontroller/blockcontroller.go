@@ -86,6 +86,9 @@ type BlockController struct {
 	ShellProcExitCode int
 	RunLock           *atomic.Bool
 	StatusVersion     int
+	recorder          *castRecorder
+	restart           restartState
+	ports             []*wshrpc.ListeningPort // as of the last poll
 }
 
 type BlockControllerRuntimeStatus struct {
@@ -94,6 +97,11 @@ type BlockControllerRuntimeStatus struct {
 	ShellProcStatus   string `json:"shellprocstatus,omitempty"`
 	ShellProcConnName string `json:"shellprocconnname,omitempty"`
 	ShellProcExitCode int    `json:"shellprocexitcode"`
+	RestartCount      int    `json:"restartcount,omitempty"`
+	LastExitCode      int    `json:"lastexitcode,omitempty"`
+	LastExitTs        int64  `json:"lastexitts,omitempty"`
+	NextRestartTs     int64  `json:"nextrestartts,omitempty"`
+	CrashLoop         bool   `json:"crashloop,omitempty"`
 }
 
 func (bc *BlockController) WithLock(f func()) {
@@ -113,6 +121,11 @@ func (bc *BlockController) GetRuntimeStatus() *BlockControllerRuntimeStatus {
 			rtn.ShellProcConnName = bc.ShellProc.ConnName
 		}
 		rtn.ShellProcExitCode = bc.ShellProcExitCode
+		rtn.RestartCount = bc.restart.RestartCount
+		rtn.LastExitCode = bc.restart.LastExitCode
+		rtn.LastExitTs = bc.restart.LastExitTs
+		rtn.NextRestartTs = bc.restart.NextRestartTs
+		rtn.CrashLoop = bc.restart.CrashLoop
 	})
 	return &rtn
 }
@@ -458,6 +471,13 @@ func getLocalShellOpts(blockMeta waveobj.MetaMapType) []string {
 	return nil
 }
 
+// terminal output, recordings and saved terminal state are compressed in the filestore (the term:compress
+// setting, only applies to new files)
+func CompressTermFiles() bool {
+	settings := wconfig.GetWatcher().GetFullConfig().Settings
+	return settings.TermCompress == nil || *settings.TermCompress
+}
+
 func (union *ConnUnion) getRemoteInfoAndShellType(blockMeta waveobj.MetaMapType) error {
 	if !union.WshEnabled {
 		return nil
@@ -525,10 +545,23 @@ func (bc *BlockController) setupAndStartShellProcess(logCtx context.Context, rc
 	// create a circular blockfile for the output
 	ctx, cancelFn := context.WithTimeout(context.Background(), 2*time.Second)
 	defer cancelFn()
-	fsErr := filestore.WFS.MakeFile(ctx, bc.BlockId, wavebase.BlockFile_Term, nil, wshrpc.FileOpts{MaxSize: DefaultTermMaxFileSize, Circular: true})
+	fsErr := filestore.WFS.MakeFile(ctx, bc.BlockId, wavebase.BlockFile_Term, nil, wshrpc.FileOpts{MaxSize: DefaultTermMaxFileSize, Circular: true, Compress: CompressTermFiles()})
 	if fsErr != nil && fsErr != fs.ErrExist {
 		return nil, fmt.Errorf("error creating blockfile: %w", fsErr)
 	}
+	remoteName := blockMeta.GetString(waveobj.MetaKey_Connection, "")
+	persistent := usePersistentSession(bc.ControllerType, remoteName, blockMeta)
+	if persistent && bc.GetRuntimeStatus().ShellProcStatus != Status_Running {
+		if shellProc := bc.attachPersistentSession(rc.TermSize); shellProc != nil {
+			// the shell is still running, so keep the terminal state
+			bc.UpdateControllerAndSendUpdate(func() bool {
+				bc.ShellProc = shellProc
+				bc.ShellProcStatus = Status_Running
+				return true
+			})
+			return shellProc, nil
+		}
+	}
 	if fsErr == fs.ErrExist {
 		// reset the terminal state
 		bc.resetTerminalState(logCtx)
@@ -538,7 +571,6 @@ func (bc *BlockController) setupAndStartShellProcess(logCtx context.Context, rc
 		return nil, nil
 	}
 	// TODO better sync here (don't let two starts happen at the same times)
-	remoteName := blockMeta.GetString(waveobj.MetaKey_Connection, "")
 	connUnion, err := bc.getConnUnion(logCtx, remoteName, blockMeta)
 	if err != nil {
 		return nil, err
@@ -567,6 +599,9 @@ func (bc *BlockController) setupAndStartShellProcess(logCtx context.Context, rc
 	} else {
 		return nil, fmt.Errorf("unknown controller type %q", bc.ControllerType)
 	}
+	if blockMeta.GetBool(waveobj.MetaKey_ShellSandbox, false) && connUnion.ConnType != ConnType_Local {
+		return nil, fmt.Errorf("shell:sandbox is only supported for local shells")
+	}
 	var shellProc *shellexec.ShellProc
 	swapToken := bc.makeSwapToken(ctx, logCtx, blockMeta, remoteName, connUnion.ShellType)
 	cmdOpts.SwapToken = swapToken
@@ -630,7 +665,12 @@ func (bc *BlockController) setupAndStartShellProcess(logCtx context.Context, rc
 			}
 		}
 	} else if connUnion.ConnType == ConnType_Local {
-		if connUnion.WshEnabled {
+		cmdOpts.Sandbox, err = makeSandboxOpts(blockMeta, cmdOpts.Cwd)
+		if err != nil {
+			return nil, err
+		}
+		// sandboxed shells don't get a jwt (wsh can't be used to leave the sandbox)
+		if connUnion.WshEnabled && cmdOpts.Sandbox == nil {
 			sockName := wavebase.GetDomainSocketName()
 			rpcContext := wshrpc.RpcContext{TabId: bc.TabId, BlockId: bc.BlockId}
 			jwtStr, err := wshutil.MakeClientJWTToken(rpcContext, sockName)
@@ -643,7 +683,11 @@ func (bc *BlockController) setupAndStartShellProcess(logCtx context.Context, rc
 		}
 		cmdOpts.ShellPath = connUnion.ShellPath
 		cmdOpts.ShellOpts = getLocalShellOpts(blockMeta)
-		shellProc, err = shellexec.StartLocalShellProc(logCtx, rc.TermSize, cmdStr, cmdOpts)
+		if persistent {
+			shellProc, err = shellexec.StartLocalSessionShellProc(logCtx, rc.TermSize, cmdStr, cmdOpts, bc.BlockId)
+		} else {
+			shellProc, err = shellexec.StartLocalShellProc(logCtx, rc.TermSize, cmdStr, cmdOpts)
+		}
 		if err != nil {
 			return nil, err
 		}
@@ -653,6 +697,7 @@ func (bc *BlockController) setupAndStartShellProcess(logCtx context.Context, rc
 	bc.UpdateControllerAndSendUpdate(func() bool {
 		bc.ShellProc = shellProc
 		bc.ShellProcStatus = Status_Running
+		bc.restart.stopRequested = false
 		return true
 	})
 	return shellProc, nil
@@ -679,6 +724,20 @@ func (bc *BlockController) manageRunningShellProcess(shellProc *shellexec.ShellP
 	wshProxy.SetRpcContext(&wshrpc.RpcContext{TabId: bc.TabId, BlockId: bc.BlockId})
 	wshutil.DefaultRouter.RegisterRoute(wshutil.MakeControllerRouteId(bc.BlockId), wshProxy, true)
 	ptyBuffer := wshutil.MakePtyBuffer(wshutil.WaveOSCPrefix, shellProc.Cmd, wshProxy.FromRemoteCh)
+	var histTracker *cmdTracker
+	if bc.ControllerType == BlockController_Shell {
+		histTracker = makeCmdTracker(bc.BlockId, blockMeta.GetString(waveobj.MetaKey_Connection, ConnType_Local))
+	}
+	triggers := makeTriggerEngine(bc.BlockId, bc.loadTriggers, bc.fireTrigger)
+	if detectPorts, openPorts := portsDetectEnabled(blockMeta); detectPorts {
+		go bc.watchPorts(shellProc, openPorts)
+	}
+	if blockMeta.GetBool(waveobj.MetaKey_TermRecord, false) {
+		err := bc.startRecording(blockMeta.GetString(waveobj.MetaKey_TermRecordPath, ""), blockMeta.GetBool(waveobj.MetaKey_TermRecordInput, false), rc.TermSize)
+		if err != nil {
+			log.Printf("error starting recording for block %s: %v\n", bc.BlockId, err)
+		}
+	}
 	go func() {
 		// handles regular output from the pty (goes to the blockfile and xterm)
 		defer func() {
@@ -686,6 +745,10 @@ func (bc *BlockController) manageRunningShellProcess(shellProc *shellexec.ShellP
 		}()
 		defer func() {
 			log.Printf("[shellproc] pty-read loop done\n")
+			if histTracker != nil {
+				histTracker.close()
+			}
+			bc.stopRecording()
 			shellProc.Close()
 			bc.WithLock(func() {
 				// so no other events are sent
@@ -694,7 +757,7 @@ func (bc *BlockController) manageRunningShellProcess(shellProc *shellexec.ShellP
 			shellProc.Cmd.Wait()
 			exitCode := shellProc.Cmd.ExitCode()
 			blockData := bc.getBlockData_noErr()
-			if blockData != nil && blockData.Meta.GetString(waveobj.MetaKey_Controller, "") == BlockController_Cmd {
+			if blockData != nil && blockData.Meta.GetString(waveobj.MetaKey_Controller, "") == BlockController_Cmd && !shellProc.IsDetached() {
 				termMsg := fmt.Sprintf("\r\nprocess finished with exit code = %d\r\n\r\n", exitCode)
 				HandleAppendBlockFile(bc.BlockId, wavebase.BlockFile_Term, []byte(termMsg))
 			}
@@ -710,6 +773,14 @@ func (bc *BlockController) manageRunningShellProcess(shellProc *shellexec.ShellP
 				err := HandleAppendBlockFile(bc.BlockId, wavebase.BlockFile_Term, buf[:nr])
 				if err != nil {
 					log.Printf("error appending to blockfile: %v\n", err)
+				} else {
+					if histTracker != nil {
+						histTracker.processOutput(buf[:nr])
+					}
+					triggers.processOutput(buf[:nr])
+				}
+				if rec := bc.getRecorder(); rec != nil {
+					rec.writeOutput(buf[:nr])
 				}
 			}
 			if err == io.EOF {
@@ -756,6 +827,7 @@ func (bc *BlockController) manageRunningShellProcess(shellProc *shellexec.ShellP
 		}()
 		// wait for the shell to finish
 		var exitCode int
+		var restartDelay time.Duration = -1
 		defer func() {
 			wshutil.DefaultRouter.UnregisterRoute(wshutil.MakeControllerRouteId(bc.BlockId))
 			bc.UpdateControllerAndSendUpdate(func() bool {
@@ -765,11 +837,25 @@ func (bc *BlockController) manageRunningShellProcess(shellProc *shellexec.ShellP
 				bc.ShellProcExitCode = exitCode
 				return true
 			})
+			if restartDelay >= 0 {
+				// must be scheduled after the status is set to done
+				bc.scheduleRestart(restartDelay)
+			}
 			log.Printf("[shellproc] shell process wait loop done\n")
 		}()
+		runStartTs := time.Now()
 		waitErr := shellProc.Cmd.Wait()
 		exitCode = shellProc.Cmd.ExitCode()
 		shellProc.SetWaitErrorAndSignalDone(waitErr)
+		if shellProc.IsDetached() {
+			// the session keeps running, we reattach to it on the next start
+			return
+		}
+		restartDelay = bc.planRestart(exitCode, time.Since(runStartTs))
+		if restartDelay >= 0 {
+			// restarting takes precedence over cmd:closeonexit
+			return
+		}
 		go checkCloseOnExit(bc.BlockId, exitCode)
 	}()
 	return nil
@@ -837,15 +923,14 @@ func setTermSizeInDB(blockId string, termSize waveobj.TermSize) error {
 	ctx, cancelFn := context.WithTimeout(context.Background(), 2*time.Second)
 	defer cancelFn()
 	ctx = waveobj.ContextWithUpdates(ctx)
-	bdata, err := wstore.DBMustGet[*waveobj.Block](ctx, blockId)
-	if err != nil {
-		return fmt.Errorf("error getting block data: %v", err)
-	}
-	if bdata.RuntimeOpts == nil {
-		bdata.RuntimeOpts = &waveobj.RuntimeOpts{}
-	}
-	bdata.RuntimeOpts.TermSize = termSize
-	err = wstore.DBUpdate(ctx, bdata)
+	_, err := wstore.UpdateObject(ctx, waveobj.MakeORef(waveobj.OType_Block, blockId), func(obj waveobj.WaveObj) error {
+		bdata := obj.(*waveobj.Block)
+		if bdata.RuntimeOpts == nil {
+			bdata.RuntimeOpts = &waveobj.RuntimeOpts{}
+		}
+		bdata.RuntimeOpts.TermSize = termSize
+		return nil
+	})
 	if err != nil {
 		return fmt.Errorf("error updating block data: %v", err)
 	}
@@ -939,6 +1024,14 @@ func (bc *BlockController) SendInput(inputUnion *BlockInputUnion) error {
 	if len(inputUnion.InputData) > 0 {
 		log.Printf("📤 发送命令到 shell channel (BlockId: %s): %q", bc.BlockId, string(inputUnion.InputData))
 	}
+	if rec := bc.getRecorder(); rec != nil {
+		if len(inputUnion.InputData) > 0 {
+			rec.writeInput(inputUnion.InputData)
+		}
+		if inputUnion.TermSize != nil {
+			rec.writeResize(*inputUnion.TermSize)
+		}
+	}
 	shellInputCh <- inputUnion
 	return nil
 }
@@ -974,6 +1067,7 @@ func CheckConnStatus(blockId string) error {
 }
 
 func (bc *BlockController) StopShellProc(shouldWait bool) {
+	bc.cancelRestart()
 	bc.Lock.Lock()
 	defer bc.Lock.Unlock()
 	if bc.ShellProc == nil || bc.ShellProcStatus == Status_Done || bc.ShellProcStatus == Status_Init {
@@ -1032,6 +1126,9 @@ func ResyncController(ctx context.Context, tabId string, blockId string, rtOpts
 	}
 	if force {
 		StopBlockController(blockId)
+		if curBc := GetBlockController(blockId); curBc != nil {
+			curBc.resetRestartState()
+		}
 		time.Sleep(100 * time.Millisecond) // TODO see if we can remove this (the "process finished with exit code" message comes out after we start reconnecting otherwise)
 	}
 	connName := blockData.Meta.GetString(waveobj.MetaKey_Connection, "")
@@ -1108,6 +1205,7 @@ func StopBlockControllerAndSetStatus(blockId string, newStatus string) {
 	if bc == nil {
 		return
 	}
+	bc.cancelRestart()
 	if bc.getShellProc() != nil {
 		bc.ShellProc.Close()
 		<-bc.ShellProc.DoneCh
@@ -1137,6 +1235,10 @@ func StopAllBlockControllers() {
 	clist := getControllerList()
 	for _, bc := range clist {
 		if bc.ShellProcStatus == Status_Running {
+			if shellProc := bc.getShellProc(); shellProc != nil && shellProc.Detach() {
+				// persistent sessions keep running
+				continue
+			}
 			go StopBlockController(bc.BlockId)
 		}
 	}
@@ -0,0 +1,460 @@
+// Copyright 2025, Command Line Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package blockcontroller
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/wavetermdev/waveterm/pkg/genconn"
+	"github.com/wavetermdev/waveterm/pkg/panichandler"
+	"github.com/wavetermdev/waveterm/pkg/userinput"
+	"github.com/wavetermdev/waveterm/pkg/util/utilfn"
+	"github.com/wavetermdev/waveterm/pkg/waveobj"
+	"github.com/wavetermdev/waveterm/pkg/wps"
+	"github.com/wavetermdev/waveterm/pkg/wshrpc"
+	"github.com/wavetermdev/waveterm/pkg/wshrpc/wshclient"
+	"github.com/wavetermdev/waveterm/pkg/wstore"
+)
+
+const (
+	InputGroupResyncInterval = 30 * time.Second // the index is kept current from block updates, and resynced this often
+	InputGroupConfirmTimeout = 30 * time.Second
+	inputGroupMaxLine        = 4096
+	inputCtrlU               = 0x15 // clears the line (in shells and readline)
+)
+
+// commands that are confirmed before being sent to a group (when term:inputgroupconfirm is set)
+var destructiveCmdRe = regexp.MustCompile(`(?i)(^|[;&|(]|\bsudo)\s*(` +
+	`rm\s+(\S+\s+)*-[a-z]*[rf]|` +
+	`(shutdown|reboot|halt|poweroff|mkfs\S*|dd|kill|killall|pkill|truncate)(\s|$)|` +
+	`git\s+(push\s+(\S+\s+)*(-f|--force\S*)(\s|$)|reset\s+--hard|clean\s+-[a-z]*f)|` +
+	`drop\s+(table|database|schema)\s)`)
+
+type inputGroupMember struct {
+	blockId string
+	group   string
+	confirm bool
+}
+
+// the group membership from the block meta.  input is sent one keystroke at a time, so the blocks are not read on
+// the input path: the index is loaded once and then kept current from the waveobj:update events for blocks (see
+// StartInputGroups).
+type inputGroupIndex struct {
+	lock    *sync.Mutex
+	loaded  bool
+	groups  map[string][]inputGroupMember // sorted by block id
+	byBlock map[string]inputGroupMember
+}
+
+var inputGroups = &inputGroupIndex{lock: &sync.Mutex{}}
+
+// StartInputGroups loads the input groups and subscribes to block updates (like the scheduler)
+func StartInputGroups(ctx context.Context) error {
+	client := wshclient.GetBareRpcClient()
+	client.EventListener.On(wps.Event_WaveObjUpdate, handleInputGroupBlockUpdate)
+	err := wshclient.EventSubCommand(client, wps.SubscriptionRequest{Event: wps.Event_WaveObjUpdate, AllScopes: true}, nil)
+	if err != nil {
+		return fmt.Errorf("error subscribing to waveobj updates: %w", err)
+	}
+	err = inputGroups.load(ctx)
+	if err != nil {
+		return err
+	}
+	go inputGroupResyncLoop()
+	return nil
+}
+
+// block updates can be missed (e.g. before the subscription), so the index is also resynced periodically
+func inputGroupResyncLoop() {
+	defer func() {
+		panichandler.PanicHandler("blockcontroller:inputGroupResyncLoop", recover())
+	}()
+	for {
+		time.Sleep(InputGroupResyncInterval)
+		ctx, cancelFn := context.WithTimeout(context.Background(), DefaultTimeout)
+		err := inputGroups.load(ctx)
+		cancelFn()
+		if err != nil {
+			log.Printf("input groups: %v\n", err)
+		}
+	}
+}
+
+func handleInputGroupBlockUpdate(event *wps.WaveEvent) {
+	var update waveobj.WaveObjUpdate
+	if err := utilfn.ReUnmarshal(&update, event.Data); err != nil || update.OType != waveobj.OType_Block {
+		return
+	}
+	if update.UpdateType == waveobj.UpdateType_Delete {
+		inputGroups.setBlock(update.OID, nil)
+		return
+	}
+	ctx, cancelFn := context.WithTimeout(context.Background(), DefaultTimeout)
+	defer cancelFn()
+	block, err := wstore.DBGet[*waveobj.Block](ctx, update.OID)
+	if err != nil {
+		log.Printf("input groups: error getting block %s: %v\n", update.OID, err)
+		return
+	}
+	inputGroups.setBlock(update.OID, block)
+}
+
+func (idx *inputGroupIndex) load(ctx context.Context) error {
+	blocks, err := wstore.DBGetAllObjsByType[*waveobj.Block](ctx, waveobj.OType_Block)
+	if err != nil {
+		return fmt.Errorf("error loading blocks: %w", err)
+	}
+	idx.lock.Lock()
+	defer idx.lock.Unlock()
+	idx.groups = make(map[string][]inputGroupMember)
+	idx.byBlock = make(map[string]inputGroupMember)
+	for _, block := range blocks {
+		idx.setBlock_nolock(block.OID, block)
+	}
+	idx.loaded = true
+	return nil
+}
+
+// loads the index if StartInputGroups was not called yet
+func (idx *inputGroupIndex) ensureLoaded(ctx context.Context) error {
+	idx.lock.Lock()
+	loaded := idx.loaded
+	idx.lock.Unlock()
+	if loaded {
+		return nil
+	}
+	return idx.load(ctx)
+}
+
+// updates the membership of the block (a nil block was deleted)
+func (idx *inputGroupIndex) setBlock(blockId string, block *waveobj.Block) {
+	idx.lock.Lock()
+	defer idx.lock.Unlock()
+	if !idx.loaded {
+		// the next load reads the block
+		return
+	}
+	idx.setBlock_nolock(blockId, block)
+}
+
+func (idx *inputGroupIndex) setBlock_nolock(blockId string, block *waveobj.Block) {
+	if old, ok := idx.byBlock[blockId]; ok {
+		delete(idx.byBlock, blockId)
+		idx.groups[old.group] = slices.DeleteFunc(slices.Clone(idx.groups[old.group]), func(member inputGroupMember) bool {
+			return member.blockId == blockId
+		})
+		if len(idx.groups[old.group]) == 0 {
+			delete(idx.groups, old.group)
+		}
+	}
+	if block == nil {
+		return
+	}
+	group := block.Meta.GetString(waveobj.MetaKey_TermInputGroup, "")
+	if group == "" {
+		return
+	}
+	member := inputGroupMember{blockId: blockId, group: group, confirm: block.Meta.GetBool(waveobj.MetaKey_TermInputGroupConfirm, false)}
+	members := append(slices.Clone(idx.groups[group]), member)
+	sort.Slice(members, func(i, j int) bool {
+		return members[i].blockId < members[j].blockId
+	})
+	idx.groups[group] = members
+	idx.byBlock[blockId] = member
+}
+
+// returns the group of the block ("" if it isn't in one) and whether it confirms destructive input
+func (idx *inputGroupIndex) lookup(ctx context.Context, blockId string) (string, bool, error) {
+	if err := idx.ensureLoaded(ctx); err != nil {
+		return "", false, err
+	}
+	idx.lock.Lock()
+	defer idx.lock.Unlock()
+	member := idx.byBlock[blockId]
+	return member.group, member.confirm, nil
+}
+
+func (idx *inputGroupIndex) getMembers(ctx context.Context, group string) ([]inputGroupMember, error) {
+	if err := idx.ensureLoaded(ctx); err != nil {
+		return nil, err
+	}
+	idx.lock.Lock()
+	defer idx.lock.Unlock()
+	return idx.groups[group], nil
+}
+
+// tracks the line being typed (best effort, history and cursor movement are not followed) to find destructive input
+type inputLineTracker struct {
+	line []byte
+}
+
+// processes input, returns a description of the destructive input in data ("" if there is none)
+func (lt *inputLineTracker) process(data []byte) string {
+	var reason string
+	setReason := func(r string) {
+		if reason == "" {
+			reason = r
+		}
+	}
+	for idx := 0; idx < len(data); idx++ {
+		ch := data[idx]
+		switch {
+		case ch == 0x1b:
+			// skip escape sequences (arrow keys, etc.)
+			if idx+1 < len(data) && (data[idx+1] == '[' || data[idx+1] == 'O') {
+				idx += 2
+				for idx < len(data) && (data[idx] < 0x40 || data[idx] > 0x7e) {
+					idx++
+				}
+			}
+		case ch == '\r' || ch == '\n':
+			if line := strings.TrimSpace(string(lt.line)); destructiveCmdRe.MatchString(line) {
+				setReason(fmt.Sprintf("%q", line))
+			}
+			lt.line = lt.line[:0]
+		case ch == 0x7f || ch == 0x08:
+			if len(lt.line) > 0 {
+				_, size := utf8.DecodeLastRune(lt.line)
+				lt.line = lt.line[:len(lt.line)-size]
+			}
+		case ch == inputCtrlU:
+			lt.line = lt.line[:0]
+		case ch == 0x03:
+			setReason("Ctrl-C")
+			lt.line = lt.line[:0]
+		case ch == 0x04:
+			setReason("Ctrl-D")
+		case ch == 0x1a:
+			setReason("Ctrl-Z")
+		case ch == 0x1c:
+			setReason("Ctrl-\\")
+		case ch < 0x20:
+		default:
+			if len(lt.line) < inputGroupMaxLine {
+				lt.line = append(lt.line, ch)
+			}
+		}
+	}
+	return reason
+}
+
+type queuedGroupInput struct {
+	sourceBlockId string
+	input         *BlockInputUnion
+	confirm       bool
+}
+
+// input is sent to the members of a group in order, input sent while a confirmation is pending is queued
+type inputGroupSender struct {
+	lock       *sync.Mutex
+	name       string
+	tracker    inputLineTracker
+	confirming bool
+	queue      []queuedGroupInput
+}
+
+var inputGroupSendersLock = &sync.Mutex{}
+var inputGroupSenders = make(map[string]*inputGroupSender)
+
+func getInputGroupSender(group string) *inputGroupSender {
+	inputGroupSendersLock.Lock()
+	defer inputGroupSendersLock.Unlock()
+	sender := inputGroupSenders[group]
+	if sender == nil {
+		sender = &inputGroupSender{lock: &sync.Mutex{}, name: group}
+		inputGroupSenders[group] = sender
+	}
+	return sender
+}
+
+// sends input to all running members of the group except sourceBlockId
+func (s *inputGroupSender) sendToMembers_nolock(ctx context.Context, sourceBlockId string, input *BlockInputUnion) int {
+	members, err := inputGroups.getMembers(ctx, s.name)
+	if err != nil {
+		log.Printf("input group %s: %v\n", s.name, err)
+		return 0
+	}
+	var numSent int
+	for _, member := range members {
+		if member.blockId == sourceBlockId {
+			continue
+		}
+		bc := GetBlockController(member.blockId)
+		if bc == nil {
+			continue
+		}
+		if bc.SendInput(input) == nil {
+			numSent++
+		}
+	}
+	return numSent
+}
+
+func (s *inputGroupSender) send(ctx context.Context, sourceBlockId string, input *BlockInputUnion, confirm bool) int {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if s.confirming {
+		// checked when the confirmation is done (the line tracker must see the input in order)
+		s.queue = append(s.queue, queuedGroupInput{sourceBlockId: sourceBlockId, input: input, confirm: confirm})
+		return 0
+	}
+	numSent, _ := s.checkAndSend_nolock(ctx, sourceBlockId, input, confirm)
+	return numSent
+}
+
+// sends the input, or starts a confirmation if it is destructive (returns true if a confirmation was started)
+func (s *inputGroupSender) checkAndSend_nolock(ctx context.Context, sourceBlockId string, input *BlockInputUnion, confirm bool) (int, bool) {
+	reason := s.tracker.process(input.InputData)
+	if input.SigName != "" {
+		reason = "signal " + input.SigName
+	}
+	if confirm && reason != "" {
+		s.confirming = true
+		go s.confirmAndSend(sourceBlockId, input, reason)
+		return 0, true
+	}
+	return s.sendToMembers_nolock(ctx, sourceBlockId, input), false
+}
+
+func (s *inputGroupSender) confirmAndSend(sourceBlockId string, input *BlockInputUnion, reason string) {
+	defer func() {
+		panichandler.PanicHandler("blockcontroller:inputgroup-confirm", recover())
+	}()
+	ctx, cancelFn := context.WithTimeout(genconn.ContextWithConnData(context.Background(), sourceBlockId), InputGroupConfirmTimeout)
+	defer cancelFn()
+	members, _ := inputGroups.getMembers(ctx, s.name)
+	request := &userinput.UserInputRequest{
+		ResponseType: "confirm",
+		Title:        "Input Group",
+		QueryText:    fmt.Sprintf("Send %s to the other %d blocks in input group %q?", reason, max(len(members)-1, 0), s.name),
+		OkLabel:      "Send",
+		CancelLabel:  "Don't Send",
+	}
+	response, err := userinput.GetUserInput(ctx, request)
+	confirmed := err == nil && response != nil && response.Confirm
+	sendCtx, sendCancelFn := context.WithTimeout(context.Background(), DefaultTimeout)
+	defer sendCancelFn()
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if confirmed {
+		s.sendToMembers_nolock(sendCtx, sourceBlockId, input)
+	} else {
+		// the keystrokes before the enter were already sent, clear the line so a later enter doesn't run it
+		s.sendToMembers_nolock(sendCtx, sourceBlockId, &BlockInputUnion{InputData: []byte{inputCtrlU}})
+		s.tracker.line = s.tracker.line[:0]
+	}
+	s.confirming = false
+	// the input that came in while waiting is checked now (it can start another confirmation)
+	queue := s.queue
+	s.queue = nil
+	for idx, queued := range queue {
+		_, confirming := s.checkAndSend_nolock(sendCtx, queued.sourceBlockId, queued.input, queued.confirm)
+		if confirming {
+			s.queue = append(queue[idx+1:], s.queue...)
+			return
+		}
+	}
+}
+
+// SendInputWithGroup sends input to the block, and to the other blocks in its input group (only keystrokes and
+// signals are sent to the group, not resizes)
+func SendInputWithGroup(ctx context.Context, blockId string, inputUnion *BlockInputUnion) error {
+	bc := GetBlockController(blockId)
+	if bc == nil {
+		return fmt.Errorf("block controller not found for block %q", blockId)
+	}
+	err := bc.SendInput(inputUnion)
+	if err != nil || (len(inputUnion.InputData) == 0 && inputUnion.SigName == "") {
+		return err
+	}
+	group, confirm, err := inputGroups.lookup(ctx, blockId)
+	if err != nil {
+		log.Printf("error looking up input group for block %s: %v\n", blockId, err)
+		return nil
+	}
+	if group == "" {
+		return nil
+	}
+	groupInput := &BlockInputUnion{InputData: inputUnion.InputData, SigName: inputUnion.SigName}
+	getInputGroupSender(group).send(ctx, blockId, groupInput, confirm)
+	return nil
+}
+
+// SendInputToGroup sends input to all running blocks in the group
+func SendInputToGroup(ctx context.Context, group string, inputData []byte) (int, error) {
+	members, err := inputGroups.getMembers(ctx, group)
+	if err != nil {
+		return 0, err
+	}
+	if len(members) == 0 {
+		return 0, fmt.Errorf("input group %q not found", group)
+	}
+	return getInputGroupSender(group).send(ctx, "", &BlockInputUnion{InputData: inputData}, false), nil
+}
+
+// ListInputGroups returns all input groups (from the block meta), sorted by name
+func ListInputGroups(ctx context.Context) ([]*wshrpc.InputGroupInfo, error) {
+	err := inputGroups.ensureLoaded(ctx)
+	if err != nil {
+		return nil, err
+	}
+	inputGroups.lock.Lock()
+	groups := inputGroups.groups
+	inputGroups.lock.Unlock()
+	var rtn []*wshrpc.InputGroupInfo
+	for name, members := range groups {
+		info := &wshrpc.InputGroupInfo{Name: name}
+		for _, member := range members {
+			infoMember := &wshrpc.InputGroupMember{BlockId: member.blockId, Confirm: member.confirm}
+			if bc := GetBlockController(member.blockId); bc != nil {
+				infoMember.Status = bc.GetRuntimeStatus().ShellProcStatus
+			}
+			info.Members = append(info.Members, infoMember)
+		}
+		rtn = append(rtn, info)
+	}
+	sort.Slice(rtn, func(i, j int) bool {
+		return rtn[i].Name < rtn[j].Name
+	})
+	return rtn, nil
+}
+
+// SetInputGroup sets (or with an empty group, clears) the term:inputgroup meta of the blocks
+func SetInputGroup(ctx context.Context, data wshrpc.CommandInputGroupSetData) error {
+	if len(data.BlockIds) == 0 {
+		return fmt.Errorf("no blocks")
+	}
+	ctx = waveobj.ContextWithUpdates(ctx)
+	metaUpdate := waveobj.MetaMapType{waveobj.MetaKey_TermInputGroup: nil}
+	if data.Group != "" {
+		metaUpdate[waveobj.MetaKey_TermInputGroup] = data.Group
+	}
+	if data.Confirm != nil {
+		metaUpdate[waveobj.MetaKey_TermInputGroupConfirm] = *data.Confirm
+	}
+	for _, blockId := range data.BlockIds {
+		err := wstore.UpdateObjectMeta(ctx, waveobj.MakeORef(waveobj.OType_Block, blockId), metaUpdate, false)
+		if err != nil {
+			return fmt.Errorf("error updating block %s: %w", blockId, err)
+		}
+	}
+	updates := waveobj.ContextGetUpdatesRtn(ctx)
+	// the index is updated right away (the update events are handled asynchronously)
+	for _, update := range updates {
+		if block, ok := update.Obj.(*waveobj.Block); ok {
+			inputGroups.setBlock(block.OID, block)
+		}
+	}
+	wps.Broker.SendUpdateEvents(updates)
+	return nil
+}
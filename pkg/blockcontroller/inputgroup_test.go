@@ -0,0 +1,85 @@
+// Copyright 2025, Command Line Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package blockcontroller
+
+import (
+	"context"
+	"reflect"
+	"sync"
+	"testing"
+
+	"github.com/wavetermdev/waveterm/pkg/waveobj"
+)
+
+func TestInputLineTracker(t *testing.T) {
+	tests := []struct {
+		name   string
+		inputs []string
+		reason string
+	}{
+		{"plain command", []string{"l", "s", " ", "-", "l", "\r"}, ""},
+		{"rm -rf", []string{"rm -rf build", "\r"}, `"rm -rf build"`},
+		{"rm with flag later", []string{"sudo rm foo -r\r"}, `"sudo rm foo -r"`},
+		{"rmdir is fine", []string{"rmdir foo\r"}, ""},
+		{"after pipe", []string{"ps aux | grep x; kill 123\r"}, `"ps aux | grep x; kill 123"`},
+		{"backspace fixes it", []string{"rm -rf /tmp/x", "\x15", "echo hi\r"}, ""},
+		{"backspace utf8", []string{"echo é", "\x7f\x7f\x7f\x7f\x7f\x7f\x7f", "reboot\r"}, `"reboot"`},
+		{"arrow keys skipped", []string{"git push -f", "\x1b[D", "\x1bOA", "\r"}, `"git push -f"`},
+		{"force push", []string{"git push origin main --force-with-lease\r"}, `"git push origin main --force-with-lease"`},
+		{"plain push", []string{"git push origin main\r"}, ""},
+		{"ctrl-c", []string{"\x03"}, "Ctrl-C"},
+		{"ctrl-d", []string{"\x04"}, "Ctrl-D"},
+		{"sql drop", []string{"DROP TABLE users;\r"}, `"DROP TABLE users;"`},
+	}
+	for _, test := range tests {
+		var tracker inputLineTracker
+		var reason string
+		for _, input := range test.inputs {
+			if r := tracker.process([]byte(input)); r != "" {
+				reason = r
+			}
+		}
+		if reason != test.reason {
+			t.Errorf("%s: expected reason %q, got %q", test.name, test.reason, reason)
+		}
+	}
+}
+
+func TestInputGroupIndexUpdates(t *testing.T) {
+	idx := &inputGroupIndex{lock: &sync.Mutex{}, loaded: true, groups: make(map[string][]inputGroupMember), byBlock: make(map[string]inputGroupMember)}
+	makeBlock := func(oid string, group string) *waveobj.Block {
+		return &waveobj.Block{OID: oid, Meta: waveobj.MetaMapType{waveobj.MetaKey_TermInputGroup: group}}
+	}
+	memberIds := func(group string) []string {
+		members, _ := idx.getMembers(context.Background(), group)
+		var rtn []string
+		for _, member := range members {
+			rtn = append(rtn, member.blockId)
+		}
+		return rtn
+	}
+	idx.setBlock("b2", makeBlock("b2", "servers"))
+	idx.setBlock("b1", makeBlock("b1", "servers"))
+	idx.setBlock("b3", makeBlock("b3", "other"))
+	if ids := memberIds("servers"); !reflect.DeepEqual(ids, []string{"b1", "b2"}) {
+		t.Errorf("expected sorted members, got %v", ids)
+	}
+	// moving a block to another group, and deleting one
+	members, _ := idx.getMembers(context.Background(), "servers")
+	idx.setBlock("b2", makeBlock("b2", "other"))
+	idx.setBlock("b3", nil)
+	if ids := memberIds("servers"); !reflect.DeepEqual(ids, []string{"b1"}) {
+		t.Errorf("expected b1 in servers, got %v", ids)
+	}
+	if ids := memberIds("other"); !reflect.DeepEqual(ids, []string{"b2"}) {
+		t.Errorf("expected b2 in other, got %v", ids)
+	}
+	if len(members) != 2 {
+		t.Errorf("the members returned before the update should not change: %v", members)
+	}
+	idx.setBlock("b1", makeBlock("b1", ""))
+	if group, _, _ := idx.lookup(context.Background(), "b1"); group != "" || len(idx.groups) != 1 {
+		t.Errorf("expected b1 to leave its group, got %q %v", group, idx.groups)
+	}
+}
@@ -0,0 +1,211 @@
+// Copyright 2025, Command Line Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package blockcontroller
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/wavetermdev/waveterm/pkg/panichandler"
+	"github.com/wavetermdev/waveterm/pkg/remote"
+	"github.com/wavetermdev/waveterm/pkg/remote/conncontroller"
+	"github.com/wavetermdev/waveterm/pkg/shellexec"
+	"github.com/wavetermdev/waveterm/pkg/waveobj"
+	"github.com/wavetermdev/waveterm/pkg/wconfig"
+	"github.com/wavetermdev/waveterm/pkg/wps"
+	"github.com/wavetermdev/waveterm/pkg/wshrpc"
+	"github.com/wavetermdev/waveterm/pkg/wshrpc/wshclient"
+	"github.com/wavetermdev/waveterm/pkg/wshutil"
+)
+
+const (
+	PortsPollInterval = 2 * time.Second
+	PortsMaxErrors    = 3 // stop watching after this many consecutive errors
+)
+
+func portsDetectEnabled(blockMeta waveobj.MetaMapType) (bool, bool) {
+	settings := wconfig.GetWatcher().GetFullConfig().Settings
+	detectDefault := settings.TermDetectPorts == nil || *settings.TermDetectPorts
+	detect := blockMeta.GetBool(waveobj.MetaKey_TermDetectPorts, detectDefault)
+	open := blockMeta.GetBool(waveobj.MetaKey_TermOpenPorts, settings.TermOpenPorts)
+	return detect, detect && open
+}
+
+// returns the ports in cur that are not in prev
+func diffPorts(prev []*wshrpc.ListeningPort, cur []*wshrpc.ListeningPort) []*wshrpc.ListeningPort {
+	prevPorts := make(map[int]bool)
+	for _, port := range prev {
+		prevPorts[port.Port] = true
+	}
+	var rtn []*wshrpc.ListeningPort
+	for _, port := range cur {
+		if !prevPorts[port.Port] {
+			rtn = append(rtn, port)
+		}
+	}
+	return rtn
+}
+
+func (bc *BlockController) getPorts() []*wshrpc.ListeningPort {
+	var rtn []*wshrpc.ListeningPort
+	bc.WithLock(func() {
+		rtn = bc.ports
+	})
+	return rtn
+}
+
+func (bc *BlockController) publishPorts(connName string, ports []*wshrpc.ListeningPort, opened []*wshrpc.ListeningPort, closed []*wshrpc.ListeningPort) {
+	bc.WithLock(func() {
+		bc.ports = ports
+	})
+	wps.Broker.Publish(wps.WaveEvent{
+		Event:  wps.Event_BlockPorts,
+		Scopes: []string{waveobj.MakeORef(waveobj.OType_Block, bc.BlockId).String()},
+		Data: wshrpc.BlockPortsData{
+			BlockId:    bc.BlockId,
+			Connection: connName,
+			Ports:      ports,
+			Opened:     opened,
+			Closed:     closed,
+		},
+	})
+}
+
+// polls the ports the processes of shellProc are listening on until it exits, and publishes a block:ports event
+// when they change.  if autoOpen is set, a web block is opened for each new port.
+func (bc *BlockController) watchPorts(shellProc *shellexec.ShellProc, autoOpen bool) {
+	defer func() {
+		panichandler.PanicHandler("blockcontroller:watchPorts", recover())
+	}()
+	ctx, cancelFn := context.WithCancel(context.Background())
+	defer cancelFn()
+	go func() {
+		<-shellProc.DoneCh
+		cancelFn()
+	}()
+	var prev []*wshrpc.ListeningPort
+	var hintPid int32
+	var numErrors int
+	autoOpened := make(map[int]bool) // so a restarted server doesn't open another web block
+	defer func() {
+		if len(prev) > 0 {
+			bc.publishPorts(shellProc.ConnName, nil, nil, prev)
+		}
+	}()
+	ticker := time.NewTicker(PortsPollInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return
+		}
+		tree, err := bc.getProcTree(ctx, hintPid, true)
+		if ctx.Err() != nil {
+			return
+		}
+		if err != nil {
+			numErrors++
+			if numErrors >= PortsMaxErrors {
+				log.Printf("block %s: not watching ports: %v\n", bc.BlockId, err)
+				return
+			}
+			continue
+		}
+		numErrors = 0
+		hintPid = tree.RootPid
+		opened := diffPorts(prev, tree.Ports)
+		closed := diffPorts(tree.Ports, prev)
+		if len(opened) == 0 && len(closed) == 0 {
+			continue
+		}
+		prev = tree.Ports
+		bc.publishPorts(shellProc.ConnName, tree.Ports, opened, closed)
+		if !autoOpen {
+			continue
+		}
+		for _, port := range opened {
+			if autoOpened[port.Port] {
+				continue
+			}
+			autoOpened[port.Port] = true
+			data := wshrpc.CommandPortOpenData{BlockId: bc.BlockId, Port: port.Port}
+			_, err := wshclient.PortOpenCommand(wshclient.GetBareRpcClient(), data, &wshrpc.RpcOpts{Route: wshutil.DefaultRoute, NoResponse: true})
+			if err != nil {
+				log.Printf("block %s: error opening port %d: %v\n", bc.BlockId, port.Port, err)
+			}
+		}
+	}
+}
+
+// GetBlockPorts scans the ports the block's processes are listening on now
+func GetBlockPorts(ctx context.Context, blockId string) (*wshrpc.BlockPortsData, error) {
+	bc := GetBlockController(blockId)
+	if bc == nil {
+		return nil, fmt.Errorf("block controller not found: %s", blockId)
+	}
+	tree, err := bc.getProcTree(ctx, 0, true)
+	if err != nil {
+		return nil, err
+	}
+	rtn := &wshrpc.BlockPortsData{BlockId: blockId, Ports: tree.Ports}
+	if shellProc := bc.getShellProc(); shellProc != nil {
+		rtn.Connection = shellProc.ConnName
+	}
+	return rtn, nil
+}
+
+// ListBlockPorts returns the ports of all blocks that are listening on any (as of their last poll)
+func ListBlockPorts() []*wshrpc.BlockPortsData {
+	var rtn []*wshrpc.BlockPortsData
+	for _, bc := range getControllerList() {
+		ports := bc.getPorts()
+		if len(ports) == 0 {
+			continue
+		}
+		data := &wshrpc.BlockPortsData{BlockId: bc.BlockId, Ports: ports}
+		if shellProc := bc.getShellProc(); shellProc != nil {
+			data.Connection = shellProc.ConnName
+		}
+		rtn = append(rtn, data)
+	}
+	return rtn
+}
+
+// GetPortUrl returns a local url for port on the block's host.  for ssh connections the port is forwarded
+// through the connection.
+func GetPortUrl(blockId string, port int) (string, error) {
+	if port <= 0 || port > 65535 {
+		return "", fmt.Errorf("invalid port %d", port)
+	}
+	bc := GetBlockController(blockId)
+	if bc == nil {
+		return "", fmt.Errorf("block controller not found: %s", blockId)
+	}
+	shellProc := bc.getShellProc()
+	if shellProc == nil {
+		return "", fmt.Errorf("block %s is not running", blockId)
+	}
+	connName := shellProc.ConnName
+	if connName == "" || strings.HasPrefix(connName, "wsl://") {
+		// wsl forwards localhost
+		return fmt.Sprintf("http://localhost:%d", port), nil
+	}
+	opts, err := remote.ParseOpts(connName)
+	if err != nil {
+		return "", fmt.Errorf("invalid connection %q: %w", connName, err)
+	}
+	conn := conncontroller.GetConn(opts)
+	if conn == nil {
+		return "", fmt.Errorf("connection %s not found", connName)
+	}
+	localPort, err := conn.ForwardPort(port)
+	if err != nil {
+		return "", fmt.Errorf("error forwarding port %d: %w", port, err)
+	}
+	return fmt.Sprintf("http://127.0.0.1:%d", localPort), nil
+}
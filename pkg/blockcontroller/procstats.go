@@ -0,0 +1,159 @@
+// Copyright 2025, Command Line Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package blockcontroller
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/wavetermdev/waveterm/pkg/panichandler"
+	"github.com/wavetermdev/waveterm/pkg/shellexec"
+	"github.com/wavetermdev/waveterm/pkg/util/procutil"
+	"github.com/wavetermdev/waveterm/pkg/wshrpc"
+	"github.com/wavetermdev/waveterm/pkg/wshrpc/wshclient"
+	"github.com/wavetermdev/waveterm/pkg/wshutil"
+)
+
+const (
+	DefaultProcStatsInterval = time.Second
+	MinProcStatsInterval     = 250 * time.Millisecond
+	ProcStatsSampleTime      = time.Second // time between the two samples used to compute cpu usage in GetBlockProcStats
+)
+
+// samples the process tree under the block's shell.  hintPid is the root pid of the previous sample (0 if unknown).
+// if withPorts is set the tcp ports the processes are listening on are also returned.
+func (bc *BlockController) getProcTree(ctx context.Context, hintPid int32, withPorts bool) (*wshrpc.ProcTreeData, error) {
+	var shellProc *shellexec.ShellProc
+	var status string
+	bc.WithLock(func() {
+		shellProc = bc.ShellProc
+		status = bc.ShellProcStatus
+	})
+	if shellProc == nil || status != Status_Running {
+		return nil, fmt.Errorf("block %s is not running", bc.BlockId)
+	}
+	if shellProc.ConnName != "" {
+		data := wshrpc.CommandRemoteProcTreeData{BlockId: bc.BlockId, Pid: hintPid, Ports: withPorts}
+		opts := &wshrpc.RpcOpts{Route: wshutil.MakeConnectionRouteId(shellProc.ConnName), Timeout: int64(DefaultTimeout / time.Millisecond)}
+		return wshclient.RemoteProcTreeCommand(wshclient.GetBareRpcClient(), data, opts)
+	}
+	var rootPid int32
+	if cmdWrap, ok := shellProc.Cmd.(shellexec.CmdWrap); ok && cmdWrap.Cmd.Process != nil {
+		rootPid = int32(cmdWrap.Cmd.Process.Pid)
+	} else {
+		// persistent sessions run under the session server, so look for the processes started for this block
+		var err error
+		rootPid, err = procutil.FindBlockRootPid(bc.BlockId, hintPid)
+		if err != nil {
+			return nil, err
+		}
+	}
+	tree, err := procutil.GetProcTree(rootPid)
+	if err != nil || !withPorts {
+		return tree, err
+	}
+	tree.Ports, err = procutil.GetListeningPorts(tree.Procs)
+	if err != nil {
+		return nil, err
+	}
+	return tree, nil
+}
+
+// StreamBlockProcStats samples the block's process tree every interval until ctx is done
+func StreamBlockProcStats(ctx context.Context, req wshrpc.BlockProcStatsRequest) chan wshrpc.RespOrErrorUnion[wshrpc.BlockProcStats] {
+	rtn := make(chan wshrpc.RespOrErrorUnion[wshrpc.BlockProcStats], 16)
+	bc := GetBlockController(req.BlockId)
+	if bc == nil {
+		rtn <- wshrpc.RespOrErrorUnion[wshrpc.BlockProcStats]{Error: fmt.Errorf("block controller not found: %s", req.BlockId)}
+		close(rtn)
+		return rtn
+	}
+	interval := DefaultProcStatsInterval
+	if req.Interval > 0 {
+		interval = max(time.Duration(req.Interval)*time.Millisecond, MinProcStatsInterval)
+	}
+	go func() {
+		defer func() {
+			panichandler.PanicHandler("blockcontroller:StreamBlockProcStats", recover())
+		}()
+		defer close(rtn)
+		var prev *wshrpc.ProcTreeData
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for count := 0; req.Count <= 0 || count < req.Count; count++ {
+			var hintPid int32
+			if prev != nil {
+				hintPid = prev.RootPid
+			}
+			tree, err := bc.getProcTree(ctx, hintPid, false)
+			if err != nil {
+				rtn <- wshrpc.RespOrErrorUnion[wshrpc.BlockProcStats]{Error: err}
+				return
+			}
+			if prev != nil && prev.RootPid != tree.RootPid {
+				// the shell was restarted
+				prev = nil
+			}
+			stats := procutil.SummarizeProcTree(bc.BlockId, prev, tree)
+			prev = tree
+			select {
+			case rtn <- wshrpc.RespOrErrorUnion[wshrpc.BlockProcStats]{Response: *stats}:
+			case <-ctx.Done():
+				return
+			}
+			select {
+			case <-ticker.C:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return rtn
+}
+
+type ProcStatsResult struct {
+	Stats *wshrpc.BlockProcStats
+	Err   error
+}
+
+// GetBlockProcStats samples the process trees of the given (running) blocks twice, ProcStatsSampleTime apart, to get their cpu usage
+func GetBlockProcStats(ctx context.Context, blockIds []string) map[string]ProcStatsResult {
+	rtn := make(map[string]ProcStatsResult)
+	var lock sync.Mutex
+	var wg sync.WaitGroup
+	for _, blockId := range blockIds {
+		bc := GetBlockController(blockId)
+		if bc == nil {
+			continue
+		}
+		wg.Add(1)
+		go func() {
+			defer func() {
+				panichandler.PanicHandler("blockcontroller:GetBlockProcStats", recover())
+			}()
+			defer wg.Done()
+			var result ProcStatsResult
+			first, err := bc.getProcTree(ctx, 0, false)
+			if err == nil {
+				select {
+				case <-time.After(ProcStatsSampleTime):
+				case <-ctx.Done():
+				}
+				var second *wshrpc.ProcTreeData
+				second, err = bc.getProcTree(ctx, first.RootPid, false)
+				if err == nil {
+					result.Stats = procutil.SummarizeProcTree(blockId, first, second)
+				}
+			}
+			result.Err = err
+			lock.Lock()
+			rtn[blockId] = result
+			lock.Unlock()
+		}()
+	}
+	wg.Wait()
+	return rtn
+}
@@ -0,0 +1,41 @@
+// Copyright 2025, Command Line Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+//go:build linux
+
+package blockcontroller
+
+import (
+	"context"
+	"os/exec"
+	"sync"
+	"testing"
+
+	"github.com/wavetermdev/waveterm/pkg/shellexec"
+)
+
+// local shells are found by the pid of their command (not through the session server)
+func TestGetProcTreeLocal(t *testing.T) {
+	cmd := exec.Command("sleep", "30")
+	err := cmd.Start()
+	if err != nil {
+		t.Fatalf("error starting sleep: %v", err)
+	}
+	defer func() {
+		cmd.Process.Kill()
+		cmd.Wait()
+	}()
+	bc := &BlockController{
+		Lock:            &sync.Mutex{},
+		BlockId:         "test-block",
+		ShellProc:       &shellexec.ShellProc{Cmd: shellexec.MakeCmdWrap(cmd, nil)},
+		ShellProcStatus: Status_Running,
+	}
+	tree, err := bc.getProcTree(context.Background(), 0, false)
+	if err != nil {
+		t.Fatalf("error getting proc tree: %v", err)
+	}
+	if tree.RootPid != int32(cmd.Process.Pid) || len(tree.Procs) == 0 || tree.Procs[0].Pid != tree.RootPid {
+		t.Errorf("unexpected proc tree: %+v", tree)
+	}
+}
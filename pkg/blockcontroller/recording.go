@@ -0,0 +1,354 @@
+// Copyright 2025, Command Line Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package blockcontroller
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"log"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/wavetermdev/waveterm/pkg/filestore"
+	"github.com/wavetermdev/waveterm/pkg/util/castutil"
+	"github.com/wavetermdev/waveterm/pkg/util/wavefileutil"
+	"github.com/wavetermdev/waveterm/pkg/wavebase"
+	"github.com/wavetermdev/waveterm/pkg/waveobj"
+	"github.com/wavetermdev/waveterm/pkg/wshrpc"
+	"github.com/wavetermdev/waveterm/pkg/wstore"
+)
+
+const MaxCastFileSize = 64 * 1024 * 1024 // recording stops when the cast file reaches this size
+
+// castRecorder writes the pty output (and optionally input) of a block as asciicast v2 events.
+// each event is a json array [elapsed-seconds, code, data] on its own line.
+type castRecorder struct {
+	lock        *sync.Mutex
+	location    string
+	recordInput bool
+	startTime   time.Time
+	size        int64
+	done        bool
+	outTail     []byte // incomplete utf-8 sequence at the end of the last output chunk
+	inTail      []byte
+
+	writeFn func(data []byte) error
+	closeFn func() error
+	nowFn   func() time.Time
+}
+
+func castLocation(blockId string, path string) string {
+	if path != "" {
+		return path
+	}
+	return fmt.Sprintf(wavefileutil.WaveFilePathPattern, blockId, wavebase.BlockFile_Cast)
+}
+
+// makeCastRecorder starts a new recording, replacing any previous recording at the same location
+func makeCastRecorder(blockId string, path string, recordInput bool, termSize waveobj.TermSize) (*castRecorder, error) {
+	rec := &castRecorder{
+		lock:        &sync.Mutex{},
+		location:    castLocation(blockId, path),
+		recordInput: recordInput,
+		nowFn:       time.Now,
+	}
+	if path != "" {
+		fullPath, err := ValidateRecordPath(path)
+		if err != nil {
+			return nil, err
+		}
+		fd, err := openRecordFile(fullPath)
+		if err != nil {
+			return nil, fmt.Errorf("cannot open recording file: %w", err)
+		}
+		rec.writeFn = func(data []byte) error {
+			_, err := fd.Write(data)
+			return err
+		}
+		rec.closeFn = fd.Close
+	} else {
+		ctx, cancelFn := context.WithTimeout(context.Background(), DefaultTimeout)
+		defer cancelFn()
+		err := filestore.WFS.MakeFile(ctx, blockId, wavebase.BlockFile_Cast, nil, wshrpc.FileOpts{Compress: CompressTermFiles()})
+		if errors.Is(err, fs.ErrExist) {
+			err = filestore.WFS.WriteFile(ctx, blockId, wavebase.BlockFile_Cast, nil)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("cannot create recording file: %w", err)
+		}
+		rec.writeFn = func(data []byte) error {
+			ctx, cancelFn := context.WithTimeout(context.Background(), DefaultTimeout)
+			defer cancelFn()
+			return filestore.WFS.AppendData(ctx, blockId, wavebase.BlockFile_Cast, data)
+		}
+		rec.closeFn = func() error { return nil }
+	}
+	err := rec.writeHeader(termSize)
+	if err != nil {
+		rec.closeFn()
+		return nil, fmt.Errorf("cannot write recording header: %w", err)
+	}
+	return rec, nil
+}
+
+// ValidateRecordPath checks a term:recordpath (the meta can come from anywhere, e.g. an imported workspace).  the
+// path must be absolute and, outside of the wave data dir, must not go through symlinks.  returns the full path.
+func ValidateRecordPath(path string) (string, error) {
+	fullPath, err := wavebase.ExpandHomeDir(path)
+	if err != nil {
+		return "", err
+	}
+	if !filepath.IsAbs(fullPath) {
+		return "", fmt.Errorf("recording path %q must be absolute", path)
+	}
+	fullPath = filepath.Clean(fullPath)
+	relPath, err := filepath.Rel(wavebase.GetWaveDataDir(), fullPath)
+	inDataDir := err == nil && filepath.IsLocal(relPath)
+	if !inDataDir {
+		dir := filepath.Dir(fullPath)
+		realDir, err := filepath.EvalSymlinks(dir)
+		if err != nil {
+			return "", fmt.Errorf("invalid recording path %q: %w", path, err)
+		}
+		if realDir != dir {
+			return "", fmt.Errorf("recording path %q must not contain symlinks", path)
+		}
+	}
+	if finfo, err := os.Lstat(fullPath); err == nil && !finfo.Mode().IsRegular() {
+		return "", fmt.Errorf("recording path %q is not a regular file", path)
+	}
+	return fullPath, nil
+}
+
+// opens (or creates) the file for a new recording.  an existing file is only replaced if it is a recording, and it
+// is only truncated after it was checked.
+func openRecordFile(fullPath string) (*os.File, error) {
+	fd, err := os.OpenFile(fullPath, os.O_CREATE|os.O_RDWR, 0600)
+	if err != nil {
+		return nil, err
+	}
+	err = checkRecordFile(fd, fullPath)
+	if err == nil {
+		err = fd.Truncate(0)
+	}
+	if err == nil {
+		_, err = fd.Seek(0, io.SeekStart)
+	}
+	if err != nil {
+		fd.Close()
+		return nil, err
+	}
+	return fd, nil
+}
+
+func checkRecordFile(fd *os.File, fullPath string) error {
+	finfo, err := fd.Stat()
+	if err != nil {
+		return err
+	}
+	// the path could have been replaced (e.g. with a symlink) since it was validated
+	linfo, err := os.Lstat(fullPath)
+	if err != nil {
+		return err
+	}
+	if !finfo.Mode().IsRegular() || !os.SameFile(finfo, linfo) {
+		return fmt.Errorf("%s is not a regular file", fullPath)
+	}
+	if finfo.Size() == 0 {
+		return nil
+	}
+	line, err := bufio.NewReader(io.LimitReader(fd, 64*1024)).ReadBytes('\n')
+	if err != nil && !errors.Is(err, io.EOF) {
+		return err
+	}
+	if _, err := castutil.ParseHeader(bytes.TrimSpace(line)); err != nil {
+		return fmt.Errorf("%s already exists and is not a recording", fullPath)
+	}
+	return nil
+}
+
+func (rec *castRecorder) writeHeader(termSize waveobj.TermSize) error {
+	rec.lock.Lock()
+	defer rec.lock.Unlock()
+	rec.startTime = rec.nowFn()
+	header := castutil.Header{
+		Version:   castutil.CastVersion,
+		Width:     termSize.Cols,
+		Height:    termSize.Rows,
+		Timestamp: rec.startTime.Unix(),
+		Env:       map[string]string{"TERM": "xterm-256color"},
+	}
+	barr, err := json.Marshal(header)
+	if err != nil {
+		return err
+	}
+	return rec.write(append(barr, '\n'))
+}
+
+// must hold lock
+func (rec *castRecorder) write(data []byte) error {
+	if rec.done {
+		return nil
+	}
+	if rec.size+int64(len(data)) > MaxCastFileSize {
+		log.Printf("recording %s reached the max size (%d bytes), stopping\n", rec.location, MaxCastFileSize)
+		rec.done = true
+		return nil
+	}
+	err := rec.writeFn(data)
+	if err != nil {
+		rec.done = true
+		return err
+	}
+	rec.size += int64(len(data))
+	return nil
+}
+
+// must hold lock
+func (rec *castRecorder) writeEvent(code string, data string) {
+	elapsed := float64(rec.nowFn().Sub(rec.startTime).Microseconds()) / 1e6
+	barr, err := json.Marshal(castutil.Event{Time: elapsed, Type: code, Data: data})
+	if err != nil {
+		return
+	}
+	err = rec.write(append(barr, '\n'))
+	if err != nil {
+		log.Printf("error writing recording %s (stopping): %v\n", rec.location, err)
+	}
+}
+
+func (rec *castRecorder) writeData(code string, tail *[]byte, data []byte) {
+	rec.lock.Lock()
+	defer rec.lock.Unlock()
+	if rec.done {
+		return
+	}
+	if len(*tail) > 0 {
+		data = append(*tail, data...)
+	}
+	var complete []byte
+	complete, *tail = castutil.SplitIncompleteUtf8(data)
+	*tail = append([]byte(nil), *tail...)
+	if len(complete) == 0 {
+		return
+	}
+	rec.writeEvent(code, string(complete))
+}
+
+func (rec *castRecorder) writeOutput(data []byte) {
+	rec.writeData(castutil.EventType_Output, &rec.outTail, data)
+}
+
+func (rec *castRecorder) writeInput(data []byte) {
+	if !rec.recordInput {
+		return
+	}
+	rec.writeData(castutil.EventType_Input, &rec.inTail, data)
+}
+
+func (rec *castRecorder) writeResize(termSize waveobj.TermSize) {
+	rec.lock.Lock()
+	defer rec.lock.Unlock()
+	if rec.done {
+		return
+	}
+	rec.writeEvent(castutil.EventType_Resize, fmt.Sprintf("%dx%d", termSize.Cols, termSize.Rows))
+}
+
+func (rec *castRecorder) close() {
+	rec.lock.Lock()
+	defer rec.lock.Unlock()
+	rec.done = true
+	if rec.closeFn != nil {
+		rec.closeFn()
+		rec.closeFn = nil
+	}
+}
+
+func (rec *castRecorder) getSize() int64 {
+	rec.lock.Lock()
+	defer rec.lock.Unlock()
+	return rec.size
+}
+
+func (bc *BlockController) getRecorder() *castRecorder {
+	bc.Lock.Lock()
+	defer bc.Lock.Unlock()
+	return bc.recorder
+}
+
+func (bc *BlockController) startRecording(path string, recordInput bool, termSize waveobj.TermSize) error {
+	rec, err := makeCastRecorder(bc.BlockId, path, recordInput, termSize)
+	if err != nil {
+		return err
+	}
+	var oldRec *castRecorder
+	bc.WithLock(func() {
+		oldRec = bc.recorder
+		bc.recorder = rec
+	})
+	if oldRec != nil {
+		oldRec.close()
+	}
+	return nil
+}
+
+func (bc *BlockController) stopRecording() *castRecorder {
+	var rec *castRecorder
+	bc.WithLock(func() {
+		rec = bc.recorder
+		bc.recorder = nil
+	})
+	if rec != nil {
+		rec.close()
+	}
+	return rec
+}
+
+// StartRecording starts recording a running block.  If the block is not running, the recording
+// starts with the next shell (term:record is set by the caller).
+func StartRecording(ctx context.Context, data wshrpc.CommandRecordStartData) (*wshrpc.CommandRecordRtnData, error) {
+	rtn := &wshrpc.CommandRecordRtnData{Location: castLocation(data.BlockId, data.Path)}
+	bc := GetBlockController(data.BlockId)
+	if bc == nil {
+		return rtn, nil
+	}
+	var running bool
+	bc.WithLock(func() {
+		running = bc.ShellProc != nil && bc.ShellProcStatus == Status_Running
+	})
+	if !running {
+		return rtn, nil
+	}
+	bdata, err := wstore.DBMustGet[*waveobj.Block](ctx, data.BlockId)
+	if err != nil {
+		return nil, fmt.Errorf("error getting block: %w", err)
+	}
+	err = bc.startRecording(data.Path, data.RecordInput, getTermSize(bdata))
+	if err != nil {
+		return nil, err
+	}
+	rtn.Active = true
+	return rtn, nil
+}
+
+func StopRecording(blockId string) (*wshrpc.CommandRecordRtnData, error) {
+	bc := GetBlockController(blockId)
+	if bc == nil {
+		return nil, fmt.Errorf("block %s is not recording", blockId)
+	}
+	rec := bc.stopRecording()
+	if rec == nil {
+		return nil, fmt.Errorf("block %s is not recording", blockId)
+	}
+	return &wshrpc.CommandRecordRtnData{Location: rec.location, Size: rec.getSize()}, nil
+}
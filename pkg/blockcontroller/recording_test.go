@@ -0,0 +1,115 @@
+// Copyright 2025, Command Line Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package blockcontroller
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/wavetermdev/waveterm/pkg/util/castutil"
+	"github.com/wavetermdev/waveterm/pkg/wavebase"
+	"github.com/wavetermdev/waveterm/pkg/waveobj"
+)
+
+func TestCastRecorder(t *testing.T) {
+	var buf bytes.Buffer
+	now := time.Unix(1700000000, 0)
+	rec := &castRecorder{
+		lock:    &sync.Mutex{},
+		writeFn: func(data []byte) error { buf.Write(data); return nil },
+		nowFn:   func() time.Time { return now },
+	}
+	if err := rec.writeHeader(waveobj.TermSize{Rows: 24, Cols: 80}); err != nil {
+		t.Fatal(err)
+	}
+	euro := []byte("€")
+	now = now.Add(500 * time.Millisecond)
+	rec.writeOutput(append([]byte("a"), euro[:2]...))
+	now = now.Add(time.Second)
+	rec.writeOutput(append(euro[2:], 'b'))
+	rec.writeInput([]byte("ls\r")) // not recorded (recordInput is false)
+	rec.writeResize(waveobj.TermSize{Rows: 30, Cols: 100})
+	rec.close()
+	rec.writeOutput([]byte("after close"))
+
+	scanner := bufio.NewScanner(&buf)
+	if !scanner.Scan() {
+		t.Fatal("missing header")
+	}
+	header, err := castutil.ParseHeader(scanner.Bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if header.Width != 80 || header.Height != 24 || header.Timestamp != 1700000000 {
+		t.Errorf("unexpected header: %+v", header)
+	}
+	var events []castutil.Event
+	for scanner.Scan() {
+		var event castutil.Event
+		if err := json.Unmarshal(scanner.Bytes(), &event); err != nil {
+			t.Fatalf("invalid event %q: %v", scanner.Text(), err)
+		}
+		events = append(events, event)
+	}
+	want := []castutil.Event{
+		{Time: 0.5, Type: castutil.EventType_Output, Data: "a"},
+		{Time: 1.5, Type: castutil.EventType_Output, Data: "€b"},
+		{Time: 1.5, Type: castutil.EventType_Resize, Data: "100x30"},
+	}
+	if len(events) != len(want) {
+		t.Fatalf("got %d events, want %d: %+v", len(events), len(want), events)
+	}
+	for i := range want {
+		if events[i] != want[i] {
+			t.Errorf("event %d: got %+v, want %+v", i, events[i], want[i])
+		}
+	}
+}
+
+func TestRecordPath(t *testing.T) {
+	dir, err := filepath.EvalSymlinks(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	oldDataDir := wavebase.DataHome_VarCache
+	wavebase.DataHome_VarCache = filepath.Join(dir, "data")
+	defer func() { wavebase.DataHome_VarCache = oldDataDir }()
+	os.MkdirAll(filepath.Join(dir, "real"), 0700)
+	os.Symlink(filepath.Join(dir, "real"), filepath.Join(dir, "link"))
+	os.WriteFile(filepath.Join(dir, "notes.txt"), []byte("keep me\n"), 0600)
+	os.WriteFile(filepath.Join(dir, "old.cast"), []byte(`{"version":2,"width":80,"height":24}`+"\n[0.5,\"o\",\"old\"]\n"), 0600)
+
+	for _, path := range []string{"rec.cast", filepath.Join(dir, "link", "rec.cast"), dir} {
+		if _, err := ValidateRecordPath(path); err == nil {
+			t.Errorf("expected %q to be rejected", path)
+		}
+	}
+	fullPath, err := ValidateRecordPath(filepath.Join(dir, "notes.txt"))
+	if err != nil {
+		t.Fatalf("error validating path: %v", err)
+	}
+	if _, err := openRecordFile(fullPath); err == nil {
+		t.Errorf("expected an existing non-recording file to be rejected")
+	}
+	if barr, _ := os.ReadFile(fullPath); string(barr) != "keep me\n" {
+		t.Errorf("non-recording file was modified: %q", barr)
+	}
+	for _, name := range []string{"old.cast", "new.cast"} {
+		fd, err := openRecordFile(filepath.Join(dir, name))
+		if err != nil {
+			t.Fatalf("error opening %s: %v", name, err)
+		}
+		fd.Write([]byte("new\n"))
+		fd.Close()
+		if barr, _ := os.ReadFile(filepath.Join(dir, name)); string(barr) != "new\n" {
+			t.Errorf("%s: got %q", name, barr)
+		}
+	}
+}
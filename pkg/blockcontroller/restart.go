@@ -0,0 +1,180 @@
+// Copyright 2025, Command Line Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package blockcontroller
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/wavetermdev/waveterm/pkg/panichandler"
+	"github.com/wavetermdev/waveterm/pkg/wavebase"
+	"github.com/wavetermdev/waveterm/pkg/waveobj"
+)
+
+const (
+	RestartPolicy_No        = "no"
+	RestartPolicy_OnFailure = "on-failure"
+	RestartPolicy_Always    = "always"
+)
+
+const (
+	DefaultRestartDelayMs    = 1000
+	DefaultRestartMaxDelayMs = 60 * 1000
+	DefaultRestartMax        = 10
+	DefaultRestartWindowMs   = 60 * 1000
+)
+
+// restartPolicy is read from the cmd:restart* block meta.
+// a run that lasts longer than Window is considered stable and resets the backoff.  when MaxRestarts
+// consecutive runs fail within the window the block is in a crash loop and is not restarted again.
+type restartPolicy struct {
+	Mode        string
+	Delay       time.Duration
+	MaxDelay    time.Duration
+	MaxRestarts int // 0 for no limit
+	Window      time.Duration
+}
+
+func getRestartPolicy(meta waveobj.MetaMapType) restartPolicy {
+	rtn := restartPolicy{
+		Mode:        meta.GetString(waveobj.MetaKey_CmdRestart, RestartPolicy_No),
+		Delay:       time.Duration(meta.GetFloat(waveobj.MetaKey_CmdRestartDelay, DefaultRestartDelayMs)) * time.Millisecond,
+		MaxDelay:    time.Duration(meta.GetFloat(waveobj.MetaKey_CmdRestartMaxDelay, DefaultRestartMaxDelayMs)) * time.Millisecond,
+		MaxRestarts: meta.GetInt(waveobj.MetaKey_CmdRestartMax, DefaultRestartMax),
+		Window:      time.Duration(meta.GetFloat(waveobj.MetaKey_CmdRestartWindow, DefaultRestartWindowMs)) * time.Millisecond,
+	}
+	if rtn.Delay < 0 {
+		rtn.Delay = 0
+	}
+	if rtn.MaxDelay < rtn.Delay {
+		rtn.MaxDelay = rtn.Delay
+	}
+	if rtn.MaxRestarts < 0 {
+		rtn.MaxRestarts = 0
+	}
+	return rtn
+}
+
+func (p restartPolicy) shouldRestart(exitCode int) bool {
+	switch p.Mode {
+	case RestartPolicy_Always:
+		return true
+	case RestartPolicy_OnFailure:
+		return exitCode != 0
+	}
+	return false
+}
+
+// exponential backoff, failures is the number of consecutive failed runs (starting at 1)
+func (p restartPolicy) backoff(failures int) time.Duration {
+	delay := p.Delay
+	for i := 1; i < failures && delay < p.MaxDelay; i++ {
+		delay *= 2
+	}
+	return min(delay, p.MaxDelay)
+}
+
+type restartState struct {
+	RestartCount  int // total restarts since the block was last started manually
+	Failures      int // consecutive runs shorter than the policy window
+	LastExitCode  int
+	LastExitTs    int64
+	NextRestartTs int64 // set while a restart is pending
+	CrashLoop     bool
+	stopRequested bool // the process was stopped on purpose, don't restart it
+	restartTimer  *time.Timer
+}
+
+// called when the process exits.  returns the delay before restarting, or -1 if the process should not be restarted.
+func (bc *BlockController) planRestart(exitCode int, runTime time.Duration) time.Duration {
+	blockData := bc.getBlockData_noErr()
+	if blockData == nil || bc.ControllerType != BlockController_Cmd {
+		return -1
+	}
+	policy := getRestartPolicy(blockData.Meta)
+	var delay time.Duration = -1
+	var termMsg string
+	bc.WithLock(func() {
+		rs := &bc.restart
+		rs.LastExitCode = exitCode
+		rs.LastExitTs = time.Now().UnixMilli()
+		if rs.stopRequested || !policy.shouldRestart(exitCode) {
+			return
+		}
+		if runTime >= policy.Window {
+			rs.Failures = 0
+		}
+		rs.Failures++
+		if policy.MaxRestarts > 0 && rs.Failures > policy.MaxRestarts {
+			rs.CrashLoop = true
+			termMsg = fmt.Sprintf("crash loop detected (%d restarts within %v), not restarting\r\n", policy.MaxRestarts, policy.Window)
+			return
+		}
+		delay = policy.backoff(rs.Failures)
+		rs.NextRestartTs = time.Now().Add(delay).UnixMilli()
+		termMsg = fmt.Sprintf("restarting in %v (restart %d)\r\n\r\n", delay, rs.RestartCount+1)
+	})
+	if termMsg != "" {
+		HandleAppendBlockFile(bc.BlockId, wavebase.BlockFile_Term, []byte(termMsg))
+	}
+	return delay
+}
+
+func (bc *BlockController) scheduleRestart(delay time.Duration) {
+	bc.WithLock(func() {
+		if bc.restart.restartTimer != nil {
+			bc.restart.restartTimer.Stop()
+		}
+		bc.restart.restartTimer = time.AfterFunc(delay, bc.doRestart)
+	})
+}
+
+func (bc *BlockController) doRestart() {
+	defer func() {
+		panichandler.PanicHandler("blockcontroller:doRestart", recover())
+	}()
+	var restartCount int
+	bc.UpdateControllerAndSendUpdate(func() bool {
+		if bc.restart.restartTimer == nil {
+			// canceled
+			return false
+		}
+		bc.restart.restartTimer = nil
+		bc.restart.NextRestartTs = 0
+		bc.restart.RestartCount++
+		restartCount = bc.restart.RestartCount
+		return true
+	})
+	if restartCount == 0 {
+		return
+	}
+	log.Printf("restarting block %s (restart %d)\n", bc.BlockId, restartCount)
+	err := startBlockController(context.Background(), bc.TabId, bc.BlockId, nil, true)
+	if err != nil {
+		log.Printf("error restarting block %s: %v\n", bc.BlockId, err)
+	}
+}
+
+// cancels any pending restart, and makes sure the running process is not restarted when it exits
+func (bc *BlockController) cancelRestart() {
+	bc.WithLock(func() {
+		bc.restart.stopRequested = true
+		bc.restart.NextRestartTs = 0
+		if bc.restart.restartTimer != nil {
+			bc.restart.restartTimer.Stop()
+			bc.restart.restartTimer = nil
+		}
+	})
+}
+
+// a manual (re)start resets the restart counters
+func (bc *BlockController) resetRestartState() {
+	bc.WithLock(func() {
+		bc.restart.RestartCount = 0
+		bc.restart.Failures = 0
+		bc.restart.CrashLoop = false
+	})
+}
@@ -0,0 +1,39 @@
+// Copyright 2025, Command Line Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package blockcontroller
+
+import (
+	"testing"
+	"time"
+
+	"github.com/wavetermdev/waveterm/pkg/waveobj"
+)
+
+func TestRestartPolicy(t *testing.T) {
+	policy := getRestartPolicy(waveobj.MetaMapType{})
+	if policy.Mode != RestartPolicy_No || policy.shouldRestart(1) {
+		t.Errorf("default policy should not restart: %#v", policy)
+	}
+	policy = getRestartPolicy(waveobj.MetaMapType{
+		waveobj.MetaKey_CmdRestart:         RestartPolicy_OnFailure,
+		waveobj.MetaKey_CmdRestartDelay:    float64(500),
+		waveobj.MetaKey_CmdRestartMaxDelay: float64(3000),
+	})
+	if policy.shouldRestart(0) || !policy.shouldRestart(2) {
+		t.Errorf("on-failure should only restart on non-zero exit codes")
+	}
+	if policy.MaxRestarts != DefaultRestartMax || policy.Window != DefaultRestartWindowMs*time.Millisecond {
+		t.Errorf("unexpected defaults: %#v", policy)
+	}
+	expected := []time.Duration{500 * time.Millisecond, time.Second, 2 * time.Second, 3 * time.Second, 3 * time.Second}
+	for i, want := range expected {
+		if got := policy.backoff(i + 1); got != want {
+			t.Errorf("backoff(%d) = %v, want %v", i+1, got, want)
+		}
+	}
+	policy = getRestartPolicy(waveobj.MetaMapType{waveobj.MetaKey_CmdRestart: RestartPolicy_Always})
+	if !policy.shouldRestart(0) {
+		t.Errorf("always should restart on exit code 0")
+	}
+}
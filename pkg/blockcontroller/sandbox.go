@@ -0,0 +1,86 @@
+// Copyright 2025, Command Line Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package blockcontroller
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/wavetermdev/waveterm/pkg/sandbox"
+	"github.com/wavetermdev/waveterm/pkg/util/shellutil"
+	"github.com/wavetermdev/waveterm/pkg/wavebase"
+	"github.com/wavetermdev/waveterm/pkg/waveobj"
+)
+
+// local shells with shell:sandbox run in a sandbox (see pkg/sandbox).  the home dir, the wave data dir and the
+// runtime dir are hidden, only the project dir (cmd:cwd, or shell:sandboxwritable) is writable, and the wave files
+// the shell needs (wsh and the shell integration) stay visible.  the sandbox can't talk to wavesrv: the wsh socket
+// is hidden, the shell gets no token or jwt (see makeLocalShellCmd), and with the host network the wavesrv tcp
+// ports are blocked.  with the host network, abstract unix sockets (e.g. X11's) can still be reached.
+func makeSandboxOpts(blockMeta waveobj.MetaMapType, cwd string) (*sandbox.Opts, error) {
+	if !blockMeta.GetBool(waveobj.MetaKey_ShellSandbox, false) {
+		return nil, nil
+	}
+	homeDir := wavebase.GetHomeDir()
+	dataDir := wavebase.GetWaveDataDir()
+	opts := &sandbox.Opts{
+		// the data dir (the dbs and the wsh socket) can be outside of the home dir (WAVETERM_DATA_HOME, XDG dirs).
+		// the runtime dir has the session bus, keyring, agent and display sockets.
+		Hide:      append([]string{homeDir, dataDir}, getRuntimeDirs()...),
+		UnsetEnv:  sandboxUnsetEnv,
+		NoNetwork: blockMeta.GetBool(waveobj.MetaKey_ShellSandboxNoNetwork, false),
+	}
+	if !opts.NoNetwork {
+		opts.BlockTcpPorts = wavebase.GetServerPorts()
+	}
+	writable := blockMeta.GetStringList(waveobj.MetaKey_ShellSandboxWritable)
+	if len(writable) == 0 && cwd != "" && cwd != homeDir && cwd != "/" {
+		writable = []string{cwd}
+	}
+	for _, path := range writable {
+		expandedPath, err := expandSandboxPath(path)
+		if err != nil {
+			return nil, err
+		}
+		opts.Writable = append(opts.Writable, expandedPath)
+	}
+	for _, path := range blockMeta.GetStringList(waveobj.MetaKey_ShellSandboxReadOnly) {
+		expandedPath, err := expandSandboxPath(path)
+		if err != nil {
+			return nil, err
+		}
+		opts.ReadOnly = append(opts.ReadOnly, expandedPath)
+	}
+	opts.ReadOnly = append(opts.ReadOnly,
+		filepath.Join(dataDir, shellutil.WaveHomeBinDir),
+		filepath.Join(dataDir, "shell"),
+	)
+	return opts, nil
+}
+
+// the env vars that point to the user's session services (which would be outside of the sandbox)
+var sandboxUnsetEnv = []string{
+	"DBUS_SESSION_BUS_ADDRESS", "SSH_AUTH_SOCK", "SSH_AGENT_PID", "GPG_AGENT_INFO", "GNOME_KEYRING_CONTROL",
+	"DISPLAY", "WAYLAND_DISPLAY", "XAUTHORITY", "PULSE_SERVER", "PIPEWIRE_REMOTE", "XDG_RUNTIME_DIR",
+}
+
+func getRuntimeDirs() []string {
+	rtn := []string{fmt.Sprintf("/run/user/%d", os.Getuid())}
+	if runtimeDir := os.Getenv("XDG_RUNTIME_DIR"); filepath.IsAbs(runtimeDir) && filepath.Clean(runtimeDir) != rtn[0] {
+		rtn = append(rtn, filepath.Clean(runtimeDir))
+	}
+	return rtn
+}
+
+func expandSandboxPath(path string) (string, error) {
+	expandedPath, err := wavebase.ExpandHomeDir(path)
+	if err != nil {
+		return "", err
+	}
+	if !filepath.IsAbs(expandedPath) {
+		return "", fmt.Errorf("shell:sandbox paths must be absolute: %q", path)
+	}
+	return expandedPath, nil
+}
@@ -0,0 +1,47 @@
+// Copyright 2025, Command Line Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package blockcontroller
+
+import (
+	"fmt"
+	"os"
+	"reflect"
+	"slices"
+	"testing"
+
+	"github.com/wavetermdev/waveterm/pkg/wavebase"
+	"github.com/wavetermdev/waveterm/pkg/waveobj"
+)
+
+func TestMakeSandboxOpts(t *testing.T) {
+	opts, err := makeSandboxOpts(waveobj.MetaMapType{}, "/work")
+	if err != nil || opts != nil {
+		t.Fatalf("expected no sandbox, got %v %v", opts, err)
+	}
+	wavebase.AddServerPort(45001)
+	opts, err = makeSandboxOpts(waveobj.MetaMapType{waveobj.MetaKey_ShellSandbox: true}, "/work")
+	if err != nil {
+		t.Fatalf("error making sandbox opts: %v", err)
+	}
+	if !reflect.DeepEqual(opts.Writable, []string{"/work"}) {
+		t.Errorf("unexpected writable paths: %v", opts.Writable)
+	}
+	// the sandbox must not be able to reach wavesrv
+	if slices.Contains(opts.ReadOnly, wavebase.GetDomainSocketName()) {
+		t.Errorf("the wsh socket should not be visible in the sandbox: %v", opts.ReadOnly)
+	}
+	if !slices.Contains(opts.Hide, wavebase.GetWaveDataDir()) {
+		t.Errorf("the data dir should be hidden (it is not always in the home dir): %v", opts.Hide)
+	}
+	if !slices.Contains(opts.Hide, fmt.Sprintf("/run/user/%d", os.Getuid())) || !slices.Contains(opts.UnsetEnv, "SSH_AUTH_SOCK") {
+		t.Errorf("the session sockets should not be reachable: %v %v", opts.Hide, opts.UnsetEnv)
+	}
+	if !slices.Contains(opts.BlockTcpPorts, 45001) {
+		t.Errorf("the wavesrv ports should be blocked: %v", opts.BlockTcpPorts)
+	}
+	opts, _ = makeSandboxOpts(waveobj.MetaMapType{waveobj.MetaKey_ShellSandbox: true, waveobj.MetaKey_ShellSandboxNoNetwork: true}, "/work")
+	if len(opts.BlockTcpPorts) != 0 {
+		t.Errorf("no ports need to be blocked without network: %v", opts.BlockTcpPorts)
+	}
+}
@@ -0,0 +1,311 @@
+// Copyright 2025, Command Line Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package blockcontroller
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io/fs"
+	"regexp"
+
+	"github.com/wavetermdev/waveterm/pkg/filestore"
+	"github.com/wavetermdev/waveterm/pkg/panichandler"
+	"github.com/wavetermdev/waveterm/pkg/util/ansiutil"
+	"github.com/wavetermdev/waveterm/pkg/wavebase"
+	"github.com/wavetermdev/waveterm/pkg/waveobj"
+	"github.com/wavetermdev/waveterm/pkg/wshrpc"
+	"github.com/wavetermdev/waveterm/pkg/wstore"
+)
+
+const DefaultScrollbackMaxMatches = 1000
+
+// scrollback functions work directly on the stored term file (not the frontend xterm.js buffer)
+
+// term files are read (and streamed) in parts of this size
+const scrollbackReadSize = wshrpc.FileChunkSize
+
+// calls fn with the term file in parts (with the file offset of each part).  reads up to the size of the file when
+// it is called, so a block that keeps writing does not keep the read going.
+func readTermFile(ctx context.Context, blockId string, fn func(offset int64, data []byte) error) error {
+	file, err := filestore.WFS.Stat(ctx, blockId, wavebase.BlockFile_Term)
+	if err != nil {
+		return err
+	}
+	var offset int64
+	for offset < file.Size {
+		partOffset, data, err := filestore.WFS.ReadAt(ctx, blockId, wavebase.BlockFile_Term, offset, min(scrollbackReadSize, file.Size-offset))
+		if err != nil {
+			return err
+		}
+		if len(data) == 0 {
+			break
+		}
+		err = fn(partOffset, data)
+		if err != nil {
+			return err
+		}
+		offset = partOffset + int64(len(data))
+	}
+	return nil
+}
+
+// streams the term file in the given format.  each response holds the next part of the output.
+func DumpScrollback(ctx context.Context, blockId string, format string) chan wshrpc.RespOrErrorUnion[wshrpc.CommandScrollbackDumpRtnData] {
+	rtn := make(chan wshrpc.RespOrErrorUnion[wshrpc.CommandScrollbackDumpRtnData], 16)
+	var convertFn func(data []byte) []byte
+	var closeFn func() []byte
+	switch format {
+	case "", wshrpc.ScrollbackFormat_Raw:
+		convertFn = func(data []byte) []byte { return data }
+		closeFn = func() []byte { return nil }
+	case wshrpc.ScrollbackFormat_Text:
+		var conv ansiutil.TextConverter
+		convertFn = conv.Convert
+		closeFn = conv.Close
+	case wshrpc.ScrollbackFormat_Html:
+		conv := ansiutil.MakeHTMLConverter("Wave Terminal Output")
+		convertFn = func(data []byte) []byte { return []byte(conv.Convert(data)) }
+		closeFn = func() []byte { return []byte(conv.Close()) }
+	default:
+		rtn <- wshrpc.RespOrErrorUnion[wshrpc.CommandScrollbackDumpRtnData]{Error: fmt.Errorf("invalid scrollback format %q (must be raw, text, or html)", format)}
+		close(rtn)
+		return rtn
+	}
+	go func() {
+		defer func() {
+			panichandler.PanicHandler("blockcontroller:DumpScrollback", recover())
+		}()
+		defer close(rtn)
+		send := func(offset int64, truncated bool, output []byte) error {
+			resp := wshrpc.CommandScrollbackDumpRtnData{
+				BlockId:   blockId,
+				Offset:    offset,
+				Data64:    base64.StdEncoding.EncodeToString(output),
+				Truncated: truncated,
+			}
+			select {
+			case rtn <- wshrpc.RespOrErrorUnion[wshrpc.CommandScrollbackDumpRtnData]{Response: resp}:
+				return nil
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		}
+		var truncated bool
+		var endOffset int64
+		first := true
+		err := readTermFile(ctx, blockId, func(offset int64, data []byte) error {
+			if first {
+				truncated = offset > 0
+				first = false
+			}
+			endOffset = offset + int64(len(data))
+			output := convertFn(data)
+			if len(output) == 0 {
+				return nil
+			}
+			return send(offset, truncated, output)
+		})
+		if err != nil {
+			rtn <- wshrpc.RespOrErrorUnion[wshrpc.CommandScrollbackDumpRtnData]{Error: fmt.Errorf("error reading term file: %w", err)}
+			return
+		}
+		if output := closeFn(); len(output) > 0 {
+			send(endOffset, truncated, output)
+		}
+	}()
+	return rtn
+}
+
+func getScrollbackGrepBlockIds(ctx context.Context, data wshrpc.CommandScrollbackGrepData) ([]string, error) {
+	switch data.Scope {
+	case "", wshrpc.ScrollbackScope_Block:
+		if data.BlockId == "" {
+			return nil, fmt.Errorf("no block specified")
+		}
+		return []string{data.BlockId}, nil
+	case wshrpc.ScrollbackScope_Tab:
+		if data.TabId == "" {
+			return nil, fmt.Errorf("no tab specified")
+		}
+		tab, err := wstore.DBMustGet[*waveobj.Tab](ctx, data.TabId)
+		if err != nil {
+			return nil, fmt.Errorf("error getting tab: %w", err)
+		}
+		// the tab's blocks and their sub-blocks
+		var rtn []string
+		blockIds := tab.BlockIds
+		for len(blockIds) > 0 {
+			rtn = append(rtn, blockIds...)
+			var subBlockIds []string
+			for _, blockId := range blockIds {
+				block, err := wstore.DBGet[*waveobj.Block](ctx, blockId)
+				if err != nil {
+					return nil, fmt.Errorf("error getting block %s: %w", blockId, err)
+				}
+				if block != nil {
+					subBlockIds = append(subBlockIds, block.SubBlockIds...)
+				}
+			}
+			blockIds = subBlockIds
+		}
+		return rtn, nil
+	case wshrpc.ScrollbackScope_All:
+		blockIds, err := wstore.DBGetAllOIDsByType(ctx, waveobj.OType_Block)
+		if err != nil {
+			return nil, fmt.Errorf("error getting blocks: %w", err)
+		}
+		return blockIds, nil
+	default:
+		return nil, fmt.Errorf("invalid scope %q (must be block, tab, or all)", data.Scope)
+	}
+}
+
+// stops the read of a term file once there are enough matches (with their context)
+var errScrollbackMaxMatches = errors.New("max matches reached")
+
+func GrepScrollback(ctx context.Context, data wshrpc.CommandScrollbackGrepData) ([]*wshrpc.ScrollbackGrepMatch, error) {
+	pattern := data.Pattern
+	if data.IgnoreCase {
+		pattern = "(?i)" + pattern
+	}
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, fmt.Errorf("invalid pattern: %w", err)
+	}
+	blockIds, err := getScrollbackGrepBlockIds(ctx, data)
+	if err != nil {
+		return nil, err
+	}
+	maxMatches := data.MaxMatches
+	if maxMatches <= 0 {
+		maxMatches = DefaultScrollbackMaxMatches
+	}
+	rtn := make([]*wshrpc.ScrollbackGrepMatch, 0)
+	for _, blockId := range blockIds {
+		if len(rtn) >= maxMatches {
+			break
+		}
+		grepper := &scrollbackGrepper{blockId: blockId, re: re, contextLines: data.ContextLines, maxMatches: maxMatches - len(rtn)}
+		err := readTermFile(ctx, blockId, func(offset int64, termData []byte) error {
+			grepper.feed(termData, offset)
+			if grepper.done() {
+				return errScrollbackMaxMatches
+			}
+			return nil
+		})
+		if errors.Is(err, fs.ErrNotExist) {
+			// not a terminal block
+			continue
+		}
+		if err != nil && err != errScrollbackMaxMatches {
+			return nil, fmt.Errorf("error reading term file for block %s: %w", blockId, err)
+		}
+		rtn = append(rtn, grepper.finish()...)
+	}
+	return rtn, nil
+}
+
+type scrollbackLine struct {
+	Text    []byte
+	Offsets []int64
+}
+
+// greps a term file that is fed in parts.  lines are matched once they are complete, the last contextLines lines
+// are kept for the context before a match, and matches wait for the lines after them.
+type scrollbackGrepper struct {
+	blockId      string
+	re           *regexp.Regexp
+	contextLines int
+	maxMatches   int
+	stripper     ansiutil.Stripper
+	curLine      scrollbackLine
+	lineNum      int
+	prevLines    []string
+	matches      []*wshrpc.ScrollbackGrepMatch
+	pending      []*wshrpc.ScrollbackGrepMatch // matches that still need lines after them
+}
+
+func (g *scrollbackGrepper) full() bool {
+	return len(g.matches) >= g.maxMatches
+}
+
+// true once there are enough matches and all of them have their context
+func (g *scrollbackGrepper) done() bool {
+	return g.full() && len(g.pending) == 0
+}
+
+func (g *scrollbackGrepper) feed(termData []byte, baseOffset int64) {
+	text, offsets := g.stripper.Strip(termData, baseOffset)
+	for len(text) > 0 && !g.done() {
+		idx := bytes.IndexByte(text, '\n')
+		if idx == -1 {
+			g.curLine.Text = append(g.curLine.Text, text...)
+			g.curLine.Offsets = append(g.curLine.Offsets, offsets...)
+			return
+		}
+		g.curLine.Text = append(g.curLine.Text, text[:idx]...)
+		g.curLine.Offsets = append(g.curLine.Offsets, offsets[:idx]...)
+		g.addLine(g.curLine)
+		g.curLine = scrollbackLine{}
+		text = text[idx+1:]
+		offsets = offsets[idx+1:]
+	}
+}
+
+// matches the last (unterminated) line and returns the matches
+func (g *scrollbackGrepper) finish() []*wshrpc.ScrollbackGrepMatch {
+	if len(g.curLine.Text) > 0 && !g.done() {
+		g.addLine(g.curLine)
+		g.curLine = scrollbackLine{}
+	}
+	return g.matches
+}
+
+func (g *scrollbackGrepper) addLine(line scrollbackLine) {
+	g.lineNum++
+	lineStr := string(line.Text)
+	if g.contextLines > 0 {
+		for _, match := range g.pending {
+			match.After = append(match.After, lineStr)
+		}
+		for len(g.pending) > 0 && len(g.pending[0].After) >= g.contextLines {
+			g.pending = g.pending[1:]
+		}
+	}
+	if g.full() {
+		return
+	}
+	var lineMatches []*wshrpc.ScrollbackGrepMatch
+	for _, loc := range g.re.FindAllIndex(line.Text, -1) {
+		if len(g.matches)+len(lineMatches) >= g.maxMatches {
+			break
+		}
+		if loc[0] == loc[1] {
+			// skip empty matches
+			continue
+		}
+		match := &wshrpc.ScrollbackGrepMatch{
+			BlockId:     g.blockId,
+			LineNum:     g.lineNum,
+			StartOffset: line.Offsets[loc[0]],
+			EndOffset:   line.Offsets[loc[1]-1] + 1,
+			Line:        lineStr,
+		}
+		if g.contextLines > 0 {
+			match.Before = append([]string(nil), g.prevLines...)
+			g.pending = append(g.pending, match)
+		}
+		lineMatches = append(lineMatches, match)
+	}
+	g.matches = append(g.matches, lineMatches...)
+	if g.contextLines > 0 {
+		g.prevLines = append(g.prevLines, lineStr)
+		if len(g.prevLines) > g.contextLines {
+			g.prevLines = g.prevLines[1:]
+		}
+	}
+}
@@ -0,0 +1,122 @@
+// Copyright 2025, Command Line Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package blockcontroller
+
+import (
+	"context"
+	"encoding/base64"
+	"os"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/wavetermdev/waveterm/pkg/filestore"
+	"github.com/wavetermdev/waveterm/pkg/wavebase"
+	"github.com/wavetermdev/waveterm/pkg/waveobj"
+	"github.com/wavetermdev/waveterm/pkg/wshrpc"
+	"github.com/wavetermdev/waveterm/pkg/wstore"
+)
+
+func TestScrollbackGrepParts(t *testing.T) {
+	termData := []byte("one\r\n\x1b[31mtwo error\x1b[0m\r\nthree\r\nfour error\r\nfive\r\nsix\r\nseven error")
+	re := regexp.MustCompile("error")
+	grep := func(partSize int, maxMatches int) []*wshrpc.ScrollbackGrepMatch {
+		g := &scrollbackGrepper{blockId: "b1", re: re, contextLines: 1, maxMatches: maxMatches}
+		for offset := 0; offset < len(termData) && !g.done(); offset += partSize {
+			g.feed(termData[offset:min(offset+partSize, len(termData))], int64(offset))
+		}
+		return g.finish()
+	}
+	whole := grep(len(termData), 10)
+	if len(whole) != 3 {
+		t.Fatalf("expected 3 matches, got %d", len(whole))
+	}
+	expected := &wshrpc.ScrollbackGrepMatch{BlockId: "b1", LineNum: 2, StartOffset: 14, EndOffset: 19, Line: "two error", Before: []string{"one"}, After: []string{"three"}}
+	if !reflect.DeepEqual(whole[0], expected) {
+		t.Errorf("expected %#v, got %#v", expected, whole[0])
+	}
+	if whole[2].Line != "seven error" || whole[2].After != nil {
+		t.Errorf("unexpected last match %#v", whole[2])
+	}
+	// escape sequences and lines that are split across parts give the same matches
+	for _, partSize := range []int{1, 2, 7} {
+		if parts := grep(partSize, 10); !reflect.DeepEqual(parts, whole) {
+			t.Errorf("part size %d: expected %v, got %v", partSize, whole, parts)
+		}
+	}
+	// the read stops at the max matches, but the last match still gets its context
+	limited := grep(1, 2)
+	if len(limited) != 2 || !reflect.DeepEqual(limited[1].After, []string{"five"}) {
+		t.Errorf("unexpected matches with max 2: %#v", limited)
+	}
+}
+
+func TestScrollbackTabAndCircular(t *testing.T) {
+	wavebase.DataHome_VarCache = t.TempDir()
+	err := os.MkdirAll(filepath.Join(wavebase.DataHome_VarCache, wavebase.WaveDBDir), 0700)
+	if err == nil {
+		err = wstore.InitWStore()
+	}
+	if err == nil {
+		err = filestore.InitFilestore()
+	}
+	if err != nil {
+		t.Fatalf("error initializing dbs: %v", err)
+	}
+	ctx := context.Background()
+	tab := &waveobj.Tab{OID: uuid.NewString(), Meta: waveobj.MetaMapType{}}
+	block := &waveobj.Block{OID: uuid.NewString(), ParentORef: waveobj.MakeORef(waveobj.OType_Tab, tab.OID).String(), Meta: waveobj.MetaMapType{}}
+	subBlock := &waveobj.Block{OID: uuid.NewString(), ParentORef: waveobj.MakeORef(waveobj.OType_Block, block.OID).String(), Meta: waveobj.MetaMapType{}}
+	tab.BlockIds = []string{block.OID}
+	block.SubBlockIds = []string{subBlock.OID}
+	for _, obj := range []waveobj.WaveObj{tab, block, subBlock} {
+		err = wstore.DBInsert(ctx, obj)
+		if err != nil {
+			t.Fatalf("error inserting %s: %v", waveobj.GetOID(obj), err)
+		}
+	}
+	// the sub-block's term file keeps the last 2 parts of 3
+	maxSize := int64(2 * filestore.DefaultPartDataSize)
+	err = filestore.WFS.MakeFile(ctx, subBlock.OID, wavebase.BlockFile_Term, nil, wshrpc.FileOpts{Circular: true, MaxSize: maxSize})
+	if err != nil {
+		t.Fatalf("error making term file: %v", err)
+	}
+	line := strings.Repeat("x", 99) + "\n"
+	termData := []byte(strings.Repeat(line, 3*filestore.DefaultPartDataSize/len(line)) + "needle\n")
+	err = filestore.WFS.AppendData(ctx, subBlock.OID, wavebase.BlockFile_Term, termData)
+	if err != nil {
+		t.Fatalf("error writing term file: %v", err)
+	}
+
+	matches, err := GrepScrollback(ctx, wshrpc.CommandScrollbackGrepData{Scope: wshrpc.ScrollbackScope_Tab, TabId: tab.OID, Pattern: "needle"})
+	if err != nil {
+		t.Fatalf("error searching tab: %v", err)
+	}
+	if len(matches) != 1 || matches[0].BlockId != subBlock.OID || matches[0].StartOffset != int64(len(termData)-7) {
+		t.Fatalf("expected the sub-block to be searched, got %#v", matches)
+	}
+
+	var output []byte
+	var firstOffset int64 = -1
+	for respUnion := range DumpScrollback(ctx, subBlock.OID, wshrpc.ScrollbackFormat_Raw) {
+		if respUnion.Error != nil {
+			t.Fatalf("error dumping scrollback: %v", respUnion.Error)
+		}
+		if firstOffset == -1 {
+			firstOffset = respUnion.Response.Offset
+		}
+		if !respUnion.Response.Truncated {
+			t.Errorf("expected the dump to be truncated")
+		}
+		part, _ := base64.StdEncoding.DecodeString(respUnion.Response.Data64)
+		output = append(output, part...)
+	}
+	kept := termData[len(termData)-int(maxSize):]
+	if firstOffset != int64(len(termData))-maxSize || string(output) != string(kept) {
+		t.Errorf("expected the retained data from offset %d, got %d bytes from offset %d", len(termData)-int(maxSize), len(output), firstOffset)
+	}
+}
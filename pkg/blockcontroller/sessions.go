@@ -0,0 +1,71 @@
+// Copyright 2025, Command Line Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package blockcontroller
+
+import (
+	"context"
+	"log"
+	"runtime"
+
+	"github.com/wavetermdev/waveterm/pkg/sessiond"
+	"github.com/wavetermdev/waveterm/pkg/shellexec"
+	"github.com/wavetermdev/waveterm/pkg/waveobj"
+	"github.com/wavetermdev/waveterm/pkg/wconfig"
+	"github.com/wavetermdev/waveterm/pkg/wstore"
+)
+
+// local shell blocks can run under a persistent session server (see pkg/sessiond), so the shell survives
+// wavesrv restarts.  block meta overrides the term:persistent setting.
+func usePersistentSession(controllerType string, remoteName string, blockMeta waveobj.MetaMapType) bool {
+	if runtime.GOOS == "windows" || controllerType != BlockController_Shell || remoteName != "" {
+		return false
+	}
+	if blockMeta.GetBool(waveobj.MetaKey_ShellSandbox, false) {
+		// the session server would run outside of the sandbox
+		return false
+	}
+	settings := wconfig.GetWatcher().GetFullConfig().Settings
+	def := settings.TermPersistent != nil && *settings.TermPersistent
+	return blockMeta.GetBool(waveobj.MetaKey_TermPersistent, def)
+}
+
+// reattaches to the block's running session, returns nil if there is no running session
+func (bc *BlockController) attachPersistentSession(termSize waveobj.TermSize) *shellexec.ShellProc {
+	if !sessiond.IsAlive(sessiond.GetSocketPath(bc.BlockId)) {
+		// clean up after a session that exited (or was killed) while we weren't attached
+		sessiond.RemoveSession(bc.BlockId)
+		return nil
+	}
+	shellProc, err := shellexec.AttachLocalSessionShellProc(bc.BlockId, termSize)
+	if err != nil {
+		log.Printf("error reattaching to session for block %s: %v\n", bc.BlockId, err)
+		return nil
+	}
+	log.Printf("reattached to persistent session for block %s\n", bc.BlockId)
+	return shellProc
+}
+
+// CleanupOrphanedSessions kills persistent sessions whose blocks no longer exist (e.g. the block was
+// closed while wavesrv was not running).  Called once at startup.
+func CleanupOrphanedSessions(ctx context.Context) {
+	blockIds, err := sessiond.ListSessions()
+	if err != nil {
+		log.Printf("error listing persistent sessions: %v\n", err)
+		return
+	}
+	for _, blockId := range blockIds {
+		block, _ := wstore.DBGet[*waveobj.Block](ctx, blockId)
+		if block != nil {
+			continue
+		}
+		sockPath := sessiond.GetSocketPath(blockId)
+		if client, err := sessiond.Attach(sockPath, 0, 0); err == nil {
+			log.Printf("killing orphaned persistent session for block %s\n", blockId)
+			client.Signal(sessiond.Signal_Kill)
+			client.Close()
+			continue
+		}
+		sessiond.RemoveSession(blockId)
+	}
+}
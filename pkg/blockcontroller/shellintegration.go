@@ -0,0 +1,250 @@
+// Copyright 2025, Command Line Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package blockcontroller
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/wavetermdev/waveterm/pkg/cmdhistory"
+	"github.com/wavetermdev/waveterm/pkg/filestore"
+	"github.com/wavetermdev/waveterm/pkg/panichandler"
+	"github.com/wavetermdev/waveterm/pkg/util/ansiutil"
+	"github.com/wavetermdev/waveterm/pkg/wavebase"
+	"github.com/wavetermdev/waveterm/pkg/wshrpc"
+)
+
+const MaxHistoryCmdTextLen = 16 * 1024
+
+// cmdTracker follows the OSC 133 (prompt/command/exit) and OSC 7 (cwd) shell integration markers in a block's
+// terminal output and records a history entry for each command.
+//
+// markers (injected by the rc files from shellutil.InitRcFiles):
+//
+//	OSC 133;A                   prompt start
+//	OSC 133;C;cmdline_url=...   command started, output follows (cmdline_url is percent-encoded)
+//	OSC 133;D;exitcode          command finished
+//	OSC 7;file://host/path      current directory
+type cmdTracker struct {
+	blockId    string
+	connName   string
+	stripper   ansiutil.Stripper
+	cwd        string
+	cur        *wshrpc.CmdHistoryEntry
+	chunkStart int64 // stripper stream position of the first byte of the current chunk
+	chunkLen   int
+	fileBase   int64 // term file offset of the first byte of the current chunk (-1 until looked up)
+
+	// for testing
+	getTermFileSize func() int64
+	saveEntry       func(entry *wshrpc.CmdHistoryEntry)
+	nowFn           func() time.Time
+}
+
+func makeCmdTracker(blockId string, connName string) *cmdTracker {
+	t := &cmdTracker{
+		blockId:  blockId,
+		connName: connName,
+		nowFn:    time.Now,
+	}
+	t.getTermFileSize = func() int64 {
+		ctx, cancelFn := context.WithTimeout(context.Background(), DefaultTimeout)
+		defer cancelFn()
+		wfile, err := filestore.WFS.Stat(ctx, blockId, wavebase.BlockFile_Term)
+		if err != nil {
+			return 0
+		}
+		return wfile.Size
+	}
+	t.saveEntry = func(entry *wshrpc.CmdHistoryEntry) {
+		go func() {
+			defer func() {
+				panichandler.PanicHandler("cmdTracker:saveEntry", recover())
+			}()
+			ctx, cancelFn := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancelFn()
+			if err := cmdhistory.InsertEntry(ctx, entry); err != nil {
+				log.Printf("error saving command history for block %s: %v\n", entry.BlockId, err)
+			}
+		}()
+	}
+	t.stripper.OnString = t.handleString
+	return t
+}
+
+// processOutput must be called with each chunk of output after it has been appended to the term file
+func (t *cmdTracker) processOutput(data []byte) {
+	t.chunkStart = t.stripper.Pos()
+	t.chunkLen = len(data)
+	t.fileBase = -1
+	t.stripper.Scan(data)
+}
+
+// close records the command that was running when the shell exited (without an exit code)
+func (t *cmdTracker) close() {
+	if t.cur == nil {
+		return
+	}
+	t.finishCmd(nil, t.getTermFileSize())
+}
+
+// converts a stripper stream position to a term file offset
+func (t *cmdTracker) fileOffset(streamPos int64) int64 {
+	if t.fileBase < 0 {
+		// the chunk has already been appended, so it ends at the current file size
+		t.fileBase = t.getTermFileSize() - int64(t.chunkLen)
+	}
+	return t.fileBase + (streamPos - t.chunkStart)
+}
+
+func (t *cmdTracker) handleString(data []byte) {
+	str := string(data)
+	if cwdUrl, ok := strings.CutPrefix(str, "7;"); ok {
+		t.handleCwd(cwdUrl)
+		return
+	}
+	rest, ok := strings.CutPrefix(str, "133;")
+	if !ok || rest == "" {
+		return
+	}
+	var params []string
+	if len(rest) > 1 {
+		params = strings.Split(strings.TrimPrefix(rest[1:], ";"), ";")
+	}
+	switch rest[0] {
+	case 'A':
+		if t.cur != nil {
+			// prompt without a finished marker (e.g. the shell doesn't report exit codes)
+			t.finishCmd(nil, t.fileOffset(t.stripper.SeqStart()))
+		}
+	case 'C':
+		t.startCmd(parseCmdLine(params), t.fileOffset(t.stripper.Pos()+1))
+	case 'D':
+		if t.cur == nil {
+			return
+		}
+		var exitCode *int
+		if len(params) > 0 {
+			if code, err := strconv.Atoi(params[0]); err == nil {
+				exitCode = &code
+			}
+		}
+		t.finishCmd(exitCode, t.fileOffset(t.stripper.SeqStart()))
+	}
+}
+
+func (t *cmdTracker) handleCwd(cwdUrl string) {
+	cwd := cwdUrl
+	if rest, ok := strings.CutPrefix(cwdUrl, "file://"); ok {
+		slashIdx := strings.Index(rest, "/")
+		if slashIdx == -1 {
+			return
+		}
+		cwd = rest[slashIdx:]
+	}
+	if unescaped, err := url.PathUnescape(cwd); err == nil {
+		cwd = unescaped
+	}
+	t.cwd = cwd
+}
+
+func parseCmdLine(params []string) string {
+	for _, param := range params {
+		if val, ok := strings.CutPrefix(param, "cmdline_url="); ok {
+			if unescaped, err := url.PathUnescape(val); err == nil {
+				return unescaped
+			}
+			return val
+		}
+		if val, ok := strings.CutPrefix(param, "cmdline="); ok {
+			return val
+		}
+	}
+	return ""
+}
+
+func (t *cmdTracker) startCmd(cmdText string, outputStart int64) {
+	if len(cmdText) > MaxHistoryCmdTextLen {
+		cmdText = cmdText[:MaxHistoryCmdTextLen]
+	}
+	if t.cur != nil {
+		if cmdText == "" {
+			// duplicate marker (e.g. from a second shell integration), keep the current command
+			return
+		}
+		if t.cur.CmdText == "" {
+			t.cur.CmdText = strings.TrimSpace(cmdText)
+			t.cur.OutputStart = outputStart
+			t.cur.OutputEnd = outputStart
+			return
+		}
+		t.finishCmd(nil, t.fileOffset(t.stripper.SeqStart()))
+	}
+	t.cur = &wshrpc.CmdHistoryEntry{
+		HistoryId:   uuid.NewString(),
+		BlockId:     t.blockId,
+		Conn:        t.connName,
+		CmdText:     strings.TrimSpace(cmdText),
+		Cwd:         t.cwd,
+		StartTs:     t.nowFn().UnixMilli(),
+		OutputStart: outputStart,
+		OutputEnd:   outputStart,
+	}
+}
+
+func (t *cmdTracker) finishCmd(exitCode *int, outputEnd int64) {
+	entry := t.cur
+	t.cur = nil
+	entry.EndTs = t.nowFn().UnixMilli()
+	entry.ExitCode = exitCode
+	entry.OutputEnd = max(outputEnd, entry.OutputStart)
+	t.saveEntry(entry)
+}
+
+func HistoryList(ctx context.Context, data wshrpc.CommandHistoryListData) ([]*wshrpc.CmdHistoryEntry, error) {
+	return cmdhistory.ListEntries(ctx, data)
+}
+
+// HistoryGetOutput returns the output of a recorded command, read from the block's term file.
+// Since the term file is circular, the start of the output may have been overwritten (Truncated is set),
+// or the output may be gone entirely.
+func HistoryGetOutput(ctx context.Context, data wshrpc.CommandHistoryGetOutputData) (*wshrpc.CommandHistoryGetOutputRtnData, error) {
+	entry, err := cmdhistory.GetEntry(ctx, data.HistoryId)
+	if err != nil {
+		return nil, err
+	}
+	rtn := &wshrpc.CommandHistoryGetOutputRtnData{Entry: entry}
+	if entry.OutputEnd <= entry.OutputStart {
+		return rtn, nil
+	}
+	offset, output, err := filestore.WFS.ReadAt(ctx, entry.BlockId, wavebase.BlockFile_Term, entry.OutputStart, entry.OutputEnd-entry.OutputStart)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, fmt.Errorf("output is no longer available (block %s has been deleted)", entry.BlockId)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error reading term file: %w", err)
+	}
+	if offset >= entry.OutputEnd {
+		return nil, fmt.Errorf("output is no longer available (it has scrolled out of the stored terminal output)")
+	}
+	rtn.Truncated = offset > entry.OutputStart
+	switch data.Format {
+	case "", wshrpc.ScrollbackFormat_Raw:
+	case wshrpc.ScrollbackFormat_Text:
+		output = ansiutil.Strip(output)
+	default:
+		return nil, fmt.Errorf("invalid format %q (must be raw or text)", data.Format)
+	}
+	rtn.Data64 = base64.StdEncoding.EncodeToString(output)
+	return rtn, nil
+}
@@ -0,0 +1,85 @@
+// Copyright 2025, Command Line Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package blockcontroller
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/wavetermdev/waveterm/pkg/wshrpc"
+)
+
+func makeTestCmdTracker(fileSize *int64, saved *[]*wshrpc.CmdHistoryEntry) *cmdTracker {
+	t := makeCmdTracker("block1", "local")
+	t.getTermFileSize = func() int64 { return *fileSize }
+	t.saveEntry = func(entry *wshrpc.CmdHistoryEntry) { *saved = append(*saved, entry) }
+	t.nowFn = func() time.Time { return time.UnixMilli(1000) }
+	return t
+}
+
+func TestCmdTracker(t *testing.T) {
+	const initialSize = 500 // output written before the tracker started
+	prefix := "\x1b]7;file://host/home/user/my%20dir\x07\x1b]133;A\x07$ "
+	cmdStart := "\x1b]133;C;cmdline_url=ls%20-la\x07"
+	output := "file1\r\nfile2\r\n"
+	cmdEnd := "\x1b]133;D;2\x1b\\"
+	stream := prefix + cmdStart + output + cmdEnd + "\x1b]133;A\x07$ "
+
+	fileSize := int64(initialSize)
+	var saved []*wshrpc.CmdHistoryEntry
+	tracker := makeTestCmdTracker(&fileSize, &saved)
+	// feed in small chunks so the markers are split across chunks
+	for len(stream) > 0 {
+		chunk := stream[:min(5, len(stream))]
+		stream = stream[len(chunk):]
+		fileSize += int64(len(chunk))
+		tracker.processOutput([]byte(chunk))
+	}
+	if len(saved) != 1 {
+		t.Fatalf("expected 1 entry, got %d", len(saved))
+	}
+	entry := saved[0]
+	if entry.CmdText != "ls -la" || entry.Cwd != "/home/user/my dir" || entry.Conn != "local" {
+		t.Errorf("unexpected entry: %+v", entry)
+	}
+	if entry.ExitCode == nil || *entry.ExitCode != 2 {
+		t.Errorf("expected exit code 2, got %v", entry.ExitCode)
+	}
+	wantStart := int64(initialSize + len(prefix) + len(cmdStart))
+	wantEnd := wantStart + int64(len(output))
+	if entry.OutputStart != wantStart || entry.OutputEnd != wantEnd {
+		t.Errorf("output range: got [%d, %d), want [%d, %d)", entry.OutputStart, entry.OutputEnd, wantStart, wantEnd)
+	}
+}
+
+func TestCmdTrackerNoExitCode(t *testing.T) {
+	fileSize := int64(0)
+	var saved []*wshrpc.CmdHistoryEntry
+	tracker := makeTestCmdTracker(&fileSize, &saved)
+	feed := func(str string) {
+		fileSize += int64(len(str))
+		tracker.processOutput([]byte(str))
+	}
+	// a C without a command line followed by one with a command line is merged into one entry
+	feed("\x1b]133;C\x07\x1b]133;C;cmdline_url=make\x07building\r\n")
+	// a new prompt without a D marker finishes the command
+	feed("\x1b]133;A\x07$ \x1b]133;C;cmdline_url=sleep%2010\x07")
+	tracker.close()
+	if len(saved) != 2 {
+		t.Fatalf("expected 2 entries, got %d", len(saved))
+	}
+	if saved[0].CmdText != "make" || saved[0].ExitCode != nil {
+		t.Errorf("unexpected first entry: %+v", saved[0])
+	}
+	if got := int(saved[0].OutputEnd - saved[0].OutputStart); got != len("building\r\n") {
+		t.Errorf("first entry output length: got %d", got)
+	}
+	if saved[1].CmdText != "sleep 10" || saved[1].OutputEnd != fileSize {
+		t.Errorf("unexpected second entry: %+v", saved[1])
+	}
+	if strings.Contains(saved[1].Cwd, "%") {
+		t.Errorf("cwd should be unescaped")
+	}
+}
@@ -0,0 +1,316 @@
+// Copyright 2025, Command Line Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package blockcontroller
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log"
+	"regexp"
+	"time"
+
+	"github.com/wavetermdev/waveterm/pkg/filestore"
+	"github.com/wavetermdev/waveterm/pkg/panichandler"
+	"github.com/wavetermdev/waveterm/pkg/util/ansiutil"
+	"github.com/wavetermdev/waveterm/pkg/util/utilfn"
+	"github.com/wavetermdev/waveterm/pkg/wavebase"
+	"github.com/wavetermdev/waveterm/pkg/waveobj"
+	"github.com/wavetermdev/waveterm/pkg/wps"
+	"github.com/wavetermdev/waveterm/pkg/wshrpc"
+	"github.com/wavetermdev/waveterm/pkg/wshrpc/wshclient"
+	"github.com/wavetermdev/waveterm/pkg/wshutil"
+	"github.com/wavetermdev/waveterm/pkg/wstore"
+)
+
+const (
+	MaxTriggerLineLen        = 4096 // longer lines are matched in pieces
+	TriggerReloadInterval    = 2 * time.Second
+	DefaultTriggerCooldownMs = 1000
+	MaxTriggerMarksFileSize  = 64 * 1024
+)
+
+type compiledTrigger struct {
+	*waveobj.TermTrigger
+	key       string
+	re        *regexp.Regexp
+	fired     bool
+	lastFired time.Time
+}
+
+func (ct *compiledTrigger) cooldown() time.Duration {
+	if ct.Cooldown > 0 {
+		return time.Duration(ct.Cooldown) * time.Millisecond
+	}
+	return DefaultTriggerCooldownMs * time.Millisecond
+}
+
+// triggerEngine matches the term:triggers patterns (block meta + workspace meta) against a block's terminal output.
+// output is matched a line at a time with escape sequences removed, so matches can span chunks.  unterminated
+// lines (e.g. "Password: ") are matched as they arrive, and a trigger fires at most once per line.
+type triggerEngine struct {
+	blockId     string
+	stripper    ansiutil.Stripper
+	triggers    []*compiledTrigger
+	loadedTs    time.Time
+	badPatterns map[string]bool // patterns that failed to compile (so we only log them once)
+	line        []byte
+	linePos     []int64 // stripper stream position of each byte in line
+	lineFired   map[string]bool
+	chunkStart  int64 // stripper stream position of the first byte of the current chunk
+	chunkLen    int
+	fileBase    int64 // term file offset of the first byte of the current chunk (-1 until looked up)
+
+	// for testing
+	loadTriggers    func() []*waveobj.TermTrigger
+	getTermFileSize func() int64
+	fire            func(trig *waveobj.TermTrigger, match *wps.TermTriggerEventData)
+	nowFn           func() time.Time
+}
+
+func makeTriggerEngine(blockId string, loadTriggers func() []*waveobj.TermTrigger, fire func(trig *waveobj.TermTrigger, match *wps.TermTriggerEventData)) *triggerEngine {
+	return &triggerEngine{
+		blockId:      blockId,
+		badPatterns:  make(map[string]bool),
+		lineFired:    make(map[string]bool),
+		loadTriggers: loadTriggers,
+		fire:         fire,
+		nowFn:        time.Now,
+		getTermFileSize: func() int64 {
+			ctx, cancelFn := context.WithTimeout(context.Background(), DefaultTimeout)
+			defer cancelFn()
+			wfile, err := filestore.WFS.Stat(ctx, blockId, wavebase.BlockFile_Term)
+			if err != nil {
+				return 0
+			}
+			return wfile.Size
+		},
+	}
+}
+
+// getMetaTriggers returns the term:triggers from a block or workspace meta
+func getMetaTriggers(meta waveobj.MetaMapType) ([]*waveobj.TermTrigger, error) {
+	if meta[waveobj.MetaKey_TermTriggers] == nil {
+		return nil, nil
+	}
+	var rtn []*waveobj.TermTrigger
+	err := utilfn.ReUnmarshal(&rtn, meta[waveobj.MetaKey_TermTriggers])
+	if err != nil {
+		return nil, fmt.Errorf("invalid term:triggers: %w", err)
+	}
+	return rtn, nil
+}
+
+func triggerKey(trig *waveobj.TermTrigger) string {
+	barr, _ := json.Marshal(trig)
+	return string(barr)
+}
+
+// reloads the triggers from meta (at most every TriggerReloadInterval), keeping the state of unchanged triggers
+func (t *triggerEngine) reload() {
+	now := t.nowFn()
+	if !t.loadedTs.IsZero() && now.Sub(t.loadedTs) < TriggerReloadInterval {
+		return
+	}
+	t.loadedTs = now
+	oldTriggers := make(map[string]*compiledTrigger)
+	for _, ct := range t.triggers {
+		oldTriggers[ct.key] = ct
+	}
+	var newTriggers []*compiledTrigger
+	for _, trig := range t.loadTriggers() {
+		if trig == nil || trig.Pattern == "" {
+			continue
+		}
+		key := triggerKey(trig)
+		if ct := oldTriggers[key]; ct != nil {
+			newTriggers = append(newTriggers, ct)
+			continue
+		}
+		re, err := regexp.Compile(trig.Pattern)
+		if err != nil {
+			if !t.badPatterns[trig.Pattern] {
+				t.badPatterns[trig.Pattern] = true
+				log.Printf("invalid term:triggers pattern %q for block %s: %v\n", trig.Pattern, t.blockId, err)
+			}
+			continue
+		}
+		newTriggers = append(newTriggers, &compiledTrigger{TermTrigger: trig, key: key, re: re})
+	}
+	t.triggers = newTriggers
+}
+
+// processOutput must be called with each chunk of output after it has been appended to the term file
+func (t *triggerEngine) processOutput(data []byte) {
+	t.reload()
+	t.chunkStart = t.stripper.Pos()
+	t.chunkLen = len(data)
+	t.fileBase = -1
+	if len(t.triggers) == 0 {
+		// keep the escape sequence state in sync
+		t.stripper.Scan(data)
+		t.resetLine()
+		return
+	}
+	text, positions := t.stripper.Strip(data, t.chunkStart)
+	for idx, ch := range text {
+		if ch == '\n' {
+			t.checkLine()
+			t.resetLine()
+			continue
+		}
+		t.line = append(t.line, ch)
+		t.linePos = append(t.linePos, positions[idx])
+		if len(t.line) >= MaxTriggerLineLen {
+			t.checkLine()
+			t.resetLine()
+		}
+	}
+	if len(t.line) > 0 {
+		t.checkLine()
+	}
+}
+
+func (t *triggerEngine) resetLine() {
+	t.line = t.line[:0]
+	t.linePos = t.linePos[:0]
+	clear(t.lineFired)
+}
+
+// converts a stripper stream position to a term file offset
+func (t *triggerEngine) fileOffset(streamPos int64) int64 {
+	if t.fileBase < 0 {
+		// the chunk has already been appended, so it ends at the current file size
+		t.fileBase = t.getTermFileSize() - int64(t.chunkLen)
+	}
+	return t.fileBase + (streamPos - t.chunkStart)
+}
+
+func (t *triggerEngine) checkLine() {
+	now := t.nowFn()
+	for _, ct := range t.triggers {
+		if t.lineFired[ct.key] || (ct.Once && ct.fired) {
+			continue
+		}
+		if !ct.lastFired.IsZero() && now.Sub(ct.lastFired) < ct.cooldown() {
+			continue
+		}
+		loc := ct.re.FindSubmatchIndex(t.line)
+		if loc == nil || loc[0] == loc[1] {
+			continue
+		}
+		t.lineFired[ct.key] = true
+		ct.fired = true
+		ct.lastFired = now
+		startOffset := t.fileOffset(t.linePos[loc[0]])
+		match := &wps.TermTriggerEventData{
+			BlockId: t.blockId,
+			Name:    ct.Name,
+			Pattern: ct.Pattern,
+			Action:  ct.Action,
+			Line:    string(t.line),
+			Match:   string(t.line[loc[0]:loc[1]]),
+			Offset:  startOffset,
+			Length:  t.fileOffset(t.linePos[loc[1]-1]) + 1 - startOffset,
+			Ts:      now.UnixMilli(),
+		}
+		template := ct.Message
+		if ct.Action == waveobj.TermTriggerAction_Input {
+			template = ct.Input
+		}
+		if template != "" {
+			match.Text = string(ct.re.Expand(nil, []byte(template), t.line, loc))
+		}
+		t.fire(ct.TermTrigger, match)
+	}
+}
+
+func (bc *BlockController) loadTriggers() []*waveobj.TermTrigger {
+	ctx, cancelFn := context.WithTimeout(context.Background(), DefaultTimeout)
+	defer cancelFn()
+	var rtn []*waveobj.TermTrigger
+	block, err := wstore.DBGet[*waveobj.Block](ctx, bc.BlockId)
+	if err != nil || block == nil {
+		return nil
+	}
+	blockTriggers, err := getMetaTriggers(block.Meta)
+	if err != nil {
+		log.Printf("block %s: %v\n", bc.BlockId, err)
+	}
+	rtn = append(rtn, blockTriggers...)
+	workspaceId, err := wstore.DBFindWorkspaceForTabId(ctx, bc.TabId)
+	if err != nil || workspaceId == "" {
+		return rtn
+	}
+	workspace, err := wstore.DBGet[*waveobj.Workspace](ctx, workspaceId)
+	if err != nil || workspace == nil {
+		return rtn
+	}
+	wsTriggers, err := getMetaTriggers(workspace.Meta)
+	if err != nil {
+		log.Printf("workspace %s: %v\n", workspaceId, err)
+	}
+	return append(rtn, wsTriggers...)
+}
+
+// runs the trigger's action (async, so the pty read loop isn't blocked)
+func (bc *BlockController) fireTrigger(trig *waveobj.TermTrigger, match *wps.TermTriggerEventData) {
+	go func() {
+		defer func() {
+			panichandler.PanicHandler("blockcontroller:fireTrigger", recover())
+		}()
+		var err error
+		switch trig.Action {
+		case waveobj.TermTriggerAction_Notify:
+			title := trig.Name
+			if title == "" {
+				title = "Terminal Trigger"
+			}
+			body := match.Text
+			if body == "" {
+				body = match.Line
+			}
+			notification := wshrpc.WaveNotificationOptions{Title: title, Body: body}
+			err = wshclient.NotifyCommand(wshclient.GetBareRpcClient(), notification, &wshrpc.RpcOpts{Route: wshutil.ElectronRoute, Timeout: 2000})
+		case waveobj.TermTriggerAction_Event:
+			wps.Broker.Publish(wps.WaveEvent{
+				Event: wps.Event_TermTrigger,
+				Scopes: []string{
+					waveobj.MakeORef(waveobj.OType_Block, bc.BlockId).String(),
+					waveobj.MakeORef(waveobj.OType_Tab, bc.TabId).String(),
+				},
+				Data: match,
+			})
+		case waveobj.TermTriggerAction_Mark:
+			err = appendTriggerMark(bc.BlockId, match)
+		case waveobj.TermTriggerAction_Input:
+			if match.Text == "" {
+				return
+			}
+			err = bc.SendInput(&BlockInputUnion{InputData: []byte(match.Text)})
+		default:
+			err = fmt.Errorf("unknown action %q", trig.Action)
+		}
+		if err != nil {
+			log.Printf("error running term trigger %q for block %s: %v\n", trig.Pattern, bc.BlockId, err)
+		}
+	}()
+}
+
+// marks are stored as json lines in a circular "marks" file next to the term file
+func appendTriggerMark(blockId string, match *wps.TermTriggerEventData) error {
+	ctx, cancelFn := context.WithTimeout(context.Background(), DefaultTimeout)
+	defer cancelFn()
+	err := filestore.WFS.MakeFile(ctx, blockId, wavebase.BlockFile_Marks, nil, wshrpc.FileOpts{MaxSize: MaxTriggerMarksFileSize, Circular: true})
+	if err != nil && !errors.Is(err, fs.ErrExist) {
+		return fmt.Errorf("cannot create marks file: %w", err)
+	}
+	barr, err := json.Marshal(match)
+	if err != nil {
+		return err
+	}
+	return HandleAppendBlockFile(blockId, wavebase.BlockFile_Marks, append(barr, '\n'))
+}
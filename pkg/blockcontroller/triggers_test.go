@@ -0,0 +1,116 @@
+// Copyright 2025, Command Line Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package blockcontroller
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/wavetermdev/waveterm/pkg/waveobj"
+	"github.com/wavetermdev/waveterm/pkg/wps"
+)
+
+type triggerTestEnv struct {
+	engine   *triggerEngine
+	fileSize int64
+	now      time.Time
+	fired    []*wps.TermTriggerEventData
+	stream   string
+}
+
+func makeTriggerTestEnv(triggers []*waveobj.TermTrigger, initialSize int64) *triggerTestEnv {
+	env := &triggerTestEnv{fileSize: initialSize, now: time.UnixMilli(1000)}
+	loadFn := func() []*waveobj.TermTrigger { return triggers }
+	fireFn := func(trig *waveobj.TermTrigger, match *wps.TermTriggerEventData) {
+		env.fired = append(env.fired, match)
+	}
+	env.engine = makeTriggerEngine("block1", loadFn, fireFn)
+	env.engine.getTermFileSize = func() int64 { return env.fileSize }
+	env.engine.nowFn = func() time.Time { return env.now }
+	return env
+}
+
+// feeds the output in small chunks so patterns and escape sequences are split across chunks
+func (env *triggerTestEnv) feed(output string, chunkSize int) {
+	env.stream += output
+	for len(output) > 0 {
+		chunk := output[:min(chunkSize, len(output))]
+		output = output[len(chunk):]
+		env.fileSize += int64(len(chunk))
+		env.engine.processOutput([]byte(chunk))
+	}
+}
+
+func TestTriggerSplitAndAnsi(t *testing.T) {
+	const initialSize = 100
+	env := makeTriggerTestEnv([]*waveobj.TermTrigger{
+		{Name: "build", Pattern: `BUILD (SUCCESSFUL|FAILED)`, Action: waveobj.TermTriggerAction_Notify, Message: "build $1"},
+	}, initialSize)
+	env.feed("compiling...\r\n\x1b[1;32mBUILD \x1b[0mSUCC\x1b]0;title\x07ESSFUL\x1b[0m in 3s\r\n", 3)
+	if len(env.fired) != 1 {
+		t.Fatalf("expected 1 match, got %d", len(env.fired))
+	}
+	match := env.fired[0]
+	if match.Match != "BUILD SUCCESSFUL" || match.Text != "build SUCCESSFUL" || !strings.HasPrefix(match.Line, "BUILD SUCCESSFUL") {
+		t.Errorf("bad match: %#v", match)
+	}
+	matchStart := match.Offset - initialSize
+	matchEnd := matchStart + match.Length
+	if env.stream[matchStart:matchStart+5] != "BUILD" || env.stream[matchEnd-3:matchEnd] != "FUL" {
+		t.Errorf("bad match offsets: %q", env.stream[matchStart:matchEnd])
+	}
+}
+
+func TestTriggerPartialLine(t *testing.T) {
+	env := makeTriggerTestEnv([]*waveobj.TermTrigger{
+		{Pattern: `(?i)password( for \w+)?: *$`, Action: waveobj.TermTriggerAction_Input, Input: "secret\n"},
+	}, 0)
+	env.feed("[sudo] pass", 4)
+	if len(env.fired) != 0 {
+		t.Fatalf("fired too early")
+	}
+	env.feed("word for mike: ", 4)
+	if len(env.fired) != 1 || env.fired[0].Text != "secret\n" {
+		t.Fatalf("expected input trigger to fire once on the prompt, got %d", len(env.fired))
+	}
+	// more output on the same line doesn't fire again
+	env.now = env.now.Add(time.Hour)
+	env.feed("\r\n", 1)
+	if len(env.fired) != 1 {
+		t.Errorf("trigger fired twice for the same line")
+	}
+}
+
+func TestTriggerCooldownAndOnce(t *testing.T) {
+	env := makeTriggerTestEnv([]*waveobj.TermTrigger{
+		{Pattern: `ERROR`, Action: waveobj.TermTriggerAction_Mark, Cooldown: 500},
+		{Pattern: `started`, Action: waveobj.TermTriggerAction_Event, Once: true},
+	}, 0)
+	env.feed("ERROR one\nERROR two\nstarted\n", 7)
+	if len(env.fired) != 2 {
+		t.Fatalf("expected 2 matches (cooldown), got %d", len(env.fired))
+	}
+	env.now = env.now.Add(time.Second)
+	env.feed("ERROR three\nstarted\n", 7)
+	if len(env.fired) != 3 || !strings.HasPrefix(env.fired[2].Line, "ERROR t") {
+		t.Fatalf("expected ERROR three after the cooldown, got %d matches", len(env.fired))
+	}
+}
+
+func TestGetMetaTriggers(t *testing.T) {
+	meta := waveobj.MetaMapType{
+		waveobj.MetaKey_TermTriggers: []any{
+			map[string]any{"pattern": "ERROR", "action": "notify", "cooldown": 2000.0},
+		},
+	}
+	triggers, err := getMetaTriggers(meta)
+	if err != nil || len(triggers) != 1 || triggers[0].Pattern != "ERROR" || triggers[0].Cooldown != 2000 {
+		t.Fatalf("bad triggers: %v %v", triggers, err)
+	}
+	_, err = getMetaTriggers(waveobj.MetaMapType{waveobj.MetaKey_TermTriggers: "ERROR"})
+	if err == nil {
+		t.Errorf("expected an error for a non-array term:triggers")
+	}
+}
@@ -0,0 +1,142 @@
+// Copyright 2025, Command Line Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+// persistent store for commands recorded from shell integration markers
+package cmdhistory
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"sync/atomic"
+
+	"github.com/wavetermdev/waveterm/pkg/wshrpc"
+	"github.com/wavetermdev/waveterm/pkg/wstore"
+)
+
+const (
+	MaxHistoryEntries = 20000 // oldest entries are removed past this limit
+	PruneInterval     = 500   // inserts between prunes (so the history can briefly be this much over the limit)
+	DefaultListLimit  = 100
+	MaxListLimit      = 10000
+)
+
+const historyTable = "db_cmdhistory"
+
+var numInserts atomic.Int64
+
+// inserts the entry.  the history is pruned on the first insert and then every PruneInterval inserts.
+// the command and the cwd are encrypted when database encryption is on (see wstore.EncodeColumn).
+func InsertEntry(ctx context.Context, entry *wshrpc.CmdHistoryEntry) error {
+	cmdText, err := wstore.EncodeColumn(historyTable, "cmdtext", entry.HistoryId, entry.CmdText)
+	if err != nil {
+		return err
+	}
+	cwd, err := wstore.EncodeColumn(historyTable, "cwd", entry.HistoryId, entry.Cwd)
+	if err != nil {
+		return err
+	}
+	prune := numInserts.Add(1)%PruneInterval == 1
+	return wstore.WithTx(ctx, func(tx *wstore.TxWrap) error {
+		query := `INSERT INTO db_cmdhistory (historyid, blockid, conn, cmdtext, cwd, startts, endts, exitcode, outputstart, outputend)
+                                      VALUES (        ?,       ?,    ?,       ?,   ?,       ?,     ?,        ?,           ?,         ?)`
+		tx.Exec(query, entry.HistoryId, entry.BlockId, entry.Conn, cmdText, cwd, entry.StartTs, entry.EndTs, entry.ExitCode, entry.OutputStart, entry.OutputEnd)
+		if prune {
+			pruneEntries(tx)
+		}
+		return nil
+	})
+}
+
+// removes the oldest entries past MaxHistoryEntries
+func pruneEntries(tx *wstore.TxWrap) {
+	numEntries := tx.GetInt(`SELECT count(*) FROM db_cmdhistory`)
+	if numEntries <= MaxHistoryEntries {
+		return
+	}
+	query := `DELETE FROM db_cmdhistory WHERE historyid IN (SELECT historyid FROM db_cmdhistory ORDER BY startts LIMIT ?)`
+	tx.Exec(query, numEntries-MaxHistoryEntries)
+}
+
+func GetEntry(ctx context.Context, historyId string) (*wshrpc.CmdHistoryEntry, error) {
+	return wstore.WithTxRtn(ctx, func(tx *wstore.TxWrap) (*wshrpc.CmdHistoryEntry, error) {
+		var rtn wshrpc.CmdHistoryEntry
+		query := `SELECT * FROM db_cmdhistory WHERE historyid = ?`
+		found := tx.Get(&rtn, query, historyId)
+		if !found {
+			return nil, fmt.Errorf("history entry %q not found", historyId)
+		}
+		return &rtn, decodeEntry(&rtn)
+	})
+}
+
+func decodeEntry(entry *wshrpc.CmdHistoryEntry) error {
+	var err error
+	entry.CmdText, err = wstore.DecodeColumn(historyTable, "cmdtext", entry.HistoryId, entry.CmdText)
+	if err != nil {
+		return err
+	}
+	entry.Cwd, err = wstore.DecodeColumn(historyTable, "cwd", entry.HistoryId, entry.Cwd)
+	return err
+}
+
+// ListEntries returns the most recent entries matching the filters, oldest first
+func ListEntries(ctx context.Context, data wshrpc.CommandHistoryListData) ([]*wshrpc.CmdHistoryEntry, error) {
+	limit := data.Limit
+	if limit <= 0 {
+		limit = DefaultListLimit
+	}
+	limit = min(limit, MaxListLimit)
+	var conds []string
+	var args []any
+	if data.BlockId != "" {
+		conds = append(conds, "blockid = ?")
+		args = append(args, data.BlockId)
+	}
+	if data.Conn != "" {
+		conds = append(conds, "conn = ?")
+		args = append(args, data.Conn)
+	}
+	if data.FailedOnly {
+		conds = append(conds, "exitcode IS NOT NULL AND exitcode <> 0")
+	}
+	if data.Since > 0 {
+		conds = append(conds, "startts >= ?")
+		args = append(args, data.Since)
+	}
+	query := `SELECT * FROM db_cmdhistory`
+	if len(conds) > 0 {
+		query += " WHERE " + strings.Join(conds, " AND ")
+	}
+	query += ` ORDER BY startts DESC`
+	// the commands can be encrypted, so the search is done after decoding them (over the whole history)
+	if data.Search == "" {
+		query += ` LIMIT ?`
+		args = append(args, limit)
+	}
+	entries, err := wstore.WithTxRtn(ctx, func(tx *wstore.TxWrap) ([]*wshrpc.CmdHistoryEntry, error) {
+		var rtn []*wshrpc.CmdHistoryEntry
+		tx.Select(&rtn, query, args...)
+		return rtn, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	var rtn []*wshrpc.CmdHistoryEntry
+	for _, entry := range entries {
+		if len(rtn) >= limit {
+			break
+		}
+		err := decodeEntry(entry)
+		if err != nil {
+			return nil, err
+		}
+		if data.Search != "" && !strings.Contains(entry.CmdText, data.Search) {
+			continue
+		}
+		rtn = append(rtn, entry)
+	}
+	slices.Reverse(rtn)
+	return rtn, nil
+}
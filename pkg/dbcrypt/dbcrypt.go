@@ -0,0 +1,367 @@
+// Copyright 2025, Command Line Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+// encryption at rest for the wave databases (filestore data parts and sensitive wstore objects).
+//
+// data is encrypted with AES-256-GCM using random data keys.  the data keys are kept in a keyset which is stored in
+// the OS keyring, sealed with a key derived from a passphrase (WAVETERM_DB_PASSPHRASE), or (for headless machines
+// without a keyring) in a local file that is only readable by the user.  the keys file in the db dir records where the
+// keyset lives.  after a key rotation the old keys stay in the keyset until all of the data has been re-encrypted.
+package dbcrypt
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"sort"
+	"sync"
+)
+
+const (
+	KeySourceAuto       = ""
+	KeySourceKeyring    = "keyring"
+	KeySourcePassphrase = "passphrase"
+	KeySourceFile       = "file"
+)
+
+const PassphraseEnvVar = "WAVETERM_DB_PASSPHRASE"
+
+// encrypted blobs are: version (1 byte) + key id + nonce + ciphertext (with the GCM tag)
+const (
+	blobVersion    = 1
+	keySize        = 32
+	keyIdSize      = 8
+	nonceSize      = 12
+	blobHeaderSize = 1 + keyIdSize + nonceSize
+)
+
+var ErrNoKey = errors.New("database encryption key is not loaded")
+
+type keyId [keyIdSize]byte
+
+type dataKey struct {
+	id   keyId
+	aead cipher.AEAD
+}
+
+type keysetType struct {
+	CurrentId string            `json:"currentid"`
+	Keys      map[string]string `json:"keys"` // hex key id => base64 key
+}
+
+type Status struct {
+	Enabled   bool
+	KeySource string
+	KeyId     string
+	NumKeys   int
+}
+
+var globalLock = &sync.Mutex{}
+var enabled bool
+var curKeySource string
+var curKeyset *keysetType
+var curKey *dataKey
+var allKeys map[keyId]*dataKey
+var passphrase string
+
+func CacheAndRemoveEnvVars() {
+	passphrase = os.Getenv(PassphraseEnvVar)
+	os.Unsetenv(PassphraseEnvVar)
+}
+
+// loads the keyset (if one exists).  when encrypt is set, new data is encrypted (and a keyset is created if needed).
+// keySource is only used when creating a new keyset, an existing keyset is always loaded from where it was stored.
+func Init(encrypt bool, keySource string) error {
+	globalLock.Lock()
+	defer globalLock.Unlock()
+	kf, err := readKeysFile()
+	if err != nil {
+		return err
+	}
+	if kf == nil {
+		if !encrypt {
+			return nil
+		}
+		return createKeyset_nolock(keySource)
+	}
+	if keySource != KeySourceAuto && keySource != kf.KeySource {
+		log.Printf("[dbcrypt] using key source %q (use rotatekey to move the keys to %q)\n", kf.KeySource, keySource)
+	}
+	keyset, err := loadKeyset(kf)
+	if err != nil {
+		return err
+	}
+	err = setKeyset_nolock(kf.KeySource, keyset)
+	if err != nil {
+		return err
+	}
+	enabled = encrypt
+	log.Printf("[dbcrypt] loaded %d database key(s) from %s, encrypt:%v\n", len(keyset.Keys), kf.KeySource, encrypt)
+	return nil
+}
+
+// true if new data should be encrypted
+func Enabled() bool {
+	globalLock.Lock()
+	defer globalLock.Unlock()
+	return enabled
+}
+
+// starts encrypting new data, creating a keyset if needed
+func Enable(keySource string) error {
+	globalLock.Lock()
+	defer globalLock.Unlock()
+	if curKey == nil {
+		err := createKeyset_nolock(keySource)
+		if err != nil {
+			return err
+		}
+	}
+	enabled = true
+	return nil
+}
+
+// stops encrypting new data (the keys stay loaded so existing data can still be read)
+func Disable() {
+	globalLock.Lock()
+	defer globalLock.Unlock()
+	enabled = false
+}
+
+func GetStatus() Status {
+	globalLock.Lock()
+	defer globalLock.Unlock()
+	rtn := Status{Enabled: enabled, KeySource: curKeySource}
+	if curKeyset != nil {
+		rtn.KeyId = curKeyset.CurrentId
+		rtn.NumKeys = len(curKeyset.Keys)
+	}
+	return rtn
+}
+
+// true if the key (hex id) is loaded, so data encrypted with it can be read
+func HasKey(id string) bool {
+	globalLock.Lock()
+	defer globalLock.Unlock()
+	if curKeyset == nil {
+		return false
+	}
+	_, ok := curKeyset.Keys[id]
+	return ok
+}
+
+// returns the (hex) ids of all of the loaded keys, sorted
+func GetKeyIds() []string {
+	globalLock.Lock()
+	defer globalLock.Unlock()
+	if curKeyset == nil {
+		return nil
+	}
+	rtn := make([]string, 0, len(curKeyset.Keys))
+	for id := range curKeyset.Keys {
+		rtn = append(rtn, id)
+	}
+	sort.Strings(rtn)
+	return rtn
+}
+
+// adds a new key and makes it the current key.  the old keys are kept until PruneKeys is called (after all of the data has been re-encrypted).
+// if keySource is set (and different from the current source), the keyset is moved there.
+func RotateKey(keySource string) (string, error) {
+	globalLock.Lock()
+	defer globalLock.Unlock()
+	if curKeyset == nil {
+		return "", ErrNoKey
+	}
+	if keySource == KeySourceAuto {
+		keySource = curKeySource
+	}
+	newId, newKey, err := makeKey()
+	if err != nil {
+		return "", err
+	}
+	keyset := &keysetType{CurrentId: newId, Keys: map[string]string{newId: newKey}}
+	for id, key := range curKeyset.Keys {
+		keyset.Keys[id] = key
+	}
+	oldKeySource := curKeySource
+	err = saveKeyset(keySource, keyset)
+	if err != nil {
+		return "", err
+	}
+	err = setKeyset_nolock(keySource, keyset)
+	if err != nil {
+		return "", err
+	}
+	if oldKeySource == KeySourceKeyring && keySource != KeySourceKeyring {
+		err = keyringDelete(keyringAccount())
+		if err != nil {
+			log.Printf("[dbcrypt] error removing old key from keyring: %v\n", err)
+		}
+	}
+	return newId, nil
+}
+
+// removes all keys except for the current key
+func PruneKeys() error {
+	globalLock.Lock()
+	defer globalLock.Unlock()
+	if curKeyset == nil || len(curKeyset.Keys) <= 1 {
+		return nil
+	}
+	keyset := &keysetType{CurrentId: curKeyset.CurrentId, Keys: map[string]string{curKeyset.CurrentId: curKeyset.Keys[curKeyset.CurrentId]}}
+	err := saveKeyset(curKeySource, keyset)
+	if err != nil {
+		return err
+	}
+	return setKeyset_nolock(curKeySource, keyset)
+}
+
+// encrypts plaintext with the current key.  aad must be the same when decrypting (it binds the blob to its location in the db).
+func Encrypt(plaintext []byte, aad []byte) ([]byte, error) {
+	globalLock.Lock()
+	key := curKey
+	globalLock.Unlock()
+	if key == nil {
+		return nil, ErrNoKey
+	}
+	rtn := make([]byte, blobHeaderSize, blobHeaderSize+len(plaintext)+key.aead.Overhead())
+	rtn[0] = blobVersion
+	copy(rtn[1:], key.id[:])
+	nonce := rtn[1+keyIdSize : blobHeaderSize]
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, fmt.Errorf("error generating nonce: %w", err)
+	}
+	return key.aead.Seal(rtn, nonce, plaintext, aad), nil
+}
+
+func Decrypt(blob []byte, aad []byte) ([]byte, error) {
+	if len(blob) < blobHeaderSize {
+		return nil, fmt.Errorf("encrypted data is too short")
+	}
+	if blob[0] != blobVersion {
+		return nil, fmt.Errorf("unknown encrypted data version %d", blob[0])
+	}
+	var id keyId
+	copy(id[:], blob[1:])
+	globalLock.Lock()
+	key := allKeys[id]
+	globalLock.Unlock()
+	if key == nil {
+		return nil, fmt.Errorf("%w (data was encrypted with key %s)", ErrNoKey, hex.EncodeToString(id[:]))
+	}
+	nonce := blob[1+keyIdSize : blobHeaderSize]
+	rtn, err := key.aead.Open(nil, nonce, blob[blobHeaderSize:], aad)
+	if err != nil {
+		return nil, fmt.Errorf("error decrypting data: %w", err)
+	}
+	return rtn, nil
+}
+
+func makeKey() (string, string, error) {
+	var id keyId
+	key := make([]byte, keySize)
+	if _, err := rand.Read(id[:]); err != nil {
+		return "", "", fmt.Errorf("error generating key id: %w", err)
+	}
+	if _, err := rand.Read(key); err != nil {
+		return "", "", fmt.Errorf("error generating key: %w", err)
+	}
+	return hex.EncodeToString(id[:]), base64.StdEncoding.EncodeToString(key), nil
+}
+
+func makeAEAD(key []byte) (cipher.AEAD, error) {
+	if len(key) != keySize {
+		return nil, fmt.Errorf("invalid key size %d", len(key))
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+func createKeyset_nolock(keySource string) error {
+	id, key, err := makeKey()
+	if err != nil {
+		return err
+	}
+	keyset := &keysetType{CurrentId: id, Keys: map[string]string{id: key}}
+	autoSource := keySource == KeySourceAuto
+	if autoSource {
+		keySource = defaultKeySource()
+	}
+	err = saveKeyset(keySource, keyset)
+	if err != nil && autoSource && keySource == KeySourceKeyring {
+		log.Printf("[dbcrypt] cannot store the database key in the keyring (%v), using a local key file\n", err)
+		keySource = KeySourceFile
+		err = saveKeyset(keySource, keyset)
+	}
+	if err != nil {
+		return err
+	}
+	log.Printf("[dbcrypt] created database key %s (%s)\n", id, keySource)
+	return setKeyset_nolock(keySource, keyset)
+}
+
+func setKeyset_nolock(keySource string, keyset *keysetType) error {
+	keys := make(map[keyId]*dataKey)
+	var current *dataKey
+	for idStr, keyStr := range keyset.Keys {
+		idBytes, err := hex.DecodeString(idStr)
+		if err != nil || len(idBytes) != keyIdSize {
+			return fmt.Errorf("invalid key id %q in keyset", idStr)
+		}
+		keyBytes, err := base64.StdEncoding.DecodeString(keyStr)
+		if err != nil {
+			return fmt.Errorf("invalid key %q in keyset: %w", idStr, err)
+		}
+		aead, err := makeAEAD(keyBytes)
+		if err != nil {
+			return fmt.Errorf("invalid key %q in keyset: %w", idStr, err)
+		}
+		key := &dataKey{aead: aead}
+		copy(key.id[:], idBytes)
+		keys[key.id] = key
+		if idStr == keyset.CurrentId {
+			current = key
+		}
+	}
+	if current == nil {
+		return fmt.Errorf("current key %q not found in keyset", keyset.CurrentId)
+	}
+	curKeySource = keySource
+	curKeyset = keyset
+	curKey = current
+	allKeys = keys
+	return nil
+}
+
+// passphrase if one was given, then the keyring, then a local key file
+func defaultKeySource() string {
+	if passphrase != "" {
+		return KeySourcePassphrase
+	}
+	if keyringAvailable() {
+		return KeySourceKeyring
+	}
+	return KeySourceFile
+}
+
+// for tests
+func reset() {
+	globalLock.Lock()
+	defer globalLock.Unlock()
+	enabled = false
+	curKeySource = ""
+	curKeyset = nil
+	curKey = nil
+	allKeys = nil
+	curKeyringAccount = ""
+}
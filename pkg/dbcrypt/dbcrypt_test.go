@@ -0,0 +1,178 @@
+// Copyright 2025, Command Line Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package dbcrypt
+
+import (
+	"bytes"
+	"os"
+	"strings"
+	"testing"
+)
+
+func initTest(t *testing.T) {
+	keysDir = t.TempDir()
+	argonTime, argonMemory, argonThreads = 1, 64, 1
+	t.Cleanup(func() {
+		reset()
+		keysDir = ""
+		passphrase = ""
+	})
+}
+
+func checkRoundTrip(t *testing.T, blob []byte, aad string, expected string) {
+	plaintext, err := Decrypt(blob, []byte(aad))
+	if err != nil {
+		t.Fatalf("error decrypting: %v", err)
+	}
+	if string(plaintext) != expected {
+		t.Errorf("expected %q, got %q", expected, plaintext)
+	}
+}
+
+func TestEncryptDecrypt(t *testing.T) {
+	initTest(t)
+	_, err := Encrypt([]byte("hello"), nil)
+	if err == nil {
+		t.Fatalf("expected an error encrypting without a key")
+	}
+	err = Enable(KeySourceFile)
+	if err != nil {
+		t.Fatalf("error enabling encryption: %v", err)
+	}
+	blob, err := Encrypt([]byte("hello world"), []byte("aad1"))
+	if err != nil {
+		t.Fatalf("error encrypting: %v", err)
+	}
+	if bytes.Contains(blob, []byte("hello")) {
+		t.Errorf("plaintext found in the encrypted blob")
+	}
+	checkRoundTrip(t, blob, "aad1", "hello world")
+	blob2, _ := Encrypt([]byte("hello world"), []byte("aad1"))
+	if bytes.Equal(blob, blob2) {
+		t.Errorf("expected different nonces")
+	}
+	if _, err := Decrypt(blob, []byte("aad2")); err == nil {
+		t.Errorf("expected an error decrypting with the wrong aad")
+	}
+	blob[len(blob)-1] ^= 1
+	if _, err := Decrypt(blob, []byte("aad1")); err == nil {
+		t.Errorf("expected an error decrypting modified data")
+	}
+	if _, err := Decrypt(blob[:10], []byte("aad1")); err == nil {
+		t.Errorf("expected an error decrypting truncated data")
+	}
+}
+
+func TestKeyFile(t *testing.T) {
+	initTest(t)
+	err := Init(false, KeySourceFile)
+	if err != nil {
+		t.Fatalf("error initializing: %v", err)
+	}
+	if _, err := os.Stat(getKeysFileName()); !os.IsNotExist(err) {
+		t.Fatalf("keys should not be created when encryption is off")
+	}
+	err = Init(true, KeySourceFile)
+	if err != nil {
+		t.Fatalf("error initializing: %v", err)
+	}
+	finfo, err := os.Stat(getKeysFileName())
+	if err != nil {
+		t.Fatalf("error reading keys file: %v", err)
+	}
+	if finfo.Mode().Perm() != 0600 {
+		t.Errorf("expected keys file mode 0600, got %v", finfo.Mode().Perm())
+	}
+	blob, err := Encrypt([]byte("secret"), nil)
+	if err != nil {
+		t.Fatalf("error encrypting: %v", err)
+	}
+	// the keys are loaded on startup even if encryption was turned off, so existing data can be read
+	reset()
+	err = Init(false, KeySourceAuto)
+	if err != nil {
+		t.Fatalf("error initializing: %v", err)
+	}
+	if Enabled() {
+		t.Errorf("encryption should be off")
+	}
+	checkRoundTrip(t, blob, "", "secret")
+}
+
+func TestPassphrase(t *testing.T) {
+	initTest(t)
+	passphrase = "correct horse battery staple"
+	err := Init(true, KeySourceAuto)
+	if err != nil {
+		t.Fatalf("error initializing: %v", err)
+	}
+	status := GetStatus()
+	if status.KeySource != KeySourcePassphrase {
+		t.Errorf("expected the passphrase key source, got %q", status.KeySource)
+	}
+	blob, _ := Encrypt([]byte("secret"), nil)
+	keysFile, _ := os.ReadFile(getKeysFileName())
+	if strings.Contains(string(keysFile), status.KeyId) {
+		t.Errorf("keys file contains the keyset in plaintext")
+	}
+	reset()
+	passphrase = "wrong passphrase"
+	err = Init(true, KeySourceAuto)
+	if err == nil {
+		t.Fatalf("expected an error with the wrong passphrase")
+	}
+	reset()
+	passphrase = ""
+	err = Init(true, KeySourceAuto)
+	if err == nil {
+		t.Fatalf("expected an error without a passphrase")
+	}
+	reset()
+	passphrase = "correct horse battery staple"
+	err = Init(true, KeySourceAuto)
+	if err != nil {
+		t.Fatalf("error initializing: %v", err)
+	}
+	checkRoundTrip(t, blob, "", "secret")
+}
+
+func TestRotateKey(t *testing.T) {
+	initTest(t)
+	err := Enable(KeySourceFile)
+	if err != nil {
+		t.Fatalf("error enabling encryption: %v", err)
+	}
+	oldKeyId := GetStatus().KeyId
+	oldBlob, _ := Encrypt([]byte("old data"), nil)
+	// move the keys from the file to a passphrase while rotating
+	passphrase = "rotate me"
+	newKeyId, err := RotateKey(KeySourcePassphrase)
+	if err != nil {
+		t.Fatalf("error rotating key: %v", err)
+	}
+	status := GetStatus()
+	if newKeyId == oldKeyId || status.KeyId != newKeyId || status.NumKeys != 2 || status.KeySource != KeySourcePassphrase {
+		t.Fatalf("unexpected status after rotating: %+v", status)
+	}
+	newBlob, _ := Encrypt([]byte("new data"), nil)
+	// old data can still be read (also after a restart) until the keys are pruned
+	reset()
+	err = Init(true, KeySourceAuto)
+	if err != nil {
+		t.Fatalf("error initializing: %v", err)
+	}
+	checkRoundTrip(t, oldBlob, "", "old data")
+	checkRoundTrip(t, newBlob, "", "new data")
+	err = PruneKeys()
+	if err != nil {
+		t.Fatalf("error pruning keys: %v", err)
+	}
+	if GetStatus().NumKeys != 1 {
+		t.Errorf("expected 1 key after pruning, got %d", GetStatus().NumKeys)
+	}
+	if _, err := Decrypt(oldBlob, nil); err == nil {
+		t.Errorf("expected an error decrypting with a pruned key")
+	}
+	checkRoundTrip(t, newBlob, "", "new data")
+}
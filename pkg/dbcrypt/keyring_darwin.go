@@ -0,0 +1,49 @@
+// Copyright 2025, Command Line Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package dbcrypt
+
+import (
+	"errors"
+	"fmt"
+	"os/exec"
+	"strings"
+)
+
+// uses the login keychain through the security tool
+
+const securityNotFoundExitCode = 44
+
+func keyringAvailable() bool {
+	_, err := exec.LookPath("security")
+	return err == nil
+}
+
+func keyringGet(account string) (string, error) {
+	output, err := exec.Command("security", "find-generic-password", "-s", keyringService, "-a", account, "-w").Output()
+	if err != nil {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) && exitErr.ExitCode() == securityNotFoundExitCode {
+			return "", fmt.Errorf("key %q not found", account)
+		}
+		return "", fmt.Errorf("security: %w", err)
+	}
+	return strings.TrimSpace(string(output)), nil
+}
+
+func keyringSet(account string, secret string) error {
+	// security has no way to read the password from stdin (without a tty), so it is briefly visible to the user's own processes
+	output, err := exec.Command("security", "add-generic-password", "-U", "-s", keyringService, "-a", account, "-l", "Wave Terminal database key", "-w", secret).CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("security: %v %s", err, strings.TrimSpace(string(output)))
+	}
+	return nil
+}
+
+func keyringDelete(account string) error {
+	output, err := exec.Command("security", "delete-generic-password", "-s", keyringService, "-a", account).CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("security: %v %s", err, strings.TrimSpace(string(output)))
+	}
+	return nil
+}
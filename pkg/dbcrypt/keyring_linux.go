@@ -0,0 +1,61 @@
+// Copyright 2025, Command Line Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package dbcrypt
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+// uses the freedesktop secret service (gnome-keyring, kwallet) through secret-tool.
+// headless machines usually don't have a session bus or a secret service running, so they fall back to the key file.
+
+func keyringAvailable() bool {
+	if _, err := exec.LookPath("secret-tool"); err != nil {
+		return false
+	}
+	if os.Getenv("DBUS_SESSION_BUS_ADDRESS") != "" {
+		return true
+	}
+	_, err := os.Stat(fmt.Sprintf("/run/user/%d/bus", os.Getuid()))
+	return err == nil
+}
+
+func keyringGet(account string) (string, error) {
+	cmd := exec.Command("secret-tool", "lookup", "service", keyringService, "account", account)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	output, err := cmd.Output()
+	if err != nil {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) && stderr.Len() == 0 {
+			return "", fmt.Errorf("key %q not found", account)
+		}
+		return "", fmt.Errorf("secret-tool: %v %s", err, strings.TrimSpace(stderr.String()))
+	}
+	return strings.TrimSpace(string(output)), nil
+}
+
+func keyringSet(account string, secret string) error {
+	// the secret is passed on stdin so it doesn't show up in the process list
+	cmd := exec.Command("secret-tool", "store", "--label=Wave Terminal database key", "service", keyringService, "account", account)
+	cmd.Stdin = strings.NewReader(secret)
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("secret-tool: %v %s", err, strings.TrimSpace(string(output)))
+	}
+	return nil
+}
+
+func keyringDelete(account string) error {
+	output, err := exec.Command("secret-tool", "clear", "service", keyringService, "account", account).CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("secret-tool: %v %s", err, strings.TrimSpace(string(output)))
+	}
+	return nil
+}
@@ -0,0 +1,26 @@
+// Copyright 2025, Command Line Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+//go:build !linux && !darwin && !windows
+
+package dbcrypt
+
+import "fmt"
+
+var errNoKeyring = fmt.Errorf("no keyring support on this platform")
+
+func keyringAvailable() bool {
+	return false
+}
+
+func keyringGet(account string) (string, error) {
+	return "", errNoKeyring
+}
+
+func keyringSet(account string, secret string) error {
+	return errNoKeyring
+}
+
+func keyringDelete(account string) error {
+	return errNoKeyring
+}
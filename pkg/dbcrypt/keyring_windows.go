@@ -0,0 +1,77 @@
+// Copyright 2025, Command Line Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package dbcrypt
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+// uses the windows credential locker (PasswordVault) through powershell.  values are passed in the environment
+// of the powershell process so they don't show up in its command line.
+
+const vaultNotFoundExitCode = 3
+
+const vaultScriptPrefix = `$ErrorActionPreference = 'Stop'
+[void][Windows.Security.Credentials.PasswordVault, Windows.Security.Credentials, ContentType = WindowsRuntime]
+$vault = New-Object Windows.Security.Credentials.PasswordVault
+`
+
+const vaultGetScript = vaultScriptPrefix + `try { $cred = $vault.Retrieve($env:WAVE_KEYRING_SERVICE, $env:WAVE_KEYRING_ACCOUNT) } catch { exit 3 }
+$cred.RetrievePassword()
+[Console]::Out.Write($cred.Password)
+`
+
+const vaultSetScript = vaultScriptPrefix + `$vault.Add((New-Object Windows.Security.Credentials.PasswordCredential($env:WAVE_KEYRING_SERVICE, $env:WAVE_KEYRING_ACCOUNT, $env:WAVE_KEYRING_SECRET)))
+`
+
+const vaultDeleteScript = vaultScriptPrefix + `try { $cred = $vault.Retrieve($env:WAVE_KEYRING_SERVICE, $env:WAVE_KEYRING_ACCOUNT) } catch { exit 0 }
+$vault.Remove($cred)
+`
+
+func keyringAvailable() bool {
+	_, err := exec.LookPath("powershell.exe")
+	return err == nil
+}
+
+func runVaultScript(script string, account string, secret string) ([]byte, error) {
+	cmd := exec.Command("powershell.exe", "-NoProfile", "-NonInteractive", "-Command", "-")
+	cmd.Stdin = strings.NewReader(script)
+	cmd.Env = append(os.Environ(), "WAVE_KEYRING_SERVICE="+keyringService, "WAVE_KEYRING_ACCOUNT="+account)
+	if secret != "" {
+		cmd.Env = append(cmd.Env, "WAVE_KEYRING_SECRET="+secret)
+	}
+	return cmd.Output()
+}
+
+func keyringGet(account string) (string, error) {
+	output, err := runVaultScript(vaultGetScript, account, "")
+	if err != nil {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) && exitErr.ExitCode() == vaultNotFoundExitCode {
+			return "", fmt.Errorf("key %q not found", account)
+		}
+		return "", fmt.Errorf("powershell: %w", err)
+	}
+	return strings.TrimSpace(string(output)), nil
+}
+
+func keyringSet(account string, secret string) error {
+	_, err := runVaultScript(vaultSetScript, account, secret)
+	if err != nil {
+		return fmt.Errorf("powershell: %w", err)
+	}
+	return nil
+}
+
+func keyringDelete(account string) error {
+	_, err := runVaultScript(vaultDeleteScript, account, "")
+	if err != nil {
+		return fmt.Errorf("powershell: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,233 @@
+// Copyright 2025, Command Line Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package dbcrypt
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+
+	"github.com/wavetermdev/waveterm/pkg/wavebase"
+	"golang.org/x/crypto/argon2"
+)
+
+const KeysFileName = "dbkeys.json"
+
+const keyringService = "waveterm"
+
+// argon2id parameters for the passphrase key (vars so the tests can make them cheap)
+var (
+	argonTime    uint32 = 3
+	argonMemory  uint32 = 64 * 1024
+	argonThreads uint8  = 4
+)
+
+const saltSize = 16
+
+var keysDir string // for tests (defaults to the wave db dir)
+var curKeyringAccount string
+
+// records where the keyset is stored.  only the "file" source keeps the keys in plaintext (the file is 0600).
+type keysFileType struct {
+	KeySource      string      `json:"keysource"`
+	KeyringAccount string      `json:"keyringaccount,omitempty"`
+	Keyset         *keysetType `json:"keyset,omitempty"`
+	Salt           string      `json:"salt,omitempty"`
+	SealedKeyset   string      `json:"sealedkeyset,omitempty"`
+}
+
+func getKeysFileName() string {
+	dir := keysDir
+	if dir == "" {
+		dir = filepath.Join(wavebase.GetWaveDataDir(), wavebase.WaveDBDir)
+	}
+	return filepath.Join(dir, KeysFileName)
+}
+
+// returns nil if there is no keys file
+func readKeysFile() (*keysFileType, error) {
+	barr, err := os.ReadFile(getKeysFileName())
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error reading keys file: %w", err)
+	}
+	var kf keysFileType
+	err = json.Unmarshal(barr, &kf)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing keys file %s: %w", getKeysFileName(), err)
+	}
+	return &kf, nil
+}
+
+func writeKeysFile(kf *keysFileType) error {
+	barr, err := json.MarshalIndent(kf, "", "  ")
+	if err != nil {
+		return err
+	}
+	fileName := getKeysFileName()
+	tmpName := fileName + ".tmp"
+	err = os.WriteFile(tmpName, barr, 0600)
+	if err != nil {
+		return fmt.Errorf("error writing keys file: %w", err)
+	}
+	err = os.Rename(tmpName, fileName)
+	if err != nil {
+		os.Remove(tmpName)
+		return fmt.Errorf("error writing keys file: %w", err)
+	}
+	return nil
+}
+
+func loadKeyset(kf *keysFileType) (*keysetType, error) {
+	switch kf.KeySource {
+	case KeySourceFile:
+		if kf.Keyset == nil {
+			return nil, fmt.Errorf("no keyset in keys file")
+		}
+		return kf.Keyset, nil
+	case KeySourcePassphrase:
+		return unsealKeyset(kf)
+	case KeySourceKeyring:
+		secret, err := keyringGet(kf.KeyringAccount)
+		if err != nil {
+			return nil, fmt.Errorf("error reading database key from keyring: %w", err)
+		}
+		var keyset keysetType
+		err = decodeSecret(secret, &keyset)
+		if err != nil {
+			return nil, fmt.Errorf("invalid database key in keyring: %w", err)
+		}
+		curKeyringAccount = kf.KeyringAccount
+		return &keyset, nil
+	default:
+		return nil, fmt.Errorf("unknown key source %q in keys file", kf.KeySource)
+	}
+}
+
+func saveKeyset(keySource string, keyset *keysetType) error {
+	kf := &keysFileType{KeySource: keySource}
+	switch keySource {
+	case KeySourceFile:
+		kf.Keyset = keyset
+	case KeySourcePassphrase:
+		err := sealKeyset(kf, keyset)
+		if err != nil {
+			return err
+		}
+	case KeySourceKeyring:
+		if !keyringAvailable() {
+			return fmt.Errorf("no keyring available")
+		}
+		secret, err := encodeSecret(keyset)
+		if err != nil {
+			return err
+		}
+		kf.KeyringAccount = keyringAccount()
+		err = keyringSet(kf.KeyringAccount, secret)
+		if err != nil {
+			return fmt.Errorf("error storing database key in keyring: %w", err)
+		}
+		curKeyringAccount = kf.KeyringAccount
+	default:
+		return fmt.Errorf("unknown key source %q", keySource)
+	}
+	return writeKeysFile(kf)
+}
+
+// the account is derived from the data dir, so dev and release builds (and multiple data dirs) don't share a key
+func keyringAccount() string {
+	if curKeyringAccount != "" {
+		return curKeyringAccount
+	}
+	hash := sha256.Sum256([]byte(filepath.Dir(getKeysFileName())))
+	return "dbkey-" + hex.EncodeToString(hash[:6])
+}
+
+func encodeSecret(v any) (string, error) {
+	barr, err := json.Marshal(v)
+	if err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(barr), nil
+}
+
+func decodeSecret(secret string, v any) error {
+	barr, err := base64.StdEncoding.DecodeString(secret)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(barr, v)
+}
+
+func passphraseKey(salt []byte) ([]byte, error) {
+	if passphrase == "" {
+		return nil, fmt.Errorf("%s is not set", PassphraseEnvVar)
+	}
+	return argon2.IDKey([]byte(passphrase), salt, argonTime, argonMemory, argonThreads, keySize), nil
+}
+
+func sealKeyset(kf *keysFileType, keyset *keysetType) error {
+	salt := make([]byte, saltSize)
+	if _, err := rand.Read(salt); err != nil {
+		return fmt.Errorf("error generating salt: %w", err)
+	}
+	kek, err := passphraseKey(salt)
+	if err != nil {
+		return err
+	}
+	aead, err := makeAEAD(kek)
+	if err != nil {
+		return err
+	}
+	plaintext, err := json.Marshal(keyset)
+	if err != nil {
+		return err
+	}
+	nonce := make([]byte, nonceSize)
+	if _, err := rand.Read(nonce); err != nil {
+		return fmt.Errorf("error generating nonce: %w", err)
+	}
+	sealed := aead.Seal(nonce, nonce, plaintext, []byte(KeySourcePassphrase))
+	kf.Salt = base64.StdEncoding.EncodeToString(salt)
+	kf.SealedKeyset = base64.StdEncoding.EncodeToString(sealed)
+	return nil
+}
+
+func unsealKeyset(kf *keysFileType) (*keysetType, error) {
+	salt, err := base64.StdEncoding.DecodeString(kf.Salt)
+	if err != nil {
+		return nil, fmt.Errorf("invalid salt in keys file: %w", err)
+	}
+	sealed, err := base64.StdEncoding.DecodeString(kf.SealedKeyset)
+	if err != nil || len(sealed) < nonceSize {
+		return nil, fmt.Errorf("invalid sealed keyset in keys file")
+	}
+	kek, err := passphraseKey(salt)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := makeAEAD(kek)
+	if err != nil {
+		return nil, err
+	}
+	plaintext, err := aead.Open(nil, sealed[:nonceSize], sealed[nonceSize:], []byte(KeySourcePassphrase))
+	if err != nil {
+		return nil, fmt.Errorf("cannot unlock database keys (wrong passphrase?)")
+	}
+	var keyset keysetType
+	err = json.Unmarshal(plaintext, &keyset)
+	if err != nil {
+		return nil, fmt.Errorf("invalid sealed keyset: %w", err)
+	}
+	return &keyset, nil
+}
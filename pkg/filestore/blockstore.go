@@ -73,6 +73,12 @@ func (f WaveFile) DataLength() int64 {
 	return f.Size
 }
 
+// the DataLength of the file after it grows to size
+func (f WaveFile) dataLengthAt(size int64) int64 {
+	f.Size = size
+	return f.DataLength()
+}
+
 // for regular files this is just 0
 // for circular files this is the index of the first byte of data we have
 func (f WaveFile) DataStartIdx() int64 {
@@ -158,6 +164,7 @@ func (s *FileStore) DeleteFile(ctx context.Context, zoneId string, name string)
 			return fmt.Errorf("error deleting file: %v", err)
 		}
 		entry.clear()
+		usage.set(zoneId, name, 0)
 		return nil
 	})
 }
@@ -231,12 +238,39 @@ func (s *FileStore) WriteFile(ctx context.Context, zoneId string, name string, d
 		if err != nil {
 			return err
 		}
+		err = s.enforceQuotas(ctx, entry, entry.File.dataLengthAt(int64(len(data))))
+		if err != nil {
+			return err
+		}
 		entry.writeAt(0, data, true)
 		// since WriteFile can *truncate* the file, we need to flush the file to the DB immediately
 		return entry.flushToDB(ctx, true)
 	})
 }
 
+// like WriteFile, but the data starts at offset.  used to write back the data of a circular file (with the offset
+// returned by ReadFile), so offsets into the file stay valid.  for regular files offset must be 0.
+func (s *FileStore) WriteFileAt(ctx context.Context, zoneId string, name string, offset int64, data []byte) error {
+	if offset < 0 {
+		return fmt.Errorf("offset must be non-negative")
+	}
+	return withLock(s, zoneId, name, func(entry *CacheEntry) error {
+		err := entry.loadFileIntoCache(ctx)
+		if err != nil {
+			return err
+		}
+		if offset > 0 && !entry.File.Opts.Circular {
+			return fmt.Errorf("offset can only be set for circular files")
+		}
+		err = s.enforceQuotas(ctx, entry, entry.File.dataLengthAt(int64(len(data))))
+		if err != nil {
+			return err
+		}
+		entry.writeAt(offset, data, true)
+		return entry.flushToDB(ctx, true)
+	})
+}
+
 func (s *FileStore) WriteAt(ctx context.Context, zoneId string, name string, offset int64, data []byte) error {
 	if offset < 0 {
 		return fmt.Errorf("offset must be non-negative")
@@ -246,10 +280,16 @@ func (s *FileStore) WriteAt(ctx context.Context, zoneId string, name string, off
 		if err != nil {
 			return err
 		}
-		file := entry.File
-		if offset > file.Size {
+		if offset > entry.File.Size {
 			return fmt.Errorf("offset is past the end of the file")
 		}
+		if endOffset := offset + int64(len(data)); endOffset > entry.File.Size {
+			err = s.enforceQuotas(ctx, entry, entry.File.dataLengthAt(endOffset))
+			if err != nil {
+				return err
+			}
+		}
+		file := entry.File
 		partMap := file.computePartMap(offset, int64(len(data)))
 		incompleteParts := incompletePartsFromMap(partMap)
 		err = entry.loadDataPartsIntoCache(ctx, incompleteParts)
@@ -267,6 +307,10 @@ func (s *FileStore) AppendData(ctx context.Context, zoneId string, name string,
 		if err != nil {
 			return err
 		}
+		err = s.enforceQuotas(ctx, entry, entry.File.dataLengthAt(entry.File.Size+int64(len(data))))
+		if err != nil {
+			return err
+		}
 		partMap := entry.File.computePartMap(entry.File.Size, int64(len(data)))
 		incompleteParts := incompletePartsFromMap(partMap)
 		if len(incompleteParts) > 0 {
@@ -333,6 +377,10 @@ func (s *FileStore) AppendIJson(ctx context.Context, zoneId string, name string,
 		if !entry.File.Opts.IJson {
 			return fmt.Errorf("file %s:%s is not an ijson file", zoneId, name)
 		}
+		err = s.enforceQuotas(ctx, entry, entry.File.Size+int64(len(data))+1)
+		if err != nil {
+			return err
+		}
 		partMap := entry.File.computePartMap(entry.File.Size, int64(len(data)))
 		incompleteParts := incompletePartsFromMap(partMap)
 		if len(incompleteParts) > 0 {
@@ -0,0 +1,88 @@
+// Copyright 2025, Command Line Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package filestore
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/wavetermdev/waveterm/pkg/util/dbutil"
+)
+
+type dbWaveFileRow struct {
+	ZoneId    string
+	Name      string
+	Size      int64
+	CreatedTs int64
+	ModTs     int64
+	Opts      string
+	Meta      string
+}
+
+type dbFileDataRow struct {
+	ZoneId  string
+	Name    string
+	PartIdx int
+	Data    []byte
+	Codec   string
+}
+
+// writes a consistent copy of the db to fileName (which must not exist), the cache is flushed first
+func (s *FileStore) BackupTo(ctx context.Context, fileName string) error {
+	_, err := s.FlushCache(ctx)
+	if err != nil {
+		return fmt.Errorf("error flushing filestore cache: %w", err)
+	}
+	_, err = globalDB.ExecContext(ctx, "VACUUM INTO ?", fileName)
+	if err != nil {
+		return fmt.Errorf("error copying filestore db: %w", err)
+	}
+	return nil
+}
+
+// copies the files of the given zones from another filestore db (a backup made by BackupTo, migrated to the current
+// schema).  the data parts are copied as stored (compressed or encrypted parts stay that way).  zones that already
+// have files are skipped.  returns the number of files copied.
+func (s *FileStore) CopyZonesFrom(ctx context.Context, srcDB *sqlx.DB, zoneIds []string) (int, error) {
+	var files []*dbWaveFileRow
+	query := "SELECT zoneid, name, size, createdts, modts, opts, meta FROM db_wave_file WHERE zoneid IN (SELECT value FROM json_each(?))"
+	err := srcDB.SelectContext(ctx, &files, query, dbutil.QuickJson(zoneIds))
+	if err != nil {
+		return 0, fmt.Errorf("error reading files: %w", err)
+	}
+	var parts []*dbFileDataRow
+	query = "SELECT zoneid, name, partidx, data, codec FROM db_file_data WHERE zoneid IN (SELECT value FROM json_each(?))"
+	err = srcDB.SelectContext(ctx, &parts, query, dbutil.QuickJson(zoneIds))
+	if err != nil {
+		return 0, fmt.Errorf("error reading data parts: %w", err)
+	}
+	// the files are added without the cache
+	defer usage.invalidate()
+	return WithTxRtn(ctx, func(tx *TxWrap) (int, error) {
+		skipZones := make(map[string]bool)
+		for _, zoneId := range zoneIds {
+			if tx.Exists("SELECT zoneid FROM db_wave_file WHERE zoneid = ?", zoneId) {
+				skipZones[zoneId] = true
+			}
+		}
+		var count int
+		for _, file := range files {
+			if skipZones[file.ZoneId] {
+				continue
+			}
+			query := "INSERT INTO db_wave_file (zoneid, name, size, createdts, modts, opts, meta) VALUES (?, ?, ?, ?, ?, ?, ?)"
+			tx.Exec(query, file.ZoneId, file.Name, file.Size, file.CreatedTs, file.ModTs, file.Opts, file.Meta)
+			count++
+		}
+		for _, part := range parts {
+			if skipZones[part.ZoneId] {
+				continue
+			}
+			query := "INSERT INTO db_file_data (zoneid, name, partidx, data, codec) VALUES (?, ?, ?, ?, ?)"
+			tx.Exec(query, part.ZoneId, part.Name, part.PartIdx, part.Data, part.Codec)
+		}
+		return count, nil
+	})
+}
@@ -200,6 +200,7 @@ func (entry *CacheEntry) writeAt(offset int64, data []byte, replace bool) {
 		entry.File.Size = endWriteOffset
 	}
 	entry.File.ModTs = time.Now().UnixMilli()
+	usage.set(entry.ZoneId, entry.Name, entry.File.DataLength())
 }
 
 // returns (realOffset, data, error)
@@ -330,7 +331,11 @@ func (entry *CacheEntry) flushToDB(ctx context.Context, replace bool) error {
 	if entry.File == nil {
 		return nil
 	}
-	err := dbWriteCacheEntry(ctx, entry.File, entry.DataEntries, replace)
+	// compress before the transaction
+	dataParts, err := encodeDataEntries(entry.File, entry.DataEntries)
+	if err == nil {
+		err = dbWriteCacheEntry(ctx, entry.File, dataParts, replace)
+	}
 	if ctx.Err() != nil {
 		// transient error
 		return ctx.Err()
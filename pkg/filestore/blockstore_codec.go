@@ -0,0 +1,275 @@
+// Copyright 2025, Command Line Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package filestore
+
+// data parts of files with the Compress option are compressed when they are flushed to the DB.  the cache
+// always holds uncompressed parts, so writes (including WriteAt and circular files) work the same for both.
+// the codec of each part is stored with the part (older parts, and parts that don't compress, have no codec).
+// when database encryption is enabled, parts are encrypted after they are compressed, which is marked with an
+// "aesgcm" suffix on the codec.  the part's zone, name and index are bound to the ciphertext (as AAD).
+
+import (
+	"bytes"
+	"compress/flate"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"strings"
+	"sync"
+
+	"github.com/wavetermdev/waveterm/pkg/dbcrypt"
+	"github.com/wavetermdev/waveterm/pkg/wshrpc"
+)
+
+const (
+	CodecNone    = ""
+	CodecDeflate = "deflate"
+	CodecAESGCM  = "aesgcm" // only used as a suffix, "aesgcm" alone means encrypted but not compressed
+)
+
+var forceCodec string // for tests, used for every part of every file (even if it doesn't make the part smaller)
+
+var flateWriterPool = sync.Pool{
+	New: func() any {
+		w, _ := flate.NewWriter(nil, flate.DefaultCompression)
+		return w
+	},
+}
+
+// a row in db_file_data
+type dbFilePart struct {
+	PartIdx int
+	Data    []byte
+	Codec   string
+}
+
+func fileCodec(opts wshrpc.FileOpts) string {
+	if forceCodec != "" {
+		return forceCodec
+	}
+	if opts.Compress {
+		return CodecDeflate
+	}
+	return CodecNone
+}
+
+func splitCodec(codec string) (string, bool) {
+	if codec == CodecAESGCM {
+		return CodecNone, true
+	}
+	if baseCodec, ok := strings.CutSuffix(codec, "+"+CodecAESGCM); ok {
+		return baseCodec, true
+	}
+	return codec, false
+}
+
+func partAAD(zoneId string, name string, partIdx int) []byte {
+	return fmt.Appendf(nil, "filestore:%s:%s:%d", zoneId, name, partIdx)
+}
+
+// returns the data to store and its codec (compressed, then encrypted if database encryption is enabled)
+func encodePart(data []byte, codec string, aad []byte) ([]byte, string, error) {
+	data, codec, err := compressPart(data, codec)
+	if err != nil || len(data) == 0 || !dbcrypt.Enabled() {
+		return data, codec, err
+	}
+	data, err = dbcrypt.Encrypt(data, aad)
+	if err != nil {
+		return nil, "", fmt.Errorf("error encrypting part: %w", err)
+	}
+	if codec == CodecNone {
+		return data, CodecAESGCM, nil
+	}
+	return data, codec + "+" + CodecAESGCM, nil
+}
+
+// parts that don't get smaller are stored as is
+func compressPart(data []byte, codec string) ([]byte, string, error) {
+	if codec == CodecNone || len(data) == 0 {
+		return data, CodecNone, nil
+	}
+	if codec != CodecDeflate {
+		return nil, "", fmt.Errorf("unknown codec %q", codec)
+	}
+	var buf bytes.Buffer
+	w := flateWriterPool.Get().(*flate.Writer)
+	defer flateWriterPool.Put(w)
+	w.Reset(&buf)
+	w.Write(data)
+	err := w.Close()
+	if err != nil {
+		return nil, "", fmt.Errorf("error compressing part: %w", err)
+	}
+	if buf.Len() >= len(data) && forceCodec == "" {
+		return data, CodecNone, nil
+	}
+	return buf.Bytes(), codec, nil
+}
+
+// returns the part data (with a capacity of partDataSize, like the parts in the cache)
+func decodePart(data []byte, codec string, aad []byte) ([]byte, error) {
+	codec, encrypted := splitCodec(codec)
+	if encrypted {
+		var err error
+		data, err = dbcrypt.Decrypt(data, aad)
+		if err != nil {
+			return nil, err
+		}
+	}
+	switch codec {
+	case CodecNone:
+		if cap(data) != int(partDataSize) {
+			newData := make([]byte, len(data), partDataSize)
+			copy(newData, data)
+			data = newData
+		}
+		return data, nil
+	case CodecDeflate:
+		r := flate.NewReader(bytes.NewReader(data))
+		defer r.Close()
+		rtn := make([]byte, partDataSize)
+		n, err := io.ReadFull(r, rtn)
+		if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+			return nil, fmt.Errorf("error decompressing part: %w", err)
+		}
+		if n == len(rtn) {
+			var extra [1]byte
+			if _, err := r.Read(extra[:]); !errors.Is(err, io.EOF) {
+				return nil, fmt.Errorf("error decompressing part: part is larger than %d bytes", partDataSize)
+			}
+		}
+		return rtn[:n], nil
+	default:
+		return nil, fmt.Errorf("unknown codec %q", codec)
+	}
+}
+
+func encodeDataEntries(file *WaveFile, dataEntries map[int]*DataCacheEntry) ([]*dbFilePart, error) {
+	codec := fileCodec(file.Opts)
+	parts := make([]*dbFilePart, 0, len(dataEntries))
+	for partIdx, dataEntry := range dataEntries {
+		if partIdx != dataEntry.PartIdx {
+			panic(fmt.Sprintf("partIdx:%d and dataEntry.PartIdx:%d do not match", partIdx, dataEntry.PartIdx))
+		}
+		data, partCodec, err := encodePart(dataEntry.Data, codec, partAAD(file.ZoneId, file.Name, partIdx))
+		if err != nil {
+			return nil, err
+		}
+		parts = append(parts, &dbFilePart{PartIdx: partIdx, Data: data, Codec: partCodec})
+	}
+	return parts, nil
+}
+
+type RecompressStats struct {
+	NumFiles int
+	NumParts int
+	OldBytes int64 // stored size of the data parts
+	NewBytes int64
+}
+
+func (rs *RecompressStats) add(other RecompressStats) {
+	rs.NumFiles += other.NumFiles
+	rs.NumParts += other.NumParts
+	rs.OldBytes += other.OldBytes
+	rs.NewBytes += other.NewBytes
+}
+
+// sets the Compress option of a file and rewrites all of its data parts with the new codec
+func (s *FileStore) Recompress(ctx context.Context, zoneId string, name string, compress bool) (RecompressStats, error) {
+	return s.rewriteFile(ctx, zoneId, name, &compress)
+}
+
+// rewrites all of the data parts of a file with the current encryption settings (after encryption is
+// enabled or disabled, or after a key rotation)
+func (s *FileStore) Reencrypt(ctx context.Context, zoneId string, name string) (RecompressStats, error) {
+	return s.rewriteFile(ctx, zoneId, name, nil)
+}
+
+func (s *FileStore) rewriteFile(ctx context.Context, zoneId string, name string, compress *bool) (RecompressStats, error) {
+	return withLockRtn(s, zoneId, name, func(entry *CacheEntry) (RecompressStats, error) {
+		var stats RecompressStats
+		// flush first, so all of the data is in the db
+		err := entry.flushToDB(ctx, false)
+		if err != nil {
+			return stats, err
+		}
+		file, err := entry.loadFileForRead(ctx)
+		if err != nil {
+			return stats, err
+		}
+		file = file.DeepCopy()
+		if compress != nil {
+			file.Opts.Compress = *compress
+		}
+		codec := fileCodec(file.Opts)
+		oldParts, err := dbGetAllFileParts(ctx, zoneId, name)
+		if err != nil {
+			return stats, fmt.Errorf("error getting data parts: %w", err)
+		}
+		newParts := make([]*dbFilePart, 0, len(oldParts))
+		for _, oldPart := range oldParts {
+			aad := partAAD(zoneId, name, oldPart.PartIdx)
+			data, err := decodePart(oldPart.Data, oldPart.Codec, aad)
+			if err != nil {
+				return stats, fmt.Errorf("part %d: %w", oldPart.PartIdx, err)
+			}
+			newData, newCodec, err := encodePart(data, codec, aad)
+			if err != nil {
+				return stats, err
+			}
+			newParts = append(newParts, &dbFilePart{PartIdx: oldPart.PartIdx, Data: newData, Codec: newCodec})
+			stats.OldBytes += int64(len(oldPart.Data))
+			stats.NewBytes += int64(len(newData))
+		}
+		err = dbRecompressFile(ctx, file, newParts)
+		if err != nil {
+			return stats, err
+		}
+		stats.NumFiles = 1
+		stats.NumParts = len(newParts)
+		return stats, nil
+	})
+}
+
+// recompresses all files (or all of the files in a zone)
+func (s *FileStore) RecompressAll(ctx context.Context, zoneId string, compress bool) (RecompressStats, error) {
+	return s.rewriteAll(ctx, zoneId, &compress)
+}
+
+// re-encrypts (or decrypts) all files, see Reencrypt
+func (s *FileStore) ReencryptAll(ctx context.Context) (RecompressStats, error) {
+	return s.rewriteAll(ctx, "", nil)
+}
+
+func (s *FileStore) rewriteAll(ctx context.Context, zoneId string, compress *bool) (RecompressStats, error) {
+	var stats RecompressStats
+	zoneIds := []string{zoneId}
+	if zoneId == "" {
+		var err error
+		zoneIds, err = dbGetAllZoneIds(ctx)
+		if err != nil {
+			return stats, fmt.Errorf("error getting zones: %w", err)
+		}
+	}
+	for _, zoneId := range zoneIds {
+		names, err := dbGetZoneFileNames(ctx, zoneId)
+		if err != nil {
+			return stats, fmt.Errorf("error getting zone files: %w", err)
+		}
+		for _, name := range names {
+			fileStats, err := s.rewriteFile(ctx, zoneId, name, compress)
+			if errors.Is(err, fs.ErrNotExist) {
+				// deleted while we were running
+				continue
+			}
+			if err != nil {
+				return stats, fmt.Errorf("error rewriting %s:%s: %w", zoneId, name, err)
+			}
+			stats.add(fileStats)
+		}
+	}
+	return stats, nil
+}
@@ -5,6 +5,7 @@ package filestore
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"io/fs"
 	"os"
@@ -62,24 +63,88 @@ func dbGetAllZoneIds(ctx context.Context) ([]string, error) {
 	})
 }
 
+func dbGetZoneUsage(ctx context.Context) ([]*ZoneUsage, error) {
+	return WithTxRtn(ctx, func(tx *TxWrap) ([]*ZoneUsage, error) {
+		var rtn []*ZoneUsage
+		query := `SELECT f.zoneid, count(*) AS numfiles, sum(f.size) AS size, max(f.modts) AS modts,
+		            coalesce((SELECT sum(length(d.data)) FROM db_file_data d WHERE d.zoneid = f.zoneid), 0) AS databytes
+		          FROM db_wave_file f
+		          GROUP BY f.zoneid`
+		tx.Select(&rtn, query)
+		return rtn, nil
+	})
+}
+
+func dbGetFileUsage(ctx context.Context, zoneId string) ([]*FileUsage, error) {
+	return WithTxRtn(ctx, func(tx *TxWrap) ([]*FileUsage, error) {
+		type rowType struct {
+			ZoneId    string
+			Name      string
+			Size      int64
+			ModTs     int64
+			Opts      string
+			DataBytes int64
+		}
+		var rows []*rowType
+		query := `SELECT f.zoneid, f.name, f.size, f.modts, f.opts,
+		            coalesce((SELECT sum(length(d.data)) FROM db_file_data d WHERE d.zoneid = f.zoneid AND d.name = f.name), 0) AS databytes
+		          FROM db_wave_file f`
+		var args []any
+		if zoneId != "" {
+			query += " WHERE f.zoneid = ?"
+			args = append(args, zoneId)
+		}
+		tx.Select(&rows, query, args...)
+		rtn := make([]*FileUsage, 0, len(rows))
+		for _, row := range rows {
+			file := WaveFile{Size: row.Size}
+			json.Unmarshal([]byte(row.Opts), &file.Opts)
+			rtn = append(rtn, &FileUsage{
+				ZoneId:     row.ZoneId,
+				Name:       row.Name,
+				Size:       row.Size,
+				DataLength: file.DataLength(),
+				DataBytes:  row.DataBytes,
+				Circular:   file.Opts.Circular,
+				ModTs:      row.ModTs,
+			})
+		}
+		return rtn, nil
+	})
+}
+
 func dbGetFileParts(ctx context.Context, zoneId string, name string, parts []int) (map[int]*DataCacheEntry, error) {
 	if len(parts) == 0 {
 		return nil, nil
 	}
-	return WithTxRtn(ctx, func(tx *TxWrap) (map[int]*DataCacheEntry, error) {
-		var data []*DataCacheEntry
-		query := "SELECT partidx, data FROM db_file_data WHERE zoneid = ? AND name = ? AND partidx IN (SELECT value FROM json_each(?))"
-		tx.Select(&data, query, zoneId, name, dbutil.QuickJsonArr(parts))
-		rtn := make(map[int]*DataCacheEntry)
-		for _, d := range data {
-			if cap(d.Data) != int(partDataSize) {
-				newData := make([]byte, len(d.Data), partDataSize)
-				copy(newData, d.Data)
-				d.Data = newData
-			}
-			rtn[d.PartIdx] = d
+	dbParts, err := WithTxRtn(ctx, func(tx *TxWrap) ([]*dbFilePart, error) {
+		var dbParts []*dbFilePart
+		query := "SELECT partidx, data, codec FROM db_file_data WHERE zoneid = ? AND name = ? AND partidx IN (SELECT value FROM json_each(?))"
+		tx.Select(&dbParts, query, zoneId, name, dbutil.QuickJsonArr(parts))
+		return dbParts, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	// decode outside of the transaction
+	rtn := make(map[int]*DataCacheEntry)
+	for _, dbPart := range dbParts {
+		data, err := decodePart(dbPart.Data, dbPart.Codec, partAAD(zoneId, name, dbPart.PartIdx))
+		if err != nil {
+			return nil, fmt.Errorf("part %d: %w", dbPart.PartIdx, err)
 		}
-		return rtn, nil
+		rtn[dbPart.PartIdx] = &DataCacheEntry{PartIdx: dbPart.PartIdx, Data: data}
+	}
+	return rtn, nil
+}
+
+// returns the parts as stored (not decoded)
+func dbGetAllFileParts(ctx context.Context, zoneId string, name string) ([]*dbFilePart, error) {
+	return WithTxRtn(ctx, func(tx *TxWrap) ([]*dbFilePart, error) {
+		var dbParts []*dbFilePart
+		query := "SELECT partidx, data, codec FROM db_file_data WHERE zoneid = ? AND name = ? ORDER BY partidx"
+		tx.Select(&dbParts, query, zoneId, name)
+		return dbParts, nil
 	})
 }
 
@@ -91,7 +156,7 @@ func dbGetZoneFiles(ctx context.Context, zoneId string) ([]*WaveFile, error) {
 	})
 }
 
-func dbWriteCacheEntry(ctx context.Context, file *WaveFile, dataEntries map[int]*DataCacheEntry, replace bool) error {
+func dbWriteCacheEntry(ctx context.Context, file *WaveFile, dataParts []*dbFilePart, replace bool) error {
 	return WithTx(ctx, func(tx *TxWrap) error {
 		query := `SELECT zoneid FROM db_wave_file WHERE zoneid = ? AND name = ?`
 		if !tx.Exists(query, file.ZoneId, file.Name) {
@@ -105,12 +170,45 @@ func dbWriteCacheEntry(ctx context.Context, file *WaveFile, dataEntries map[int]
 			query = `DELETE FROM db_file_data WHERE zoneid = ? AND name = ?`
 			tx.Exec(query, file.ZoneId, file.Name)
 		}
-		dataPartQuery := `REPLACE INTO db_file_data (zoneid, name, partidx, data) VALUES (?, ?, ?, ?)`
-		for partIdx, dataEntry := range dataEntries {
-			if partIdx != dataEntry.PartIdx {
-				panic(fmt.Sprintf("partIdx:%d and dataEntry.PartIdx:%d do not match", partIdx, dataEntry.PartIdx))
-			}
-			tx.Exec(dataPartQuery, file.ZoneId, file.Name, dataEntry.PartIdx, dataEntry.Data)
+		dataPartQuery := `REPLACE INTO db_file_data (zoneid, name, partidx, data, codec) VALUES (?, ?, ?, ?, ?)`
+		for _, dataPart := range dataParts {
+			tx.Exec(dataPartQuery, file.ZoneId, file.Name, dataPart.PartIdx, dataPart.Data, dataPart.Codec)
+		}
+		return nil
+	})
+}
+
+// updates the file (after its max size changed) and replaces all of its data parts
+func dbResizeFile(ctx context.Context, file *WaveFile, dataParts []*dbFilePart) error {
+	return WithTx(ctx, func(tx *TxWrap) error {
+		query := `SELECT zoneid FROM db_wave_file WHERE zoneid = ? AND name = ?`
+		if !tx.Exists(query, file.ZoneId, file.Name) {
+			return os.ErrNotExist
+		}
+		query = `UPDATE db_wave_file SET size = ?, modts = ?, opts = ? WHERE zoneid = ? AND name = ?`
+		tx.Exec(query, file.Size, file.ModTs, dbutil.QuickJson(file.Opts), file.ZoneId, file.Name)
+		query = `DELETE FROM db_file_data WHERE zoneid = ? AND name = ?`
+		tx.Exec(query, file.ZoneId, file.Name)
+		dataPartQuery := `INSERT INTO db_file_data (zoneid, name, partidx, data, codec) VALUES (?, ?, ?, ?, ?)`
+		for _, dataPart := range dataParts {
+			tx.Exec(dataPartQuery, file.ZoneId, file.Name, dataPart.PartIdx, dataPart.Data, dataPart.Codec)
+		}
+		return nil
+	})
+}
+
+// updates the opts of the file (for Compress) and replaces its recompressed data parts
+func dbRecompressFile(ctx context.Context, file *WaveFile, dataParts []*dbFilePart) error {
+	return WithTx(ctx, func(tx *TxWrap) error {
+		query := `SELECT zoneid FROM db_wave_file WHERE zoneid = ? AND name = ?`
+		if !tx.Exists(query, file.ZoneId, file.Name) {
+			return os.ErrNotExist
+		}
+		query = `UPDATE db_wave_file SET opts = ? WHERE zoneid = ? AND name = ?`
+		tx.Exec(query, dbutil.QuickJson(file.Opts), file.ZoneId, file.Name)
+		dataPartQuery := `UPDATE db_file_data SET data = ?, codec = ? WHERE zoneid = ? AND name = ? AND partidx = ?`
+		for _, dataPart := range dataParts {
+			tx.Exec(dataPartQuery, dataPart.Data, dataPart.Codec, file.ZoneId, file.Name, dataPart.PartIdx)
 		}
 		return nil
 	})
@@ -0,0 +1,213 @@
+// Copyright 2025, Command Line Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package filestore
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+)
+
+const (
+	QuotaZone     = "zone"
+	QuotaFileType = "filetype"
+	QuotaTotal    = "total"
+)
+
+// limits on the data stored in the filestore (0 means no limit).  usage is the data length of the files (the
+// uncompressed data that is kept, for circular files at most MaxSize), not the stored size of the data parts.
+type Quotas struct {
+	MaxZoneSize     int64            // all of the files in a zone (e.g. a block)
+	MaxFileTypeSize map[string]int64 // all of the files with a name (e.g. "term") across zones
+	MaxTotalSize    int64            // all files
+}
+
+func (q Quotas) enabled() bool {
+	return q.MaxZoneSize > 0 || len(q.MaxFileTypeSize) > 0 || q.MaxTotalSize > 0
+}
+
+// returns the current quotas (set by the server from the settings)
+var GetQuotas func() Quotas
+
+var ErrQuotaExceeded = errors.New("filestore quota exceeded")
+
+// returned by writes that would exceed a quota.  circular files are trimmed instead (they drop their oldest data).
+type QuotaError struct {
+	Quota  string // QuotaZone, QuotaFileType or QuotaTotal
+	ZoneId string
+	Name   string
+	Limit  int64
+	Used   int64 // usage including the write
+}
+
+func (e *QuotaError) Error() string {
+	return fmt.Sprintf("%v: writing %s:%s would use %d bytes of the %s quota (%d bytes)", ErrQuotaExceeded, e.ZoneId, e.Name, e.Used, e.Quota, e.Limit)
+}
+
+func (e *QuotaError) Unwrap() error {
+	return ErrQuotaExceeded
+}
+
+func (e *QuotaError) excess() int64 {
+	return e.Used - e.Limit
+}
+
+// tracks the data length of every file, so quotas can be checked without querying the db.  it is loaded from the db
+// the first time a quota is checked, then kept up to date by the writes (unflushed writes are included).
+type usageTracker struct {
+	lock   *sync.Mutex
+	loaded bool
+	files  map[cacheKey]int64
+	zones  map[string]int64
+	types  map[string]int64
+	total  int64
+}
+
+var usage = &usageTracker{lock: &sync.Mutex{}}
+
+func (u *usageTracker) load_nolock(ctx context.Context) error {
+	files, err := dbGetFileUsage(ctx, "")
+	if err != nil {
+		return fmt.Errorf("error loading filestore usage: %w", err)
+	}
+	u.files = make(map[cacheKey]int64)
+	u.zones = make(map[string]int64)
+	u.types = make(map[string]int64)
+	u.total = 0
+	for _, file := range files {
+		u.files[cacheKey{ZoneId: file.ZoneId, Name: file.Name}] = file.DataLength
+		u.zones[file.ZoneId] += file.DataLength
+		u.types[file.Name] += file.DataLength
+		u.total += file.DataLength
+	}
+	u.loaded = true
+	return nil
+}
+
+func (u *usageTracker) set(zoneId string, name string, dataLength int64) {
+	u.lock.Lock()
+	defer u.lock.Unlock()
+	if !u.loaded {
+		return
+	}
+	key := cacheKey{ZoneId: zoneId, Name: name}
+	delta := dataLength - u.files[key]
+	if dataLength == 0 {
+		delete(u.files, key)
+	} else {
+		u.files[key] = dataLength
+	}
+	u.zones[zoneId] += delta
+	u.types[name] += delta
+	u.total += delta
+}
+
+// forces a reload from the db (after files were changed without the cache, e.g. CopyZonesFrom)
+func (u *usageTracker) invalidate() {
+	u.lock.Lock()
+	defer u.lock.Unlock()
+	u.loaded = false
+	u.files = nil
+	u.zones = nil
+	u.types = nil
+	u.total = 0
+}
+
+// returns the exceeded quota with the largest excess (nil if growing the file by growth bytes fits all quotas)
+func (u *usageTracker) check(ctx context.Context, zoneId string, name string, growth int64, quotas Quotas) (*QuotaError, error) {
+	u.lock.Lock()
+	defer u.lock.Unlock()
+	if !u.loaded {
+		err := u.load_nolock(ctx)
+		if err != nil {
+			return nil, err
+		}
+	}
+	var rtn *QuotaError
+	checkFn := func(quota string, limit int64, used int64) {
+		if limit <= 0 || used+growth <= limit {
+			return
+		}
+		qerr := &QuotaError{Quota: quota, ZoneId: zoneId, Name: name, Limit: limit, Used: used + growth}
+		if rtn == nil || qerr.excess() > rtn.excess() {
+			rtn = qerr
+		}
+	}
+	checkFn(QuotaZone, quotas.MaxZoneSize, u.zones[zoneId])
+	checkFn(QuotaFileType, quotas.MaxFileTypeSize[name], u.types[name])
+	checkFn(QuotaTotal, quotas.MaxTotalSize, u.total)
+	return rtn, nil
+}
+
+// must hold the entry lock (and the file must be loaded into the cache).  checks that the file can grow to
+// newDataLength.  circular files are shrunk to fit (dropping their oldest data), other files get a *QuotaError.
+func (s *FileStore) enforceQuotas(ctx context.Context, entry *CacheEntry, newDataLength int64) error {
+	if GetQuotas == nil {
+		return nil
+	}
+	quotas := GetQuotas()
+	if !quotas.enabled() {
+		return nil
+	}
+	file := entry.File
+	growth := newDataLength - file.DataLength()
+	if growth <= 0 {
+		return nil
+	}
+	qerr, err := usage.check(ctx, file.ZoneId, file.Name, growth, quotas)
+	if err != nil || qerr == nil {
+		return err
+	}
+	if !file.Opts.Circular {
+		return qerr
+	}
+	newMaxSize := (newDataLength - qerr.excess()) / partDataSize * partDataSize
+	if newMaxSize < partDataSize {
+		newMaxSize = partDataSize
+	}
+	if newMaxSize >= file.Opts.MaxSize {
+		// already as small as it can be (circular files can't grow past their max size)
+		return nil
+	}
+	return s.resizeCircular_nolock(ctx, entry, newMaxSize)
+}
+
+// must hold the entry lock.  changes the max size of a circular file, keeping its newest data.  the file is
+// written to the db right away (the data parts move when the max size changes).
+func (s *FileStore) resizeCircular_nolock(ctx context.Context, entry *CacheEntry, maxSize int64) error {
+	err := entry.loadFileIntoCache(ctx)
+	if err != nil {
+		return err
+	}
+	file := entry.File
+	if !file.Opts.Circular {
+		return fmt.Errorf("file %s:%s is not circular", file.ZoneId, file.Name)
+	}
+	if maxSize <= 0 || maxSize%partDataSize != 0 {
+		return fmt.Errorf("invalid max size %d for circular file", maxSize)
+	}
+	_, data, err := entry.readAt(ctx, 0, 0, true)
+	if err != nil {
+		return err
+	}
+	if int64(len(data)) > maxSize {
+		data = data[int64(len(data))-maxSize:]
+	}
+	size := file.Size
+	file.Opts.MaxSize = maxSize
+	file.Size = size - int64(len(data))
+	entry.DataEntries = make(map[int]*DataCacheEntry)
+	entry.writeAt(file.Size, data, false)
+	dataParts, err := encodeDataEntries(file, entry.DataEntries)
+	if err != nil {
+		return err
+	}
+	err = dbResizeFile(ctx, file, dataParts)
+	entry.clear()
+	if err != nil {
+		return err
+	}
+	return entry.loadFileIntoCache(ctx)
+}
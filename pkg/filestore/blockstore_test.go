@@ -10,17 +10,73 @@ import (
 	"fmt"
 	"io/fs"
 	"log"
+	"os"
+	"path/filepath"
 	"reflect"
+	"strings"
 	"sync"
 	"sync/atomic"
 	"testing"
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/wavetermdev/waveterm/pkg/dbcrypt"
 	"github.com/wavetermdev/waveterm/pkg/ijson"
+	"github.com/wavetermdev/waveterm/pkg/wavebase"
 	"github.com/wavetermdev/waveterm/pkg/wshrpc"
 )
 
+// runs all of the tests without and with compression, and without and with encryption
+func TestMain(m *testing.M) {
+	os.Exit(runTests(m))
+}
+
+func runTests(m *testing.M) int {
+	dataDir, err := initTestKeys()
+	if err != nil {
+		log.Printf("error creating test key: %v\n", err)
+		return 1
+	}
+	defer os.RemoveAll(dataDir)
+	defer func() {
+		forceCodec = CodecNone
+	}()
+	for _, encrypt := range []bool{false, true} {
+		for _, codec := range []string{CodecNone, CodecDeflate} {
+			log.Printf("running filestore tests with codec %q, encrypt:%v\n", codec, encrypt)
+			forceCodec = codec
+			if encrypt {
+				dbcrypt.Enable(dbcrypt.KeySourceFile)
+			} else {
+				dbcrypt.Disable()
+			}
+			code := m.Run()
+			if code != 0 {
+				return code
+			}
+		}
+	}
+	return 0
+}
+
+// creates a database key (in a temp data dir), returns the data dir
+func initTestKeys() (string, error) {
+	dataDir, err := os.MkdirTemp("", "filestore-test")
+	if err != nil {
+		return "", err
+	}
+	wavebase.DataHome_VarCache = dataDir
+	err = os.MkdirAll(filepath.Join(dataDir, wavebase.WaveDBDir), 0700)
+	if err == nil {
+		err = dbcrypt.Enable(dbcrypt.KeySourceFile)
+	}
+	if err != nil {
+		os.RemoveAll(dataDir)
+		return "", err
+	}
+	return dataDir, nil
+}
+
 func initDb(t *testing.T) {
 	t.Logf("initializing db for %q", t.Name())
 	useTestingDb = true
@@ -42,6 +98,7 @@ func cleanupDb(t *testing.T) {
 	useTestingDb = false
 	partDataSize = DefaultPartDataSize
 	WFS.clearCache()
+	usage.invalidate()
 	if warningCount.Load() > 0 {
 		t.Errorf("warning count: %d", warningCount.Load())
 	}
@@ -492,6 +549,47 @@ func TestCircularWrites(t *testing.T) {
 	}
 }
 
+func TestWriteFileAt(t *testing.T) {
+	initDb(t)
+	defer cleanupDb(t)
+	ctx, cancelFn := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancelFn()
+	zoneId := uuid.NewString()
+	err := WFS.MakeFile(ctx, zoneId, "c1", nil, wshrpc.FileOpts{Circular: true, MaxSize: 50})
+	if err != nil {
+		t.Fatalf("error creating file: %v", err)
+	}
+	err = WFS.WriteFileAt(ctx, zoneId, "c1", 68, []byte("9 foo456789 123456789 123456789 apple banana world"))
+	if err != nil {
+		t.Fatalf("error writing data: %v", err)
+	}
+	checkFileSize(t, ctx, zoneId, "c1", 118)
+	offset, _, _ := WFS.ReadFile(ctx, zoneId, "c1")
+	if offset != 68 {
+		t.Errorf("offset mismatch: expected 68, got %d", offset)
+	}
+	checkFileData(t, ctx, zoneId, "c1", "9 foo456789 123456789 123456789 apple banana world")
+	err = WFS.AppendData(ctx, zoneId, "c1", []byte(" more"))
+	if err != nil {
+		t.Fatalf("error appending data: %v", err)
+	}
+	checkFileSize(t, ctx, zoneId, "c1", 123)
+	checkFileData(t, ctx, zoneId, "c1", "456789 123456789 123456789 apple banana world more")
+	err = WFS.MakeFile(ctx, zoneId, "f1", nil, wshrpc.FileOpts{})
+	if err != nil {
+		t.Fatalf("error creating file: %v", err)
+	}
+	err = WFS.WriteFileAt(ctx, zoneId, "f1", 10, []byte("hello"))
+	if err == nil {
+		t.Errorf("expected error writing a regular file at an offset")
+	}
+	err = WFS.WriteFileAt(ctx, zoneId, "f1", 0, []byte("hello"))
+	if err != nil {
+		t.Fatalf("error writing data: %v", err)
+	}
+	checkFileData(t, ctx, zoneId, "f1", "hello")
+}
+
 func makeText(n int) string {
 	var buf bytes.Buffer
 	for i := 0; i < n; i++ {
@@ -774,3 +872,478 @@ func TestIJson(t *testing.T) {
 		t.Errorf("data mismatch: expected %v, got %v", rootSet["data"], outData)
 	}
 }
+
+// returns the codec of each stored part (without the encryption suffix)
+func getPartCodecs(t *testing.T, ctx context.Context, zoneId string, name string) map[int]string {
+	parts, err := dbGetAllFileParts(ctx, zoneId, name)
+	if err != nil {
+		t.Fatalf("error getting parts: %v", err)
+	}
+	rtn := make(map[int]string)
+	for _, part := range parts {
+		rtn[part.PartIdx], _ = splitCodec(part.Codec)
+	}
+	return rtn
+}
+
+func flushCache(t *testing.T, ctx context.Context) {
+	_, err := WFS.FlushCache(ctx)
+	if err != nil {
+		t.Fatalf("error flushing cache: %v", err)
+	}
+}
+
+func TestCompressedParts(t *testing.T) {
+	initDb(t)
+	defer cleanupDb(t)
+	partDataSize = 200 // small parts are not compressed
+
+	ctx, cancelFn := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancelFn()
+	zoneId := uuid.NewString()
+	fileName := "z1"
+	err := WFS.MakeFile(ctx, zoneId, fileName, nil, wshrpc.FileOpts{Compress: true})
+	if err != nil {
+		t.Fatalf("error creating file: %v", err)
+	}
+	data := makeText(480)
+	err = WFS.AppendData(ctx, zoneId, fileName, []byte(data))
+	if err != nil {
+		t.Fatalf("error appending data: %v", err)
+	}
+	flushCache(t, ctx)
+	parts, err := dbGetAllFileParts(ctx, zoneId, fileName)
+	if err != nil {
+		t.Fatalf("error getting parts: %v", err)
+	}
+	if len(parts) != 3 {
+		t.Fatalf("expected 3 parts, got %d", len(parts))
+	}
+	for _, part := range parts[:2] {
+		if codec, _ := splitCodec(part.Codec); codec != CodecDeflate || len(part.Data) >= int(partDataSize) {
+			t.Errorf("part %d not compressed: codec %q, %d bytes", part.PartIdx, part.Codec, len(part.Data))
+		}
+	}
+	checkFileData(t, ctx, zoneId, fileName, data)
+	// write over the compressed parts (which must be loaded into the cache)
+	err = WFS.WriteAt(ctx, zoneId, fileName, 195, []byte("hello"))
+	if err != nil {
+		t.Fatalf("error writing data: %v", err)
+	}
+	err = WFS.AppendData(ctx, zoneId, fileName, []byte("world"))
+	if err != nil {
+		t.Fatalf("error appending data: %v", err)
+	}
+	flushCache(t, ctx)
+	expected := data[:195] + "hello" + data[200:] + "world"
+	checkFileSize(t, ctx, zoneId, fileName, 485)
+	checkFileData(t, ctx, zoneId, fileName, expected)
+	checkFileDataAt(t, ctx, zoneId, fileName, 193, "34hello01")
+}
+
+func TestCompressedCircularWrites(t *testing.T) {
+	initDb(t)
+	defer cleanupDb(t)
+	partDataSize = 200 // small parts are not compressed
+
+	ctx, cancelFn := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancelFn()
+	zoneId := uuid.NewString()
+	err := WFS.MakeFile(ctx, zoneId, "c1", nil, wshrpc.FileOpts{Circular: true, MaxSize: 400, Compress: true})
+	if err != nil {
+		t.Fatalf("error creating file: %v", err)
+	}
+	data := makeText(520)
+	err = WFS.AppendData(ctx, zoneId, "c1", []byte(data))
+	if err != nil {
+		t.Fatalf("error appending data: %v", err)
+	}
+	flushCache(t, ctx)
+	checkFileSize(t, ctx, zoneId, "c1", 520)
+	checkFileData(t, ctx, zoneId, "c1", data[120:])
+	err = WFS.WriteAt(ctx, zoneId, "c1", 398, []byte("abcd"))
+	if err != nil {
+		t.Fatalf("error writing data: %v", err)
+	}
+	flushCache(t, ctx)
+	err = WFS.AppendData(ctx, zoneId, "c1", []byte("banana"))
+	if err != nil {
+		t.Fatalf("error appending data: %v", err)
+	}
+	flushCache(t, ctx)
+	checkFileSize(t, ctx, zoneId, "c1", 526)
+	checkFileData(t, ctx, zoneId, "c1", data[126:398]+"abcd"+data[402:]+"banana")
+	for partIdx, codec := range getPartCodecs(t, ctx, zoneId, "c1") {
+		if codec != CodecDeflate {
+			t.Errorf("part %d: expected codec %q, got %q", partIdx, CodecDeflate, codec)
+		}
+	}
+}
+
+func TestRecompress(t *testing.T) {
+	initDb(t)
+	defer cleanupDb(t)
+	partDataSize = 200 // small parts are not compressed
+
+	ctx, cancelFn := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancelFn()
+	zoneId := uuid.NewString()
+	fileName := "r1"
+	err := WFS.MakeFile(ctx, zoneId, fileName, nil, wshrpc.FileOpts{})
+	if err != nil {
+		t.Fatalf("error creating file: %v", err)
+	}
+	data := makeText(400)
+	err = WFS.WriteFile(ctx, zoneId, fileName, []byte(data))
+	if err != nil {
+		t.Fatalf("error writing data: %v", err)
+	}
+	// unflushed data is flushed before recompressing
+	err = WFS.AppendData(ctx, zoneId, fileName, []byte("0123456789"))
+	if err != nil {
+		t.Fatalf("error appending data: %v", err)
+	}
+	data += "0123456789"
+	stats, err := WFS.RecompressAll(ctx, zoneId, true)
+	if err != nil {
+		t.Fatalf("error recompressing: %v", err)
+	}
+	if stats.NumFiles != 1 || stats.NumParts != 3 {
+		t.Errorf("unexpected stats: %+v", stats)
+	}
+	if forceCodec == CodecNone && stats.NewBytes >= stats.OldBytes {
+		t.Errorf("data was not compressed: %+v", stats)
+	}
+	file, err := WFS.Stat(ctx, zoneId, fileName)
+	if err != nil {
+		t.Fatalf("error stating file: %v", err)
+	}
+	if !file.Opts.Compress {
+		t.Errorf("expected the compress option to be set")
+	}
+	checkFileData(t, ctx, zoneId, fileName, data)
+	stats, err = WFS.Recompress(ctx, zoneId, fileName, false)
+	if err != nil {
+		t.Fatalf("error recompressing: %v", err)
+	}
+	if forceCodec == CodecNone && !dbcrypt.Enabled() {
+		if stats.NewBytes != int64(len(data)) {
+			t.Errorf("expected %d bytes after decompressing, got %d", len(data), stats.NewBytes)
+		}
+		for partIdx, codec := range getPartCodecs(t, ctx, zoneId, fileName) {
+			if codec != CodecNone {
+				t.Errorf("part %d: expected no codec, got %q", partIdx, codec)
+			}
+		}
+	}
+	checkFileData(t, ctx, zoneId, fileName, data)
+}
+
+func TestDecodePart(t *testing.T) {
+	partDataSize = 200
+	defer func() {
+		partDataSize = DefaultPartDataSize
+	}()
+	data := []byte(makeText(200))
+	encoded, codec, err := compressPart(data, CodecDeflate)
+	if err != nil || codec != CodecDeflate {
+		t.Fatalf("error encoding part: %v (codec %q)", err, codec)
+	}
+	decoded, err := decodePart(encoded, codec, nil)
+	if err != nil {
+		t.Fatalf("error decoding part: %v", err)
+	}
+	if string(decoded) != string(data) || cap(decoded) != 200 {
+		t.Errorf("decoded part mismatch: %q (cap %d)", decoded, cap(decoded))
+	}
+	// parts can't be larger than partDataSize
+	encoded, _, _ = compressPart([]byte(makeText(260)), CodecDeflate)
+	_, err = decodePart(encoded, CodecDeflate, nil)
+	if err == nil {
+		t.Errorf("expected an error for an oversized part")
+	}
+	_, err = decodePart([]byte("not deflate data"), CodecDeflate, nil)
+	if err == nil {
+		t.Errorf("expected an error for corrupt data")
+	}
+	_, err = decodePart(data, "zstd", nil)
+	if err == nil {
+		t.Errorf("expected an error for an unknown codec")
+	}
+}
+
+// returns the bytes of the sqlite db file (the test db is in memory, so it is copied to a file first)
+func rawDBBytes(t *testing.T, ctx context.Context) []byte {
+	fileName := filepath.Join(t.TempDir(), "raw.db")
+	_, err := globalDB.ExecContext(ctx, "VACUUM INTO ?", fileName)
+	if err != nil {
+		t.Fatalf("error copying db: %v", err)
+	}
+	barr, err := os.ReadFile(fileName)
+	if err != nil {
+		t.Fatalf("error reading db: %v", err)
+	}
+	return barr
+}
+
+func TestEncryptedParts(t *testing.T) {
+	initDb(t)
+	defer cleanupDb(t)
+	oldCodec := forceCodec
+	forceCodec = CodecNone // so the plaintext is visible when encryption is off
+	wasEnabled := dbcrypt.Enabled()
+	defer func() {
+		forceCodec = oldCodec
+		if wasEnabled {
+			dbcrypt.Enable(dbcrypt.KeySourceFile)
+		} else {
+			dbcrypt.Disable()
+		}
+	}()
+	dbcrypt.Disable()
+
+	ctx, cancelFn := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancelFn()
+	zoneId := uuid.NewString()
+	secret := "export AWS_SECRET_ACCESS_KEY=wJalrXUtnFEMI/K7MDENG\n"
+	data := strings.Repeat(secret, 10)
+	err := WFS.MakeFile(ctx, zoneId, "term", nil, wshrpc.FileOpts{})
+	if err != nil {
+		t.Fatalf("error creating file: %v", err)
+	}
+	err = WFS.WriteFile(ctx, zoneId, "term", []byte(data))
+	if err != nil {
+		t.Fatalf("error writing data: %v", err)
+	}
+	flushCache(t, ctx)
+	if !bytes.Contains(rawDBBytes(t, ctx), []byte("AWS_SECRET")) {
+		t.Fatalf("expected plaintext in the db before encrypting")
+	}
+
+	// encrypt the existing data in place
+	err = dbcrypt.Enable(dbcrypt.KeySourceFile)
+	if err != nil {
+		t.Fatalf("error enabling encryption: %v", err)
+	}
+	stats, err := WFS.ReencryptAll(ctx)
+	if err != nil {
+		t.Fatalf("error encrypting: %v", err)
+	}
+	if stats.NumFiles != 1 {
+		t.Errorf("unexpected stats: %+v", stats)
+	}
+	// new writes are encrypted as well
+	err = WFS.AppendData(ctx, zoneId, "term", []byte(secret))
+	if err != nil {
+		t.Fatalf("error appending data: %v", err)
+	}
+	data += secret
+	flushCache(t, ctx)
+	parts, err := dbGetAllFileParts(ctx, zoneId, "term")
+	if err != nil {
+		t.Fatalf("error getting parts: %v", err)
+	}
+	for _, part := range parts {
+		if part.Codec != CodecAESGCM {
+			t.Errorf("part %d: expected codec %q, got %q", part.PartIdx, CodecAESGCM, part.Codec)
+		}
+	}
+	if bytes.Contains(rawDBBytes(t, ctx), []byte("AWS_SECRET")) {
+		t.Errorf("found plaintext in the db after encrypting")
+	}
+	WFS.clearCache()
+	checkFileData(t, ctx, zoneId, "term", data)
+
+	// rotate the key, the old key is needed until everything is re-encrypted
+	oldStatus := dbcrypt.GetStatus()
+	newKeyId, err := dbcrypt.RotateKey("")
+	if err != nil {
+		t.Fatalf("error rotating key: %v", err)
+	}
+	if newKeyId == oldStatus.KeyId {
+		t.Fatalf("key id did not change")
+	}
+	checkFileData(t, ctx, zoneId, "term", data)
+	_, err = WFS.ReencryptAll(ctx)
+	if err != nil {
+		t.Fatalf("error re-encrypting: %v", err)
+	}
+	err = dbcrypt.PruneKeys()
+	if err != nil {
+		t.Fatalf("error pruning keys: %v", err)
+	}
+	if dbcrypt.GetStatus().NumKeys != 1 {
+		t.Errorf("expected 1 key after pruning, got %d", dbcrypt.GetStatus().NumKeys)
+	}
+	WFS.clearCache()
+	checkFileData(t, ctx, zoneId, "term", data)
+	if bytes.Contains(rawDBBytes(t, ctx), []byte("AWS_SECRET")) {
+		t.Errorf("found plaintext in the db after rotating the key")
+	}
+
+	// a part can't be moved to another file
+	parts, err = dbGetAllFileParts(ctx, zoneId, "term")
+	if err != nil {
+		t.Fatalf("error getting parts: %v", err)
+	}
+	_, err = decodePart(parts[0].Data, parts[0].Codec, partAAD(zoneId, "other", parts[0].PartIdx))
+	if err == nil {
+		t.Errorf("expected an error decrypting a part with the wrong aad")
+	}
+
+	// and decrypt it again
+	dbcrypt.Disable()
+	_, err = WFS.ReencryptAll(ctx)
+	if err != nil {
+		t.Fatalf("error decrypting: %v", err)
+	}
+	for partIdx, codec := range getPartCodecs(t, ctx, zoneId, "term") {
+		if codec != CodecNone {
+			t.Errorf("part %d: expected no codec, got %q", partIdx, codec)
+		}
+	}
+	checkFileData(t, ctx, zoneId, "term", data)
+}
+
+func TestZoneUsage(t *testing.T) {
+	initDb(t)
+	defer cleanupDb(t)
+
+	ctx, cancelFn := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancelFn()
+	zoneIds := []string{uuid.NewString(), uuid.NewString()}
+	for idx, zoneId := range zoneIds {
+		for fileIdx := 0; fileIdx <= idx; fileIdx++ {
+			fileName := fmt.Sprintf("f%d", fileIdx)
+			err := WFS.MakeFile(ctx, zoneId, fileName, nil, wshrpc.FileOpts{})
+			if err != nil {
+				t.Fatalf("error creating file: %v", err)
+			}
+			err = WFS.WriteFile(ctx, zoneId, fileName, []byte(makeText(70)))
+			if err != nil {
+				t.Fatalf("error writing data: %v", err)
+			}
+		}
+	}
+	usage, err := WFS.GetZoneUsage(ctx)
+	if err != nil {
+		t.Fatalf("error getting zone usage: %v", err)
+	}
+	usageMap := make(map[string]*ZoneUsage)
+	for _, zone := range usage {
+		usageMap[zone.ZoneId] = zone
+	}
+	for idx, zoneId := range zoneIds {
+		zone := usageMap[zoneId]
+		if zone == nil {
+			t.Fatalf("zone %d missing from usage", idx)
+		}
+		if zone.NumFiles != idx+1 || zone.Size != int64(70*(idx+1)) || zone.DataBytes <= 0 || zone.ModTs == 0 {
+			t.Errorf("unexpected usage for zone %d: %+v", idx, zone)
+		}
+	}
+	err = WFS.DeleteZone(ctx, zoneIds[1])
+	if err != nil {
+		t.Fatalf("error deleting zone: %v", err)
+	}
+	usage, err = WFS.GetZoneUsage(ctx)
+	if err != nil {
+		t.Fatalf("error getting zone usage: %v", err)
+	}
+	if len(usage) != 1 || usage[0].ZoneId != zoneIds[0] {
+		t.Errorf("expected only zone 0 after delete, got %d zones", len(usage))
+	}
+	vacuumed, err := WFS.Vacuum(ctx, false)
+	if err != nil || vacuumed {
+		t.Errorf("expected no vacuum with little free space (vacuumed:%v, err:%v)", vacuumed, err)
+	}
+	vacuumed, err = WFS.Vacuum(ctx, true)
+	if err != nil || !vacuumed {
+		t.Errorf("error vacuuming (vacuumed:%v): %v", vacuumed, err)
+	}
+	checkFileData(t, ctx, zoneIds[0], "f0", makeText(70))
+}
+
+func TestQuotas(t *testing.T) {
+	initDb(t)
+	defer cleanupDb(t)
+	GetQuotas = func() Quotas {
+		return Quotas{MaxZoneSize: 200, MaxFileTypeSize: map[string]int64{"log": 120}, MaxTotalSize: 300}
+	}
+	defer func() { GetQuotas = nil }()
+
+	ctx, cancelFn := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancelFn()
+	zoneId := uuid.NewString()
+	err := WFS.MakeFile(ctx, zoneId, "log", nil, wshrpc.FileOpts{})
+	if err != nil {
+		t.Fatalf("error creating file: %v", err)
+	}
+	err = WFS.WriteFile(ctx, zoneId, "log", []byte(makeText(100)))
+	if err != nil {
+		t.Fatalf("error writing data: %v", err)
+	}
+	err = WFS.AppendData(ctx, zoneId, "log", []byte(makeText(30)))
+	var qerr *QuotaError
+	if !errors.Is(err, ErrQuotaExceeded) || !errors.As(err, &qerr) || qerr.Quota != QuotaFileType || qerr.Used != 130 {
+		t.Fatalf("expected filetype quota error, got %v", err)
+	}
+	checkFileData(t, ctx, zoneId, "log", makeText(100))
+
+	// the circular file is shrunk to fit the zone quota (dropping its oldest data)
+	err = WFS.MakeFile(ctx, zoneId, "term", nil, wshrpc.FileOpts{Circular: true, MaxSize: 200})
+	if err != nil {
+		t.Fatalf("error creating file: %v", err)
+	}
+	err = WFS.AppendData(ctx, zoneId, "term", []byte(strings.Repeat("a", 100)))
+	if err != nil {
+		t.Fatalf("error appending data: %v", err)
+	}
+	err = WFS.AppendData(ctx, zoneId, "term", []byte(strings.Repeat("b", 50)))
+	if err != nil {
+		t.Fatalf("error appending data: %v", err)
+	}
+	checkFileSize(t, ctx, zoneId, "term", 150)
+	checkFileData(t, ctx, zoneId, "term", strings.Repeat("a", 50)+strings.Repeat("b", 50))
+	flushCache(t, ctx)
+	WFS.clearCache()
+	file, err := WFS.Stat(ctx, zoneId, "term")
+	if err != nil {
+		t.Fatalf("error stating file: %v", err)
+	}
+	if file.Opts.MaxSize != 100 {
+		t.Errorf("expected max size 100 after trim, got %d", file.Opts.MaxSize)
+	}
+	checkFileData(t, ctx, zoneId, "term", strings.Repeat("a", 50)+strings.Repeat("b", 50))
+	err = WFS.AppendData(ctx, zoneId, "term", []byte("c"))
+	if err != nil {
+		t.Fatalf("error appending data: %v", err)
+	}
+	checkFileData(t, ctx, zoneId, "term", strings.Repeat("a", 49)+strings.Repeat("b", 50)+"c")
+
+	zoneId2 := uuid.NewString()
+	err = WFS.MakeFile(ctx, zoneId2, "data", nil, wshrpc.FileOpts{})
+	if err != nil {
+		t.Fatalf("error creating file: %v", err)
+	}
+	qerr = nil
+	err = WFS.WriteFile(ctx, zoneId2, "data", []byte(makeText(150)))
+	if !errors.As(err, &qerr) || qerr.Quota != QuotaTotal {
+		t.Fatalf("expected total quota error, got %v", err)
+	}
+	err = WFS.DeleteFile(ctx, zoneId, "log")
+	if err != nil {
+		t.Fatalf("error deleting file: %v", err)
+	}
+	err = WFS.WriteFile(ctx, zoneId2, "data", []byte(makeText(150)))
+	if err != nil {
+		t.Fatalf("expected write to fit after delete, got %v", err)
+	}
+	files, err := WFS.GetFileUsage(ctx, zoneId)
+	if err != nil {
+		t.Fatalf("error getting file usage: %v", err)
+	}
+	if len(files) != 1 || files[0].Name != "term" || files[0].DataLength != 100 || !files[0].Circular {
+		t.Errorf("unexpected file usage: %+v", files)
+	}
+}
@@ -0,0 +1,89 @@
+// Copyright 2025, Command Line Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package filestore
+
+import (
+	"context"
+	"fmt"
+	"os"
+)
+
+// min free space in the db file before Vacuum(ctx, false) rebuilds it
+const VacuumMinFreeBytes = 8 * 1024 * 1024
+
+type ZoneUsage struct {
+	ZoneId    string
+	NumFiles  int
+	Size      int64 // total size of the files
+	DataBytes int64 // stored (possibly compressed) size of the data parts
+	ModTs     int64 // last modification of a file in the zone
+}
+
+type FileUsage struct {
+	ZoneId     string
+	Name       string
+	Size       int64
+	DataLength int64 // the data that is kept (for circular files at most MaxSize)
+	DataBytes  int64 // stored (possibly compressed) size of the data parts
+	Circular   bool
+	ModTs      int64
+}
+
+// returns the usage of every file in the db, or of the files in a zone (unflushed writes are not included)
+func (s *FileStore) GetFileUsage(ctx context.Context, zoneId string) ([]*FileUsage, error) {
+	return dbGetFileUsage(ctx, zoneId)
+}
+
+// returns the usage of every zone in the db (unflushed writes are not included)
+func (s *FileStore) GetZoneUsage(ctx context.Context) ([]*ZoneUsage, error) {
+	return dbGetZoneUsage(ctx)
+}
+
+// returns the size of the db files (including the WAL)
+func GetDBSize() int64 {
+	if useTestingDb {
+		return 0
+	}
+	var size int64
+	for _, suffix := range []string{"", "-wal"} {
+		finfo, err := os.Stat(GetDBName() + suffix)
+		if err == nil {
+			size += finfo.Size()
+		}
+	}
+	return size
+}
+
+// returns the number of unused bytes in the db file (space from deleted data that sqlite will reuse)
+func GetDBFreeBytes(ctx context.Context) (int64, error) {
+	return WithTxRtn(ctx, func(tx *TxWrap) (int64, error) {
+		freePages := tx.GetInt64("PRAGMA freelist_count")
+		pageSize := tx.GetInt64("PRAGMA page_size")
+		return freePages * pageSize, nil
+	})
+}
+
+// rebuilds the db file (VACUUM), so the space of deleted data is returned to the filesystem.  unless force is
+// set this only runs when there are at least VacuumMinFreeBytes to reclaim.  returns true if the db was vacuumed.
+func (s *FileStore) Vacuum(ctx context.Context, force bool) (bool, error) {
+	if !force {
+		freeBytes, err := GetDBFreeBytes(ctx)
+		if err != nil {
+			return false, err
+		}
+		if freeBytes < VacuumMinFreeBytes {
+			return false, nil
+		}
+	}
+	// VACUUM can't run in a transaction
+	_, err := globalDB.ExecContext(ctx, "VACUUM")
+	if err != nil {
+		return false, fmt.Errorf("error vacuuming filestore db: %w", err)
+	}
+	_, err = globalDB.ExecContext(ctx, "PRAGMA wal_checkpoint(TRUNCATE)")
+	if err != nil {
+		return true, fmt.Errorf("error truncating filestore wal: %w", err)
+	}
+	return true, nil
+}
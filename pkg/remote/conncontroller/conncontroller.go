@@ -61,6 +61,7 @@ type SSHConn struct {
 	Client             *ssh.Client
 	DomainSockName     string // if "", then no domain socket
 	DomainSockListener net.Listener
+	Tunnels            map[int]net.Listener // local listeners forwarding to remote ports, by remote port
 	ConnController     *ssh.Session
 	Error              string
 	WshError           string
@@ -171,6 +172,10 @@ func (conn *SSHConn) close_nolock() {
 		conn.ConnController.Close()
 		conn.ConnController = nil
 	}
+	for remotePort, listener := range conn.Tunnels {
+		listener.Close()
+		delete(conn.Tunnels, remotePort)
+	}
 	if conn.Client != nil {
 		conn.Client.Close()
 		conn.Client = nil
@@ -0,0 +1,87 @@
+// Copyright 2025, Command Line Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package conncontroller
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"sync"
+
+	"github.com/wavetermdev/waveterm/pkg/panichandler"
+)
+
+// ForwardPort forwards a local port to remotePort on the remote host (like ssh -L) and returns the local port.
+// an existing tunnel to remotePort is reused.  tunnels are closed with the connection (so a
+// restarted server is reachable at the same local port).
+func (conn *SSHConn) ForwardPort(remotePort int) (int, error) {
+	if conn.GetClient() == nil {
+		return 0, fmt.Errorf("connection %s is not connected", conn.GetName())
+	}
+	var listener net.Listener
+	var err error
+	conn.WithLock(func() {
+		if existing := conn.Tunnels[remotePort]; existing != nil {
+			listener = existing
+			return
+		}
+		listener, err = net.Listen("tcp", "127.0.0.1:0")
+		if err != nil {
+			return
+		}
+		if conn.Tunnels == nil {
+			conn.Tunnels = make(map[int]net.Listener)
+		}
+		conn.Tunnels[remotePort] = listener
+		go conn.runTunnel(listener, remotePort)
+	})
+	if err != nil {
+		return 0, fmt.Errorf("cannot open local port: %w", err)
+	}
+	return listener.Addr().(*net.TCPAddr).Port, nil
+}
+
+func (conn *SSHConn) runTunnel(listener net.Listener, remotePort int) {
+	defer func() {
+		panichandler.PanicHandler("conncontroller:runTunnel", recover())
+	}()
+	log.Printf("forwarding %s to %s:%d\n", listener.Addr(), conn.GetName(), remotePort)
+	for {
+		localConn, err := listener.Accept()
+		if err != nil {
+			// closed
+			return
+		}
+		go conn.forwardTunnelConn(localConn, remotePort)
+	}
+}
+
+func (conn *SSHConn) forwardTunnelConn(localConn net.Conn, remotePort int) {
+	defer func() {
+		panichandler.PanicHandler("conncontroller:forwardTunnelConn", recover())
+	}()
+	defer localConn.Close()
+	client := conn.GetClient()
+	if client == nil {
+		return
+	}
+	// "localhost" is resolved on the remote host, so servers listening only on ipv6 are reachable too
+	remoteConn, err := client.Dial("tcp", fmt.Sprintf("localhost:%d", remotePort))
+	if err != nil {
+		log.Printf("tunnel to %s:%d: %v\n", conn.GetName(), remotePort, err)
+		return
+	}
+	defer remoteConn.Close()
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		io.Copy(remoteConn, localConn)
+		remoteConn.Close()
+	}()
+	io.Copy(localConn, remoteConn)
+	localConn.Close()
+	wg.Wait()
+}
@@ -91,6 +91,15 @@ func ListEntriesStream(ctx context.Context, path string, opts *wshrpc.FileListOp
 	return client.ListEntriesStream(ctx, conn, opts)
 }
 
+func Search(ctx context.Context, path string, opts *wshrpc.FileSearchOpts) <-chan wshrpc.RespOrErrorUnion[wshrpc.CommandFileSearchRtnData] {
+	log.Printf("Search: %v", path)
+	client, conn := CreateFileShareClient(ctx, path)
+	if conn == nil || client == nil {
+		return wshutil.SendErrCh[wshrpc.CommandFileSearchRtnData](fmt.Errorf(ErrorParsingConnection, path))
+	}
+	return client.Search(ctx, conn, opts)
+}
+
 func Stat(ctx context.Context, path string) (*wshrpc.FileInfo, error) {
 	log.Printf("Stat: %v", path)
 	client, conn := CreateFileShareClient(ctx, path)
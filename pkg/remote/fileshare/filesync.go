@@ -0,0 +1,395 @@
+// Copyright 2025, Command Line Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package fileshare
+
+import (
+	"archive/tar"
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/wavetermdev/waveterm/pkg/panichandler"
+	"github.com/wavetermdev/waveterm/pkg/remote/connparse"
+	"github.com/wavetermdev/waveterm/pkg/remote/fileshare/fspath"
+	"github.com/wavetermdev/waveterm/pkg/remote/fileshare/fstype"
+	"github.com/wavetermdev/waveterm/pkg/remote/fileshare/fsutil"
+	"github.com/wavetermdev/waveterm/pkg/remote/fileshare/wshfs"
+	"github.com/wavetermdev/waveterm/pkg/util/tarcopy"
+	"github.com/wavetermdev/waveterm/pkg/wshrpc"
+	"github.com/wavetermdev/waveterm/pkg/wshrpc/wshclient"
+	"github.com/wavetermdev/waveterm/pkg/wshutil"
+)
+
+const (
+	SyncReason_New      = "new"
+	SyncReason_Size     = "size"
+	SyncReason_ModTime  = "modtime"
+	SyncReason_Checksum = "checksum"
+	SyncReason_Type     = "type"
+
+	syncWriteChunkSize = 16 * wshrpc.FileChunkSize
+	// number of files requested in one tar stream
+	syncCopyBatchSize = 1000
+)
+
+type syncCopyItem struct {
+	Entry  *wshrpc.FileManifestEntry
+	Reason string
+}
+
+type syncPlan struct {
+	Copies    []syncCopyItem
+	Deletes   []*wshrpc.FileManifestEntry
+	Mkdirs    []string
+	Unchanged int
+}
+
+func getManifest(ctx context.Context, client fstype.FileShareClient, conn *connparse.Connection, opts *wshrpc.FileSyncOpts) (map[string]*wshrpc.FileManifestEntry, error) {
+	var entries []*wshrpc.FileManifestEntry
+	if conn.GetType() == connparse.ConnectionTypeWsh {
+		data := wshrpc.CommandRemoteFileManifestData{Path: conn.Path, Checksum: opts.Checksum, Excludes: opts.Excludes}
+		rtnCh := wshclient.RemoteFileManifestCommand(wshfs.RpcClient, data, &wshrpc.RpcOpts{Route: wshutil.MakeConnectionRouteId(conn.Host), Timeout: opts.Timeout})
+		for respUnion := range rtnCh {
+			if respUnion.Error != nil {
+				return nil, respUnion.Error
+			}
+			entries = append(entries, respUnion.Response.Entries...)
+		}
+	} else {
+		var err error
+		entries, err = fsutil.ListManifest(ctx, client, conn, opts.Excludes)
+		if err != nil {
+			return nil, err
+		}
+	}
+	rtn := make(map[string]*wshrpc.FileManifestEntry, len(entries))
+	for _, entry := range entries {
+		rtn[entry.Path] = entry
+	}
+	return rtn, nil
+}
+
+// computeSyncPlan compares the source and destination manifests.
+// files are copied if they are missing, differ in size, or (depending on checksum) have different checksums or a different modtime.
+// keepsModTime is set if the destination keeps the modtimes of copied files, then any modtime difference counts (so a
+// touch on the destination does not hide a change).  otherwise the destination's modtime is the time of the copy and
+// only a newer source modtime counts.
+func computeSyncPlan(srcManifest, destManifest map[string]*wshrpc.FileManifestEntry, opts *wshrpc.FileSyncOpts, keepsModTime bool) *syncPlan {
+	plan := &syncPlan{}
+	srcPaths := make([]string, 0, len(srcManifest))
+	for path := range srcManifest {
+		srcPaths = append(srcPaths, path)
+	}
+	sort.Strings(srcPaths)
+	for _, path := range srcPaths {
+		srcEntry := srcManifest[path]
+		destEntry := destManifest[path]
+		if srcEntry.IsDir {
+			if destEntry == nil {
+				plan.Mkdirs = append(plan.Mkdirs, path)
+			} else if !destEntry.IsDir {
+				// a file is in the way of a directory
+				plan.Deletes = append(plan.Deletes, destEntry)
+				plan.Mkdirs = append(plan.Mkdirs, path)
+			}
+			continue
+		}
+		reason := ""
+		switch {
+		case destEntry == nil:
+			reason = SyncReason_New
+		case destEntry.IsDir:
+			plan.Deletes = append(plan.Deletes, destEntry)
+			reason = SyncReason_Type
+		case srcEntry.Size != destEntry.Size:
+			reason = SyncReason_Size
+		case opts.Checksum && srcEntry.Checksum != "" && destEntry.Checksum != "":
+			if srcEntry.Checksum != destEntry.Checksum {
+				reason = SyncReason_Checksum
+			}
+		case keepsModTime && srcEntry.ModTime != destEntry.ModTime:
+			reason = SyncReason_ModTime
+		case !keepsModTime && srcEntry.ModTime > destEntry.ModTime:
+			reason = SyncReason_ModTime
+		}
+		if reason == "" {
+			plan.Unchanged++
+			continue
+		}
+		plan.Copies = append(plan.Copies, syncCopyItem{Entry: srcEntry, Reason: reason})
+	}
+	if opts.Delete {
+		destPaths := make([]string, 0, len(destManifest))
+		for path := range destManifest {
+			if srcManifest[path] == nil {
+				destPaths = append(destPaths, path)
+			}
+		}
+		sort.Strings(destPaths)
+		deletedDirs := make(map[string]bool)
+		for _, entry := range plan.Deletes {
+			if entry.IsDir {
+				deletedDirs[entry.Path] = true
+			}
+		}
+		for _, path := range destPaths {
+			// entries inside a deleted directory are removed with it
+			if hasDeletedParent(path, deletedDirs) {
+				continue
+			}
+			destEntry := destManifest[path]
+			if destEntry.IsDir {
+				deletedDirs[path] = true
+			}
+			plan.Deletes = append(plan.Deletes, destEntry)
+		}
+	}
+	return plan
+}
+
+func hasDeletedParent(path string, deletedDirs map[string]bool) bool {
+	for dir := fspath.Dir(path); dir != "." && dir != fspath.Separator; dir = fspath.Dir(dir) {
+		if deletedDirs[dir] {
+			return true
+		}
+	}
+	return false
+}
+
+func syncChildConn(conn *connparse.Connection, relPath string) *connparse.Connection {
+	rtn := *conn
+	rtn.Path = fspath.Join(conn.Path, relPath)
+	return &rtn
+}
+
+// writes the contents of reader to the destination, in chunks if the filesystem supports appending.
+// modTime (unix ms, if set) is passed with the last write, so the destination keeps the source's modtime.
+func syncPutFile(ctx context.Context, client fstype.FileShareClient, conn *connparse.Connection, header *tar.Header, reader io.Reader, modTime int64) error {
+	// PutFile/AppendFile modify the FileInfo opts, so each call gets a new one
+	makeInfo := func(last bool) *wshrpc.FileInfo {
+		info := &wshrpc.FileInfo{Path: conn.Path, Mode: header.FileInfo().Mode().Perm()}
+		if last && modTime > 0 {
+			info.Opts = &wshrpc.FileOpts{ModTime: modTime}
+		}
+		return info
+	}
+	if !client.GetCapability().CanAppend {
+		data, err := io.ReadAll(reader)
+		if err != nil {
+			return err
+		}
+		return client.PutFile(ctx, conn, wshrpc.FileData{Info: makeInfo(true), Data64: base64.StdEncoding.EncodeToString(data)})
+	}
+	buf := make([]byte, syncWriteChunkSize)
+	first := true
+	for {
+		n, readErr := io.ReadFull(reader, buf)
+		last := errors.Is(readErr, io.EOF) || errors.Is(readErr, io.ErrUnexpectedEOF)
+		if readErr != nil && !last {
+			return readErr
+		}
+		// an empty last write only sets the modtime (the size was a multiple of the chunk size)
+		if n > 0 || first || (last && modTime > 0) {
+			fileData := wshrpc.FileData{Info: makeInfo(last), Data64: base64.StdEncoding.EncodeToString(buf[:n])}
+			var err error
+			if first {
+				err = client.PutFile(ctx, conn, fileData)
+			} else {
+				err = client.AppendFile(ctx, conn, fileData)
+			}
+			if err != nil {
+				return err
+			}
+			first = false
+		}
+		if last {
+			return nil
+		}
+	}
+}
+
+func Sync(ctx context.Context, data wshrpc.CommandFileSyncData) <-chan wshrpc.RespOrErrorUnion[wshrpc.FileSyncUpdate] {
+	opts := data.Opts
+	if opts == nil {
+		opts = &wshrpc.FileSyncOpts{}
+	}
+	log.Printf("Sync: srcuri: %v, desturi: %v, opts: %v", data.SrcUri, data.DestUri, opts)
+	srcClient, srcConn := CreateFileShareClient(ctx, data.SrcUri)
+	if srcConn == nil || srcClient == nil {
+		return wshutil.SendErrCh[wshrpc.FileSyncUpdate](fmt.Errorf("error creating fileshare client, could not parse source connection %s", data.SrcUri))
+	}
+	destClient, destConn := CreateFileShareClient(ctx, data.DestUri)
+	if destConn == nil || destClient == nil {
+		return wshutil.SendErrCh[wshrpc.FileSyncUpdate](fmt.Errorf("error creating fileshare client, could not parse destination connection %s", data.DestUri))
+	}
+	ch := make(chan wshrpc.RespOrErrorUnion[wshrpc.FileSyncUpdate], 32)
+	go func() {
+		defer func() {
+			panichandler.PanicHandler("fileshare.Sync", recover())
+		}()
+		defer close(ch)
+		err := syncInternal(ctx, srcClient, srcConn, destClient, destConn, opts, func(update wshrpc.FileSyncUpdate) {
+			ch <- wshrpc.RespOrErrorUnion[wshrpc.FileSyncUpdate]{Response: update}
+		})
+		if err != nil {
+			ch <- wshutil.RespErr[wshrpc.FileSyncUpdate](err)
+		}
+	}()
+	return ch
+}
+
+func syncInternal(ctx context.Context, srcClient fstype.FileShareClient, srcConn *connparse.Connection, destClient fstype.FileShareClient, destConn *connparse.Connection, opts *wshrpc.FileSyncOpts, sendUpdate func(wshrpc.FileSyncUpdate)) error {
+	if opts.Timeout <= 0 {
+		opts.Timeout = fstype.DefaultTimeout.Milliseconds()
+	}
+	srcConn.Path = strings.TrimSuffix(srcConn.Path, fspath.Separator)
+	destConn.Path = strings.TrimSuffix(destConn.Path, fspath.Separator)
+	srcInfo, err := srcClient.Stat(ctx, srcConn)
+	if err != nil {
+		return fmt.Errorf("error getting source file info: %w", err)
+	}
+	if srcInfo.NotFound {
+		return fmt.Errorf("source %q not found", srcConn.GetFullURI())
+	}
+	if !srcInfo.IsDir {
+		return fmt.Errorf("source %q is not a directory", srcConn.GetFullURI())
+	}
+	srcManifest, err := getManifest(ctx, srcClient, srcConn, opts)
+	if err != nil {
+		return fmt.Errorf("error reading source: %w", err)
+	}
+	destManifest, err := getManifest(ctx, destClient, destConn, opts)
+	if err != nil {
+		return fmt.Errorf("error reading destination: %w", err)
+	}
+	keepsModTime := destClient.GetCapability().CanSetModTime
+	plan := computeSyncPlan(srcManifest, destManifest, opts, keepsModTime)
+	progress := wshrpc.FileSyncUpdate{
+		DryRun:       opts.DryRun,
+		FilesTotal:   len(plan.Copies),
+		NumUnchanged: plan.Unchanged,
+	}
+	for _, item := range plan.Copies {
+		progress.BytesTotal += item.Entry.Size
+	}
+
+	// deletes go first so that files and directories can replace each other
+	for _, entry := range plan.Deletes {
+		if !opts.DryRun {
+			err := destClient.Delete(ctx, syncChildConn(destConn, entry.Path), entry.IsDir)
+			if err != nil {
+				return fmt.Errorf("error deleting %q: %w", entry.Path, err)
+			}
+		}
+		progress.NumDeleted++
+		update := progress
+		update.Op = wshrpc.FileSyncOp_Delete
+		update.Path = entry.Path
+		sendUpdate(update)
+	}
+	if opts.DryRun {
+		for _, item := range plan.Copies {
+			progress.FilesDone++
+			progress.BytesDone += item.Entry.Size
+			update := progress
+			update.Op = wshrpc.FileSyncOp_Copy
+			update.Path = item.Entry.Path
+			update.Reason = item.Reason
+			update.Size = item.Entry.Size
+			sendUpdate(update)
+		}
+		progress.Op = wshrpc.FileSyncOp_Summary
+		sendUpdate(progress)
+		return nil
+	}
+
+	if destClient.GetCapability().CanMkdir {
+		if len(destManifest) == 0 {
+			destInfo, err := destClient.Stat(ctx, destConn)
+			if err == nil && destInfo.NotFound {
+				if err := destClient.Mkdir(ctx, destConn); err != nil {
+					return fmt.Errorf("error creating destination directory: %w", err)
+				}
+			}
+		}
+		// mkdirs are sorted, so parents are created before their children
+		for _, dirPath := range plan.Mkdirs {
+			if err := destClient.Mkdir(ctx, syncChildConn(destConn, dirPath)); err != nil {
+				return fmt.Errorf("error creating directory %q: %w", dirPath, err)
+			}
+		}
+	}
+
+	if len(plan.Copies) > 0 {
+		copyStart := time.Now()
+		// large trees are copied in batches, so the file list of a single request stays small
+		for start := 0; start < len(plan.Copies); start += syncCopyBatchSize {
+			batch := plan.Copies[start:min(start+syncCopyBatchSize, len(plan.Copies))]
+			err = syncCopyBatch(ctx, srcClient, srcConn, destClient, destConn, opts, batch, keepsModTime, func(item syncCopyItem, size int64) {
+				progress.FilesDone++
+				progress.BytesDone += size
+				update := progress
+				update.Op = wshrpc.FileSyncOp_Copy
+				update.Path = item.Entry.Path
+				update.Reason = item.Reason
+				update.Size = size
+				sendUpdate(update)
+			})
+			if err != nil {
+				return fmt.Errorf("error copying files: %w", err)
+			}
+		}
+		log.Printf("Sync: %d files (%d bytes) copied in %.3fs\n", progress.FilesDone, progress.BytesDone, time.Since(copyStart).Seconds())
+	}
+	progress.Op = wshrpc.FileSyncOp_Summary
+	sendUpdate(progress)
+	return nil
+}
+
+// copies the files in batch from the source to the destination with a single tar stream
+func syncCopyBatch(ctx context.Context, srcClient fstype.FileShareClient, srcConn *connparse.Connection, destClient fstype.FileShareClient, destConn *connparse.Connection, opts *wshrpc.FileSyncOpts, batch []syncCopyItem, keepsModTime bool, copied func(item syncCopyItem, size int64)) error {
+	copyItems := make(map[string]syncCopyItem, len(batch))
+	files := make([]string, 0, len(batch))
+	for _, item := range batch {
+		copyItems[item.Entry.Path] = item
+		files = append(files, item.Entry.Path)
+	}
+	// the trailing slash makes the tar entries relative to the source directory
+	tarSrcConn := *srcConn
+	tarSrcConn.Path = srcConn.Path + fspath.Separator
+	copyOpts := &wshrpc.FileCopyOpts{Recursive: true, Timeout: opts.Timeout, Files: files}
+	readCtx, cancel := context.WithCancelCause(ctx)
+	defer cancel(nil)
+	ioch := srcClient.ReadTarStream(readCtx, &tarSrcConn, copyOpts)
+	err := tarcopy.TarCopyDest(readCtx, cancel, ioch, func(next *tar.Header, reader *tar.Reader, singleFile bool) error {
+		if next.Typeflag == tar.TypeDir {
+			return nil
+		}
+		relPath := fspath.ToSlash(next.Name)
+		item, ok := copyItems[relPath]
+		if !ok {
+			return fmt.Errorf("protocol error: unexpected file %q in tar stream", relPath)
+		}
+		var modTime int64
+		if keepsModTime {
+			modTime = item.Entry.ModTime
+		}
+		if err := syncPutFile(ctx, destClient, syncChildConn(destConn, relPath), next, reader, modTime); err != nil {
+			return fmt.Errorf("error writing %q: %w", relPath, err)
+		}
+		copied(item, next.Size)
+		return nil
+	})
+	if err != nil {
+		cancel(err)
+		return err
+	}
+	return nil
+}
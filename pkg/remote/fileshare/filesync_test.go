@@ -0,0 +1,173 @@
+// Copyright 2025, Command Line Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package fileshare
+
+import (
+	"archive/tar"
+	"bytes"
+	"context"
+	"encoding/base64"
+	"reflect"
+	"testing"
+
+	"github.com/wavetermdev/waveterm/pkg/remote/connparse"
+	"github.com/wavetermdev/waveterm/pkg/remote/fileshare/fstype"
+	"github.com/wavetermdev/waveterm/pkg/remote/fileshare/fsutil"
+	"github.com/wavetermdev/waveterm/pkg/wshrpc"
+)
+
+func makeManifest(entries ...*wshrpc.FileManifestEntry) map[string]*wshrpc.FileManifestEntry {
+	rtn := make(map[string]*wshrpc.FileManifestEntry)
+	for _, entry := range entries {
+		rtn[entry.Path] = entry
+	}
+	return rtn
+}
+
+func TestComputeSyncPlan(t *testing.T) {
+	src := makeManifest(
+		&wshrpc.FileManifestEntry{Path: "a.txt", Size: 10, ModTime: 100, Checksum: "aaa"},
+		&wshrpc.FileManifestEntry{Path: "b.txt", Size: 10, ModTime: 200, Checksum: "bbb"},
+		&wshrpc.FileManifestEntry{Path: "c.txt", Size: 10, ModTime: 100, Checksum: "ccc"},
+		&wshrpc.FileManifestEntry{Path: "dir", IsDir: true},
+		&wshrpc.FileManifestEntry{Path: "dir/d.txt", Size: 5, ModTime: 100},
+		&wshrpc.FileManifestEntry{Path: "e", Size: 1, ModTime: 100},
+	)
+	dest := makeManifest(
+		&wshrpc.FileManifestEntry{Path: "a.txt", Size: 10, ModTime: 150, Checksum: "aaa"},
+		&wshrpc.FileManifestEntry{Path: "b.txt", Size: 10, ModTime: 150, Checksum: "bbb"},
+		&wshrpc.FileManifestEntry{Path: "c.txt", Size: 11, ModTime: 150, Checksum: "xxx"},
+		&wshrpc.FileManifestEntry{Path: "e", IsDir: true},
+		&wshrpc.FileManifestEntry{Path: "e/old.txt", Size: 1, ModTime: 100},
+		&wshrpc.FileManifestEntry{Path: "extra", IsDir: true},
+		&wshrpc.FileManifestEntry{Path: "extra/f.txt", Size: 1, ModTime: 100},
+		&wshrpc.FileManifestEntry{Path: "g.txt", Size: 1, ModTime: 100},
+	)
+	getCopies := func(plan *syncPlan) map[string]string {
+		rtn := make(map[string]string)
+		for _, item := range plan.Copies {
+			rtn[item.Entry.Path] = item.Reason
+		}
+		return rtn
+	}
+	getDeletes := func(plan *syncPlan) []string {
+		var rtn []string
+		for _, entry := range plan.Deletes {
+			rtn = append(rtn, entry.Path)
+		}
+		return rtn
+	}
+
+	plan := computeSyncPlan(src, dest, &wshrpc.FileSyncOpts{}, false)
+	wantCopies := map[string]string{"b.txt": SyncReason_ModTime, "c.txt": SyncReason_Size, "dir/d.txt": SyncReason_New, "e": SyncReason_Type}
+	if got := getCopies(plan); !reflect.DeepEqual(got, wantCopies) {
+		t.Errorf("copies: got %v, want %v", got, wantCopies)
+	}
+	if got := getDeletes(plan); !reflect.DeepEqual(got, []string{"e"}) {
+		t.Errorf("deletes: got %v, want [e]", got)
+	}
+	if !reflect.DeepEqual(plan.Mkdirs, []string{"dir"}) {
+		t.Errorf("mkdirs: got %v, want [dir]", plan.Mkdirs)
+	}
+	if plan.Unchanged != 1 {
+		t.Errorf("unchanged: got %d, want 1", plan.Unchanged)
+	}
+
+	// with checksums, b.txt is unchanged even though it is newer
+	plan = computeSyncPlan(src, dest, &wshrpc.FileSyncOpts{Checksum: true, Delete: true}, false)
+	if _, ok := getCopies(plan)["b.txt"]; ok {
+		t.Errorf("b.txt should not be copied when checksums match")
+	}
+	wantDeletes := []string{"e", "extra", "g.txt"}
+	if got := getDeletes(plan); !reflect.DeepEqual(got, wantDeletes) {
+		t.Errorf("deletes: got %v, want %v", got, wantDeletes)
+	}
+
+	// if the destination keeps modtimes, an older source modtime (a touch on the destination) is a change too
+	plan = computeSyncPlan(src, dest, &wshrpc.FileSyncOpts{}, true)
+	wantCopies["a.txt"] = SyncReason_ModTime
+	if got := getCopies(plan); !reflect.DeepEqual(got, wantCopies) {
+		t.Errorf("copies: got %v, want %v", got, wantCopies)
+	}
+}
+
+type syncTestWrite struct {
+	Append  bool
+	Size    int
+	ModTime int64
+}
+
+// records the writes, the other methods are not used by syncPutFile
+type syncTestClient struct {
+	fstype.FileShareClient
+	writes []syncTestWrite
+}
+
+func (c *syncTestClient) GetCapability() wshrpc.FileShareCapability {
+	return wshrpc.FileShareCapability{CanAppend: true, CanSetModTime: true}
+}
+
+func (c *syncTestClient) write(data wshrpc.FileData, isAppend bool) error {
+	barr, err := base64.StdEncoding.DecodeString(data.Data64)
+	if err != nil {
+		return err
+	}
+	write := syncTestWrite{Append: isAppend, Size: len(barr)}
+	if data.Info.Opts != nil {
+		write.ModTime = data.Info.Opts.ModTime
+	}
+	c.writes = append(c.writes, write)
+	return nil
+}
+
+func (c *syncTestClient) PutFile(ctx context.Context, conn *connparse.Connection, data wshrpc.FileData) error {
+	return c.write(data, false)
+}
+
+func (c *syncTestClient) AppendFile(ctx context.Context, conn *connparse.Connection, data wshrpc.FileData) error {
+	return c.write(data, true)
+}
+
+func TestSyncPutFileModTime(t *testing.T) {
+	conn := &connparse.Connection{Scheme: connparse.ConnectionTypeWsh, Host: "local", Path: "/tmp/x"}
+	header := &tar.Header{Name: "x", Mode: 0644}
+	tests := []struct {
+		size int
+		want []syncTestWrite
+	}{
+		{10, []syncTestWrite{{Size: 10, ModTime: 1000}}},
+		{syncWriteChunkSize + 1, []syncTestWrite{{Size: syncWriteChunkSize}, {Append: true, Size: 1, ModTime: 1000}}},
+		// the size is a multiple of the chunk size, so the modtime comes with an empty write
+		{2 * syncWriteChunkSize, []syncTestWrite{{Size: syncWriteChunkSize}, {Append: true, Size: syncWriteChunkSize}, {Append: true, ModTime: 1000}}},
+	}
+	for _, tt := range tests {
+		client := &syncTestClient{}
+		err := syncPutFile(context.Background(), client, conn, header, bytes.NewReader(make([]byte, tt.size)), 1000)
+		if err != nil {
+			t.Fatalf("size %d: error writing file: %v", tt.size, err)
+		}
+		if !reflect.DeepEqual(client.writes, tt.want) {
+			t.Errorf("size %d: got writes %v, want %v", tt.size, client.writes, tt.want)
+		}
+	}
+}
+
+func TestMatchExclude(t *testing.T) {
+	excludes := []string{"node_modules", "*.log", "build/tmp"}
+	tests := map[string]bool{
+		"src/main.go":              false,
+		"node_modules":             true,
+		"web/node_modules/x/y.js":  true,
+		"debug.log":                true,
+		"logs/debug.log":           true,
+		"build/tmp":                true,
+		"build/out":                false,
+		"node_modules_backup/a.js": false,
+	}
+	for path, want := range tests {
+		if got := fsutil.MatchExclude(path, excludes); got != want {
+			t.Errorf("MatchExclude(%q) = %v, want %v", path, got, want)
+		}
+	}
+}
@@ -0,0 +1,79 @@
+// Copyright 2025, Command Line Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package fileshare
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/wavetermdev/waveterm/pkg/panichandler"
+	"github.com/wavetermdev/waveterm/pkg/remote/connparse"
+	"github.com/wavetermdev/waveterm/pkg/remote/fileshare/fspath"
+	"github.com/wavetermdev/waveterm/pkg/remote/fileshare/fstype"
+	"github.com/wavetermdev/waveterm/pkg/remote/fileshare/wshfs"
+	"github.com/wavetermdev/waveterm/pkg/wps"
+	"github.com/wavetermdev/waveterm/pkg/wshrpc"
+	"github.com/wavetermdev/waveterm/pkg/wshrpc/wshclient"
+	"github.com/wavetermdev/waveterm/pkg/wshutil"
+)
+
+// Watch streams change events for data.Uri until ctx is done.  Event paths are returned as full URIs.
+// Each event is also published as a file:change event, scoped to both the watched URI and the URI of the changed entry's parent directory, so that open file browsers can refresh.
+// Only wsh connections are supported.
+func Watch(ctx context.Context, data wshrpc.CommandFileWatchData) <-chan wshrpc.RespOrErrorUnion[wshrpc.FileWatchEvent] {
+	log.Printf("Watch: %v", data.Uri)
+	conn, err := connparse.ParseURIAndReplaceCurrentHost(ctx, data.Uri)
+	if err != nil {
+		return wshutil.SendErrCh[wshrpc.FileWatchEvent](fmt.Errorf(ErrorParsingConnection, data.Uri))
+	}
+	if conn.GetType() != connparse.ConnectionTypeWsh {
+		return wshutil.SendErrCh[wshrpc.FileWatchEvent](fmt.Errorf("file watch is not supported for %s connections", conn.GetType()))
+	}
+	timeout := fstype.DefaultTimeout
+	if deadline, ok := ctx.Deadline(); ok {
+		timeout = time.Until(deadline)
+	}
+	rpcOpts := &wshrpc.RpcOpts{Route: wshutil.MakeConnectionRouteId(conn.Host), Timeout: timeout.Milliseconds()}
+	remoteData := wshrpc.CommandRemoteFileWatchData{Path: conn.Path, Recursive: data.Recursive, Excludes: data.Excludes}
+	remoteCh := wshclient.RemoteFileWatchCommand(wshfs.RpcClient, remoteData, rpcOpts)
+	watchUri := conn.GetFullURI()
+	rtn := make(chan wshrpc.RespOrErrorUnion[wshrpc.FileWatchEvent], 32)
+	go func() {
+		defer func() {
+			panichandler.PanicHandler("fileshare.Watch", recover())
+		}()
+		defer close(rtn)
+		for {
+			select {
+			case <-ctx.Done():
+				if rpcOpts.StreamCancelFn != nil {
+					rpcOpts.StreamCancelFn()
+				}
+				return
+			case respUnion, ok := <-remoteCh:
+				if !ok {
+					return
+				}
+				if respUnion.Error != nil {
+					rtn <- respUnion
+					return
+				}
+				event := respUnion.Response
+				eventConn := *conn
+				eventConn.Path = event.Path
+				event.Path = eventConn.GetFullURI()
+				eventConn.Path = fspath.Dir(eventConn.Path)
+				wps.Broker.Publish(wps.WaveEvent{
+					Event:  wps.Event_FileChange,
+					Scopes: []string{watchUri, eventConn.GetFullURI()},
+					Data:   event,
+				})
+				rtn <- wshrpc.RespOrErrorUnion[wshrpc.FileWatchEvent]{Response: event}
+			}
+		}
+	}()
+	return rtn
+}
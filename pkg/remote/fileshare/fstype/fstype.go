@@ -35,6 +35,8 @@ type FileShareClient interface {
 	ListEntries(ctx context.Context, conn *connparse.Connection, opts *wshrpc.FileListOpts) ([]*wshrpc.FileInfo, error)
 	// ListEntriesStream returns a stream of entries at the given path
 	ListEntriesStream(ctx context.Context, conn *connparse.Connection, opts *wshrpc.FileListOpts) <-chan wshrpc.RespOrErrorUnion[wshrpc.CommandRemoteListEntriesRtnData]
+	// Search returns a stream of files under the given path that match the search options
+	Search(ctx context.Context, conn *connparse.Connection, opts *wshrpc.FileSearchOpts) <-chan wshrpc.RespOrErrorUnion[wshrpc.CommandFileSearchRtnData]
 	// PutFile writes the given data to the file at the given path
 	PutFile(ctx context.Context, conn *connparse.Connection, data wshrpc.FileData) error
 	// AppendFile appends the given data to the file at the given path
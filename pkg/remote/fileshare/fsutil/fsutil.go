@@ -10,6 +10,7 @@ import (
 	"io"
 	"io/fs"
 	"log"
+	pathpkg "path"
 	"strings"
 
 	"github.com/wavetermdev/waveterm/pkg/remote/connparse"
@@ -314,3 +315,126 @@ func ReadFileStreamToWriter(ctx context.Context, readCh <-chan wshrpc.RespOrErro
 		return err
 	})
 }
+
+// MatchExclude returns true if the relative path matches one of the exclude globs.
+// Patterns are matched against the whole path and against each path element, so "node_modules" excludes the directory anywhere in the tree, and "*.o" excludes object files in any directory.
+func MatchExclude(relPath string, excludes []string) bool {
+	if len(excludes) == 0 || relPath == "" {
+		return false
+	}
+	parts := strings.Split(relPath, fspath.Separator)
+	for _, pattern := range excludes {
+		pattern = strings.TrimSuffix(pattern, fspath.Separator)
+		if pattern == "" {
+			continue
+		}
+		if ok, _ := pathpkg.Match(pattern, relPath); ok {
+			return true
+		}
+		for _, part := range parts {
+			if ok, _ := pathpkg.Match(pattern, part); ok {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// ListManifest recursively lists the entries under conn using ListEntries, for filesystems that do not support RemoteFileManifestCommand.
+// Returned paths are relative to conn.Path. Checksums are not computed.
+func ListManifest(ctx context.Context, client fstype.FileShareClient, conn *connparse.Connection, excludes []string) ([]*wshrpc.FileManifestEntry, error) {
+	finfo, err := client.Stat(ctx, conn)
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return nil, fmt.Errorf("error getting file info: %w", err)
+	}
+	if err != nil || finfo.NotFound {
+		return nil, nil
+	}
+	if !finfo.IsDir {
+		return nil, fmt.Errorf("%q is not a directory", conn.GetFullURI())
+	}
+	var rtn []*wshrpc.FileManifestEntry
+	var listDir func(dirPath string, relDir string) error
+	listDir = func(dirPath string, relDir string) error {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		dirConn := *conn
+		dirConn.Path = dirPath
+		entries, err := client.ListEntries(ctx, &dirConn, nil)
+		if err != nil {
+			return fmt.Errorf("error listing %q: %w", dirConn.GetFullURI(), err)
+		}
+		for _, entry := range entries {
+			name := fspath.Base(entry.Name)
+			if name == "" {
+				continue
+			}
+			relPath := name
+			if relDir != "" {
+				relPath = relDir + fspath.Separator + name
+			}
+			if MatchExclude(relPath, excludes) {
+				continue
+			}
+			rtn = append(rtn, &wshrpc.FileManifestEntry{
+				Path:    relPath,
+				Size:    max(entry.Size, 0),
+				ModTime: entry.ModTime,
+				Mode:    entry.Mode,
+				IsDir:   entry.IsDir,
+			})
+			if entry.IsDir {
+				if err := listDir(fspath.Join(dirPath, name), relPath); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	}
+	if err := listDir(conn.Path, ""); err != nil {
+		return nil, err
+	}
+	return rtn, nil
+}
+
+// FileFilter restricts a tar stream to the files listed in FileCopyOpts.Files (and the directories that contain them).
+// A nil FileFilter matches everything.
+type FileFilter struct {
+	files map[string]bool
+	dirs  map[string]bool
+}
+
+// MakeFileFilter returns nil if files is empty
+func MakeFileFilter(files []string) *FileFilter {
+	if len(files) == 0 {
+		return nil
+	}
+	rtn := &FileFilter{files: make(map[string]bool), dirs: make(map[string]bool)}
+	for _, file := range files {
+		file = strings.Trim(fspath.ToSlash(file), fspath.Separator)
+		if file == "" {
+			continue
+		}
+		rtn.files[file] = true
+		for dir := fspath.Dir(file); dir != "." && dir != fspath.Separator; dir = fspath.Dir(dir) {
+			rtn.dirs[dir] = true
+		}
+	}
+	return rtn
+}
+
+func (f *FileFilter) HasFile(relPath string) bool {
+	if f == nil {
+		return true
+	}
+	return f.files[strings.Trim(fspath.ToSlash(relPath), fspath.Separator)]
+}
+
+func (f *FileFilter) HasDir(relPath string) bool {
+	if f == nil {
+		return true
+	}
+	relPath = strings.Trim(fspath.ToSlash(relPath), fspath.Separator)
+	return relPath == "" || relPath == "." || f.dirs[relPath]
+}
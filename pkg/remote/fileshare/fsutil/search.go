@@ -0,0 +1,199 @@
+// Copyright 2025, Command Line Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package fsutil
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	pathpkg "path"
+	"regexp"
+	"strings"
+
+	"github.com/wavetermdev/waveterm/pkg/remote/fileshare/fspath"
+	"github.com/wavetermdev/waveterm/pkg/wshrpc"
+)
+
+const (
+	DefaultSearchMaxResults = 1000
+	MaxSearchLineMatches    = 100 // per file
+	MaxSearchLineLen        = 512
+	MaxSearchScanLineLen    = 1024 * 1024
+	searchBinaryCheckLen    = 8000
+)
+
+// FileSearcher applies FileSearchOpts to candidate files.  It is shared by the Search implementations so that
+// name, size, mtime and content matching behave the same on every filesystem.
+type FileSearcher struct {
+	opts         wshrpc.FileSearchOpts
+	namePatterns []string
+	contentRe    *regexp.Regexp
+	maxResults   int
+	numResults   int
+}
+
+func MakeFileSearcher(opts *wshrpc.FileSearchOpts) (*FileSearcher, error) {
+	rtn := &FileSearcher{maxResults: DefaultSearchMaxResults}
+	if opts != nil {
+		rtn.opts = *opts
+	}
+	if rtn.opts.MaxResults > 0 {
+		rtn.maxResults = rtn.opts.MaxResults
+	}
+	for _, pattern := range rtn.opts.NamePatterns {
+		if pattern == "" {
+			continue
+		}
+		if rtn.opts.IgnoreCase {
+			pattern = strings.ToLower(pattern)
+		}
+		if _, err := pathpkg.Match(pattern, ""); err != nil {
+			return nil, fmt.Errorf("invalid name pattern %q: %w", pattern, err)
+		}
+		rtn.namePatterns = append(rtn.namePatterns, pattern)
+	}
+	if rtn.opts.ContentRegex != "" {
+		reStr := rtn.opts.ContentRegex
+		if rtn.opts.IgnoreCase {
+			reStr = "(?i)" + reStr
+		}
+		re, err := regexp.Compile(reStr)
+		if err != nil {
+			return nil, fmt.Errorf("invalid content regex: %w", err)
+		}
+		rtn.contentRe = re
+	}
+	return rtn, nil
+}
+
+func (s *FileSearcher) HasContentSearch() bool {
+	return s.contentRe != nil
+}
+
+// Done returns true once MaxResults matches have been returned
+func (s *FileSearcher) Done() bool {
+	return s.numResults >= s.maxResults
+}
+
+// IsExcluded returns true if relPath (relative to the search root) matches one of the exclude globs.
+// Callers should not descend into excluded directories.
+func (s *FileSearcher) IsExcluded(relPath string) bool {
+	return MatchExclude(relPath, s.opts.Excludes)
+}
+
+func (s *FileSearcher) matchName(relPath string) bool {
+	if len(s.namePatterns) == 0 {
+		return true
+	}
+	relPath = fspath.ToSlash(relPath)
+	name := fspath.Base(relPath)
+	if s.opts.IgnoreCase {
+		relPath = strings.ToLower(relPath)
+		name = strings.ToLower(name)
+	}
+	for _, pattern := range s.namePatterns {
+		target := name
+		if strings.Contains(pattern, fspath.Separator) {
+			target = relPath
+		}
+		if ok, _ := pathpkg.Match(pattern, target); ok {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *FileSearcher) matchInfo(finfo *wshrpc.FileInfo) bool {
+	if finfo.IsDir {
+		if !s.opts.IncludeDirs || s.contentRe != nil {
+			return false
+		}
+	} else {
+		if s.opts.MinSize > 0 && finfo.Size < s.opts.MinSize {
+			return false
+		}
+		if s.opts.MaxSize > 0 && finfo.Size > s.opts.MaxSize {
+			return false
+		}
+	}
+	if s.opts.ModifiedAfter > 0 && finfo.ModTime < s.opts.ModifiedAfter {
+		return false
+	}
+	if s.opts.ModifiedBefore > 0 && finfo.ModTime >= s.opts.ModifiedBefore {
+		return false
+	}
+	return true
+}
+
+// Match checks the file against the search options and returns a result, or nil if it does not match.
+// openFn is only called when a content regex is set and the name and info filters have matched.
+// Files that cannot be opened or look binary are skipped rather than failing the search.
+func (s *FileSearcher) Match(relPath string, finfo *wshrpc.FileInfo, openFn func() (io.ReadCloser, error)) (*wshrpc.FileSearchResult, error) {
+	if s.Done() || finfo == nil {
+		return nil, nil
+	}
+	if !s.matchName(relPath) || !s.matchInfo(finfo) {
+		return nil, nil
+	}
+	rtn := &wshrpc.FileSearchResult{Info: finfo}
+	if s.contentRe != nil {
+		reader, err := openFn()
+		if err != nil {
+			return nil, nil
+		}
+		defer reader.Close()
+		matches, err := s.MatchContent(reader)
+		if err != nil {
+			return nil, err
+		}
+		if len(matches) == 0 {
+			return nil, nil
+		}
+		rtn.Matches = matches
+	}
+	s.numResults++
+	return rtn, nil
+}
+
+// MatchContent returns the lines of reader that match the content regex (up to MaxSearchLineMatches).
+// Returns nil for binary content (a NUL byte near the start of the file).
+func (s *FileSearcher) MatchContent(reader io.Reader) ([]*wshrpc.FileSearchLineMatch, error) {
+	if s.contentRe == nil {
+		return nil, nil
+	}
+	bufReader := bufio.NewReaderSize(reader, searchBinaryCheckLen)
+	head, err := bufReader.Peek(searchBinaryCheckLen)
+	if err != nil && err != io.EOF && err != bufio.ErrBufferFull {
+		return nil, nil
+	}
+	if bytes.IndexByte(head, 0) >= 0 {
+		return nil, nil
+	}
+	var rtn []*wshrpc.FileSearchLineMatch
+	scanner := bufio.NewScanner(bufReader)
+	scanner.Buffer(make([]byte, 0, 64*1024), MaxSearchScanLineLen)
+	lineNum := 0
+	for scanner.Scan() {
+		lineNum++
+		line := scanner.Bytes()
+		if !s.contentRe.Match(line) {
+			continue
+		}
+		rtn = append(rtn, &wshrpc.FileSearchLineMatch{LineNum: lineNum, Line: truncateSearchLine(line)})
+		if len(rtn) >= MaxSearchLineMatches {
+			break
+		}
+	}
+	// a read error or an overlong line ends the scan of this file, but keeps the matches found so far
+	return rtn, nil
+}
+
+func truncateSearchLine(line []byte) string {
+	line = bytes.TrimRight(line, "\r")
+	if len(line) > MaxSearchLineLen {
+		line = line[:MaxSearchLineLen]
+	}
+	return strings.ToValidUTF8(string(line), "")
+}
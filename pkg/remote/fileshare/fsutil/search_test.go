@@ -0,0 +1,92 @@
+// Copyright 2025, Command Line Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package fsutil
+
+import (
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/wavetermdev/waveterm/pkg/wshrpc"
+)
+
+func openString(str string) func() (io.ReadCloser, error) {
+	return func() (io.ReadCloser, error) {
+		return io.NopCloser(strings.NewReader(str)), nil
+	}
+}
+
+func TestFileSearcherMatch(t *testing.T) {
+	searcher, err := MakeFileSearcher(&wshrpc.FileSearchOpts{
+		NamePatterns:  []string{"*.GO", "docs/*.md"},
+		IgnoreCase:    true,
+		MinSize:       10,
+		ModifiedAfter: 1000,
+	})
+	if err != nil {
+		t.Fatalf("error making searcher: %v", err)
+	}
+	tests := []struct {
+		relPath string
+		info    wshrpc.FileInfo
+		want    bool
+	}{
+		{"main.go", wshrpc.FileInfo{Size: 100, ModTime: 2000}, true},
+		{"pkg/util/util.go", wshrpc.FileInfo{Size: 100, ModTime: 2000}, true},
+		{"docs/readme.md", wshrpc.FileInfo{Size: 100, ModTime: 2000}, true},
+		{"other/readme.md", wshrpc.FileInfo{Size: 100, ModTime: 2000}, false},
+		{"small.go", wshrpc.FileInfo{Size: 5, ModTime: 2000}, false},
+		{"old.go", wshrpc.FileInfo{Size: 100, ModTime: 500}, false},
+		{"dir.go", wshrpc.FileInfo{IsDir: true, ModTime: 2000}, false},
+	}
+	for _, test := range tests {
+		info := test.info
+		result, err := searcher.Match(test.relPath, &info, nil)
+		if err != nil {
+			t.Fatalf("error matching %q: %v", test.relPath, err)
+		}
+		if got := result != nil; got != test.want {
+			t.Errorf("Match(%q) = %v, want %v", test.relPath, got, test.want)
+		}
+	}
+}
+
+func TestFileSearcherContent(t *testing.T) {
+	searcher, err := MakeFileSearcher(&wshrpc.FileSearchOpts{ContentRegex: "todo", IgnoreCase: true, MaxResults: 2})
+	if err != nil {
+		t.Fatalf("error making searcher: %v", err)
+	}
+	info := &wshrpc.FileInfo{Size: 100}
+	result, err := searcher.Match("a.txt", info, openString("first\r\n// TODO: fix\r\nlast todo\n"))
+	if err != nil || result == nil {
+		t.Fatalf("expected match, got %v, %v", result, err)
+	}
+	if len(result.Matches) != 2 || result.Matches[0].LineNum != 2 || result.Matches[0].Line != "// TODO: fix" || result.Matches[1].LineNum != 3 {
+		t.Errorf("unexpected line matches: %+v, %+v", result.Matches[0], result.Matches[1])
+	}
+	if result, _ := searcher.Match("b.txt", info, openString("nothing here\n")); result != nil {
+		t.Errorf("expected no match for b.txt")
+	}
+	if result, _ := searcher.Match("c.bin", info, openString("todo\x00binary")); result != nil {
+		t.Errorf("expected binary file to be skipped")
+	}
+	if result, _ := searcher.Match("d.txt", info, openString("todo\n")); result == nil {
+		t.Errorf("expected match for d.txt")
+	}
+	if !searcher.Done() {
+		t.Errorf("expected searcher to be done after MaxResults matches")
+	}
+	if result, _ := searcher.Match("e.txt", info, openString("todo\n")); result != nil {
+		t.Errorf("expected no results after MaxResults")
+	}
+}
+
+func TestFileSearcherInvalidOpts(t *testing.T) {
+	if _, err := MakeFileSearcher(&wshrpc.FileSearchOpts{ContentRegex: "("}); err == nil {
+		t.Errorf("expected error for invalid regex")
+	}
+	if _, err := MakeFileSearcher(&wshrpc.FileSearchOpts{NamePatterns: []string{"[a-"}}); err == nil {
+		t.Errorf("expected error for invalid name pattern")
+	}
+}
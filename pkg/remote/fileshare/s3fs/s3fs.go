@@ -296,7 +296,11 @@ func (c S3Client) ReadTarStream(ctx context.Context, conn *connparse.Connection,
 				tree.Add(path)
 			}
 
+			fileFilter := fsutil.MakeFileFilter(opts.Files)
 			if err := c.listFilesPrefix(ctx, input, func(obj *types.Object) (bool, error) {
+				if fileFilter != nil && !fileFilter.HasFile(strings.TrimPrefix(*obj.Key, aws.ToString(input.Prefix))) {
+					return true, nil
+				}
 				wg.Add(1)
 				go getObjectAndFileInfo(obj)
 				return true, nil
@@ -485,6 +489,93 @@ func (c S3Client) ListEntriesStream(ctx context.Context, conn *connparse.Connect
 	}
 }
 
+func (c S3Client) Search(ctx context.Context, conn *connparse.Connection, opts *wshrpc.FileSearchOpts) <-chan wshrpc.RespOrErrorUnion[wshrpc.CommandFileSearchRtnData] {
+	bucket := conn.Host
+	if bucket == "" || bucket == fspath.Separator {
+		return wshutil.SendErrCh[wshrpc.CommandFileSearchRtnData](fmt.Errorf("bucket must be specified"))
+	}
+	searcher, err := fsutil.MakeFileSearcher(opts)
+	if err != nil {
+		return wshutil.SendErrCh[wshrpc.CommandFileSearchRtnData](err)
+	}
+	keyPrefix := strings.Trim(conn.Path, fspath.Separator)
+	rtn := make(chan wshrpc.RespOrErrorUnion[wshrpc.CommandFileSearchRtnData], 16)
+	go func() {
+		defer close(rtn)
+		var results []*wshrpc.FileSearchResult
+		flush := func() {
+			if len(results) > 0 {
+				rtn <- wshrpc.RespOrErrorUnion[wshrpc.CommandFileSearchRtnData]{Response: wshrpc.CommandFileSearchRtnData{Results: results}}
+				results = nil
+			}
+		}
+		if err := c.listFilesPrefix(ctx, &s3.ListObjectsV2Input{
+			Bucket: aws.String(bucket),
+			Prefix: aws.String(keyPrefix),
+		}, func(obj *types.Object) (bool, error) {
+			if searcher.Done() {
+				return false, nil
+			}
+			if obj.Key == nil || strings.HasSuffix(*obj.Key, fspath.Separator) {
+				// skip "directory" placeholder objects
+				return true, nil
+			}
+			key := *obj.Key
+			relPath := key
+			if keyPrefix != "" {
+				if key == keyPrefix {
+					relPath = fspath.Base(key)
+				} else if strings.HasPrefix(key, keyPrefix+fspath.Separator) {
+					relPath = strings.TrimPrefix(key, keyPrefix+fspath.Separator)
+				} else {
+					return true, nil
+				}
+			}
+			if searcher.IsExcluded(relPath) {
+				return true, nil
+			}
+			path := fspath.Join(bucket, key)
+			finfo := &wshrpc.FileInfo{
+				Name: key,
+				Dir:  fsutil.GetParentPathString(path),
+				Path: path,
+			}
+			if obj.Size != nil {
+				finfo.Size = *obj.Size
+			}
+			if obj.LastModified != nil {
+				finfo.ModTime = obj.LastModified.UnixMilli()
+			}
+			fileutil.AddMimeTypeToFileInfo(path, finfo)
+			result, err := searcher.Match(relPath, finfo, func() (io.ReadCloser, error) {
+				output, err := c.client.GetObject(ctx, &s3.GetObjectInput{
+					Bucket: aws.String(bucket),
+					Key:    aws.String(key),
+				})
+				if err != nil {
+					return nil, err
+				}
+				return output.Body, nil
+			})
+			if err != nil {
+				return false, err
+			}
+			if result != nil {
+				results = append(results, result)
+				if searcher.HasContentSearch() || len(results) >= wshrpc.DirChunkSize {
+					flush()
+				}
+			}
+			return true, nil
+		}); err != nil {
+			rtn <- wshutil.RespErr[wshrpc.CommandFileSearchRtnData](err)
+			return
+		}
+		flush()
+	}()
+	return rtn
+}
+
 func (c S3Client) Stat(ctx context.Context, conn *connparse.Connection) (*wshrpc.FileInfo, error) {
 	bucketName := conn.Host
 	objectKey := conn.Path
@@ -4,6 +4,7 @@
 package wavefs
 
 import (
+	"bytes"
 	"context"
 	"encoding/base64"
 	"errors"
@@ -146,8 +147,26 @@ func (c WaveClient) ReadTarStream(ctx context.Context, conn *connparse.Connectio
 	schemeAndHost := conn.GetSchemeAndHost() + "/"
 
 	var entries []*wshrpc.FileInfo
+	var fileFilter *fsutil.FileFilter
+	if opts != nil && !singleFile {
+		fileFilter = fsutil.MakeFileFilter(opts.Files)
+	}
 	if singleFile {
 		entries = []*wshrpc.FileInfo{finfo}
+	} else if fileFilter != nil {
+		allEntries, err := c.ListEntries(ctx, conn, &wshrpc.FileListOpts{All: true})
+		if err != nil {
+			return wshutil.SendErrCh[iochantypes.Packet](fmt.Errorf("error listing blockfiles: %w", err))
+		}
+		dirPrefix := ""
+		if cleanedPath != "" {
+			dirPrefix = cleanedPath + fspath.Separator
+		}
+		for _, entry := range allEntries {
+			if fileFilter.HasFile(strings.TrimPrefix(entry.Name, dirPrefix)) {
+				entries = append(entries, entry)
+			}
+		}
 	} else {
 		entries, err = c.ListEntries(ctx, conn, nil)
 		if err != nil {
@@ -173,8 +192,14 @@ func (c WaveClient) ReadTarStream(ctx context.Context, conn *connparse.Connectio
 				return
 			}
 			file.Mode = 0644
+			internalPath := strings.TrimPrefix(file.Path, schemeAndHost)
+			headerPath := file.Path
+			if fileFilter != nil {
+				// filtered entries are always files relative to the source directory
+				headerPath = internalPath
+			}
 
-			if err = writeHeader(fileutil.ToFsFileInfo(file), file.Path, singleFile); err != nil {
+			if err = writeHeader(fileutil.ToFsFileInfo(file), headerPath, singleFile); err != nil {
 				rtn <- wshutil.RespErr[iochantypes.Packet](fmt.Errorf("error writing tar header: %w", err))
 				return
 			}
@@ -184,8 +209,6 @@ func (c WaveClient) ReadTarStream(ctx context.Context, conn *connparse.Connectio
 
 			log.Printf("ReadTarStream: reading file: %s\n", file.Path)
 
-			internalPath := strings.TrimPrefix(file.Path, schemeAndHost)
-
 			_, dataBuf, err := filestore.WFS.ReadFile(ctx, conn.Host, internalPath)
 			if err != nil {
 				rtn <- wshutil.RespErr[iochantypes.Packet](fmt.Errorf("error reading blockfile: %w", err))
@@ -277,6 +300,72 @@ func (c WaveClient) ListEntries(ctx context.Context, conn *connparse.Connection,
 	return fileList, nil
 }
 
+// Search matches the files under the given path prefix.  Wave files have no real directories, so IncludeDirs has no effect.
+func (c WaveClient) Search(ctx context.Context, conn *connparse.Connection, opts *wshrpc.FileSearchOpts) <-chan wshrpc.RespOrErrorUnion[wshrpc.CommandFileSearchRtnData] {
+	ch := make(chan wshrpc.RespOrErrorUnion[wshrpc.CommandFileSearchRtnData], 16)
+	go func() {
+		defer close(ch)
+		searcher, err := fsutil.MakeFileSearcher(opts)
+		if err != nil {
+			ch <- wshutil.RespErr[wshrpc.CommandFileSearchRtnData](err)
+			return
+		}
+		zoneId := conn.Host
+		prefix, err := cleanPath(conn.Path)
+		if err != nil {
+			ch <- wshutil.RespErr[wshrpc.CommandFileSearchRtnData](fmt.Errorf("error cleaning path: %w", err))
+			return
+		}
+		var fileList []*filestore.WaveFile
+		if err := listFilesPrefix(ctx, zoneId, "", func(wf *filestore.WaveFile) error {
+			if prefix == "" || wf.Name == prefix || strings.HasPrefix(wf.Name, prefix+fspath.Separator) {
+				fileList = append(fileList, wf)
+			}
+			return nil
+		}); err != nil {
+			ch <- wshutil.RespErr[wshrpc.CommandFileSearchRtnData](fmt.Errorf("error listing entries: %w", err))
+			return
+		}
+		var results []*wshrpc.FileSearchResult
+		for _, wf := range fileList {
+			if ctx.Err() != nil {
+				ch <- wshutil.RespErr[wshrpc.CommandFileSearchRtnData](ctx.Err())
+				return
+			}
+			if searcher.Done() {
+				break
+			}
+			relPath := wf.Name
+			if prefix != "" && wf.Name != prefix {
+				relPath = strings.TrimPrefix(wf.Name, prefix+fspath.Separator)
+			}
+			if searcher.IsExcluded(relPath) {
+				continue
+			}
+			finfo := wavefileutil.WaveFileToFileInfo(wf)
+			finfo.ModTime = wf.ModTs
+			result, err := searcher.Match(relPath, finfo, func() (io.ReadCloser, error) {
+				_, data, err := filestore.WFS.ReadFile(ctx, zoneId, wf.Name)
+				if err != nil {
+					return nil, err
+				}
+				return io.NopCloser(bytes.NewReader(data)), nil
+			})
+			if err != nil {
+				ch <- wshutil.RespErr[wshrpc.CommandFileSearchRtnData](err)
+				return
+			}
+			if result != nil {
+				results = append(results, result)
+			}
+		}
+		for i := 0; i < len(results); i += wshrpc.DirChunkSize {
+			ch <- wshrpc.RespOrErrorUnion[wshrpc.CommandFileSearchRtnData]{Response: wshrpc.CommandFileSearchRtnData{Results: results[i:min(i+wshrpc.DirChunkSize, len(results))]}}
+		}
+	}()
+	return ch
+}
+
 func (c WaveClient) Stat(ctx context.Context, conn *connparse.Connection) (*wshrpc.FileInfo, error) {
 	zoneId := conn.Host
 	if zoneId == "" {
@@ -66,6 +66,14 @@ func (c WshClient) ListEntriesStream(ctx context.Context, conn *connparse.Connec
 	return wshclient.RemoteListEntriesCommand(RpcClient, wshrpc.CommandRemoteListEntriesData{Path: conn.Path, Opts: opts}, &wshrpc.RpcOpts{Route: wshutil.MakeConnectionRouteId(conn.Host)})
 }
 
+func (c WshClient) Search(ctx context.Context, conn *connparse.Connection, opts *wshrpc.FileSearchOpts) <-chan wshrpc.RespOrErrorUnion[wshrpc.CommandFileSearchRtnData] {
+	timeout := fstype.DefaultTimeout.Milliseconds()
+	if opts != nil && opts.Timeout > 0 {
+		timeout = opts.Timeout
+	}
+	return wshclient.RemoteFileSearchCommand(RpcClient, wshrpc.FileSearchData{Path: conn.Path, Opts: opts}, &wshrpc.RpcOpts{Route: wshutil.MakeConnectionRouteId(conn.Host), Timeout: timeout})
+}
+
 func (c WshClient) Stat(ctx context.Context, conn *connparse.Connection) (*wshrpc.FileInfo, error) {
 	return wshclient.RemoteFileInfoCommand(RpcClient, conn.Path, &wshrpc.RpcOpts{Route: wshutil.MakeConnectionRouteId(conn.Host)})
 }
@@ -142,5 +150,5 @@ func (c WshClient) GetConnectionType() string {
 }
 
 func (c WshClient) GetCapability() wshrpc.FileShareCapability {
-	return wshrpc.FileShareCapability{CanAppend: true, CanMkdir: true}
+	return wshrpc.FileShareCapability{CanAppend: true, CanMkdir: true, CanSetModTime: true}
 }
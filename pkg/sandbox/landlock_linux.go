@@ -0,0 +1,75 @@
+// Copyright 2025, Command Line Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+//go:build linux
+
+package sandbox
+
+import (
+	"fmt"
+	"unsafe"
+
+	"golang.org/x/sys/unix"
+)
+
+// landlock (linux 6.7+, abi 4) can restrict tcp connects by port.  it is used to keep sandboxed shells that
+// share the host network away from the wavesrv ports.  landlock rules are an allow list, so every other port is
+// allowed.
+
+const (
+	landlockCreateRulesetVersion = 1
+	landlockRuleNetPort          = 2
+	landlockMinNetAbi            = 4
+)
+
+type landlockRulesetAttr struct {
+	HandledAccessFs  uint64
+	HandledAccessNet uint64
+}
+
+type landlockNetPortAttr struct {
+	AllowedAccess uint64
+	Port          uint64
+}
+
+func landlockNetSupported() bool {
+	abi, _, errno := unix.Syscall(unix.SYS_LANDLOCK_CREATE_RULESET, 0, 0, landlockCreateRulesetVersion)
+	return errno == 0 && int(abi) >= landlockMinNetAbi
+}
+
+var errNoLandlockNet = fmt.Errorf("blocking the wave ports needs landlock network rules (linux 6.7+), set shell:sandboxnonet to run without network instead")
+
+// blocks tcp connects to ports for this thread (and the process it execs).  must be called after no-new-privs is set.
+func blockTcpPorts(ports []int) error {
+	if len(ports) == 0 {
+		return nil
+	}
+	if !landlockNetSupported() {
+		return errNoLandlockNet
+	}
+	attr := landlockRulesetAttr{HandledAccessNet: unix.LANDLOCK_ACCESS_NET_CONNECT_TCP}
+	fd, _, errno := unix.Syscall(unix.SYS_LANDLOCK_CREATE_RULESET, uintptr(unsafe.Pointer(&attr)), unsafe.Sizeof(attr), 0)
+	if errno != 0 {
+		return fmt.Errorf("cannot create landlock ruleset: %w", errno)
+	}
+	defer unix.Close(int(fd))
+	blocked := make(map[int]bool)
+	for _, port := range ports {
+		blocked[port] = true
+	}
+	for port := 0; port <= 0xffff; port++ {
+		if blocked[port] {
+			continue
+		}
+		rule := landlockNetPortAttr{AllowedAccess: unix.LANDLOCK_ACCESS_NET_CONNECT_TCP, Port: uint64(port)}
+		_, _, errno = unix.Syscall6(unix.SYS_LANDLOCK_ADD_RULE, fd, landlockRuleNetPort, uintptr(unsafe.Pointer(&rule)), 0, 0, 0)
+		if errno != 0 {
+			return fmt.Errorf("cannot add landlock rule for port %d: %w", port, errno)
+		}
+	}
+	_, _, errno = unix.Syscall(unix.SYS_LANDLOCK_RESTRICT_SELF, fd, 0, 0)
+	if errno != 0 {
+		return fmt.Errorf("cannot restrict tcp ports: %w", errno)
+	}
+	return nil
+}
@@ -0,0 +1,24 @@
+// Copyright 2025, Command Line Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+// sandbox runs local shells in a restricted environment built with unprivileged linux namespaces (like bubblewrap).
+//
+// wavesrv starts "wsh sandboxexec" (stage 1) in a new user, mount and pid namespace (and network namespace if the
+// network is disabled), where it is root.  stage 1 makes every mount read-only (failing if one can't be), mounts a
+// new /proc that only shows the sandbox's processes, hides the home directory, the wave data dir, /tmp and /dev/shm behind empty tmpfs mounts, bind mounts the writable and read-only paths back in, and then starts stage 2 in a
+// nested user namespace that maps back to the real uid (so the sandbox can't undo the mounts).  stage 2 sets
+// no-new-privs, blocks the wavesrv tcp ports with landlock (unless the network is disabled), installs a seccomp
+// filter (no mounts, namespaces, ptrace, kernel modules, bpf, ...) and execs the shell.
+package sandbox
+
+// Opts are passed from wavesrv to stage 1 (and from stage 1 to stage 2) as json
+type Opts struct {
+	Writable      []string `json:"writable,omitempty"`      // absolute paths, writable in the sandbox (e.g. the project dir)
+	ReadOnly      []string `json:"readonly,omitempty"`      // absolute paths in hidden dirs that stay visible (read-only)
+	Hide          []string `json:"hide,omitempty"`          // dirs replaced by an empty tmpfs (e.g. the home dir)
+	UnsetEnv      []string `json:"unsetenv,omitempty"`      // env vars that are removed (e.g. agent sockets)
+	NoNetwork     bool     `json:"nonetwork,omitempty"`     // only a loopback interface
+	BlockTcpPorts []int    `json:"blocktcpports,omitempty"` // with the host network, ports that can't be connected to (needs linux 6.7+)
+	Uid           int      `json:"uid"`                     // the real uid/gid, set by WrapCmd
+	Gid           int      `json:"gid"`
+}
@@ -0,0 +1,405 @@
+// Copyright 2025, Command Line Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+//go:build linux
+
+package sandbox
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"os/signal"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"syscall"
+
+	"golang.org/x/sys/unix"
+)
+
+// flags that are kept when a mount is remounted (they are locked in a user namespace, so must not be cleared)
+const keptMountFlags = unix.MS_NOSUID | unix.MS_NODEV | unix.MS_NOEXEC | unix.MS_NOATIME | unix.MS_NODIRATIME | unix.MS_RELATIME
+
+// WrapCmd returns a command that runs ecmd (with its env and dir) in a sandbox.  wshPath is the wsh binary that
+// sets up the sandbox ("wsh sandboxexec").
+func WrapCmd(wshPath string, ecmd *exec.Cmd, opts Opts) (*exec.Cmd, error) {
+	if ecmd.Err != nil {
+		return nil, ecmd.Err
+	}
+	if !opts.NoNetwork && len(opts.BlockTcpPorts) > 0 && !landlockNetSupported() {
+		return nil, errNoLandlockNet
+	}
+	opts.Uid = os.Getuid()
+	opts.Gid = os.Getgid()
+	optsJson, err := json.Marshal(opts)
+	if err != nil {
+		return nil, fmt.Errorf("error marshaling sandbox opts: %w", err)
+	}
+	args := []string{"sandboxexec", "--opts", string(optsJson), "--", ecmd.Path}
+	args = append(args, ecmd.Args[1:]...)
+	wrapCmd := exec.Command(wshPath, args...)
+	for _, envStr := range ecmd.Env {
+		envKey, _, _ := strings.Cut(envStr, "=")
+		if !slices.Contains(opts.UnsetEnv, envKey) {
+			wrapCmd.Env = append(wrapCmd.Env, envStr)
+		}
+	}
+	wrapCmd.Dir = ecmd.Dir
+	// a new pid namespace, so the sandbox can't see (or reach through /proc/<pid>/root) the processes outside of it
+	cloneFlags := syscall.CLONE_NEWUSER | syscall.CLONE_NEWNS | syscall.CLONE_NEWIPC | syscall.CLONE_NEWPID
+	if opts.NoNetwork {
+		cloneFlags |= syscall.CLONE_NEWNET
+	}
+	wrapCmd.SysProcAttr = &syscall.SysProcAttr{
+		Cloneflags:  uintptr(cloneFlags),
+		UidMappings: []syscall.SysProcIDMap{{ContainerID: 0, HostID: opts.Uid, Size: 1}},
+		GidMappings: []syscall.SysProcIDMap{{ContainerID: 0, HostID: opts.Gid, Size: 1}},
+	}
+	return wrapCmd, nil
+}
+
+// Run is stage 1, it sets up the sandbox and runs args in it (through stage 2).  returns the exit code of args.
+// stage 1 is pid 1 of the sandbox's pid namespace, when it exits the kernel kills the processes left in the sandbox.
+func Run(opts Opts, args []string) (int, error) {
+	if len(args) == 0 {
+		return 0, fmt.Errorf("no command")
+	}
+	cwd, err := os.Getwd()
+	if err != nil {
+		cwd = "/"
+	}
+	err = setupMounts(opts)
+	if err != nil {
+		return 0, err
+	}
+	if opts.NoNetwork {
+		err = setLoopbackUp()
+		if err != nil {
+			return 0, fmt.Errorf("cannot set up loopback interface: %w", err)
+		}
+	}
+	if _, err := os.Stat(cwd); err != nil {
+		// the cwd was hidden
+		cwd = "/"
+		if len(opts.Hide) > 0 {
+			cwd = opts.Hide[0]
+		}
+	}
+	optsJson, err := json.Marshal(opts)
+	if err != nil {
+		return 0, fmt.Errorf("error marshaling sandbox opts: %w", err)
+	}
+	childArgs := append([]string{"sandboxexec", "--exec", "--opts", string(optsJson), "--"}, args...)
+	child := exec.Command("/proc/self/exe", childArgs...)
+	child.Env = os.Environ()
+	child.Dir = cwd
+	child.Stdin = os.Stdin
+	child.Stdout = os.Stdout
+	child.Stderr = os.Stderr
+	child.SysProcAttr = &syscall.SysProcAttr{
+		Cloneflags:  syscall.CLONE_NEWUSER,
+		UidMappings: []syscall.SysProcIDMap{{ContainerID: opts.Uid, HostID: 0, Size: 1}},
+		GidMappings: []syscall.SysProcIDMap{{ContainerID: opts.Gid, HostID: 0, Size: 1}},
+	}
+	// the shell is in our session (we are the session leader of the pty).  catch the terminal signals (so they
+	// stay at their defaults in the child), and pass on the ones wavesrv sends us.
+	sigCh := make(chan os.Signal, 8)
+	signal.Notify(sigCh, unix.SIGHUP, unix.SIGTERM, unix.SIGUSR1, unix.SIGUSR2, unix.SIGINT, unix.SIGQUIT, unix.SIGTSTP, unix.SIGTTIN, unix.SIGTTOU)
+	err = child.Start()
+	if err != nil {
+		return 0, fmt.Errorf("cannot start sandboxed command: %w", err)
+	}
+	go func() {
+		for sig := range sigCh {
+			switch sig {
+			case unix.SIGHUP, unix.SIGTERM, unix.SIGUSR1, unix.SIGUSR2:
+				child.Process.Signal(sig)
+			}
+		}
+	}()
+	// as pid 1 we also reap the orphaned processes in the sandbox
+	for {
+		var status unix.WaitStatus
+		pid, err := unix.Wait4(-1, &status, 0, nil)
+		if err == unix.EINTR {
+			continue
+		}
+		if err != nil {
+			return 0, err
+		}
+		if pid != child.Process.Pid {
+			continue
+		}
+		if status.Signaled() {
+			return 128 + int(status.Signal()), nil
+		}
+		return status.ExitStatus(), nil
+	}
+}
+
+// Exec is stage 2, it restricts the process and execs args
+func Exec(opts Opts, args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("no command")
+	}
+	// the landlock restriction is per thread, the exec must happen on the same thread
+	runtime.LockOSThread()
+	err := unix.Prctl(unix.PR_SET_NO_NEW_PRIVS, 1, 0, 0, 0)
+	if err != nil {
+		return fmt.Errorf("cannot set no-new-privs: %w", err)
+	}
+	if !opts.NoNetwork {
+		err = blockTcpPorts(opts.BlockTcpPorts)
+		if err != nil {
+			return err
+		}
+	}
+	err = installSeccompFilter()
+	if err != nil {
+		return err
+	}
+	path, err := exec.LookPath(args[0])
+	if err != nil {
+		return err
+	}
+	return syscall.Exec(path, args, os.Environ())
+}
+
+type bindMount struct {
+	path     string
+	fd       int
+	isDir    bool
+	writable bool
+}
+
+func openBind(path string, writable bool) (*bindMount, error) {
+	if !filepath.IsAbs(path) {
+		return nil, fmt.Errorf("sandbox path %q is not absolute", path)
+	}
+	fd, err := unix.Open(path, unix.O_PATH|unix.O_CLOEXEC, 0)
+	if err != nil {
+		return nil, fmt.Errorf("cannot open %s: %w", path, err)
+	}
+	var stat unix.Stat_t
+	err = unix.Fstat(fd, &stat)
+	if err != nil {
+		unix.Close(fd)
+		return nil, fmt.Errorf("cannot stat %s: %w", path, err)
+	}
+	return &bindMount{path: filepath.Clean(path), fd: fd, isDir: stat.Mode&unix.S_IFMT == unix.S_IFDIR, writable: writable}, nil
+}
+
+// remounts the mount at path (keeping its locked flags)
+func remount(path string, readOnly bool) error {
+	var statfs unix.Statfs_t
+	err := unix.Statfs(path, &statfs)
+	if err != nil {
+		return err
+	}
+	flags := uintptr(unix.MS_BIND|unix.MS_REMOUNT) | (uintptr(statfs.Flags) & keptMountFlags)
+	if readOnly {
+		flags |= unix.MS_RDONLY
+	}
+	return unix.Mount("", path, "", flags, "")
+}
+
+// the mounts that are not made read-only.  /dev is made read-only like the rest (device nodes can still be
+// written to on a read-only mount), but the ptys must keep working.  /proc is replaced (see mountProc).
+func keepMount(mountPoint string) bool {
+	for _, dir := range []string{"/dev/pts", "/proc"} {
+		if mountPoint == dir || strings.HasPrefix(mountPoint, dir+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+// mounts a new procfs for the sandbox's pid namespace over the host's /proc (like bubblewrap's --proc).  the
+// kernel settings in it stay read-only.
+func mountProc() error {
+	err := unix.Mount("proc", "/proc", "proc", unix.MS_NOSUID|unix.MS_NODEV|unix.MS_NOEXEC, "")
+	if err != nil {
+		return fmt.Errorf("cannot mount /proc: %w", err)
+	}
+	for _, name := range []string{"sys", "sysrq-trigger", "irq", "bus"} {
+		path := "/proc/" + name
+		err := unix.Mount(path, path, "", unix.MS_BIND|unix.MS_REC, "")
+		if errors.Is(err, unix.ENOENT) {
+			continue
+		}
+		if err == nil {
+			err = remount(path, true)
+		}
+		if err != nil {
+			return fmt.Errorf("cannot make %s read-only: %w", path, err)
+		}
+	}
+	return nil
+}
+
+func (b *bindMount) mount() error {
+	// the mount point may be in a hidden dir (on a tmpfs), so create it
+	if b.isDir {
+		os.MkdirAll(b.path, 0755)
+	} else if _, err := os.Stat(b.path); err != nil {
+		os.MkdirAll(filepath.Dir(b.path), 0755)
+		if fd, err := unix.Open(b.path, unix.O_CREAT|unix.O_WRONLY|unix.O_CLOEXEC, 0644); err == nil {
+			unix.Close(fd)
+		}
+	}
+	src := "/proc/self/fd/" + strconv.Itoa(b.fd)
+	err := unix.Mount(src, b.path, "", unix.MS_BIND|unix.MS_REC, "")
+	if err != nil {
+		return fmt.Errorf("cannot bind mount %s: %w", b.path, err)
+	}
+	err = remount(b.path, !b.writable)
+	if err != nil {
+		return fmt.Errorf("cannot remount %s: %w", b.path, err)
+	}
+	return nil
+}
+
+func setupMounts(opts Opts) error {
+	err := unix.Mount("", "/", "", unix.MS_REC|unix.MS_PRIVATE, "")
+	if err != nil {
+		return fmt.Errorf("cannot make mounts private: %w", err)
+	}
+	// open the bind sources first, so they can still be reached after the dirs they are in are hidden
+	var binds []*bindMount
+	defer func() {
+		for _, bind := range binds {
+			unix.Close(bind.fd)
+		}
+	}()
+	for _, path := range opts.Writable {
+		bind, err := openBind(path, true)
+		if err != nil {
+			return err
+		}
+		binds = append(binds, bind)
+	}
+	for _, path := range opts.ReadOnly {
+		bind, err := openBind(path, false)
+		if errors.Is(err, unix.ENOENT) {
+			// optional
+			continue
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "sandbox: %v\n", err)
+			continue
+		}
+		binds = append(binds, bind)
+	}
+	// parents first
+	sort.SliceStable(binds, func(i, j int) bool {
+		return len(binds[i].path) < len(binds[j].path)
+	})
+	mountInfo, err := os.ReadFile("/proc/self/mountinfo")
+	if err != nil {
+		return fmt.Errorf("cannot read mounts: %w", err)
+	}
+	for _, mountPoint := range parseMountInfo(mountInfo) {
+		if keepMount(mountPoint) {
+			continue
+		}
+		err := remount(mountPoint, true)
+		if errors.Is(err, unix.ENOENT) {
+			// the mount point is gone (or was under a mount that is gone)
+			continue
+		}
+		if err != nil {
+			// fail closed, the sandbox must not have a writable mount that was not asked for
+			return fmt.Errorf("cannot make %s read-only: %w", mountPoint, err)
+		}
+	}
+	err = mountProc()
+	if err != nil {
+		return err
+	}
+	for _, dir := range opts.Hide {
+		if _, err := os.Stat(dir); errors.Is(err, os.ErrNotExist) {
+			continue
+		}
+		err := unix.Mount("tmpfs", dir, "tmpfs", unix.MS_NOSUID|unix.MS_NODEV, "mode=0755")
+		if err != nil {
+			return fmt.Errorf("cannot hide %s: %w", dir, err)
+		}
+	}
+	err = unix.Mount("tmpfs", "/tmp", "tmpfs", unix.MS_NOSUID|unix.MS_NODEV, "mode=1777")
+	if err != nil {
+		return fmt.Errorf("cannot mount private /tmp: %w", err)
+	}
+	// /dev/shm is shared with the host (and world writable)
+	if _, err := os.Stat("/dev/shm"); err == nil {
+		err = unix.Mount("tmpfs", "/dev/shm", "tmpfs", unix.MS_NOSUID|unix.MS_NODEV, "mode=1777")
+		if err != nil {
+			return fmt.Errorf("cannot mount private /dev/shm: %w", err)
+		}
+	}
+	for _, bind := range binds {
+		err := bind.mount()
+		if err != nil {
+			if bind.writable {
+				return err
+			}
+			fmt.Fprintf(os.Stderr, "sandbox: %v\n", err)
+		}
+	}
+	return nil
+}
+
+// returns the mount points in /proc/self/mountinfo (in mount order).  see proc(5), the mount point is the 5th
+// field, with spaces, tabs, newlines and backslashes escaped as octal.
+func parseMountInfo(data []byte) []string {
+	var rtn []string
+	for _, line := range strings.Split(string(data), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) < 5 {
+			continue
+		}
+		rtn = append(rtn, unescapeMountPath(fields[4]))
+	}
+	return rtn
+}
+
+func unescapeMountPath(path string) string {
+	if !strings.Contains(path, "\\") {
+		return path
+	}
+	var sb strings.Builder
+	for idx := 0; idx < len(path); idx++ {
+		if path[idx] == '\\' && idx+3 < len(path) {
+			if val, err := strconv.ParseUint(path[idx+1:idx+4], 8, 8); err == nil {
+				sb.WriteByte(byte(val))
+				idx += 3
+				continue
+			}
+		}
+		sb.WriteByte(path[idx])
+	}
+	return sb.String()
+}
+
+func setLoopbackUp() error {
+	fd, err := unix.Socket(unix.AF_INET, unix.SOCK_DGRAM|unix.SOCK_CLOEXEC, 0)
+	if err != nil {
+		return err
+	}
+	defer unix.Close(fd)
+	ifreq, err := unix.NewIfreq("lo")
+	if err != nil {
+		return err
+	}
+	err = unix.IoctlIfreq(fd, unix.SIOCGIFFLAGS, ifreq)
+	if err != nil {
+		return err
+	}
+	ifreq.SetUint16(ifreq.Uint16() | unix.IFF_UP)
+	return unix.IoctlIfreq(fd, unix.SIOCSIFFLAGS, ifreq)
+}
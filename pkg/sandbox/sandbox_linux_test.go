@@ -0,0 +1,161 @@
+// Copyright 2025, Command Line Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+//go:build linux
+
+package sandbox
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"reflect"
+	"strings"
+	"testing"
+	"unsafe"
+)
+
+// the sandbox runs the test binary as "sandboxexec" (like wsh) for its stages
+func TestMain(m *testing.M) {
+	if len(os.Args) > 4 && os.Args[1] == "sandboxexec" {
+		os.Exit(runTestStage(os.Args[2:]))
+	}
+	os.Exit(m.Run())
+}
+
+// args are [--exec] --opts json -- cmd [args...]
+func runTestStage(args []string) int {
+	stage2 := args[0] == "--exec"
+	if stage2 {
+		args = args[1:]
+	}
+	var opts Opts
+	if len(args) < 4 || args[0] != "--opts" || args[2] != "--" || json.Unmarshal([]byte(args[1]), &opts) != nil {
+		fmt.Fprintf(os.Stderr, "sandbox: invalid args %q\n", args)
+		return 1
+	}
+	if stage2 {
+		err := Exec(opts, args[3:])
+		fmt.Fprintf(os.Stderr, "sandbox: %v\n", err)
+		return 1
+	}
+	exitCode, err := Run(opts, args[3:])
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "sandbox: %v\n", err)
+		return 1
+	}
+	return exitCode
+}
+
+func runInSandbox(t *testing.T, script string) (string, error) {
+	testBin, err := os.Executable()
+	if err != nil {
+		t.Fatalf("cannot find the test binary: %v", err)
+	}
+	ecmd := exec.Command("/bin/sh", "-c", script)
+	ecmd.Dir = "/"
+	wrapCmd, err := WrapCmd(testBin, ecmd, Opts{})
+	if err != nil {
+		t.Fatalf("error wrapping command: %v", err)
+	}
+	output, err := wrapCmd.CombinedOutput()
+	return strings.TrimSpace(string(output)), err
+}
+
+func TestSandboxHostProcesses(t *testing.T) {
+	if output, err := runInSandbox(t, "true"); err != nil {
+		t.Skipf("cannot run the sandbox here: %v %s", err, output)
+	}
+	script := fmt.Sprintf("ls /proc/%d/root/ >/dev/null 2>&1 && echo reachable; readlink /proc/self; cat /proc/1/comm", os.Getpid())
+	output, err := runInSandbox(t, script)
+	if err != nil {
+		t.Fatalf("error running in sandbox: %v %s", err, output)
+	}
+	lines := strings.Split(output, "\n")
+	if len(lines) != 2 || lines[0] == "reachable" {
+		t.Fatalf("the host processes should not be reachable, got %q", output)
+	}
+	// /proc shows the sandbox's pid namespace, where stage 1 is pid 1
+	if lines[0] == fmt.Sprint(os.Getpid()) || lines[1] != exeComm(t) {
+		t.Errorf("expected a new procfs, got %q", output)
+	}
+}
+
+func exeComm(t *testing.T) string {
+	comm, err := os.ReadFile("/proc/self/comm")
+	if err != nil {
+		t.Fatalf("cannot read comm: %v", err)
+	}
+	return strings.TrimSpace(string(comm))
+}
+
+func TestParseMountInfo(t *testing.T) {
+	data := `22 1 8:1 / / rw,relatime shared:1 - ext4 /dev/sda1 rw
+23 22 0:5 / /dev rw,nosuid shared:2 - devtmpfs udev rw
+41 22 0:35 / /home/me/my\040dir rw,relatime shared:20 - fuse.sshfs host:/ rw
+42 22 0:36 / /mnt/back\134slash rw - tmpfs tmpfs rw
+
+`
+	want := []string{"/", "/dev", "/home/me/my dir", "/mnt/back\\slash"}
+	got := parseMountInfo([]byte(data))
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("parseMountInfo = %q, want %q", got, want)
+	}
+}
+
+func TestUnescapeMountPath(t *testing.T) {
+	tests := map[string]string{
+		"/plain":         "/plain",
+		`/a\040b`:        "/a b",
+		`/tab\011x\012y`: "/tab\tx\ny",
+		`/short\04`:      `/short\04`,
+		`/bad\999`:       `/bad\999`,
+	}
+	for input, want := range tests {
+		if got := unescapeMountPath(input); got != want {
+			t.Errorf("unescapeMountPath(%q) = %q, want %q", input, got, want)
+		}
+	}
+}
+
+func TestKeepMount(t *testing.T) {
+	tests := map[string]bool{
+		"/dev":         false,
+		"/dev/shm":     false,
+		"/dev/pts":     true,
+		"/proc":        true,
+		"/proc/sys/fs": true,
+		"/process":     false,
+		"/":            false,
+		"/devices":     false,
+		"/home":        false,
+	}
+	for mountPoint, want := range tests {
+		if got := keepMount(mountPoint); got != want {
+			t.Errorf("keepMount(%q) = %v, want %v", mountPoint, got, want)
+		}
+	}
+}
+
+// the structs are passed to the kernel, they must match struct landlock_ruleset_attr and landlock_net_port_attr
+func TestLandlockStructSizes(t *testing.T) {
+	if size := unsafe.Sizeof(landlockRulesetAttr{}); size != 16 {
+		t.Errorf("landlockRulesetAttr size = %d, want 16", size)
+	}
+	if size := unsafe.Sizeof(landlockNetPortAttr{}); size != 16 {
+		t.Errorf("landlockNetPortAttr size = %d, want 16", size)
+	}
+}
+
+func TestWrapCmdUnsetEnv(t *testing.T) {
+	ecmd := exec.Command("/bin/sh")
+	ecmd.Env = []string{"PATH=/bin", "SSH_AUTH_SOCK=/run/user/1000/agent", "DISPLAY=:0"}
+	wrapCmd, err := WrapCmd("/bin/wsh", ecmd, Opts{UnsetEnv: []string{"SSH_AUTH_SOCK", "DISPLAY"}})
+	if err != nil {
+		t.Fatalf("error wrapping command: %v", err)
+	}
+	if !reflect.DeepEqual(wrapCmd.Env, []string{"PATH=/bin"}) {
+		t.Errorf("unexpected env: %q", wrapCmd.Env)
+	}
+}
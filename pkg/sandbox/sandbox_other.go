@@ -0,0 +1,25 @@
+// Copyright 2025, Command Line Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+//go:build !linux
+
+package sandbox
+
+import (
+	"fmt"
+	"os/exec"
+)
+
+var errNotSupported = fmt.Errorf("shell:sandbox is only supported on linux")
+
+func WrapCmd(wshPath string, ecmd *exec.Cmd, opts Opts) (*exec.Cmd, error) {
+	return nil, errNotSupported
+}
+
+func Run(opts Opts, args []string) (int, error) {
+	return 0, errNotSupported
+}
+
+func Exec(opts Opts, args []string) error {
+	return errNotSupported
+}
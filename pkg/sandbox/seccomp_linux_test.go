@@ -0,0 +1,43 @@
+// Copyright 2025, Command Line Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+//go:build linux && (amd64 || arm64)
+
+package sandbox
+
+import (
+	"testing"
+
+	"golang.org/x/sys/unix"
+)
+
+func TestSeccompFilter(t *testing.T) {
+	for _, checkX32 := range []bool{true, false} {
+		filter := makeSeccompFilter(unix.AUDIT_ARCH_X86_64, checkX32)
+		last := filter[len(filter)-1]
+		if last.Code != unix.BPF_RET|unix.BPF_K || last.K != unix.SECCOMP_RET_ALLOW {
+			t.Errorf("filter doesn't end with allow: %+v", last)
+		}
+		// all jumps must stay in the program
+		for idx, inst := range filter {
+			if inst.Code&0x07 != unix.BPF_JMP {
+				continue
+			}
+			if idx+1+int(inst.Jt) >= len(filter) || idx+1+int(inst.Jf) >= len(filter) {
+				t.Errorf("jump out of range at %d: %+v", idx, inst)
+			}
+		}
+		denied := 0
+		for idx, inst := range filter {
+			if inst.Code == unix.BPF_JMP|unix.BPF_JEQ|unix.BPF_K && inst.Jt == 0 && inst.Jf == 1 {
+				ret := filter[idx+1]
+				if ret.K == unix.SECCOMP_RET_ERRNO|uint32(unix.EPERM) {
+					denied++
+				}
+			}
+		}
+		if denied != len(deniedSyscalls) {
+			t.Errorf("got %d denied syscalls, want %d", denied, len(deniedSyscalls))
+		}
+	}
+}
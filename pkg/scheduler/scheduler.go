@@ -0,0 +1,314 @@
+// Copyright 2025, Command Line Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+// scheduler reruns cmd blocks on a timetable (cmd:schedule block meta, a cron expression or an interval).
+// schedules are picked up from waveobj:update events, runs go through blockcontroller and their results
+// come back as controllerstatus events.
+package scheduler
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/wavetermdev/waveterm/pkg/blockcontroller"
+	"github.com/wavetermdev/waveterm/pkg/panichandler"
+	"github.com/wavetermdev/waveterm/pkg/util/cronutil"
+	"github.com/wavetermdev/waveterm/pkg/util/utilfn"
+	"github.com/wavetermdev/waveterm/pkg/waveobj"
+	"github.com/wavetermdev/waveterm/pkg/wps"
+	"github.com/wavetermdev/waveterm/pkg/wshrpc"
+	"github.com/wavetermdev/waveterm/pkg/wshrpc/wshclient"
+	"github.com/wavetermdev/waveterm/pkg/wstore"
+)
+
+const MaxRunHistory = 20
+
+const DefaultTimeout = 5 * time.Second
+
+// meta changes made through the object service don't publish waveobj:update events, so we also resync periodically
+const ResyncInterval = 30 * time.Second
+
+type schedEntry struct {
+	BlockId  string
+	Spec     string
+	Paused   bool
+	Err      string
+	Sched    cronutil.Schedule
+	NextRun  time.Time
+	Runs     []*wshrpc.ScheduleRun
+	Timer    *time.Timer
+	InFlight *wshrpc.ScheduleRun // the run we started, until the controller reports it is done
+	Started  bool                // the controller reported InFlight as running
+}
+
+var globalLock = &sync.Mutex{}
+var entries = make(map[string]*schedEntry)
+
+// Start loads the schedules of all blocks and subscribes to block updates
+func Start(ctx context.Context) error {
+	client := wshclient.GetBareRpcClient()
+	client.EventListener.On(wps.Event_WaveObjUpdate, handleWaveObjUpdate)
+	client.EventListener.On(wps.Event_ControllerStatus, handleControllerStatus)
+	err := wshclient.EventSubCommand(client, wps.SubscriptionRequest{Event: wps.Event_WaveObjUpdate, AllScopes: true}, nil)
+	if err != nil {
+		return fmt.Errorf("error subscribing to waveobj updates: %w", err)
+	}
+	err = wshclient.EventSubCommand(client, wps.SubscriptionRequest{Event: wps.Event_ControllerStatus, AllScopes: true}, nil)
+	if err != nil {
+		return fmt.Errorf("error subscribing to controller status: %w", err)
+	}
+	err = syncAllBlocks(ctx)
+	if err != nil {
+		return err
+	}
+	go resyncLoop()
+	return nil
+}
+
+func syncAllBlocks(ctx context.Context) error {
+	blocks, err := wstore.DBGetAllObjsByType[*waveobj.Block](ctx, waveobj.OType_Block)
+	if err != nil {
+		return fmt.Errorf("error loading blocks: %w", err)
+	}
+	blockMap := make(map[string]*waveobj.Block)
+	for _, block := range blocks {
+		blockMap[block.OID] = block
+		syncBlock(block.OID, block)
+	}
+	globalLock.Lock()
+	var deleted []string
+	for blockId := range entries {
+		if blockMap[blockId] == nil {
+			deleted = append(deleted, blockId)
+		}
+	}
+	globalLock.Unlock()
+	for _, blockId := range deleted {
+		syncBlock(blockId, nil)
+	}
+	return nil
+}
+
+func resyncLoop() {
+	defer func() {
+		panichandler.PanicHandler("scheduler:resyncLoop", recover())
+	}()
+	for {
+		time.Sleep(ResyncInterval)
+		ctx, cancelFn := context.WithTimeout(context.Background(), DefaultTimeout)
+		err := syncAllBlocks(ctx)
+		cancelFn()
+		if err != nil {
+			log.Printf("scheduler: %v\n", err)
+		}
+	}
+}
+
+func handleWaveObjUpdate(event *wps.WaveEvent) {
+	var update waveobj.WaveObjUpdate
+	if err := utilfn.ReUnmarshal(&update, event.Data); err != nil || update.OType != waveobj.OType_Block {
+		return
+	}
+	if update.UpdateType == waveobj.UpdateType_Delete {
+		syncBlock(update.OID, nil)
+		return
+	}
+	ctx, cancelFn := context.WithTimeout(context.Background(), DefaultTimeout)
+	defer cancelFn()
+	block, err := wstore.DBGet[*waveobj.Block](ctx, update.OID)
+	if err != nil {
+		log.Printf("scheduler: error getting block %s: %v\n", update.OID, err)
+		return
+	}
+	syncBlock(update.OID, block)
+}
+
+// records the result of a run we started
+func handleControllerStatus(event *wps.WaveEvent) {
+	var status blockcontroller.BlockControllerRuntimeStatus
+	if err := utilfn.ReUnmarshal(&status, event.Data); err != nil {
+		return
+	}
+	globalLock.Lock()
+	defer globalLock.Unlock()
+	entry := entries[status.BlockId]
+	if entry == nil || entry.InFlight == nil {
+		return
+	}
+	if status.ShellProcStatus == blockcontroller.Status_Running {
+		entry.Started = true
+		return
+	}
+	// ignore the status updates sent while the previous run is stopped
+	if status.ShellProcStatus != blockcontroller.Status_Done || !entry.Started {
+		return
+	}
+	exitCode := status.ShellProcExitCode
+	entry.InFlight.EndTs = time.Now().UnixMilli()
+	entry.InFlight.ExitCode = &exitCode
+	entry.InFlight = nil
+}
+
+// updates the schedule for a block from its meta (block is nil if it was deleted)
+func syncBlock(blockId string, block *waveobj.Block) {
+	var spec string
+	var paused bool
+	if block != nil {
+		spec = block.Meta.GetString(waveobj.MetaKey_CmdSchedule, "")
+		paused = block.Meta.GetBool(waveobj.MetaKey_CmdSchedulePaused, false)
+	}
+	globalLock.Lock()
+	defer globalLock.Unlock()
+	entry := entries[blockId]
+	if spec == "" {
+		if entry != nil {
+			stopTimer_nolock(entry)
+			delete(entries, blockId)
+		}
+		return
+	}
+	if entry != nil && entry.Spec == spec && entry.Paused == paused {
+		// not a schedule change (most block updates aren't)
+		return
+	}
+	if entry == nil {
+		entry = &schedEntry{BlockId: blockId}
+		entries[blockId] = entry
+	}
+	stopTimer_nolock(entry)
+	entry.Spec = spec
+	entry.Paused = paused
+	entry.Err = ""
+	entry.Sched = nil
+	entry.NextRun = time.Time{}
+	if block.Meta.GetString(waveobj.MetaKey_Controller, "") != blockcontroller.BlockController_Cmd {
+		entry.Err = "cmd:schedule requires the cmd controller"
+		return
+	}
+	sched, err := cronutil.Parse(spec)
+	if err != nil {
+		entry.Err = err.Error()
+		return
+	}
+	entry.Sched = sched
+	scheduleNext_nolock(entry, time.Now())
+}
+
+func stopTimer_nolock(entry *schedEntry) {
+	if entry.Timer != nil {
+		entry.Timer.Stop()
+		entry.Timer = nil
+	}
+}
+
+func scheduleNext_nolock(entry *schedEntry, from time.Time) {
+	if entry.Paused || entry.Sched == nil {
+		return
+	}
+	next := entry.Sched.Next(from)
+	if next.IsZero() {
+		entry.Err = "schedule never runs"
+		return
+	}
+	entry.NextRun = next
+	blockId := entry.BlockId
+	entry.Timer = time.AfterFunc(time.Until(next), func() {
+		runScheduled(blockId)
+	})
+}
+
+func addRun_nolock(entry *schedEntry, run *wshrpc.ScheduleRun) {
+	entry.Runs = append(entry.Runs, run)
+	if len(entry.Runs) > MaxRunHistory {
+		entry.Runs = entry.Runs[len(entry.Runs)-MaxRunHistory:]
+	}
+}
+
+func runScheduled(blockId string) {
+	defer func() {
+		panichandler.PanicHandler("scheduler:runScheduled", recover())
+	}()
+	globalLock.Lock()
+	entry := entries[blockId]
+	if entry == nil || entry.Paused || entry.Sched == nil {
+		globalLock.Unlock()
+		return
+	}
+	now := time.Now()
+	entry.Timer = nil
+	scheduleNext_nolock(entry, now)
+	run := &wshrpc.ScheduleRun{StartTs: now.UnixMilli()}
+	bc := blockcontroller.GetBlockController(blockId)
+	if entry.InFlight != nil || (bc != nil && bc.GetRuntimeStatus().ShellProcStatus == blockcontroller.Status_Running) {
+		// don't overlap runs
+		run.Skipped = true
+		addRun_nolock(entry, run)
+		globalLock.Unlock()
+		return
+	}
+	entry.InFlight = run
+	entry.Started = false
+	entry.Err = ""
+	addRun_nolock(entry, run)
+	globalLock.Unlock()
+
+	ctx, cancelFn := context.WithTimeout(context.Background(), DefaultTimeout)
+	defer cancelFn()
+	err := startRun(ctx, blockId)
+	if err != nil {
+		log.Printf("scheduler: error running block %s: %v\n", blockId, err)
+		globalLock.Lock()
+		if entry.InFlight == run {
+			entry.InFlight = nil
+			run.EndTs = time.Now().UnixMilli()
+			entry.Err = err.Error()
+		}
+		globalLock.Unlock()
+	}
+}
+
+func startRun(ctx context.Context, blockId string) error {
+	tabId, err := wstore.DBFindTabForBlockId(ctx, blockId)
+	if err != nil {
+		return fmt.Errorf("error finding tab for block: %w", err)
+	}
+	return blockcontroller.ResyncController(ctx, tabId, blockId, nil, true)
+}
+
+func makeScheduleInfo_nolock(entry *schedEntry) *wshrpc.ScheduleInfo {
+	rtn := &wshrpc.ScheduleInfo{
+		BlockId:  entry.BlockId,
+		Schedule: entry.Spec,
+		Paused:   entry.Paused,
+		Error:    entry.Err,
+	}
+	if !entry.Paused && !entry.NextRun.IsZero() {
+		rtn.NextRunTs = entry.NextRun.UnixMilli()
+	}
+	for _, run := range entry.Runs {
+		runCopy := *run
+		rtn.Runs = append(rtn.Runs, &runCopy)
+	}
+	return rtn
+}
+
+// ListSchedules returns the schedule of blockId, or of all scheduled blocks if blockId is empty
+func ListSchedules(blockId string) []*wshrpc.ScheduleInfo {
+	globalLock.Lock()
+	defer globalLock.Unlock()
+	var rtn []*wshrpc.ScheduleInfo
+	for _, entry := range entries {
+		if blockId != "" && entry.BlockId != blockId {
+			continue
+		}
+		rtn = append(rtn, makeScheduleInfo_nolock(entry))
+	}
+	sort.Slice(rtn, func(i, j int) bool {
+		return rtn[i].BlockId < rtn[j].BlockId
+	})
+	return rtn
+}
@@ -53,13 +53,11 @@ func (cs *ClientService) FocusWindow(ctx context.Context, windowId string) error
 
 func (cs *ClientService) AgreeTos(ctx context.Context) (waveobj.UpdatesRtnType, error) {
 	ctx = waveobj.ContextWithUpdates(ctx)
-	clientData, err := wstore.DBGetSingleton[*waveobj.Client](ctx)
-	if err != nil {
-		return nil, fmt.Errorf("error getting client data: %w", err)
-	}
 	timestamp := time.Now().UnixMilli()
-	clientData.TosAgreed = timestamp
-	err = wstore.DBUpdate(ctx, clientData)
+	_, err := wcore.UpdateClientData(ctx, func(clientData *waveobj.Client) error {
+		clientData.TosAgreed = timestamp
+		return nil
+	})
 	if err != nil {
 		return nil, fmt.Errorf("error updating client data: %w", err)
 	}
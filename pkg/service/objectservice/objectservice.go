@@ -9,6 +9,7 @@ import (
 	"strings"
 	"time"
 
+	"github.com/wavetermdev/waveterm/pkg/panichandler"
 	"github.com/wavetermdev/waveterm/pkg/tsgen/tsgenmeta"
 	"github.com/wavetermdev/waveterm/pkg/waveobj"
 	"github.com/wavetermdev/waveterm/pkg/wcore"
@@ -164,23 +165,42 @@ func (svc *ObjectService) UpdateObject(uiContext waveobj.UIContext, waveObj wave
 		return nil, fmt.Errorf("update wavobj is nil")
 	}
 	oref := waveobj.ORefFromWaveObj(waveObj)
-	found, err := wstore.DBExistsORef(ctx, *oref)
+	// frontend writes are last-writer-wins (they are sent without waiting for the result, a version conflict would
+	// lose the edit).  the read and the write are in one transaction, so no other write can come in between.
+	oldObj, err := wstore.WithTxRtn(ctx, func(tx *wstore.TxWrap) (waveobj.WaveObj, error) {
+		oldObj, err := wstore.DBGetORef(tx.Context(), *oref)
+		if err != nil {
+			return nil, fmt.Errorf("error getting object: %w", err)
+		}
+		if oldObj == nil {
+			return nil, fmt.Errorf("object not found: %s", oref)
+		}
+		waveobj.SetVersion(waveObj, waveobj.GetVersion(oldObj))
+		err = wstore.DBUpdate(tx.Context(), waveObj)
+		if err != nil {
+			return nil, fmt.Errorf("error updating object: %w", err)
+		}
+		return oldObj, nil
+	})
 	if err != nil {
-		return nil, fmt.Errorf("error getting object: %w", err)
-	}
-	if !found {
-		return nil, fmt.Errorf("object not found: %s", oref)
+		return nil, err
 	}
-	err = wstore.DBUpdate(ctx, waveObj)
-	if err != nil {
-		return nil, fmt.Errorf("error updating object: %w", err)
+	if layoutState, ok := waveObj.(*waveobj.LayoutState); ok {
+		wcore.NoteLayoutUpdate(oldObj.(*waveobj.LayoutState), layoutState)
 	}
+	updates := waveobj.ContextGetUpdatesRtn(ctx)
+	go func() {
+		defer func() {
+			panichandler.PanicHandler("ObjectService:UpdateObject:SendUpdateEvents", recover())
+		}()
+		wps.Broker.SendUpdateEvents(updates)
+	}()
 	if (waveObj.GetOType() == waveobj.OType_Workspace) && (waveObj.(*waveobj.Workspace).Name != "") {
 		wps.Broker.Publish(wps.WaveEvent{
 			Event: wps.Event_WorkspaceUpdate})
 	}
 	if returnUpdates {
-		return waveobj.ContextGetUpdatesRtn(ctx), nil
+		return updates, nil
 	}
 	return nil, nil
 }
@@ -64,18 +64,17 @@ func (ws *WindowService) SetWindowPosAndSize(ctx context.Context, windowId strin
 		return nil, nil
 	}
 	ctx = waveobj.ContextWithUpdates(ctx)
-	win, err := wstore.DBMustGet[*waveobj.Window](ctx, windowId)
-	if err != nil {
-		return nil, err
-	}
-	if pos != nil {
-		win.Pos = *pos
-	}
-	if size != nil {
-		win.WinSize = *size
-	}
-	win.IsNew = false
-	err = wstore.DBUpdate(ctx, win)
+	_, err := wstore.UpdateObject(ctx, waveobj.MakeORef(waveobj.OType_Window, windowId), func(obj waveobj.WaveObj) error {
+		win := obj.(*waveobj.Window)
+		if pos != nil {
+			win.Pos = *pos
+		}
+		if size != nil {
+			win.WinSize = *size
+		}
+		win.IsNew = false
+		return nil
+	})
 	if err != nil {
 		return nil, err
 	}
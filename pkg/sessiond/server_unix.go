@@ -0,0 +1,262 @@
+// Copyright 2025, Command Line Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+//go:build !windows
+
+package sessiond
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"os"
+	"os/exec"
+	"os/signal"
+	"path/filepath"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/creack/pty"
+	"github.com/wavetermdev/waveterm/pkg/panichandler"
+)
+
+const clientWriteTimeout = 10 * time.Second
+
+type serverClient struct {
+	conn net.Conn
+	enc  *json.Encoder
+}
+
+func (sc *serverClient) send(pk Packet) error {
+	sc.conn.SetWriteDeadline(time.Now().Add(clientWriteTimeout))
+	return sc.enc.Encode(pk)
+}
+
+type server struct {
+	lock     *sync.Mutex
+	cmd      *exec.Cmd
+	ptmx     pty.Pty
+	client   *serverClient
+	buf      []byte // output produced while no client is attached
+	exited   bool
+	exitCode int
+	doneCh   chan struct{} // closed once the exit code has been delivered to a client
+	doneOnce *sync.Once
+}
+
+// detaches the session server from wavesrv's process group and session, so it is not signaled with it
+func setDetachedProcAttr(cmd *exec.Cmd) {
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setsid: true}
+}
+
+func signalFromName(name string) (syscall.Signal, bool) {
+	switch name {
+	case Signal_Hup:
+		return syscall.SIGHUP, true
+	case Signal_Int:
+		return syscall.SIGINT, true
+	case Signal_Term:
+		return syscall.SIGTERM, true
+	case Signal_Kill:
+		return syscall.SIGKILL, true
+	}
+	return 0, false
+}
+
+// RunServer starts cmd in a new pty and serves it on sockPath until the process has exited and its
+// exit code has been delivered to a client (or ExitLingerTime has passed).
+func RunServer(sockPath string, cmd *exec.Cmd, rows int, cols int) error {
+	err := CheckSocketPath(sockPath)
+	if err != nil {
+		return err
+	}
+	err = os.MkdirAll(filepath.Dir(sockPath), 0700)
+	if err != nil {
+		return fmt.Errorf("cannot create session dir: %w", err)
+	}
+	if IsAlive(sockPath) {
+		return fmt.Errorf("session %q is already running", sockPath)
+	}
+	os.Remove(sockPath)
+	listener, err := net.Listen("unix", sockPath)
+	if err != nil {
+		return fmt.Errorf("cannot listen on %q: %w", sockPath, err)
+	}
+	defer os.Remove(sockPath)
+	defer listener.Close()
+	os.Chmod(sockPath, 0600)
+	// we have no controlling terminal, but make sure a hangup of our parent's session can't stop us
+	signal.Ignore(syscall.SIGHUP, syscall.SIGPIPE)
+	ptmx, err := pty.StartWithSize(cmd, &pty.Winsize{Rows: uint16(rows), Cols: uint16(cols)})
+	if err != nil {
+		return fmt.Errorf("cannot start command: %w", err)
+	}
+	srv := &server{
+		lock:     &sync.Mutex{},
+		cmd:      cmd,
+		ptmx:     ptmx,
+		doneCh:   make(chan struct{}),
+		doneOnce: &sync.Once{},
+	}
+	go srv.acceptLoop(listener)
+	srv.outputLoop()
+	waitErr := cmd.Wait()
+	ptmx.Close()
+	exitCode := cmd.ProcessState.ExitCode()
+	log.Printf("session process exited (code %d, err %v)\n", exitCode, waitErr)
+	srv.lock.Lock()
+	srv.exited = true
+	srv.exitCode = exitCode
+	if srv.client != nil {
+		srv.deliverExit_nolock()
+	}
+	srv.lock.Unlock()
+	select {
+	case <-srv.doneCh:
+	case <-time.After(ExitLingerTime):
+		log.Printf("no client collected the exit code, shutting down\n")
+	}
+	return nil
+}
+
+func (srv *server) outputLoop() {
+	buf := make([]byte, 4096)
+	for {
+		n, err := srv.ptmx.Read(buf)
+		if n > 0 {
+			srv.handleOutput(buf[:n])
+		}
+		if err != nil {
+			// EIO once the process (and every other holder of the tty) has exited
+			return
+		}
+	}
+}
+
+func (srv *server) handleOutput(data []byte) {
+	srv.lock.Lock()
+	defer srv.lock.Unlock()
+	if srv.client != nil {
+		err := srv.client.send(Packet{Type: PacketType_Output, Data: data})
+		if err == nil {
+			return
+		}
+		log.Printf("error sending output, dropping client: %v\n", err)
+		srv.dropClient_nolock()
+	}
+	srv.buf = append(srv.buf, data...)
+	if len(srv.buf) > MaxBufferSize {
+		srv.buf = append([]byte(nil), srv.buf[len(srv.buf)-MaxBufferSize:]...)
+	}
+}
+
+func (srv *server) dropClient_nolock() {
+	if srv.client == nil {
+		return
+	}
+	srv.client.conn.Close()
+	srv.client = nil
+}
+
+// sends the exit code to the current client, the server exits after this
+func (srv *server) deliverExit_nolock() {
+	err := srv.client.send(Packet{Type: PacketType_Exit, ExitCode: srv.exitCode})
+	srv.dropClient_nolock()
+	if err != nil {
+		log.Printf("error sending exit code: %v\n", err)
+		return
+	}
+	srv.doneOnce.Do(func() { close(srv.doneCh) })
+}
+
+func (srv *server) acceptLoop(listener net.Listener) {
+	defer func() {
+		panichandler.PanicHandler("sessiond:acceptLoop", recover())
+	}()
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		go srv.handleConn(conn)
+	}
+}
+
+func (srv *server) attachClient(sc *serverClient) error {
+	srv.lock.Lock()
+	defer srv.lock.Unlock()
+	// only one client at a time, a new attach replaces the old client
+	srv.dropClient_nolock()
+	for len(srv.buf) > 0 {
+		chunk := srv.buf[:min(len(srv.buf), MaxOutputPktSize)]
+		if err := sc.send(Packet{Type: PacketType_Output, Data: chunk}); err != nil {
+			sc.conn.Close()
+			return err
+		}
+		srv.buf = srv.buf[len(chunk):]
+	}
+	srv.buf = nil
+	srv.client = sc
+	if srv.exited {
+		srv.deliverExit_nolock()
+	}
+	return nil
+}
+
+func (srv *server) detachClient(sc *serverClient) {
+	srv.lock.Lock()
+	defer srv.lock.Unlock()
+	if srv.client == sc {
+		srv.client = nil
+	}
+	sc.conn.Close()
+}
+
+func (srv *server) handleConn(conn net.Conn) {
+	defer func() {
+		panichandler.PanicHandler("sessiond:handleConn", recover())
+	}()
+	sc := &serverClient{conn: conn, enc: json.NewEncoder(conn)}
+	dec := json.NewDecoder(conn)
+	var pk Packet
+	if err := dec.Decode(&pk); err != nil || pk.Type != PacketType_Attach {
+		// IsAlive() probes connect and close without attaching
+		conn.Close()
+		return
+	}
+	if pk.Rows > 0 && pk.Cols > 0 {
+		pty.Setsize(srv.ptmx, &pty.Winsize{Rows: uint16(pk.Rows), Cols: uint16(pk.Cols)})
+	}
+	if err := srv.attachClient(sc); err != nil {
+		log.Printf("error attaching client: %v\n", err)
+		return
+	}
+	defer srv.detachClient(sc)
+	for {
+		var pk Packet
+		if err := dec.Decode(&pk); err != nil {
+			if !errors.Is(err, io.EOF) && !errors.Is(err, net.ErrClosed) {
+				log.Printf("error reading from client: %v\n", err)
+			}
+			return
+		}
+		switch pk.Type {
+		case PacketType_Input:
+			srv.ptmx.Write(pk.Data)
+		case PacketType_Resize:
+			if pk.Rows > 0 && pk.Cols > 0 {
+				pty.Setsize(srv.ptmx, &pty.Winsize{Rows: uint16(pk.Rows), Cols: uint16(pk.Cols)})
+			}
+		case PacketType_Signal:
+			if sig, ok := signalFromName(pk.Signal); ok && srv.cmd.Process != nil {
+				srv.cmd.Process.Signal(sig)
+			}
+		case PacketType_Detach:
+			return
+		}
+	}
+}
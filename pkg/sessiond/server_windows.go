@@ -0,0 +1,18 @@
+// Copyright 2025, Command Line Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+//go:build windows
+
+package sessiond
+
+import (
+	"fmt"
+	"os/exec"
+)
+
+func RunServer(sockPath string, cmd *exec.Cmd, rows int, cols int) error {
+	return fmt.Errorf("persistent sessions are not supported on windows")
+}
+
+func setDetachedProcAttr(cmd *exec.Cmd) {
+}
@@ -0,0 +1,350 @@
+// Copyright 2025, Command Line Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+// sessiond implements persistent shell sessions.  A small supervisor process ("wsh sessiond") owns the
+// pty of a local shell and serves it over a unix socket named after the block id.  wavesrv attaches
+// to the socket instead of owning the pty directly, so the shell survives wavesrv restarts, and
+// output produced while no client is attached is buffered and replayed on the next attach.
+//
+// The protocol is newline delimited json Packets.  The client sends an attach packet first.
+package sessiond
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/wavetermdev/waveterm/pkg/panichandler"
+	"github.com/wavetermdev/waveterm/pkg/wavebase"
+)
+
+const (
+	SessionDirName   = "sessions"
+	ProtocolVersion  = 1
+	MaxBufferSize    = 256 * 1024     // output buffered while detached (matches the term file size)
+	ExitLingerTime   = 24 * time.Hour // how long a session waits for a client to collect its exit code
+	DialTimeout      = 2 * time.Second
+	DetachTimeout    = 2 * time.Second
+	MaxOutputPktSize = 32 * 1024
+
+	SocketNameHashLen     = 8   // bytes of the block id hash in the socket name (16 hex chars)
+	MaxSocketPathLen      = 103 // sun_path is 104 bytes on macOS and the BSDs (including the terminating nul)
+	MaxSocketPathLenLinux = 107
+)
+
+const (
+	PacketType_Attach = "attach" // client -> server (must be first)
+	PacketType_Input  = "input"  // client -> server
+	PacketType_Resize = "resize" // client -> server
+	PacketType_Signal = "signal" // client -> server
+	PacketType_Detach = "detach" // client -> server, the server closes the connection
+	PacketType_Output = "output" // server -> client
+	PacketType_Exit   = "exit"   // server -> client, sent once the process has exited and all output was sent
+)
+
+const (
+	Signal_Hup  = "HUP"
+	Signal_Int  = "INT"
+	Signal_Term = "TERM"
+	Signal_Kill = "KILL"
+)
+
+var ErrDetached = errors.New("detached from session")
+
+type Packet struct {
+	Type     string `json:"type"`
+	Version  int    `json:"version,omitempty"`
+	Data     []byte `json:"data,omitempty"`
+	Rows     int    `json:"rows,omitempty"`
+	Cols     int    `json:"cols,omitempty"`
+	Signal   string `json:"signal,omitempty"`
+	ExitCode int    `json:"exitcode,omitempty"`
+}
+
+func GetSessionDir() string {
+	return filepath.Join(wavebase.GetWaveDataDir(), SessionDirName)
+}
+
+// sockets are named after a short hash of the block id, a unix socket path has to fit in sun_path (104 bytes on
+// macOS, where the data dir is in "~/Library/Application Support").  the log file keeps the block id.
+func GetSocketPath(blockId string) string {
+	legacyPath := filepath.Join(GetSessionDir(), blockId+".sock")
+	if _, err := os.Stat(legacyPath); err == nil {
+		// a session started before sockets were named by hash
+		return legacyPath
+	}
+	hash := sha256.Sum256([]byte(blockId))
+	return filepath.Join(GetSessionDir(), hex.EncodeToString(hash[:SocketNameHashLen])+".sock")
+}
+
+func GetLogPath(blockId string) string {
+	return filepath.Join(GetSessionDir(), blockId+".log")
+}
+
+// CheckSocketPath returns an error if sockPath is too long to listen on
+func CheckSocketPath(sockPath string) error {
+	maxLen := MaxSocketPathLen
+	if runtime.GOOS == "linux" {
+		maxLen = MaxSocketPathLenLinux
+	}
+	if len(sockPath) > maxLen {
+		return fmt.Errorf("session socket path %q is too long (%d bytes, the limit is %d), use a shorter data dir", sockPath, len(sockPath), maxLen)
+	}
+	return nil
+}
+
+// ListSessions returns the block ids that have a session (found by their log files, the session may no
+// longer be alive)
+func ListSessions() ([]string, error) {
+	entries, err := os.ReadDir(GetSessionDir())
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var rtn []string
+	for _, entry := range entries {
+		if blockId, ok := strings.CutSuffix(entry.Name(), ".log"); ok {
+			rtn = append(rtn, blockId)
+		}
+	}
+	return rtn, nil
+}
+
+// IsAlive returns true if a session server is accepting connections on sockPath
+func IsAlive(sockPath string) bool {
+	conn, err := net.DialTimeout("unix", sockPath, DialTimeout)
+	if err != nil {
+		return false
+	}
+	conn.Close()
+	return true
+}
+
+// RemoveSession removes the socket and log files of a session that is no longer running
+func RemoveSession(blockId string) {
+	os.Remove(GetSocketPath(blockId))
+	os.Remove(GetLogPath(blockId))
+}
+
+// StartServer starts a session server ("wsh sessiond") for the command in ecmd, using ecmd's
+// environment and working directory.  The server outlives the calling process.
+func StartServer(wshPath string, sockPath string, logPath string, ecmd *exec.Cmd, rows int, cols int) error {
+	err := CheckSocketPath(sockPath)
+	if err != nil {
+		return err
+	}
+	err = os.MkdirAll(filepath.Dir(sockPath), 0700)
+	if err != nil {
+		return fmt.Errorf("cannot create session dir: %w", err)
+	}
+	args := []string{"sessiond", "--socket", sockPath, "--rows", strconv.Itoa(rows), "--cols", strconv.Itoa(cols), "--", ecmd.Path}
+	args = append(args, ecmd.Args[1:]...)
+	serverCmd := exec.Command(wshPath, args...)
+	serverCmd.Env = ecmd.Env
+	serverCmd.Dir = ecmd.Dir
+	// the log file is also how ListSessions finds the session
+	logFile, err := os.OpenFile(logPath, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0600)
+	if err != nil {
+		return fmt.Errorf("cannot create session log: %w", err)
+	}
+	defer logFile.Close()
+	serverCmd.Stdout = logFile
+	serverCmd.Stderr = logFile
+	setDetachedProcAttr(serverCmd)
+	err = serverCmd.Start()
+	if err != nil {
+		return fmt.Errorf("cannot start session server: %w", err)
+	}
+	go func() {
+		defer func() {
+			panichandler.PanicHandler("sessiond:StartServer:wait", recover())
+		}()
+		// reap the server if it exits while we're still running
+		serverCmd.Wait()
+	}()
+	return nil
+}
+
+// AttachWithRetry attaches to a session that is starting up
+func AttachWithRetry(sockPath string, rows int, cols int, timeout time.Duration) (*Client, error) {
+	deadline := time.Now().Add(timeout)
+	for {
+		client, err := Attach(sockPath, rows, cols)
+		if err == nil || time.Now().After(deadline) {
+			return client, err
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+}
+
+// Client is an attached connection to a session.  Reading returns the session's output (starting
+// with any output buffered while detached) and returns io.EOF when the session exits or is detached.
+type Client struct {
+	conn       net.Conn
+	encLock    *sync.Mutex
+	enc        *json.Encoder
+	pipeReader *io.PipeReader
+	pipeWriter *io.PipeWriter
+	doneCh     chan struct{}
+	exited     bool // set before doneCh is closed
+	exitCode   int
+	detached   atomic.Bool
+}
+
+func Attach(sockPath string, rows int, cols int) (*Client, error) {
+	conn, err := net.DialTimeout("unix", sockPath, DialTimeout)
+	if err != nil {
+		return nil, fmt.Errorf("cannot connect to session: %w", err)
+	}
+	pipeReader, pipeWriter := io.Pipe()
+	client := &Client{
+		conn:       conn,
+		encLock:    &sync.Mutex{},
+		enc:        json.NewEncoder(conn),
+		pipeReader: pipeReader,
+		pipeWriter: pipeWriter,
+		doneCh:     make(chan struct{}),
+		exitCode:   -1,
+	}
+	err = client.send(Packet{Type: PacketType_Attach, Version: ProtocolVersion, Rows: rows, Cols: cols})
+	if err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("cannot attach to session: %w", err)
+	}
+	go client.readLoop()
+	return client, nil
+}
+
+func (c *Client) readLoop() {
+	defer func() {
+		panichandler.PanicHandler("sessiond:Client.readLoop", recover())
+	}()
+	defer close(c.doneCh)
+	defer c.pipeWriter.Close()
+	dec := json.NewDecoder(c.conn)
+	for {
+		var pk Packet
+		if err := dec.Decode(&pk); err != nil {
+			return
+		}
+		switch pk.Type {
+		case PacketType_Output:
+			if _, err := c.pipeWriter.Write(pk.Data); err != nil {
+				return
+			}
+		case PacketType_Exit:
+			c.exitCode = pk.ExitCode
+			c.exited = true
+			return
+		}
+	}
+}
+
+func (c *Client) send(pk Packet) error {
+	c.encLock.Lock()
+	defer c.encLock.Unlock()
+	return c.enc.Encode(pk)
+}
+
+func (c *Client) Read(p []byte) (int, error) {
+	return c.pipeReader.Read(p)
+}
+
+func (c *Client) Write(p []byte) (int, error) {
+	if c.detached.Load() {
+		return 0, ErrDetached
+	}
+	err := c.send(Packet{Type: PacketType_Input, Data: p})
+	if err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+func (c *Client) Resize(rows int, cols int) error {
+	if c.detached.Load() {
+		return ErrDetached
+	}
+	return c.send(Packet{Type: PacketType_Resize, Rows: rows, Cols: cols})
+}
+
+func (c *Client) Signal(sig string) error {
+	if c.detached.Load() {
+		return ErrDetached
+	}
+	return c.send(Packet{Type: PacketType_Signal, Signal: sig})
+}
+
+// Detach disconnects from the session and leaves it running.  Output already sent by the server is
+// still returned by Read before io.EOF.
+func (c *Client) Detach() error {
+	if !c.detached.CompareAndSwap(false, true) {
+		return nil
+	}
+	err := c.send(Packet{Type: PacketType_Detach})
+	go func() {
+		defer func() {
+			panichandler.PanicHandler("sessiond:Client.Detach", recover())
+		}()
+		select {
+		case <-c.doneCh:
+		case <-time.After(DetachTimeout):
+			c.conn.Close()
+		}
+	}()
+	return err
+}
+
+func (c *Client) IsDetached() bool {
+	return c.detached.Load()
+}
+
+func (c *Client) Done() <-chan struct{} {
+	return c.doneCh
+}
+
+// Wait waits for the session to exit.  Returns ErrDetached if the client detached, or an error if
+// the connection to the session was lost.
+func (c *Client) Wait() error {
+	<-c.doneCh
+	if c.exited {
+		if c.exitCode != 0 {
+			return fmt.Errorf("exit status %d", c.exitCode)
+		}
+		return nil
+	}
+	if c.detached.Load() {
+		return ErrDetached
+	}
+	return fmt.Errorf("lost connection to session")
+}
+
+// only valid after Wait() returns, -1 if the exit code is unknown
+func (c *Client) ExitCode() int {
+	select {
+	case <-c.doneCh:
+		return c.exitCode
+	default:
+		return -1
+	}
+}
+
+func (c *Client) Close() error {
+	c.pipeReader.Close()
+	return c.conn.Close()
+}
@@ -0,0 +1,129 @@
+// Copyright 2025, Command Line Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+//go:build !windows
+
+package sessiond
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/wavetermdev/waveterm/pkg/wavebase"
+)
+
+func readUntil(t *testing.T, client *Client, out *bytes.Buffer, want string) {
+	t.Helper()
+	buf := make([]byte, 1024)
+	deadline := time.Now().Add(5 * time.Second)
+	for !strings.Contains(out.String(), want) {
+		if time.Now().After(deadline) {
+			t.Fatalf("timeout waiting for %q, got %q", want, out.String())
+		}
+		n, err := client.Read(buf)
+		out.Write(buf[:n])
+		if err != nil {
+			if !strings.Contains(out.String(), want) {
+				t.Fatalf("read error waiting for %q (got %q): %v", want, out.String(), err)
+			}
+			return
+		}
+	}
+}
+
+func TestSessionDetachAndReattach(t *testing.T) {
+	sockPath := filepath.Join(t.TempDir(), "test.sock")
+	cmd := exec.Command("sh", "-c", "stty -echo; echo started; read line; echo got:$line; sleep 0.2; echo while-detached; read line; exit 3")
+	serverErrCh := make(chan error, 1)
+	go func() {
+		serverErrCh <- RunServer(sockPath, cmd, 24, 80)
+	}()
+	var client *Client
+	var err error
+	for i := 0; i < 50; i++ {
+		client, err = Attach(sockPath, 24, 80)
+		if err == nil {
+			break
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+	if err != nil {
+		t.Fatalf("cannot attach: %v", err)
+	}
+	var out bytes.Buffer
+	readUntil(t, client, &out, "started")
+	client.Write([]byte("hello\n"))
+	readUntil(t, client, &out, "got:hello")
+
+	// detach, the output printed while detached is buffered
+	client.Detach()
+	io.Copy(io.Discard, client)
+	if err := client.Wait(); err != ErrDetached {
+		t.Fatalf("expected ErrDetached, got %v", err)
+	}
+	client.Close()
+	time.Sleep(500 * time.Millisecond)
+
+	client, err = Attach(sockPath, 24, 80)
+	if err != nil {
+		t.Fatalf("cannot reattach: %v", err)
+	}
+	out.Reset()
+	readUntil(t, client, &out, "while-detached")
+	client.Write([]byte("bye\n"))
+	io.Copy(io.Discard, client)
+	client.Wait()
+	if client.ExitCode() != 3 {
+		t.Errorf("expected exit code 3, got %d", client.ExitCode())
+	}
+	select {
+	case err := <-serverErrCh:
+		if err != nil {
+			t.Errorf("server error: %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("server did not exit")
+	}
+	if IsAlive(sockPath) {
+		t.Errorf("socket should be removed")
+	}
+}
+
+func TestSocketPath(t *testing.T) {
+	// a macOS data dir for a long user name
+	wavebase.DataHome_VarCache = "/Users/firstname.lastname/Library/Application Support/waveterm"
+	defer func() { wavebase.DataHome_VarCache = "" }()
+	blockId := "0d1e3bc2-5a4f-4c7e-9a1e-2b6f1f0c9d3a"
+	sockPath := GetSocketPath(blockId)
+	if filepath.Dir(sockPath) != GetSessionDir() || strings.Contains(sockPath, blockId) {
+		t.Errorf("unexpected socket path %q", sockPath)
+	}
+	if err := CheckSocketPath(sockPath); err != nil {
+		t.Errorf("socket path should fit: %v", err)
+	}
+	if GetSocketPath(blockId) != sockPath || GetSocketPath("other") == sockPath {
+		t.Errorf("socket paths should be stable and unique")
+	}
+	if err := CheckSocketPath("/" + strings.Repeat("x", 200) + ".sock"); err == nil {
+		t.Errorf("expected an error for a long socket path")
+	}
+
+	// sessions are listed by their log files, sockets from before the hashed names are still found
+	wavebase.DataHome_VarCache = t.TempDir()
+	os.MkdirAll(GetSessionDir(), 0700)
+	os.WriteFile(GetLogPath(blockId), nil, 0600)
+	os.WriteFile(filepath.Join(GetSessionDir(), blockId+".sock"), nil, 0600)
+	blockIds, err := ListSessions()
+	if err != nil || len(blockIds) != 1 || blockIds[0] != blockId {
+		t.Errorf("unexpected sessions %v (err %v)", blockIds, err)
+	}
+	if GetSocketPath(blockId) != filepath.Join(GetSessionDir(), blockId+".sock") {
+		t.Errorf("the legacy socket should be used, got %q", GetSocketPath(blockId))
+	}
+}
@@ -4,6 +4,7 @@
 package shellexec
 
 import (
+	"fmt"
 	"io"
 	"os"
 	"os/exec"
@@ -14,6 +15,7 @@ import (
 
 	"github.com/creack/pty"
 	"github.com/wavetermdev/waveterm/pkg/panichandler"
+	"github.com/wavetermdev/waveterm/pkg/sessiond"
 	"github.com/wavetermdev/waveterm/pkg/wsl"
 	"golang.org/x/crypto/ssh"
 )
@@ -232,3 +234,82 @@ func (wcw WslCmdWrap) KillGraceful(timeout time.Duration) {
 func (wcw WslCmdWrap) SetSize(w int, h int) error {
 	return nil
 }
+
+// SessionCmdWrap is a shell running under a persistent session server (see pkg/sessiond)
+type SessionCmdWrap struct {
+	Client   *sessiond.Client
+	SockPath string
+}
+
+func (scw SessionCmdWrap) Kill() {
+	scw.Client.Signal(sessiond.Signal_Kill)
+}
+
+func (scw SessionCmdWrap) KillGraceful(timeout time.Duration) {
+	if scw.Client.Signal(sessiond.Signal_Hup) != nil {
+		return
+	}
+	go func() {
+		defer func() {
+			panichandler.PanicHandler("KillGraceful-session:Kill", recover())
+		}()
+		select {
+		case <-scw.Client.Done():
+		case <-time.After(timeout):
+			scw.Client.Signal(sessiond.Signal_Kill)
+		}
+	}()
+}
+
+func (scw SessionCmdWrap) Wait() error {
+	return scw.Client.Wait()
+}
+
+// the session server has already started the process
+func (scw SessionCmdWrap) Start() error {
+	return nil
+}
+
+func (scw SessionCmdWrap) ExitCode() int {
+	return scw.Client.ExitCode()
+}
+
+func (scw SessionCmdWrap) StdinPipe() (io.WriteCloser, error) {
+	return nil, fmt.Errorf("StdinPipe not supported for session shells")
+}
+
+func (scw SessionCmdWrap) StdoutPipe() (io.ReadCloser, error) {
+	return nil, fmt.Errorf("StdoutPipe not supported for session shells")
+}
+
+func (scw SessionCmdWrap) StderrPipe() (io.ReadCloser, error) {
+	return nil, fmt.Errorf("StderrPipe not supported for session shells")
+}
+
+func (scw SessionCmdWrap) SetSize(w int, h int) error {
+	return scw.Client.Resize(w, h)
+}
+
+func (scw SessionCmdWrap) Fd() uintptr {
+	return 0
+}
+
+func (scw SessionCmdWrap) Name() string {
+	return "sessiond:" + scw.SockPath
+}
+
+func (scw SessionCmdWrap) Read(p []byte) (int, error) {
+	return scw.Client.Read(p)
+}
+
+func (scw SessionCmdWrap) Write(p []byte) (int, error) {
+	return scw.Client.Write(p)
+}
+
+func (scw SessionCmdWrap) WriteString(s string) (int, error) {
+	return scw.Client.Write([]byte(s))
+}
+
+func (scw SessionCmdWrap) Close() error {
+	return scw.Client.Close()
+}
@@ -24,6 +24,8 @@ import (
 	"github.com/wavetermdev/waveterm/pkg/blocklogger"
 	"github.com/wavetermdev/waveterm/pkg/panichandler"
 	"github.com/wavetermdev/waveterm/pkg/remote/conncontroller"
+	"github.com/wavetermdev/waveterm/pkg/sandbox"
+	"github.com/wavetermdev/waveterm/pkg/sessiond"
 	"github.com/wavetermdev/waveterm/pkg/util/pamparse"
 	"github.com/wavetermdev/waveterm/pkg/util/shellutil"
 	"github.com/wavetermdev/waveterm/pkg/wavebase"
@@ -35,6 +37,7 @@ import (
 )
 
 const DefaultGracefulKillWait = 400 * time.Millisecond
+const sessionStartTimeout = 5 * time.Second
 
 type CommandOptsType struct {
 	Interactive bool                      `json:"interactive,omitempty"`
@@ -43,6 +46,7 @@ type CommandOptsType struct {
 	ShellPath   string                    `json:"shellPath,omitempty"`
 	ShellOpts   []string                  `json:"shellOpts,omitempty"`
 	SwapToken   *shellutil.TokenSwapEntry `json:"swapToken,omitempty"`
+	Sandbox     *sandbox.Opts             `json:"sandbox,omitempty"` // local only
 }
 
 type ShellProc struct {
@@ -71,6 +75,22 @@ func (sp *ShellProc) Close() {
 	}()
 }
 
+// Detach leaves a persistent session shell running (Wait then returns sessiond.ErrDetached).
+// Returns false if the shell is not a session shell.
+func (sp *ShellProc) Detach() bool {
+	scw, ok := sp.Cmd.(SessionCmdWrap)
+	if !ok {
+		return false
+	}
+	scw.Client.Detach()
+	return true
+}
+
+func (sp *ShellProc) IsDetached() bool {
+	scw, ok := sp.Cmd.(SessionCmdWrap)
+	return ok && scw.Client.IsDetached()
+}
+
 func (sp *ShellProc) SetWaitErrorAndSignalDone(waitErr error) {
 	sp.CloseOnce.Do(func() {
 		sp.WaitErr = waitErr
@@ -471,7 +491,8 @@ func isFishShell(shellPath string) bool {
 	return strings.Contains(shellBase, "fish")
 }
 
-func StartLocalShellProc(logCtx context.Context, termSize waveobj.TermSize, cmdStr string, cmdOpts CommandOptsType) (*ShellProc, error) {
+// builds the command for a local shell (environment, rcfiles, cwd) and registers its swap token
+func makeLocalShellCmd(logCtx context.Context, termSize waveobj.TermSize, cmdStr string, cmdOpts CommandOptsType) (*exec.Cmd, waveobj.TermSize, error) {
 	shellutil.InitCustomShellStartupFiles()
 	var ecmd *exec.Cmd
 	var shellOpts []string
@@ -515,12 +536,23 @@ func StartLocalShellProc(logCtx context.Context, termSize waveobj.TermSize, cmdS
 		ecmd.Env = os.Environ()
 	}
 
-	packedToken, err := cmdOpts.SwapToken.PackForClient()
-	if err != nil {
-		blocklogger.Infof(logCtx, "error packing swap token: %v", err)
+	if cmdOpts.Sandbox != nil {
+		// no swap token in a sandbox (it could be exchanged for a jwt), the env is set directly
+		sandboxEnv := make(map[string]string)
+		for k, v := range cmdOpts.SwapToken.Env {
+			if k != wavebase.WaveJwtTokenVarName {
+				sandboxEnv[k] = v
+			}
+		}
+		shellutil.UpdateCmdEnv(ecmd, sandboxEnv)
 	} else {
-		blocklogger.Debugf(logCtx, "packed swaptoken %s\n", packedToken)
-		shellutil.UpdateCmdEnv(ecmd, map[string]string{wavebase.WaveSwapTokenVarName: packedToken})
+		packedToken, err := cmdOpts.SwapToken.PackForClient()
+		if err != nil {
+			blocklogger.Infof(logCtx, "error packing swap token: %v", err)
+		} else {
+			blocklogger.Debugf(logCtx, "packed swaptoken %s\n", packedToken)
+			shellutil.UpdateCmdEnv(ecmd, map[string]string{wavebase.WaveSwapTokenVarName: packedToken})
+		}
 	}
 
 	/*
@@ -561,17 +593,75 @@ func StartLocalShellProc(logCtx context.Context, termSize waveobj.TermSize, cmdS
 		termSize.Cols = shellutil.DefaultTermCols
 	}
 	if termSize.Rows <= 0 || termSize.Cols <= 0 {
-		return nil, fmt.Errorf("invalid term size: %v", termSize)
+		return nil, termSize, fmt.Errorf("invalid term size: %v", termSize)
+	}
+	if cmdOpts.Sandbox == nil {
+		shellutil.AddTokenSwapEntry(cmdOpts.SwapToken)
+	}
+	return ecmd, termSize, nil
+}
+
+func StartLocalShellProc(logCtx context.Context, termSize waveobj.TermSize, cmdStr string, cmdOpts CommandOptsType) (*ShellProc, error) {
+	ecmd, termSize, err := makeLocalShellCmd(logCtx, termSize, cmdStr, cmdOpts)
+	if err != nil {
+		return nil, err
+	}
+	if cmdOpts.Sandbox != nil {
+		wshPath := filepath.Join(wavebase.GetWaveDataDir(), shellutil.WaveHomeBinDir, "wsh")
+		blocklogger.Infof(logCtx, "[conndebug] starting shell in sandbox (writable: %s)\n", strings.Join(cmdOpts.Sandbox.Writable, ", "))
+		ecmd, err = sandbox.WrapCmd(wshPath, ecmd, *cmdOpts.Sandbox)
+		if err != nil {
+			return nil, err
+		}
 	}
-	shellutil.AddTokenSwapEntry(cmdOpts.SwapToken)
 	cmdPty, err := pty.StartWithSize(ecmd, &pty.Winsize{Rows: uint16(termSize.Rows), Cols: uint16(termSize.Cols)})
 	if err != nil {
+		if cmdOpts.Sandbox != nil {
+			return nil, fmt.Errorf("cannot start sandbox (unprivileged user namespaces may be disabled): %w", err)
+		}
 		return nil, err
 	}
 	cmdWrap := MakeCmdWrap(ecmd, cmdPty)
 	return &ShellProc{Cmd: cmdWrap, CloseOnce: &sync.Once{}, DoneCh: make(chan any)}, nil
 }
 
+// StartLocalSessionShellProc starts a local shell under a persistent session server ("wsh sessiond")
+// keyed by blockId, so the shell keeps running when wavesrv exits (see AttachLocalSessionShellProc)
+func StartLocalSessionShellProc(logCtx context.Context, termSize waveobj.TermSize, cmdStr string, cmdOpts CommandOptsType, blockId string) (*ShellProc, error) {
+	if runtime.GOOS == "windows" {
+		return nil, fmt.Errorf("persistent sessions are not supported on windows")
+	}
+	ecmd, termSize, err := makeLocalShellCmd(logCtx, termSize, cmdStr, cmdOpts)
+	if err != nil {
+		return nil, err
+	}
+	// use the copy of wsh in the data dir, it survives app updates (and AppImage unmounts)
+	wshPath := filepath.Join(wavebase.GetWaveDataDir(), shellutil.WaveHomeBinDir, "wsh")
+	sockPath := sessiond.GetSocketPath(blockId)
+	blocklogger.Debugf(logCtx, "[conndebug] starting session server %s\n", sockPath)
+	err = sessiond.StartServer(wshPath, sockPath, sessiond.GetLogPath(blockId), ecmd, termSize.Rows, termSize.Cols)
+	if err != nil {
+		return nil, err
+	}
+	client, err := sessiond.AttachWithRetry(sockPath, termSize.Rows, termSize.Cols, sessionStartTimeout)
+	if err != nil {
+		return nil, fmt.Errorf("session server did not start (see %s): %w", sessiond.GetLogPath(blockId), err)
+	}
+	cmdWrap := SessionCmdWrap{Client: client, SockPath: sockPath}
+	return &ShellProc{Cmd: cmdWrap, CloseOnce: &sync.Once{}, DoneCh: make(chan any)}, nil
+}
+
+// AttachLocalSessionShellProc reattaches to a running persistent session (started by StartLocalSessionShellProc)
+func AttachLocalSessionShellProc(blockId string, termSize waveobj.TermSize) (*ShellProc, error) {
+	sockPath := sessiond.GetSocketPath(blockId)
+	client, err := sessiond.Attach(sockPath, termSize.Rows, termSize.Cols)
+	if err != nil {
+		return nil, err
+	}
+	cmdWrap := SessionCmdWrap{Client: client, SockPath: sockPath}
+	return &ShellProc{Cmd: cmdWrap, CloseOnce: &sync.Once{}, DoneCh: make(chan any)}, nil
+}
+
 func RunSimpleCmdInPty(ecmd *exec.Cmd, termSize waveobj.TermSize) ([]byte, error) {
 	ecmd.Env = os.Environ()
 	shellutil.UpdateCmdEnv(ecmd, shellutil.WaveshellLocalEnvVars(shellutil.DefaultTermType))
@@ -0,0 +1,258 @@
+// Copyright 2025, Command Line Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package ansiutil
+
+import (
+	"fmt"
+	"html"
+	"strconv"
+	"strings"
+)
+
+var basicColors = []string{
+	"#000000", "#cc0000", "#4e9a06", "#c4a000", "#3465a4", "#75507b", "#06989a", "#d3d7cf",
+	"#555753", "#ef2929", "#8ae234", "#fce94f", "#729fcf", "#ad7fa8", "#34e2e2", "#eeeeec",
+}
+
+const htmlDefaultFg = "#d3d7cf"
+const htmlDefaultBg = "#000000"
+
+type sgrStyle struct {
+	Fg        string
+	Bg        string
+	Bold      bool
+	Dim       bool
+	Italic    bool
+	Underline bool
+	Strike    bool
+	Inverse   bool
+}
+
+func (st sgrStyle) css() string {
+	fg, bg := st.Fg, st.Bg
+	if st.Inverse {
+		fg, bg = bg, fg
+		if fg == "" {
+			fg = htmlDefaultBg
+		}
+		if bg == "" {
+			bg = htmlDefaultFg
+		}
+	}
+	var parts []string
+	if fg != "" {
+		parts = append(parts, "color:"+fg)
+	}
+	if bg != "" {
+		parts = append(parts, "background-color:"+bg)
+	}
+	if st.Bold {
+		parts = append(parts, "font-weight:bold")
+	}
+	if st.Dim {
+		parts = append(parts, "opacity:0.7")
+	}
+	if st.Italic {
+		parts = append(parts, "font-style:italic")
+	}
+	if st.Underline && st.Strike {
+		parts = append(parts, "text-decoration:underline line-through")
+	} else if st.Underline {
+		parts = append(parts, "text-decoration:underline")
+	} else if st.Strike {
+		parts = append(parts, "text-decoration:line-through")
+	}
+	return strings.Join(parts, ";")
+}
+
+func color256(n int) string {
+	if n < 0 || n > 255 {
+		return ""
+	}
+	if n < 16 {
+		return basicColors[n]
+	}
+	if n < 232 {
+		n -= 16
+		levels := []int{0, 95, 135, 175, 215, 255}
+		return fmt.Sprintf("#%02x%02x%02x", levels[n/36], levels[(n/6)%6], levels[n%6])
+	}
+	gray := 8 + (n-232)*10
+	return fmt.Sprintf("#%02x%02x%02x", gray, gray, gray)
+}
+
+func parseSgrParams(params []byte) []int {
+	if len(params) == 0 {
+		return []int{0}
+	}
+	fields := strings.FieldsFunc(string(params), func(r rune) bool { return r == ';' || r == ':' })
+	rtn := make([]int, 0, len(fields))
+	for _, f := range fields {
+		n, err := strconv.Atoi(f)
+		if err != nil {
+			n = 0
+		}
+		rtn = append(rtn, n)
+	}
+	if len(rtn) == 0 {
+		return []int{0}
+	}
+	return rtn
+}
+
+// parses an extended color (38/48) starting at params[idx], returns the color and the number of params consumed
+func parseExtColor(params []int, idx int) (string, int) {
+	if idx+1 >= len(params) {
+		return "", len(params) - idx
+	}
+	switch params[idx+1] {
+	case 5:
+		if idx+2 < len(params) {
+			return color256(params[idx+2]), 3
+		}
+	case 2:
+		if idx+4 < len(params) {
+			return fmt.Sprintf("#%02x%02x%02x", params[idx+2]&0xff, params[idx+3]&0xff, params[idx+4]&0xff), 5
+		}
+	}
+	return "", len(params) - idx
+}
+
+func (st *sgrStyle) apply(params []int) {
+	for idx := 0; idx < len(params); idx++ {
+		p := params[idx]
+		switch {
+		case p == 0:
+			*st = sgrStyle{}
+		case p == 1:
+			st.Bold = true
+		case p == 2:
+			st.Dim = true
+		case p == 3:
+			st.Italic = true
+		case p == 4:
+			st.Underline = true
+		case p == 7:
+			st.Inverse = true
+		case p == 9:
+			st.Strike = true
+		case p == 22:
+			st.Bold = false
+			st.Dim = false
+		case p == 23:
+			st.Italic = false
+		case p == 24:
+			st.Underline = false
+		case p == 27:
+			st.Inverse = false
+		case p == 29:
+			st.Strike = false
+		case p >= 30 && p <= 37:
+			st.Fg = basicColors[p-30]
+		case p == 38:
+			color, consumed := parseExtColor(params, idx)
+			st.Fg = color
+			idx += consumed - 1
+		case p == 39:
+			st.Fg = ""
+		case p >= 40 && p <= 47:
+			st.Bg = basicColors[p-40]
+		case p == 48:
+			color, consumed := parseExtColor(params, idx)
+			st.Bg = color
+			idx += consumed - 1
+		case p == 49:
+			st.Bg = ""
+		case p >= 90 && p <= 97:
+			st.Fg = basicColors[p-90+8]
+		case p >= 100 && p <= 107:
+			st.Bg = basicColors[p-100+8]
+		}
+	}
+}
+
+// ToHTML converts raw terminal output into a standalone HTML document, preserving SGR colors and text attributes
+func ToHTML(data []byte, title string) string {
+	c := MakeHTMLConverter(title)
+	return c.Convert(data) + c.Close()
+}
+
+// HTMLConverter does what ToHTML does for output that is fed in parts (the sgr state carries over between parts)
+type HTMLConverter struct {
+	title       string
+	stripper    Stripper
+	style       sgrStyle
+	curCss      string
+	spanOpen    bool
+	wroteHeader bool
+}
+
+func MakeHTMLConverter(title string) *HTMLConverter {
+	c := &HTMLConverter{title: title}
+	c.stripper.OnCsi = func(params []byte, final byte) {
+		if final != 'm' {
+			return
+		}
+		c.style.apply(parseSgrParams(params))
+	}
+	return c
+}
+
+// returns the html for data (the document header is written before the first part)
+func (c *HTMLConverter) Convert(data []byte) string {
+	var body strings.Builder
+	c.writeHeader(&body)
+	var run []byte
+	flushRun := func() {
+		if len(run) == 0 {
+			return
+		}
+		body.WriteString(html.EscapeString(string(run)))
+		run = run[:0]
+	}
+	for _, ch := range data {
+		if !c.stripper.processByte(ch) {
+			continue
+		}
+		newCss := c.style.css()
+		if newCss != c.curCss {
+			flushRun()
+			if c.spanOpen {
+				body.WriteString("</span>")
+				c.spanOpen = false
+			}
+			if newCss != "" {
+				body.WriteString(`<span style="` + newCss + `">`)
+				c.spanOpen = true
+			}
+			c.curCss = newCss
+		}
+		run = append(run, ch)
+	}
+	flushRun()
+	return body.String()
+}
+
+// closes the open span and returns the end of the document
+func (c *HTMLConverter) Close() string {
+	var rtn strings.Builder
+	c.writeHeader(&rtn)
+	if c.spanOpen {
+		rtn.WriteString("</span>")
+		c.spanOpen = false
+	}
+	rtn.WriteString("</pre>\n</body>\n</html>\n")
+	return rtn.String()
+}
+
+func (c *HTMLConverter) writeHeader(w *strings.Builder) {
+	if c.wroteHeader {
+		return
+	}
+	c.wroteHeader = true
+	w.WriteString("<!DOCTYPE html>\n<html>\n<head>\n<meta charset=\"utf-8\">\n")
+	w.WriteString("<title>" + html.EscapeString(c.title) + "</title>\n")
+	w.WriteString("</head>\n<body style=\"margin:0;background-color:" + htmlDefaultBg + "\">\n")
+	w.WriteString("<pre style=\"margin:0;padding:8px;color:" + htmlDefaultFg + ";font-family:monospace;white-space:pre-wrap\">")
+}
@@ -0,0 +1,223 @@
+// Copyright 2025, Command Line Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+// utilities for dealing with raw terminal output (ANSI escape sequences)
+package ansiutil
+
+const (
+	stateGround = iota
+	stateEsc
+	stateEscIntermediate
+	stateCsi
+	stateString    // OSC, DCS, SOS, PM, APC (terminated by BEL or ST)
+	stateStringEsc // saw ESC inside a string, expecting '\\'
+)
+
+// Stripper removes escape sequences and non-printing control characters from terminal output.
+// it is stateful, so sequences split across calls to Strip are handled correctly.
+// \n and \t are passed through, \r is dropped.
+type Stripper struct {
+	state    int
+	csiBuf   []byte
+	OnCsi    func(params []byte, final byte) // optional, called for every complete CSI sequence
+	OnString func(data []byte)               // optional, called for every complete OSC/DCS/etc string
+	strBuf   []byte
+	pos      int64 // stream position (total bytes fed) of the byte being processed
+	seqStart int64 // stream position of the ESC that started the current sequence
+}
+
+const maxSeqBufSize = 4096
+
+// returns the stripped text, and for every output byte, the offset of the corresponding input byte (relative to baseOffset)
+func (s *Stripper) Strip(data []byte, baseOffset int64) ([]byte, []int64) {
+	text := make([]byte, 0, len(data))
+	offsets := make([]int64, 0, len(data))
+	for idx, ch := range data {
+		if s.processByte(ch) {
+			text = append(text, ch)
+			offsets = append(offsets, baseOffset+int64(idx))
+		}
+		s.pos++
+	}
+	return text, offsets
+}
+
+// Scan runs data through the state machine without collecting text, for callers that only need OnCsi/OnString
+func (s *Stripper) Scan(data []byte) {
+	for _, ch := range data {
+		s.processByte(ch)
+		s.pos++
+	}
+}
+
+// Pos returns the stream position (number of bytes fed to Strip/Scan so far) of the byte being processed.
+// Inside OnCsi/OnString this is the position of the sequence's final byte.
+func (s *Stripper) Pos() int64 {
+	return s.pos
+}
+
+// SeqStart returns the stream position of the ESC that started the current (or just completed) sequence.
+// The sequence may have started in an earlier call to Strip/Scan.
+func (s *Stripper) SeqStart() int64 {
+	return s.seqStart
+}
+
+// returns true if the byte is printable output
+func (s *Stripper) processByte(ch byte) bool {
+	switch s.state {
+	case stateGround:
+		if ch == 0x1b {
+			s.state = stateEsc
+			s.seqStart = s.pos
+			return false
+		}
+		if ch == '\n' || ch == '\t' {
+			return true
+		}
+		if ch < 0x20 || ch == 0x7f {
+			return false
+		}
+		return true
+	case stateEsc:
+		switch {
+		case ch == '[':
+			s.state = stateCsi
+			s.csiBuf = s.csiBuf[:0]
+		case ch == ']' || ch == 'P' || ch == 'X' || ch == '^' || ch == '_':
+			s.state = stateString
+			s.strBuf = s.strBuf[:0]
+		case ch >= 0x20 && ch <= 0x2f:
+			s.state = stateEscIntermediate
+		case ch == 0x1b:
+			// stay in esc
+		default:
+			s.state = stateGround
+		}
+		return false
+	case stateEscIntermediate:
+		if ch < 0x20 || ch > 0x2f {
+			s.state = stateGround
+		}
+		return false
+	case stateCsi:
+		if ch >= 0x40 && ch <= 0x7e {
+			if s.OnCsi != nil {
+				s.OnCsi(s.csiBuf, ch)
+			}
+			s.state = stateGround
+			return false
+		}
+		if ch == 0x1b {
+			s.state = stateEsc
+			s.seqStart = s.pos
+			return false
+		}
+		if len(s.csiBuf) < maxSeqBufSize {
+			s.csiBuf = append(s.csiBuf, ch)
+		}
+		return false
+	case stateString:
+		if ch == 0x07 {
+			s.finishString()
+			return false
+		}
+		if ch == 0x1b {
+			s.state = stateStringEsc
+			return false
+		}
+		if len(s.strBuf) < maxSeqBufSize {
+			s.strBuf = append(s.strBuf, ch)
+		}
+		return false
+	case stateStringEsc:
+		if ch == '\\' {
+			s.finishString()
+			return false
+		}
+		// not a valid ST, treat as the start of a new escape sequence
+		s.finishString()
+		s.state = stateEsc
+		s.seqStart = s.pos - 1
+		return s.processByte(ch)
+	}
+	s.state = stateGround
+	return false
+}
+
+func (s *Stripper) finishString() {
+	if s.OnString != nil {
+		s.OnString(s.strBuf)
+	}
+	s.state = stateGround
+}
+
+// InGround returns true if the stripper is not in the middle of an escape sequence
+func (s *Stripper) InGround() bool {
+	return s.state == stateGround
+}
+
+// StripWithOffsets strips a complete buffer, returning the text and the input offset of each output byte
+func StripWithOffsets(data []byte) ([]byte, []int64) {
+	var s Stripper
+	return s.Strip(data, 0)
+}
+
+// Strip converts raw terminal output to plain text.
+// unlike StripWithOffsets, carriage returns are interpreted (text after a bare \r overwrites the start of the line)
+// and backspaces remove the previous character.
+func Strip(data []byte) []byte {
+	var c TextConverter
+	return append(c.Convert(data), c.Close()...)
+}
+
+// TextConverter does what Strip does for output that is fed in parts.  the current line is held back until it is
+// complete (or Close is called), since a later \r can still overwrite it.
+type TextConverter struct {
+	s    Stripper
+	line []byte
+	col  int
+}
+
+// returns the lines completed by data
+func (c *TextConverter) Convert(data []byte) []byte {
+	rtn := make([]byte, 0, len(data))
+	for _, ch := range data {
+		if c.s.state == stateGround {
+			if ch == '\r' {
+				c.col = 0
+				continue
+			}
+			if ch == '\b' {
+				if c.col > 0 {
+					c.col--
+				}
+				continue
+			}
+		}
+		if !c.s.processByte(ch) {
+			continue
+		}
+		if ch == '\n' {
+			rtn = append(rtn, c.line...)
+			rtn = append(rtn, '\n')
+			c.line = c.line[:0]
+			c.col = 0
+			continue
+		}
+		if c.col < len(c.line) {
+			c.line[c.col] = ch
+		} else {
+			c.line = append(c.line, ch)
+		}
+		c.col++
+	}
+	return rtn
+}
+
+// returns the last (unterminated) line
+func (c *TextConverter) Close() []byte {
+	rtn := c.line
+	c.line = nil
+	c.col = 0
+	return rtn
+}
@@ -0,0 +1,86 @@
+// Copyright 2025, Command Line Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package ansiutil
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestStrip(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{"hello\r\nworld\r\n", "hello\nworld\n"},
+		{"\x1b[1;31mred\x1b[0m text", "red text"},
+		{"\x1b]0;title\x07prompt$ ", "prompt$ "},
+		{"\x1b]7;file://host/tmp\x1b\\ok", "ok"},
+		{"abc\bd", "abd"},
+		{"progress 10%\rprogress 100%\n", "progress 100%\n"},
+		{"12345\rab\n", "ab345\n"},
+		{"\x1b(Bplain", "plain"},
+	}
+	for _, tt := range tests {
+		got := string(Strip([]byte(tt.input)))
+		if got != tt.want {
+			t.Errorf("Strip(%q) = %q, want %q", tt.input, got, tt.want)
+		}
+	}
+}
+
+func TestStripOffsetsAcrossChunks(t *testing.T) {
+	input := "ab\x1b[31mcd\x1b]0;x\x07ef"
+	var s Stripper
+	var text []byte
+	var offsets []int64
+	// feed one byte at a time so every escape sequence is split
+	for idx := 0; idx < len(input); idx++ {
+		chunkText, chunkOffsets := s.Strip([]byte{input[idx]}, int64(idx))
+		text = append(text, chunkText...)
+		offsets = append(offsets, chunkOffsets...)
+	}
+	if string(text) != "abcdef" {
+		t.Fatalf("got text %q, want %q", text, "abcdef")
+	}
+	for idx, off := range offsets {
+		if input[off] != text[idx] {
+			t.Errorf("offset %d for %q points to %q", off, text[idx], input[off])
+		}
+	}
+}
+
+func TestToHTML(t *testing.T) {
+	out := ToHTML([]byte("a<b \x1b[1;31mred\x1b[0m \x1b[38;5;21mblue\x1b[m"), "test")
+	if !strings.Contains(out, "a&lt;b ") {
+		t.Errorf("expected escaped text in output: %s", out)
+	}
+	if !strings.Contains(out, `<span style="color:#cc0000;font-weight:bold">red</span>`) {
+		t.Errorf("expected bold red span in output: %s", out)
+	}
+	if !strings.Contains(out, `<span style="color:#0000ff">blue</span>`) {
+		t.Errorf("expected 256 color span in output: %s", out)
+	}
+}
+
+func TestConvertersInParts(t *testing.T) {
+	input := []byte("a<b \x1b[1;31mred\x1b[0m 12345\rab\nnext \x1b]0;title\x07line\x1b[32m green")
+	var textConv TextConverter
+	htmlConv := MakeHTMLConverter("test")
+	var text []byte
+	var html string
+	// feed one byte at a time so every escape sequence and line is split
+	for idx := range input {
+		text = append(text, textConv.Convert(input[idx:idx+1])...)
+		html += htmlConv.Convert(input[idx : idx+1])
+	}
+	text = append(text, textConv.Close()...)
+	html += htmlConv.Close()
+	if string(text) != string(Strip(input)) {
+		t.Errorf("text in parts: got %q, want %q", text, Strip(input))
+	}
+	if html != ToHTML(input, "test") {
+		t.Errorf("html in parts: got %q, want %q", html, ToHTML(input, "test"))
+	}
+}
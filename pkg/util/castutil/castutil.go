@@ -0,0 +1,87 @@
+// Copyright 2025, Command Line Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+// asciicast v2 file format (https://docs.asciinema.org/manual/asciicast/v2/)
+package castutil
+
+import (
+	"encoding/json"
+	"fmt"
+	"unicode/utf8"
+)
+
+const CastVersion = 2
+
+const (
+	EventType_Output = "o"
+	EventType_Input  = "i"
+	EventType_Resize = "r"
+)
+
+// first line of a cast file
+type Header struct {
+	Version   int               `json:"version"`
+	Width     int               `json:"width"`
+	Height    int               `json:"height"`
+	Timestamp int64             `json:"timestamp,omitempty"`
+	Title     string            `json:"title,omitempty"`
+	Env       map[string]string `json:"env,omitempty"`
+}
+
+// every other line is an event, encoded as a json array [time, type, data]
+type Event struct {
+	Time float64 // seconds since the start of the recording
+	Type string
+	Data string
+}
+
+func ParseHeader(line []byte) (*Header, error) {
+	var header Header
+	err := json.Unmarshal(line, &header)
+	if err != nil {
+		return nil, fmt.Errorf("invalid asciicast header: %w", err)
+	}
+	if header.Version != CastVersion {
+		return nil, fmt.Errorf("unsupported asciicast version %d", header.Version)
+	}
+	return &header, nil
+}
+
+func (e Event) MarshalJSON() ([]byte, error) {
+	return json.Marshal([]any{e.Time, e.Type, e.Data})
+}
+
+func (e *Event) UnmarshalJSON(data []byte) error {
+	var arr []json.RawMessage
+	if err := json.Unmarshal(data, &arr); err != nil {
+		return err
+	}
+	if len(arr) != 3 {
+		return fmt.Errorf("invalid asciicast event (expected 3 elements, got %d)", len(arr))
+	}
+	if err := json.Unmarshal(arr[0], &e.Time); err != nil {
+		return fmt.Errorf("invalid asciicast event time: %w", err)
+	}
+	if err := json.Unmarshal(arr[1], &e.Type); err != nil {
+		return fmt.Errorf("invalid asciicast event type: %w", err)
+	}
+	if err := json.Unmarshal(arr[2], &e.Data); err != nil {
+		return fmt.Errorf("invalid asciicast event data: %w", err)
+	}
+	return nil
+}
+
+// SplitIncompleteUtf8 splits off a trailing partial utf-8 sequence, so that multi-byte characters
+// split across reads are not replaced by U+FFFD when written as json strings
+func SplitIncompleteUtf8(data []byte) ([]byte, []byte) {
+	for i := 1; i < utf8.UTFMax && i <= len(data); i++ {
+		if !utf8.RuneStart(data[len(data)-i]) {
+			continue
+		}
+		if utf8.FullRune(data[len(data)-i:]) {
+			return data, nil
+		}
+		return data[:len(data)-i], data[len(data)-i:]
+	}
+	return data, nil
+}
@@ -0,0 +1,51 @@
+// Copyright 2025, Command Line Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package castutil
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestEventRoundTrip(t *testing.T) {
+	event := Event{Time: 1.25, Type: EventType_Output, Data: "hello\r\n\x1b[0m"}
+	barr, err := json.Marshal(event)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(barr) != `[1.25,"o","hello\r\n\u001b[0m"]` {
+		t.Errorf("unexpected encoding: %s", barr)
+	}
+	var decoded Event
+	if err := json.Unmarshal(barr, &decoded); err != nil {
+		t.Fatal(err)
+	}
+	if decoded != event {
+		t.Errorf("got %+v, want %+v", decoded, event)
+	}
+	if err := json.Unmarshal([]byte(`[1, "o"]`), &decoded); err == nil {
+		t.Errorf("expected error for short event")
+	}
+}
+
+func TestSplitIncompleteUtf8(t *testing.T) {
+	full := []byte("ab€") // € is 3 bytes
+	tests := []struct {
+		input    []byte
+		complete string
+		rest     string
+	}{
+		{full, "ab€", ""},
+		{full[:4], "ab", string(full[2:4])},
+		{full[:3], "ab", string(full[2:3])},
+		{[]byte("abc"), "abc", ""},
+		{[]byte{}, "", ""},
+	}
+	for _, test := range tests {
+		complete, rest := SplitIncompleteUtf8(test.input)
+		if string(complete) != test.complete || string(rest) != test.rest {
+			t.Errorf("SplitIncompleteUtf8(%q) = %q, %q, want %q, %q", test.input, complete, rest, test.complete, test.rest)
+		}
+	}
+}
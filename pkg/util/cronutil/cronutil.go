@@ -0,0 +1,220 @@
+// Copyright 2025, Command Line Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+// cronutil parses schedules: standard 5 field cron expressions ("*/5 * * * *"), the @hourly/@daily/...
+// descriptors, and intervals ("@every 30s" or just "30s").
+package cronutil
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const MinInterval = time.Second
+
+type Schedule interface {
+	// Next returns the first activation time after t
+	Next(t time.Time) time.Time
+}
+
+type IntervalSchedule struct {
+	Interval time.Duration
+}
+
+func (s IntervalSchedule) Next(t time.Time) time.Time {
+	return t.Add(s.Interval)
+}
+
+// CronSchedule holds a bitset of the allowed values for each field
+type CronSchedule struct {
+	Minute uint64
+	Hour   uint64
+	Dom    uint64
+	Month  uint64
+	Dow    uint64
+	domAny bool
+	dowAny bool
+}
+
+type fieldBounds struct {
+	name  string
+	min   int
+	max   int
+	names map[string]int
+}
+
+var (
+	minuteBounds = fieldBounds{name: "minute", min: 0, max: 59}
+	hourBounds   = fieldBounds{name: "hour", min: 0, max: 23}
+	domBounds    = fieldBounds{name: "day of month", min: 1, max: 31}
+	monthBounds  = fieldBounds{name: "month", min: 1, max: 12, names: map[string]int{
+		"jan": 1, "feb": 2, "mar": 3, "apr": 4, "may": 5, "jun": 6,
+		"jul": 7, "aug": 8, "sep": 9, "oct": 10, "nov": 11, "dec": 12,
+	}}
+	// 7 is also sunday
+	dowBounds = fieldBounds{name: "day of week", min: 0, max: 7, names: map[string]int{
+		"sun": 0, "mon": 1, "tue": 2, "wed": 3, "thu": 4, "fri": 5, "sat": 6,
+	}}
+)
+
+var descriptors = map[string]string{
+	"@yearly":   "0 0 1 1 *",
+	"@annually": "0 0 1 1 *",
+	"@monthly":  "0 0 1 * *",
+	"@weekly":   "0 0 * * 0",
+	"@daily":    "0 0 * * *",
+	"@midnight": "0 0 * * *",
+	"@hourly":   "0 * * * *",
+}
+
+func Parse(spec string) (Schedule, error) {
+	spec = strings.TrimSpace(spec)
+	if spec == "" {
+		return nil, fmt.Errorf("empty schedule")
+	}
+	if intervalStr, ok := strings.CutPrefix(spec, "@every "); ok {
+		return parseInterval(strings.TrimSpace(intervalStr))
+	}
+	if expanded, ok := descriptors[strings.ToLower(spec)]; ok {
+		spec = expanded
+	}
+	fields := strings.Fields(spec)
+	if len(fields) == 1 {
+		return parseInterval(fields[0])
+	}
+	if len(fields) != 5 {
+		return nil, fmt.Errorf("invalid schedule %q: expected 5 cron fields (minute hour day-of-month month day-of-week) or an interval", spec)
+	}
+	var rtn CronSchedule
+	var err error
+	if rtn.Minute, err = parseField(fields[0], minuteBounds); err != nil {
+		return nil, err
+	}
+	if rtn.Hour, err = parseField(fields[1], hourBounds); err != nil {
+		return nil, err
+	}
+	if rtn.Dom, err = parseField(fields[2], domBounds); err != nil {
+		return nil, err
+	}
+	if rtn.Month, err = parseField(fields[3], monthBounds); err != nil {
+		return nil, err
+	}
+	if rtn.Dow, err = parseField(fields[4], dowBounds); err != nil {
+		return nil, err
+	}
+	if rtn.Dow&(1<<7) != 0 {
+		rtn.Dow |= 1
+	}
+	rtn.domAny = fields[2] == "*" || fields[2] == "?"
+	rtn.dowAny = fields[4] == "*" || fields[4] == "?"
+	return rtn, nil
+}
+
+func parseInterval(str string) (Schedule, error) {
+	interval, err := time.ParseDuration(str)
+	if err != nil {
+		return nil, fmt.Errorf("invalid interval %q: %w", str, err)
+	}
+	if interval < MinInterval {
+		return nil, fmt.Errorf("interval %q is too short (minimum %v)", str, MinInterval)
+	}
+	return IntervalSchedule{Interval: interval}, nil
+}
+
+func parseValue(str string, bounds fieldBounds) (int, error) {
+	if val, ok := bounds.names[strings.ToLower(str)]; ok {
+		return val, nil
+	}
+	val, err := strconv.Atoi(str)
+	if err != nil || val < bounds.min || val > bounds.max {
+		return 0, fmt.Errorf("invalid %s %q (must be %d-%d)", bounds.name, str, bounds.min, bounds.max)
+	}
+	return val, nil
+}
+
+// parses a comma separated list of "*", "N", "N-M", with an optional "/step"
+func parseField(field string, bounds fieldBounds) (uint64, error) {
+	var bits uint64
+	for _, part := range strings.Split(field, ",") {
+		rangeStr, stepStr, hasStep := strings.Cut(part, "/")
+		step := 1
+		if hasStep {
+			var err error
+			step, err = strconv.Atoi(stepStr)
+			if err != nil || step <= 0 {
+				return 0, fmt.Errorf("invalid step %q in %s field", stepStr, bounds.name)
+			}
+		}
+		var start, end int
+		if rangeStr == "*" || rangeStr == "?" {
+			start, end = bounds.min, bounds.max
+		} else if startStr, endStr, isRange := strings.Cut(rangeStr, "-"); isRange {
+			var err error
+			if start, err = parseValue(startStr, bounds); err != nil {
+				return 0, err
+			}
+			if end, err = parseValue(endStr, bounds); err != nil {
+				return 0, err
+			}
+			if end < start {
+				return 0, fmt.Errorf("invalid range %q in %s field", rangeStr, bounds.name)
+			}
+		} else {
+			var err error
+			if start, err = parseValue(rangeStr, bounds); err != nil {
+				return 0, err
+			}
+			end = start
+			if hasStep {
+				// "N/step" means N through max
+				end = bounds.max
+			}
+		}
+		for val := start; val <= end; val += step {
+			bits |= 1 << uint(val)
+		}
+	}
+	return bits, nil
+}
+
+func hasBit(bits uint64, val int) bool {
+	return bits&(1<<uint(val)) != 0
+}
+
+// standard cron semantics: if both day of month and day of week are restricted, either can match
+func (s CronSchedule) dayMatches(t time.Time) bool {
+	domMatch := hasBit(s.Dom, t.Day())
+	dowMatch := hasBit(s.Dow, int(t.Weekday()))
+	if s.domAny || s.dowAny {
+		return domMatch && dowMatch
+	}
+	return domMatch || dowMatch
+}
+
+func (s CronSchedule) Next(t time.Time) time.Time {
+	t = t.Truncate(time.Minute).Add(time.Minute)
+	// a matching time is always found within a few years (e.g. feb 29), give up after that
+	limit := t.AddDate(5, 0, 0)
+	for t.Before(limit) {
+		if !hasBit(s.Month, int(t.Month())) {
+			t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, t.Location())
+			continue
+		}
+		if !s.dayMatches(t) {
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, t.Location())
+			continue
+		}
+		if !hasBit(s.Hour, t.Hour()) {
+			t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour()+1, 0, 0, 0, t.Location())
+			continue
+		}
+		if !hasBit(s.Minute, t.Minute()) {
+			t = t.Add(time.Minute)
+			continue
+		}
+		return t
+	}
+	return time.Time{}
+}
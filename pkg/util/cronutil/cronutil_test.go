@@ -0,0 +1,50 @@
+// Copyright 2025, Command Line Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package cronutil
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseErrors(t *testing.T) {
+	bad := []string{"", "* * * *", "60 * * * *", "* 24 * * *", "*/0 * * * *", "5-1 * * * *", "@every 10ms", "foo", "* * * abc *"}
+	for _, spec := range bad {
+		if _, err := Parse(spec); err == nil {
+			t.Errorf("expected error for %q", spec)
+		}
+	}
+}
+
+func TestNext(t *testing.T) {
+	base := time.Date(2025, 3, 14, 10, 7, 30, 0, time.UTC) // a friday
+	tests := []struct {
+		spec string
+		want time.Time
+	}{
+		{"30s", base.Add(30 * time.Second)},
+		{"@every 5m", base.Add(5 * time.Minute)},
+		{"* * * * *", time.Date(2025, 3, 14, 10, 8, 0, 0, time.UTC)},
+		{"*/15 * * * *", time.Date(2025, 3, 14, 10, 15, 0, 0, time.UTC)},
+		{"0 3 * * *", time.Date(2025, 3, 15, 3, 0, 0, 0, time.UTC)},
+		{"@hourly", time.Date(2025, 3, 14, 11, 0, 0, 0, time.UTC)},
+		{"0 9 * * mon-fri", time.Date(2025, 3, 17, 9, 0, 0, 0, time.UTC)},
+		{"0 0 1 jan *", time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)},
+		{"0 0 29 2 *", time.Date(2028, 2, 29, 0, 0, 0, 0, time.UTC)},
+		{"0 12 * * 7", time.Date(2025, 3, 16, 12, 0, 0, 0, time.UTC)},
+		// day of month OR day of week when both are restricted
+		{"0 0 20 * 6", time.Date(2025, 3, 15, 0, 0, 0, 0, time.UTC)},
+		{"5,10-12/2 * * * *", time.Date(2025, 3, 14, 10, 10, 0, 0, time.UTC)},
+	}
+	for _, test := range tests {
+		sched, err := Parse(test.spec)
+		if err != nil {
+			t.Errorf("parse %q: %v", test.spec, err)
+			continue
+		}
+		if got := sched.Next(base); !got.Equal(test.want) {
+			t.Errorf("%q: next = %v, want %v", test.spec, got, test.want)
+		}
+	}
+}
@@ -5,6 +5,7 @@ package migrateutil
 
 import (
 	"database/sql"
+	"errors"
 	"fmt"
 	"io/fs"
 	"log"
@@ -23,6 +24,29 @@ func GetMigrateVersion(m *migrate.Migrate) (uint, bool, error) {
 	return curVersion, dirty, err
 }
 
+// returns the version of the newest migration in migrationFS
+func GetMaxVersion(migrationFS fs.FS, migrationsName string) (uint, error) {
+	fsVar, err := iofs.New(migrationFS, migrationsName)
+	if err != nil {
+		return 0, fmt.Errorf("opening fs: %w", err)
+	}
+	defer fsVar.Close()
+	version, err := fsVar.First()
+	if err != nil {
+		return 0, fmt.Errorf("reading first migration: %w", err)
+	}
+	for {
+		next, err := fsVar.Next(version)
+		if errors.Is(err, fs.ErrNotExist) {
+			return version, nil
+		}
+		if err != nil {
+			return 0, fmt.Errorf("reading migrations: %w", err)
+		}
+		version = next
+	}
+}
+
 func MakeMigrate(storeName string, db *sql.DB, migrationFS fs.FS, migrationsName string) (*migrate.Migrate, error) {
 	fsVar, err := iofs.New(migrationFS, migrationsName)
 	if err != nil {
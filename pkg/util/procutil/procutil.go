@@ -0,0 +1,191 @@
+// Copyright 2025, Command Line Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+// procutil samples the process tree under a block's shell (/proc on linux, gopsutil elsewhere), summarizes
+// two samples into cpu/memory/fd usage, and finds the tcp ports the processes are listening on.
+package procutil
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/wavetermdev/waveterm/pkg/util/shellutil"
+	"github.com/wavetermdev/waveterm/pkg/wavebase"
+	"github.com/wavetermdev/waveterm/pkg/wshrpc"
+)
+
+const MaxChildNames = 10
+
+// GetProcTree returns rootPid and all of its descendants (root first)
+func GetProcTree(rootPid int32) (*wshrpc.ProcTreeData, error) {
+	ts := time.Now().UnixMilli()
+	procs, err := listProcs()
+	if err != nil {
+		return nil, err
+	}
+	tree := buildTree(rootPid, procs)
+	if tree == nil {
+		return nil, fmt.Errorf("process %d not found", rootPid)
+	}
+	for _, proc := range tree {
+		proc.NumFds = countFds(proc.Pid)
+	}
+	return &wshrpc.ProcTreeData{Ts: ts, RootPid: rootPid, Procs: tree}, nil
+}
+
+func buildTree(rootPid int32, procs []*wshrpc.ProcInfo) []*wshrpc.ProcInfo {
+	byPid := make(map[int32]*wshrpc.ProcInfo)
+	children := make(map[int32][]*wshrpc.ProcInfo)
+	for _, proc := range procs {
+		byPid[proc.Pid] = proc
+		if proc.Pid != proc.PPid {
+			children[proc.PPid] = append(children[proc.PPid], proc)
+		}
+	}
+	root := byPid[rootPid]
+	if root == nil {
+		return nil
+	}
+	rtn := []*wshrpc.ProcInfo{root}
+	for idx := 0; idx < len(rtn); idx++ {
+		rtn = append(rtn, children[rtn[idx].Pid]...)
+	}
+	return rtn
+}
+
+// checks if the process was started for blockId (its environment has the block's id, or the swap token the
+// shell was started with, which is how remote shells are started)
+func envMatchesBlock(env []string, blockId string) bool {
+	for _, kv := range env {
+		key, val, ok := strings.Cut(kv, "=")
+		if !ok {
+			continue
+		}
+		switch key {
+		case "WAVETERM_BLOCKID":
+			if val == blockId {
+				return true
+			}
+		case wavebase.WaveSwapTokenVarName:
+			token, err := shellutil.UnpackSwapToken(val)
+			if err == nil && token.RpcContext != nil && token.RpcContext.BlockId == blockId {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// FindBlockRootPid finds the topmost process that was started for blockId.  hintPid (the result of a
+// previous call) is checked first, so the full process list is only scanned once.
+func FindBlockRootPid(blockId string, hintPid int32) (int32, error) {
+	if hintPid > 0 {
+		env, err := procEnv(hintPid)
+		if err == nil && envMatchesBlock(env, blockId) {
+			return hintPid, nil
+		}
+	}
+	procs, err := listProcs()
+	if err != nil {
+		return 0, err
+	}
+	matches := make(map[int32]*wshrpc.ProcInfo)
+	for _, proc := range procs {
+		env, err := procEnv(proc.Pid)
+		if err != nil || !envMatchesBlock(env, blockId) {
+			continue
+		}
+		matches[proc.Pid] = proc
+	}
+	var root *wshrpc.ProcInfo
+	for _, proc := range matches {
+		if matches[proc.PPid] != nil {
+			continue
+		}
+		if root == nil || proc.StartTime < root.StartTime {
+			root = proc
+		}
+	}
+	if root == nil {
+		return 0, fmt.Errorf("no process found for block %s", blockId)
+	}
+	return root.Pid, nil
+}
+
+type childUsage struct {
+	name string
+	cpu  float64
+	rss  int64
+}
+
+// SummarizeProcTree totals a sample of a block's process tree.  cpu usage is computed from the cpu time used since
+// prev, so it is 0 when prev is nil.
+func SummarizeProcTree(blockId string, prev *wshrpc.ProcTreeData, cur *wshrpc.ProcTreeData) *wshrpc.BlockProcStats {
+	rtn := &wshrpc.BlockProcStats{
+		BlockId:  blockId,
+		Ts:       cur.Ts,
+		RootPid:  cur.RootPid,
+		NumProcs: len(cur.Procs),
+	}
+	var elapsedSecs float64
+	prevProcs := make(map[int32]*wshrpc.ProcInfo)
+	if prev != nil && cur.Ts > prev.Ts {
+		elapsedSecs = float64(cur.Ts-prev.Ts) / 1000
+		for _, proc := range prev.Procs {
+			prevProcs[proc.Pid] = proc
+		}
+	}
+	var children []childUsage
+	for idx, proc := range cur.Procs {
+		rtn.Rss += proc.Rss
+		if proc.NumFds > 0 {
+			rtn.NumFds += int(proc.NumFds)
+		}
+		var cpu float64
+		if elapsedSecs > 0 {
+			used := proc.CpuTime
+			if prevProc := prevProcs[proc.Pid]; prevProc != nil && prevProc.StartTime == proc.StartTime {
+				used -= prevProc.CpuTime
+			}
+			cpu = max(used, 0) / elapsedSecs * 100
+		}
+		rtn.Cpu += cpu
+		if idx > 0 {
+			children = append(children, childUsage{name: proc.Name, cpu: cpu, rss: proc.Rss})
+		}
+	}
+	sort.SliceStable(children, func(i, j int) bool {
+		if children[i].cpu != children[j].cpu {
+			return children[i].cpu > children[j].cpu
+		}
+		return children[i].rss > children[j].rss
+	})
+	seen := make(map[string]bool)
+	for _, child := range children {
+		if seen[child.name] || len(rtn.Children) >= MaxChildNames {
+			continue
+		}
+		seen[child.name] = true
+		rtn.Children = append(rtn.Children, child.name)
+	}
+	return rtn
+}
+
+// a server listening on both ipv4 and ipv6 (or with several worker processes) is reported once per port
+func dedupPorts(ports []*wshrpc.ListeningPort) []*wshrpc.ListeningPort {
+	var rtn []*wshrpc.ListeningPort
+	seen := make(map[int]bool)
+	for _, port := range ports {
+		if seen[port.Port] {
+			continue
+		}
+		seen[port.Port] = true
+		rtn = append(rtn, port)
+	}
+	sort.Slice(rtn, func(i, j int) bool {
+		return rtn[i].Port < rtn[j].Port
+	})
+	return rtn
+}
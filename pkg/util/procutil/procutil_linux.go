@@ -0,0 +1,192 @@
+// Copyright 2025, Command Line Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+//go:build linux
+
+package procutil
+
+import (
+	"bytes"
+	"encoding/hex"
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/wavetermdev/waveterm/pkg/wshrpc"
+)
+
+// USER_HZ, the unit of the cpu times in /proc/[pid]/stat (100 on all supported architectures)
+const clockTicks = 100
+
+func listProcs() ([]*wshrpc.ProcInfo, error) {
+	entries, err := os.ReadDir("/proc")
+	if err != nil {
+		return nil, fmt.Errorf("cannot read /proc: %w", err)
+	}
+	pageSize := int64(os.Getpagesize())
+	var rtn []*wshrpc.ProcInfo
+	for _, entry := range entries {
+		if _, err := strconv.Atoi(entry.Name()); err != nil {
+			continue
+		}
+		data, err := os.ReadFile("/proc/" + entry.Name() + "/stat")
+		if err != nil {
+			// exited
+			continue
+		}
+		proc, err := parseProcStat(data, pageSize)
+		if err != nil {
+			continue
+		}
+		rtn = append(rtn, proc)
+	}
+	return rtn, nil
+}
+
+// parses /proc/[pid]/stat, see proc(5).  the command name is in parens and can contain spaces and parens.
+func parseProcStat(data []byte, pageSize int64) (*wshrpc.ProcInfo, error) {
+	str := string(data)
+	openIdx := strings.IndexByte(str, '(')
+	closeIdx := strings.LastIndexByte(str, ')')
+	if openIdx < 0 || closeIdx < openIdx {
+		return nil, fmt.Errorf("invalid stat line")
+	}
+	pid, err := strconv.ParseInt(strings.TrimSpace(str[:openIdx]), 10, 32)
+	if err != nil {
+		return nil, fmt.Errorf("invalid pid: %w", err)
+	}
+	// fields[0] is field 3 (state)
+	fields := strings.Fields(str[closeIdx+1:])
+	if len(fields) < 22 {
+		return nil, fmt.Errorf("invalid stat line, only %d fields", len(fields))
+	}
+	ppid, err := strconv.ParseInt(fields[1], 10, 32)
+	if err != nil {
+		return nil, fmt.Errorf("invalid ppid: %w", err)
+	}
+	utime, _ := strconv.ParseUint(fields[11], 10, 64)
+	stime, _ := strconv.ParseUint(fields[12], 10, 64)
+	startTime, _ := strconv.ParseInt(fields[19], 10, 64)
+	rssPages, _ := strconv.ParseInt(fields[21], 10, 64)
+	return &wshrpc.ProcInfo{
+		Pid:       int32(pid),
+		PPid:      int32(ppid),
+		Name:      str[openIdx+1 : closeIdx],
+		CpuTime:   float64(utime+stime) / clockTicks,
+		Rss:       rssPages * pageSize,
+		StartTime: startTime,
+	}, nil
+}
+
+func procEnv(pid int32) ([]string, error) {
+	data, err := os.ReadFile(fmt.Sprintf("/proc/%d/environ", pid))
+	if err != nil {
+		return nil, err
+	}
+	var rtn []string
+	for _, kv := range bytes.Split(data, []byte{0}) {
+		if len(kv) > 0 {
+			rtn = append(rtn, string(kv))
+		}
+	}
+	return rtn, nil
+}
+
+// returns -1 if the fds can't be read (process owned by another user)
+func countFds(pid int32) int32 {
+	entries, err := os.ReadDir(fmt.Sprintf("/proc/%d/fd", pid))
+	if err != nil {
+		return -1
+	}
+	return int32(len(entries))
+}
+
+const tcpStateListen = "0A"
+
+// GetListeningPorts returns the tcp ports the given processes are listening on.  /proc/net/tcp{,6} maps the
+// listening sockets to inodes, and /proc/[pid]/fd has the socket inodes each process has open.
+func GetListeningPorts(procs []*wshrpc.ProcInfo) ([]*wshrpc.ListeningPort, error) {
+	listeners := make(map[string]*wshrpc.ListeningPort) // by socket inode
+	for _, fileName := range []string{"/proc/net/tcp", "/proc/net/tcp6"} {
+		data, err := os.ReadFile(fileName)
+		if err != nil {
+			if fileName == "/proc/net/tcp6" {
+				// no ipv6
+				continue
+			}
+			return nil, fmt.Errorf("cannot read %s: %w", fileName, err)
+		}
+		for inode, port := range parseProcNetTcp(data) {
+			listeners[inode] = port
+		}
+	}
+	if len(listeners) == 0 {
+		return nil, nil
+	}
+	var rtn []*wshrpc.ListeningPort
+	for _, proc := range procs {
+		fdDir := fmt.Sprintf("/proc/%d/fd", proc.Pid)
+		entries, err := os.ReadDir(fdDir)
+		if err != nil {
+			continue
+		}
+		for _, entry := range entries {
+			link, err := os.Readlink(fdDir + "/" + entry.Name())
+			if err != nil {
+				continue
+			}
+			inode, ok := strings.CutPrefix(link, "socket:[")
+			if !ok {
+				continue
+			}
+			listener := listeners[strings.TrimSuffix(inode, "]")]
+			if listener == nil {
+				continue
+			}
+			rtn = append(rtn, &wshrpc.ListeningPort{Port: listener.Port, Address: listener.Address, Pid: proc.Pid, Name: proc.Name})
+		}
+	}
+	return dedupPorts(rtn), nil
+}
+
+// parses /proc/net/tcp or /proc/net/tcp6, returns the listening sockets by inode
+func parseProcNetTcp(data []byte) map[string]*wshrpc.ListeningPort {
+	rtn := make(map[string]*wshrpc.ListeningPort)
+	lines := strings.Split(string(data), "\n")
+	for _, line := range lines[min(1, len(lines)):] {
+		// sl local_address rem_address st tx_queue:rx_queue tr:tm->when retrnsmt uid timeout inode
+		fields := strings.Fields(line)
+		if len(fields) < 10 || fields[3] != tcpStateListen {
+			continue
+		}
+		addrHex, portHex, ok := strings.Cut(fields[1], ":")
+		if !ok {
+			continue
+		}
+		port, err := strconv.ParseUint(portHex, 16, 16)
+		if err != nil {
+			continue
+		}
+		addr, err := parseProcNetAddr(addrHex)
+		if err != nil {
+			continue
+		}
+		rtn[fields[9]] = &wshrpc.ListeningPort{Port: int(port), Address: addr}
+	}
+	return rtn
+}
+
+// addresses are in hex, as 32-bit words in host (little endian) byte order
+func parseProcNetAddr(addrHex string) (string, error) {
+	raw, err := hex.DecodeString(addrHex)
+	if err != nil || (len(raw) != net.IPv4len && len(raw) != net.IPv6len) {
+		return "", fmt.Errorf("invalid address %q", addrHex)
+	}
+	ip := make(net.IP, len(raw))
+	for i := 0; i < len(raw); i += 4 {
+		ip[i], ip[i+1], ip[i+2], ip[i+3] = raw[i+3], raw[i+2], raw[i+1], raw[i]
+	}
+	return ip.String(), nil
+}
@@ -0,0 +1,53 @@
+// Copyright 2025, Command Line Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+//go:build linux
+
+package procutil
+
+import "testing"
+
+func TestParseProcStat(t *testing.T) {
+	line := "4321 (my (weird) cmd) S 1234 4321 4321 34816 4321 4194304 1158 0 0 0 250 50 0 0 20 0 1 0 98765 10223616 512 18446744073709551615 0 0 0 0 0 0 0 0 0 0 0 0 17 3 0 0 0 0 0\n"
+	proc, err := parseProcStat([]byte(line), 4096)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if proc.Pid != 4321 || proc.PPid != 1234 || proc.Name != "my (weird) cmd" {
+		t.Errorf("bad proc: %+v", proc)
+	}
+	if proc.CpuTime != 3.0 || proc.StartTime != 98765 || proc.Rss != 512*4096 {
+		t.Errorf("bad usage: %+v", proc)
+	}
+	if _, err := parseProcStat([]byte("12 (short) S 1"), 4096); err == nil {
+		t.Errorf("expected error for a short line")
+	}
+}
+
+func TestParseProcNetTcp(t *testing.T) {
+	data := `  sl  local_address rem_address   st tx_queue rx_queue tr tm->when retrnsmt   uid  timeout inode
+   0: 0100007F:1F90 00000000:0000 0A 00000000:00000000 00:00000000 00000000  1000        0 51234 1 0000000000000000 100 0 0 10 0
+   1: 00000000:0016 00000000:0000 0A 00000000:00000000 00:00000000 00000000     0        0 1111 1 0000000000000000 100 0 0 10 0
+   2: 0100007F:A1B2 0100007F:1F90 01 00000000:00000000 00:00000000 00000000  1000        0 51299 1 0000000000000000 20 4 30 10 -1
+`
+	ports := parseProcNetTcp([]byte(data))
+	if len(ports) != 2 {
+		t.Fatalf("expected 2 listening sockets, got %d", len(ports))
+	}
+	if port := ports["51234"]; port == nil || port.Port != 8080 || port.Address != "127.0.0.1" {
+		t.Errorf("bad port: %+v", port)
+	}
+	if port := ports["1111"]; port == nil || port.Port != 22 || port.Address != "0.0.0.0" {
+		t.Errorf("bad port: %+v", port)
+	}
+}
+
+func TestParseProcNetAddr(t *testing.T) {
+	addr, err := parseProcNetAddr("00000000000000000000000001000000")
+	if err != nil || addr != "::1" {
+		t.Errorf("expected ::1, got %q %v", addr, err)
+	}
+	if _, err := parseProcNetAddr("0100"); err == nil {
+		t.Errorf("expected error for a short address")
+	}
+}
@@ -0,0 +1,79 @@
+// Copyright 2025, Command Line Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+//go:build !linux
+
+package procutil
+
+import (
+	"fmt"
+
+	"github.com/shirou/gopsutil/v4/net"
+	"github.com/shirou/gopsutil/v4/process"
+	"github.com/wavetermdev/waveterm/pkg/wshrpc"
+)
+
+func listProcs() ([]*wshrpc.ProcInfo, error) {
+	procs, err := process.Processes()
+	if err != nil {
+		return nil, fmt.Errorf("cannot list processes: %w", err)
+	}
+	var rtn []*wshrpc.ProcInfo
+	for _, proc := range procs {
+		ppid, err := proc.Ppid()
+		if err != nil {
+			// exited
+			continue
+		}
+		info := &wshrpc.ProcInfo{Pid: proc.Pid, PPid: ppid}
+		info.Name, _ = proc.Name()
+		if times, err := proc.Times(); err == nil {
+			info.CpuTime = times.User + times.System
+		}
+		if memInfo, err := proc.MemoryInfo(); err == nil {
+			info.Rss = int64(memInfo.RSS)
+		}
+		info.StartTime, _ = proc.CreateTime()
+		rtn = append(rtn, info)
+	}
+	return rtn, nil
+}
+
+func procEnv(pid int32) ([]string, error) {
+	proc, err := process.NewProcess(pid)
+	if err != nil {
+		return nil, err
+	}
+	return proc.Environ()
+}
+
+// returns -1 if the fds can't be counted
+func countFds(pid int32) int32 {
+	proc, err := process.NewProcess(pid)
+	if err != nil {
+		return -1
+	}
+	numFds, err := proc.NumFDs()
+	if err != nil {
+		return -1
+	}
+	return numFds
+}
+
+// GetListeningPorts returns the tcp ports the given processes are listening on
+func GetListeningPorts(procs []*wshrpc.ProcInfo) ([]*wshrpc.ListeningPort, error) {
+	var rtn []*wshrpc.ListeningPort
+	for _, proc := range procs {
+		conns, err := net.ConnectionsPid("tcp", proc.Pid)
+		if err != nil {
+			continue
+		}
+		for _, conn := range conns {
+			if conn.Status != "LISTEN" {
+				continue
+			}
+			rtn = append(rtn, &wshrpc.ListeningPort{Port: int(conn.Laddr.Port), Address: conn.Laddr.IP, Pid: proc.Pid, Name: proc.Name})
+		}
+	}
+	return dedupPorts(rtn), nil
+}
@@ -0,0 +1,87 @@
+// Copyright 2025, Command Line Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package procutil
+
+import (
+	"os"
+	"reflect"
+	"testing"
+
+	"github.com/wavetermdev/waveterm/pkg/util/shellutil"
+	"github.com/wavetermdev/waveterm/pkg/wshrpc"
+)
+
+func TestBuildTree(t *testing.T) {
+	procs := []*wshrpc.ProcInfo{
+		{Pid: 1, PPid: 0, Name: "init"},
+		{Pid: 10, PPid: 1, Name: "bash"},
+		{Pid: 11, PPid: 10, Name: "make"},
+		{Pid: 12, PPid: 11, Name: "cc"},
+		{Pid: 13, PPid: 1, Name: "other"},
+		{Pid: 14, PPid: 10, Name: "vim"},
+	}
+	tree := buildTree(10, procs)
+	var names []string
+	for _, proc := range tree {
+		names = append(names, proc.Name)
+	}
+	if !reflect.DeepEqual(names, []string{"bash", "make", "vim", "cc"}) {
+		t.Errorf("bad tree: %v", names)
+	}
+	if buildTree(99, procs) != nil {
+		t.Errorf("expected nil tree for a missing root")
+	}
+}
+
+func TestSummarizeProcTree(t *testing.T) {
+	prev := &wshrpc.ProcTreeData{Ts: 1000, RootPid: 10, Procs: []*wshrpc.ProcInfo{
+		{Pid: 10, Name: "bash", CpuTime: 1.0, StartTime: 5},
+		{Pid: 11, Name: "node", CpuTime: 10.0, StartTime: 6},
+		{Pid: 12, Name: "sleep", CpuTime: 3.0, StartTime: 7},
+	}}
+	cur := &wshrpc.ProcTreeData{Ts: 3000, RootPid: 10, Procs: []*wshrpc.ProcInfo{
+		{Pid: 10, Name: "bash", CpuTime: 1.0, Rss: 1000, NumFds: 4, StartTime: 5},
+		{Pid: 11, Name: "node", CpuTime: 12.0, Rss: 5000, NumFds: 20, StartTime: 6},
+		{Pid: 12, Name: "cc", CpuTime: 0.5, Rss: 2000, NumFds: -1, StartTime: 9}, // pid reused
+	}}
+	stats := SummarizeProcTree("block1", prev, cur)
+	// node used 2s over 2s (100%), cc 0.5s since it started (25%)
+	if stats.Cpu != 125 || stats.Rss != 8000 || stats.NumFds != 24 || stats.NumProcs != 3 {
+		t.Errorf("bad stats: %+v", stats)
+	}
+	if !reflect.DeepEqual(stats.Children, []string{"node", "cc"}) {
+		t.Errorf("bad children: %v", stats.Children)
+	}
+	first := SummarizeProcTree("block1", nil, cur)
+	if first.Cpu != 0 || first.Rss != 8000 {
+		t.Errorf("bad first sample: %+v", first)
+	}
+}
+
+func TestEnvMatchesBlock(t *testing.T) {
+	token := &shellutil.UnpackedTokenType{Token: "abc", RpcContext: &wshrpc.RpcContext{BlockId: "block1"}}
+	packed, err := token.Pack()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !envMatchesBlock([]string{"HOME=/root", "WAVETERM_SWAPTOKEN=" + packed}, "block1") {
+		t.Errorf("expected swap token to match")
+	}
+	if !envMatchesBlock([]string{"WAVETERM_BLOCKID=block1"}, "block1") {
+		t.Errorf("expected block id to match")
+	}
+	if envMatchesBlock([]string{"WAVETERM_BLOCKID=block2", "WAVETERM_SWAPTOKEN=" + packed}, "block3") {
+		t.Errorf("unexpected match")
+	}
+}
+
+func TestGetProcTreeSelf(t *testing.T) {
+	tree, err := GetProcTree(int32(os.Getpid()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tree.Procs[0].Pid != int32(os.Getpid()) || tree.Procs[0].Rss <= 0 {
+		t.Errorf("bad root proc: %+v", tree.Procs[0])
+	}
+}
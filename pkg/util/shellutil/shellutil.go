@@ -73,6 +73,42 @@ unset WAVETERM_WSHBINDIR
 if [[ -n ${_comps+x} ]]; then
   source <(wsh completion zsh)
 fi
+
+# shell integration: OSC 133 command markers and OSC 7 cwd (set WAVETERM_SHELLINTEGRATION=0 to disable)
+if [[ "$WAVETERM_SHELLINTEGRATION" != "0" && -z "$_waveterm_si_installed" ]]; then
+  _waveterm_si_installed=1
+  _waveterm_si_incmd=""
+  _waveterm_si_urlencode() {
+    emulate -L zsh
+    setopt no_multibyte
+    local str="$1" out="" c i
+    for (( i = 1; i <= $#str; i++ )); do
+      c="$str[i]"
+      case "$c" in
+        [a-zA-Z0-9/._~-]) out+="$c" ;;
+        *) printf -v c '%%%02X' "'$c"; out+="$c" ;;
+      esac
+    done
+    print -rn -- "$out"
+  }
+  _waveterm_si_preexec() {
+    _waveterm_si_incmd=1
+    printf '\033]133;C;cmdline_url=%s\007' "$(_waveterm_si_urlencode "$1")"
+  }
+  _waveterm_si_precmd() {
+    local ret=$?
+    if [[ -n "$_waveterm_si_incmd" ]]; then
+      printf '\033]133;D;%s\007' "$ret"
+      _waveterm_si_incmd=""
+    fi
+    printf '\033]7;file://%s%s\007' "$HOST" "$(_waveterm_si_urlencode "$PWD")"
+    printf '\033]133;A\007'
+  }
+  typeset -ga preexec_functions precmd_functions
+  preexec_functions+=(_waveterm_si_preexec)
+  # must run first to see the exit code of the command
+  precmd_functions=(_waveterm_si_precmd $precmd_functions)
+fi
 `
 
 	ZshStartup_Zlogin = `
@@ -133,6 +169,80 @@ if type _init_completion &>/dev/null; then
   source <(wsh completion bash)
 fi
 
+
+# shell integration: OSC 133 command markers and OSC 7 cwd (set WAVETERM_SHELLINTEGRATION=0 to disable)
+# a DEBUG trap that is already set (e.g. bash-preexec) is run after ours, with the status and $_ of the command
+if [ "$WAVETERM_SHELLINTEGRATION" != "0" ] && [ -z "$_waveterm_si_installed" ]; then
+    _waveterm_si_installed=1
+    _waveterm_si_atprompt=""
+    _waveterm_si_incmd=""
+    _waveterm_si_lasthistnum=""
+    _waveterm_si_histre='^ *([0-9]+)[* ] *(.*)$'
+    _waveterm_si_urlencode() {
+        local LC_ALL=C str="$1" out="" c i
+        for (( i = 0; i < ${#str}; i++ )); do
+            c="${str:i:1}"
+            case "$c" in
+                [a-zA-Z0-9/._~-]) out+="$c" ;;
+                *) printf -v c '%%%02X' "'$c"; out+="$c" ;;
+            esac
+        done
+        printf '%s' "$out"
+    }
+    _waveterm_si_preexec() {
+        # ignore commands run from PROMPT_COMMAND and completion functions
+        [ -n "$COMP_LINE" ] && return
+        [ -z "$_waveterm_si_atprompt" ] && return
+        _waveterm_si_atprompt=""
+        _waveterm_si_incmd=1
+        local hist histnum cmd
+        hist="$(HISTTIMEFORMAT= builtin history 1)"
+        if [[ "$hist" =~ $_waveterm_si_histre ]]; then
+            histnum="${BASH_REMATCH[1]}"
+            cmd="${BASH_REMATCH[2]}"
+        fi
+        # the command was not added to the history (e.g. HISTCONTROL=ignorespace)
+        if [ -z "$cmd" ] || [ "$histnum" = "$_waveterm_si_lasthistnum" ]; then
+            cmd="$BASH_COMMAND"
+        fi
+        _waveterm_si_lasthistnum="$histnum"
+        printf '\033]133;C;cmdline_url=%s\007' "$(_waveterm_si_urlencode "$cmd")"
+    }
+    _waveterm_si_savestatus() {
+        _waveterm_si_status=$?
+    }
+    _waveterm_si_precmd() {
+        if [ -n "$_waveterm_si_incmd" ]; then
+            printf '\033]133;D;%s\007' "$_waveterm_si_status"
+            _waveterm_si_incmd=""
+        fi
+        printf '\033]7;file://%s%s\007' "$HOSTNAME" "$(_waveterm_si_urlencode "$PWD")"
+        printf '\033]133;A\007'
+        _waveterm_si_atprompt=1
+    }
+    _waveterm_si_pc="$PROMPT_COMMAND"
+    while [[ "$_waveterm_si_pc" == *[\;\ ] ]]; do
+        _waveterm_si_pc="${_waveterm_si_pc%?}"
+    done
+    PROMPT_COMMAND="_waveterm_si_savestatus${_waveterm_si_pc:+; $_waveterm_si_pc}; _waveterm_si_precmd"
+    unset _waveterm_si_pc
+    _waveterm_si_trapcmd() {
+        eval "set -- $1"
+        printf '%s' "$3"
+    }
+    _waveterm_si_prevtrap="$(_waveterm_si_trapcmd "$(trap -p DEBUG)")"
+    _waveterm_si_setstatus() {
+        return "$1"
+    }
+    _waveterm_si_debug() {
+        _waveterm_si_preexec
+        [ -z "$_waveterm_si_prevtrap" ] && return
+        # sets $? and $_ back for the previous trap
+        _waveterm_si_setstatus "$1" "$2"
+        eval "$_waveterm_si_prevtrap"
+    }
+    trap '_waveterm_si_debug "$?" "$_"' DEBUG
+fi
 `
 
 	FishStartup_Wavefish = `
@@ -146,6 +256,21 @@ set -e WAVETERM_SWAPTOKEN
 
 # Load Wave completions
 wsh completion fish | source
+
+# shell integration: OSC 133 command markers and OSC 7 cwd (set WAVETERM_SHELLINTEGRATION=0 to disable)
+if test "$WAVETERM_SHELLINTEGRATION" != "0"; and not set -q _waveterm_si_installed
+    set -g _waveterm_si_installed 1
+    function _waveterm_si_preexec --on-event fish_preexec
+        printf '\e]133;C;cmdline_url=%s\a' (string escape --style=url -- $argv[1])
+    end
+    function _waveterm_si_postexec --on-event fish_postexec
+        printf '\e]133;D;%s\a' $status
+    end
+    function _waveterm_si_prompt --on-event fish_prompt
+        printf '\e]7;file://%s%s\a' $hostname (string escape --style=url -- $PWD)
+        printf '\e]133;A\a'
+    end
+end
 `
 
 	PwshStartup_wavepwsh = `
@@ -162,6 +287,46 @@ Remove-Item Env:WAVETERM_SWAPTOKEN
 
 # Load Wave completions
 wsh completion powershell | Out-String | Invoke-Expression
+
+# shell integration: OSC 133 command markers and OSC 7 cwd (set WAVETERM_SHELLINTEGRATION=0 to disable)
+if ($env:WAVETERM_SHELLINTEGRATION -ne "0" -and -not $global:_waveterm_si_installed) {
+    $global:_waveterm_si_installed = $true
+    $global:_waveterm_si_incmd = $false
+    $global:_waveterm_si_origprompt = $function:prompt
+    function global:prompt {
+        $ok = $?
+        $code = $global:LASTEXITCODE
+        $esc = [char]27
+        $bel = [char]7
+        $out = ""
+        if ($global:_waveterm_si_incmd) {
+            if ($ok) { $exit = 0 } elseif ($code) { $exit = $code } else { $exit = 1 }
+            $out += "$esc]133;D;$exit$bel"
+            $global:_waveterm_si_incmd = $false
+        }
+        $loc = $executionContext.SessionState.Path.CurrentLocation
+        if ($loc.Provider.Name -eq "FileSystem") {
+            $p = $loc.ProviderPath -replace '\\', '/'
+            if (-not $p.StartsWith("/")) { $p = "/" + $p }
+            $out += "$esc]7;file://$([System.Net.Dns]::GetHostName())$([uri]::EscapeUriString($p))$bel"
+        }
+        $out += "$esc]133;A$bel"
+        $out + (& $global:_waveterm_si_origprompt)
+        $global:LASTEXITCODE = $code
+    }
+    # the command line is taken from PSConsoleHostReadLine (PSReadLine), so the Enter key handler stays the user's
+    if (Test-Path Function:\PSConsoleHostReadLine) {
+        $global:_waveterm_si_origreadline = $function:PSConsoleHostReadLine
+        function global:PSConsoleHostReadLine {
+            $line = & $global:_waveterm_si_origreadline
+            if ($line -and $line.Trim() -ne "") {
+                $global:_waveterm_si_incmd = $true
+                [Console]::Write([char]27 + "]133;C;cmdline_url=" + [uri]::EscapeDataString($line) + [char]7)
+            }
+            $line
+        }
+    }
+}
 `
 )
 
@@ -41,6 +41,8 @@ const (
 	BlockFile_Term  = "term"            // used for main pty output
 	BlockFile_Cache = "cache:term:full" // for cached block
 	BlockFile_VDom  = "vdom"            // used for alt html layout
+	BlockFile_Cast  = "cast"            // asciicast recording of the pty output
+	BlockFile_Marks = "marks"           // term:triggers marks (json lines)
 	BlockFile_Env   = "env"
 )
 
@@ -163,6 +165,22 @@ func GetDomainSocketName() string {
 	return filepath.Join(GetWaveDataDir(), DomainSocketBaseName)
 }
 
+var serverPorts []int
+
+// AddServerPort records a tcp port wavesrv listens on (on 127.0.0.1)
+func AddServerPort(port int) {
+	baseLock.Lock()
+	defer baseLock.Unlock()
+	serverPorts = append(serverPorts, port)
+}
+
+// GetServerPorts returns the tcp ports wavesrv listens on (sandboxed shells can't connect to them)
+func GetServerPorts() []int {
+	baseLock.Lock()
+	defer baseLock.Unlock()
+	return append([]int(nil), serverPorts...)
+}
+
 func EnsureWaveDataDir() error {
 	return CacheEnsureDir(GetWaveDataDir(), "wavehome", 0700, "wave home directory")
 }
@@ -51,6 +51,13 @@ const (
 	MetaKey_CmdArgs                          = "cmd:args"
 	MetaKey_CmdShell                         = "cmd:shell"
 	MetaKey_CmdAllowConnChange               = "cmd:allowconnchange"
+	MetaKey_CmdRestart                       = "cmd:restart"
+	MetaKey_CmdRestartDelay                  = "cmd:restartdelay"
+	MetaKey_CmdRestartMaxDelay               = "cmd:restartmaxdelay"
+	MetaKey_CmdRestartMax                    = "cmd:restartmax"
+	MetaKey_CmdRestartWindow                 = "cmd:restartwindow"
+	MetaKey_CmdSchedule                      = "cmd:schedule"
+	MetaKey_CmdSchedulePaused                = "cmd:schedulepaused"
 	MetaKey_CmdEnv                           = "cmd:env"
 	MetaKey_CmdCwd                           = "cmd:cwd"
 	MetaKey_CmdInitScript                    = "cmd:initscript"
@@ -103,6 +110,21 @@ const (
 	MetaKey_TermTransparency                 = "term:transparency"
 	MetaKey_TermAllowBracketedPaste          = "term:allowbracketedpaste"
 	MetaKey_TermConnDebug                    = "term:conndebug"
+	MetaKey_TermRecord                       = "term:record"
+	MetaKey_TermRecordInput                  = "term:recordinput"
+	MetaKey_TermRecordPath                   = "term:recordpath"
+	MetaKey_TermPersistent                   = "term:persistent"
+	MetaKey_TermTriggers                     = "term:triggers"
+	MetaKey_TermDetectPorts                  = "term:detectports"
+	MetaKey_TermOpenPorts                    = "term:openports"
+	MetaKey_TermInputGroup                   = "term:inputgroup"
+	MetaKey_TermInputGroupConfirm            = "term:inputgroupconfirm"
+
+	MetaKey_ShellClear                       = "shell:*"
+	MetaKey_ShellSandbox                     = "shell:sandbox"
+	MetaKey_ShellSandboxWritable             = "shell:sandboxwritable"
+	MetaKey_ShellSandboxReadOnly             = "shell:sandboxreadonly"
+	MetaKey_ShellSandboxNoNetwork            = "shell:sandboxnonet"
 
 	MetaKey_WebZoom                          = "web:zoom"
 	MetaKey_WebHideNav                       = "web:hidenav"
@@ -50,6 +50,13 @@ type MetaTSType struct {
 	CmdArgs             []string `json:"cmd:args,omitempty"`  // args for cmd (only if cmd:shell is false)
 	CmdShell            bool     `json:"cmd:shell,omitempty"` // shell expansion for cmd+args (defaults to true)
 	CmdAllowConnChange  bool     `json:"cmd:allowconnchange,omitempty"`
+	CmdRestart          string   `json:"cmd:restart,omitempty"`         // no, on-failure, always
+	CmdRestartDelay     float64  `json:"cmd:restartdelay,omitempty"`    // initial backoff in ms (default 1000)
+	CmdRestartMaxDelay  float64  `json:"cmd:restartmaxdelay,omitempty"` // max backoff in ms (default 60000)
+	CmdRestartMax       int      `json:"cmd:restartmax,omitempty"`      // max consecutive quick restarts before giving up (default 10, 0 for no limit)
+	CmdRestartWindow    float64  `json:"cmd:restartwindow,omitempty"`   // a run longer than this (ms) resets the backoff (default 60000)
+	CmdSchedule         string   `json:"cmd:schedule,omitempty"`        // cron expression or interval, reruns the cmd on a schedule
+	CmdSchedulePaused   bool     `json:"cmd:schedulepaused,omitempty"`
 
 	// these can be nested under "[conn]"
 	CmdEnv            map[string]string `json:"cmd:env,omitempty"`
@@ -105,7 +112,23 @@ type MetaTSType struct {
 	TermVDomToolbarBlockId  string   `json:"term:vdomtoolbarblockid,omitempty"`
 	TermTransparency        *float64 `json:"term:transparency,omitempty"` // default 0.5
 	TermAllowBracketedPaste *bool    `json:"term:allowbracketedpaste,omitempty"`
-	TermConnDebug           string   `json:"term:conndebug,omitempty"` // null, info, debug
+	TermConnDebug           string   `json:"term:conndebug,omitempty"`   // null, info, debug
+	TermRecord              bool     `json:"term:record,omitempty"`      // record output as an asciicast v2 file
+	TermRecordInput         bool     `json:"term:recordinput,omitempty"` // also record input events
+	TermRecordPath          string   `json:"term:recordpath,omitempty"`  // local path for the recording (defaults to the block's "cast" file)
+	TermPersistent          *bool    `json:"term:persistent,omitempty"`  // matches settings
+
+	TermTriggers          []*TermTrigger `json:"term:triggers,omitempty"`          // also read from workspace meta
+	TermDetectPorts       *bool          `json:"term:detectports,omitempty"`       // matches settings
+	TermOpenPorts         *bool          `json:"term:openports,omitempty"`         // matches settings
+	TermInputGroup        string         `json:"term:inputgroup,omitempty"`        // input typed in a block is sent to all blocks in its group
+	TermInputGroupConfirm bool           `json:"term:inputgroupconfirm,omitempty"` // confirm before sending destructive keys (ctrl-c, rm -rf, ...) to the group
+
+	ShellClear            bool     `json:"shell:*,omitempty"`
+	ShellSandbox          bool     `json:"shell:sandbox,omitempty"`         // run the local shell in a sandbox (linux only)
+	ShellSandboxWritable  []string `json:"shell:sandboxwritable,omitempty"` // writable paths (defaults to cmd:cwd)
+	ShellSandboxReadOnly  []string `json:"shell:sandboxreadonly,omitempty"` // read-only paths in the (hidden) home dir
+	ShellSandboxNoNetwork bool     `json:"shell:sandboxnonet,omitempty"`    // no network access (only loopback)
 
 	WebZoom      float64 `json:"web:zoom,omitempty"`
 	WebHideNav   *bool   `json:"web:hidenav,omitempty"`
@@ -123,6 +146,24 @@ type MetaTSType struct {
 	Count int `json:"count,omitempty"` // temp for cpu plot. will remove later
 }
 
+const (
+	TermTriggerAction_Notify = "notify"
+	TermTriggerAction_Event  = "event"
+	TermTriggerAction_Mark   = "mark"
+	TermTriggerAction_Input  = "input"
+)
+
+// TermTrigger fires an action when its pattern matches a line of terminal output (escape sequences removed)
+type TermTrigger struct {
+	Name     string  `json:"name,omitempty"`
+	Pattern  string  `json:"pattern"`            // go regexp
+	Action   string  `json:"action"`             // notify, event, mark, input
+	Message  string  `json:"message,omitempty"`  // notification body (defaults to the matching line), $1 etc. are expanded
+	Input    string  `json:"input,omitempty"`    // text sent to the terminal for the input action, $1 etc. are expanded
+	Once     bool    `json:"once,omitempty"`     // fire at most once per process
+	Cooldown float64 `json:"cooldown,omitempty"` // minimum ms between firings
+}
+
 type MetaDataDecl struct {
 	Key        string   `json:"key"`
 	Desc       string   `json:"desc,omitempty"`
@@ -36,6 +36,10 @@ const (
 	ConfigKey_TermCopyOnSelect               = "term:copyonselect"
 	ConfigKey_TermTransparency               = "term:transparency"
 	ConfigKey_TermAllowBracketedPaste        = "term:allowbracketedpaste"
+	ConfigKey_TermPersistent                 = "term:persistent"
+	ConfigKey_TermDetectPorts                = "term:detectports"
+	ConfigKey_TermOpenPorts                  = "term:openports"
+	ConfigKey_TermCompress                   = "term:compress"
 
 	ConfigKey_EditorMinimapEnabled           = "editor:minimapenabled"
 	ConfigKey_EditorStickyScrollEnabled      = "editor:stickyscrollenabled"
@@ -92,5 +96,25 @@ const (
 	ConfigKey_ConnClear                      = "conn:*"
 	ConfigKey_ConnAskBeforeWshInstall        = "conn:askbeforewshinstall"
 	ConfigKey_ConnWshEnabled                 = "conn:wshenabled"
+
+	ConfigKey_DbClear                        = "db:*"
+	ConfigKey_DbEncrypt                      = "db:encrypt"
+	ConfigKey_DbKeySource                    = "db:keysource"
+
+	ConfigKey_StorageClear                   = "storage:*"
+	ConfigKey_StorageMaxBlockMb              = "storage:maxblockmb"
+	ConfigKey_StorageMaxTermMb               = "storage:maxtermmb"
+	ConfigKey_StorageMaxTotalMb              = "storage:maxtotalmb"
+
+	ConfigKey_TrashClear                     = "trash:*"
+	ConfigKey_TrashDisabled                  = "trash:disabled"
+	ConfigKey_TrashRetentionDays             = "trash:retentiondays"
+	ConfigKey_TrashMaxEntries                = "trash:maxentries"
+
+	ConfigKey_EventJournalClear              = "eventjournal:*"
+	ConfigKey_EventJournalEnabled            = "eventjournal:enabled"
+	ConfigKey_EventJournalEvents             = "eventjournal:events"
+	ConfigKey_EventJournalRetentionDays      = "eventjournal:retentiondays"
+	ConfigKey_EventJournalMaxEntries         = "eventjournal:maxentries"
 )
 
@@ -82,6 +82,10 @@ type SettingsType struct {
 	TermCopyOnSelect        *bool    `json:"term:copyonselect,omitempty"`
 	TermTransparency        *float64 `json:"term:transparency,omitempty"`
 	TermAllowBracketedPaste *bool    `json:"term:allowbracketedpaste,omitempty"`
+	TermPersistent          *bool    `json:"term:persistent,omitempty"`
+	TermDetectPorts         *bool    `json:"term:detectports,omitempty"`
+	TermOpenPorts           bool     `json:"term:openports,omitempty"`
+	TermCompress            *bool    `json:"term:compress,omitempty"`
 
 	EditorMinimapEnabled      bool    `json:"editor:minimapenabled,omitempty"`
 	EditorStickyScrollEnabled bool    `json:"editor:stickyscrollenabled,omitempty"`
@@ -138,6 +142,26 @@ type SettingsType struct {
 	ConnClear               bool  `json:"conn:*,omitempty"`
 	ConnAskBeforeWshInstall *bool `json:"conn:askbeforewshinstall,omitempty"`
 	ConnWshEnabled          bool  `json:"conn:wshenabled,omitempty"`
+
+	DbClear     bool   `json:"db:*,omitempty"`
+	DbEncrypt   bool   `json:"db:encrypt,omitempty"`
+	DbKeySource string `json:"db:keysource,omitempty"`
+
+	StorageClear      bool `json:"storage:*,omitempty"`
+	StorageMaxBlockMb int  `json:"storage:maxblockmb,omitempty"`
+	StorageMaxTermMb  int  `json:"storage:maxtermmb,omitempty"`
+	StorageMaxTotalMb int  `json:"storage:maxtotalmb,omitempty"`
+
+	TrashClear         bool `json:"trash:*,omitempty"`
+	TrashDisabled      bool `json:"trash:disabled,omitempty"`
+	TrashRetentionDays int  `json:"trash:retentiondays,omitempty"`
+	TrashMaxEntries    int  `json:"trash:maxentries,omitempty"`
+
+	EventJournalClear         bool     `json:"eventjournal:*,omitempty"`
+	EventJournalEnabled       bool     `json:"eventjournal:enabled,omitempty"`
+	EventJournalEvents        []string `json:"eventjournal:events,omitempty"`
+	EventJournalRetentionDays int      `json:"eventjournal:retentiondays,omitempty"`
+	EventJournalMaxEntries    int      `json:"eventjournal:maxentries,omitempty"`
 }
 
 type ConfigError struct {
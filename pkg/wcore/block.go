@@ -51,7 +51,10 @@ func createSubBlockObj(ctx context.Context, parentBlockId string, blockDef *wave
 		}
 		wstore.DBInsert(tx.Context(), blockData)
 		parentBlock.SubBlockIds = append(parentBlock.SubBlockIds, blockId)
-		wstore.DBUpdate(tx.Context(), parentBlock)
+		err := wstore.DBUpdate(tx.Context(), parentBlock)
+		if err != nil {
+			return nil, fmt.Errorf("error updating parent block: %w", err)
+		}
 		return blockData, nil
 	})
 }
@@ -132,7 +135,10 @@ func createBlockObj(ctx context.Context, tabId string, blockDef *waveobj.BlockDe
 		}
 		wstore.DBInsert(tx.Context(), blockData)
 		tab.BlockIds = append(tab.BlockIds, blockId)
-		wstore.DBUpdate(tx.Context(), tab)
+		err := wstore.DBUpdate(tx.Context(), tab)
+		if err != nil {
+			return nil, fmt.Errorf("error updating tab: %w", err)
+		}
 		return blockData, nil
 	})
 }
@@ -141,7 +147,13 @@ func createBlockObj(ctx context.Context, tabId string, blockDef *waveobj.BlockDe
 // Also deletes LayoutState.
 // recursive: if true, will recursively close parent tab, window, workspace, if they are empty.
 // Returns new active tab id, error.
+// The block (with its sub-blocks and files) is moved to the trash unless trash:disabled is set.
 func DeleteBlock(ctx context.Context, blockId string, recursive bool) error {
+	return deleteBlock(ctx, blockId, recursive, trashEnabled())
+}
+
+// trash is false for blocks that are deleted with their tab or parent block (they are trashed with it)
+func deleteBlock(ctx context.Context, blockId string, recursive bool, trash bool) error {
 	block, err := wstore.DBMustGet[*waveobj.Block](ctx, blockId)
 	if err != nil {
 		return fmt.Errorf("error getting block: %w", err)
@@ -149,9 +161,16 @@ func DeleteBlock(ctx context.Context, blockId string, recursive bool) error {
 	if block == nil {
 		return nil
 	}
+	if trash {
+		// the block is not deleted if it cannot be restored
+		err := trashBlock(ctx, block)
+		if err != nil {
+			return fmt.Errorf("error moving block %s to trash (set trash:disabled to close it without the trash): %w", blockId, err)
+		}
+	}
 	if len(block.SubBlockIds) > 0 {
 		for _, subBlockId := range block.SubBlockIds {
-			err := DeleteBlock(ctx, subBlockId, recursive)
+			err := deleteBlock(ctx, subBlockId, recursive, false)
 			if err != nil {
 				return fmt.Errorf("error deleting subblock %s: %w", subBlockId, err)
 			}
@@ -202,14 +221,20 @@ func deleteBlockObj(ctx context.Context, blockId string) (int, error) {
 				tab, _ := wstore.DBGet[*waveobj.Tab](tx.Context(), parentORef.OID)
 				if tab != nil {
 					tab.BlockIds = utilfn.RemoveElemFromSlice(tab.BlockIds, blockId)
-					wstore.DBUpdate(tx.Context(), tab)
+					err = wstore.DBUpdate(tx.Context(), tab)
+					if err != nil {
+						return -1, fmt.Errorf("error updating tab: %w", err)
+					}
 					parentBlockCount = len(tab.BlockIds)
 				}
 			} else if parentORef.OType == waveobj.OType_Block {
 				parentBlock, _ := wstore.DBGet[*waveobj.Block](tx.Context(), parentORef.OID)
 				if parentBlock != nil {
 					parentBlock.SubBlockIds = utilfn.RemoveElemFromSlice(parentBlock.SubBlockIds, blockId)
-					wstore.DBUpdate(tx.Context(), parentBlock)
+					err = wstore.DBUpdate(tx.Context(), parentBlock)
+					if err != nil {
+						return -1, fmt.Errorf("error updating parent block: %w", err)
+					}
 					parentBlockCount = len(parentBlock.SubBlockIds)
 				}
 			}
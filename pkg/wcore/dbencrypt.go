@@ -0,0 +1,105 @@
+// Copyright 2025, Command Line Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package wcore
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sync"
+
+	"github.com/wavetermdev/waveterm/pkg/dbcrypt"
+	"github.com/wavetermdev/waveterm/pkg/filestore"
+	"github.com/wavetermdev/waveterm/pkg/waveobj"
+	"github.com/wavetermdev/waveterm/pkg/wconfig"
+	"github.com/wavetermdev/waveterm/pkg/wshrpc"
+	"github.com/wavetermdev/waveterm/pkg/wstore"
+)
+
+var dbEncryptLock = &sync.Mutex{}
+
+// turns database encryption on or off (or rotates the key) and rewrites the existing data in place.
+// both dbs are vacuumed afterwards so the freed pages don't keep the old plaintext (or the old ciphertext).
+// the setting is only saved once all of the data has been rewritten.  if the rewrite fails partway, the dbs hold a
+// mix of encrypted and plaintext data (readable, since the keys stay loaded) and running it again finishes the job.
+func RunDBEncrypt(ctx context.Context, data wshrpc.CommandDBEncryptData) (*wshrpc.DBEncryptRtnData, error) {
+	dbEncryptLock.Lock()
+	defer dbEncryptLock.Unlock()
+	wasEnabled := dbcrypt.Enabled()
+	switch {
+	case data.Off && data.Rotate:
+		return nil, fmt.Errorf("cannot rotate the key and turn off encryption at the same time")
+	case data.Off:
+		dbcrypt.Disable()
+	case data.Rotate:
+		if !dbcrypt.Enabled() {
+			return nil, fmt.Errorf("database encryption is not enabled")
+		}
+		keyId, err := dbcrypt.RotateKey(data.KeySource)
+		if err != nil {
+			return nil, fmt.Errorf("error rotating key: %w", err)
+		}
+		log.Printf("[dbcrypt] rotated to key %s\n", keyId)
+	default:
+		err := dbcrypt.Enable(data.KeySource)
+		if err != nil {
+			return nil, fmt.Errorf("error enabling encryption: %w", err)
+		}
+	}
+	// new data is written the way the saved setting says until the rewrite is done
+	restoreMode := func() {
+		if data.Rotate || wasEnabled == dbcrypt.Enabled() {
+			return
+		}
+		if wasEnabled {
+			if err := dbcrypt.Enable(data.KeySource); err != nil {
+				log.Printf("[dbcrypt] error turning encryption back on: %v\n", err)
+			}
+		} else {
+			dbcrypt.Disable()
+		}
+	}
+	numObjects, err := wstore.DBReencryptAll(ctx)
+	if err != nil {
+		restoreMode()
+		return nil, fmt.Errorf("error rewriting objects (run the command again to finish): %w", err)
+	}
+	fileStats, err := filestore.WFS.ReencryptAll(ctx)
+	if err != nil {
+		restoreMode()
+		return nil, fmt.Errorf("error rewriting files (run the command again to finish): %w", err)
+	}
+	var saveErr error
+	if data.Rotate {
+		// everything uses the new key now
+		err = dbcrypt.PruneKeys()
+		if err != nil {
+			return nil, fmt.Errorf("error removing old keys: %w", err)
+		}
+	} else {
+		saveErr = wconfig.SetBaseConfigValue(waveobj.MetaMapType{wconfig.ConfigKey_DbEncrypt: !data.Off})
+	}
+	err = wstore.DBVacuum(ctx)
+	if err != nil {
+		return nil, err
+	}
+	_, err = filestore.WFS.Vacuum(ctx, true)
+	if err != nil {
+		return nil, err
+	}
+	if saveErr != nil {
+		return nil, fmt.Errorf("the data was rewritten, but the %s setting could not be saved: %w", wconfig.ConfigKey_DbEncrypt, saveErr)
+	}
+	status := dbcrypt.GetStatus()
+	rtn := &wshrpc.DBEncryptRtnData{
+		Encrypted:  status.Enabled,
+		KeySource:  status.KeySource,
+		KeyId:      status.KeyId,
+		NumObjects: numObjects,
+		NumFiles:   fileStats.NumFiles,
+		NumParts:   fileStats.NumParts,
+	}
+	log.Printf("[dbcrypt] rewrote %d objects and %d files (%d parts), encrypted:%v\n", numObjects, fileStats.NumFiles, fileStats.NumParts, status.Enabled)
+	return rtn, nil
+}
@@ -0,0 +1,49 @@
+// Copyright 2025, Command Line Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package wcore
+
+import (
+	"context"
+	"testing"
+
+	"github.com/wavetermdev/waveterm/pkg/dbcrypt"
+	"github.com/wavetermdev/waveterm/pkg/wconfig"
+	"github.com/wavetermdev/waveterm/pkg/wshrpc"
+)
+
+func TestDBEncryptSavesSettingAfterRewrite(t *testing.T) {
+	initTestDbs(t)
+	defer dbcrypt.Disable()
+	getSetting := func() any {
+		t.Helper()
+		m, cerrs := wconfig.ReadWaveHomeConfigFile(wconfig.SettingsFile)
+		if len(cerrs) > 0 {
+			t.Fatalf("error reading settings: %v", cerrs[0])
+		}
+		return m[wconfig.ConfigKey_DbEncrypt]
+	}
+
+	// the rewrite fails, so the setting is not saved and new data is not encrypted
+	canceledCtx, cancelFn := context.WithCancel(context.Background())
+	cancelFn()
+	_, err := RunDBEncrypt(canceledCtx, wshrpc.CommandDBEncryptData{KeySource: dbcrypt.KeySourceFile})
+	if err == nil {
+		t.Fatalf("expected the rewrite to fail")
+	}
+	if val := getSetting(); val != nil {
+		t.Errorf("expected no %s setting after a failed rewrite, got %v", wconfig.ConfigKey_DbEncrypt, val)
+	}
+	if dbcrypt.Enabled() {
+		t.Errorf("expected encryption to be off after a failed rewrite")
+	}
+
+	// running it again finishes the job
+	rtn, err := RunDBEncrypt(context.Background(), wshrpc.CommandDBEncryptData{KeySource: dbcrypt.KeySourceFile})
+	if err != nil {
+		t.Fatalf("error encrypting: %v", err)
+	}
+	if !rtn.Encrypted || getSetting() != true {
+		t.Errorf("expected encryption to be on and saved, got %v and %v", rtn.Encrypted, getSetting())
+	}
+}
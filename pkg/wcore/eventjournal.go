@@ -0,0 +1,254 @@
+// Copyright 2025, Command Line Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package wcore
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"log"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/wavetermdev/waveterm/pkg/panichandler"
+	"github.com/wavetermdev/waveterm/pkg/wconfig"
+	"github.com/wavetermdev/waveterm/pkg/wps"
+	"github.com/wavetermdev/waveterm/pkg/wshrpc"
+	"github.com/wavetermdev/waveterm/pkg/wstore"
+)
+
+// the event journal keeps selected wps events in the database (eventjournal:* settings), so that subscribers
+// that were disconnected can catch up.  events get their seq when they are published and are written in batches
+// by a background goroutine.
+
+const (
+	DefaultEventJournalRetentionDays = 7
+	DefaultEventJournalMaxEntries    = 100000
+	EventJournalPurgeInterval        = time.Hour
+	DefaultEventReplayMaxItems       = 1000
+	MaxEventReplayMaxItems           = 10000
+)
+
+const eventJournalWriteInterval = 250 * time.Millisecond
+
+// events journaled when eventjournal:events is not set.  term:trigger is left out, its events have terminal output
+// (the journal is not encrypted).
+var DefaultJournalEvents = []string{
+	wps.Event_ConnChange,
+	wps.Event_ControllerStatus,
+	wps.Event_BlockClose,
+	wps.Event_RouteGone,
+	wps.Event_WorkspaceUpdate,
+}
+
+type eventJournal struct {
+	Lock      *sync.Mutex
+	WriteLock *sync.Mutex // held while a batch is written (so a flush waits for a write in progress)
+	Enabled   bool
+	Events    []string
+	LastSeq   int64
+	Queue     []*wps.WaveEvent
+	WriteCh   chan struct{}
+}
+
+var journal = &eventJournal{
+	Lock:      &sync.Mutex{},
+	WriteLock: &sync.Mutex{},
+	WriteCh:   make(chan struct{}, 1),
+}
+
+func getJournalEvents(settings wconfig.SettingsType) []string {
+	if len(settings.EventJournalEvents) > 0 {
+		return settings.EventJournalEvents
+	}
+	return DefaultJournalEvents
+}
+
+func getEventJournalLimits() (time.Duration, int) {
+	settings := wconfig.GetWatcher().GetFullConfig().Settings
+	retentionDays := cmp.Or(settings.EventJournalRetentionDays, DefaultEventJournalRetentionDays)
+	maxEntries := cmp.Or(settings.EventJournalMaxEntries, DefaultEventJournalMaxEntries)
+	return time.Duration(retentionDays) * 24 * time.Hour, maxEntries
+}
+
+func (j *eventJournal) setSettings(settings wconfig.SettingsType) {
+	j.Lock.Lock()
+	defer j.Lock.Unlock()
+	j.Enabled = settings.EventJournalEnabled
+	j.Events = getJournalEvents(settings)
+}
+
+func (j *eventJournal) getSettings() (bool, []string) {
+	j.Lock.Lock()
+	defer j.Lock.Unlock()
+	return j.Enabled, j.Events
+}
+
+// AppendEvent implements wps.EventJournal.  the settings are taken from the config events (the config watcher
+// publishes them while holding its lock, so the config cannot be read here).
+func (j *eventJournal) AppendEvent(event *wps.WaveEvent) {
+	if event.Event == wps.Event_Config {
+		if update, ok := event.Data.(wconfig.WatcherUpdate); ok {
+			j.setSettings(update.FullConfig.Settings)
+		}
+	}
+	j.Lock.Lock()
+	if !j.Enabled || !slices.Contains(j.Events, event.Event) {
+		j.Lock.Unlock()
+		return
+	}
+	j.LastSeq++
+	event.Seq = j.LastSeq
+	event.Ts = time.Now().UnixMilli()
+	// the queue gets a copy, the caller still owns event (and sends it)
+	eventCopy := *event
+	j.Queue = append(j.Queue, &eventCopy)
+	j.Lock.Unlock()
+	select {
+	case j.WriteCh <- struct{}{}:
+	default:
+	}
+}
+
+// writes the queued events.  when it returns, every event appended before the call is in the database.
+func (j *eventJournal) flush(ctx context.Context) error {
+	j.WriteLock.Lock()
+	defer j.WriteLock.Unlock()
+	j.Lock.Lock()
+	queue := j.Queue
+	j.Queue = nil
+	j.Lock.Unlock()
+	if len(queue) == 0 {
+		return nil
+	}
+	err := wstore.DBInsertEvents(ctx, queue)
+	if err != nil {
+		return fmt.Errorf("error writing %d events to the event journal: %w", len(queue), err)
+	}
+	return nil
+}
+
+func (j *eventJournal) writeLoop() {
+	defer func() {
+		panichandler.PanicHandler("eventJournal.writeLoop", recover())
+	}()
+	for range j.WriteCh {
+		// collect events for a short time, so they are written in batches
+		time.Sleep(eventJournalWriteInterval)
+		ctx, cancelFn := context.WithTimeout(context.Background(), 10*time.Second)
+		err := j.flush(ctx)
+		cancelFn()
+		if err != nil {
+			log.Printf("%v\n", err)
+		}
+	}
+}
+
+// StartEventJournal continues the sequence numbers of the journal and installs it in the broker.  events are only
+// journaled while eventjournal:enabled is set.
+func StartEventJournal(ctx context.Context) error {
+	lastSeq, err := wstore.DBGetLastEventSeq(ctx)
+	if err != nil {
+		return fmt.Errorf("error reading event journal seq: %w", err)
+	}
+	journal.setSettings(wconfig.GetWatcher().GetFullConfig().Settings)
+	journal.Lock.Lock()
+	journal.LastSeq = lastSeq
+	journal.Lock.Unlock()
+	go journal.writeLoop()
+	wps.Broker.SetJournal(journal)
+	return nil
+}
+
+// FlushEventJournal writes the queued events (called on shutdown)
+func FlushEventJournal(ctx context.Context) error {
+	return journal.flush(ctx)
+}
+
+// ReplayEvents returns the journaled events after data.SinceSeq that match data.Events and data.Scopes
+func ReplayEvents(ctx context.Context, data wshrpc.CommandEventReplayData) (*wshrpc.EventReplayRtnData, error) {
+	maxItems := data.MaxItems
+	if maxItems <= 0 {
+		maxItems = DefaultEventReplayMaxItems
+	}
+	maxItems = min(maxItems, MaxEventReplayMaxItems)
+	err := journal.flush(ctx)
+	if err != nil {
+		return nil, err
+	}
+	rtn := &wshrpc.EventReplayRtnData{Events: []*wps.WaveEvent{}}
+	rtn.Enabled, rtn.JournalEvents = journal.getSettings()
+	rtn.FirstSeq, err = wstore.DBGetFirstEventSeq(ctx)
+	if err != nil {
+		return nil, err
+	}
+	rtn.LastSeq, err = wstore.DBGetLastEventSeq(ctx)
+	if err != nil {
+		return nil, err
+	}
+	sinceSeq := data.SinceSeq
+	for {
+		// scopes are filtered here, so read more than maxItems per page
+		events, err := wstore.DBReadEvents(ctx, sinceSeq, data.Events, maxItems+1)
+		if err != nil {
+			return nil, err
+		}
+		for _, event := range events {
+			sinceSeq = event.Seq
+			if !eventMatchesScopes(event, data.Scopes) {
+				continue
+			}
+			if len(rtn.Events) == maxItems {
+				rtn.More = true
+				return rtn, nil
+			}
+			rtn.Events = append(rtn.Events, event)
+		}
+		if len(events) <= maxItems {
+			return rtn, nil
+		}
+	}
+}
+
+func eventMatchesScopes(event *wps.WaveEvent, scopes []string) bool {
+	sub := wps.SubscriptionRequest{Event: event.Event, Scopes: scopes, AllScopes: len(scopes) == 0}
+	return sub.Matches(event)
+}
+
+// SendReplayedEvents sends the journaled events after sub.SinceSeq that match the subscription to routeId (used to
+// resume a subscription).  events published while they are sent can arrive before the replayed ones, clients
+// should use the seq to drop duplicates.
+func SendReplayedEvents(ctx context.Context, routeId string, sub wps.SubscriptionRequest) error {
+	client := wps.Broker.GetClient()
+	if client == nil || sub.SinceSeq <= 0 {
+		return nil
+	}
+	data := wshrpc.CommandEventReplayData{SinceSeq: sub.SinceSeq, Events: []string{sub.Event}, MaxItems: MaxEventReplayMaxItems}
+	if !sub.AllScopes {
+		data.Scopes = sub.Scopes
+		if len(data.Scopes) == 0 {
+			return nil
+		}
+	}
+	for {
+		rtn, err := ReplayEvents(ctx, data)
+		if err != nil {
+			return err
+		}
+		for _, event := range rtn.Events {
+			client.SendEvent(routeId, *event)
+			data.SinceSeq = event.Seq
+		}
+		if !rtn.More {
+			return nil
+		}
+	}
+}
+
+// PurgeEventJournal removes the events past the eventjournal:retentiondays and eventjournal:maxentries limits
+func PurgeEventJournal(ctx context.Context) (int, error) {
+	retention, maxEntries := getEventJournalLimits()
+	return wstore.DBPurgeEvents(ctx, time.Now().Add(-retention).UnixMilli(), maxEntries)
+}
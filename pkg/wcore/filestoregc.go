@@ -0,0 +1,110 @@
+// Copyright 2025, Command Line Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package wcore
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"reflect"
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/wavetermdev/waveterm/pkg/filestore"
+	"github.com/wavetermdev/waveterm/pkg/waveobj"
+	"github.com/wavetermdev/waveterm/pkg/wshrpc"
+	"github.com/wavetermdev/waveterm/pkg/wstore"
+)
+
+// the filestore gc runs this long after startup
+const FileStoreGCStartupDelay = 2 * time.Minute
+
+// zones with recently modified files are kept (their object may still be being created)
+const fileStoreGCMinZoneAge = 10 * time.Minute
+
+// every object (including trashed objects) can own a filestore zone (and the client owns its temp zone)
+func getLiveZoneIds(ctx context.Context) (map[string]bool, error) {
+	liveIds := make(map[string]bool)
+	for _, rtype := range waveobj.AllWaveObjTypes() {
+		otype := reflect.Zero(rtype).Interface().(waveobj.WaveObj).GetOType()
+		oids, err := wstore.DBGetAllOIDsByType(ctx, otype)
+		if err != nil {
+			return nil, fmt.Errorf("error getting %s ids: %w", otype, err)
+		}
+		for _, oid := range oids {
+			liveIds[oid] = true
+		}
+	}
+	// trashed objects keep their zones until they are purged
+	trashedIds, err := wstore.DBGetTrashedOIDs(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("error getting trashed ids: %w", err)
+	}
+	for _, oid := range trashedIds {
+		liveIds[oid] = true
+	}
+	client, err := wstore.DBGetSingleton[*waveobj.Client](ctx)
+	if err != nil {
+		return nil, fmt.Errorf("error getting client: %w", err)
+	}
+	liveIds[client.TempOID] = true
+	return liveIds, nil
+}
+
+// finds the filestore zones whose block/tab/workspace no longer exists in wstore (e.g. blocks that were not
+// deleted cleanly) and deletes them, then vacuums the filestore db
+func RunFileStoreGC(ctx context.Context, data wshrpc.CommandFileStoreGCData) (*wshrpc.FileStoreGCRtnData, error) {
+	rtn := &wshrpc.FileStoreGCRtnData{DryRun: data.DryRun, Zones: []*wshrpc.FileStoreGCZone{}, DBSizeBefore: filestore.GetDBSize()}
+	liveIds, err := getLiveZoneIds(ctx)
+	if err != nil {
+		return nil, err
+	}
+	usage, err := filestore.WFS.GetZoneUsage(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("error getting filestore zones: %w", err)
+	}
+	minModTs := time.Now().Add(-fileStoreGCMinZoneAge).UnixMilli()
+	for _, zone := range usage {
+		if liveIds[zone.ZoneId] || zone.ModTs > minModTs {
+			continue
+		}
+		if _, err := uuid.Parse(zone.ZoneId); err != nil {
+			// not an object id (zones are only created for objects, but don't remove what we don't know)
+			continue
+		}
+		rtn.Zones = append(rtn.Zones, &wshrpc.FileStoreGCZone{ZoneId: zone.ZoneId, NumFiles: zone.NumFiles, Size: zone.Size, DataBytes: zone.DataBytes})
+		rtn.ReclaimableBytes += zone.DataBytes
+	}
+	sort.Slice(rtn.Zones, func(i, j int) bool {
+		return rtn.Zones[i].DataBytes > rtn.Zones[j].DataBytes
+	})
+	if data.DryRun {
+		rtn.FreeBytes, err = filestore.GetDBFreeBytes(ctx)
+		if err != nil {
+			return nil, err
+		}
+		rtn.DBSizeAfter = rtn.DBSizeBefore
+		return rtn, nil
+	}
+	for _, zone := range rtn.Zones {
+		err := filestore.WFS.DeleteZone(ctx, zone.ZoneId)
+		if err != nil {
+			return nil, fmt.Errorf("error deleting zone %s: %w", zone.ZoneId, err)
+		}
+	}
+	rtn.FreeBytes, err = filestore.GetDBFreeBytes(ctx)
+	if err != nil {
+		return nil, err
+	}
+	rtn.Vacuumed, err = filestore.WFS.Vacuum(ctx, data.Vacuum)
+	if err != nil {
+		return nil, err
+	}
+	rtn.DBSizeAfter = filestore.GetDBSize()
+	if len(rtn.Zones) > 0 || rtn.Vacuumed {
+		log.Printf("filestore gc: removed %d orphaned zones (%d bytes), vacuumed:%v, db size %d -> %d\n", len(rtn.Zones), rtn.ReclaimableBytes, rtn.Vacuumed, rtn.DBSizeBefore, rtn.DBSizeAfter)
+	}
+	return rtn, nil
+}
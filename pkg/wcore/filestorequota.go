@@ -0,0 +1,123 @@
+// Copyright 2025, Command Line Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package wcore
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/wavetermdev/waveterm/pkg/filestore"
+	"github.com/wavetermdev/waveterm/pkg/wavebase"
+	"github.com/wavetermdev/waveterm/pkg/waveobj"
+	"github.com/wavetermdev/waveterm/pkg/wconfig"
+	"github.com/wavetermdev/waveterm/pkg/wshrpc"
+	"github.com/wavetermdev/waveterm/pkg/wstore"
+)
+
+const defaultStorageUsageZones = 20
+
+const mb = 1024 * 1024
+
+// the filestore quotas from the storage:* settings (installed as filestore.GetQuotas)
+func GetFileStoreQuotas() filestore.Quotas {
+	settings := wconfig.GetWatcher().GetFullConfig().Settings
+	quotas := filestore.Quotas{
+		MaxZoneSize:  int64(settings.StorageMaxBlockMb) * mb,
+		MaxTotalSize: int64(settings.StorageMaxTotalMb) * mb,
+	}
+	if settings.StorageMaxTermMb > 0 {
+		quotas.MaxFileTypeSize = map[string]int64{wavebase.BlockFile_Term: int64(settings.StorageMaxTermMb) * mb}
+	}
+	return quotas
+}
+
+// returns the usage of the filestore by zone and by file type, so large zones (e.g. a block with a huge log) can be found
+func GetFileStoreUsage(ctx context.Context, data wshrpc.CommandFileStoreUsageData) (*wshrpc.FileStoreUsageRtnData, error) {
+	files, err := filestore.WFS.GetFileUsage(ctx, "")
+	if err != nil {
+		return nil, fmt.Errorf("error getting filestore usage: %w", err)
+	}
+	quotas := GetFileStoreQuotas()
+	rtn := &wshrpc.FileStoreUsageRtnData{
+		DBSize: filestore.GetDBSize(),
+		Quotas: wshrpc.FileStoreQuotas{
+			MaxZoneSize:     quotas.MaxZoneSize,
+			MaxFileTypeSize: quotas.MaxFileTypeSize,
+			MaxTotalSize:    quotas.MaxTotalSize,
+		},
+		Zones:     []*wshrpc.FileStoreZoneUsage{},
+		FileTypes: []*wshrpc.FileStoreTypeUsage{},
+	}
+	zones := make(map[string]*wshrpc.FileStoreZoneUsage)
+	fileTypes := make(map[string]*wshrpc.FileStoreTypeUsage)
+	for _, file := range files {
+		rtn.DataLength += file.DataLength
+		rtn.DataBytes += file.DataBytes
+		zone := zones[file.ZoneId]
+		if zone == nil {
+			zone = &wshrpc.FileStoreZoneUsage{ZoneId: file.ZoneId}
+			zones[file.ZoneId] = zone
+			rtn.Zones = append(rtn.Zones, zone)
+		}
+		zone.NumFiles++
+		zone.DataLength += file.DataLength
+		zone.DataBytes += file.DataBytes
+		zone.ModTs = max(zone.ModTs, file.ModTs)
+		fileType := fileTypes[file.Name]
+		if fileType == nil {
+			fileType = &wshrpc.FileStoreTypeUsage{Name: file.Name}
+			fileTypes[file.Name] = fileType
+			rtn.FileTypes = append(rtn.FileTypes, fileType)
+		}
+		fileType.NumFiles++
+		fileType.DataLength += file.DataLength
+		fileType.DataBytes += file.DataBytes
+		if data.ZoneId != "" && file.ZoneId == data.ZoneId {
+			rtn.Files = append(rtn.Files, &wshrpc.FileStoreFileUsage{
+				Name:       file.Name,
+				Size:       file.Size,
+				DataLength: file.DataLength,
+				DataBytes:  file.DataBytes,
+				Circular:   file.Circular,
+				ModTs:      file.ModTs,
+			})
+		}
+	}
+	rtn.NumZones = len(rtn.Zones)
+	sort.Slice(rtn.Zones, func(i, j int) bool {
+		return rtn.Zones[i].DataLength > rtn.Zones[j].DataLength
+	})
+	sort.Slice(rtn.FileTypes, func(i, j int) bool {
+		return rtn.FileTypes[i].DataLength > rtn.FileTypes[j].DataLength
+	})
+	sort.Slice(rtn.Files, func(i, j int) bool {
+		return rtn.Files[i].DataLength > rtn.Files[j].DataLength
+	})
+	limit := data.Limit
+	if limit <= 0 {
+		limit = defaultStorageUsageZones
+	}
+	if len(rtn.Zones) > limit {
+		rtn.Zones = rtn.Zones[:limit]
+	}
+	for _, zone := range rtn.Zones {
+		oref, err := wstore.DBResolveEasyOID(ctx, zone.ZoneId)
+		if errors.Is(err, wstore.ErrNotFound) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		zone.OType = oref.OType
+		if oref.OType == waveobj.OType_Block {
+			block, err := wstore.DBGet[*waveobj.Block](ctx, oref.OID)
+			if err == nil && block != nil {
+				zone.View = block.Meta.GetString(waveobj.MetaKey_View, "")
+			}
+		}
+	}
+	return rtn, nil
+}
@@ -99,20 +99,17 @@ func GetLayoutIdForTab(ctx context.Context, tabId string) (string, error) {
 }
 
 func QueueLayoutAction(ctx context.Context, layoutStateId string, actions ...waveobj.LayoutActionData) error {
-	layoutStateObj, err := wstore.DBGet[*waveobj.LayoutState](ctx, layoutStateId)
-	if err != nil {
-		return fmt.Errorf("unable to get layout state for given id %s: %w", layoutStateId, err)
-	}
-
-	if layoutStateObj.PendingBackendActions == nil {
-		layoutStateObj.PendingBackendActions = &actions
-	} else {
-		*layoutStateObj.PendingBackendActions = append(*layoutStateObj.PendingBackendActions, actions...)
-	}
-
-	err = wstore.DBUpdate(ctx, layoutStateObj)
+	_, err := wstore.UpdateObject(ctx, waveobj.MakeORef(waveobj.OType_LayoutState, layoutStateId), func(obj waveobj.WaveObj) error {
+		layoutStateObj := obj.(*waveobj.LayoutState)
+		if layoutStateObj.PendingBackendActions == nil {
+			layoutStateObj.PendingBackendActions = &actions
+		} else {
+			*layoutStateObj.PendingBackendActions = append(*layoutStateObj.PendingBackendActions, actions...)
+		}
+		return nil
+	})
 	if err != nil {
-		return fmt.Errorf("unable to update layout state with new actions: %w", err)
+		return fmt.Errorf("unable to update layout state %s with new actions: %w", layoutStateId, err)
 	}
 	return nil
 }
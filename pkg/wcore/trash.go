@@ -0,0 +1,518 @@
+// Copyright 2025, Command Line Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package wcore
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"log"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/wavetermdev/waveterm/pkg/panichandler"
+	"github.com/wavetermdev/waveterm/pkg/util/utilfn"
+	"github.com/wavetermdev/waveterm/pkg/waveobj"
+	"github.com/wavetermdev/waveterm/pkg/wconfig"
+	"github.com/wavetermdev/waveterm/pkg/wshrpc"
+	"github.com/wavetermdev/waveterm/pkg/wstore"
+)
+
+const (
+	DefaultTrashRetentionDays = 7
+	DefaultTrashMaxEntries    = 200
+	TrashPurgeInterval        = time.Hour
+)
+
+// how long the layout position of a block that was removed from its layout is remembered
+const recentLayoutPosTTL = time.Minute
+
+func trashEnabled() bool {
+	return !wconfig.GetWatcher().GetFullConfig().Settings.TrashDisabled
+}
+
+func getTrashLimits() (time.Duration, int) {
+	settings := wconfig.GetWatcher().GetFullConfig().Settings
+	retentionDays := cmp.Or(settings.TrashRetentionDays, DefaultTrashRetentionDays)
+	maxEntries := cmp.Or(settings.TrashMaxEntries, DefaultTrashMaxEntries)
+	return time.Duration(retentionDays) * 24 * time.Hour, maxEntries
+}
+
+// returns the block and all of its sub-blocks
+func collectBlockObjs(ctx context.Context, block *waveobj.Block) ([]waveobj.WaveObj, error) {
+	rtn := []waveobj.WaveObj{block}
+	for _, subBlockId := range block.SubBlockIds {
+		subBlock, err := wstore.DBGet[*waveobj.Block](ctx, subBlockId)
+		if err != nil {
+			return nil, fmt.Errorf("error getting subblock %s: %w", subBlockId, err)
+		}
+		if subBlock == nil {
+			continue
+		}
+		subObjs, err := collectBlockObjs(ctx, subBlock)
+		if err != nil {
+			return nil, err
+		}
+		rtn = append(rtn, subObjs...)
+	}
+	return rtn, nil
+}
+
+func countBlocks(objs []waveobj.WaveObj) int {
+	var rtn int
+	for _, obj := range objs {
+		if obj.GetOType() == waveobj.OType_Block {
+			rtn++
+		}
+	}
+	return rtn
+}
+
+// moves a copy of the tab (with its layout and blocks) to the trash.  must be called before the tab is deleted.
+func trashTab(ctx context.Context, workspaceId string, tab *waveobj.Tab, index int, pinned bool) error {
+	objs := []waveobj.WaveObj{tab}
+	layoutState, err := wstore.DBGet[*waveobj.LayoutState](ctx, tab.LayoutState)
+	if err != nil {
+		return fmt.Errorf("error getting layout: %w", err)
+	}
+	if layoutState != nil {
+		objs = append(objs, layoutState)
+	}
+	for _, blockId := range tab.BlockIds {
+		block, err := wstore.DBGet[*waveobj.Block](ctx, blockId)
+		if err != nil {
+			return fmt.Errorf("error getting block %s: %w", blockId, err)
+		}
+		if block == nil {
+			continue
+		}
+		blockObjs, err := collectBlockObjs(ctx, block)
+		if err != nil {
+			return err
+		}
+		objs = append(objs, blockObjs...)
+	}
+	entry := &wshrpc.TrashEntry{
+		TrashId:     uuid.NewString(),
+		OType:       waveobj.OType_Tab,
+		OID:         tab.OID,
+		DeletedTs:   time.Now().UnixMilli(),
+		Name:        tab.Name,
+		WorkspaceId: workspaceId,
+		Index:       index,
+		Pinned:      pinned,
+		NumBlocks:   countBlocks(objs),
+	}
+	err = wstore.DBInsertTrash(ctx, entry, objs)
+	if err != nil {
+		return err
+	}
+	goPurgeTrash()
+	return nil
+}
+
+// moves a copy of the block (with its sub-blocks) to the trash.  must be called before the block is deleted.
+func trashBlock(ctx context.Context, block *waveobj.Block) error {
+	objs, err := collectBlockObjs(ctx, block)
+	if err != nil {
+		return err
+	}
+	entry := &wshrpc.TrashEntry{
+		TrashId:    uuid.NewString(),
+		OType:      waveobj.OType_Block,
+		OID:        block.OID,
+		DeletedTs:  time.Now().UnixMilli(),
+		View:       block.Meta.GetString(waveobj.MetaKey_View, ""),
+		ParentORef: block.ParentORef,
+		NumBlocks:  len(objs),
+	}
+	parentORef := waveobj.ParseORefNoErr(block.ParentORef)
+	if parentORef != nil && parentORef.OType == waveobj.OType_Tab {
+		entry.TabId = parentORef.OID
+		tab, _ := wstore.DBGet[*waveobj.Tab](ctx, parentORef.OID)
+		if tab != nil {
+			entry.Index = utilfn.FindStringInSlice(tab.BlockIds, block.OID)
+			entry.Layout = getBlockLayoutPos(ctx, tab.LayoutState, block.OID)
+		}
+	} else if parentORef != nil && parentORef.OType == waveobj.OType_Block {
+		parentBlock, _ := wstore.DBGet[*waveobj.Block](ctx, parentORef.OID)
+		if parentBlock != nil {
+			entry.Index = utilfn.FindStringInSlice(parentBlock.SubBlockIds, block.OID)
+		}
+		entry.TabId, _ = wstore.DBFindTabForBlockId(ctx, parentORef.OID)
+	}
+	if entry.TabId != "" {
+		entry.WorkspaceId, _ = wstore.DBFindWorkspaceForTabId(ctx, entry.TabId)
+	}
+	err = wstore.DBInsertTrash(ctx, entry, objs)
+	if err != nil {
+		return err
+	}
+	goPurgeTrash()
+	return nil
+}
+
+func ListTrash(ctx context.Context, data wshrpc.CommandTrashListData) ([]*wshrpc.TrashEntry, error) {
+	entries, err := wstore.DBListTrash(ctx)
+	if err != nil {
+		return nil, err
+	}
+	rtn := make([]*wshrpc.TrashEntry, 0, len(entries))
+	for _, entry := range entries {
+		if data.WorkspaceId != "" && entry.WorkspaceId != data.WorkspaceId {
+			continue
+		}
+		if data.OType != "" && entry.OType != data.OType {
+			continue
+		}
+		rtn = append(rtn, entry)
+	}
+	return rtn, nil
+}
+
+// trashId can be a unique prefix of the id
+func resolveTrashEntry(ctx context.Context, trashId string) (*wshrpc.TrashEntry, error) {
+	if trashId == "" {
+		return nil, fmt.Errorf("no trash id")
+	}
+	entry, err := wstore.DBGetTrash(ctx, trashId)
+	if err == nil {
+		return entry, nil
+	}
+	if err != wstore.ErrNotFound {
+		return nil, err
+	}
+	entries, err := wstore.DBListTrash(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var matches []*wshrpc.TrashEntry
+	for _, entry := range entries {
+		if strings.HasPrefix(entry.TrashId, trashId) {
+			matches = append(matches, entry)
+		}
+	}
+	if len(matches) == 0 {
+		return nil, fmt.Errorf("trash entry %q not found", trashId)
+	}
+	if len(matches) > 1 {
+		return nil, fmt.Errorf("trash id %q is ambiguous (%d entries)", trashId, len(matches))
+	}
+	return matches[0], nil
+}
+
+// restores a closed tab or block into its original workspace, tab and layout position
+func RestoreTrash(ctx context.Context, data wshrpc.CommandTrashRestoreData) (*wshrpc.TrashRestoreRtnData, error) {
+	entry, err := resolveTrashEntry(ctx, data.TrashId)
+	if err != nil {
+		return nil, err
+	}
+	switch entry.OType {
+	case waveobj.OType_Tab:
+		return restoreTab(ctx, entry, data.WorkspaceId)
+	case waveobj.OType_Block:
+		return restoreBlock(ctx, entry)
+	default:
+		return nil, fmt.Errorf("cannot restore %s objects", entry.OType)
+	}
+}
+
+func insertRestoredObjs(ctx context.Context, objs []waveobj.WaveObj) error {
+	for _, obj := range objs {
+		oref := waveobj.ORefFromWaveObj(obj)
+		exists, err := wstore.DBExistsORef(ctx, *oref)
+		if err != nil {
+			return err
+		}
+		if exists {
+			return fmt.Errorf("cannot restore, %s already exists", oref)
+		}
+		err = wstore.DBInsert(ctx, obj)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func insertAtIndex(ids []string, index int, id string) []string {
+	if index < 0 {
+		index = len(ids)
+	}
+	return slices.Insert(ids, min(index, len(ids)), id)
+}
+
+func restoreTab(ctx context.Context, entry *wshrpc.TrashEntry, workspaceId string) (*wshrpc.TrashRestoreRtnData, error) {
+	workspaceId = cmp.Or(workspaceId, entry.WorkspaceId)
+	objs, err := wstore.DBGetTrashObjs(ctx, entry.TrashId)
+	if err != nil {
+		return nil, err
+	}
+	err = wstore.WithTx(ctx, func(tx *wstore.TxWrap) error {
+		ws, _ := wstore.DBGet[*waveobj.Workspace](tx.Context(), workspaceId)
+		if ws == nil {
+			return fmt.Errorf("workspace %s no longer exists (restore the tab into another workspace)", workspaceId)
+		}
+		err := insertRestoredObjs(tx.Context(), objs)
+		if err != nil {
+			return err
+		}
+		if entry.Pinned {
+			ws.PinnedTabIds = insertAtIndex(ws.PinnedTabIds, entry.Index, entry.OID)
+		} else {
+			ws.TabIds = insertAtIndex(ws.TabIds, entry.Index, entry.OID)
+		}
+		ws.ActiveTabId = entry.OID
+		err = wstore.DBUpdate(tx.Context(), ws)
+		if err != nil {
+			return err
+		}
+		_, err = wstore.DBRemoveTrash(tx.Context(), entry.TrashId)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	log.Printf("restored tab %s into workspace %s (%d blocks)\n", entry.OID, workspaceId, entry.NumBlocks)
+	SendActiveTabUpdate(ctx, workspaceId, entry.OID)
+	return &wshrpc.TrashRestoreRtnData{OType: entry.OType, OID: entry.OID, WorkspaceId: workspaceId, TabId: entry.OID, NumBlocks: entry.NumBlocks}, nil
+}
+
+func restoreBlock(ctx context.Context, entry *wshrpc.TrashEntry) (*wshrpc.TrashRestoreRtnData, error) {
+	parentORef, err := waveobj.ParseORef(entry.ParentORef)
+	if err != nil {
+		return nil, fmt.Errorf("bad parent for block %s: %w", entry.OID, err)
+	}
+	if parentORef.OType == waveobj.OType_Tab {
+		// closing the last block of a tab closes the tab, so the tab is restored first
+		tab, _ := wstore.DBGet[*waveobj.Tab](ctx, parentORef.OID)
+		if tab == nil {
+			tabEntry, err := wstore.DBFindTrashForOID(ctx, waveobj.OType_Tab, parentORef.OID)
+			if err != nil {
+				return nil, err
+			}
+			if tabEntry == nil {
+				return nil, fmt.Errorf("tab %s no longer exists", parentORef.OID)
+			}
+			_, err = restoreTab(ctx, tabEntry, "")
+			if err != nil {
+				return nil, fmt.Errorf("error restoring tab %s: %w", parentORef.OID, err)
+			}
+		}
+	}
+	objs, err := wstore.DBGetTrashObjs(ctx, entry.TrashId)
+	if err != nil {
+		return nil, err
+	}
+	var tab *waveobj.Tab
+	err = wstore.WithTx(ctx, func(tx *wstore.TxWrap) error {
+		err := insertRestoredObjs(tx.Context(), objs)
+		if err != nil {
+			return err
+		}
+		switch parentORef.OType {
+		case waveobj.OType_Tab:
+			tab, _ = wstore.DBGet[*waveobj.Tab](tx.Context(), parentORef.OID)
+			if tab == nil {
+				return fmt.Errorf("tab %s no longer exists", parentORef.OID)
+			}
+			tab.BlockIds = insertAtIndex(tab.BlockIds, entry.Index, entry.OID)
+			err = wstore.DBUpdate(tx.Context(), tab)
+		case waveobj.OType_Block:
+			parentBlock, _ := wstore.DBGet[*waveobj.Block](tx.Context(), parentORef.OID)
+			if parentBlock == nil {
+				return fmt.Errorf("parent block %s no longer exists", parentORef.OID)
+			}
+			parentBlock.SubBlockIds = insertAtIndex(parentBlock.SubBlockIds, entry.Index, entry.OID)
+			err = wstore.DBUpdate(tx.Context(), parentBlock)
+		default:
+			return fmt.Errorf("bad parent type %q for block %s", parentORef.OType, entry.OID)
+		}
+		if err != nil {
+			return err
+		}
+		_, err = wstore.DBRemoveTrash(tx.Context(), entry.TrashId)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	if tab != nil {
+		layoutState, _ := wstore.DBGet[*waveobj.LayoutState](ctx, tab.LayoutState)
+		// a restored tab may still have the block in its layout
+		if layoutState != nil && !slices.Contains(collectLayoutBlockIds(layoutState.RootNode, nil), entry.OID) {
+			err = QueueLayoutAction(ctx, tab.LayoutState, makeRestoreLayoutAction(entry, tab))
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+	log.Printf("restored block %s into %s\n", entry.OID, entry.ParentORef)
+	return &wshrpc.TrashRestoreRtnData{OType: entry.OType, OID: entry.OID, WorkspaceId: entry.WorkspaceId, TabId: entry.TabId, NumBlocks: entry.NumBlocks}, nil
+}
+
+// puts the block next to the block it was next to (if that block is still there), otherwise it is added like a new block
+func makeRestoreLayoutAction(entry *wshrpc.TrashEntry, tab *waveobj.Tab) waveobj.LayoutActionData {
+	action := waveobj.LayoutActionData{ActionType: LayoutActionDataType_Insert, BlockId: entry.OID, Focused: true}
+	pos := entry.Layout
+	if pos == nil {
+		return action
+	}
+	action.NodeSize = pos.NodeSize
+	if pos.TargetBlockId != "" && slices.Contains(tab.BlockIds, pos.TargetBlockId) {
+		action.ActionType = pos.ActionType
+		action.TargetBlockId = pos.TargetBlockId
+		action.Position = pos.Position
+	}
+	return action
+}
+
+// removes the entries past the retention window (and past the max number of entries), deleting their files
+func PurgeTrash(ctx context.Context) (int, error) {
+	retention, maxEntries := getTrashLimits()
+	trashIds, err := wstore.DBGetExpiredTrashIds(ctx, time.Now().Add(-retention).UnixMilli(), maxEntries)
+	if err != nil {
+		return 0, err
+	}
+	for idx, trashId := range trashIds {
+		err = wstore.DBPurgeTrash(ctx, trashId)
+		if err != nil {
+			return idx, fmt.Errorf("error purging trash entry %s: %w", trashId, err)
+		}
+	}
+	return len(trashIds), nil
+}
+
+func goPurgeTrash() {
+	go func() {
+		defer func() {
+			panichandler.PanicHandler("PurgeTrash", recover())
+		}()
+		ctx, cancelFn := context.WithTimeout(context.Background(), time.Minute)
+		defer cancelFn()
+		_, err := PurgeTrash(ctx)
+		if err != nil {
+			log.Printf("error purging trash: %v\n", err)
+		}
+	}()
+}
+
+// layout nodes (as stored in the LayoutState) are {"id", "data": {"blockId"}, "children", "flexDirection", "size"}
+
+func layoutNodeBlockId(node any) string {
+	nodeMap, _ := node.(map[string]any)
+	data, _ := nodeMap["data"].(map[string]any)
+	blockId, _ := data["blockId"].(string)
+	return blockId
+}
+
+func collectLayoutBlockIds(node any, rtn []string) []string {
+	nodeMap, ok := node.(map[string]any)
+	if !ok {
+		return rtn
+	}
+	if blockId := layoutNodeBlockId(nodeMap); blockId != "" {
+		rtn = append(rtn, blockId)
+	}
+	children, _ := nodeMap["children"].([]any)
+	for _, child := range children {
+		rtn = collectLayoutBlockIds(child, rtn)
+	}
+	return rtn
+}
+
+// finds the node of the block and records its neighbor (a leaf sibling) and the direction of its parent
+func findLayoutPos(node any, blockId string) (*wshrpc.TrashLayoutPos, bool) {
+	nodeMap, ok := node.(map[string]any)
+	if !ok {
+		return nil, false
+	}
+	if layoutNodeBlockId(nodeMap) == blockId {
+		// the only block in the layout
+		return &wshrpc.TrashLayoutPos{}, true
+	}
+	children, _ := nodeMap["children"].([]any)
+	for idx, child := range children {
+		if layoutNodeBlockId(child) != blockId {
+			if pos, found := findLayoutPos(child, blockId); found {
+				return pos, true
+			}
+			continue
+		}
+		pos := &wshrpc.TrashLayoutPos{ActionType: LayoutActionDataType_SplitVertical}
+		if flexDirection, _ := nodeMap["flexDirection"].(string); flexDirection == "row" {
+			pos.ActionType = LayoutActionDataType_SplitHorizontal
+		}
+		childMap, _ := child.(map[string]any)
+		if size, ok := childMap["size"].(float64); ok && size > 0 {
+			nodeSize := uint(size)
+			pos.NodeSize = &nodeSize
+		}
+		if idx > 0 && layoutNodeBlockId(children[idx-1]) != "" {
+			pos.TargetBlockId = layoutNodeBlockId(children[idx-1])
+			pos.Position = "after"
+		} else if idx+1 < len(children) && layoutNodeBlockId(children[idx+1]) != "" {
+			pos.TargetBlockId = layoutNodeBlockId(children[idx+1])
+			pos.Position = "before"
+		}
+		return pos, true
+	}
+	return nil, false
+}
+
+type recentLayoutPos struct {
+	pos *wshrpc.TrashLayoutPos
+	ts  time.Time
+}
+
+var recentLayoutLock = &sync.Mutex{}
+var recentLayoutPositions = make(map[string]recentLayoutPos)
+
+func getBlockLayoutPos(ctx context.Context, layoutStateId string, blockId string) *wshrpc.TrashLayoutPos {
+	layoutState, _ := wstore.DBGet[*waveobj.LayoutState](ctx, layoutStateId)
+	if layoutState != nil {
+		if pos, found := findLayoutPos(layoutState.RootNode, blockId); found {
+			return pos
+		}
+	}
+	recentLayoutLock.Lock()
+	defer recentLayoutLock.Unlock()
+	recent, ok := recentLayoutPositions[blockId]
+	if !ok || time.Since(recent.ts) > recentLayoutPosTTL {
+		return nil
+	}
+	delete(recentLayoutPositions, blockId)
+	return recent.pos
+}
+
+// called after the frontend saved a layout.  the frontend removes a closed block from its layout before the block
+// is deleted, so the positions of removed blocks are remembered for a little while (for the trash).
+func NoteLayoutUpdate(oldLayout *waveobj.LayoutState, newLayout *waveobj.LayoutState) {
+	if !trashEnabled() {
+		return
+	}
+	oldBlockIds := collectLayoutBlockIds(oldLayout.RootNode, nil)
+	if len(oldBlockIds) == 0 {
+		return
+	}
+	newBlockIds := collectLayoutBlockIds(newLayout.RootNode, nil)
+	recentLayoutLock.Lock()
+	defer recentLayoutLock.Unlock()
+	for blockId, recent := range recentLayoutPositions {
+		if time.Since(recent.ts) > recentLayoutPosTTL {
+			delete(recentLayoutPositions, blockId)
+		}
+	}
+	for _, blockId := range oldBlockIds {
+		if slices.Contains(newBlockIds, blockId) {
+			continue
+		}
+		if pos, found := findLayoutPos(oldLayout.RootNode, blockId); found {
+			recentLayoutPositions[blockId] = recentLayoutPos{pos: pos, ts: time.Now()}
+		}
+	}
+}
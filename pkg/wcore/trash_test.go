@@ -0,0 +1,231 @@
+// Copyright 2025, Command Line Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package wcore
+
+import (
+	"context"
+	"encoding/json"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/wavetermdev/waveterm/pkg/filestore"
+	"github.com/wavetermdev/waveterm/pkg/waveobj"
+	"github.com/wavetermdev/waveterm/pkg/wshrpc"
+	"github.com/wavetermdev/waveterm/pkg/wstore"
+)
+
+// a row with block a, a column (b over c) and block d
+const testLayoutJson = `{"id":"root","flexDirection":"row","size":10,"children":[
+	{"id":"n1","flexDirection":"row","size":30,"data":{"blockId":"a"}},
+	{"id":"n2","flexDirection":"column","size":40,"children":[
+		{"id":"n3","flexDirection":"row","size":10,"data":{"blockId":"b"}},
+		{"id":"n4","flexDirection":"row","size":10,"data":{"blockId":"c"}}
+	]},
+	{"id":"n5","flexDirection":"row","size":30,"data":{"blockId":"d"}}
+]}`
+
+func TestFindLayoutPos(t *testing.T) {
+	var rootNode any
+	err := json.Unmarshal([]byte(testLayoutJson), &rootNode)
+	if err != nil {
+		t.Fatalf("error parsing layout: %v", err)
+	}
+	size := func(v uint) *uint { return &v }
+	tests := []struct {
+		blockId  string
+		expected *wshrpc.TrashLayoutPos
+	}{
+		{"a", &wshrpc.TrashLayoutPos{ActionType: LayoutActionDataType_SplitHorizontal, NodeSize: size(30)}},
+		{"b", &wshrpc.TrashLayoutPos{TargetBlockId: "c", Position: "before", ActionType: LayoutActionDataType_SplitVertical, NodeSize: size(10)}},
+		{"c", &wshrpc.TrashLayoutPos{TargetBlockId: "b", Position: "after", ActionType: LayoutActionDataType_SplitVertical, NodeSize: size(10)}},
+		{"d", &wshrpc.TrashLayoutPos{ActionType: LayoutActionDataType_SplitHorizontal, NodeSize: size(30)}},
+		{"e", nil},
+	}
+	for _, test := range tests {
+		pos, found := findLayoutPos(rootNode, test.blockId)
+		if found != (test.expected != nil) || !reflect.DeepEqual(pos, test.expected) {
+			t.Errorf("block %s: expected %+v, got %+v (found %v)", test.blockId, test.expected, pos, found)
+		}
+	}
+	blockIds := collectLayoutBlockIds(rootNode, nil)
+	if !reflect.DeepEqual(blockIds, []string{"a", "b", "c", "d"}) {
+		t.Errorf("unexpected layout blocks %v", blockIds)
+	}
+}
+
+func TestTrashRestore(t *testing.T) {
+	initTestDbs(t)
+	ctx, cancelFn := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancelFn()
+	ws, err := CreateWorkspace(ctx, "trash", "", "", false, true)
+	if err != nil {
+		t.Fatalf("error creating workspace: %v", err)
+	}
+	tabId, err := CreateTab(ctx, ws.OID, "closed", false, false, true)
+	if err != nil {
+		t.Fatalf("error creating tab: %v", err)
+	}
+	var blockIds []string
+	for idx := 0; idx < 2; idx++ {
+		block, err := createBlockObj(ctx, tabId, &waveobj.BlockDef{Meta: waveobj.MetaMapType{waveobj.MetaKey_View: "term"}}, nil)
+		if err != nil {
+			t.Fatalf("error creating block: %v", err)
+		}
+		blockIds = append(blockIds, block.OID)
+		err = filestore.WFS.MakeFile(ctx, block.OID, "term", nil, wshrpc.FileOpts{})
+		if err == nil {
+			err = filestore.WFS.WriteFile(ctx, block.OID, "term", []byte("output "+block.OID))
+		}
+		if err != nil {
+			t.Fatalf("error writing file: %v", err)
+		}
+	}
+	tab, _ := wstore.DBGet[*waveobj.Tab](ctx, tabId)
+	layoutNode := func(nodeId string, blockId string) any {
+		return map[string]any{"id": nodeId, "flexDirection": "row", "size": 50.0, "data": map[string]any{"blockId": blockId}}
+	}
+	setLayout := func(children ...any) {
+		t.Helper()
+		_, err := wstore.UpdateObject(ctx, waveobj.MakeORef(waveobj.OType_LayoutState, tab.LayoutState), func(obj waveobj.WaveObj) error {
+			obj.(*waveobj.LayoutState).RootNode = map[string]any{"id": "root", "flexDirection": "row", "children": children}
+			return nil
+		})
+		if err != nil {
+			t.Fatalf("error updating layout: %v", err)
+		}
+	}
+	setLayout(layoutNode("n1", blockIds[0]), layoutNode("n2", blockIds[1]))
+	checkFiles := func() {
+		t.Helper()
+		for _, blockId := range blockIds {
+			_, data, err := filestore.WFS.ReadFile(ctx, blockId, "term")
+			if err != nil || string(data) != "output "+blockId {
+				t.Errorf("file of block %s: got %q (err %v)", blockId, data, err)
+			}
+		}
+	}
+
+	// close the second block (the frontend removes it from the layout), then restore it next to the first block
+	err = DeleteBlock(ctx, blockIds[1], false)
+	if err != nil {
+		t.Fatalf("error deleting block: %v", err)
+	}
+	setLayout(layoutNode("n1", blockIds[0]))
+	entries, _ := ListTrash(ctx, wshrpc.CommandTrashListData{OType: waveobj.OType_Block})
+	if len(entries) != 1 || entries[0].OID != blockIds[1] || entries[0].Index != 1 {
+		t.Fatalf("unexpected trash entries: %v", entries)
+	}
+	checkFiles()
+	err = wstore.DBInsert(ctx, &waveobj.Client{OID: uuid.NewString(), TempOID: uuid.NewString()})
+	if err != nil {
+		t.Fatalf("error inserting client: %v", err)
+	}
+	liveIds, err := getLiveZoneIds(ctx)
+	if err != nil {
+		t.Fatalf("error getting live zone ids: %v", err)
+	}
+	if !liveIds[blockIds[1]] {
+		t.Errorf("the zone of the trashed block should be kept by the filestore gc")
+	}
+	_, err = RestoreTrash(ctx, wshrpc.CommandTrashRestoreData{TrashId: entries[0].TrashId})
+	if err != nil {
+		t.Fatalf("error restoring block: %v", err)
+	}
+	tab, _ = wstore.DBGet[*waveobj.Tab](ctx, tabId)
+	if !reflect.DeepEqual(tab.BlockIds, blockIds) {
+		t.Errorf("restored block ids: got %v", tab.BlockIds)
+	}
+	if block, _ := wstore.DBGet[*waveobj.Block](ctx, blockIds[1]); block == nil || block.ParentORef != waveobj.MakeORef(waveobj.OType_Tab, tabId).String() {
+		t.Errorf("restored block: %+v", block)
+	}
+	layout, _ := wstore.DBGet[*waveobj.LayoutState](ctx, tab.LayoutState)
+	nodeSize := uint(50)
+	expectedAction := waveobj.LayoutActionData{ActionType: LayoutActionDataType_SplitHorizontal, BlockId: blockIds[1], TargetBlockId: blockIds[0], Position: "after", NodeSize: &nodeSize, Focused: true}
+	if layout.PendingBackendActions == nil || len(*layout.PendingBackendActions) != 1 || !reflect.DeepEqual((*layout.PendingBackendActions)[0], expectedAction) {
+		t.Errorf("unexpected layout actions: %+v", layout.PendingBackendActions)
+	}
+	checkFiles()
+
+	// close the tab, then restore it (with its layout and blocks) at its old position
+	_, err = DeleteTab(ctx, ws.OID, tabId, false)
+	if err != nil {
+		t.Fatalf("error deleting tab: %v", err)
+	}
+	if tab, _ := wstore.DBGet[*waveobj.Tab](ctx, tabId); tab != nil {
+		t.Fatalf("tab was not deleted")
+	}
+	entries, _ = ListTrash(ctx, wshrpc.CommandTrashListData{OType: waveobj.OType_Tab})
+	if len(entries) != 1 || entries[0].OID != tabId || entries[0].NumBlocks != 2 {
+		t.Fatalf("unexpected trash entries: %v", entries)
+	}
+	checkFiles()
+	rtn, err := RestoreTrash(ctx, wshrpc.CommandTrashRestoreData{TrashId: entries[0].TrashId[:8]})
+	if err != nil {
+		t.Fatalf("error restoring tab: %v", err)
+	}
+	if rtn.WorkspaceId != ws.OID || rtn.NumBlocks != 2 {
+		t.Errorf("unexpected restore result: %+v", rtn)
+	}
+	// both tabs are pinned (created as on the initial launch), the restored tab goes back after the first one
+	ws, _ = GetWorkspace(ctx, ws.OID)
+	if len(ws.PinnedTabIds) != 2 || ws.PinnedTabIds[1] != tabId || ws.ActiveTabId != tabId {
+		t.Errorf("restored workspace: %+v", ws)
+	}
+	tab, _ = wstore.DBGet[*waveobj.Tab](ctx, tabId)
+	if tab == nil || !reflect.DeepEqual(tab.BlockIds, blockIds) {
+		t.Fatalf("restored tab: %+v", tab)
+	}
+	layout, _ = wstore.DBGet[*waveobj.LayoutState](ctx, tab.LayoutState)
+	if layout == nil || !reflect.DeepEqual(collectLayoutBlockIds(layout.RootNode, nil), []string{blockIds[0]}) {
+		t.Errorf("restored layout: %+v", layout)
+	}
+	checkFiles()
+	if entries, _ := ListTrash(ctx, wshrpc.CommandTrashListData{}); len(entries) != 0 {
+		t.Errorf("trash should be empty: %v", entries)
+	}
+}
+
+func TestTrashErrorKeepsObjects(t *testing.T) {
+	initTestDbs(t)
+	ctx, cancelFn := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancelFn()
+	ws, err := CreateWorkspace(ctx, "trash", "", "", false, true)
+	if err != nil {
+		t.Fatalf("error creating workspace: %v", err)
+	}
+	tabId := ws.PinnedTabIds[0]
+	block, err := createBlockObj(ctx, tabId, &waveobj.BlockDef{Meta: waveobj.MetaMapType{waveobj.MetaKey_View: "term"}}, nil)
+	if err != nil {
+		t.Fatalf("error creating block: %v", err)
+	}
+	// the trash can't be written to
+	err = wstore.WithTx(ctx, func(tx *wstore.TxWrap) error {
+		tx.Exec(`DROP TABLE db_trash`)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("error dropping trash table: %v", err)
+	}
+
+	// closing the block or the tab fails, nothing is deleted
+	err = DeleteBlock(ctx, block.OID, false)
+	if err == nil {
+		t.Errorf("expected an error deleting the block")
+	}
+	if block, _ := wstore.DBGet[*waveobj.Block](ctx, block.OID); block == nil {
+		t.Errorf("the block was deleted")
+	}
+	_, err = DeleteTab(ctx, ws.OID, tabId, false)
+	if err == nil {
+		t.Errorf("expected an error deleting the tab")
+	}
+	if tab, _ := wstore.DBGet[*waveobj.Tab](ctx, tabId); tab == nil || !reflect.DeepEqual(tab.BlockIds, []string{block.OID}) {
+		t.Errorf("the tab was changed: %+v", tab)
+	}
+	if ws, _ := GetWorkspace(ctx, ws.OID); ws == nil || len(ws.PinnedTabIds) != 1 {
+		t.Errorf("the tab was removed from the workspace: %+v", ws)
+	}
+}
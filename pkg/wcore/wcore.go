@@ -39,8 +39,13 @@ func EnsureInitialData() error {
 	}
 	if client.TempOID == "" {
 		log.Println("client.TempOID is empty")
-		client.TempOID = uuid.NewString()
-		err = wstore.DBUpdate(ctx, client)
+		tempOID := uuid.NewString()
+		client, err = UpdateClientData(ctx, func(client *waveobj.Client) error {
+			if client.TempOID == "" {
+				client.TempOID = tempOID
+			}
+			return nil
+		})
 		if err != nil {
 			return fmt.Errorf("error updating client: %w", err)
 		}
@@ -90,3 +95,19 @@ func GetClientData(ctx context.Context) (*waveobj.Client, error) {
 	}
 	return clientData, nil
 }
+
+// applies fn to the client and writes it back.  fn is applied again if the client was changed in between, so it
+// must only depend on the client it is passed.
+func UpdateClientData(ctx context.Context, fn func(client *waveobj.Client) error) (*waveobj.Client, error) {
+	clientData, err := GetClientData(ctx)
+	if err != nil {
+		return nil, err
+	}
+	obj, err := wstore.UpdateObject(ctx, waveobj.MakeORef(waveobj.OType_Client, clientData.OID), func(obj waveobj.WaveObj) error {
+		return fn(obj.(*waveobj.Client))
+	})
+	if err != nil {
+		return nil, err
+	}
+	return obj.(*waveobj.Client), nil
+}
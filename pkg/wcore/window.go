@@ -46,8 +46,10 @@ func SwitchWorkspace(ctx context.Context, windowId string, workspaceId string) (
 			return nil, err
 		}
 	}
-	window.WorkspaceId = workspaceId
-	err = wstore.DBUpdate(ctx, window)
+	_, err = wstore.UpdateObject(ctx, waveobj.MakeORef(waveobj.OType_Window, windowId), func(obj waveobj.WaveObj) error {
+		obj.(*waveobj.Window).WorkspaceId = workspaceId
+		return nil
+	})
 	if err != nil {
 		return nil, fmt.Errorf("error updating window: %w", err)
 	}
@@ -115,12 +117,10 @@ func CreateWindow(ctx context.Context, winSize *waveobj.WinSize, workspaceId str
 	if err != nil {
 		return nil, fmt.Errorf("error inserting window: %w", err)
 	}
-	client, err := GetClientData(ctx)
-	if err != nil {
-		return nil, fmt.Errorf("error getting client: %w", err)
-	}
-	client.WindowIds = append(client.WindowIds, windowId)
-	err = wstore.DBUpdate(ctx, client)
+	_, err = UpdateClientData(ctx, func(client *waveobj.Client) error {
+		client.WindowIds = append(client.WindowIds, windowId)
+		return nil
+	})
 	if err != nil {
 		return nil, fmt.Errorf("error updating client: %w", err)
 	}
@@ -149,12 +149,10 @@ func CloseWindow(ctx context.Context, windowId string, fromElectron bool) error
 	} else {
 		log.Printf("error getting window %s: %v\n", windowId, err)
 	}
-	client, err := wstore.DBGetSingleton[*waveobj.Client](ctx)
-	if err != nil {
-		return fmt.Errorf("error getting client: %w", err)
-	}
-	client.WindowIds = utilfn.RemoveElemFromSlice(client.WindowIds, windowId)
-	err = wstore.DBUpdate(ctx, client)
+	_, err = UpdateClientData(ctx, func(client *waveobj.Client) error {
+		client.WindowIds = utilfn.RemoveElemFromSlice(client.WindowIds, windowId)
+		return nil
+	})
 	if err != nil {
 		return fmt.Errorf("error updating client: %w", err)
 	}
@@ -193,17 +191,18 @@ func CheckAndFixWindow(ctx context.Context, windowId string) *waveobj.Window {
 
 func FocusWindow(ctx context.Context, windowId string) error {
 	log.Printf("FocusWindow %s\n", windowId)
-	client, err := GetClientData(ctx)
-	if err != nil {
-		log.Printf("error getting client data: %v\n", err)
-		return err
-	}
-	winIdx := utilfn.SliceIdx(client.WindowIds, windowId)
-	if winIdx == -1 {
-		log.Printf("window %s not found in client data\n", windowId)
+	_, err := UpdateClientData(ctx, func(client *waveobj.Client) error {
+		winIdx := utilfn.SliceIdx(client.WindowIds, windowId)
+		if winIdx == -1 {
+			log.Printf("window %s not found in client data\n", windowId)
+			return nil
+		}
+		client.WindowIds = utilfn.MoveSliceIdxToFront(client.WindowIds, winIdx)
+		log.Printf("client.WindowIds: %v\n", client.WindowIds)
 		return nil
+	})
+	if err != nil {
+		log.Printf("error updating client data: %v\n", err)
 	}
-	client.WindowIds = utilfn.MoveSliceIdxToFront(client.WindowIds, winIdx)
-	log.Printf("client.WindowIds: %v\n", client.WindowIds)
-	return wstore.DBUpdate(ctx, client)
+	return err
 }
@@ -0,0 +1,80 @@
+// Copyright 2025, Command Line Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package wcore
+
+import (
+	"context"
+	"reflect"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestConcurrentWindowUpdates(t *testing.T) {
+	initTestDbs(t)
+	ctx, cancelFn := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancelFn()
+	_, err := CreateClient(ctx)
+	if err != nil {
+		t.Fatalf("error creating client: %v", err)
+	}
+	ws, err := CreateWorkspace(ctx, "windows", "terminal", "", false, true)
+	if err != nil {
+		t.Fatalf("error creating workspace: %v", err)
+	}
+
+	// windows opened at the same time all have to be added to the client
+	const numWindows = 10
+	windowIds := make([]string, numWindows)
+	var wg sync.WaitGroup
+	for idx := 0; idx < numWindows; idx++ {
+		wg.Add(1)
+		go func(idx int) {
+			defer wg.Done()
+			window, err := CreateWindow(ctx, nil, ws.OID)
+			if err != nil {
+				t.Errorf("error creating window: %v", err)
+				return
+			}
+			windowIds[idx] = window.OID
+		}(idx)
+	}
+	wg.Wait()
+	client, err := GetClientData(ctx)
+	if err != nil {
+		t.Fatalf("error getting client: %v", err)
+	}
+	if len(client.WindowIds) != numWindows {
+		t.Fatalf("expected %d windows, got %v", numWindows, client.WindowIds)
+	}
+
+	// focusing and closing windows concurrently
+	for idx := 0; idx < numWindows; idx++ {
+		wg.Add(1)
+		go func(idx int) {
+			defer wg.Done()
+			var err error
+			if idx%2 == 0 {
+				err = FocusWindow(ctx, windowIds[idx])
+			} else {
+				err = CloseWindow(ctx, windowIds[idx], true)
+			}
+			if err != nil {
+				t.Errorf("error updating window %s: %v", windowIds[idx], err)
+			}
+		}(idx)
+	}
+	wg.Wait()
+	client, _ = GetClientData(ctx)
+	var expectedIds []string
+	for idx := 0; idx < numWindows; idx += 2 {
+		expectedIds = append(expectedIds, windowIds[idx])
+	}
+	sort.Strings(expectedIds)
+	sort.Strings(client.WindowIds)
+	if !reflect.DeepEqual(client.WindowIds, expectedIds) {
+		t.Errorf("expected windows %v, got %v", expectedIds, client.WindowIds)
+	}
+}
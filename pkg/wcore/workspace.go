@@ -5,6 +5,7 @@ package wcore
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log"
 	"time"
@@ -108,11 +109,33 @@ func UpdateWorkspace(ctx context.Context, workspaceId string, name string, icon
 		updated = true
 	}
 	if updated {
-		wstore.DBUpdate(ctx, ws)
+		name, icon, color := ws.Name, ws.Icon, ws.Color
+		ws, err = updateWorkspaceObj(ctx, workspaceId, func(ws *waveobj.Workspace) error {
+			ws.Name, ws.Icon, ws.Color = name, icon, color
+			return nil
+		})
+		if err != nil {
+			return nil, false, fmt.Errorf("error updating workspace: %w", err)
+		}
 	}
 	return ws, updated, nil
 }
 
+// applies fn to the workspace and writes it back.  fn is applied again if the workspace was changed in between, so
+// it must only depend on the workspace it is passed.
+func updateWorkspaceObj(ctx context.Context, workspaceId string, fn func(ws *waveobj.Workspace) error) (*waveobj.Workspace, error) {
+	obj, err := wstore.UpdateObject(ctx, waveobj.MakeORef(waveobj.OType_Workspace, workspaceId), func(obj waveobj.WaveObj) error {
+		return fn(obj.(*waveobj.Workspace))
+	})
+	if errors.Is(err, wstore.ErrNotFound) {
+		return nil, fmt.Errorf("workspace not found: %q", workspaceId)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return obj.(*waveobj.Workspace), nil
+}
+
 // If force is true, it will delete even if workspace is named.
 // If workspace is empty, it will be deleted, even if it is named.
 // Returns true if workspace was deleted, false if it was not deleted.
@@ -244,35 +267,41 @@ func CreateTab(ctx context.Context, workspaceId string, tabName string, activate
 }
 
 func createTabObj(ctx context.Context, workspaceId string, name string, pinned bool) (*waveobj.Tab, error) {
-	ws, err := GetWorkspace(ctx, workspaceId)
-	if err != nil {
-		return nil, fmt.Errorf("workspace %s not found: %w", workspaceId, err)
-	}
-	layoutStateId := uuid.NewString()
-	tab := &waveobj.Tab{
-		OID:         uuid.NewString(),
-		Name:        name,
-		BlockIds:    []string{},
-		LayoutState: layoutStateId,
-	}
-	layoutState := &waveobj.LayoutState{
-		OID: layoutStateId,
-	}
-	if pinned {
-		ws.PinnedTabIds = append(ws.PinnedTabIds, tab.OID)
-	} else {
-		ws.TabIds = append(ws.TabIds, tab.OID)
-	}
-	wstore.DBInsert(ctx, tab)
-	wstore.DBInsert(ctx, layoutState)
-	wstore.DBUpdate(ctx, ws)
-	return tab, nil
+	return wstore.WithTxRtn(ctx, func(tx *wstore.TxWrap) (*waveobj.Tab, error) {
+		ws, err := GetWorkspace(tx.Context(), workspaceId)
+		if err != nil {
+			return nil, fmt.Errorf("workspace %s not found: %w", workspaceId, err)
+		}
+		layoutStateId := uuid.NewString()
+		tab := &waveobj.Tab{
+			OID:         uuid.NewString(),
+			Name:        name,
+			BlockIds:    []string{},
+			LayoutState: layoutStateId,
+		}
+		layoutState := &waveobj.LayoutState{
+			OID: layoutStateId,
+		}
+		if pinned {
+			ws.PinnedTabIds = append(ws.PinnedTabIds, tab.OID)
+		} else {
+			ws.TabIds = append(ws.TabIds, tab.OID)
+		}
+		wstore.DBInsert(tx.Context(), tab)
+		wstore.DBInsert(tx.Context(), layoutState)
+		err = wstore.DBUpdate(tx.Context(), ws)
+		if err != nil {
+			return nil, fmt.Errorf("error updating workspace: %w", err)
+		}
+		return tab, nil
+	})
 }
 
 // Must delete all blocks individually first.
 // Also deletes LayoutState.
 // recursive: if true, will recursively close parent window, workspace, if they are empty.
 // Returns new active tab id, error.
+// The tab (with its layout, blocks and files) is moved to the trash unless trash:disabled is set.
 func DeleteTab(ctx context.Context, workspaceId string, tabId string, recursive bool) (string, error) {
 	ws, _ := wstore.DBGet[*waveobj.Workspace](ctx, workspaceId)
 	if ws == nil {
@@ -282,11 +311,7 @@ func DeleteTab(ctx context.Context, workspaceId string, tabId string, recursive
 	// ensure tab is in workspace
 	tabIdx := utilfn.FindStringInSlice(ws.TabIds, tabId)
 	tabIdxPinned := utilfn.FindStringInSlice(ws.PinnedTabIds, tabId)
-	if tabIdx != -1 {
-		ws.TabIds = append(ws.TabIds[:tabIdx], ws.TabIds[tabIdx+1:]...)
-	} else if tabIdxPinned != -1 {
-		ws.PinnedTabIds = append(ws.PinnedTabIds[:tabIdxPinned], ws.PinnedTabIds[tabIdxPinned+1:]...)
-	} else {
+	if tabIdx == -1 && tabIdxPinned == -1 {
 		return "", fmt.Errorf("tab %s not found in workspace %s", tabId, workspaceId)
 	}
 
@@ -295,27 +320,43 @@ func DeleteTab(ctx context.Context, workspaceId string, tabId string, recursive
 	if tab == nil {
 		return "", fmt.Errorf("tab not found: %q", tabId)
 	}
+	if trashEnabled() {
+		// the tab is not deleted if it cannot be restored
+		err := trashTab(ctx, workspaceId, tab, max(tabIdx, tabIdxPinned), tabIdx == -1)
+		if err != nil {
+			return "", fmt.Errorf("error moving tab %s to trash (set trash:disabled to close it without the trash): %w", tabId, err)
+		}
+	}
 	for _, blockId := range tab.BlockIds {
-		err := DeleteBlock(ctx, blockId, false)
+		err := deleteBlock(ctx, blockId, false, false)
 		if err != nil {
 			return "", fmt.Errorf("error deleting block %s: %w", blockId, err)
 		}
 	}
 
+	// the workspace can change while the blocks are deleted, so the tab is removed from a fresh copy.
 	// if the tab is active, determine new active tab
-	newActiveTabId := ws.ActiveTabId
-	if ws.ActiveTabId == tabId {
-		if len(ws.TabIds) > 0 && tabIdx != -1 {
-			newActiveTabId = ws.TabIds[max(0, min(tabIdx-1, len(ws.TabIds)-1))]
-		} else if len(ws.PinnedTabIds) > 0 {
-			newActiveTabId = ws.PinnedTabIds[0]
-		} else {
-			newActiveTabId = ""
+	var newActiveTabId string
+	_, err := updateWorkspaceObj(ctx, workspaceId, func(ws *waveobj.Workspace) error {
+		tabIdx := utilfn.FindStringInSlice(ws.TabIds, tabId)
+		ws.TabIds = utilfn.RemoveElemFromSlice(ws.TabIds, tabId)
+		ws.PinnedTabIds = utilfn.RemoveElemFromSlice(ws.PinnedTabIds, tabId)
+		newActiveTabId = ws.ActiveTabId
+		if ws.ActiveTabId == tabId {
+			if len(ws.TabIds) > 0 && tabIdx != -1 {
+				newActiveTabId = ws.TabIds[max(0, min(tabIdx-1, len(ws.TabIds)-1))]
+			} else if len(ws.PinnedTabIds) > 0 {
+				newActiveTabId = ws.PinnedTabIds[0]
+			} else {
+				newActiveTabId = ""
+			}
 		}
+		ws.ActiveTabId = newActiveTabId
+		return nil
+	})
+	if err != nil {
+		return "", fmt.Errorf("error updating workspace: %w", err)
 	}
-	ws.ActiveTabId = newActiveTabId
-
-	wstore.DBUpdate(ctx, ws)
 	wstore.DBDelete(ctx, waveobj.OType_Tab, tabId)
 	wstore.DBDelete(ctx, waveobj.OType_LayoutState, tab.LayoutState)
 
@@ -336,40 +377,39 @@ func DeleteTab(ctx context.Context, workspaceId string, tabId string, recursive
 
 func SetActiveTab(ctx context.Context, workspaceId string, tabId string) error {
 	if tabId != "" && workspaceId != "" {
-		workspace, err := GetWorkspace(ctx, workspaceId)
-		if err != nil {
-			return fmt.Errorf("workspace %s not found: %w", workspaceId, err)
-		}
 		tab, _ := wstore.DBGet[*waveobj.Tab](ctx, tabId)
 		if tab == nil {
 			return fmt.Errorf("tab not found: %q", tabId)
 		}
-		workspace.ActiveTabId = tabId
-		wstore.DBUpdate(ctx, workspace)
+		_, err := updateWorkspaceObj(ctx, workspaceId, func(workspace *waveobj.Workspace) error {
+			workspace.ActiveTabId = tabId
+			return nil
+		})
+		if err != nil {
+			return fmt.Errorf("error setting active tab: %w", err)
+		}
 	}
 	return nil
 }
 
 func ChangeTabPinning(ctx context.Context, workspaceId string, tabId string, pinned bool) error {
 	if tabId != "" && workspaceId != "" {
-		workspace, err := GetWorkspace(ctx, workspaceId)
-		if err != nil {
-			return fmt.Errorf("workspace %s not found: %w", workspaceId, err)
-		}
-		if pinned && utilfn.FindStringInSlice(workspace.PinnedTabIds, tabId) == -1 {
-			if utilfn.FindStringInSlice(workspace.TabIds, tabId) == -1 {
-				return fmt.Errorf("tab %s not found in workspace %s", tabId, workspaceId)
-			}
-			workspace.TabIds = utilfn.RemoveElemFromSlice(workspace.TabIds, tabId)
-			workspace.PinnedTabIds = append(workspace.PinnedTabIds, tabId)
-		} else if !pinned && utilfn.FindStringInSlice(workspace.PinnedTabIds, tabId) != -1 {
-			if utilfn.FindStringInSlice(workspace.PinnedTabIds, tabId) == -1 {
-				return fmt.Errorf("tab %s not found in workspace %s", tabId, workspaceId)
+		_, err := updateWorkspaceObj(ctx, workspaceId, func(workspace *waveobj.Workspace) error {
+			if pinned && utilfn.FindStringInSlice(workspace.PinnedTabIds, tabId) == -1 {
+				if utilfn.FindStringInSlice(workspace.TabIds, tabId) == -1 {
+					return fmt.Errorf("tab %s not found in workspace %s", tabId, workspaceId)
+				}
+				workspace.TabIds = utilfn.RemoveElemFromSlice(workspace.TabIds, tabId)
+				workspace.PinnedTabIds = append(workspace.PinnedTabIds, tabId)
+			} else if !pinned && utilfn.FindStringInSlice(workspace.PinnedTabIds, tabId) != -1 {
+				workspace.PinnedTabIds = utilfn.RemoveElemFromSlice(workspace.PinnedTabIds, tabId)
+				workspace.TabIds = append([]string{tabId}, workspace.TabIds...)
 			}
-			workspace.PinnedTabIds = utilfn.RemoveElemFromSlice(workspace.PinnedTabIds, tabId)
-			workspace.TabIds = append([]string{tabId}, workspace.TabIds...)
+			return nil
+		})
+		if err != nil {
+			return err
 		}
-		wstore.DBUpdate(ctx, workspace)
 	}
 	return nil
 }
@@ -382,14 +422,12 @@ func SendActiveTabUpdate(ctx context.Context, workspaceId string, newActiveTabId
 }
 
 func UpdateWorkspaceTabIds(ctx context.Context, workspaceId string, tabIds []string, pinnedTabIds []string) error {
-	ws, _ := wstore.DBGet[*waveobj.Workspace](ctx, workspaceId)
-	if ws == nil {
-		return fmt.Errorf("workspace not found: %q", workspaceId)
-	}
-	ws.TabIds = tabIds
-	ws.PinnedTabIds = pinnedTabIds
-	wstore.DBUpdate(ctx, ws)
-	return nil
+	_, err := updateWorkspaceObj(ctx, workspaceId, func(ws *waveobj.Workspace) error {
+		ws.TabIds = tabIds
+		ws.PinnedTabIds = pinnedTabIds
+		return nil
+	})
+	return err
 }
 
 func ListWorkspaces(ctx context.Context) (waveobj.WorkspaceList, error) {
@@ -442,44 +480,29 @@ func ListWorkspaces(ctx context.Context) (waveobj.WorkspaceList, error) {
 func SetIcon(workspaceId string, icon string) error {
 	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
 	defer cancel()
-	ws, e := wstore.DBGet[*waveobj.Workspace](ctx, workspaceId)
-	if e != nil {
-		return e
-	}
-	if ws == nil {
-		return fmt.Errorf("workspace not found: %q", workspaceId)
-	}
-	ws.Icon = icon
-	wstore.DBUpdate(ctx, ws)
-	return nil
+	_, err := updateWorkspaceObj(ctx, workspaceId, func(ws *waveobj.Workspace) error {
+		ws.Icon = icon
+		return nil
+	})
+	return err
 }
 
 func SetColor(workspaceId string, color string) error {
 	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
 	defer cancel()
-	ws, e := wstore.DBGet[*waveobj.Workspace](ctx, workspaceId)
-	if e != nil {
-		return e
-	}
-	if ws == nil {
-		return fmt.Errorf("workspace not found: %q", workspaceId)
-	}
-	ws.Color = color
-	wstore.DBUpdate(ctx, ws)
-	return nil
+	_, err := updateWorkspaceObj(ctx, workspaceId, func(ws *waveobj.Workspace) error {
+		ws.Color = color
+		return nil
+	})
+	return err
 }
 
 func SetName(workspaceId string, name string) error {
 	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
 	defer cancel()
-	ws, e := wstore.DBGet[*waveobj.Workspace](ctx, workspaceId)
-	if e != nil {
-		return e
-	}
-	if ws == nil {
-		return fmt.Errorf("workspace not found: %q", workspaceId)
-	}
-	ws.Name = name
-	wstore.DBUpdate(ctx, ws)
-	return nil
+	_, err := updateWorkspaceObj(ctx, workspaceId, func(ws *waveobj.Workspace) error {
+		ws.Name = name
+		return nil
+	})
+	return err
 }
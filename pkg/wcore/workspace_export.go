@@ -0,0 +1,413 @@
+// Copyright 2025, Command Line Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package wcore
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/wavetermdev/waveterm/pkg/filestore"
+	"github.com/wavetermdev/waveterm/pkg/wavebase"
+	"github.com/wavetermdev/waveterm/pkg/waveobj"
+	"github.com/wavetermdev/waveterm/pkg/wps"
+	"github.com/wavetermdev/waveterm/pkg/wshrpc"
+	"github.com/wavetermdev/waveterm/pkg/wstore"
+)
+
+// a workspace export is the complete object graph of a workspace (unlike a favorite, which only keeps the tab
+// configs).  importing it creates an independent copy, every object gets a new id and all references to the
+// old ids (tab and block lists, layouts, parent orefs, meta) are remapped.  encrypted blocks and files are
+// exported decrypted.  an export can come from someone else, so the imported blocks do not run their commands on
+// start, their schedules (cmd:schedule) are imported paused, and the meta that writes files or sends input
+// (term:recordpath, input triggers) is dropped.  commands only run once the user starts or resumes them.
+
+const WorkspaceExportVersion = 1
+
+type WorkspaceExport struct {
+	ExportVersion int                    `json:"exportversion"`
+	WaveVersion   string                 `json:"waveversion"`
+	CreatedTs     int64                  `json:"createdts"`
+	Workspace     *waveobj.Workspace     `json:"workspace"`
+	Tabs          []*waveobj.Tab         `json:"tabs"`
+	LayoutStates  []*waveobj.LayoutState `json:"layoutstates"`
+	Blocks        []*waveobj.Block       `json:"blocks"` // including sub-blocks
+	Files         []*WorkspaceExportFile `json:"files,omitempty"`
+}
+
+type WorkspaceExportFile struct {
+	ZoneId string          `json:"zoneid"` // the object the file belongs to
+	Name   string          `json:"name"`
+	Opts   wshrpc.FileOpts `json:"opts"`
+	Meta   wshrpc.FileMeta `json:"meta,omitempty"`
+	Offset int64           `json:"offset,omitempty"` // circular files: the offset of the data
+	Data64 string          `json:"data64"`
+}
+
+func (e *WorkspaceExport) numObjects() int {
+	return 1 + len(e.Tabs) + len(e.LayoutStates) + len(e.Blocks)
+}
+
+func ExportWorkspace(ctx context.Context, workspaceId string, withFiles bool) (*WorkspaceExport, error) {
+	ws, err := wstore.DBMustGet[*waveobj.Workspace](ctx, workspaceId)
+	if err != nil {
+		return nil, fmt.Errorf("error getting workspace %s: %w", workspaceId, err)
+	}
+	rtn := &WorkspaceExport{
+		ExportVersion: WorkspaceExportVersion,
+		WaveVersion:   wavebase.WaveVersion,
+		CreatedTs:     time.Now().UnixMilli(),
+		Workspace:     ws,
+	}
+	var blockIds []string
+	for _, tabId := range append(append([]string{}, ws.PinnedTabIds...), ws.TabIds...) {
+		tab, err := wstore.DBMustGet[*waveobj.Tab](ctx, tabId)
+		if err != nil {
+			return nil, fmt.Errorf("error getting tab %s: %w", tabId, err)
+		}
+		rtn.Tabs = append(rtn.Tabs, tab)
+		blockIds = append(blockIds, tab.BlockIds...)
+		if tab.LayoutState == "" {
+			continue
+		}
+		layout, err := wstore.DBGet[*waveobj.LayoutState](ctx, tab.LayoutState)
+		if err != nil {
+			return nil, fmt.Errorf("error getting layout for tab %s: %w", tabId, err)
+		}
+		if layout != nil {
+			rtn.LayoutStates = append(rtn.LayoutStates, layout)
+		}
+	}
+	// blocks and their sub-blocks
+	for len(blockIds) > 0 {
+		var subBlockIds []string
+		for _, blockId := range blockIds {
+			block, err := wstore.DBGet[*waveobj.Block](ctx, blockId)
+			if err != nil {
+				return nil, fmt.Errorf("error getting block %s: %w", blockId, err)
+			}
+			if block == nil {
+				continue
+			}
+			rtn.Blocks = append(rtn.Blocks, block)
+			subBlockIds = append(subBlockIds, block.SubBlockIds...)
+		}
+		blockIds = subBlockIds
+	}
+	if !withFiles {
+		return rtn, nil
+	}
+	zoneIds := []string{ws.OID}
+	for _, tab := range rtn.Tabs {
+		zoneIds = append(zoneIds, tab.OID)
+	}
+	for _, block := range rtn.Blocks {
+		zoneIds = append(zoneIds, block.OID)
+	}
+	for _, zoneId := range zoneIds {
+		files, err := filestore.WFS.ListFiles(ctx, zoneId)
+		if err != nil {
+			return nil, err
+		}
+		for _, file := range files {
+			offset, data, err := filestore.WFS.ReadFile(ctx, zoneId, file.Name)
+			if err != nil {
+				return nil, fmt.Errorf("error reading file %s/%s: %w", zoneId, file.Name, err)
+			}
+			rtn.Files = append(rtn.Files, &WorkspaceExportFile{
+				ZoneId: zoneId,
+				Name:   file.Name,
+				Opts:   file.Opts,
+				Meta:   file.Meta,
+				Offset: offset,
+				Data64: base64.StdEncoding.EncodeToString(data),
+			})
+		}
+	}
+	return rtn, nil
+}
+
+// maps the ids of the exported objects to new ids (ids that are not in the export are dropped)
+type exportIdMap map[string]string
+
+func (m exportIdMap) ids(oldIds []string) []string {
+	rtn := make([]string, 0, len(oldIds))
+	for _, oldId := range oldIds {
+		if newId, ok := m[oldId]; ok {
+			rtn = append(rtn, newId)
+		}
+	}
+	return rtn
+}
+
+// remaps the top-level meta values that are ids (or orefs) of exported objects
+func (m exportIdMap) meta(meta waveobj.MetaMapType) waveobj.MetaMapType {
+	if meta == nil {
+		return nil
+	}
+	rtn := make(waveobj.MetaMapType, len(meta))
+	for key, val := range meta {
+		rtn[key] = val
+		strVal, ok := val.(string)
+		if !ok || strVal == "" {
+			continue
+		}
+		if newId, ok := m[strVal]; ok {
+			rtn[key] = newId
+		} else if oref, err := waveobj.ParseORef(strVal); err == nil {
+			if newId, ok := m[oref.OID]; ok {
+				rtn[key] = waveobj.MakeORef(oref.OType, newId).String()
+			}
+		}
+	}
+	return rtn
+}
+
+// removes the meta that would write files or send input to a terminal on this machine (meta is a copy)
+func sanitizeImportMeta(meta waveobj.MetaMapType) waveobj.MetaMapType {
+	if meta == nil {
+		return nil
+	}
+	delete(meta, waveobj.MetaKey_TermRecordPath)
+	if triggers, ok := meta[waveobj.MetaKey_TermTriggers].([]any); ok {
+		keptTriggers := make([]any, 0, len(triggers))
+		for _, trigger := range triggers {
+			if triggerMap, ok := trigger.(map[string]any); ok && triggerMap["action"] == waveobj.TermTriggerAction_Input {
+				continue
+			}
+			keptTriggers = append(keptTriggers, trigger)
+		}
+		meta[waveobj.MetaKey_TermTriggers] = keptTriggers
+	}
+	return meta
+}
+
+// creates an independent copy of the exported workspace (with its tabs, layouts, blocks and files)
+func ImportWorkspace(ctx context.Context, export *WorkspaceExport) (*wshrpc.WorkspaceImportRtnData, error) {
+	if export.ExportVersion > WorkspaceExportVersion {
+		return nil, fmt.Errorf("export was made by a newer version of wave (%s, export version %d), this version supports up to %d", export.WaveVersion, export.ExportVersion, WorkspaceExportVersion)
+	}
+	if export.Workspace == nil {
+		return nil, fmt.Errorf("invalid export, no workspace")
+	}
+	idMap := exportIdMap{export.Workspace.OID: uuid.NewString()}
+	for _, tab := range export.Tabs {
+		idMap[tab.OID] = uuid.NewString()
+	}
+	for _, layout := range export.LayoutStates {
+		idMap[layout.OID] = uuid.NewString()
+	}
+	for _, block := range export.Blocks {
+		idMap[block.OID] = uuid.NewString()
+	}
+	oldWs := export.Workspace
+	ws := &waveobj.Workspace{
+		OID:          idMap[oldWs.OID],
+		Name:         oldWs.Name,
+		Icon:         oldWs.Icon,
+		Color:        oldWs.Color,
+		TabIds:       idMap.ids(oldWs.TabIds),
+		PinnedTabIds: idMap.ids(oldWs.PinnedTabIds),
+		ActiveTabId:  idMap[oldWs.ActiveTabId],
+		Meta:         sanitizeImportMeta(idMap.meta(oldWs.Meta)),
+	}
+	if ws.ActiveTabId == "" {
+		if len(ws.PinnedTabIds) > 0 {
+			ws.ActiveTabId = ws.PinnedTabIds[0]
+		} else if len(ws.TabIds) > 0 {
+			ws.ActiveTabId = ws.TabIds[0]
+		}
+	}
+	objs := []waveobj.WaveObj{ws}
+	for _, oldTab := range export.Tabs {
+		tab := &waveobj.Tab{
+			OID:         idMap[oldTab.OID],
+			Name:        oldTab.Name,
+			LayoutState: idMap[oldTab.LayoutState],
+			BlockIds:    idMap.ids(oldTab.BlockIds),
+			Meta:        sanitizeImportMeta(idMap.meta(oldTab.Meta)),
+		}
+		if tab.LayoutState == "" {
+			// no layout in the export, the tab's blocks are inserted when the layout is loaded
+			actions := make([]waveobj.LayoutActionData, 0, len(tab.BlockIds))
+			for _, blockId := range tab.BlockIds {
+				actions = append(actions, waveobj.LayoutActionData{ActionType: LayoutActionDataType_Insert, BlockId: blockId})
+			}
+			tab.LayoutState = uuid.NewString()
+			objs = append(objs, &waveobj.LayoutState{OID: tab.LayoutState, PendingBackendActions: &actions})
+		}
+		objs = append(objs, tab)
+	}
+	for _, oldLayout := range export.LayoutStates {
+		objs = append(objs, importLayoutState(oldLayout, idMap))
+	}
+	for _, oldBlock := range export.Blocks {
+		block := &waveobj.Block{
+			OID:         idMap[oldBlock.OID],
+			RuntimeOpts: oldBlock.RuntimeOpts,
+			Stickers:    oldBlock.Stickers,
+			Meta:        sanitizeImportMeta(idMap.meta(oldBlock.Meta)),
+			SubBlockIds: idMap.ids(oldBlock.SubBlockIds),
+		}
+		if block.Meta.GetString(waveobj.MetaKey_Controller, "") != "" {
+			// commands only run when the user starts them
+			block.Meta[waveobj.MetaKey_CmdRunOnStart] = false
+			block.Meta[waveobj.MetaKey_CmdRunOnce] = false
+		}
+		if block.Meta.GetString(waveobj.MetaKey_CmdSchedule, "") != "" {
+			// the scheduler picks up any block with a schedule, it has to be resumed with wsh schedule
+			block.Meta[waveobj.MetaKey_CmdSchedulePaused] = true
+		}
+		if parentORef, err := waveobj.ParseORef(oldBlock.ParentORef); err == nil {
+			if newId, ok := idMap[parentORef.OID]; ok {
+				block.ParentORef = waveobj.MakeORef(parentORef.OType, newId).String()
+			}
+		}
+		if block.ParentORef == "" {
+			return nil, fmt.Errorf("invalid export, block %s has no parent", oldBlock.OID)
+		}
+		objs = append(objs, block)
+	}
+	err := wstore.WithTx(ctx, func(tx *wstore.TxWrap) error {
+		for _, obj := range objs {
+			err := wstore.DBInsert(tx.Context(), obj)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error importing workspace: %w", err)
+	}
+	if len(ws.TabIds) == 0 && len(ws.PinnedTabIds) == 0 {
+		_, err = CreateTab(ctx, ws.OID, "", true, false, false)
+		if err != nil {
+			return nil, fmt.Errorf("error creating tab: %w", err)
+		}
+	}
+	numFiles := 0
+	for _, file := range export.Files {
+		zoneId, ok := idMap[file.ZoneId]
+		if !ok {
+			continue
+		}
+		err = importFile(ctx, zoneId, file)
+		if err != nil {
+			// the objects are already imported, a file that cannot be written is skipped
+			log.Printf("error importing file %s/%s: %v\n", file.ZoneId, file.Name, err)
+			continue
+		}
+		numFiles++
+	}
+	wps.Broker.Publish(wps.WaveEvent{
+		Event: wps.Event_WorkspaceUpdate,
+	})
+	log.Printf("imported workspace %s as %s (%d objects, %d files)\n", oldWs.OID, ws.OID, len(objs), numFiles)
+	return &wshrpc.WorkspaceImportRtnData{
+		WorkspaceId: ws.OID,
+		Name:        ws.Name,
+		NumObjects:  len(objs),
+		NumFiles:    numFiles,
+	}, nil
+}
+
+func importLayoutState(oldLayout *waveobj.LayoutState, idMap exportIdMap) *waveobj.LayoutState {
+	// node ids are local to the layout, only the block ids are remapped
+	layout := &waveobj.LayoutState{
+		OID:             idMap[oldLayout.OID],
+		RootNode:        updateLayoutNodeBlockIds(oldLayout.RootNode, idMap),
+		MagnifiedNodeId: oldLayout.MagnifiedNodeId,
+		FocusedNodeId:   oldLayout.FocusedNodeId,
+		Meta:            idMap.meta(oldLayout.Meta),
+	}
+	if oldLayout.LeafOrder != nil {
+		leafOrder := make([]waveobj.LeafOrderEntry, 0, len(*oldLayout.LeafOrder))
+		for _, entry := range *oldLayout.LeafOrder {
+			if newId, ok := idMap[entry.BlockId]; ok {
+				leafOrder = append(leafOrder, waveobj.LeafOrderEntry{NodeId: entry.NodeId, BlockId: newId})
+			}
+		}
+		layout.LeafOrder = &leafOrder
+	}
+	if oldLayout.PendingBackendActions != nil {
+		actions := make([]waveobj.LayoutActionData, 0, len(*oldLayout.PendingBackendActions))
+		for _, action := range *oldLayout.PendingBackendActions {
+			newId, ok := idMap[action.BlockId]
+			if !ok {
+				continue
+			}
+			action.BlockId = newId
+			if action.TargetBlockId != "" {
+				action.TargetBlockId = idMap[action.TargetBlockId]
+			}
+			actions = append(actions, action)
+		}
+		layout.PendingBackendActions = &actions
+	}
+	return layout
+}
+
+func importFile(ctx context.Context, zoneId string, file *WorkspaceExportFile) error {
+	data, err := base64.StdEncoding.DecodeString(file.Data64)
+	if err != nil {
+		return fmt.Errorf("error decoding data: %w", err)
+	}
+	err = filestore.WFS.MakeFile(ctx, zoneId, file.Name, file.Meta, file.Opts)
+	if err != nil {
+		return err
+	}
+	return filestore.WFS.WriteFileAt(ctx, zoneId, file.Name, file.Offset, data)
+}
+
+func ExportWorkspaceToFile(ctx context.Context, data wshrpc.CommandWorkspaceExportData) (*wshrpc.WorkspaceExportRtnData, error) {
+	if !filepath.IsAbs(data.Path) {
+		return nil, fmt.Errorf("export path must be absolute")
+	}
+	export, err := ExportWorkspace(ctx, data.WorkspaceId, data.Files)
+	if err != nil {
+		return nil, err
+	}
+	barr, err := json.MarshalIndent(export, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("error encoding export: %w", err)
+	}
+	tmpName := data.Path + ".tmp"
+	err = os.WriteFile(tmpName, barr, 0600)
+	if err == nil {
+		err = os.Rename(tmpName, data.Path)
+	}
+	if err != nil {
+		os.Remove(tmpName)
+		return nil, fmt.Errorf("error writing export: %w", err)
+	}
+	return &wshrpc.WorkspaceExportRtnData{
+		Path:       data.Path,
+		Size:       int64(len(barr)),
+		NumObjects: export.numObjects(),
+		NumFiles:   len(export.Files),
+	}, nil
+}
+
+func ImportWorkspaceFromFile(ctx context.Context, data wshrpc.CommandWorkspaceImportData) (*wshrpc.WorkspaceImportRtnData, error) {
+	if !filepath.IsAbs(data.Path) {
+		return nil, fmt.Errorf("import path must be absolute")
+	}
+	barr, err := os.ReadFile(data.Path)
+	if err != nil {
+		return nil, fmt.Errorf("error reading export: %w", err)
+	}
+	var export WorkspaceExport
+	err = json.Unmarshal(barr, &export)
+	if err != nil {
+		return nil, fmt.Errorf("invalid export file: %w", err)
+	}
+	return ImportWorkspace(ctx, &export)
+}
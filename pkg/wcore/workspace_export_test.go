@@ -0,0 +1,205 @@
+// Copyright 2025, Command Line Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package wcore
+
+import (
+	"context"
+	"encoding/json"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/wavetermdev/waveterm/pkg/filestore"
+	"github.com/wavetermdev/waveterm/pkg/waveobj"
+	"github.com/wavetermdev/waveterm/pkg/wshrpc"
+	"github.com/wavetermdev/waveterm/pkg/wstore"
+)
+
+func TestImportLayoutState(t *testing.T) {
+	var rootNode any
+	err := json.Unmarshal([]byte(testLayoutJson), &rootNode)
+	if err != nil {
+		t.Fatalf("error parsing layout: %v", err)
+	}
+	idMap := exportIdMap{"layout": "layout2", "a": "a2", "b": "b2", "c": "c2", "d": "d2"}
+	oldLayout := &waveobj.LayoutState{
+		OID:           "layout",
+		Version:       5,
+		RootNode:      rootNode,
+		FocusedNodeId: "n3",
+		LeafOrder:     &[]waveobj.LeafOrderEntry{{NodeId: "n1", BlockId: "a"}, {NodeId: "n3", BlockId: "b"}, {NodeId: "nx", BlockId: "x"}},
+		PendingBackendActions: &[]waveobj.LayoutActionData{
+			{ActionType: LayoutActionDataType_SplitVertical, BlockId: "c", TargetBlockId: "b", Position: "after"},
+			{ActionType: LayoutActionDataType_Insert, BlockId: "x"},
+		},
+	}
+	layout := importLayoutState(oldLayout, idMap)
+	if layout.OID != "layout2" || layout.Version != 0 || layout.FocusedNodeId != "n3" {
+		t.Errorf("unexpected layout: %+v", layout)
+	}
+	blockIds := collectLayoutBlockIds(layout.RootNode, nil)
+	if !reflect.DeepEqual(blockIds, []string{"a2", "b2", "c2", "d2"}) {
+		t.Errorf("root node block ids: got %v", blockIds)
+	}
+	expectedLeafOrder := []waveobj.LeafOrderEntry{{NodeId: "n1", BlockId: "a2"}, {NodeId: "n3", BlockId: "b2"}}
+	if !reflect.DeepEqual(*layout.LeafOrder, expectedLeafOrder) {
+		t.Errorf("leaf order: got %v", *layout.LeafOrder)
+	}
+	expectedActions := []waveobj.LayoutActionData{{ActionType: LayoutActionDataType_SplitVertical, BlockId: "c2", TargetBlockId: "b2", Position: "after"}}
+	if !reflect.DeepEqual(*layout.PendingBackendActions, expectedActions) {
+		t.Errorf("pending actions: got %v", *layout.PendingBackendActions)
+	}
+	// the old layout is unchanged
+	if (*oldLayout.LeafOrder)[0].BlockId != "a" || (*oldLayout.PendingBackendActions)[0].BlockId != "c" {
+		t.Errorf("old layout was modified")
+	}
+}
+
+func TestExportIdMapMeta(t *testing.T) {
+	oldId, newId, otherId := uuid.NewString(), uuid.NewString(), uuid.NewString()
+	idMap := exportIdMap{oldId: newId}
+	meta := waveobj.MetaMapType{
+		"view":   "term",
+		"target": oldId,
+		"parent": "block:" + oldId,
+		"other":  "block:" + otherId,
+		"size":   12.0,
+	}
+	expected := waveobj.MetaMapType{
+		"view":   "term",
+		"target": newId,
+		"parent": "block:" + newId,
+		"other":  "block:" + otherId,
+		"size":   12.0,
+	}
+	if got := idMap.meta(meta); !reflect.DeepEqual(got, expected) {
+		t.Errorf("meta: got %v, expected %v", got, expected)
+	}
+	if idMap.meta(nil) != nil {
+		t.Errorf("nil meta should stay nil")
+	}
+	if got := idMap.ids([]string{otherId, oldId}); !reflect.DeepEqual(got, []string{newId}) {
+		t.Errorf("ids: got %v", got)
+	}
+}
+
+func TestExportImportWorkspace(t *testing.T) {
+	initTestDbs(t)
+	ctx, cancelFn := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancelFn()
+	ws, err := CreateWorkspace(ctx, "export", "", "", false, true)
+	if err != nil {
+		t.Fatalf("error creating workspace: %v", err)
+	}
+	tabId := ws.PinnedTabIds[0]
+	block, err := createBlockObj(ctx, tabId, &waveobj.BlockDef{Meta: waveobj.MetaMapType{
+		waveobj.MetaKey_View:           "term",
+		waveobj.MetaKey_Controller:     "cmd",
+		waveobj.MetaKey_Cmd:            "make run",
+		waveobj.MetaKey_CmdSchedule:    "*/5 * * * *",
+		waveobj.MetaKey_TermRecordPath: "/tmp/rec.cast",
+		waveobj.MetaKey_TermTriggers: []any{
+			map[string]any{"pattern": "password:", "action": waveobj.TermTriggerAction_Input, "input": "x\n"},
+			map[string]any{"pattern": "done", "action": waveobj.TermTriggerAction_Notify},
+		},
+	}}, nil)
+	if err != nil {
+		t.Fatalf("error creating block: %v", err)
+	}
+	subBlock, err := createSubBlockObj(ctx, block.OID, &waveobj.BlockDef{Meta: waveobj.MetaMapType{waveobj.MetaKey_View: "preview"}})
+	if err != nil {
+		t.Fatalf("error creating sub block: %v", err)
+	}
+	err = filestore.WFS.MakeFile(ctx, block.OID, "term", nil, wshrpc.FileOpts{})
+	if err != nil {
+		t.Fatalf("error making file: %v", err)
+	}
+	err = filestore.WFS.WriteFile(ctx, block.OID, "term", []byte("terminal output"))
+	if err != nil {
+		t.Fatalf("error writing file: %v", err)
+	}
+
+	export, err := ExportWorkspace(ctx, ws.OID, true)
+	if err != nil {
+		t.Fatalf("error exporting workspace: %v", err)
+	}
+	// through json, like an export file
+	barr, err := json.Marshal(export)
+	if err != nil {
+		t.Fatalf("error marshaling export: %v", err)
+	}
+	var fileExport WorkspaceExport
+	err = json.Unmarshal(barr, &fileExport)
+	if err != nil {
+		t.Fatalf("error parsing export: %v", err)
+	}
+	rtn, err := ImportWorkspace(ctx, &fileExport)
+	if err != nil {
+		t.Fatalf("error importing workspace: %v", err)
+	}
+	if rtn.WorkspaceId == ws.OID || rtn.NumObjects != 5 || rtn.NumFiles != 1 {
+		t.Errorf("unexpected import result: %+v", rtn)
+	}
+	newWs, err := GetWorkspace(ctx, rtn.WorkspaceId)
+	if err != nil {
+		t.Fatalf("error getting imported workspace: %v", err)
+	}
+	if len(newWs.PinnedTabIds) != 1 || newWs.PinnedTabIds[0] == tabId || newWs.ActiveTabId != newWs.PinnedTabIds[0] {
+		t.Fatalf("unexpected imported workspace: %+v", newWs)
+	}
+	newTab, err := wstore.DBMustGet[*waveobj.Tab](ctx, newWs.PinnedTabIds[0])
+	if err != nil {
+		t.Fatalf("error getting imported tab: %v", err)
+	}
+	if len(newTab.BlockIds) != 1 || newTab.BlockIds[0] == block.OID {
+		t.Fatalf("unexpected imported tab: %+v", newTab)
+	}
+	oldTab, _ := wstore.DBGet[*waveobj.Tab](ctx, tabId)
+	if layout, _ := wstore.DBGet[*waveobj.LayoutState](ctx, newTab.LayoutState); layout == nil || newTab.LayoutState == oldTab.LayoutState {
+		t.Errorf("layout %s of the imported tab not found", newTab.LayoutState)
+	}
+	newBlock, err := wstore.DBMustGet[*waveobj.Block](ctx, newTab.BlockIds[0])
+	if err != nil {
+		t.Fatalf("error getting imported block: %v", err)
+	}
+	if newBlock.ParentORef != waveobj.MakeORef(waveobj.OType_Tab, newTab.OID).String() {
+		t.Errorf("block parent: got %s", newBlock.ParentORef)
+	}
+	if len(newBlock.SubBlockIds) != 1 || newBlock.SubBlockIds[0] == subBlock.OID {
+		t.Fatalf("unexpected sub blocks: %v", newBlock.SubBlockIds)
+	}
+	newSubBlock, err := wstore.DBMustGet[*waveobj.Block](ctx, newBlock.SubBlockIds[0])
+	if err != nil {
+		t.Fatalf("error getting imported sub block: %v", err)
+	}
+	if newSubBlock.ParentORef != waveobj.MakeORef(waveobj.OType_Block, newBlock.OID).String() {
+		t.Errorf("sub block parent: got %s", newSubBlock.ParentORef)
+	}
+
+	// the imported block does not run its command, record to the exported path or send input
+	meta := newBlock.Meta
+	if meta.GetString(waveobj.MetaKey_Cmd, "") != "make run" || meta.GetBool(waveobj.MetaKey_CmdRunOnStart, true) || meta.GetBool(waveobj.MetaKey_CmdRunOnce, true) {
+		t.Errorf("unexpected cmd meta: %v", meta)
+	}
+	if meta.GetString(waveobj.MetaKey_CmdSchedule, "") != "*/5 * * * *" || !meta.GetBool(waveobj.MetaKey_CmdSchedulePaused, false) {
+		t.Errorf("the schedule should be imported paused: %v", meta)
+	}
+	if _, ok := meta[waveobj.MetaKey_TermRecordPath]; ok {
+		t.Errorf("term:recordpath was imported")
+	}
+	triggers, _ := meta[waveobj.MetaKey_TermTriggers].([]any)
+	if len(triggers) != 1 || triggers[0].(map[string]any)["action"] != waveobj.TermTriggerAction_Notify {
+		t.Errorf("unexpected triggers: %v", triggers)
+	}
+	// the exported workspace is unchanged
+	if oldBlock, _ := wstore.DBGet[*waveobj.Block](ctx, block.OID); oldBlock == nil || oldBlock.Meta.GetString(waveobj.MetaKey_TermRecordPath, "") == "" {
+		t.Errorf("exported block was modified: %+v", oldBlock)
+	}
+
+	_, data, err := filestore.WFS.ReadFile(ctx, newBlock.OID, "term")
+	if err != nil || string(data) != "terminal output" {
+		t.Errorf("imported file: got %q (err %v)", data, err)
+	}
+}
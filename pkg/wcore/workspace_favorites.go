@@ -519,20 +519,8 @@ func restoreTabFromFavorite(ctx context.Context, workspaceId string, defaultTab
 		}
 	}
 	
-	// 7. 将标签页添加到工作区
-	workspace, err := wstore.DBGet[*waveobj.Workspace](ctx, workspaceId)
-	if err != nil {
-		return "", fmt.Errorf("workspace not found: %w", err)
-	}
-	
-	if defaultTab.Pinned {
-		workspace.PinnedTabIds = append(workspace.PinnedTabIds, tabId)
-	} else {
-		workspace.TabIds = append(workspace.TabIds, tabId)
-	}
-	
-	// 8. 保存到数据库
-	err = wstore.DBInsert(ctx, tab)
+	// 7. 保存到数据库
+	err := wstore.DBInsert(ctx, tab)
 	if err != nil {
 		return "", fmt.Errorf("failed to insert tab: %w", err)
 	}
@@ -549,7 +537,15 @@ func restoreTabFromFavorite(ctx context.Context, workspaceId string, defaultTab
 		}
 	}
 	
-	err = wstore.DBUpdate(ctx, workspace)
+	// 8. 将标签页添加到工作区
+	_, err = updateWorkspaceObj(ctx, workspaceId, func(ws *waveobj.Workspace) error {
+		if defaultTab.Pinned {
+			ws.PinnedTabIds = append(ws.PinnedTabIds, tabId)
+		} else {
+			ws.TabIds = append(ws.TabIds, tabId)
+		}
+		return nil
+	})
 	if err != nil {
 		return "", fmt.Errorf("failed to update workspace: %w", err)
 	}
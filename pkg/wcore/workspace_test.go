@@ -0,0 +1,80 @@
+// Copyright 2025, Command Line Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package wcore
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/wavetermdev/waveterm/pkg/filestore"
+	"github.com/wavetermdev/waveterm/pkg/wavebase"
+	"github.com/wavetermdev/waveterm/pkg/waveobj"
+	"github.com/wavetermdev/waveterm/pkg/wstore"
+)
+
+// opens a wstore and a filestore db in a temp data dir (with an empty config dir)
+func initTestDbs(t *testing.T) {
+	wavebase.DataHome_VarCache = t.TempDir()
+	wavebase.ConfigHome_VarCache = t.TempDir()
+	err := os.MkdirAll(filepath.Join(wavebase.DataHome_VarCache, wavebase.WaveDBDir), 0700)
+	if err != nil {
+		t.Fatalf("error creating db dir: %v", err)
+	}
+	err = wstore.InitWStore()
+	if err != nil {
+		t.Fatalf("error initializing wstore: %v", err)
+	}
+	err = filestore.InitFilestore()
+	if err != nil {
+		t.Fatalf("error initializing filestore: %v", err)
+	}
+}
+
+func TestWorkspaceTabUpdates(t *testing.T) {
+	initTestDbs(t)
+	ctx, cancelFn := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancelFn()
+	ws, err := CreateWorkspace(ctx, "test", "", "", false, true)
+	if err != nil {
+		t.Fatalf("error creating workspace: %v", err)
+	}
+	tab1 := ws.PinnedTabIds[0]
+	tab2, err := CreateTab(ctx, ws.OID, "", true, false, true)
+	if err != nil {
+		t.Fatalf("error creating tab: %v", err)
+	}
+	tab3, err := CreateTab(ctx, ws.OID, "", false, false, true)
+	if err != nil {
+		t.Fatalf("error creating tab: %v", err)
+	}
+	err = ChangeTabPinning(ctx, ws.OID, tab3, true)
+	if err != nil {
+		t.Fatalf("error pinning tab: %v", err)
+	}
+	err = SetName(ws.OID, "renamed")
+	if err != nil {
+		t.Fatalf("error renaming workspace: %v", err)
+	}
+	newActiveTabId, err := DeleteTab(ctx, ws.OID, tab2, false)
+	if err != nil {
+		t.Fatalf("error deleting tab: %v", err)
+	}
+	if newActiveTabId != tab1 {
+		t.Errorf("expected active tab %s, got %s", tab1, newActiveTabId)
+	}
+	ws, _ = GetWorkspace(ctx, ws.OID)
+	if ws.Name != "renamed" || ws.ActiveTabId != tab1 || len(ws.TabIds) != 0 || !reflect.DeepEqual(ws.PinnedTabIds, []string{tab1, tab3}) {
+		t.Errorf("unexpected workspace: %+v", ws)
+	}
+	if tab, _ := wstore.DBGet[*waveobj.Tab](ctx, tab2); tab != nil {
+		t.Errorf("tab %s was not deleted", tab2)
+	}
+	if err := SetName("not-a-workspace", "x"); err == nil {
+		t.Errorf("expected an error renaming a missing workspace")
+	}
+}
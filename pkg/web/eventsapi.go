@@ -0,0 +1,68 @@
+// Copyright 2025, Command Line Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+// REST API handlers for the event journal
+package web
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/wavetermdev/waveterm/pkg/wcore"
+	"github.com/wavetermdev/waveterm/pkg/wshrpc"
+)
+
+// handleEventsAPI routes event journal API requests
+//
+//	GET /api/v1/events  - journaled events after a seq, in seq order
+//	                      (?since=seq&event=name&scope=scope&limit=N, event and scope can be repeated)
+//
+// the response has "lastseq" (the newest seq in the journal) and "more" (call again with since set to the seq of
+// the last event to get the rest).  events can carry terminal output, so requests must have the X-AuthKey header
+// (the route is wrapped with WebFnWrap).
+func handleEventsAPI(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	path := strings.Trim(strings.TrimPrefix(r.URL.Path, "/api/v1/events"), "/")
+	if path != "" {
+		http.Error(w, "Not Found", http.StatusNotFound)
+		return
+	}
+	if r.Method != "GET" {
+		http.Error(w, "Method Not Allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	query := r.URL.Query()
+	data := wshrpc.CommandEventReplayData{Events: query["event"], Scopes: query["scope"]}
+	var err error
+	if sinceStr := query.Get("since"); sinceStr != "" {
+		data.SinceSeq, err = strconv.ParseInt(sinceStr, 10, 64)
+		if err != nil || data.SinceSeq < 0 {
+			writeErrorResponse(w, "Invalid since parameter", http.StatusBadRequest)
+			return
+		}
+	}
+	if limitStr := query.Get("limit"); limitStr != "" {
+		data.MaxItems, err = strconv.Atoi(limitStr)
+		if err != nil || data.MaxItems < 0 {
+			writeErrorResponse(w, "Invalid limit parameter", http.StatusBadRequest)
+			return
+		}
+	}
+	rtn, err := wcore.ReplayEvents(r.Context(), data)
+	if err != nil {
+		log.Printf("Error replaying events: %v", err)
+		writeErrorResponse(w, "Internal server error: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	json.NewEncoder(w).Encode(map[string]any{
+		"success":  true,
+		"events":   rtn.Events,
+		"firstseq": rtn.FirstSeq,
+		"lastseq":  rtn.LastSeq,
+		"more":     rtn.More,
+		"enabled":  rtn.Enabled,
+	})
+}
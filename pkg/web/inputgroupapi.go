@@ -0,0 +1,180 @@
+// Copyright 2025, Command Line Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+// REST API handlers for input broadcast groups
+package web
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"mime"
+	"net/http"
+	"strings"
+
+	"github.com/wavetermdev/waveterm/pkg/blockcontroller"
+	"github.com/wavetermdev/waveterm/pkg/wshrpc"
+)
+
+// InputGroupAPIRequest is the body of the join, leave and input requests
+type InputGroupAPIRequest struct {
+	BlockIds []string `json:"block_ids,omitempty"` // join, leave (leave without blocks disbands the group)
+	Confirm  *bool    `json:"confirm,omitempty"`   // join
+	Data     string   `json:"data,omitempty"`      // input, sent as is (include "\r" to press enter)
+}
+
+type InputGroupAPIMember struct {
+	BlockId string `json:"block_id"`
+	Status  string `json:"status,omitempty"`
+	Confirm bool   `json:"confirm"`
+}
+
+type InputGroupAPIInfo struct {
+	Name    string                 `json:"name"`
+	Members []*InputGroupAPIMember `json:"members"`
+}
+
+// handleInputGroupAPI routes input group API requests
+//
+//	GET    /api/v1/inputgroups               - list groups
+//	POST   /api/v1/inputgroups/{name}/join   - add blocks to a group
+//	POST   /api/v1/inputgroups/{name}/leave  - remove blocks from a group
+//	POST   /api/v1/inputgroups/{name}/input  - send input to all blocks in a group
+//	DELETE /api/v1/inputgroups/{name}        - remove all blocks from a group
+//
+// requests must have the X-AuthKey header (the route is wrapped with WebFnWrap), and POST bodies must be sent
+// as application/json.
+func handleInputGroupAPI(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	path := strings.Trim(strings.TrimPrefix(r.URL.Path, "/api/v1/inputgroups"), "/")
+	var pathParts []string
+	if path != "" {
+		pathParts = strings.Split(path, "/")
+	}
+	ctx := r.Context()
+	switch {
+	case r.Method == "GET" && len(pathParts) == 0:
+		handleListInputGroups(w, ctx)
+	case r.Method == "POST" && len(pathParts) == 2:
+		if !isJsonContentType(r) {
+			writeErrorResponse(w, "Content-Type must be application/json", http.StatusUnsupportedMediaType)
+			return
+		}
+		var req InputGroupAPIRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeErrorResponse(w, "Invalid JSON request body", http.StatusBadRequest)
+			return
+		}
+		switch pathParts[1] {
+		case "join":
+			handleJoinInputGroup(w, ctx, pathParts[0], req)
+		case "leave":
+			handleLeaveInputGroup(w, ctx, pathParts[0], req.BlockIds)
+		case "input":
+			handleInputGroupInput(w, ctx, pathParts[0], req)
+		default:
+			http.Error(w, "Not Found", http.StatusNotFound)
+		}
+	case r.Method == "DELETE" && len(pathParts) == 1:
+		handleLeaveInputGroup(w, ctx, pathParts[0], nil)
+	case r.Method == "GET" || r.Method == "POST" || r.Method == "DELETE":
+		http.Error(w, "Not Found", http.StatusNotFound)
+	default:
+		http.Error(w, "Method Not Allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+func handleListInputGroups(w http.ResponseWriter, ctx context.Context) {
+	groups, err := blockcontroller.ListInputGroups(ctx)
+	if err != nil {
+		log.Printf("Error listing input groups: %v", err)
+		writeErrorResponse(w, fmt.Sprintf("Internal server error: %s", err.Error()), http.StatusInternalServerError)
+		return
+	}
+	apiGroups := make([]*InputGroupAPIInfo, 0, len(groups))
+	for _, group := range groups {
+		apiGroup := &InputGroupAPIInfo{Name: group.Name}
+		for _, member := range group.Members {
+			apiGroup.Members = append(apiGroup.Members, &InputGroupAPIMember{BlockId: member.BlockId, Status: member.Status, Confirm: member.Confirm})
+		}
+		apiGroups = append(apiGroups, apiGroup)
+	}
+	json.NewEncoder(w).Encode(map[string]any{
+		"success": true,
+		"groups":  apiGroups,
+	})
+}
+
+func handleJoinInputGroup(w http.ResponseWriter, ctx context.Context, group string, req InputGroupAPIRequest) {
+	if len(req.BlockIds) == 0 {
+		writeErrorResponse(w, "block_ids is required", http.StatusBadRequest)
+		return
+	}
+	err := blockcontroller.SetInputGroup(ctx, wshrpc.CommandInputGroupSetData{BlockIds: req.BlockIds, Group: group, Confirm: req.Confirm})
+	if err != nil {
+		log.Printf("Error joining input group: %v", err)
+		writeErrorResponse(w, fmt.Sprintf("Internal server error: %s", err.Error()), http.StatusInternalServerError)
+		return
+	}
+	json.NewEncoder(w).Encode(map[string]any{
+		"success": true,
+		"message": fmt.Sprintf("%d blocks joined input group %q", len(req.BlockIds), group),
+	})
+}
+
+// without blockIds all blocks leave the group
+func handleLeaveInputGroup(w http.ResponseWriter, ctx context.Context, group string, blockIds []string) {
+	if len(blockIds) == 0 {
+		groups, err := blockcontroller.ListInputGroups(ctx)
+		if err != nil {
+			writeErrorResponse(w, fmt.Sprintf("Internal server error: %s", err.Error()), http.StatusInternalServerError)
+			return
+		}
+		for _, info := range groups {
+			if info.Name != group {
+				continue
+			}
+			for _, member := range info.Members {
+				blockIds = append(blockIds, member.BlockId)
+			}
+		}
+		if len(blockIds) == 0 {
+			writeErrorResponse(w, fmt.Sprintf("input group %q not found", group), http.StatusNotFound)
+			return
+		}
+	}
+	err := blockcontroller.SetInputGroup(ctx, wshrpc.CommandInputGroupSetData{BlockIds: blockIds})
+	if err != nil {
+		log.Printf("Error leaving input group: %v", err)
+		writeErrorResponse(w, fmt.Sprintf("Internal server error: %s", err.Error()), http.StatusInternalServerError)
+		return
+	}
+	json.NewEncoder(w).Encode(map[string]any{
+		"success": true,
+		"message": fmt.Sprintf("%d blocks left input group %q", len(blockIds), group),
+	})
+}
+
+func handleInputGroupInput(w http.ResponseWriter, ctx context.Context, group string, req InputGroupAPIRequest) {
+	if req.Data == "" {
+		writeErrorResponse(w, "data is required", http.StatusBadRequest)
+		return
+	}
+	numSent, err := blockcontroller.SendInputToGroup(ctx, group, []byte(req.Data))
+	if err != nil {
+		writeErrorResponse(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	json.NewEncoder(w).Encode(map[string]any{
+		"success": true,
+		"sent_to": numSent,
+	})
+}
+
+// isJsonContentType returns true if the request body is declared as json (a form post from another site cannot
+// set this content type without a preflight)
+func isJsonContentType(r *http.Request) bool {
+	mediaType, _, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	return err == nil && mediaType == "application/json"
+}
@@ -0,0 +1,84 @@
+// Copyright 2025, Command Line Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+// REST API handlers for the trash (closed tabs and blocks)
+package web
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/wavetermdev/waveterm/pkg/waveobj"
+	"github.com/wavetermdev/waveterm/pkg/wcore"
+	"github.com/wavetermdev/waveterm/pkg/wps"
+	"github.com/wavetermdev/waveterm/pkg/wshrpc"
+)
+
+// TrashAPIRestoreRequest is the (optional) body of the restore request
+type TrashAPIRestoreRequest struct {
+	WorkspaceId string `json:"workspace_id,omitempty"` // tabs: restore into this workspace instead of the original one
+}
+
+// handleTrashAPI routes trash API requests
+//
+//	GET  /api/v1/trash                    - list the trash (filters: ?workspace_id=...&type=tab|block)
+//	POST /api/v1/trash/{trashid}/restore  - restore a closed tab or block
+//
+// requests must have the X-AuthKey header (the route is wrapped with WebFnWrap).
+func handleTrashAPI(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	path := strings.Trim(strings.TrimPrefix(r.URL.Path, "/api/v1/trash"), "/")
+	var pathParts []string
+	if path != "" {
+		pathParts = strings.Split(path, "/")
+	}
+	ctx := r.Context()
+	switch {
+	case r.Method == "GET" && len(pathParts) == 0:
+		handleListTrash(w, ctx, wshrpc.CommandTrashListData{WorkspaceId: r.URL.Query().Get("workspace_id"), OType: r.URL.Query().Get("type")})
+	case r.Method == "POST" && len(pathParts) == 2 && pathParts[1] == "restore":
+		var req TrashAPIRestoreRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil && !errors.Is(err, io.EOF) {
+			writeErrorResponse(w, "Invalid JSON request body", http.StatusBadRequest)
+			return
+		}
+		handleRestoreTrash(w, ctx, wshrpc.CommandTrashRestoreData{TrashId: pathParts[0], WorkspaceId: req.WorkspaceId})
+	case r.Method == "GET" || r.Method == "POST":
+		http.Error(w, "Not Found", http.StatusNotFound)
+	default:
+		http.Error(w, "Method Not Allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+func handleListTrash(w http.ResponseWriter, ctx context.Context, data wshrpc.CommandTrashListData) {
+	entries, err := wcore.ListTrash(ctx, data)
+	if err != nil {
+		log.Printf("Error listing trash: %v", err)
+		writeErrorResponse(w, "Internal server error: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	json.NewEncoder(w).Encode(map[string]any{
+		"success": true,
+		"entries": entries,
+	})
+}
+
+func handleRestoreTrash(w http.ResponseWriter, ctx context.Context, data wshrpc.CommandTrashRestoreData) {
+	ctx = waveobj.ContextWithUpdates(ctx)
+	rtn, err := wcore.RestoreTrash(ctx, data)
+	if err != nil {
+		log.Printf("Error restoring trash entry %s: %v", data.TrashId, err)
+		writeErrorResponse(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	wps.Broker.SendUpdateEvents(waveobj.ContextGetUpdatesRtn(ctx))
+	json.NewEncoder(w).Encode(map[string]any{
+		"success":  true,
+		"restored": rtn,
+	})
+}
@@ -5,6 +5,7 @@ package web
 
 import (
 	"bytes"
+	"context"
 	"encoding/base64"
 	"encoding/json"
 	"fmt"
@@ -51,6 +52,9 @@ const (
 
 	ContentLengthHeaderKey = "Content-Length"
 	LastModifiedHeaderKey  = "Last-Modified"
+	AcceptRangesHeaderKey  = "Accept-Ranges"
+	ContentRangeHeaderKey  = "Content-Range"
+	ETagHeaderKey          = "ETag"
 
 	WaveZoneFileInfoHeaderKey = "X-ZoneFileInfo"
 )
@@ -160,6 +164,7 @@ func handleWaveFile(w http.ResponseWriter, r *http.Request) {
 		offset, err = strconv.ParseInt(offsetStr, 10, 64)
 		if err != nil {
 			http.Error(w, fmt.Sprintf("invalid offset: %v", err), http.StatusBadRequest)
+			return
 		}
 	}
 	if _, err := uuid.Parse(zoneId); err != nil {
@@ -183,33 +188,36 @@ func handleWaveFile(w http.ResponseWriter, r *http.Request) {
 	jsonFileBArr, err := json.Marshal(file)
 	if err != nil {
 		http.Error(w, fmt.Sprintf("error serializing file info: %v", err), http.StatusInternalServerError)
+		return
 	}
-	// can make more efficient by checking modtime + If-Modified-Since headers to allow caching
 	dataStartIdx := file.DataStartIdx()
 	if offset >= dataStartIdx {
-		dataStartIdx = offset
+		dataStartIdx = min(offset, file.Size)
 	}
 	w.Header().Set(ContentTypeHeaderKey, ContentTypeBinary)
-	w.Header().Set(ContentLengthHeaderKey, fmt.Sprintf("%d", file.Size-dataStartIdx))
 	w.Header().Set(WaveZoneFileInfoHeaderKey, base64.StdEncoding.EncodeToString(jsonFileBArr))
-	w.Header().Set(LastModifiedHeaderKey, time.UnixMilli(file.ModTs).UTC().Format(http.TimeFormat))
-	if dataStartIdx >= file.Size {
-		w.WriteHeader(http.StatusOK)
-		return
-	}
-	for offset := dataStartIdx; offset < file.Size; offset += filestore.DefaultPartDataSize {
-		_, data, err := filestore.WFS.ReadAt(r.Context(), zoneId, name, offset, filestore.DefaultPartDataSize)
+	// ranges are relative to dataStartIdx (the start of the body)
+	serveRanges(w, r, file.Size-dataStartIdx, makeETag(dataStartIdx, file.Size, file.ModTs), time.UnixMilli(file.ModTs), func(ctx context.Context, w io.Writer, offset int64, length int64) error {
+		return writeWaveFileRange(ctx, w, zoneId, name, dataStartIdx+offset, length)
+	})
+}
+
+func writeWaveFileRange(ctx context.Context, w io.Writer, zoneId string, name string, offset int64, length int64) error {
+	endOffset := offset + length
+	for offset < endOffset {
+		_, data, err := filestore.WFS.ReadAt(ctx, zoneId, name, offset, min(filestore.DefaultPartDataSize, endOffset-offset))
 		if err != nil {
-			if offset == 0 {
-				http.Error(w, fmt.Sprintf("error reading file: %v", err), http.StatusInternalServerError)
-			} else {
-				// nothing to do, the headers have already been sent
-				log.Printf("error reading file %s/%s @ %d: %v\n", zoneId, name, offset, err)
-			}
-			return
+			return fmt.Errorf("error reading file %s/%s @ %d: %w", zoneId, name, offset, err)
+		}
+		if len(data) == 0 {
+			return fmt.Errorf("error reading file %s/%s @ %d: unexpected end of file", zoneId, name, offset)
+		}
+		if _, err := w.Write(data); err != nil {
+			return err
 		}
-		w.Write(data)
+		offset += int64(len(data))
 	}
+	return nil
 }
 
 func serveTransparentGIF(w http.ResponseWriter) {
@@ -339,17 +347,85 @@ func handleStreamFile(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 	no404 := r.URL.Query().Get("no404")
-	data := wshrpc.FileData{
-		Info: &wshrpc.FileInfo{
-			Path: path,
-		},
-	}
-	rtnCh := fileshare.ReadStream(r.Context(), data)
-	err := handleRemoteStreamFileFromCh(w, r, path, rtnCh, nil, no404 != "")
+	finfo, err := fileshare.Stat(r.Context(), path)
 	if err != nil {
 		log.Printf("error streaming file %q %q: %v\n", conn, path, err)
 		http.Error(w, fmt.Sprintf("error streaming file: %v", err), http.StatusInternalServerError)
+		return
+	}
+	if finfo.NotFound {
+		if no404 != "" {
+			serveTransparentGIF(w)
+			return
+		}
+		http.Error(w, fmt.Sprintf("file not found: %q", path), http.StatusNotFound)
+		return
+	}
+	if finfo.IsDir {
+		http.Error(w, fmt.Sprintf("cannot stream directory: %q", path), http.StatusBadRequest)
+		return
+	}
+	contentType := finfo.MimeType
+	if contentType == "" {
+		contentType = ContentTypeBinary
+	}
+	w.Header().Set(ContentTypeHeaderKey, contentType)
+	var modTime time.Time
+	if finfo.ModTime > 0 {
+		modTime = time.UnixMilli(finfo.ModTime)
+	}
+	// circular wave files only keep their last maxsize bytes (finfo.Size is the total written), ranges are relative
+	// to the first byte that is kept
+	var dataStart int64
+	dataLength := finfo.Size
+	if finfo.Opts != nil && finfo.Opts.Circular && finfo.Opts.MaxSize > 0 && finfo.Size > finfo.Opts.MaxSize {
+		dataStart = finfo.Size - finfo.Opts.MaxSize
+		dataLength = finfo.Opts.MaxSize
+	}
+	serveRanges(w, r, dataLength, makeETag(dataStart, finfo.Size, finfo.ModTime), modTime, func(ctx context.Context, w io.Writer, offset int64, length int64) error {
+		return writeStreamFileRange(ctx, w, path, dataStart+offset, length)
+	})
+}
+
+// streams a range of a file from its fileshare backend (wavefs, wshfs or s3fs) into w
+func writeStreamFileRange(ctx context.Context, w io.Writer, path string, offset int64, length int64) error {
+	if length <= 0 {
+		return nil
+	}
+	data := wshrpc.FileData{
+		Info: &wshrpc.FileInfo{Path: path},
+		At:   &wshrpc.FileDataAt{Offset: offset, Size: int(length)},
+	}
+	rtnCh := fileshare.ReadStream(ctx, data)
+	defer utilfn.DrainChannelSafe(rtnCh, "writeStreamFileRange")
+	var written int64
+	for written < length {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case respUnion, ok := <-rtnCh:
+			if !ok {
+				return fmt.Errorf("file %q is shorter than expected (%d of %d bytes)", path, written, length)
+			}
+			if respUnion.Error != nil {
+				return respUnion.Error
+			}
+			if respUnion.Response.Data64 == "" {
+				continue
+			}
+			barr, err := base64.StdEncoding.DecodeString(respUnion.Response.Data64)
+			if err != nil {
+				return fmt.Errorf("error decoding file data: %w", err)
+			}
+			// the file may have grown since it was stat'ed
+			barr = barr[:min(int64(len(barr)), length-written)]
+			if _, err := w.Write(barr); err != nil {
+				return err
+			}
+			written += int64(len(barr))
+		}
 	}
+	return nil
 }
 
 func WriteJsonError(w http.ResponseWriter, errVal error) {
@@ -403,7 +479,7 @@ func WebFnWrap(opts WebFnOpts, fn WebFnType) WebFnType {
 		if !opts.AllowCaching {
 			w.Header().Set(CacheControlHeaderKey, CacheControlHeaderNoCache)
 		}
-		w.Header().Set("Access-Control-Expose-Headers", "X-ZoneFileInfo")
+		w.Header().Set("Access-Control-Expose-Headers", "X-ZoneFileInfo, Content-Range, Accept-Ranges, ETag")
 		err := authkey.ValidateIncomingRequest(r)
 		if err != nil {
 			w.WriteHeader(http.StatusUnauthorized)
@@ -421,6 +497,9 @@ func MakeTCPListener(serviceName string) (net.Listener, error) {
 		return nil, fmt.Errorf("error creating listener at %v: %v", serverAddr, err)
 	}
 	log.Printf("Server [%s] listening on %s\n", serviceName, rtn.Addr())
+	if tcpAddr, ok := rtn.Addr().(*net.TCPAddr); ok {
+		wavebase.AddServerPort(tcpAddr.Port)
+	}
 	return rtn, nil
 }
 
@@ -451,6 +530,9 @@ func RunWebServer(listener net.Listener) {
 	
 	// Widget API endpoints
 	gr.PathPrefix("/api/v1/widgets").HandlerFunc(handleWidgetAPI)
+	gr.PathPrefix("/api/v1/inputgroups").HandlerFunc(WebFnWrap(WebFnOpts{}, handleInputGroupAPI))
+	gr.PathPrefix("/api/v1/trash").HandlerFunc(WebFnWrap(WebFnOpts{}, handleTrashAPI))
+	gr.PathPrefix("/api/v1/events").HandlerFunc(WebFnWrap(WebFnOpts{}, handleEventsAPI))
 	
 	gr.PathPrefix(docsitePrefix).Handler(http.StripPrefix(docsitePrefix, docsite.GetDocsiteHandler()))
 	gr.PathPrefix(schemaPrefix).Handler(http.StripPrefix(schemaPrefix, schema.GetSchemaHandler()))
@@ -0,0 +1,259 @@
+// Copyright 2025, Command Line Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package web
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"mime/multipart"
+	"net/http"
+	"net/textproto"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// more ranges than this are ignored (the whole body is sent)
+const maxRanges = 16
+
+var errRangeNotSatisfiable = errors.New("range not satisfiable")
+
+// a byte range of a response body (RFC 7233)
+type httpRange struct {
+	Start  int64
+	Length int64
+}
+
+func (ra httpRange) contentRange(size int64) string {
+	return fmt.Sprintf("bytes %d-%d/%d", ra.Start, ra.Start+ra.Length-1, size)
+}
+
+func (ra httpRange) mimeHeader(contentType string, size int64) textproto.MIMEHeader {
+	return textproto.MIMEHeader{
+		"Content-Range": {ra.contentRange(size)},
+		"Content-Type":  {contentType},
+	}
+}
+
+// reads length bytes of the body (starting at offset) into w
+type rangeReadFn func(ctx context.Context, w io.Writer, offset int64, length int64) error
+
+// parses a Range header for a body of size bytes.  returns nil (send the whole body) if there is no header, or if
+// it can't be parsed (invalid ranges are ignored), and errRangeNotSatisfiable if none of the ranges overlap the body.
+func parseRangeHeader(header string, size int64) ([]httpRange, error) {
+	const prefix = "bytes="
+	if !strings.HasPrefix(header, prefix) {
+		return nil, nil
+	}
+	var ranges []httpRange
+	var sumLength int64
+	noOverlap := false
+	for _, spec := range strings.Split(header[len(prefix):], ",") {
+		spec = strings.TrimSpace(spec)
+		if spec == "" {
+			continue
+		}
+		startStr, endStr, ok := strings.Cut(spec, "-")
+		if !ok {
+			return nil, nil
+		}
+		startStr, endStr = strings.TrimSpace(startStr), strings.TrimSpace(endStr)
+		var ra httpRange
+		if startStr == "" {
+			// suffix range, the last n bytes
+			n, err := strconv.ParseInt(endStr, 10, 64)
+			if err != nil || n < 0 {
+				return nil, nil
+			}
+			if n == 0 || size == 0 {
+				noOverlap = true
+				continue
+			}
+			n = min(n, size)
+			ra = httpRange{Start: size - n, Length: n}
+		} else {
+			start, err := strconv.ParseInt(startStr, 10, 64)
+			if err != nil || start < 0 {
+				return nil, nil
+			}
+			end := size - 1
+			if endStr != "" {
+				end, err = strconv.ParseInt(endStr, 10, 64)
+				if err != nil || end < start {
+					return nil, nil
+				}
+			}
+			if start >= size {
+				noOverlap = true
+				continue
+			}
+			end = min(end, size-1)
+			ra = httpRange{Start: start, Length: end - start + 1}
+		}
+		ranges = append(ranges, ra)
+		sumLength += ra.Length
+	}
+	if len(ranges) == 0 {
+		if noOverlap {
+			return nil, errRangeNotSatisfiable
+		}
+		return nil, nil
+	}
+	if len(ranges) > maxRanges || sumLength > size {
+		// not worth it (or an attempt to make us send the same data many times)
+		return nil, nil
+	}
+	return ranges, nil
+}
+
+// a strong validator from the size and modification time (like the ETags of most static file servers).  start is
+// the file offset the body starts at (bodies with different starts must not share an etag).
+func makeETag(start int64, size int64, modTs int64) string {
+	return fmt.Sprintf("\"%x-%x-%x\"", modTs, size, start)
+}
+
+// true if etag is in the If-None-Match list (weak comparison)
+func etagListMatch(list string, etag string) bool {
+	for _, candidate := range strings.Split(list, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" || strings.TrimPrefix(candidate, "W/") == etag {
+			return true
+		}
+	}
+	return false
+}
+
+// true if the client's copy is current (If-None-Match, or If-Modified-Since if there is no If-None-Match)
+func checkNotModified(r *http.Request, etag string, modTime time.Time) bool {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		return false
+	}
+	if inm := r.Header.Get("If-None-Match"); inm != "" {
+		return etag != "" && etagListMatch(inm, etag)
+	}
+	ims := r.Header.Get("If-Modified-Since")
+	if ims == "" || modTime.IsZero() {
+		return false
+	}
+	t, err := http.ParseTime(ims)
+	if err != nil {
+		return false
+	}
+	return !modTime.Truncate(time.Second).After(t)
+}
+
+// true if the Range header should be used (there is no If-Range, or it matches the current version)
+func checkIfRange(r *http.Request, etag string, modTime time.Time) bool {
+	ir := r.Header.Get("If-Range")
+	if ir == "" {
+		return true
+	}
+	if strings.HasPrefix(ir, "\"") || strings.HasPrefix(ir, "W/") {
+		// strong comparison
+		return etag != "" && ir == etag
+	}
+	t, err := http.ParseTime(ir)
+	if err != nil || modTime.IsZero() {
+		return false
+	}
+	return modTime.Truncate(time.Second).Equal(t)
+}
+
+type countingWriter int64
+
+func (cw *countingWriter) Write(p []byte) (int, error) {
+	*cw += countingWriter(len(p))
+	return len(p), nil
+}
+
+// the size of a multipart/byteranges body
+func multipartRangesSize(ranges []httpRange, boundary string, contentType string, size int64) int64 {
+	var cw countingWriter
+	mw := multipart.NewWriter(&cw)
+	mw.SetBoundary(boundary)
+	for _, ra := range ranges {
+		mw.CreatePart(ra.mimeHeader(contentType, size))
+		cw += countingWriter(ra.Length)
+	}
+	mw.Close()
+	return int64(cw)
+}
+
+// serves a body of size bytes with HEAD, conditional (If-None-Match, If-Modified-Since) and range requests (single
+// and multiple ranges, with If-Range).  the Content-Type header must already be set.  etag and modTime are optional.
+func serveRanges(w http.ResponseWriter, r *http.Request, size int64, etag string, modTime time.Time, readFn rangeReadFn) {
+	w.Header().Set(AcceptRangesHeaderKey, "bytes")
+	if etag != "" {
+		w.Header().Set(ETagHeaderKey, etag)
+	}
+	if !modTime.IsZero() {
+		w.Header().Set(LastModifiedHeaderKey, modTime.UTC().Format(http.TimeFormat))
+	}
+	if checkNotModified(r, etag, modTime) {
+		w.Header().Del(ContentTypeHeaderKey)
+		w.Header().Del(ContentLengthHeaderKey)
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+	var ranges []httpRange
+	if rangeHeader := r.Header.Get("Range"); rangeHeader != "" && checkIfRange(r, etag, modTime) {
+		var err error
+		ranges, err = parseRangeHeader(rangeHeader, size)
+		if err != nil {
+			w.Header().Set(ContentRangeHeaderKey, fmt.Sprintf("bytes */%d", size))
+			http.Error(w, err.Error(), http.StatusRequestedRangeNotSatisfiable)
+			return
+		}
+	}
+	ctx := r.Context()
+	isHead := r.Method == http.MethodHead
+	var err error
+	switch len(ranges) {
+	case 0:
+		w.Header().Set(ContentLengthHeaderKey, fmt.Sprintf("%d", size))
+		w.WriteHeader(http.StatusOK)
+		if isHead {
+			return
+		}
+		err = readFn(ctx, w, 0, size)
+	case 1:
+		ra := ranges[0]
+		w.Header().Set(ContentRangeHeaderKey, ra.contentRange(size))
+		w.Header().Set(ContentLengthHeaderKey, fmt.Sprintf("%d", ra.Length))
+		w.WriteHeader(http.StatusPartialContent)
+		if isHead {
+			return
+		}
+		err = readFn(ctx, w, ra.Start, ra.Length)
+	default:
+		contentType := w.Header().Get(ContentTypeHeaderKey)
+		mw := multipart.NewWriter(w)
+		w.Header().Set(ContentTypeHeaderKey, "multipart/byteranges; boundary="+mw.Boundary())
+		w.Header().Set(ContentLengthHeaderKey, fmt.Sprintf("%d", multipartRangesSize(ranges, mw.Boundary(), contentType, size)))
+		w.WriteHeader(http.StatusPartialContent)
+		if isHead {
+			return
+		}
+		for _, ra := range ranges {
+			var part io.Writer
+			part, err = mw.CreatePart(ra.mimeHeader(contentType, size))
+			if err == nil {
+				err = readFn(ctx, part, ra.Start, ra.Length)
+			}
+			if err != nil {
+				break
+			}
+		}
+		if err == nil {
+			err = mw.Close()
+		}
+	}
+	if err != nil {
+		// the headers have already been sent
+		log.Printf("error serving %s: %v\n", r.URL.Path, err)
+	}
+}
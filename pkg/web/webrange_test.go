@@ -0,0 +1,191 @@
+// Copyright 2025, Command Line Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package web
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/wavetermdev/waveterm/pkg/filestore"
+	"github.com/wavetermdev/waveterm/pkg/wavebase"
+	"github.com/wavetermdev/waveterm/pkg/wshrpc"
+)
+
+func TestParseRangeHeader(t *testing.T) {
+	tests := []struct {
+		header   string
+		size     int64
+		expected []httpRange
+		err      error
+	}{
+		{"", 100, nil, nil},
+		{"bytes=0-9", 100, []httpRange{{0, 10}}, nil},
+		{"bytes=90-", 100, []httpRange{{90, 10}}, nil},
+		{"bytes=-5", 100, []httpRange{{95, 5}}, nil},
+		{"bytes=-500", 100, []httpRange{{0, 100}}, nil},
+		{"bytes=50-500", 100, []httpRange{{50, 50}}, nil},
+		{"bytes=0-1, 10-11", 100, []httpRange{{0, 2}, {10, 2}}, nil},
+		{"bytes=0-1,200-300", 100, []httpRange{{0, 2}}, nil},
+		{"bytes=200-300", 100, nil, errRangeNotSatisfiable},
+		{"bytes=0-", 0, nil, errRangeNotSatisfiable},
+		{"bytes=5-1", 100, nil, nil},
+		{"bytes=abc", 100, nil, nil},
+		{"items=0-1", 100, nil, nil},
+		{"bytes=0-99,0-99", 100, nil, nil},
+	}
+	for _, test := range tests {
+		ranges, err := parseRangeHeader(test.header, test.size)
+		if err != test.err || !reflect.DeepEqual(ranges, test.expected) {
+			t.Errorf("%q (size %d): expected %v %v, got %v %v", test.header, test.size, test.expected, test.err, ranges, err)
+		}
+	}
+}
+
+func serveTestBody(t *testing.T, method string, headers map[string]string) *httptest.ResponseRecorder {
+	body := "0123456789abcdefghij"
+	modTime := time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC)
+	req := httptest.NewRequest(method, "/wave/stream-file", nil)
+	for key, val := range headers {
+		req.Header.Set(key, val)
+	}
+	w := httptest.NewRecorder()
+	w.Header().Set(ContentTypeHeaderKey, "text/plain")
+	serveRanges(w, req, int64(len(body)), makeETag(0, int64(len(body)), modTime.UnixMilli()), modTime, func(ctx context.Context, w io.Writer, offset int64, length int64) error {
+		_, err := io.WriteString(w, body[offset:offset+length])
+		return err
+	})
+	return w
+}
+
+func TestServeRanges(t *testing.T) {
+	etag := makeETag(0, 20, time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC).UnixMilli())
+	w := serveTestBody(t, http.MethodGet, nil)
+	if w.Code != http.StatusOK || w.Body.String() != "0123456789abcdefghij" || w.Header().Get(AcceptRangesHeaderKey) != "bytes" || w.Header().Get(ETagHeaderKey) != etag {
+		t.Errorf("full body: got %d %q %v", w.Code, w.Body.String(), w.Header())
+	}
+	w = serveTestBody(t, http.MethodGet, map[string]string{"Range": "bytes=2-4"})
+	if w.Code != http.StatusPartialContent || w.Body.String() != "234" || w.Header().Get(ContentRangeHeaderKey) != "bytes 2-4/20" {
+		t.Errorf("single range: got %d %q %v", w.Code, w.Body.String(), w.Header())
+	}
+	w = serveTestBody(t, http.MethodHead, map[string]string{"Range": "bytes=-3"})
+	if w.Code != http.StatusPartialContent || w.Body.Len() != 0 || w.Header().Get(ContentLengthHeaderKey) != "3" {
+		t.Errorf("head: got %d %q %v", w.Code, w.Body.String(), w.Header())
+	}
+	w = serveTestBody(t, http.MethodGet, map[string]string{"Range": "bytes=30-"})
+	if w.Code != http.StatusRequestedRangeNotSatisfiable || w.Header().Get(ContentRangeHeaderKey) != "bytes */20" {
+		t.Errorf("unsatisfiable range: got %d %v", w.Code, w.Header())
+	}
+	w = serveTestBody(t, http.MethodGet, map[string]string{"If-None-Match": `"other", ` + etag})
+	if w.Code != http.StatusNotModified || w.Body.Len() != 0 {
+		t.Errorf("if-none-match: got %d %q", w.Code, w.Body.String())
+	}
+	w = serveTestBody(t, http.MethodGet, map[string]string{"If-Modified-Since": "Thu, 02 Jan 2025 03:04:05 GMT"})
+	if w.Code != http.StatusNotModified {
+		t.Errorf("if-modified-since: got %d", w.Code)
+	}
+	w = serveTestBody(t, http.MethodGet, map[string]string{"Range": "bytes=0-1", "If-Range": `"stale"`})
+	if w.Code != http.StatusOK || w.Body.Len() != 20 {
+		t.Errorf("if-range mismatch: got %d %q", w.Code, w.Body.String())
+	}
+	w = serveTestBody(t, http.MethodGet, map[string]string{"Range": "bytes=0-1", "If-Range": etag})
+	if w.Code != http.StatusPartialContent || w.Body.String() != "01" {
+		t.Errorf("if-range match: got %d %q", w.Code, w.Body.String())
+	}
+
+	w = serveTestBody(t, http.MethodGet, map[string]string{"Range": "bytes=0-1,18-"})
+	if w.Code != http.StatusPartialContent {
+		t.Fatalf("multi range: got %d", w.Code)
+	}
+	if w.Header().Get(ContentLengthHeaderKey) != "" && w.Header().Get(ContentLengthHeaderKey) != strconv.Itoa(w.Body.Len()) {
+		t.Errorf("multi range: content-length %s, body is %d bytes", w.Header().Get(ContentLengthHeaderKey), w.Body.Len())
+	}
+	mediaType, params, err := mime.ParseMediaType(w.Header().Get(ContentTypeHeaderKey))
+	if err != nil || mediaType != "multipart/byteranges" {
+		t.Fatalf("multi range: content type %q", w.Header().Get(ContentTypeHeaderKey))
+	}
+	mr := multipart.NewReader(w.Body, params["boundary"])
+	var parts []string
+	for {
+		part, err := mr.NextPart()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("multi range: %v", err)
+		}
+		data, _ := io.ReadAll(part)
+		parts = append(parts, part.Header.Get("Content-Range")+" "+string(data))
+	}
+	if !reflect.DeepEqual(parts, []string{"bytes 0-1/20 01", "bytes 18-19/20 ij"}) {
+		t.Errorf("multi range: got parts %q", parts)
+	}
+}
+
+func TestServeCircularWaveFile(t *testing.T) {
+	wavebase.DataHome_VarCache = t.TempDir()
+	err := os.MkdirAll(filepath.Join(wavebase.DataHome_VarCache, wavebase.WaveDBDir), 0700)
+	if err == nil {
+		err = filestore.InitFilestore()
+	}
+	if err != nil {
+		t.Fatalf("error initializing filestore: %v", err)
+	}
+	ctx := context.Background()
+	zoneId := uuid.NewString()
+	// 3 parts written, only the last 2 are kept
+	maxSize := int64(2 * filestore.DefaultPartDataSize)
+	err = filestore.WFS.MakeFile(ctx, zoneId, "term", nil, wshrpc.FileOpts{Circular: true, MaxSize: maxSize})
+	if err != nil {
+		t.Fatalf("error making file: %v", err)
+	}
+	data := make([]byte, 3*filestore.DefaultPartDataSize)
+	for idx := range data {
+		data[idx] = byte('a' + idx%26)
+	}
+	err = filestore.WFS.AppendData(ctx, zoneId, "term", data)
+	if err != nil {
+		t.Fatalf("error writing file: %v", err)
+	}
+	kept := data[len(data)-int(maxSize):]
+
+	// ranges are relative to the first byte that is kept
+	req := httptest.NewRequest(http.MethodGet, "/wave/stream-file?path="+url.QueryEscape("wavefile://"+zoneId+"/term"), nil)
+	req.Header.Set("Range", "bytes=0-9")
+	w := httptest.NewRecorder()
+	handleStreamFile(w, req)
+	if w.Code != http.StatusPartialContent || w.Body.String() != string(kept[:10]) || w.Header().Get(ContentRangeHeaderKey) != fmt.Sprintf("bytes 0-9/%d", maxSize) {
+		t.Errorf("stream-file range: got %d %q %v", w.Code, w.Body.String(), w.Header())
+	}
+
+	// bodies starting at different offsets have different etags
+	getWaveFile := func(offset int64) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, fmt.Sprintf("/wave/file?zoneid=%s&name=term&offset=%d", zoneId, offset), nil)
+		w := httptest.NewRecorder()
+		handleWaveFile(w, req)
+		return w
+	}
+	w1, w2, w3 := getWaveFile(0), getWaveFile(int64(len(data)-10)), getWaveFile(int64(len(data)-int(maxSize)))
+	if w1.Body.String() != string(kept) || w2.Body.String() != string(data[len(data)-10:]) {
+		t.Errorf("wave file bodies: got %d and %d bytes", w1.Body.Len(), w2.Body.Len())
+	}
+	if w1.Header().Get(ETagHeaderKey) == w2.Header().Get(ETagHeaderKey) {
+		t.Errorf("different bodies share the etag %s", w1.Header().Get(ETagHeaderKey))
+	}
+	if w1.Header().Get(ETagHeaderKey) != w3.Header().Get(ETagHeaderKey) {
+		t.Errorf("the same body should have the same etag (%s, %s)", w1.Header().Get(ETagHeaderKey), w3.Header().Get(ETagHeaderKey))
+	}
+}
@@ -38,9 +38,16 @@ type persistEventWrap struct {
 	Events       []*WaveEvent
 }
 
+// EventJournal writes events to durable storage.  AppendEvent is called for every published event (before it is
+// sent), it sets Seq and Ts on the events it keeps and must not block.
+type EventJournal interface {
+	AppendEvent(event *WaveEvent)
+}
+
 type BrokerType struct {
 	Lock       *sync.Mutex
 	Client     Client
+	Journal    EventJournal
 	SubMap     map[string]*BrokerSubscription
 	PersistMap map[persistKey]*persistEventWrap
 }
@@ -73,6 +80,18 @@ func (b *BrokerType) GetClient() Client {
 	return b.Client
 }
 
+func (b *BrokerType) SetJournal(journal EventJournal) {
+	b.Lock.Lock()
+	defer b.Lock.Unlock()
+	b.Journal = journal
+}
+
+func (b *BrokerType) GetJournal() EventJournal {
+	b.Lock.Lock()
+	defer b.Lock.Unlock()
+	return b.Journal
+}
+
 // if already subscribed, this will *resubscribe* with the new subscription (remove the old one, and replace with this one)
 func (b *BrokerType) Subscribe(subRouteId string, sub SubscriptionRequest) {
 	// log.Printf("[wps] sub %s %s\n", subRouteId, sub.Event)
@@ -230,6 +249,9 @@ func (b *BrokerType) persistEvent(event WaveEvent) {
 
 func (b *BrokerType) Publish(event WaveEvent) {
 	// log.Printf("BrokerType.Publish: %v\n", event)
+	if journal := b.GetJournal(); journal != nil {
+		journal.AppendEvent(&event)
+	}
 	if event.Persist > 0 {
 		b.persistEvent(event)
 	}
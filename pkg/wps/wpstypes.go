@@ -16,6 +16,9 @@ const (
 	Event_UserInput        = "userinput"
 	Event_RouteGone        = "route:gone"
 	Event_WorkspaceUpdate  = "workspace:update"
+	Event_FileChange       = "file:change"
+	Event_TermTrigger      = "term:trigger"
+	Event_BlockPorts       = "block:ports"
 )
 
 type WaveEvent struct {
@@ -23,6 +26,8 @@ type WaveEvent struct {
 	Scopes  []string `json:"scopes,omitempty"`
 	Sender  string   `json:"sender,omitempty"`
 	Persist int      `json:"persist,omitempty"`
+	Seq     int64    `json:"seq,omitempty"` // set for events written to the event journal
+	Ts      int64    `json:"ts,omitempty"`  // set for events written to the event journal
 	Data    any      `json:"data,omitempty"`
 }
 
@@ -34,6 +39,25 @@ type SubscriptionRequest struct {
 	Event     string   `json:"event"`
 	Scopes    []string `json:"scopes,omitempty"`
 	AllScopes bool     `json:"allscopes,omitempty"`
+	SinceSeq  int64    `json:"sinceseq,omitempty"` // if > 0, journaled events after this seq are sent first
+}
+
+// Matches returns true if the event would be sent to this subscription
+func (sub SubscriptionRequest) Matches(event *WaveEvent) bool {
+	if event.Event != sub.Event {
+		return false
+	}
+	if sub.AllScopes {
+		return true
+	}
+	for _, subScope := range sub.Scopes {
+		for _, scope := range event.Scopes {
+			if subScope == scope || (scopeHasStarMatch(subScope) && utilfn.StarMatchString(subScope, scope, ":")) {
+				return true
+			}
+		}
+	}
+	return false
 }
 
 const (
@@ -50,3 +74,17 @@ type WSFileEventData struct {
 	FileOp   string `json:"fileop"`
 	Data64   string `json:"data64"`
 }
+
+// a term:triggers match, published for the "event" action and stored in the block's marks file for the "mark" action
+type TermTriggerEventData struct {
+	BlockId string `json:"blockid"`
+	Name    string `json:"name,omitempty"`
+	Pattern string `json:"pattern"`
+	Action  string `json:"action"`
+	Line    string `json:"line"`
+	Match   string `json:"match"`
+	Text    string `json:"text,omitempty"` // the expanded message or input
+	Offset  int64  `json:"offset"`         // term file offset of the match
+	Length  int64  `json:"length"`         // length of the match in the term file (including any escape sequences)
+	Ts      int64  `json:"ts"`
+}
@@ -40,12 +40,30 @@ func AuthenticateTokenCommand(w *wshutil.WshRpc, data wshrpc.CommandAuthenticate
 	return resp, err
 }
 
+// command "backupcreate", wshserver.BackupCreateCommand
+func BackupCreateCommand(w *wshutil.WshRpc, data wshrpc.CommandBackupCreateData, opts *wshrpc.RpcOpts) (*wshrpc.BackupCreateRtnData, error) {
+	resp, err := sendRpcRequestCallHelper[*wshrpc.BackupCreateRtnData](w, "backupcreate", data, opts)
+	return resp, err
+}
+
+// command "backuprestore", wshserver.BackupRestoreCommand
+func BackupRestoreCommand(w *wshutil.WshRpc, data wshrpc.CommandBackupRestoreData, opts *wshrpc.RpcOpts) (*wshrpc.BackupRestoreRtnData, error) {
+	resp, err := sendRpcRequestCallHelper[*wshrpc.BackupRestoreRtnData](w, "backuprestore", data, opts)
+	return resp, err
+}
+
 // command "blockinfo", wshserver.BlockInfoCommand
 func BlockInfoCommand(w *wshutil.WshRpc, data string, opts *wshrpc.RpcOpts) (*wshrpc.BlockInfoData, error) {
 	resp, err := sendRpcRequestCallHelper[*wshrpc.BlockInfoData](w, "blockinfo", data, opts)
 	return resp, err
 }
 
+// command "blockslist", wshserver.BlocksListCommand
+func BlocksListCommand(w *wshutil.WshRpc, data wshrpc.CommandBlocksListData, opts *wshrpc.RpcOpts) ([]*wshrpc.BlocksListEntry, error) {
+	resp, err := sendRpcRequestCallHelper[[]*wshrpc.BlocksListEntry](w, "blockslist", data, opts)
+	return resp, err
+}
+
 // command "connconnect", wshserver.ConnConnectCommand
 func ConnConnectCommand(w *wshutil.WshRpc, data wshrpc.ConnRequest, opts *wshrpc.RpcOpts) error {
 	_, err := sendRpcRequestCallHelper[any](w, "connconnect", data, opts)
@@ -130,6 +148,12 @@ func CreateSubBlockCommand(w *wshutil.WshRpc, data wshrpc.CommandCreateSubBlockD
 	return resp, err
 }
 
+// command "dbencrypt", wshserver.DBEncryptCommand
+func DBEncryptCommand(w *wshutil.WshRpc, data wshrpc.CommandDBEncryptData, opts *wshrpc.RpcOpts) (*wshrpc.DBEncryptRtnData, error) {
+	resp, err := sendRpcRequestCallHelper[*wshrpc.DBEncryptRtnData](w, "dbencrypt", data, opts)
+	return resp, err
+}
+
 // command "deleteblock", wshserver.DeleteBlockCommand
 func DeleteBlockCommand(w *wshutil.WshRpc, data wshrpc.CommandDeleteBlockData, opts *wshrpc.RpcOpts) error {
 	_, err := sendRpcRequestCallHelper[any](w, "deleteblock", data, opts)
@@ -184,6 +208,12 @@ func EventRecvCommand(w *wshutil.WshRpc, data wps.WaveEvent, opts *wshrpc.RpcOpt
 	return err
 }
 
+// command "eventreplay", wshserver.EventReplayCommand
+func EventReplayCommand(w *wshutil.WshRpc, data wshrpc.CommandEventReplayData, opts *wshrpc.RpcOpts) (*wshrpc.EventReplayRtnData, error) {
+	resp, err := sendRpcRequestCallHelper[*wshrpc.EventReplayRtnData](w, "eventreplay", data, opts)
+	return resp, err
+}
+
 // command "eventsub", wshserver.EventSubCommand
 func EventSubCommand(w *wshutil.WshRpc, data wps.SubscriptionRequest, opts *wshrpc.RpcOpts) error {
 	_, err := sendRpcRequestCallHelper[any](w, "eventsub", data, opts)
@@ -284,17 +314,50 @@ func FileReadStreamCommand(w *wshutil.WshRpc, data wshrpc.FileData, opts *wshrpc
 	return sendRpcRequestResponseStreamHelper[wshrpc.FileData](w, "filereadstream", data, opts)
 }
 
+// command "filesearch", wshserver.FileSearchCommand
+func FileSearchCommand(w *wshutil.WshRpc, data wshrpc.FileSearchData, opts *wshrpc.RpcOpts) chan wshrpc.RespOrErrorUnion[wshrpc.CommandFileSearchRtnData] {
+	return sendRpcRequestResponseStreamHelper[wshrpc.CommandFileSearchRtnData](w, "filesearch", data, opts)
+}
+
 // command "filesharecapability", wshserver.FileShareCapabilityCommand
 func FileShareCapabilityCommand(w *wshutil.WshRpc, data string, opts *wshrpc.RpcOpts) (wshrpc.FileShareCapability, error) {
 	resp, err := sendRpcRequestCallHelper[wshrpc.FileShareCapability](w, "filesharecapability", data, opts)
 	return resp, err
 }
 
+// command "filestoregc", wshserver.FileStoreGCCommand
+func FileStoreGCCommand(w *wshutil.WshRpc, data wshrpc.CommandFileStoreGCData, opts *wshrpc.RpcOpts) (*wshrpc.FileStoreGCRtnData, error) {
+	resp, err := sendRpcRequestCallHelper[*wshrpc.FileStoreGCRtnData](w, "filestoregc", data, opts)
+	return resp, err
+}
+
+// command "filestorerecompress", wshserver.FileStoreRecompressCommand
+func FileStoreRecompressCommand(w *wshutil.WshRpc, data wshrpc.CommandFileStoreRecompressData, opts *wshrpc.RpcOpts) (*wshrpc.FileStoreRecompressRtnData, error) {
+	resp, err := sendRpcRequestCallHelper[*wshrpc.FileStoreRecompressRtnData](w, "filestorerecompress", data, opts)
+	return resp, err
+}
+
+// command "filestoreusage", wshserver.FileStoreUsageCommand
+func FileStoreUsageCommand(w *wshutil.WshRpc, data wshrpc.CommandFileStoreUsageData, opts *wshrpc.RpcOpts) (*wshrpc.FileStoreUsageRtnData, error) {
+	resp, err := sendRpcRequestCallHelper[*wshrpc.FileStoreUsageRtnData](w, "filestoreusage", data, opts)
+	return resp, err
+}
+
 // command "filestreamtar", wshserver.FileStreamTarCommand
 func FileStreamTarCommand(w *wshutil.WshRpc, data wshrpc.CommandRemoteStreamTarData, opts *wshrpc.RpcOpts) chan wshrpc.RespOrErrorUnion[iochantypes.Packet] {
 	return sendRpcRequestResponseStreamHelper[iochantypes.Packet](w, "filestreamtar", data, opts)
 }
 
+// command "filesync", wshserver.FileSyncCommand
+func FileSyncCommand(w *wshutil.WshRpc, data wshrpc.CommandFileSyncData, opts *wshrpc.RpcOpts) chan wshrpc.RespOrErrorUnion[wshrpc.FileSyncUpdate] {
+	return sendRpcRequestResponseStreamHelper[wshrpc.FileSyncUpdate](w, "filesync", data, opts)
+}
+
+// command "filewatch", wshserver.FileWatchCommand
+func FileWatchCommand(w *wshutil.WshRpc, data wshrpc.CommandFileWatchData, opts *wshrpc.RpcOpts) chan wshrpc.RespOrErrorUnion[wshrpc.FileWatchEvent] {
+	return sendRpcRequestResponseStreamHelper[wshrpc.FileWatchEvent](w, "filewatch", data, opts)
+}
+
 // command "filewrite", wshserver.FileWriteCommand
 func FileWriteCommand(w *wshutil.WshRpc, data wshrpc.FileData, opts *wshrpc.RpcOpts) error {
 	_, err := sendRpcRequestCallHelper[any](w, "filewrite", data, opts)
@@ -337,6 +400,36 @@ func GetVarCommand(w *wshutil.WshRpc, data wshrpc.CommandVarData, opts *wshrpc.R
 	return resp, err
 }
 
+// command "historygetoutput", wshserver.HistoryGetOutputCommand
+func HistoryGetOutputCommand(w *wshutil.WshRpc, data wshrpc.CommandHistoryGetOutputData, opts *wshrpc.RpcOpts) (*wshrpc.CommandHistoryGetOutputRtnData, error) {
+	resp, err := sendRpcRequestCallHelper[*wshrpc.CommandHistoryGetOutputRtnData](w, "historygetoutput", data, opts)
+	return resp, err
+}
+
+// command "historylist", wshserver.HistoryListCommand
+func HistoryListCommand(w *wshutil.WshRpc, data wshrpc.CommandHistoryListData, opts *wshrpc.RpcOpts) ([]*wshrpc.CmdHistoryEntry, error) {
+	resp, err := sendRpcRequestCallHelper[[]*wshrpc.CmdHistoryEntry](w, "historylist", data, opts)
+	return resp, err
+}
+
+// command "inputgrouplist", wshserver.InputGroupListCommand
+func InputGroupListCommand(w *wshutil.WshRpc, opts *wshrpc.RpcOpts) ([]*wshrpc.InputGroupInfo, error) {
+	resp, err := sendRpcRequestCallHelper[[]*wshrpc.InputGroupInfo](w, "inputgrouplist", nil, opts)
+	return resp, err
+}
+
+// command "inputgroupsend", wshserver.InputGroupSendCommand
+func InputGroupSendCommand(w *wshutil.WshRpc, data wshrpc.CommandInputGroupSendData, opts *wshrpc.RpcOpts) error {
+	_, err := sendRpcRequestCallHelper[any](w, "inputgroupsend", data, opts)
+	return err
+}
+
+// command "inputgroupset", wshserver.InputGroupSetCommand
+func InputGroupSetCommand(w *wshutil.WshRpc, data wshrpc.CommandInputGroupSetData, opts *wshrpc.RpcOpts) error {
+	_, err := sendRpcRequestCallHelper[any](w, "inputgroupset", data, opts)
+	return err
+}
+
 // command "message", wshserver.MessageCommand
 func MessageCommand(w *wshutil.WshRpc, data wshrpc.CommandMessageData, opts *wshrpc.RpcOpts) error {
 	_, err := sendRpcRequestCallHelper[any](w, "message", data, opts)
@@ -349,12 +442,42 @@ func NotifyCommand(w *wshutil.WshRpc, data wshrpc.WaveNotificationOptions, opts
 	return err
 }
 
+// command "patchmeta", wshserver.PatchMetaCommand
+func PatchMetaCommand(w *wshutil.WshRpc, data wshrpc.CommandPatchMetaData, opts *wshrpc.RpcOpts) (waveobj.MetaMapType, error) {
+	resp, err := sendRpcRequestCallHelper[waveobj.MetaMapType](w, "patchmeta", data, opts)
+	return resp, err
+}
+
 // command "path", wshserver.PathCommand
 func PathCommand(w *wshutil.WshRpc, data wshrpc.PathCommandData, opts *wshrpc.RpcOpts) (string, error) {
 	resp, err := sendRpcRequestCallHelper[string](w, "path", data, opts)
 	return resp, err
 }
 
+// command "portopen", wshserver.PortOpenCommand
+func PortOpenCommand(w *wshutil.WshRpc, data wshrpc.CommandPortOpenData, opts *wshrpc.RpcOpts) (*wshrpc.PortOpenRtnData, error) {
+	resp, err := sendRpcRequestCallHelper[*wshrpc.PortOpenRtnData](w, "portopen", data, opts)
+	return resp, err
+}
+
+// command "portslist", wshserver.PortsListCommand
+func PortsListCommand(w *wshutil.WshRpc, data wshrpc.CommandPortsListData, opts *wshrpc.RpcOpts) ([]*wshrpc.BlockPortsData, error) {
+	resp, err := sendRpcRequestCallHelper[[]*wshrpc.BlockPortsData](w, "portslist", data, opts)
+	return resp, err
+}
+
+// command "recordstart", wshserver.RecordStartCommand
+func RecordStartCommand(w *wshutil.WshRpc, data wshrpc.CommandRecordStartData, opts *wshrpc.RpcOpts) (*wshrpc.CommandRecordRtnData, error) {
+	resp, err := sendRpcRequestCallHelper[*wshrpc.CommandRecordRtnData](w, "recordstart", data, opts)
+	return resp, err
+}
+
+// command "recordstop", wshserver.RecordStopCommand
+func RecordStopCommand(w *wshutil.WshRpc, data wshrpc.CommandRecordStopData, opts *wshrpc.RpcOpts) (*wshrpc.CommandRecordRtnData, error) {
+	resp, err := sendRpcRequestCallHelper[*wshrpc.CommandRecordRtnData](w, "recordstop", data, opts)
+	return resp, err
+}
+
 // command "recordtevent", wshserver.RecordTEventCommand
 func RecordTEventCommand(w *wshutil.WshRpc, data telemetrydata.TEvent, opts *wshrpc.RpcOpts) error {
 	_, err := sendRpcRequestCallHelper[any](w, "recordtevent", data, opts)
@@ -385,18 +508,33 @@ func RemoteFileJoinCommand(w *wshutil.WshRpc, data []string, opts *wshrpc.RpcOpt
 	return resp, err
 }
 
+// command "remotefilemanifest", wshserver.RemoteFileManifestCommand
+func RemoteFileManifestCommand(w *wshutil.WshRpc, data wshrpc.CommandRemoteFileManifestData, opts *wshrpc.RpcOpts) chan wshrpc.RespOrErrorUnion[wshrpc.CommandRemoteFileManifestRtnData] {
+	return sendRpcRequestResponseStreamHelper[wshrpc.CommandRemoteFileManifestRtnData](w, "remotefilemanifest", data, opts)
+}
+
 // command "remotefilemove", wshserver.RemoteFileMoveCommand
 func RemoteFileMoveCommand(w *wshutil.WshRpc, data wshrpc.CommandFileCopyData, opts *wshrpc.RpcOpts) error {
 	_, err := sendRpcRequestCallHelper[any](w, "remotefilemove", data, opts)
 	return err
 }
 
+// command "remotefilesearch", wshserver.RemoteFileSearchCommand
+func RemoteFileSearchCommand(w *wshutil.WshRpc, data wshrpc.FileSearchData, opts *wshrpc.RpcOpts) chan wshrpc.RespOrErrorUnion[wshrpc.CommandFileSearchRtnData] {
+	return sendRpcRequestResponseStreamHelper[wshrpc.CommandFileSearchRtnData](w, "remotefilesearch", data, opts)
+}
+
 // command "remotefiletouch", wshserver.RemoteFileTouchCommand
 func RemoteFileTouchCommand(w *wshutil.WshRpc, data string, opts *wshrpc.RpcOpts) error {
 	_, err := sendRpcRequestCallHelper[any](w, "remotefiletouch", data, opts)
 	return err
 }
 
+// command "remotefilewatch", wshserver.RemoteFileWatchCommand
+func RemoteFileWatchCommand(w *wshutil.WshRpc, data wshrpc.CommandRemoteFileWatchData, opts *wshrpc.RpcOpts) chan wshrpc.RespOrErrorUnion[wshrpc.FileWatchEvent] {
+	return sendRpcRequestResponseStreamHelper[wshrpc.FileWatchEvent](w, "remotefilewatch", data, opts)
+}
+
 // command "remotegetinfo", wshserver.RemoteGetInfoCommand
 func RemoteGetInfoCommand(w *wshutil.WshRpc, opts *wshrpc.RpcOpts) (wshrpc.RemoteInfo, error) {
 	resp, err := sendRpcRequestCallHelper[wshrpc.RemoteInfo](w, "remotegetinfo", nil, opts)
@@ -420,6 +558,12 @@ func RemoteMkdirCommand(w *wshutil.WshRpc, data string, opts *wshrpc.RpcOpts) er
 	return err
 }
 
+// command "remoteproctree", wshserver.RemoteProcTreeCommand
+func RemoteProcTreeCommand(w *wshutil.WshRpc, data wshrpc.CommandRemoteProcTreeData, opts *wshrpc.RpcOpts) (*wshrpc.ProcTreeData, error) {
+	resp, err := sendRpcRequestCallHelper[*wshrpc.ProcTreeData](w, "remoteproctree", data, opts)
+	return resp, err
+}
+
 // command "remotestreamcpudata", wshserver.RemoteStreamCpuDataCommand
 func RemoteStreamCpuDataCommand(w *wshutil.WshRpc, opts *wshrpc.RpcOpts) chan wshrpc.RespOrErrorUnion[wshrpc.TimeSeriesData] {
 	return sendRpcRequestResponseStreamHelper[wshrpc.TimeSeriesData](w, "remotestreamcpudata", nil, opts)
@@ -459,6 +603,23 @@ func RouteUnannounceCommand(w *wshutil.WshRpc, opts *wshrpc.RpcOpts) error {
 	return err
 }
 
+// command "schedulelist", wshserver.ScheduleListCommand
+func ScheduleListCommand(w *wshutil.WshRpc, data wshrpc.CommandScheduleListData, opts *wshrpc.RpcOpts) ([]*wshrpc.ScheduleInfo, error) {
+	resp, err := sendRpcRequestCallHelper[[]*wshrpc.ScheduleInfo](w, "schedulelist", data, opts)
+	return resp, err
+}
+
+// command "scrollbackdump", wshserver.ScrollbackDumpCommand
+func ScrollbackDumpCommand(w *wshutil.WshRpc, data wshrpc.CommandScrollbackDumpData, opts *wshrpc.RpcOpts) chan wshrpc.RespOrErrorUnion[wshrpc.CommandScrollbackDumpRtnData] {
+	return sendRpcRequestResponseStreamHelper[wshrpc.CommandScrollbackDumpRtnData](w, "scrollbackdump", data, opts)
+}
+
+// command "scrollbackgrep", wshserver.ScrollbackGrepCommand
+func ScrollbackGrepCommand(w *wshutil.WshRpc, data wshrpc.CommandScrollbackGrepData, opts *wshrpc.RpcOpts) ([]*wshrpc.ScrollbackGrepMatch, error) {
+	resp, err := sendRpcRequestCallHelper[[]*wshrpc.ScrollbackGrepMatch](w, "scrollbackgrep", data, opts)
+	return resp, err
+}
+
 // command "sendtelemetry", wshserver.SendTelemetryCommand
 func SendTelemetryCommand(w *wshutil.WshRpc, opts *wshrpc.RpcOpts) error {
 	_, err := sendRpcRequestCallHelper[any](w, "sendtelemetry", nil, opts)
@@ -501,6 +662,11 @@ func SetWorkspaceWidgetConfigCommand(w *wshutil.WshRpc, data wshrpc.WorkspaceWid
 	return err
 }
 
+// command "streamblockprocstats", wshserver.StreamBlockProcStatsCommand
+func StreamBlockProcStatsCommand(w *wshutil.WshRpc, data wshrpc.BlockProcStatsRequest, opts *wshrpc.RpcOpts) chan wshrpc.RespOrErrorUnion[wshrpc.BlockProcStats] {
+	return sendRpcRequestResponseStreamHelper[wshrpc.BlockProcStats](w, "streamblockprocstats", data, opts)
+}
+
 // command "streamcpudata", wshserver.StreamCpuDataCommand
 func StreamCpuDataCommand(w *wshutil.WshRpc, data wshrpc.CpuDataRequest, opts *wshrpc.RpcOpts) chan wshrpc.RespOrErrorUnion[wshrpc.TimeSeriesData] {
 	return sendRpcRequestResponseStreamHelper[wshrpc.TimeSeriesData](w, "streamcpudata", data, opts)
@@ -522,6 +688,18 @@ func TestCommand(w *wshutil.WshRpc, data string, opts *wshrpc.RpcOpts) error {
 	return err
 }
 
+// command "trashlist", wshserver.TrashListCommand
+func TrashListCommand(w *wshutil.WshRpc, data wshrpc.CommandTrashListData, opts *wshrpc.RpcOpts) ([]*wshrpc.TrashEntry, error) {
+	resp, err := sendRpcRequestCallHelper[[]*wshrpc.TrashEntry](w, "trashlist", data, opts)
+	return resp, err
+}
+
+// command "trashrestore", wshserver.TrashRestoreCommand
+func TrashRestoreCommand(w *wshutil.WshRpc, data wshrpc.CommandTrashRestoreData, opts *wshrpc.RpcOpts) (*wshrpc.TrashRestoreRtnData, error) {
+	resp, err := sendRpcRequestCallHelper[*wshrpc.TrashRestoreRtnData](w, "trashrestore", data, opts)
+	return resp, err
+}
+
 // command "vdomasyncinitiation", wshserver.VDomAsyncInitiationCommand
 func VDomAsyncInitiationCommand(w *wshutil.WshRpc, data vdom.VDomAsyncInitiationRequest, opts *wshrpc.RpcOpts) error {
 	_, err := sendRpcRequestCallHelper[any](w, "vdomasyncinitiation", data, opts)
@@ -562,6 +740,18 @@ func WebSelectorCommand(w *wshutil.WshRpc, data wshrpc.CommandWebSelectorData, o
 	return resp, err
 }
 
+// command "workspaceexport", wshserver.WorkspaceExportCommand
+func WorkspaceExportCommand(w *wshutil.WshRpc, data wshrpc.CommandWorkspaceExportData, opts *wshrpc.RpcOpts) (*wshrpc.WorkspaceExportRtnData, error) {
+	resp, err := sendRpcRequestCallHelper[*wshrpc.WorkspaceExportRtnData](w, "workspaceexport", data, opts)
+	return resp, err
+}
+
+// command "workspaceimport", wshserver.WorkspaceImportCommand
+func WorkspaceImportCommand(w *wshutil.WshRpc, data wshrpc.CommandWorkspaceImportData, opts *wshrpc.RpcOpts) (*wshrpc.WorkspaceImportRtnData, error) {
+	resp, err := sendRpcRequestCallHelper[*wshrpc.WorkspaceImportRtnData](w, "workspaceimport", data, opts)
+	return resp, err
+}
+
 // command "workspacelist", wshserver.WorkspaceListCommand
 func WorkspaceListCommand(w *wshutil.WshRpc, opts *wshrpc.RpcOpts) ([]wshrpc.WorkspaceInfoData, error) {
 	resp, err := sendRpcRequestCallHelper[[]wshrpc.WorkspaceInfoData](w, "workspacelist", nil, opts)
@@ -0,0 +1,111 @@
+// Copyright 2025, Command Line Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package wshremote
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+
+	"github.com/wavetermdev/waveterm/pkg/remote/fileshare/fsutil"
+	"github.com/wavetermdev/waveterm/pkg/wavebase"
+	"github.com/wavetermdev/waveterm/pkg/wshrpc"
+	"github.com/wavetermdev/waveterm/pkg/wshutil"
+)
+
+// RemoteFileSearchCommand walks the tree at data.Path and streams back the files that match data.Opts.
+// Results are sent in chunks of DirChunkSize, or one at a time for content searches (which are slow enough that the caller wants to see matches as they are found).
+func (impl *ServerImpl) RemoteFileSearchCommand(ctx context.Context, data wshrpc.FileSearchData) <-chan wshrpc.RespOrErrorUnion[wshrpc.CommandFileSearchRtnData] {
+	ch := make(chan wshrpc.RespOrErrorUnion[wshrpc.CommandFileSearchRtnData], 16)
+	go func() {
+		defer close(ch)
+		searcher, err := fsutil.MakeFileSearcher(data.Opts)
+		if err != nil {
+			ch <- wshutil.RespErr[wshrpc.CommandFileSearchRtnData](err)
+			return
+		}
+		rootPath, err := wavebase.ExpandHomeDir(data.Path)
+		if err != nil {
+			ch <- wshutil.RespErr[wshrpc.CommandFileSearchRtnData](err)
+			return
+		}
+		rootPath = filepath.Clean(rootPath)
+		if _, err := os.Stat(rootPath); err != nil {
+			ch <- wshutil.RespErr[wshrpc.CommandFileSearchRtnData](fmt.Errorf("cannot stat %q: %w", data.Path, err))
+			return
+		}
+		var results []*wshrpc.FileSearchResult
+		flush := func() {
+			if len(results) > 0 {
+				ch <- wshrpc.RespOrErrorUnion[wshrpc.CommandFileSearchRtnData]{Response: wshrpc.CommandFileSearchRtnData{Results: results}}
+				results = nil
+			}
+		}
+		err = filepath.WalkDir(rootPath, func(path string, d fs.DirEntry, err error) error {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			if searcher.Done() {
+				return filepath.SkipAll
+			}
+			if err != nil {
+				if path == rootPath {
+					return err
+				}
+				// unreadable directories are skipped rather than failing the search
+				return nil
+			}
+			var relPath string
+			if path == rootPath {
+				if d.IsDir() {
+					return nil
+				}
+				relPath = d.Name()
+			} else {
+				relPath, err = filepath.Rel(rootPath, path)
+				if err != nil {
+					return err
+				}
+				relPath = filepath.ToSlash(relPath)
+			}
+			if searcher.IsExcluded(relPath) {
+				if d.IsDir() {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+			finfo, err := d.Info()
+			if err != nil {
+				// file disappeared during the walk
+				return nil
+			}
+			if !finfo.IsDir() && !finfo.Mode().IsRegular() {
+				return nil
+			}
+			result, err := searcher.Match(relPath, statToFileInfo(path, finfo, false), func() (io.ReadCloser, error) {
+				return os.Open(path)
+			})
+			if err != nil {
+				return err
+			}
+			if result == nil {
+				return nil
+			}
+			results = append(results, result)
+			if searcher.HasContentSearch() || len(results) >= wshrpc.DirChunkSize {
+				flush()
+			}
+			return nil
+		})
+		if err != nil {
+			ch <- wshutil.RespErr[wshrpc.CommandFileSearchRtnData](err)
+			return
+		}
+		flush()
+	}()
+	return ch
+}
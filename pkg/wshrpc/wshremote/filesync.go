@@ -0,0 +1,123 @@
+// Copyright 2025, Command Line Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package wshremote
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+
+	"github.com/wavetermdev/waveterm/pkg/remote/fileshare/fsutil"
+	"github.com/wavetermdev/waveterm/pkg/util/utilfn"
+	"github.com/wavetermdev/waveterm/pkg/wavebase"
+	"github.com/wavetermdev/waveterm/pkg/wshrpc"
+	"github.com/wavetermdev/waveterm/pkg/wshutil"
+)
+
+func fileChecksum(path string) (string, error) {
+	fd, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer utilfn.GracefulClose(fd, "fileChecksum", path)
+	hash := sha256.New()
+	if _, err := io.Copy(hash, fd); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// RemoteFileManifestCommand walks the directory at data.Path and returns an entry (relative path, size, modtime, optional sha256) for every file and directory.
+// A missing root returns an empty manifest.
+func (impl *ServerImpl) RemoteFileManifestCommand(ctx context.Context, data wshrpc.CommandRemoteFileManifestData) <-chan wshrpc.RespOrErrorUnion[wshrpc.CommandRemoteFileManifestRtnData] {
+	ch := make(chan wshrpc.RespOrErrorUnion[wshrpc.CommandRemoteFileManifestRtnData], 16)
+	go func() {
+		defer close(ch)
+		rootPath, err := wavebase.ExpandHomeDir(data.Path)
+		if err != nil {
+			ch <- wshutil.RespErr[wshrpc.CommandRemoteFileManifestRtnData](err)
+			return
+		}
+		rootPath = filepath.Clean(rootPath)
+		rootInfo, err := os.Stat(rootPath)
+		if errors.Is(err, fs.ErrNotExist) {
+			return
+		}
+		if err != nil {
+			ch <- wshutil.RespErr[wshrpc.CommandRemoteFileManifestRtnData](fmt.Errorf("cannot stat %q: %w", data.Path, err))
+			return
+		}
+		if !rootInfo.IsDir() {
+			ch <- wshutil.RespErr[wshrpc.CommandRemoteFileManifestRtnData](fmt.Errorf("%q is not a directory", data.Path))
+			return
+		}
+		var entries []*wshrpc.FileManifestEntry
+		err = filepath.WalkDir(rootPath, func(path string, d fs.DirEntry, err error) error {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			if err != nil {
+				return err
+			}
+			if path == rootPath {
+				return nil
+			}
+			relPath, err := filepath.Rel(rootPath, path)
+			if err != nil {
+				return err
+			}
+			relPath = filepath.ToSlash(relPath)
+			if fsutil.MatchExclude(relPath, data.Excludes) {
+				if d.IsDir() {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+			finfo, err := d.Info()
+			if err != nil {
+				// file disappeared during the walk
+				return nil
+			}
+			if !finfo.IsDir() && !finfo.Mode().IsRegular() {
+				// skip symlinks, devices, sockets, etc.
+				return nil
+			}
+			entry := &wshrpc.FileManifestEntry{
+				Path:    relPath,
+				ModTime: finfo.ModTime().UnixMilli(),
+				Mode:    finfo.Mode(),
+				IsDir:   finfo.IsDir(),
+			}
+			if !finfo.IsDir() {
+				entry.Size = finfo.Size()
+				if data.Checksum {
+					entry.Checksum, err = fileChecksum(path)
+					if err != nil {
+						return fmt.Errorf("cannot compute checksum for %q: %w", path, err)
+					}
+				}
+			}
+			entries = append(entries, entry)
+			if len(entries) >= wshrpc.DirChunkSize {
+				ch <- wshrpc.RespOrErrorUnion[wshrpc.CommandRemoteFileManifestRtnData]{Response: wshrpc.CommandRemoteFileManifestRtnData{Entries: entries}}
+				entries = nil
+			}
+			return nil
+		})
+		if err != nil {
+			ch <- wshutil.RespErr[wshrpc.CommandRemoteFileManifestRtnData](err)
+			return
+		}
+		if len(entries) > 0 {
+			ch <- wshrpc.RespOrErrorUnion[wshrpc.CommandRemoteFileManifestRtnData]{Response: wshrpc.CommandRemoteFileManifestRtnData{Entries: entries}}
+		}
+	}()
+	return ch
+}
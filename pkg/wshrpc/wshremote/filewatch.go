@@ -0,0 +1,164 @@
+// Copyright 2025, Command Line Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package wshremote
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"log"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/wavetermdev/waveterm/pkg/panichandler"
+	"github.com/wavetermdev/waveterm/pkg/remote/fileshare/fsutil"
+	"github.com/wavetermdev/waveterm/pkg/wavebase"
+	"github.com/wavetermdev/waveterm/pkg/wshrpc"
+	"github.com/wavetermdev/waveterm/pkg/wshutil"
+)
+
+// fsnotify op to FileWatchOp (chmod events are ignored)
+func fileWatchOp(op fsnotify.Op) string {
+	switch {
+	case op.Has(fsnotify.Create):
+		return wshrpc.FileWatchOp_Create
+	case op.Has(fsnotify.Write):
+		return wshrpc.FileWatchOp_Write
+	case op.Has(fsnotify.Remove):
+		return wshrpc.FileWatchOp_Remove
+	case op.Has(fsnotify.Rename):
+		return wshrpc.FileWatchOp_Rename
+	}
+	return ""
+}
+
+type fileWatchState struct {
+	watcher   *fsnotify.Watcher
+	rootPath  string
+	rootIsDir bool
+	recursive bool
+	excludes  []string
+}
+
+func (ws *fileWatchState) isExcluded(path string) bool {
+	if path == ws.rootPath || !ws.rootIsDir {
+		return false
+	}
+	relPath, err := filepath.Rel(ws.rootPath, path)
+	if err != nil {
+		return false
+	}
+	return fsutil.MatchExclude(filepath.ToSlash(relPath), ws.excludes)
+}
+
+// addDir adds dirPath to the watcher, and all of its subdirectories if the watch is recursive
+func (ws *fileWatchState) addDir(dirPath string) error {
+	if !ws.recursive {
+		return ws.watcher.Add(dirPath)
+	}
+	return filepath.WalkDir(dirPath, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if path == dirPath {
+				return err
+			}
+			return nil
+		}
+		if !d.IsDir() {
+			return nil
+		}
+		if ws.isExcluded(path) {
+			return filepath.SkipDir
+		}
+		if err := ws.watcher.Add(path); err != nil {
+			if path == dirPath {
+				return err
+			}
+			log.Printf("filewatch: cannot watch %q: %v\n", path, err)
+		}
+		return nil
+	})
+}
+
+// RemoteFileWatchCommand streams change events for data.Path until the request is canceled.
+// A file is watched through its parent directory so that editors that replace files on save are handled.
+func (impl *ServerImpl) RemoteFileWatchCommand(ctx context.Context, data wshrpc.CommandRemoteFileWatchData) <-chan wshrpc.RespOrErrorUnion[wshrpc.FileWatchEvent] {
+	rootPath, err := wavebase.ExpandHomeDir(data.Path)
+	if err != nil {
+		return wshutil.SendErrCh[wshrpc.FileWatchEvent](err)
+	}
+	rootPath = filepath.Clean(rootPath)
+	rootInfo, err := os.Stat(rootPath)
+	if err != nil {
+		return wshutil.SendErrCh[wshrpc.FileWatchEvent](fmt.Errorf("cannot stat %q: %w", data.Path, err))
+	}
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return wshutil.SendErrCh[wshrpc.FileWatchEvent](fmt.Errorf("cannot create file watcher: %w", err))
+	}
+	ws := &fileWatchState{
+		watcher:   watcher,
+		rootPath:  rootPath,
+		rootIsDir: rootInfo.IsDir(),
+		recursive: data.Recursive && rootInfo.IsDir(),
+		excludes:  data.Excludes,
+	}
+	if ws.rootIsDir {
+		err = ws.addDir(rootPath)
+	} else {
+		err = watcher.Add(filepath.Dir(rootPath))
+	}
+	if err != nil {
+		watcher.Close()
+		return wshutil.SendErrCh[wshrpc.FileWatchEvent](fmt.Errorf("cannot watch %q: %w", data.Path, err))
+	}
+	ch := make(chan wshrpc.RespOrErrorUnion[wshrpc.FileWatchEvent], 32)
+	go func() {
+		defer func() {
+			panichandler.PanicHandler("RemoteFileWatchCommand", recover())
+		}()
+		defer close(ch)
+		defer watcher.Close()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case err, ok := <-watcher.Errors:
+				if !ok {
+					return
+				}
+				log.Printf("filewatch error for %q: %v\n", rootPath, err)
+			case event, ok := <-watcher.Events:
+				if !ok {
+					return
+				}
+				if !ws.rootIsDir && event.Name != rootPath {
+					continue
+				}
+				op := fileWatchOp(event.Op)
+				if op == "" || ws.isExcluded(event.Name) {
+					continue
+				}
+				watchEvent := wshrpc.FileWatchEvent{Path: event.Name, Op: op, Ts: time.Now().UnixMilli()}
+				if op == wshrpc.FileWatchOp_Create || op == wshrpc.FileWatchOp_Write {
+					if finfo, err := os.Stat(event.Name); err == nil && finfo.IsDir() {
+						watchEvent.IsDir = true
+						if op == wshrpc.FileWatchOp_Create && ws.recursive {
+							if err := ws.addDir(event.Name); err != nil {
+								log.Printf("filewatch: cannot watch new directory %q: %v\n", event.Name, err)
+							}
+						}
+					}
+				}
+				select {
+				case ch <- wshrpc.RespOrErrorUnion[wshrpc.FileWatchEvent]{Response: watchEvent}:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}
+	}()
+	return ch
+}
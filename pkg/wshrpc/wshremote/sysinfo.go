@@ -4,12 +4,14 @@
 package wshremote
 
 import (
+	"context"
 	"log"
 	"strconv"
 	"time"
 
 	"github.com/shirou/gopsutil/v4/cpu"
 	"github.com/shirou/gopsutil/v4/mem"
+	"github.com/wavetermdev/waveterm/pkg/util/procutil"
 	"github.com/wavetermdev/waveterm/pkg/wps"
 	"github.com/wavetermdev/waveterm/pkg/wshrpc"
 	"github.com/wavetermdev/waveterm/pkg/wshrpc/wshclient"
@@ -70,3 +72,19 @@ func RunSysInfoLoop(client *wshutil.WshRpc, connName string) {
 		time.Sleep(1 * time.Second)
 	}
 }
+
+func (impl *ServerImpl) RemoteProcTreeCommand(ctx context.Context, data wshrpc.CommandRemoteProcTreeData) (*wshrpc.ProcTreeData, error) {
+	rootPid, err := procutil.FindBlockRootPid(data.BlockId, data.Pid)
+	if err != nil {
+		return nil, err
+	}
+	tree, err := procutil.GetProcTree(rootPid)
+	if err != nil || !data.Ports {
+		return tree, err
+	}
+	tree.Ports, err = procutil.GetListeningPorts(tree.Procs)
+	if err != nil {
+		return nil, err
+	}
+	return tree, nil
+}
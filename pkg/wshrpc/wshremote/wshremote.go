@@ -19,6 +19,7 @@ import (
 
 	"github.com/wavetermdev/waveterm/pkg/remote/connparse"
 	"github.com/wavetermdev/waveterm/pkg/remote/fileshare/fstype"
+	"github.com/wavetermdev/waveterm/pkg/remote/fileshare/fsutil"
 	"github.com/wavetermdev/waveterm/pkg/remote/fileshare/wshfs"
 	"github.com/wavetermdev/waveterm/pkg/suggestion"
 	"github.com/wavetermdev/waveterm/pkg/util/fileutil"
@@ -153,8 +154,10 @@ func (impl *ServerImpl) remoteStreamFileRegular(ctx context.Context, path string
 			if !byteRange.All && filePos+int64(n) > byteRange.End {
 				n = int(byteRange.End - filePos)
 			}
+			// the range of this chunk (so FileData.At has the offset of the data)
+			chunkRange := ByteRangeType{All: byteRange.All, Start: filePos, End: filePos + int64(n)}
 			filePos += int64(n)
-			dataCallback(nil, buf[:n], byteRange)
+			dataCallback(nil, buf[:n], chunkRange)
 		}
 		if !byteRange.All && filePos >= byteRange.End {
 			break
@@ -254,6 +257,10 @@ func (impl *ServerImpl) RemoteTarStreamCommand(ctx context.Context, data wshrpc.
 	}
 	readerCtx, cancel := context.WithTimeout(ctx, timeout)
 	rtn, writeHeader, fileWriter, tarClose := tarcopy.TarCopySrc(readerCtx, pathPrefix)
+	var fileFilter *fsutil.FileFilter
+	if !singleFile {
+		fileFilter = fsutil.MakeFileFilter(opts.Files)
+	}
 
 	go func() {
 		defer func() {
@@ -267,6 +274,18 @@ func (impl *ServerImpl) RemoteTarStreamCommand(ctx context.Context, data wshrpc.
 			if err != nil {
 				return err
 			}
+			if fileFilter != nil {
+				relPath, err := filepath.Rel(cleanedPath, path)
+				if err != nil {
+					return err
+				}
+				if info.IsDir() && !fileFilter.HasDir(relPath) {
+					return filepath.SkipDir
+				}
+				if !info.IsDir() && !fileFilter.HasFile(relPath) {
+					return nil
+				}
+			}
 			if err = writeHeader(info, path, singleFile); err != nil {
 				return err
 			}
@@ -817,6 +836,12 @@ func (*ServerImpl) RemoteWriteFileCommand(ctx context.Context, data wshrpc.FileD
 	if err != nil {
 		return fmt.Errorf("cannot write to file %q: %w", path, err)
 	}
+	if data.Info.Opts != nil && data.Info.Opts.ModTime > 0 {
+		modTime := time.UnixMilli(data.Info.Opts.ModTime)
+		if err := os.Chtimes(path, modTime, modTime); err != nil {
+			return fmt.Errorf("cannot set modtime of file %q: %w", path, err)
+		}
+	}
 	return nil
 }
 
@@ -59,6 +59,7 @@ const (
 	Command_Message           = "message"
 	Command_GetMeta           = "getmeta"
 	Command_SetMeta           = "setmeta"
+	Command_PatchMeta         = "patchmeta"
 	Command_SetView           = "setview"
 	Command_ControllerInput   = "controllerinput"
 	Command_ControllerRestart = "controllerrestart"
@@ -69,6 +70,25 @@ const (
 	Command_BlockInfo         = "blockinfo"
 	Command_CreateBlock       = "createblock"
 	Command_DeleteBlock       = "deleteblock"
+	Command_ScrollbackDump    = "scrollbackdump"
+	Command_ScrollbackGrep    = "scrollbackgrep"
+	Command_HistoryList       = "historylist"
+	Command_HistoryGetOutput  = "historygetoutput"
+	Command_RecordStart       = "recordstart"
+	Command_RecordStop        = "recordstop"
+	Command_ScheduleList      = "schedulelist"
+	Command_BlocksList        = "blockslist"
+	Command_PortsList         = "portslist"
+	Command_PortOpen          = "portopen"
+	Command_InputGroupList    = "inputgrouplist"
+	Command_InputGroupSet     = "inputgroupset"
+	Command_InputGroupSend    = "inputgroupsend"
+	Command_BackupCreate      = "backupcreate"
+	Command_BackupRestore     = "backuprestore"
+	Command_TrashList         = "trashlist"
+	Command_TrashRestore      = "trashrestore"
+	Command_WorkspaceExport   = "workspaceexport"
+	Command_WorkspaceImport   = "workspaceimport"
 
 	Command_FileWrite           = "filewrite"
 	Command_FileRead            = "fileread"
@@ -80,6 +100,13 @@ const (
 	Command_FileAppendIJson     = "fileappendijson"
 	Command_FileJoin            = "filejoin"
 	Command_FileShareCapability = "filesharecapability"
+	Command_FileSync            = "filesync"
+	Command_FileSearch          = "filesearch"
+	Command_FileWatch           = "filewatch"
+	Command_FileStoreRecompress = "filestorerecompress"
+	Command_FileStoreGC         = "filestoregc"
+	Command_FileStoreUsage      = "filestoreusage"
+	Command_DBEncrypt           = "dbencrypt"
 
 	Command_EventPublish         = "eventpublish"
 	Command_EventRecv            = "eventrecv"
@@ -87,9 +114,11 @@ const (
 	Command_EventUnsub           = "eventunsub"
 	Command_EventUnsubAll        = "eventunsuball"
 	Command_EventReadHistory     = "eventreadhistory"
+	Command_EventReplay          = "eventreplay"
 	Command_StreamTest           = "streamtest"
 	Command_StreamWaveAi         = "streamwaveai"
 	Command_StreamCpuData        = "streamcpudata"
+	Command_StreamBlockProcStats = "streamblockprocstats"
 	Command_Test                 = "test"
 	Command_SetConfig                = "setconfig"
 	Command_SetConnectionsConfig     = "connectionsconfig"
@@ -104,6 +133,9 @@ const (
 
 	Command_RemoteFileDelete     = "remotefiledelete"
 	Command_RemoteFileJoin       = "remotefilejoin"
+	Command_RemoteFileManifest   = "remotefilemanifest"
+	Command_RemoteFileSearch     = "remotefilesearch"
+	Command_RemoteFileWatch      = "remotefilewatch"
 	Command_WaveInfo             = "waveinfo"
 	Command_WshActivity          = "wshactivity"
 	Command_Activity             = "activity"
@@ -112,6 +144,7 @@ const (
 	Command_RemoteMkdir          = "remotemkdir"
 	Command_RemoteGetInfo        = "remotegetinfo"
 	Command_RemoteInstallRcfiles = "remoteinstallrcfiles"
+	Command_RemoteProcTree       = "remoteproctree"
 
 	Command_ConnStatus       = "connstatus"
 	Command_WslStatus        = "wslstatus"
@@ -156,6 +189,7 @@ type WshRpcInterface interface {
 	MessageCommand(ctx context.Context, data CommandMessageData) error
 	GetMetaCommand(ctx context.Context, data CommandGetMetaData) (waveobj.MetaMapType, error)
 	SetMetaCommand(ctx context.Context, data CommandSetMetaData) error
+	PatchMetaCommand(ctx context.Context, data CommandPatchMetaData) (waveobj.MetaMapType, error)
 	SetViewCommand(ctx context.Context, data CommandBlockSetViewData) error
 	ControllerInputCommand(ctx context.Context, data CommandBlockInputData) error
 	ControllerStopCommand(ctx context.Context, blockId string) error
@@ -183,6 +217,9 @@ type WshRpcInterface interface {
 	FileListCommand(ctx context.Context, data FileListData) ([]*FileInfo, error)
 	FileJoinCommand(ctx context.Context, paths []string) (*FileInfo, error)
 	FileListStreamCommand(ctx context.Context, data FileListData) <-chan RespOrErrorUnion[CommandRemoteListEntriesRtnData]
+	FileSyncCommand(ctx context.Context, data CommandFileSyncData) <-chan RespOrErrorUnion[FileSyncUpdate]
+	FileSearchCommand(ctx context.Context, data FileSearchData) <-chan RespOrErrorUnion[CommandFileSearchRtnData]
+	FileWatchCommand(ctx context.Context, data CommandFileWatchData) <-chan RespOrErrorUnion[FileWatchEvent]
 
 	FileShareCapabilityCommand(ctx context.Context, path string) (FileShareCapability, error)
 	EventPublishCommand(ctx context.Context, data wps.WaveEvent) error
@@ -190,9 +227,11 @@ type WshRpcInterface interface {
 	EventUnsubCommand(ctx context.Context, data string) error
 	EventUnsubAllCommand(ctx context.Context) error
 	EventReadHistoryCommand(ctx context.Context, data CommandEventReadHistoryData) ([]*wps.WaveEvent, error)
+	EventReplayCommand(ctx context.Context, data CommandEventReplayData) (*EventReplayRtnData, error)
 	StreamTestCommand(ctx context.Context) chan RespOrErrorUnion[int]
 	StreamWaveAiCommand(ctx context.Context, request WaveAIStreamRequest) chan RespOrErrorUnion[WaveAIPacketType]
 	StreamCpuDataCommand(ctx context.Context, request CpuDataRequest) chan RespOrErrorUnion[TimeSeriesData]
+	StreamBlockProcStatsCommand(ctx context.Context, request BlockProcStatsRequest) chan RespOrErrorUnion[BlockProcStats]
 	TestCommand(ctx context.Context, data string) error
 	SetConfigCommand(ctx context.Context, data MetaSettingsType) error
 	SetConnectionsConfigCommand(ctx context.Context, data ConnConfigRequest) error
@@ -211,6 +250,29 @@ type WshRpcInterface interface {
 	FetchSuggestionsCommand(ctx context.Context, data FetchSuggestionsData) (*FetchSuggestionsResponse, error)
 	DisposeSuggestionsCommand(ctx context.Context, widgetId string) error
 	GetTabCommand(ctx context.Context, tabId string) (*waveobj.Tab, error)
+	ScrollbackDumpCommand(ctx context.Context, data CommandScrollbackDumpData) chan RespOrErrorUnion[CommandScrollbackDumpRtnData]
+	ScrollbackGrepCommand(ctx context.Context, data CommandScrollbackGrepData) ([]*ScrollbackGrepMatch, error)
+	HistoryListCommand(ctx context.Context, data CommandHistoryListData) ([]*CmdHistoryEntry, error)
+	HistoryGetOutputCommand(ctx context.Context, data CommandHistoryGetOutputData) (*CommandHistoryGetOutputRtnData, error)
+	RecordStartCommand(ctx context.Context, data CommandRecordStartData) (*CommandRecordRtnData, error)
+	RecordStopCommand(ctx context.Context, data CommandRecordStopData) (*CommandRecordRtnData, error)
+	ScheduleListCommand(ctx context.Context, data CommandScheduleListData) ([]*ScheduleInfo, error)
+	BlocksListCommand(ctx context.Context, data CommandBlocksListData) ([]*BlocksListEntry, error)
+	PortsListCommand(ctx context.Context, data CommandPortsListData) ([]*BlockPortsData, error)
+	PortOpenCommand(ctx context.Context, data CommandPortOpenData) (*PortOpenRtnData, error)
+	InputGroupListCommand(ctx context.Context) ([]*InputGroupInfo, error)
+	InputGroupSetCommand(ctx context.Context, data CommandInputGroupSetData) error
+	InputGroupSendCommand(ctx context.Context, data CommandInputGroupSendData) error
+	FileStoreRecompressCommand(ctx context.Context, data CommandFileStoreRecompressData) (*FileStoreRecompressRtnData, error)
+	FileStoreGCCommand(ctx context.Context, data CommandFileStoreGCData) (*FileStoreGCRtnData, error)
+	FileStoreUsageCommand(ctx context.Context, data CommandFileStoreUsageData) (*FileStoreUsageRtnData, error)
+	DBEncryptCommand(ctx context.Context, data CommandDBEncryptData) (*DBEncryptRtnData, error)
+	BackupCreateCommand(ctx context.Context, data CommandBackupCreateData) (*BackupCreateRtnData, error)
+	BackupRestoreCommand(ctx context.Context, data CommandBackupRestoreData) (*BackupRestoreRtnData, error)
+	TrashListCommand(ctx context.Context, data CommandTrashListData) ([]*TrashEntry, error)
+	TrashRestoreCommand(ctx context.Context, data CommandTrashRestoreData) (*TrashRestoreRtnData, error)
+	WorkspaceExportCommand(ctx context.Context, data CommandWorkspaceExportData) (*WorkspaceExportRtnData, error)
+	WorkspaceImportCommand(ctx context.Context, data CommandWorkspaceImportData) (*WorkspaceImportRtnData, error)
 
 	// connection functions
 	ConnStatusCommand(ctx context.Context) ([]ConnStatus, error)
@@ -240,10 +302,14 @@ type WshRpcInterface interface {
 	RemoteFileDeleteCommand(ctx context.Context, data CommandDeleteFileData) error
 	RemoteWriteFileCommand(ctx context.Context, data FileData) error
 	RemoteFileJoinCommand(ctx context.Context, paths []string) (*FileInfo, error)
+	RemoteFileManifestCommand(ctx context.Context, data CommandRemoteFileManifestData) <-chan RespOrErrorUnion[CommandRemoteFileManifestRtnData]
+	RemoteFileSearchCommand(ctx context.Context, data FileSearchData) <-chan RespOrErrorUnion[CommandFileSearchRtnData]
+	RemoteFileWatchCommand(ctx context.Context, data CommandRemoteFileWatchData) <-chan RespOrErrorUnion[FileWatchEvent]
 	RemoteMkdirCommand(ctx context.Context, path string) error
 	RemoteStreamCpuDataCommand(ctx context.Context) chan RespOrErrorUnion[TimeSeriesData]
 	RemoteGetInfoCommand(ctx context.Context) (RemoteInfo, error)
 	RemoteInstallRcFilesCommand(ctx context.Context) error
+	RemoteProcTreeCommand(ctx context.Context, data CommandRemoteProcTreeData) (*ProcTreeData, error)
 
 	// emain
 	WebSelectorCommand(ctx context.Context, data CommandWebSelectorData) ([]string, error)
@@ -353,6 +419,12 @@ type CommandSetMetaData struct {
 	Meta waveobj.MetaMapType `json:"meta"`
 }
 
+// ijson commands (set, del, append with a path into the meta), applied atomically
+type CommandPatchMetaData struct {
+	ORef     waveobj.ORef    `json:"oref" wshcontext:"BlockORef"`
+	Commands []ijson.Command `json:"commands"`
+}
+
 type CommandResolveIdsData struct {
 	BlockId string   `json:"blockid" wshcontext:"BlockId"`
 	Ids     []string `json:"ids"`
@@ -437,6 +509,8 @@ type FileOpts struct {
 	IJsonBudget int   `json:"ijsonbudget,omitempty"`
 	Truncate    bool  `json:"truncate,omitempty"`
 	Append      bool  `json:"append,omitempty"`
+	Compress    bool  `json:"compress,omitempty"` // data parts are compressed in the db
+	ModTime     int64 `json:"modtime,omitempty"`  // unix ms, set as the file's modtime after a write (if supported)
 }
 
 type FileMeta = map[string]any
@@ -454,6 +528,66 @@ type FileListOpts struct {
 	Limit  int  `json:"limit,omitempty"`
 }
 
+type FileSearchData struct {
+	Path string          `json:"path"`
+	Opts *FileSearchOpts `json:"opts,omitempty"`
+}
+
+type FileSearchOpts struct {
+	NamePatterns   []string `json:"namepatterns,omitempty"`   // glob patterns matched against the file name (any may match)
+	ContentRegex   string   `json:"contentregex,omitempty"`   // if set, only files with a matching line are returned
+	IgnoreCase     bool     `json:"ignorecase,omitempty"`     // applies to NamePatterns and ContentRegex
+	MinSize        int64    `json:"minsize,omitempty"`        // in bytes
+	MaxSize        int64    `json:"maxsize,omitempty"`        // in bytes, 0 means no limit
+	ModifiedAfter  int64    `json:"modifiedafter,omitempty"`  // unix ms
+	ModifiedBefore int64    `json:"modifiedbefore,omitempty"` // unix ms
+	IncludeDirs    bool     `json:"includedirs,omitempty"`    // also return directories (ignored if ContentRegex is set)
+	Excludes       []string `json:"excludes,omitempty"`       // glob patterns for paths to skip (see FileSyncOpts)
+	MaxResults     int      `json:"maxresults,omitempty"`
+	Timeout        int64    `json:"timeout,omitempty"`
+}
+
+type FileSearchResult struct {
+	Info    *FileInfo              `json:"info"`
+	Matches []*FileSearchLineMatch `json:"matches,omitempty"`
+}
+
+type FileSearchLineMatch struct {
+	LineNum int    `json:"linenum"`
+	Line    string `json:"line"`
+}
+
+type CommandFileSearchRtnData struct {
+	Results []*FileSearchResult `json:"results"`
+}
+
+const (
+	FileWatchOp_Create = "create"
+	FileWatchOp_Write  = "write"
+	FileWatchOp_Remove = "remove"
+	FileWatchOp_Rename = "rename"
+)
+
+type CommandFileWatchData struct {
+	Uri       string   `json:"uri"`
+	Recursive bool     `json:"recursive,omitempty"`
+	Excludes  []string `json:"excludes,omitempty"` // glob patterns for paths to ignore (see FileSyncOpts)
+}
+
+type CommandRemoteFileWatchData struct {
+	Path      string   `json:"path"`
+	Recursive bool     `json:"recursive,omitempty"`
+	Excludes  []string `json:"excludes,omitempty"`
+}
+
+// FileWatchEvent.Path is a local path in RemoteFileWatchCommand responses, and a full URI in FileWatchCommand responses
+type FileWatchEvent struct {
+	Path  string `json:"path"`
+	Op    string `json:"op"`
+	IsDir bool   `json:"isdir,omitempty"`
+	Ts    int64  `json:"ts"`
+}
+
 type FileCreateData struct {
 	Path string         `json:"path"`
 	Meta map[string]any `json:"meta,omitempty"`
@@ -475,12 +609,460 @@ type CommandDeleteBlockData struct {
 	BlockId string `json:"blockid" wshcontext:"BlockId"`
 }
 
+const (
+	ScrollbackFormat_Raw  = "raw"
+	ScrollbackFormat_Text = "text"
+	ScrollbackFormat_Html = "html"
+)
+
+type CommandScrollbackDumpData struct {
+	BlockId string `json:"blockid" wshcontext:"BlockId"`
+	Format  string `json:"format,omitempty"` // raw (default), text, html
+}
+
+type CommandScrollbackDumpRtnData struct {
+	BlockId   string `json:"blockid"`
+	Offset    int64  `json:"offset"` // file offset of the part (term files are circular)
+	Data64    string `json:"data64"` // the next part of the output
+	Truncated bool   `json:"truncated,omitempty"`
+}
+
+const (
+	ScrollbackScope_Block = "block"
+	ScrollbackScope_Tab   = "tab"
+	ScrollbackScope_All   = "all"
+)
+
+type CommandScrollbackGrepData struct {
+	Scope        string `json:"scope,omitempty"` // block (default), tab, all
+	BlockId      string `json:"blockid,omitempty" wshcontext:"BlockId"`
+	TabId        string `json:"tabid,omitempty" wshcontext:"TabId"`
+	Pattern      string `json:"pattern"`
+	IgnoreCase   bool   `json:"ignorecase,omitempty"`
+	ContextLines int    `json:"contextlines,omitempty"`
+	MaxMatches   int    `json:"maxmatches,omitempty"`
+}
+
+type ScrollbackGrepMatch struct {
+	BlockId     string   `json:"blockid"`
+	LineNum     int      `json:"linenum"`     // 1-based, relative to the start of the stored output
+	StartOffset int64    `json:"startoffset"` // term file offset of the first byte of the match
+	EndOffset   int64    `json:"endoffset"`   // term file offset just past the last byte of the match
+	Line        string   `json:"line"`
+	Before      []string `json:"before,omitempty"`
+	After       []string `json:"after,omitempty"`
+}
+
+// a command recorded from the OSC 133 shell integration markers
+type CmdHistoryEntry struct {
+	HistoryId   string `json:"historyid"`
+	BlockId     string `json:"blockid"`
+	Conn        string `json:"conn"`
+	CmdText     string `json:"cmdtext"`
+	Cwd         string `json:"cwd,omitempty"`
+	StartTs     int64  `json:"startts"`
+	EndTs       int64  `json:"endts,omitempty"`
+	ExitCode    *int   `json:"exitcode,omitempty"` // nil if the shell did not report one
+	OutputStart int64  `json:"outputstart"`        // term file offset of the first byte of output
+	OutputEnd   int64  `json:"outputend"`          // term file offset just past the last byte of output
+}
+
+type CommandHistoryListData struct {
+	BlockId    string `json:"blockid,omitempty"`
+	Conn       string `json:"conn,omitempty"`
+	Search     string `json:"search,omitempty"` // substring of the command text
+	FailedOnly bool   `json:"failedonly,omitempty"`
+	Since      int64  `json:"since,omitempty"` // unix ms
+	Limit      int    `json:"limit,omitempty"`
+}
+
+type CommandHistoryGetOutputData struct {
+	HistoryId string `json:"historyid"`
+	Format    string `json:"format,omitempty"` // ScrollbackFormat_Raw (default) or ScrollbackFormat_Text
+}
+
+type CommandHistoryGetOutputRtnData struct {
+	Entry     *CmdHistoryEntry `json:"entry"`
+	Data64    string           `json:"data64"`
+	Truncated bool             `json:"truncated,omitempty"` // the start of the output has been overwritten in the term file
+}
+
+type CommandRecordStartData struct {
+	BlockId     string `json:"blockid" wshcontext:"BlockId"`
+	Path        string `json:"path,omitempty"` // local file to write the recording to (defaults to the block's "cast" file)
+	RecordInput bool   `json:"recordinput,omitempty"`
+}
+
+type CommandRecordStopData struct {
+	BlockId string `json:"blockid" wshcontext:"BlockId"`
+}
+
+type CommandRecordRtnData struct {
+	Location string `json:"location"` // local path or wavefile:// URI of the recording
+	Active   bool   `json:"active"`   // false if the block is not running (recording starts with the shell)
+	Size     int64  `json:"size,omitempty"`
+}
+
+type CommandScheduleListData struct {
+	BlockId string `json:"blockid,omitempty"` // all scheduled blocks if empty
+}
+
+type ScheduleRun struct {
+	StartTs  int64 `json:"startts"`
+	EndTs    int64 `json:"endts,omitempty"`
+	ExitCode *int  `json:"exitcode,omitempty"`
+	Skipped  bool  `json:"skipped,omitempty"` // skipped because the previous run was still running
+}
+
+type ScheduleInfo struct {
+	BlockId   string         `json:"blockid"`
+	Schedule  string         `json:"schedule"`
+	Paused    bool           `json:"paused,omitempty"`
+	NextRunTs int64          `json:"nextrunts,omitempty"`
+	Error     string         `json:"error,omitempty"`
+	Runs      []*ScheduleRun `json:"runs,omitempty"` // oldest first
+}
+
+type BlockProcStatsRequest struct {
+	BlockId  string `json:"blockid"`
+	Interval int    `json:"interval,omitempty"` // ms between samples, default 1000
+	Count    int    `json:"count,omitempty"`    // number of samples, 0 for no limit
+}
+
+// usage of the process tree under a block's shell
+type BlockProcStats struct {
+	BlockId  string   `json:"blockid"`
+	Ts       int64    `json:"ts"`
+	RootPid  int32    `json:"rootpid"`
+	NumProcs int      `json:"numprocs"`
+	Cpu      float64  `json:"cpu"` // percent of one cpu, summed over the tree
+	Rss      int64    `json:"rss"` // bytes
+	NumFds   int      `json:"numfds"`
+	Children []string `json:"children,omitempty"` // names of the processes under the shell, busiest first
+}
+
+type ProcInfo struct {
+	Pid       int32   `json:"pid"`
+	PPid      int32   `json:"ppid"`
+	Name      string  `json:"name"`
+	CpuTime   float64 `json:"cputime"` // user + system, in seconds
+	Rss       int64   `json:"rss"`
+	NumFds    int32   `json:"numfds"`    // -1 if unknown
+	StartTime int64   `json:"starttime"` // only used to detect reused pids
+}
+
+type ProcTreeData struct {
+	Ts      int64            `json:"ts"`
+	RootPid int32            `json:"rootpid"`
+	Procs   []*ProcInfo      `json:"procs"`           // root first
+	Ports   []*ListeningPort `json:"ports,omitempty"` // only if requested
+}
+
+type CommandRemoteProcTreeData struct {
+	BlockId string `json:"blockid"`
+	Pid     int32  `json:"pid,omitempty"`   // the root pid from a previous call, if known
+	Ports   bool   `json:"ports,omitempty"` // also find the tcp ports the processes are listening on
+}
+
+type ListeningPort struct {
+	Port    int    `json:"port"`
+	Address string `json:"address"` // e.g. 127.0.0.1, 0.0.0.0, ::
+	Pid     int32  `json:"pid"`
+	Name    string `json:"name,omitempty"` // process name
+}
+
+// the ports a block's processes are listening on (also the data of block:ports events)
+type BlockPortsData struct {
+	BlockId    string           `json:"blockid"`
+	Connection string           `json:"connection,omitempty"`
+	Ports      []*ListeningPort `json:"ports"`
+	Opened     []*ListeningPort `json:"opened,omitempty"` // events only, ports opened since the last event
+	Closed     []*ListeningPort `json:"closed,omitempty"` // events only
+}
+
+type CommandPortsListData struct {
+	BlockId string `json:"blockid,omitempty"`
+}
+
+type CommandPortOpenData struct {
+	BlockId string `json:"blockid"`
+	Port    int    `json:"port"`
+}
+
+type PortOpenRtnData struct {
+	Url     string `json:"url"`
+	BlockId string `json:"blockid"` // the new web block
+}
+
+type InputGroupMember struct {
+	BlockId string `json:"blockid"`
+	Status  string `json:"status,omitempty"` // controller status, empty if the block has no controller
+	Confirm bool   `json:"confirm,omitempty"`
+}
+
+type InputGroupInfo struct {
+	Name    string              `json:"name"`
+	Members []*InputGroupMember `json:"members"`
+}
+
+// sets the input group of the blocks, an empty group removes them from their group
+type CommandInputGroupSetData struct {
+	BlockIds []string `json:"blockids"`
+	Group    string   `json:"group"`
+	Confirm  *bool    `json:"confirm,omitempty"`
+}
+
+type CommandInputGroupSendData struct {
+	Group       string `json:"group"`
+	InputData64 string `json:"inputdata64"`
+}
+
+// rewrites the data of all files (or the files in a zone) compressed or uncompressed
+type CommandFileStoreRecompressData struct {
+	ZoneId   string `json:"zoneid,omitempty"`
+	Compress bool   `json:"compress"`
+}
+
+type FileStoreRecompressRtnData struct {
+	NumFiles int   `json:"numfiles"`
+	NumParts int   `json:"numparts"`
+	OldBytes int64 `json:"oldbytes"`
+	NewBytes int64 `json:"newbytes"`
+}
+
+// removes the filestore zones of objects that no longer exist
+type CommandFileStoreGCData struct {
+	DryRun bool `json:"dryrun,omitempty"`
+	Vacuum bool `json:"vacuum,omitempty"` // vacuum even if there is little free space in the db
+}
+
+type FileStoreGCZone struct {
+	ZoneId    string `json:"zoneid"`
+	NumFiles  int    `json:"numfiles"`
+	Size      int64  `json:"size"`
+	DataBytes int64  `json:"databytes"` // stored size
+}
+
+type FileStoreGCRtnData struct {
+	DryRun           bool               `json:"dryrun,omitempty"`
+	Zones            []*FileStoreGCZone `json:"zones"`            // orphaned zones (removed unless DryRun)
+	ReclaimableBytes int64              `json:"reclaimablebytes"` // stored size of the orphaned zones
+	FreeBytes        int64              `json:"freebytes"`        // unused space in the db before the vacuum
+	Vacuumed         bool               `json:"vacuumed,omitempty"`
+	DBSizeBefore     int64              `json:"dbsizebefore"`
+	DBSizeAfter      int64              `json:"dbsizeafter"`
+}
+
+type CommandFileStoreUsageData struct {
+	ZoneId string `json:"zoneid,omitempty"` // also list the files of this zone
+	Limit  int    `json:"limit,omitempty"`  // max number of zones (largest first), 0 for the default
+}
+
+type FileStoreQuotas struct {
+	MaxZoneSize     int64            `json:"maxzonesize,omitempty"`
+	MaxFileTypeSize map[string]int64 `json:"maxfiletypesize,omitempty"`
+	MaxTotalSize    int64            `json:"maxtotalsize,omitempty"`
+}
+
+// sizes are the data lengths of the files (what counts towards the quotas), DataBytes is the stored size
+type FileStoreZoneUsage struct {
+	ZoneId     string `json:"zoneid"`
+	OType      string `json:"otype,omitempty"` // type of the object that owns the zone (empty if it doesn't exist)
+	View       string `json:"view,omitempty"`  // for blocks
+	NumFiles   int    `json:"numfiles"`
+	DataLength int64  `json:"datalength"`
+	DataBytes  int64  `json:"databytes"`
+	ModTs      int64  `json:"modts"`
+}
+
+type FileStoreTypeUsage struct {
+	Name       string `json:"name"`
+	NumFiles   int    `json:"numfiles"`
+	DataLength int64  `json:"datalength"`
+	DataBytes  int64  `json:"databytes"`
+}
+
+type FileStoreFileUsage struct {
+	Name       string `json:"name"`
+	Size       int64  `json:"size"`
+	DataLength int64  `json:"datalength"`
+	DataBytes  int64  `json:"databytes"`
+	Circular   bool   `json:"circular,omitempty"`
+	ModTs      int64  `json:"modts"`
+}
+
+type FileStoreUsageRtnData struct {
+	DBSize     int64                 `json:"dbsize"`
+	DataLength int64                 `json:"datalength"`
+	DataBytes  int64                 `json:"databytes"`
+	NumZones   int                   `json:"numzones"`
+	Quotas     FileStoreQuotas       `json:"quotas"`
+	Zones      []*FileStoreZoneUsage `json:"zones"` // largest first
+	FileTypes  []*FileStoreTypeUsage `json:"filetypes"`
+	Files      []*FileStoreFileUsage `json:"files,omitempty"` // files of the requested zone
+}
+
+type CommandDBEncryptData struct {
+	Off       bool   `json:"off,omitempty"`       // decrypt the existing data and stop encrypting new data
+	Rotate    bool   `json:"rotate,omitempty"`    // switch to a new key and re-encrypt the existing data
+	KeySource string `json:"keysource,omitempty"` // keyring, passphrase or file (only used for new keys and when rotating)
+}
+
+type DBEncryptRtnData struct {
+	Encrypted  bool   `json:"encrypted"`
+	KeySource  string `json:"keysource,omitempty"`
+	KeyId      string `json:"keyid,omitempty"`
+	NumObjects int    `json:"numobjects"` // rewritten wstore objects and history entries
+	NumFiles   int    `json:"numfiles"`   // rewritten filestore files
+	NumParts   int    `json:"numparts"`
+}
+
+type BackupManifest struct {
+	BackupVersion    int                `json:"backupversion"`
+	WaveVersion      string             `json:"waveversion"`
+	CreatedTs        int64              `json:"createdts"`
+	WStoreVersion    uint               `json:"wstoreversion"` // migration versions of the dbs
+	FilestoreVersion uint               `json:"filestoreversion"`
+	EncryptionKeyId  string             `json:"encryptionkeyid,omitempty"`  // set if the dbs may contain encrypted data
+	EncryptionKeyIds []string           `json:"encryptionkeyids,omitempty"` // all keys in the keyset (data may not have been re-encrypted yet)
+	Workspaces       []*BackupWorkspace `json:"workspaces"`
+}
+
+type BackupWorkspace struct {
+	WorkspaceId string `json:"workspaceid"`
+	Name        string `json:"name,omitempty"`
+	NumTabs     int    `json:"numtabs"`
+}
+
+type CommandBackupCreateData struct {
+	Path string `json:"path"` // local path of the archive (.tar.gz)
+}
+
+type BackupCreateRtnData struct {
+	Path     string          `json:"path"`
+	Size     int64           `json:"size"`
+	Manifest *BackupManifest `json:"manifest"`
+}
+
+type CommandBackupRestoreData struct {
+	Path        string `json:"path"`
+	WorkspaceId string `json:"workspaceid,omitempty"` // only restore this workspace (into the running app)
+	DryRun      bool   `json:"dryrun,omitempty"`      // only validate the archive
+}
+
+type BackupRestoreRtnData struct {
+	Manifest        *BackupManifest `json:"manifest"`
+	NumObjects      int             `json:"numobjects,omitempty"` // restored objects (workspace restore)
+	NumFiles        int             `json:"numfiles,omitempty"`
+	RestartRequired bool            `json:"restartrequired,omitempty"` // full restores are applied on the next start
+}
+
+// a closed tab or block (with its sub-blocks, layout and files) that can be restored until the retention window ends
+type TrashEntry struct {
+	TrashId     string          `json:"trashid"`
+	OType       string          `json:"otype"` // "tab" or "block"
+	OID         string          `json:"oid"`
+	DeletedTs   int64           `json:"deletedts"`
+	Name        string          `json:"name,omitempty"` // tab name
+	View        string          `json:"view,omitempty"` // block view
+	WorkspaceId string          `json:"workspaceid,omitempty"`
+	TabId       string          `json:"tabid,omitempty"`      // blocks: the tab the block was in
+	ParentORef  string          `json:"parentoref,omitempty"` // blocks: the tab or block the block was in
+	Index       int             `json:"index"`                // position in the workspace's tab list (or the parent's block list)
+	Pinned      bool            `json:"pinned,omitempty"`
+	NumBlocks   int             `json:"numblocks"`
+	Layout      *TrashLayoutPos `json:"layout,omitempty"`
+}
+
+// where a block was in its tab's layout: next to a sibling block (in the direction of their parent node)
+type TrashLayoutPos struct {
+	TargetBlockId string `json:"targetblockid,omitempty"`
+	Position      string `json:"position,omitempty"`   // "before" or "after" the target
+	ActionType    string `json:"actiontype,omitempty"` // splithorizontal or splitvertical
+	NodeSize      *uint  `json:"nodesize,omitempty"`
+}
+
+type CommandTrashListData struct {
+	WorkspaceId string `json:"workspaceid,omitempty"`
+	OType       string `json:"otype,omitempty"`
+}
+
+type CommandTrashRestoreData struct {
+	TrashId     string `json:"trashid"`
+	WorkspaceId string `json:"workspaceid,omitempty"` // tabs: restore into this workspace instead of the original one
+}
+
+type TrashRestoreRtnData struct {
+	OType       string `json:"otype"`
+	OID         string `json:"oid"`
+	WorkspaceId string `json:"workspaceid,omitempty"`
+	TabId       string `json:"tabid,omitempty"`
+	NumBlocks   int    `json:"numblocks"`
+}
+
+type CommandWorkspaceExportData struct {
+	WorkspaceId string `json:"workspaceid"`
+	Path        string `json:"path"`            // local path of the export (.json)
+	Files       bool   `json:"files,omitempty"` // include the files of the workspace, tabs and blocks
+}
+
+type WorkspaceExportRtnData struct {
+	Path       string `json:"path"`
+	Size       int64  `json:"size"`
+	NumObjects int    `json:"numobjects"`
+	NumFiles   int    `json:"numfiles"`
+}
+
+type CommandWorkspaceImportData struct {
+	Path string `json:"path"`
+}
+
+type WorkspaceImportRtnData struct {
+	WorkspaceId string `json:"workspaceid"` // the new workspace (all objects get new ids)
+	Name        string `json:"name,omitempty"`
+	NumObjects  int    `json:"numobjects"`
+	NumFiles    int    `json:"numfiles"`
+}
+
+type CommandBlocksListData struct {
+	WorkspaceId string `json:"workspaceid,omitempty"`
+	Stats       bool   `json:"stats,omitempty"` // sample the process trees of running blocks (takes about a second)
+}
+
+type BlocksListEntry struct {
+	WorkspaceId string          `json:"workspaceid"`
+	TabId       string          `json:"tabid"`
+	BlockId     string          `json:"blockid"`
+	View        string          `json:"view,omitempty"`
+	Controller  string          `json:"controller,omitempty"`
+	Connection  string          `json:"connection,omitempty"`
+	Status      string          `json:"status,omitempty"` // shell process status
+	Stats       *BlockProcStats `json:"stats,omitempty"`
+	StatsError  string          `json:"statserror,omitempty"`
+}
+
 type CommandEventReadHistoryData struct {
 	Event    string `json:"event"`
 	Scope    string `json:"scope"`
 	MaxItems int    `json:"maxitems"`
 }
 
+type CommandEventReplayData struct {
+	SinceSeq int64    `json:"sinceseq"`
+	Events   []string `json:"events,omitempty"` // all journaled events if empty
+	Scopes   []string `json:"scopes,omitempty"` // may contain "*" wildcards, all scopes if empty
+	MaxItems int      `json:"maxitems,omitempty"`
+}
+
+type EventReplayRtnData struct {
+	Events        []*wps.WaveEvent `json:"events"`
+	FirstSeq      int64            `json:"firstseq"` // oldest seq still in the journal (earlier events were purged)
+	LastSeq       int64            `json:"lastseq"`  // newest seq written to the journal
+	More          bool             `json:"more,omitempty"`
+	Enabled       bool             `json:"enabled"`
+	JournalEvents []string         `json:"journalevents"` // the events that are journaled
+}
+
 type WaveAIStreamRequest struct {
 	ClientId string                    `json:"clientid,omitempty"`
 	Opts     *WaveAIOptsType           `json:"opts"`
@@ -549,10 +1131,64 @@ type CommandRemoteStreamTarData struct {
 }
 
 type FileCopyOpts struct {
-	Overwrite bool  `json:"overwrite,omitempty"`
-	Recursive bool  `json:"recursive,omitempty"` // only used for move, always true for copy
-	Merge     bool  `json:"merge,omitempty"`
-	Timeout   int64 `json:"timeout,omitempty"`
+	Overwrite bool     `json:"overwrite,omitempty"`
+	Recursive bool     `json:"recursive,omitempty"` // only used for move, always true for copy
+	Merge     bool     `json:"merge,omitempty"`
+	Timeout   int64    `json:"timeout,omitempty"`
+	Files     []string `json:"files,omitempty"` // if set, tar streams only include these files (paths relative to the source directory)
+}
+
+type CommandFileSyncData struct {
+	SrcUri  string        `json:"srcuri"`
+	DestUri string        `json:"desturi"`
+	Opts    *FileSyncOpts `json:"opts,omitempty"`
+}
+
+type FileSyncOpts struct {
+	Delete   bool     `json:"delete,omitempty"`   // delete files in the destination that are not in the source
+	DryRun   bool     `json:"dryrun,omitempty"`   // only report what would be done
+	Checksum bool     `json:"checksum,omitempty"` // compare checksums instead of modification times (wsh connections only)
+	Excludes []string `json:"excludes,omitempty"` // glob patterns matched against relative paths and path elements
+	Timeout  int64    `json:"timeout,omitempty"`
+}
+
+const (
+	FileSyncOp_Copy    = "copy"
+	FileSyncOp_Delete  = "delete"
+	FileSyncOp_Summary = "summary"
+)
+
+type FileSyncUpdate struct {
+	Op           string `json:"op"`
+	Path         string `json:"path,omitempty"`   // relative to the sync root
+	Reason       string `json:"reason,omitempty"` // why a file is copied (new, size, modtime, checksum, type)
+	Size         int64  `json:"size,omitempty"`
+	DryRun       bool   `json:"dryrun,omitempty"`
+	FilesDone    int    `json:"filesdone"`
+	FilesTotal   int    `json:"filestotal"`
+	BytesDone    int64  `json:"bytesdone"`
+	BytesTotal   int64  `json:"bytestotal"`
+	NumDeleted   int    `json:"numdeleted,omitempty"`
+	NumUnchanged int    `json:"numunchanged,omitempty"`
+}
+
+type CommandRemoteFileManifestData struct {
+	Path     string   `json:"path"`
+	Checksum bool     `json:"checksum,omitempty"`
+	Excludes []string `json:"excludes,omitempty"`
+}
+
+type FileManifestEntry struct {
+	Path     string      `json:"path"` // relative to the manifest root, "/" separated
+	Size     int64       `json:"size,omitempty"`
+	ModTime  int64       `json:"modtime,omitempty"`
+	Mode     os.FileMode `json:"mode,omitempty"`
+	IsDir    bool        `json:"isdir,omitempty"`
+	Checksum string      `json:"checksum,omitempty"` // hex sha256
+}
+
+type CommandRemoteFileManifestRtnData struct {
+	Entries []*FileManifestEntry `json:"entries"`
 }
 
 type CommandRemoteStreamFileData struct {
@@ -792,4 +1428,6 @@ type FileShareCapability struct {
 	CanAppend bool `json:"canappend"`
 	// CanMkdir indicates whether the file share supports creating directories
 	CanMkdir bool `json:"canmkdir"`
+	// CanSetModTime indicates whether writes keep the modtime passed in FileOpts.ModTime
+	CanSetModTime bool `json:"cansetmodtime"`
 }
@@ -18,6 +18,7 @@ import (
 	"time"
 
 	"github.com/skratchdot/open-golang/open"
+	"github.com/wavetermdev/waveterm/pkg/backup"
 	"github.com/wavetermdev/waveterm/pkg/blockcontroller"
 	"github.com/wavetermdev/waveterm/pkg/blocklogger"
 	"github.com/wavetermdev/waveterm/pkg/filestore"
@@ -27,6 +28,7 @@ import (
 	"github.com/wavetermdev/waveterm/pkg/remote/awsconn"
 	"github.com/wavetermdev/waveterm/pkg/remote/conncontroller"
 	"github.com/wavetermdev/waveterm/pkg/remote/fileshare"
+	"github.com/wavetermdev/waveterm/pkg/scheduler"
 	"github.com/wavetermdev/waveterm/pkg/suggestion"
 	"github.com/wavetermdev/waveterm/pkg/telemetry"
 	"github.com/wavetermdev/waveterm/pkg/telemetry/telemetrydata"
@@ -159,6 +161,15 @@ func (ws *WshServer) SetMetaCommand(ctx context.Context, data wshrpc.CommandSetM
 	return nil
 }
 
+func (ws *WshServer) PatchMetaCommand(ctx context.Context, data wshrpc.CommandPatchMetaData) (waveobj.MetaMapType, error) {
+	meta, err := wstore.PatchObjectMeta(ctx, data.ORef, data.Commands)
+	if err != nil {
+		return nil, fmt.Errorf("error patching object meta: %w", err)
+	}
+	sendWaveObjUpdate(data.ORef)
+	return meta, nil
+}
+
 func sendWaveObjUpdate(oref waveobj.ORef) {
 	ctx, cancelFn := context.WithTimeout(context.Background(), 2*time.Second)
 	defer cancelFn()
@@ -286,12 +297,8 @@ func (ws *WshServer) CreateSubBlockCommand(ctx context.Context, data wshrpc.Comm
 func (ws *WshServer) SetViewCommand(ctx context.Context, data wshrpc.CommandBlockSetViewData) error {
 	log.Printf("SETVIEW: %s | %q\n", data.BlockId, data.View)
 	ctx = waveobj.ContextWithUpdates(ctx)
-	block, err := wstore.DBGet[*waveobj.Block](ctx, data.BlockId)
-	if err != nil {
-		return fmt.Errorf("error getting block: %w", err)
-	}
-	block.Meta[waveobj.MetaKey_View] = data.View
-	err = wstore.DBUpdate(ctx, block)
+	oref := waveobj.MakeORef(waveobj.OType_Block, data.BlockId)
+	err := wstore.UpdateObjectMeta(ctx, oref, waveobj.MetaMapType{waveobj.MetaKey_View: data.View}, false)
 	if err != nil {
 		return fmt.Errorf("error updating block: %w", err)
 	}
@@ -333,7 +340,7 @@ func (ws *WshServer) ControllerInputCommand(ctx context.Context, data wshrpc.Com
 		inputUnion.InputData = inputBuf[:nw]
 		log.Printf("📥 收到命令 (BlockId: %s): %q", data.BlockId, string(inputBuf[:nw]))
 	}
-	return bc.SendInput(inputUnion)
+	return blockcontroller.SendInputWithGroup(ctx, bc.BlockId, inputUnion)
 }
 
 func (ws *WshServer) ControllerAppendOutputCommand(ctx context.Context, data wshrpc.CommandControllerAppendOutputData) error {
@@ -378,6 +385,14 @@ func (ws *WshServer) FileListStreamCommand(ctx context.Context, data wshrpc.File
 	return fileshare.ListEntriesStream(ctx, data.Path, data.Opts)
 }
 
+func (ws *WshServer) FileSearchCommand(ctx context.Context, data wshrpc.FileSearchData) <-chan wshrpc.RespOrErrorUnion[wshrpc.CommandFileSearchRtnData] {
+	return fileshare.Search(ctx, data.Path, data.Opts)
+}
+
+func (ws *WshServer) FileWatchCommand(ctx context.Context, data wshrpc.CommandFileWatchData) <-chan wshrpc.RespOrErrorUnion[wshrpc.FileWatchEvent] {
+	return fileshare.Watch(ctx, data)
+}
+
 func (ws *WshServer) FileWriteCommand(ctx context.Context, data wshrpc.FileData) error {
 	return fileshare.PutFile(ctx, data)
 }
@@ -398,6 +413,10 @@ func (ws *WshServer) FileMoveCommand(ctx context.Context, data wshrpc.CommandFil
 	return fileshare.Move(ctx, data)
 }
 
+func (ws *WshServer) FileSyncCommand(ctx context.Context, data wshrpc.CommandFileSyncData) <-chan wshrpc.RespOrErrorUnion[wshrpc.FileSyncUpdate] {
+	return fileshare.Sync(ctx, data)
+}
+
 func (ws *WshServer) FileStreamTarCommand(ctx context.Context, data wshrpc.CommandRemoteStreamTarData) <-chan wshrpc.RespOrErrorUnion[iochantypes.Packet] {
 	return fileshare.ReadTarStream(ctx, data)
 }
@@ -504,6 +523,12 @@ func (ws *WshServer) EventSubCommand(ctx context.Context, data wps.SubscriptionR
 		return fmt.Errorf("no rpc source set")
 	}
 	wps.Broker.Subscribe(rpcSource, data)
+	if data.SinceSeq > 0 {
+		err := wcore.SendReplayedEvents(ctx, rpcSource, data)
+		if err != nil {
+			return fmt.Errorf("error replaying events: %w", err)
+		}
+	}
 	return nil
 }
 
@@ -530,6 +555,10 @@ func (ws *WshServer) EventReadHistoryCommand(ctx context.Context, data wshrpc.Co
 	return events, nil
 }
 
+func (ws *WshServer) EventReplayCommand(ctx context.Context, data wshrpc.CommandEventReplayData) (*wshrpc.EventReplayRtnData, error) {
+	return wcore.ReplayEvents(ctx, data)
+}
+
 func (ws *WshServer) SetConfigCommand(ctx context.Context, data wshrpc.MetaSettingsType) error {
 	log.Printf("SETCONFIG: %v\n", data)
 	return wconfig.SetBaseConfigValue(data.MetaMapType)
@@ -983,3 +1012,229 @@ func (ws *WshServer) GetTabCommand(ctx context.Context, tabId string) (*waveobj.
 	}
 	return tab, nil
 }
+
+func (ws *WshServer) ScrollbackDumpCommand(ctx context.Context, data wshrpc.CommandScrollbackDumpData) chan wshrpc.RespOrErrorUnion[wshrpc.CommandScrollbackDumpRtnData] {
+	return blockcontroller.DumpScrollback(ctx, data.BlockId, data.Format)
+}
+
+func (ws *WshServer) ScrollbackGrepCommand(ctx context.Context, data wshrpc.CommandScrollbackGrepData) ([]*wshrpc.ScrollbackGrepMatch, error) {
+	return blockcontroller.GrepScrollback(ctx, data)
+}
+
+func (ws *WshServer) HistoryListCommand(ctx context.Context, data wshrpc.CommandHistoryListData) ([]*wshrpc.CmdHistoryEntry, error) {
+	return blockcontroller.HistoryList(ctx, data)
+}
+
+func (ws *WshServer) HistoryGetOutputCommand(ctx context.Context, data wshrpc.CommandHistoryGetOutputData) (*wshrpc.CommandHistoryGetOutputRtnData, error) {
+	return blockcontroller.HistoryGetOutput(ctx, data)
+}
+
+func (ws *WshServer) RecordStartCommand(ctx context.Context, data wshrpc.CommandRecordStartData) (*wshrpc.CommandRecordRtnData, error) {
+	oref := waveobj.MakeORef(waveobj.OType_Block, data.BlockId)
+	meta := waveobj.MetaMapType{
+		waveobj.MetaKey_TermRecord:      true,
+		waveobj.MetaKey_TermRecordInput: nil,
+		waveobj.MetaKey_TermRecordPath:  nil,
+	}
+	if data.RecordInput {
+		meta[waveobj.MetaKey_TermRecordInput] = true
+	}
+	if data.Path != "" {
+		if _, err := blockcontroller.ValidateRecordPath(data.Path); err != nil {
+			return nil, err
+		}
+		meta[waveobj.MetaKey_TermRecordPath] = data.Path
+	}
+	err := wstore.UpdateObjectMeta(ctx, oref, meta, false)
+	if err != nil {
+		return nil, fmt.Errorf("error updating block meta: %w", err)
+	}
+	sendWaveObjUpdate(oref)
+	return blockcontroller.StartRecording(ctx, data)
+}
+
+func (ws *WshServer) RecordStopCommand(ctx context.Context, data wshrpc.CommandRecordStopData) (*wshrpc.CommandRecordRtnData, error) {
+	oref := waveobj.MakeORef(waveobj.OType_Block, data.BlockId)
+	err := wstore.UpdateObjectMeta(ctx, oref, waveobj.MetaMapType{waveobj.MetaKey_TermRecord: nil}, false)
+	if err != nil {
+		return nil, fmt.Errorf("error updating block meta: %w", err)
+	}
+	sendWaveObjUpdate(oref)
+	return blockcontroller.StopRecording(data.BlockId)
+}
+
+func (ws *WshServer) ScheduleListCommand(ctx context.Context, data wshrpc.CommandScheduleListData) ([]*wshrpc.ScheduleInfo, error) {
+	return scheduler.ListSchedules(data.BlockId), nil
+}
+
+func (ws *WshServer) StreamBlockProcStatsCommand(ctx context.Context, request wshrpc.BlockProcStatsRequest) chan wshrpc.RespOrErrorUnion[wshrpc.BlockProcStats] {
+	return blockcontroller.StreamBlockProcStats(ctx, request)
+}
+
+func (ws *WshServer) BlocksListCommand(ctx context.Context, data wshrpc.CommandBlocksListData) ([]*wshrpc.BlocksListEntry, error) {
+	workspaces, err := wstore.DBGetAllObjsByType[*waveobj.Workspace](ctx, waveobj.OType_Workspace)
+	if err != nil {
+		return nil, fmt.Errorf("error listing workspaces: %w", err)
+	}
+	var rtn []*wshrpc.BlocksListEntry
+	var runningIds []string
+	for _, workspace := range workspaces {
+		if data.WorkspaceId != "" && workspace.OID != data.WorkspaceId {
+			continue
+		}
+		tabIds := append(append([]string{}, workspace.PinnedTabIds...), workspace.TabIds...)
+		for _, tabId := range tabIds {
+			tab, err := wstore.DBGet[*waveobj.Tab](ctx, tabId)
+			if err != nil || tab == nil {
+				continue
+			}
+			for _, blockId := range tab.BlockIds {
+				block, err := wstore.DBGet[*waveobj.Block](ctx, blockId)
+				if err != nil || block == nil {
+					continue
+				}
+				entry := &wshrpc.BlocksListEntry{
+					WorkspaceId: workspace.OID,
+					TabId:       tabId,
+					BlockId:     blockId,
+					View:        block.Meta.GetString(waveobj.MetaKey_View, ""),
+					Controller:  block.Meta.GetString(waveobj.MetaKey_Controller, ""),
+					Connection:  block.Meta.GetString(waveobj.MetaKey_Connection, ""),
+				}
+				if bc := blockcontroller.GetBlockController(blockId); bc != nil {
+					entry.Status = bc.GetRuntimeStatus().ShellProcStatus
+					if entry.Status == blockcontroller.Status_Running {
+						runningIds = append(runningIds, blockId)
+					}
+				}
+				rtn = append(rtn, entry)
+			}
+		}
+	}
+	if data.Stats && len(runningIds) > 0 {
+		results := blockcontroller.GetBlockProcStats(ctx, runningIds)
+		for _, entry := range rtn {
+			result, ok := results[entry.BlockId]
+			if !ok {
+				continue
+			}
+			entry.Stats = result.Stats
+			if result.Err != nil {
+				entry.StatsError = result.Err.Error()
+			}
+		}
+	}
+	return rtn, nil
+}
+
+func (ws *WshServer) PortsListCommand(ctx context.Context, data wshrpc.CommandPortsListData) ([]*wshrpc.BlockPortsData, error) {
+	if data.BlockId == "" {
+		return blockcontroller.ListBlockPorts(), nil
+	}
+	ports, err := blockcontroller.GetBlockPorts(ctx, data.BlockId)
+	if err != nil {
+		return nil, err
+	}
+	return []*wshrpc.BlockPortsData{ports}, nil
+}
+
+// opens a web block (split to the right of the block) showing the port
+func (ws *WshServer) PortOpenCommand(ctx context.Context, data wshrpc.CommandPortOpenData) (*wshrpc.PortOpenRtnData, error) {
+	url, err := blockcontroller.GetPortUrl(data.BlockId, data.Port)
+	if err != nil {
+		return nil, err
+	}
+	tabId, err := wstore.DBFindTabForBlockId(ctx, data.BlockId)
+	if err != nil {
+		return nil, fmt.Errorf("error finding tab for block: %w", err)
+	}
+	createData := wshrpc.CommandCreateBlockData{
+		TabId: tabId,
+		BlockDef: &waveobj.BlockDef{
+			Meta: waveobj.MetaMapType{
+				waveobj.MetaKey_View: "web",
+				waveobj.MetaKey_Url:  url,
+			},
+		},
+		TargetBlockId: data.BlockId,
+		TargetAction:  "splitright",
+	}
+	oref, err := ws.CreateBlockCommand(ctx, createData)
+	if err != nil {
+		return nil, err
+	}
+	return &wshrpc.PortOpenRtnData{Url: url, BlockId: oref.OID}, nil
+}
+
+func (ws *WshServer) InputGroupListCommand(ctx context.Context) ([]*wshrpc.InputGroupInfo, error) {
+	return blockcontroller.ListInputGroups(ctx)
+}
+
+func (ws *WshServer) InputGroupSetCommand(ctx context.Context, data wshrpc.CommandInputGroupSetData) error {
+	return blockcontroller.SetInputGroup(ctx, data)
+}
+
+func (ws *WshServer) InputGroupSendCommand(ctx context.Context, data wshrpc.CommandInputGroupSendData) error {
+	inputData, err := base64.StdEncoding.DecodeString(data.InputData64)
+	if err != nil {
+		return fmt.Errorf("error decoding input data: %w", err)
+	}
+	_, err = blockcontroller.SendInputToGroup(ctx, data.Group, inputData)
+	return err
+}
+
+func (ws *WshServer) FileStoreRecompressCommand(ctx context.Context, data wshrpc.CommandFileStoreRecompressData) (*wshrpc.FileStoreRecompressRtnData, error) {
+	stats, err := filestore.WFS.RecompressAll(ctx, data.ZoneId, data.Compress)
+	if err != nil {
+		return nil, err
+	}
+	return &wshrpc.FileStoreRecompressRtnData{NumFiles: stats.NumFiles, NumParts: stats.NumParts, OldBytes: stats.OldBytes, NewBytes: stats.NewBytes}, nil
+}
+
+func (ws *WshServer) FileStoreGCCommand(ctx context.Context, data wshrpc.CommandFileStoreGCData) (*wshrpc.FileStoreGCRtnData, error) {
+	return wcore.RunFileStoreGC(ctx, data)
+}
+
+func (ws *WshServer) FileStoreUsageCommand(ctx context.Context, data wshrpc.CommandFileStoreUsageData) (*wshrpc.FileStoreUsageRtnData, error) {
+	return wcore.GetFileStoreUsage(ctx, data)
+}
+
+func (ws *WshServer) DBEncryptCommand(ctx context.Context, data wshrpc.CommandDBEncryptData) (*wshrpc.DBEncryptRtnData, error) {
+	return wcore.RunDBEncrypt(ctx, data)
+}
+
+func (ws *WshServer) BackupCreateCommand(ctx context.Context, data wshrpc.CommandBackupCreateData) (*wshrpc.BackupCreateRtnData, error) {
+	return backup.Create(ctx, data.Path)
+}
+
+func (ws *WshServer) BackupRestoreCommand(ctx context.Context, data wshrpc.CommandBackupRestoreData) (*wshrpc.BackupRestoreRtnData, error) {
+	return backup.Restore(ctx, data)
+}
+
+func (ws *WshServer) TrashListCommand(ctx context.Context, data wshrpc.CommandTrashListData) ([]*wshrpc.TrashEntry, error) {
+	return wcore.ListTrash(ctx, data)
+}
+
+func (ws *WshServer) TrashRestoreCommand(ctx context.Context, data wshrpc.CommandTrashRestoreData) (*wshrpc.TrashRestoreRtnData, error) {
+	ctx = waveobj.ContextWithUpdates(ctx)
+	rtn, err := wcore.RestoreTrash(ctx, data)
+	if err != nil {
+		return nil, err
+	}
+	wps.Broker.SendUpdateEvents(waveobj.ContextGetUpdatesRtn(ctx))
+	return rtn, nil
+}
+
+func (ws *WshServer) WorkspaceExportCommand(ctx context.Context, data wshrpc.CommandWorkspaceExportData) (*wshrpc.WorkspaceExportRtnData, error) {
+	return wcore.ExportWorkspaceToFile(ctx, data)
+}
+
+func (ws *WshServer) WorkspaceImportCommand(ctx context.Context, data wshrpc.CommandWorkspaceImportData) (*wshrpc.WorkspaceImportRtnData, error) {
+	ctx = waveobj.ContextWithUpdates(ctx)
+	rtn, err := wcore.ImportWorkspaceFromFile(ctx, data)
+	if err != nil {
+		return nil, err
+	}
+	wps.Broker.SendUpdateEvents(waveobj.ContextGetUpdatesRtn(ctx))
+	return rtn, nil
+}
@@ -7,6 +7,7 @@ import (
 	"context"
 	"fmt"
 
+	"github.com/wavetermdev/waveterm/pkg/ijson"
 	"github.com/wavetermdev/waveterm/pkg/util/utilfn"
 	"github.com/wavetermdev/waveterm/pkg/waveobj"
 )
@@ -23,32 +24,121 @@ func UpdateTabName(ctx context.Context, tabId, name string) error {
 		if tab == nil {
 			return fmt.Errorf("tab not found: %q", tabId)
 		}
-		if tabId != "" {
-			tab.Name = name
-			DBUpdate(tx.Context(), tab)
+		tab.Name = name
+		return DBUpdate(tx.Context(), tab)
+	})
+}
+
+// number of optimistic attempts to update an object before the update is done in a single transaction
+const maxUpdateRetries = 5
+
+// reads the object, applies fn and writes it back.  if the object was changed in between (a version conflict),
+// it is read again and fn is applied again, so fn must only depend on the object it is passed.  returns the
+// updated object.
+func UpdateObject(ctx context.Context, oref waveobj.ORef, fn func(obj waveobj.WaveObj) error) (waveobj.WaveObj, error) {
+	if oref.IsEmpty() {
+		return nil, fmt.Errorf("empty object reference")
+	}
+	for attempt := 0; attempt < maxUpdateRetries; attempt++ {
+		obj, err := updateObjectOnce(ctx, oref, fn)
+		if !IsVersionConflict(err) {
+			return obj, err
 		}
-		return nil
+	}
+	// still conflicting, the last attempt holds the transaction (so the object cannot change in between)
+	return WithTxRtn(ctx, func(tx *TxWrap) (waveobj.WaveObj, error) {
+		return updateObjectOnce(tx.Context(), oref, fn)
 	})
 }
 
+func updateObjectOnce(ctx context.Context, oref waveobj.ORef, fn func(obj waveobj.WaveObj) error) (waveobj.WaveObj, error) {
+	obj, err := DBGetORef(ctx, oref)
+	if err != nil {
+		return nil, err
+	}
+	if obj == nil {
+		return nil, ErrNotFound
+	}
+	err = fn(obj)
+	if err != nil {
+		return nil, err
+	}
+	err = DBUpdate(ctx, obj)
+	if err != nil {
+		return nil, err
+	}
+	return obj, nil
+}
+
+// merges meta into the object's meta (retried on version conflicts, so concurrent updates are not lost)
 func UpdateObjectMeta(ctx context.Context, oref waveobj.ORef, meta waveobj.MetaMapType, mergeSpecial bool) error {
-	return WithTx(ctx, func(tx *TxWrap) error {
-		if oref.IsEmpty() {
-			return fmt.Errorf("empty object reference")
-		}
-		obj, _ := DBGetORef(tx.Context(), oref)
-		if obj == nil {
-			return ErrNotFound
-		}
+	_, err := UpdateObject(ctx, oref, func(obj waveobj.WaveObj) error {
 		objMeta := waveobj.GetMeta(obj)
 		if objMeta == nil {
 			objMeta = make(map[string]any)
 		}
-		newMeta := waveobj.MergeMeta(objMeta, meta, mergeSpecial)
-		waveobj.SetMeta(obj, newMeta)
-		DBUpdate(tx.Context(), obj)
+		waveobj.SetMeta(obj, waveobj.MergeMeta(objMeta, meta, mergeSpecial))
 		return nil
 	})
+	return err
+}
+
+// applies ijson commands (set, del, append) to the object's meta.  unlike UpdateObjectMeta, nested values can be
+// changed without replacing their top-level key.  returns the new meta.
+func PatchObjectMeta(ctx context.Context, oref waveobj.ORef, commands []ijson.Command) (waveobj.MetaMapType, error) {
+	commands, err := normalizeMetaCommands(commands)
+	if err != nil {
+		return nil, err
+	}
+	obj, err := UpdateObject(ctx, oref, func(obj waveobj.WaveObj) error {
+		meta := map[string]any(waveobj.GetMeta(obj))
+		if meta == nil {
+			meta = make(map[string]any)
+		}
+		newMeta, err := ijson.ApplyCommands(meta, commands, 0)
+		if err != nil {
+			return err
+		}
+		newMetaMap, ok := newMeta.(map[string]any)
+		if !ok {
+			return fmt.Errorf("meta must be an object")
+		}
+		waveobj.SetMeta(obj, newMetaMap)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return waveobj.GetMeta(obj), nil
+}
+
+// validates the commands (they need a non-empty path) and converts path indexes decoded from json (float64) to ints
+func normalizeMetaCommands(commands []ijson.Command) ([]ijson.Command, error) {
+	rtn := make([]ijson.Command, 0, len(commands))
+	for idx, command := range commands {
+		path, _ := command["path"].([]any)
+		if len(path) == 0 {
+			return nil, fmt.Errorf("meta command %d: path must not be empty", idx)
+		}
+		newPath := make([]any, len(path))
+		for pidx, elem := range path {
+			if fval, ok := elem.(float64); ok && fval == float64(int(fval)) {
+				elem = int(fval)
+			}
+			newPath[pidx] = elem
+		}
+		newCommand := make(ijson.Command, len(command))
+		for key, val := range command {
+			newCommand[key] = val
+		}
+		newCommand["path"] = newPath
+		_, err := ijson.ValidateAndMarshalCommand(newCommand)
+		if err != nil {
+			return nil, fmt.Errorf("meta command %d: %w", idx, err)
+		}
+		rtn = append(rtn, newCommand)
+	}
+	return rtn, nil
 }
 
 func MoveBlockToTab(ctx context.Context, currentTabId string, newTabId string, blockId string) error {
@@ -72,9 +162,12 @@ func MoveBlockToTab(ctx context.Context, currentTabId string, newTabId string, b
 		currentTab.BlockIds = utilfn.RemoveElemFromSlice(currentTab.BlockIds, blockId)
 		newTab.BlockIds = append(newTab.BlockIds, blockId)
 		block.ParentORef = waveobj.MakeORef(waveobj.OType_Tab, newTabId).String()
-		DBUpdate(tx.Context(), block)
-		DBUpdate(tx.Context(), currentTab)
-		DBUpdate(tx.Context(), newTab)
+		for _, obj := range []waveobj.WaveObj{block, currentTab, newTab} {
+			err := DBUpdate(tx.Context(), obj)
+			if err != nil {
+				return err
+			}
+		}
 		return nil
 	})
 }
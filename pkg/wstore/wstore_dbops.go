@@ -5,6 +5,7 @@ package wstore
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log"
 	"reflect"
@@ -63,12 +64,19 @@ func DBGetWSCounts(ctx context.Context) (int, int, error) {
 
 var viewRe = regexp.MustCompile(`^[a-z0-9]{1,20}$`)
 
+// blocks can be encrypted, so they are decoded here rather than using json_extract
 func DBGetBlockViewCounts(ctx context.Context) (map[string]int, error) {
 	return WithTxRtn(ctx, func(tx *TxWrap) (map[string]int, error) {
-		query := `SELECT COALESCE(json_extract(data, '$.meta.view'), '') AS view FROM db_block`
-		views := tx.SelectStrings(query)
+		query := `SELECT oid, version, data FROM db_block`
+		var rows []idDataType
+		tx.Select(&rows, query)
 		rtn := make(map[string]int)
-		for _, view := range views {
+		for _, row := range rows {
+			obj, err := objFromRow(waveobj.OType_Block, row)
+			if err != nil {
+				return nil, err
+			}
+			view := obj.(*waveobj.Block).Meta.GetString(waveobj.MetaKey_View, "")
 			if view == "" {
 				continue
 			}
@@ -113,12 +121,7 @@ func DBGetSingletonByType(ctx context.Context, otype string) (waveobj.WaveObj, e
 		if !found {
 			return nil, ErrNotFound
 		}
-		rtn, err := waveobj.FromJson(row.Data)
-		if err != nil {
-			return rtn, err
-		}
-		waveobj.SetVersion(rtn, row.Version)
-		return rtn, nil
+		return objFromRow(otype, row)
 	})
 }
 
@@ -157,12 +160,7 @@ func DBGetORef(ctx context.Context, oref waveobj.ORef) (waveobj.WaveObj, error)
 		if !found {
 			return nil, nil
 		}
-		rtn, err := waveobj.FromJson(row.Data)
-		if err != nil {
-			return rtn, err
-		}
-		waveobj.SetVersion(rtn, row.Version)
-		return rtn, nil
+		return objFromRow(oref.OType, row)
 	})
 }
 
@@ -174,11 +172,10 @@ func dbSelectOIDs(ctx context.Context, otype string, oids []string) ([]waveobj.W
 		tx.Select(&rows, query, dbutil.QuickJson(oids))
 		rtn := make([]waveobj.WaveObj, 0, len(rows))
 		for _, row := range rows {
-			waveObj, err := waveobj.FromJson(row.Data)
+			waveObj, err := objFromRow(otype, row)
 			if err != nil {
 				return nil, err
 			}
-			waveobj.SetVersion(waveObj, row.Version)
 			rtn = append(rtn, waveObj)
 		}
 		return rtn, nil
@@ -229,12 +226,10 @@ func DBGetAllObjsByType[T waveobj.WaveObj](ctx context.Context, otype string) ([
 		log.Printf("[DEBUG] DBGetAllObjsByType found %d rows for type: %s\n", len(rows), otype)
 		for i, row := range rows {
 			log.Printf("[DEBUG] Row %d: OID=%s\n", i, row.OId)
-			waveObj, err := waveobj.FromJson(row.Data)
+			waveObj, err := objFromRow(otype, row)
 			if err != nil {
 				return nil, err
 			}
-			waveobj.SetVersion(waveObj, row.Version)
-
 			rtn = append(rtn, waveObj.(T))
 		}
 		return rtn, nil
@@ -276,16 +271,22 @@ func DBSelectMap[T waveobj.WaveObj](ctx context.Context, ids []string) (map[stri
 }
 
 func DBDelete(ctx context.Context, otype string, id string) error {
+	var trashed bool
 	err := WithTx(ctx, func(tx *TxWrap) error {
 		table := tableNameFromOType(otype)
 		query := fmt.Sprintf("DELETE FROM %s WHERE oid = ?", table)
 		tx.Exec(query, id)
 		waveobj.ContextAddUpdate(ctx, waveobj.WaveObjUpdate{UpdateType: waveobj.UpdateType_Delete, OType: otype, OID: id})
+		trashed = tx.Exists(`SELECT oid FROM db_trashobj WHERE otype = ? AND oid = ?`, otype, id)
 		return nil
 	})
 	if err != nil {
 		return err
 	}
+	if trashed {
+		// the zone is deleted when the trash entry is purged
+		return nil
+	}
 	go func() {
 		defer func() {
 			panichandler.PanicHandler("DBDelete:filestore.DeleteZone", recover())
@@ -302,19 +303,49 @@ func DBDelete(ctx context.Context, otype string, id string) error {
 	return nil
 }
 
+// returned by DBUpdate when the object was changed after it was read
+type VersionConflictError struct {
+	OType     string
+	OID       string
+	Version   int // the version of the update
+	DBVersion int // the current version
+}
+
+func (e *VersionConflictError) Error() string {
+	return fmt.Sprintf("version conflict updating %s:%s (version %d, current version %d)", e.OType, e.OID, e.Version, e.DBVersion)
+}
+
+func IsVersionConflict(err error) bool {
+	var conflictErr *VersionConflictError
+	return errors.As(err, &conflictErr)
+}
+
+// updates are compare-and-swap on the version: val must have the version of the object it was read from,
+// otherwise a *VersionConflictError is returned (and nothing is written).  on success val gets the new version.
 func DBUpdate(ctx context.Context, val waveobj.WaveObj) error {
 	oid := waveobj.GetOID(val)
 	if oid == "" {
 		return fmt.Errorf("cannot update %T value with empty id", val)
 	}
 	jsonData, err := waveobj.ToJson(val)
+	if err == nil {
+		jsonData, err = encodeObjData(val.GetOType(), oid, jsonData)
+	}
 	if err != nil {
 		return err
 	}
 	return WithTx(ctx, func(tx *TxWrap) error {
 		table := waveObjTableName(val)
-		query := fmt.Sprintf("UPDATE %s SET data = ?, version = version+1 WHERE oid = ? RETURNING version", table)
-		newVersion := tx.GetInt(query, jsonData, oid)
+		version := waveobj.GetVersion(val)
+		query := fmt.Sprintf("UPDATE %s SET data = ?, version = version+1 WHERE oid = ? AND version = ? RETURNING version", table)
+		newVersion := tx.GetInt(query, jsonData, oid, version)
+		if newVersion == 0 {
+			dbVersion := tx.GetInt(fmt.Sprintf("SELECT version FROM %s WHERE oid = ?", table), oid)
+			if dbVersion == 0 {
+				return ErrNotFound
+			}
+			return &VersionConflictError{OType: val.GetOType(), OID: oid, Version: version, DBVersion: dbVersion}
+		}
 		waveobj.SetVersion(val, newVersion)
 		waveobj.ContextAddUpdate(ctx, waveobj.WaveObjUpdate{UpdateType: waveobj.UpdateType_Update, OType: val.GetOType(), OID: oid, Obj: val})
 		return nil
@@ -327,6 +358,9 @@ func DBInsert(ctx context.Context, val waveobj.WaveObj) error {
 		return fmt.Errorf("cannot insert %T value with empty id", val)
 	}
 	jsonData, err := waveobj.ToJson(val)
+	if err == nil {
+		jsonData, err = encodeObjData(val.GetOType(), oid, jsonData)
+	}
 	if err != nil {
 		return err
 	}
@@ -347,12 +381,17 @@ func DBFindTabForBlockId(ctx context.Context, blockId string) (string, error) {
 			if iterNum > 5 {
 				return "", fmt.Errorf("too many iterations looking for tab in block parents")
 			}
-			query := `
-			SELECT json_extract(b.data, '$.parentoref') AS parentoref
-			FROM db_block b
-			WHERE b.oid = ?;`
-			parentORef := tx.GetString(query, blockId)
-			oref, err := waveobj.ParseORef(parentORef)
+			// blocks can be encrypted, so this can't use json_extract
+			query := `SELECT oid, version, data FROM db_block WHERE oid = ?`
+			var row idDataType
+			if !tx.Get(&row, query, blockId) {
+				return "", fmt.Errorf("block %s not found", blockId)
+			}
+			obj, err := objFromRow(waveobj.OType_Block, row)
+			if err != nil {
+				return "", err
+			}
+			oref, err := waveobj.ParseORef(obj.(*waveobj.Block).ParentORef)
 			if err != nil {
 				return "", fmt.Errorf("bad block parent oref: %v", err)
 			}
@@ -403,3 +442,26 @@ func DBFindWindowForWorkspaceId(ctx context.Context, workspaceId string) (string
 		return tx.GetString(query, workspaceId), nil
 	})
 }
+
+// rebuilds the db file (VACUUM), so the pages of rewritten or deleted rows don't keep the old data
+func DBVacuum(ctx context.Context) error {
+	// VACUUM can't run in a transaction
+	_, err := globalDB.ExecContext(ctx, "VACUUM")
+	if err != nil {
+		return fmt.Errorf("error vacuuming wstore db: %w", err)
+	}
+	_, err = globalDB.ExecContext(ctx, "PRAGMA wal_checkpoint(TRUNCATE)")
+	if err != nil {
+		return fmt.Errorf("error truncating wstore wal: %w", err)
+	}
+	return nil
+}
+
+// writes a consistent copy of the db to fileName (which must not exist)
+func DBBackupTo(ctx context.Context, fileName string) error {
+	_, err := globalDB.ExecContext(ctx, "VACUUM INTO ?", fileName)
+	if err != nil {
+		return fmt.Errorf("error copying wstore db: %w", err)
+	}
+	return nil
+}
@@ -24,6 +24,7 @@ const WStoreDBName = "waveterm.db"
 type TxWrap = txwrap.TxWrap
 
 var globalDB *sqlx.DB
+var useTestingDb bool // just for testing (forces MakeDB() to return an in-memory db)
 
 func InitWStore() error {
 	ctx, cancelFn := context.WithTimeout(context.Background(), 2*time.Second)
@@ -50,8 +51,14 @@ func GetDBName() string {
 }
 
 func MakeDB(ctx context.Context) (*sqlx.DB, error) {
-	dbName := GetDBName()
-	rtn, err := sqlx.Open("sqlite3", fmt.Sprintf("file:%s?mode=rwc&_journal_mode=WAL&_busy_timeout=5000", dbName))
+	var rtn *sqlx.DB
+	var err error
+	if useTestingDb {
+		rtn, err = sqlx.Open("sqlite3", ":memory:")
+	} else {
+		dbName := GetDBName()
+		rtn, err = sqlx.Open("sqlite3", fmt.Sprintf("file:%s?mode=rwc&_journal_mode=WAL&_busy_timeout=5000", dbName))
+	}
 	if err != nil {
 		return nil, err
 	}
@@ -0,0 +1,199 @@
+// Copyright 2025, Command Line Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package wstore
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"fmt"
+
+	"github.com/wavetermdev/waveterm/pkg/dbcrypt"
+	"github.com/wavetermdev/waveterm/pkg/waveobj"
+)
+
+// block meta holds commands, env vars, cwds and urls, so blocks are encrypted when database encryption is on.
+// encrypted rows are stored as a prefix + base64 (the data column is text), so encrypted and plaintext
+// rows can be mixed (rows are converted one at a time by DBReencryptAll).
+const encryptedDataPrefix = "wenc1:"
+
+var encryptedOTypes = map[string]bool{
+	waveobj.OType_Block: true,
+}
+
+// text columns outside of the object tables that are encrypted the same way (the command history has the
+// commands and their cwds).  the columns are read and written by their packages with EncodeColumn and DecodeColumn.
+type encryptedColumnsType struct {
+	Table    string
+	IdColumn string
+	Columns  []string
+}
+
+var encryptedColumns = []encryptedColumnsType{
+	{Table: "db_cmdhistory", IdColumn: "historyid", Columns: []string{"cmdtext", "cwd"}},
+}
+
+func objAAD(otype string, oid string) []byte {
+	return []byte("wstore:" + otype + ":" + oid)
+}
+
+func columnAAD(table string, column string, id string) []byte {
+	return []byte("wstore:" + table + "." + column + ":" + id)
+}
+
+func encodeObjData(otype string, oid string, jsonData []byte) ([]byte, error) {
+	if !encryptedOTypes[otype] || !dbcrypt.Enabled() {
+		return jsonData, nil
+	}
+	rtn, err := encryptData(jsonData, objAAD(otype, oid))
+	if err != nil {
+		return nil, fmt.Errorf("error encrypting %s:%s: %w", otype, oid, err)
+	}
+	return rtn, nil
+}
+
+func decodeObjData(otype string, oid string, data []byte) ([]byte, error) {
+	rtn, err := decryptData(data, objAAD(otype, oid))
+	if err != nil {
+		return nil, fmt.Errorf("error decrypting %s:%s: %w", otype, oid, err)
+	}
+	return rtn, nil
+}
+
+// returns the value to store in an encrypted column (the value itself if encryption is off)
+func EncodeColumn(table string, column string, id string, value string) (string, error) {
+	if value == "" || !dbcrypt.Enabled() {
+		return value, nil
+	}
+	rtn, err := encryptData([]byte(value), columnAAD(table, column, id))
+	if err != nil {
+		return "", fmt.Errorf("error encrypting %s.%s:%s: %w", table, column, id, err)
+	}
+	return string(rtn), nil
+}
+
+// returns the value of an encrypted column (plaintext values are returned as they are)
+func DecodeColumn(table string, column string, id string, value string) (string, error) {
+	rtn, err := decryptData([]byte(value), columnAAD(table, column, id))
+	if err != nil {
+		return "", fmt.Errorf("error decrypting %s.%s:%s: %w", table, column, id, err)
+	}
+	return string(rtn), nil
+}
+
+func encryptData(data []byte, aad []byte) ([]byte, error) {
+	blob, err := dbcrypt.Encrypt(data, aad)
+	if err != nil {
+		return nil, err
+	}
+	rtn := make([]byte, len(encryptedDataPrefix)+base64.StdEncoding.EncodedLen(len(blob)))
+	copy(rtn, encryptedDataPrefix)
+	base64.StdEncoding.Encode(rtn[len(encryptedDataPrefix):], blob)
+	return rtn, nil
+}
+
+func decryptData(data []byte, aad []byte) ([]byte, error) {
+	encoded, found := bytes.CutPrefix(data, []byte(encryptedDataPrefix))
+	if !found {
+		return data, nil
+	}
+	blob := make([]byte, base64.StdEncoding.DecodedLen(len(encoded)))
+	n, err := base64.StdEncoding.Decode(blob, encoded)
+	if err != nil {
+		return nil, err
+	}
+	return dbcrypt.Decrypt(blob[:n], aad)
+}
+
+func objFromRow(otype string, row idDataType) (waveobj.WaveObj, error) {
+	jsonData, err := decodeObjData(otype, row.OId, row.Data)
+	if err != nil {
+		return nil, err
+	}
+	rtn, err := waveobj.FromJson(jsonData)
+	if err != nil {
+		return rtn, err
+	}
+	waveobj.SetVersion(rtn, row.Version)
+	return rtn, nil
+}
+
+// decodes a row read from a copy of the wstore db (like a backup)
+func DecodeDBObj(otype string, oid string, version int, data []byte) (waveobj.WaveObj, error) {
+	return objFromRow(otype, idDataType{OId: oid, Version: version, Data: data})
+}
+
+// rewrites the encrypted object types (including trashed objects) and columns with the current encryption settings
+// (after encryption is enabled or disabled, or after a key rotation).  versions are not changed.  returns the number
+// of objects and rows.
+func DBReencryptAll(ctx context.Context) (int, error) {
+	return WithTxRtn(ctx, func(tx *TxWrap) (int, error) {
+		var count int
+		for otype := range encryptedOTypes {
+			table := tableNameFromOType(otype)
+			var rows []idDataType
+			tx.Select(&rows, fmt.Sprintf("SELECT oid, version, data FROM %s", table))
+			for _, row := range rows {
+				jsonData, err := decodeObjData(otype, row.OId, row.Data)
+				if err != nil {
+					return count, err
+				}
+				newData, err := encodeObjData(otype, row.OId, jsonData)
+				if err != nil {
+					return count, err
+				}
+				tx.Exec(fmt.Sprintf("UPDATE %s SET data = ? WHERE oid = ?", table), newData, row.OId)
+				count++
+			}
+			var trashRows []trashObjRow
+			tx.Select(&trashRows, `SELECT otype, oid, data FROM db_trashobj WHERE otype = ?`, otype)
+			for _, row := range trashRows {
+				jsonData, err := decodeObjData(otype, row.OID, row.Data)
+				if err != nil {
+					return count, err
+				}
+				newData, err := encodeObjData(otype, row.OID, jsonData)
+				if err != nil {
+					return count, err
+				}
+				tx.Exec(`UPDATE db_trashobj SET data = ? WHERE otype = ? AND oid = ?`, newData, otype, row.OID)
+				count++
+			}
+		}
+		for _, encCols := range encryptedColumns {
+			numRows, err := reencryptColumns(tx, encCols)
+			count += numRows
+			if err != nil {
+				return count, err
+			}
+		}
+		return count, nil
+	})
+}
+
+type columnRow struct {
+	Id    string
+	Value string
+}
+
+func reencryptColumns(tx *TxWrap, encCols encryptedColumnsType) (int, error) {
+	var numRows int
+	for _, column := range encCols.Columns {
+		var rows []columnRow
+		tx.Select(&rows, fmt.Sprintf("SELECT %s AS id, %s AS value FROM %s", encCols.IdColumn, column, encCols.Table))
+		for _, row := range rows {
+			value, err := DecodeColumn(encCols.Table, column, row.Id, row.Value)
+			if err != nil {
+				return numRows, err
+			}
+			newValue, err := EncodeColumn(encCols.Table, column, row.Id, value)
+			if err != nil {
+				return numRows, err
+			}
+			tx.Exec(fmt.Sprintf("UPDATE %s SET %s = ? WHERE %s = ?", encCols.Table, column, encCols.IdColumn), newValue, row.Id)
+		}
+		numRows = max(numRows, len(rows))
+	}
+	return numRows, nil
+}
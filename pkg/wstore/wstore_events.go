@@ -0,0 +1,91 @@
+// Copyright 2025, Command Line Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package wstore
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/wavetermdev/waveterm/pkg/wps"
+)
+
+// the event journal (db_event).  seq is an AUTOINCREMENT column, so sqlite remembers the largest seq even after
+// the rows are purged, and sequence numbers are never reused.
+
+// the events must already have their Seq and Ts set.  events that cannot be marshaled are skipped.
+func DBInsertEvents(ctx context.Context, events []*wps.WaveEvent) error {
+	if len(events) == 0 {
+		return nil
+	}
+	return WithTx(ctx, func(tx *TxWrap) error {
+		query := `INSERT INTO db_event (seq, ts, event, data) VALUES (?, ?, ?, ?)`
+		for _, event := range events {
+			barr, err := json.Marshal(event)
+			if err != nil {
+				log.Printf("error marshaling event %q (seq %d) for the event journal: %v\n", event.Event, event.Seq, err)
+				continue
+			}
+			tx.Exec(query, event.Seq, event.Ts, event.Event, barr)
+		}
+		return nil
+	})
+}
+
+// returns the largest seq ever written to the journal (0 if the journal was never written)
+func DBGetLastEventSeq(ctx context.Context) (int64, error) {
+	return WithTxRtn(ctx, func(tx *TxWrap) (int64, error) {
+		return int64(tx.GetInt(`SELECT seq FROM sqlite_sequence WHERE name = 'db_event'`)), nil
+	})
+}
+
+// returns the smallest seq still in the journal (0 if the journal is empty)
+func DBGetFirstEventSeq(ctx context.Context) (int64, error) {
+	return WithTxRtn(ctx, func(tx *TxWrap) (int64, error) {
+		return int64(tx.GetInt(`SELECT COALESCE(MIN(seq), 0) FROM db_event`)), nil
+	})
+}
+
+// returns up to limit events with seq > sinceSeq (in seq order), only the given events if eventNames is not empty
+func DBReadEvents(ctx context.Context, sinceSeq int64, eventNames []string, limit int) ([]*wps.WaveEvent, error) {
+	return WithTxRtn(ctx, func(tx *TxWrap) ([]*wps.WaveEvent, error) {
+		query := `SELECT data FROM db_event WHERE seq > ?`
+		args := []any{sinceSeq}
+		if len(eventNames) > 0 {
+			query += ` AND event IN (` + strings.TrimSuffix(strings.Repeat("?,", len(eventNames)), ",") + `)`
+			for _, eventName := range eventNames {
+				args = append(args, eventName)
+			}
+		}
+		query += ` ORDER BY seq LIMIT ?`
+		args = append(args, limit)
+		dataStrs := tx.SelectStrings(query, args...)
+		rtn := make([]*wps.WaveEvent, 0, len(dataStrs))
+		for _, dataStr := range dataStrs {
+			var event wps.WaveEvent
+			err := json.Unmarshal([]byte(dataStr), &event)
+			if err != nil {
+				return nil, fmt.Errorf("error parsing journaled event: %w", err)
+			}
+			rtn = append(rtn, &event)
+		}
+		return rtn, nil
+	})
+}
+
+// removes the events written before beforeTs, plus the oldest events past maxEntries (if > 0).  returns the
+// number of events removed.
+func DBPurgeEvents(ctx context.Context, beforeTs int64, maxEntries int) (int, error) {
+	return WithTxRtn(ctx, func(tx *TxWrap) (int, error) {
+		numEvents := tx.GetInt(`SELECT count(*) FROM db_event`)
+		tx.Exec(`DELETE FROM db_event WHERE ts < ?`, beforeTs)
+		if maxEntries > 0 {
+			query := `DELETE FROM db_event WHERE seq <= (SELECT seq FROM db_event ORDER BY seq DESC LIMIT 1 OFFSET ?)`
+			tx.Exec(query, maxEntries)
+		}
+		return numEvents - tx.GetInt(`SELECT count(*) FROM db_event`), nil
+	})
+}
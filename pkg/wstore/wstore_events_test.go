@@ -0,0 +1,72 @@
+// Copyright 2025, Command Line Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package wstore
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/wavetermdev/waveterm/pkg/wps"
+)
+
+func TestEventJournal(t *testing.T) {
+	initDb(t)
+	defer cleanupDb(t)
+
+	ctx, cancelFn := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancelFn()
+	var events []*wps.WaveEvent
+	for seq := int64(1); seq <= 10; seq++ {
+		eventName := wps.Event_ControllerStatus
+		if seq%2 == 0 {
+			eventName = wps.Event_ConnChange
+		}
+		events = append(events, &wps.WaveEvent{
+			Event:  eventName,
+			Scopes: []string{"block:1"},
+			Seq:    seq,
+			Ts:     1000 * seq,
+			Data:   map[string]any{"n": seq},
+		})
+	}
+	err := DBInsertEvents(ctx, events)
+	if err != nil {
+		t.Fatalf("error inserting events: %v", err)
+	}
+	rtn, err := DBReadEvents(ctx, 3, nil, 100)
+	if err != nil {
+		t.Fatalf("error reading events: %v", err)
+	}
+	if len(rtn) != 7 || rtn[0].Seq != 4 || rtn[6].Seq != 10 {
+		t.Fatalf("unexpected events: %v", rtn)
+	}
+	if rtn[0].Ts != 4000 || rtn[0].Scopes[0] != "block:1" || rtn[0].Data.(map[string]any)["n"] != 4.0 {
+		t.Errorf("unexpected event: %+v", rtn[0])
+	}
+	rtn, _ = DBReadEvents(ctx, 0, []string{wps.Event_ConnChange}, 3)
+	if len(rtn) != 3 || rtn[0].Seq != 2 || rtn[2].Seq != 6 {
+		t.Errorf("unexpected connchange events: %v", rtn)
+	}
+
+	// by age (seq 1-2), then by count (keeps 5)
+	numPurged, err := DBPurgeEvents(ctx, 3000, 5)
+	if err != nil {
+		t.Fatalf("error purging events: %v", err)
+	}
+	if numPurged != 5 {
+		t.Errorf("expected 5 events purged, got %d", numPurged)
+	}
+	if firstSeq, _ := DBGetFirstEventSeq(ctx); firstSeq != 6 {
+		t.Errorf("expected first seq 6, got %d", firstSeq)
+	}
+	// the last seq is kept even when the journal is empty
+	DBPurgeEvents(ctx, 100000, 0)
+	if firstSeq, _ := DBGetFirstEventSeq(ctx); firstSeq != 0 {
+		t.Errorf("expected an empty journal, got first seq %d", firstSeq)
+	}
+	if lastSeq, _ := DBGetLastEventSeq(ctx); lastSeq != 10 {
+		t.Errorf("expected last seq 10, got %d", lastSeq)
+	}
+}
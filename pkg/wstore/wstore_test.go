@@ -0,0 +1,277 @@
+// Copyright 2025, Command Line Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package wstore
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/wavetermdev/waveterm/pkg/dbcrypt"
+	"github.com/wavetermdev/waveterm/pkg/wavebase"
+	"github.com/wavetermdev/waveterm/pkg/waveobj"
+	"github.com/wavetermdev/waveterm/pkg/wshrpc"
+)
+
+func initDb(t *testing.T) {
+	t.Logf("initializing db for %q", t.Name())
+	useTestingDb = true
+	wavebase.DataHome_VarCache = t.TempDir()
+	err := os.MkdirAll(filepath.Join(wavebase.DataHome_VarCache, wavebase.WaveDBDir), 0700)
+	if err != nil {
+		t.Fatalf("error creating db dir: %v", err)
+	}
+	err = InitWStore()
+	if err != nil {
+		t.Fatalf("error initializing wstore: %v", err)
+	}
+}
+
+func cleanupDb(t *testing.T) {
+	t.Logf("cleaning up db for %q", t.Name())
+	if globalDB != nil {
+		globalDB.Close()
+		globalDB = nil
+	}
+	useTestingDb = false
+	dbcrypt.Disable()
+}
+
+// returns the bytes of the sqlite db file (the test db is in memory, so it is copied to a file first)
+func rawDBBytes(t *testing.T, ctx context.Context) []byte {
+	fileName := filepath.Join(t.TempDir(), "raw.db")
+	_, err := globalDB.ExecContext(ctx, "VACUUM INTO ?", fileName)
+	if err != nil {
+		t.Fatalf("error copying db: %v", err)
+	}
+	barr, err := os.ReadFile(fileName)
+	if err != nil {
+		t.Fatalf("error reading db: %v", err)
+	}
+	return barr
+}
+
+func makeTestBlock(tabId string, cmd string) *waveobj.Block {
+	return &waveobj.Block{
+		OID:        uuid.NewString(),
+		ParentORef: waveobj.MakeORef(waveobj.OType_Tab, tabId).String(),
+		Meta: waveobj.MetaMapType{
+			waveobj.MetaKey_View: "term",
+			waveobj.MetaKey_Cmd:  cmd,
+		},
+	}
+}
+
+func checkBlock(t *testing.T, ctx context.Context, blockId string, cmd string) {
+	block, err := DBMustGet[*waveobj.Block](ctx, blockId)
+	if err != nil {
+		t.Fatalf("error getting block: %v", err)
+	}
+	if block.Meta.GetString(waveobj.MetaKey_Cmd, "") != cmd {
+		t.Errorf("block %s: expected cmd %q, got %q", blockId, cmd, block.Meta.GetString(waveobj.MetaKey_Cmd, ""))
+	}
+}
+
+// inserts a history entry the way cmdhistory.InsertEntry does
+func insertTestHistoryEntry(t *testing.T, ctx context.Context, historyId string, cmdText string, cwd string) {
+	encCmdText, err := EncodeColumn("db_cmdhistory", "cmdtext", historyId, cmdText)
+	if err == nil {
+		cwd, err = EncodeColumn("db_cmdhistory", "cwd", historyId, cwd)
+	}
+	if err == nil {
+		err = WithTx(ctx, func(tx *TxWrap) error {
+			query := `INSERT INTO db_cmdhistory (historyid, blockid, conn, cmdtext, cwd, startts, endts, outputstart, outputend)
+                                          VALUES (        ?,       ?,   '',       ?,   ?,       1,     2,           0,         0)`
+			tx.Exec(query, historyId, uuid.NewString(), encCmdText, cwd)
+			return nil
+		})
+	}
+	if err != nil {
+		t.Fatalf("error inserting history entry: %v", err)
+	}
+}
+
+func checkHistoryEntry(t *testing.T, ctx context.Context, historyId string, cmdText string) {
+	value, err := WithTxRtn(ctx, func(tx *TxWrap) (string, error) {
+		return tx.GetString(`SELECT cmdtext FROM db_cmdhistory WHERE historyid = ?`, historyId), nil
+	})
+	if err == nil {
+		value, err = DecodeColumn("db_cmdhistory", "cmdtext", historyId, value)
+	}
+	if err != nil || value != cmdText {
+		t.Errorf("history entry %s: expected %q, got %q (err %v)", historyId, cmdText, value, err)
+	}
+}
+
+func TestEncryptedBlocks(t *testing.T) {
+	initDb(t)
+	defer cleanupDb(t)
+
+	ctx, cancelFn := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancelFn()
+	tabId := uuid.NewString()
+	secretCmd := "curl -H 'Authorization: Bearer sk-live-TOKEN1234' https://example.com"
+	block1 := makeTestBlock(tabId, secretCmd)
+	err := DBInsert(ctx, block1)
+	if err != nil {
+		t.Fatalf("error inserting block: %v", err)
+	}
+	historyId := uuid.NewString()
+	insertTestHistoryEntry(t, ctx, historyId, "export API_KEY=sk-hist-TOKEN5678", "/home/user/history-project")
+	raw := rawDBBytes(t, ctx)
+	if !bytes.Contains(raw, []byte("sk-live-TOKEN1234")) || !bytes.Contains(raw, []byte("sk-hist-TOKEN5678")) {
+		t.Fatalf("expected plaintext in the db before encrypting")
+	}
+
+	// encrypt the existing blocks in place
+	err = dbcrypt.Enable(dbcrypt.KeySourceFile)
+	if err != nil {
+		t.Fatalf("error enabling encryption: %v", err)
+	}
+	count, err := DBReencryptAll(ctx)
+	if err != nil {
+		t.Fatalf("error encrypting: %v", err)
+	}
+	if count != 2 {
+		t.Errorf("expected 1 object and 1 history entry to be encrypted, got %d", count)
+	}
+	// new and updated blocks are encrypted as well
+	block2 := makeTestBlock(tabId, "ssh -i ~/.ssh/id_secretkey host")
+	err = DBInsert(ctx, block2)
+	if err != nil {
+		t.Fatalf("error inserting block: %v", err)
+	}
+	block1.Meta[waveobj.MetaKey_CmdCwd] = "/home/user/secret-project"
+	err = DBUpdate(ctx, block1)
+	if err != nil {
+		t.Fatalf("error updating block: %v", err)
+	}
+	insertTestHistoryEntry(t, ctx, uuid.NewString(), "ssh -i ~/.ssh/id_histkey host", "/home/user/history-project")
+	raw = rawDBBytes(t, ctx)
+	for _, plaintext := range []string{"sk-live-TOKEN1234", "id_secretkey", "secret-project", "parentoref", "sk-hist-TOKEN5678", "id_histkey", "history-project"} {
+		if bytes.Contains(raw, []byte(plaintext)) {
+			t.Errorf("found %q in the db after encrypting", plaintext)
+		}
+	}
+	checkBlock(t, ctx, block1.OID, secretCmd)
+	checkBlock(t, ctx, block2.OID, "ssh -i ~/.ssh/id_secretkey host")
+	counts, err := DBGetBlockViewCounts(ctx)
+	if err != nil || counts["term"] != 2 {
+		t.Errorf("unexpected view counts: %v (err %v)", counts, err)
+	}
+	foundTabId, err := DBFindTabForBlockId(ctx, block2.OID)
+	if err != nil || foundTabId != tabId {
+		t.Errorf("expected tab %s, got %q (err %v)", tabId, foundTabId, err)
+	}
+
+	// rotate the key and re-encrypt
+	_, err = dbcrypt.RotateKey("")
+	if err != nil {
+		t.Fatalf("error rotating key: %v", err)
+	}
+	_, err = DBReencryptAll(ctx)
+	if err != nil {
+		t.Fatalf("error re-encrypting: %v", err)
+	}
+	err = dbcrypt.PruneKeys()
+	if err != nil {
+		t.Fatalf("error pruning keys: %v", err)
+	}
+	checkBlock(t, ctx, block1.OID, secretCmd)
+	checkHistoryEntry(t, ctx, historyId, "export API_KEY=sk-hist-TOKEN5678")
+	blocks, err := DBGetAllObjsByType[*waveobj.Block](ctx, waveobj.OType_Block)
+	if err != nil || len(blocks) != 2 {
+		t.Errorf("expected 2 blocks, got %d (err %v)", len(blocks), err)
+	}
+
+	// and decrypt them again
+	dbcrypt.Disable()
+	_, err = DBReencryptAll(ctx)
+	if err != nil {
+		t.Fatalf("error decrypting: %v", err)
+	}
+	raw = rawDBBytes(t, ctx)
+	if !bytes.Contains(raw, []byte("sk-live-TOKEN1234")) || !bytes.Contains(raw, []byte("sk-hist-TOKEN5678")) {
+		t.Errorf("expected plaintext in the db after decrypting")
+	}
+	checkHistoryEntry(t, ctx, historyId, "export API_KEY=sk-hist-TOKEN5678")
+	checkBlock(t, ctx, block2.OID, "ssh -i ~/.ssh/id_secretkey host")
+}
+
+func TestTrash(t *testing.T) {
+	initDb(t)
+	defer cleanupDb(t)
+
+	ctx, cancelFn := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancelFn()
+	tabId := uuid.NewString()
+	blocks := []*waveobj.Block{makeTestBlock(tabId, "ls"), makeTestBlock(tabId, "top"), makeTestBlock(tabId, "htop")}
+	for idx, block := range blocks {
+		err := DBInsert(ctx, block)
+		if err != nil {
+			t.Fatalf("error inserting block: %v", err)
+		}
+		entry := &wshrpc.TrashEntry{TrashId: uuid.NewString(), OType: waveobj.OType_Block, OID: block.OID, DeletedTs: int64(1000 * (idx + 1)), View: "term", NumBlocks: 1}
+		err = DBInsertTrash(ctx, entry, []waveobj.WaveObj{block})
+		if err != nil {
+			t.Fatalf("error trashing block: %v", err)
+		}
+		err = DBDelete(ctx, waveobj.OType_Block, block.OID)
+		if err != nil {
+			t.Fatalf("error deleting block: %v", err)
+		}
+	}
+	entries, err := DBListTrash(ctx)
+	if err != nil || len(entries) != 3 {
+		t.Fatalf("expected 3 trash entries, got %d (err %v)", len(entries), err)
+	}
+	if entries[0].OID != blocks[2].OID || entries[2].OID != blocks[0].OID {
+		t.Errorf("expected the newest entry first")
+	}
+	entry, err := DBFindTrashForOID(ctx, waveobj.OType_Block, blocks[1].OID)
+	if err != nil || entry == nil || entry.TrashId != entries[1].TrashId {
+		t.Fatalf("expected to find the trash entry of block %s, got %v (err %v)", blocks[1].OID, entry, err)
+	}
+	objs, err := DBGetTrashObjs(ctx, entry.TrashId)
+	if err != nil || len(objs) != 1 {
+		t.Fatalf("expected 1 trashed object, got %d (err %v)", len(objs), err)
+	}
+	if cmd := objs[0].(*waveobj.Block).Meta.GetString(waveobj.MetaKey_Cmd, ""); cmd != "top" {
+		t.Errorf("expected trashed block with cmd %q, got %q", "top", cmd)
+	}
+	oids, err := DBGetTrashedOIDs(ctx)
+	if err != nil || len(oids) != 3 {
+		t.Errorf("expected 3 trashed oids, got %v (err %v)", oids, err)
+	}
+
+	// the oldest entry is past the retention window, and the next one is past the max number of entries
+	expiredIds, err := DBGetExpiredTrashIds(ctx, 1500, 1)
+	if err != nil || len(expiredIds) != 2 {
+		t.Fatalf("expected 2 expired entries, got %v (err %v)", expiredIds, err)
+	}
+	for _, trashId := range expiredIds {
+		if trashId == entries[0].TrashId {
+			t.Errorf("newest entry should not expire")
+		}
+	}
+
+	// restoring removes the entry
+	err = DBInsert(ctx, objs[0])
+	if err != nil {
+		t.Fatalf("error restoring block: %v", err)
+	}
+	removedOIDs, err := DBRemoveTrash(ctx, entry.TrashId)
+	if err != nil || len(removedOIDs) != 1 || removedOIDs[0] != blocks[1].OID {
+		t.Errorf("expected block %s to be removed from the trash, got %v (err %v)", blocks[1].OID, removedOIDs, err)
+	}
+	checkBlock(t, ctx, blocks[1].OID, "top")
+	_, err = DBGetTrash(ctx, entry.TrashId)
+	if err != ErrNotFound {
+		t.Errorf("expected restored entry to be gone, got err %v", err)
+	}
+}
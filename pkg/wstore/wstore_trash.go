@@ -0,0 +1,164 @@
+// Copyright 2025, Command Line Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package wstore
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+
+	"github.com/wavetermdev/waveterm/pkg/filestore"
+	"github.com/wavetermdev/waveterm/pkg/util/dbutil"
+	"github.com/wavetermdev/waveterm/pkg/waveobj"
+	"github.com/wavetermdev/waveterm/pkg/wshrpc"
+)
+
+// closed tabs and blocks are moved to the trash tables (the objects are stored the same way as in their own
+// tables, so encrypted blocks stay encrypted).  the filestore zones of trashed objects are kept until the
+// entry is purged.
+
+type trashObjRow struct {
+	OType string
+	OID   string
+	Data  []byte
+}
+
+// objs must include the entry's object (and everything that should be restored with it)
+func DBInsertTrash(ctx context.Context, entry *wshrpc.TrashEntry, objs []waveobj.WaveObj) error {
+	type encodedObj struct {
+		otype string
+		oid   string
+		data  []byte
+	}
+	encoded := make([]encodedObj, 0, len(objs))
+	for _, obj := range objs {
+		oid := waveobj.GetOID(obj)
+		jsonData, err := waveobj.ToJson(obj)
+		if err == nil {
+			jsonData, err = encodeObjData(obj.GetOType(), oid, jsonData)
+		}
+		if err != nil {
+			return err
+		}
+		encoded = append(encoded, encodedObj{otype: obj.GetOType(), oid: oid, data: jsonData})
+	}
+	return WithTx(ctx, func(tx *TxWrap) error {
+		query := `INSERT INTO db_trash (trashid, otype, oid, deletedts, entry) VALUES (?, ?, ?, ?, ?)`
+		tx.Exec(query, entry.TrashId, entry.OType, entry.OID, entry.DeletedTs, dbutil.QuickJson(entry))
+		for _, obj := range encoded {
+			// an object can only be in the trash once (replace in case a restore was interrupted)
+			query = `INSERT OR REPLACE INTO db_trashobj (otype, oid, trashid, data) VALUES (?, ?, ?, ?)`
+			tx.Exec(query, obj.otype, obj.oid, entry.TrashId, obj.data)
+		}
+		return nil
+	})
+}
+
+func scanTrashEntries(entryStrs []string) ([]*wshrpc.TrashEntry, error) {
+	rtn := make([]*wshrpc.TrashEntry, 0, len(entryStrs))
+	for _, entryStr := range entryStrs {
+		var entry wshrpc.TrashEntry
+		err := json.Unmarshal([]byte(entryStr), &entry)
+		if err != nil {
+			return nil, fmt.Errorf("error parsing trash entry: %w", err)
+		}
+		rtn = append(rtn, &entry)
+	}
+	return rtn, nil
+}
+
+// newest first
+func DBListTrash(ctx context.Context) ([]*wshrpc.TrashEntry, error) {
+	return WithTxRtn(ctx, func(tx *TxWrap) ([]*wshrpc.TrashEntry, error) {
+		entryStrs := tx.SelectStrings(`SELECT entry FROM db_trash ORDER BY deletedts DESC`)
+		return scanTrashEntries(entryStrs)
+	})
+}
+
+func DBGetTrash(ctx context.Context, trashId string) (*wshrpc.TrashEntry, error) {
+	return WithTxRtn(ctx, func(tx *TxWrap) (*wshrpc.TrashEntry, error) {
+		entries, err := scanTrashEntries(tx.SelectStrings(`SELECT entry FROM db_trash WHERE trashid = ?`, trashId))
+		if err != nil {
+			return nil, err
+		}
+		if len(entries) == 0 {
+			return nil, ErrNotFound
+		}
+		return entries[0], nil
+	})
+}
+
+// returns the most recent entry for the object (nil if it is not in the trash)
+func DBFindTrashForOID(ctx context.Context, otype string, oid string) (*wshrpc.TrashEntry, error) {
+	return WithTxRtn(ctx, func(tx *TxWrap) (*wshrpc.TrashEntry, error) {
+		query := `SELECT entry FROM db_trash WHERE otype = ? AND oid = ? ORDER BY deletedts DESC LIMIT 1`
+		entries, err := scanTrashEntries(tx.SelectStrings(query, otype, oid))
+		if err != nil || len(entries) == 0 {
+			return nil, err
+		}
+		return entries[0], nil
+	})
+}
+
+func DBGetTrashObjs(ctx context.Context, trashId string) ([]waveobj.WaveObj, error) {
+	return WithTxRtn(ctx, func(tx *TxWrap) ([]waveobj.WaveObj, error) {
+		var rows []trashObjRow
+		tx.Select(&rows, `SELECT otype, oid, data FROM db_trashobj WHERE trashid = ?`, trashId)
+		rtn := make([]waveobj.WaveObj, 0, len(rows))
+		for _, row := range rows {
+			obj, err := objFromRow(row.OType, idDataType{OId: row.OID, Data: row.Data})
+			if err != nil {
+				return nil, err
+			}
+			rtn = append(rtn, obj)
+		}
+		return rtn, nil
+	})
+}
+
+// removes the entry (after it was restored), the filestore zones of its objects are kept
+func DBRemoveTrash(ctx context.Context, trashId string) ([]string, error) {
+	return WithTxRtn(ctx, func(tx *TxWrap) ([]string, error) {
+		oids := tx.SelectStrings(`SELECT oid FROM db_trashobj WHERE trashid = ?`, trashId)
+		tx.Exec(`DELETE FROM db_trashobj WHERE trashid = ?`, trashId)
+		tx.Exec(`DELETE FROM db_trash WHERE trashid = ?`, trashId)
+		return oids, nil
+	})
+}
+
+// removes the entry and deletes the filestore zones of its objects
+func DBPurgeTrash(ctx context.Context, trashId string) error {
+	oids, err := DBRemoveTrash(ctx, trashId)
+	if err != nil {
+		return err
+	}
+	for _, oid := range oids {
+		err = filestore.WFS.DeleteZone(ctx, oid)
+		if err != nil {
+			log.Printf("error deleting filestore zone %s (purging trash): %v\n", oid, err)
+		}
+	}
+	return nil
+}
+
+// returns the entries deleted before beforeTs, plus the oldest entries past maxEntries (if > 0)
+func DBGetExpiredTrashIds(ctx context.Context, beforeTs int64, maxEntries int) ([]string, error) {
+	return WithTxRtn(ctx, func(tx *TxWrap) ([]string, error) {
+		query := `SELECT trashid FROM db_trash WHERE deletedts < ?`
+		args := []any{beforeTs}
+		if maxEntries > 0 {
+			query += ` UNION SELECT trashid FROM (SELECT trashid FROM db_trash ORDER BY deletedts DESC LIMIT -1 OFFSET ?)`
+			args = append(args, maxEntries)
+		}
+		return tx.SelectStrings(query, args...), nil
+	})
+}
+
+// ids of all trashed objects (their filestore zones are still in use)
+func DBGetTrashedOIDs(ctx context.Context) ([]string, error) {
+	return WithTxRtn(ctx, func(tx *TxWrap) ([]string, error) {
+		return tx.SelectStrings(`SELECT oid FROM db_trashobj`), nil
+	})
+}
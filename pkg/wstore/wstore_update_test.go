@@ -0,0 +1,244 @@
+// Copyright 2025, Command Line Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package wstore
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/wavetermdev/waveterm/pkg/ijson"
+	"github.com/wavetermdev/waveterm/pkg/waveobj"
+)
+
+func TestVersionConflict(t *testing.T) {
+	initDb(t)
+	defer cleanupDb(t)
+
+	ctx, cancelFn := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancelFn()
+	block := makeTestBlock(uuid.NewString(), "ls")
+	err := DBInsert(ctx, block)
+	if err != nil {
+		t.Fatalf("error inserting block: %v", err)
+	}
+	block1, _ := DBMustGet[*waveobj.Block](ctx, block.OID)
+	block2, _ := DBMustGet[*waveobj.Block](ctx, block.OID)
+	block1.Meta[waveobj.MetaKey_Cmd] = "pwd"
+	err = DBUpdate(ctx, block1)
+	if err != nil {
+		t.Fatalf("error updating block: %v", err)
+	}
+	if block1.Version != 2 {
+		t.Errorf("expected version 2, got %d", block1.Version)
+	}
+	// block2 is stale now
+	block2.Meta[waveobj.MetaKey_Cmd] = "whoami"
+	err = DBUpdate(ctx, block2)
+	var conflictErr *VersionConflictError
+	if !errors.As(err, &conflictErr) {
+		t.Fatalf("expected a version conflict, got %v", err)
+	}
+	if conflictErr.Version != 1 || conflictErr.DBVersion != 2 || conflictErr.OID != block.OID {
+		t.Errorf("unexpected conflict error: %+v", conflictErr)
+	}
+	checkBlock(t, ctx, block.OID, "pwd")
+	// retried on the current version
+	block2, _ = DBMustGet[*waveobj.Block](ctx, block.OID)
+	block2.Meta[waveobj.MetaKey_Cmd] = "whoami"
+	err = DBUpdate(ctx, block2)
+	if err != nil {
+		t.Fatalf("error updating block: %v", err)
+	}
+	checkBlock(t, ctx, block.OID, "whoami")
+
+	missing := makeTestBlock(uuid.NewString(), "ls")
+	missing.Version = 1
+	err = DBUpdate(ctx, missing)
+	if err != ErrNotFound {
+		t.Errorf("expected ErrNotFound updating a missing block, got %v", err)
+	}
+}
+
+// many goroutines setting meta on the same block (with UpdateObjectMeta, like SetMetaCommand), none of the updates
+// can be lost
+func TestConcurrentSetMeta(t *testing.T) {
+	initDb(t)
+	defer cleanupDb(t)
+
+	ctx, cancelFn := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancelFn()
+	block := makeTestBlock(uuid.NewString(), "ls")
+	err := DBInsert(ctx, block)
+	if err != nil {
+		t.Fatalf("error inserting block: %v", err)
+	}
+	oref := waveobj.MakeORef(waveobj.OType_Block, block.OID)
+	const numWorkers = 20
+	const numUpdates = 25
+	var wg sync.WaitGroup
+	errCh := make(chan error, numWorkers*numUpdates*2)
+	for worker := 0; worker < numWorkers; worker++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < numUpdates; i++ {
+				meta := waveobj.MetaMapType{
+					fmt.Sprintf("test:%d:%d", worker, i): i,
+					fmt.Sprintf("test:last:%d", worker):  i,
+				}
+				if err := UpdateObjectMeta(ctx, oref, meta, false); err != nil {
+					errCh <- err
+				}
+				appendCmd := ijson.MakeAppendCommand(ijson.Path{"test:list"}, fmt.Sprintf("%d:%d", worker, i))
+				if _, err := PatchObjectMeta(ctx, oref, []ijson.Command{appendCmd}); err != nil {
+					errCh <- err
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	close(errCh)
+	for err := range errCh {
+		t.Errorf("error updating meta: %v", err)
+	}
+	result, err := DBMustGet[*waveobj.Block](ctx, block.OID)
+	if err != nil {
+		t.Fatalf("error getting block: %v", err)
+	}
+	for worker := 0; worker < numWorkers; worker++ {
+		for i := 0; i < numUpdates; i++ {
+			if _, ok := result.Meta[fmt.Sprintf("test:%d:%d", worker, i)]; !ok {
+				t.Errorf("lost update %d of worker %d", i, worker)
+			}
+		}
+		if result.Meta.GetInt(fmt.Sprintf("test:last:%d", worker), -1) != numUpdates-1 {
+			t.Errorf("worker %d: expected last %d, got %v", worker, numUpdates-1, result.Meta[fmt.Sprintf("test:last:%d", worker)])
+		}
+	}
+	list, _ := result.Meta["test:list"].([]any)
+	if len(list) != numWorkers*numUpdates {
+		t.Errorf("expected %d appended values, got %d", numWorkers*numUpdates, len(list))
+	}
+	if result.Meta.GetString(waveobj.MetaKey_Cmd, "") != "ls" {
+		t.Errorf("cmd was overwritten: %v", result.Meta[waveobj.MetaKey_Cmd])
+	}
+	// one version per update
+	if expected := 1 + 2*numWorkers*numUpdates; result.Version != expected {
+		t.Errorf("expected version %d, got %d", expected, result.Version)
+	}
+}
+
+// stale whole-object writes racing with meta updates: every write either succeeds or gets a conflict, and the
+// meta updates are never lost
+func TestConcurrentStaleUpdates(t *testing.T) {
+	initDb(t)
+	defer cleanupDb(t)
+
+	ctx, cancelFn := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancelFn()
+	block := makeTestBlock(uuid.NewString(), "ls")
+	err := DBInsert(ctx, block)
+	if err != nil {
+		t.Fatalf("error inserting block: %v", err)
+	}
+	oref := waveobj.MakeORef(waveobj.OType_Block, block.OID)
+	const numUpdates = 100
+	var wg sync.WaitGroup
+	var numConflicts, numWrites int
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < numUpdates; i++ {
+			err := UpdateObjectMeta(ctx, oref, waveobj.MetaMapType{fmt.Sprintf("test:%d", i): true}, false)
+			if err != nil {
+				t.Errorf("error updating meta: %v", err)
+			}
+		}
+	}()
+	go func() {
+		defer wg.Done()
+		for i := 0; i < numUpdates; i++ {
+			stale, err := DBMustGet[*waveobj.Block](ctx, block.OID)
+			if err != nil {
+				t.Errorf("error getting block: %v", err)
+				return
+			}
+			time.Sleep(time.Millisecond / 10)
+			stale.Stickers = []*waveobj.StickerType{{StickerType: "test"}}
+			err = DBUpdate(ctx, stale)
+			if IsVersionConflict(err) {
+				numConflicts++
+			} else if err != nil {
+				t.Errorf("unexpected error: %v", err)
+			} else {
+				numWrites++
+			}
+		}
+	}()
+	wg.Wait()
+	result, err := DBMustGet[*waveobj.Block](ctx, block.OID)
+	if err != nil {
+		t.Fatalf("error getting block: %v", err)
+	}
+	for i := 0; i < numUpdates; i++ {
+		if !result.Meta.GetBool(fmt.Sprintf("test:%d", i), false) {
+			t.Errorf("lost meta update %d", i)
+		}
+	}
+	if expected := 1 + numUpdates + numWrites; result.Version != expected {
+		t.Errorf("expected version %d, got %d (%d conflicts)", expected, result.Version, numConflicts)
+	}
+}
+
+func TestPatchObjectMeta(t *testing.T) {
+	initDb(t)
+	defer cleanupDb(t)
+
+	ctx, cancelFn := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancelFn()
+	block := makeTestBlock(uuid.NewString(), "ls")
+	block.Meta["test:obj"] = map[string]any{"a": 1.0, "b": 2.0}
+	err := DBInsert(ctx, block)
+	if err != nil {
+		t.Fatalf("error inserting block: %v", err)
+	}
+	oref := waveobj.MakeORef(waveobj.OType_Block, block.OID)
+	commands := []ijson.Command{
+		ijson.MakeSetCommand(ijson.Path{"test:obj", "b"}, 3.0),
+		ijson.MakeSetCommand(ijson.Path{"test:obj", "c"}, "x"),
+		ijson.MakeDelCommand(ijson.Path{waveobj.MetaKey_Cmd}),
+		ijson.MakeAppendCommand(ijson.Path{"test:list"}, "one"),
+	}
+	meta, err := PatchObjectMeta(ctx, oref, commands)
+	if err != nil {
+		t.Fatalf("error patching meta: %v", err)
+	}
+	if !ijson.DeepEqual(meta["test:obj"], map[string]any{"a": 1.0, "b": 3.0, "c": "x"}) {
+		t.Errorf("unexpected test:obj: %v", meta["test:obj"])
+	}
+	if _, ok := meta[waveobj.MetaKey_Cmd]; ok {
+		t.Errorf("cmd should have been deleted")
+	}
+	// paths decoded from json have float64 indexes
+	meta, err = PatchObjectMeta(ctx, oref, []ijson.Command{{"type": "set", "path": []any{"test:list", 0.0}, "data": "first"}})
+	if err != nil {
+		t.Fatalf("error patching meta: %v", err)
+	}
+	if !ijson.DeepEqual(meta["test:list"], []any{"first"}) {
+		t.Errorf("unexpected test:list: %v", meta["test:list"])
+	}
+	_, err = PatchObjectMeta(ctx, oref, []ijson.Command{ijson.MakeSetCommand(nil, map[string]any{})})
+	if err == nil {
+		t.Errorf("expected an error for an empty path")
+	}
+	block2, _ := DBMustGet[*waveobj.Block](ctx, block.OID)
+	if block2.Meta.GetString(waveobj.MetaKey_View, "") != "term" {
+		t.Errorf("view should be unchanged")
+	}
+}